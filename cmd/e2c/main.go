@@ -8,6 +8,7 @@ import (
 	"os"
 
 	"github.com/nlamirault/e2c/internal/cmd"
+	"github.com/nlamirault/e2c/internal/config"
 	"github.com/nlamirault/e2c/internal/logger"
 )
 
@@ -16,12 +17,12 @@ func main() {
 	logConfig := logger.NewConfig()
 
 	// Set log level from environment variable
-	if envLevel := os.Getenv("E2C_LOG_LEVEL"); envLevel != "" {
+	if envLevel := os.Getenv(config.EnvLogLevel); envLevel != "" {
 		logConfig.Level = logger.ParseLevel(envLevel)
 	}
 
 	// Set log format from environment variable
-	if envFormat := os.Getenv("E2C_LOG_FORMAT"); envFormat != "" {
+	if envFormat := os.Getenv(config.EnvLogFormat); envFormat != "" {
 		logConfig.Format = logger.ParseFormat(envFormat)
 	}
 
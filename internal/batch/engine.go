@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package batch
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Options configures the batch engine
+type Options struct {
+	Concurrency int              // Maximum number of items processed at the same time
+	MaxRetries  int              // Number of retries of a failed item
+	Backoff     time.Duration    // Delay before the first retry, doubled for each retry
+	Rate        float64          // Maximum number of calls per second, 0 for no limit
+	Retryable   func(error) bool // Returns true if an error can be retried, all errors are retried if nil
+}
+
+// DefaultOptions returns the default engine options
+func DefaultOptions() Options {
+	return Options{
+		Concurrency: 5,
+		MaxRetries:  3,
+		Backoff:     time.Second,
+		Rate:        10,
+	}
+}
+
+// Result is the outcome of the processing of an item
+type Result struct {
+	ID       string        // Item identifier
+	Err      error         // Error of the last attempt, nil on success
+	Attempts int           // Number of attempts
+	Duration time.Duration // Total processing time, including retries
+}
+
+// Progress is called each time an item has been processed, or skipped
+// because the batch was cancelled
+type Progress func(done, failed, total int)
+
+// Func processes an item
+type Func func(ctx context.Context, id string) error
+
+// Engine runs a function over a set of items with bounded concurrency,
+// rate limiting and retries with exponential backoff
+type Engine struct {
+	log     *slog.Logger
+	options Options
+}
+
+// NewEngine creates a new batch engine
+func NewEngine(log *slog.Logger, options Options) *Engine {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+
+	return &Engine{
+		log:     log,
+		options: options,
+	}
+}
+
+// Run processes the items and returns their results in the same order.
+// Cancelling the context stops the processing: the items not processed yet
+// are reported with the context error.
+func (e *Engine) Run(ctx context.Context, ids []string, fn Func, progress Progress) []Result {
+	e.log.Info("Starting batch", "items", len(ids), "concurrency", e.options.Concurrency)
+
+	results := make([]Result, len(ids))
+	limiter := newLimiter(e.options.Rate)
+	defer limiter.stop()
+
+	var (
+		mutex  sync.Mutex
+		done   int
+		failed int
+		wg     sync.WaitGroup
+	)
+
+	jobs := make(chan int)
+	for w := 0; w < e.options.Concurrency && w < len(ids); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				result := e.process(ctx, ids[index], fn, limiter)
+
+				mutex.Lock()
+				results[index] = result
+				done++
+				if result.Err != nil {
+					failed++
+				}
+				d, f := done, failed
+				mutex.Unlock()
+
+				if progress != nil {
+					progress(d, f, len(ids))
+				}
+			}
+		}()
+	}
+
+	for index := range ids {
+		select {
+		case jobs <- index:
+		case <-ctx.Done():
+			// Report the remaining items as cancelled, so that the progress
+			// adds up to all the items
+			for i := index; i < len(ids); i++ {
+				mutex.Lock()
+				results[i] = Result{ID: ids[i], Err: ctx.Err()}
+				done++
+				failed++
+				d, f := done, failed
+				mutex.Unlock()
+
+				if progress != nil {
+					progress(d, f, len(ids))
+				}
+			}
+			close(jobs)
+			wg.Wait()
+			e.log.Warn("Batch cancelled", "items", len(ids), "failed", failed)
+			return results
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	e.log.Info("Batch completed", "items", len(ids), "failed", failed)
+
+	return results
+}
+
+// process processes an item, retrying on errors
+func (e *Engine) process(ctx context.Context, id string, fn Func, limiter *limiter) Result {
+	start := time.Now()
+	result := Result{ID: id}
+	backoff := e.options.Backoff
+
+	for attempt := 0; attempt <= e.options.MaxRetries; attempt++ {
+		if err := limiter.wait(ctx); err != nil {
+			result.Err = err
+			break
+		}
+
+		result.Attempts++
+		result.Err = fn(ctx, id)
+		if result.Err == nil || ctx.Err() != nil {
+			break
+		}
+		if e.options.Retryable != nil && !e.options.Retryable(result.Err) {
+			break
+		}
+		if attempt == e.options.MaxRetries {
+			break
+		}
+
+		e.log.Debug("Retrying batch item", "id", id, "attempt", result.Attempts, "backoff", backoff, "error", result.Err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+			result.Duration = time.Since(start)
+			return result
+		}
+		backoff *= 2
+	}
+
+	result.Duration = time.Since(start)
+	return result
+}
+
+// limiter spaces out the calls to respect a maximum rate
+type limiter struct {
+	ticker *time.Ticker
+}
+
+// newLimiter creates a limiter allowing rate calls per second, without limit if rate is 0
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return &limiter{}
+	}
+	return &limiter{
+		ticker: time.NewTicker(time.Duration(float64(time.Second) / rate)),
+	}
+}
+
+// wait blocks until the next call is allowed
+func (l *limiter) wait(ctx context.Context) error {
+	if l.ticker == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop releases the limiter resources
+func (l *limiter) stop() {
+	if l.ticker != nil {
+		l.ticker.Stop()
+	}
+}
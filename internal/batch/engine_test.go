@@ -0,0 +1,247 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	errThrottled = errors.New("throttled")
+	errNotFound  = errors.New("not found")
+)
+
+// newTestEngine creates an engine without logs, rate limit nor backoff delay
+func newTestEngine(options Options) *Engine {
+	options.Backoff = time.Millisecond
+	return NewEngine(slog.New(slog.NewTextHandler(io.Discard, nil)), options)
+}
+
+func TestRunRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		maxRetries   int
+		retryable    func(error) bool
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "success", failures: 0, err: errThrottled, maxRetries: 3, wantAttempts: 1},
+		{name: "retried until success", failures: 2, err: errThrottled, maxRetries: 3, wantAttempts: 3},
+		{name: "retries exhausted", failures: 10, err: errThrottled, maxRetries: 3, wantAttempts: 4, wantErr: errThrottled},
+		{name: "no retries", failures: 10, err: errThrottled, maxRetries: 0, wantAttempts: 1, wantErr: errThrottled},
+		{name: "negative retries", failures: 10, err: errThrottled, maxRetries: -1, wantAttempts: 1, wantErr: errThrottled},
+		{
+			name:         "retryable error",
+			failures:     1,
+			err:          errThrottled,
+			maxRetries:   3,
+			retryable:    func(err error) bool { return errors.Is(err, errThrottled) },
+			wantAttempts: 2,
+		},
+		{
+			name:         "non retryable error",
+			failures:     10,
+			err:          errNotFound,
+			maxRetries:   3,
+			retryable:    func(err error) bool { return errors.Is(err, errThrottled) },
+			wantAttempts: 1,
+			wantErr:      errNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine(Options{Concurrency: 1, MaxRetries: tt.maxRetries, Retryable: tt.retryable})
+
+			calls := 0
+			results := engine.Run(context.Background(), []string{"i-1"}, func(ctx context.Context, id string) error {
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			}, nil)
+
+			if len(results) != 1 {
+				t.Fatalf("Run() returned %d results, want 1", len(results))
+			}
+			if results[0].Attempts != tt.wantAttempts || calls != tt.wantAttempts {
+				t.Errorf("attempts = %d (%d calls), want %d", results[0].Attempts, calls, tt.wantAttempts)
+			}
+			if !errors.Is(results[0].Err, tt.wantErr) || (tt.wantErr == nil && results[0].Err != nil) {
+				t.Errorf("error = %v, want %v", results[0].Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunResultsOrder(t *testing.T) {
+	ids := []string{"i-1", "i-2", "i-3", "i-4", "i-5", "i-6"}
+	// The first items take the longest, so they complete last
+	delays := map[string]time.Duration{
+		"i-1": 30 * time.Millisecond,
+		"i-2": 20 * time.Millisecond,
+		"i-3": 10 * time.Millisecond,
+	}
+
+	engine := newTestEngine(Options{Concurrency: 3})
+	results := engine.Run(context.Background(), ids, func(ctx context.Context, id string) error {
+		time.Sleep(delays[id])
+		if id == "i-4" {
+			return errNotFound
+		}
+		return nil
+	}, nil)
+
+	got := make([]string, 0, len(results))
+	for _, result := range results {
+		got = append(got, result.ID)
+	}
+	if !reflect.DeepEqual(got, ids) {
+		t.Errorf("result IDs = %q, want %q", got, ids)
+	}
+	for _, result := range results {
+		if wantErr := result.ID == "i-4"; (result.Err != nil) != wantErr {
+			t.Errorf("%s: error = %v, want error %t", result.ID, result.Err, wantErr)
+		}
+	}
+}
+
+func TestRunProgress(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		items       int
+		failing     map[string]bool
+		wantFailed  int
+	}{
+		{name: "sequential", concurrency: 1, items: 4, failing: map[string]bool{"i-2": true}, wantFailed: 1},
+		{name: "concurrent", concurrency: 4, items: 20, failing: map[string]bool{"i-1": true, "i-7": true, "i-19": true}, wantFailed: 3},
+		{name: "all succeed", concurrency: 2, items: 5, wantFailed: 0},
+		{name: "empty", concurrency: 2, items: 0, wantFailed: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := make([]string, tt.items)
+			for i := range ids {
+				ids[i] = fmt.Sprintf("i-%d", i)
+			}
+
+			var (
+				mutex  sync.Mutex
+				calls  int
+				final  [2]int
+				totals = map[int]bool{}
+			)
+			engine := newTestEngine(Options{Concurrency: tt.concurrency})
+			engine.Run(context.Background(), ids, func(ctx context.Context, id string) error {
+				if tt.failing[id] {
+					return errNotFound
+				}
+				return nil
+			}, func(done, failed, total int) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				calls++
+				totals[total] = true
+				if done == total {
+					final = [2]int{done, failed}
+				}
+			})
+
+			if calls != tt.items {
+				t.Errorf("progress called %d times, want %d", calls, tt.items)
+			}
+			if tt.items > 0 && (len(totals) != 1 || !totals[tt.items]) {
+				t.Errorf("progress totals = %v, want %d", totals, tt.items)
+			}
+			if tt.items > 0 && final != [2]int{tt.items, tt.wantFailed} {
+				t.Errorf("final progress = %v, want [%d %d]", final, tt.items, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ids := []string{"i-1", "i-2", "i-3", "i-4", "i-5"}
+	var (
+		mutex     sync.Mutex
+		processed []string
+		progress  [][3]int
+	)
+	engine := newTestEngine(Options{Concurrency: 1, MaxRetries: 3})
+	results := engine.Run(ctx, ids, func(ctx context.Context, id string) error {
+		processed = append(processed, id)
+		if id == "i-2" {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}, func(done, failed, total int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		progress = append(progress, [3]int{done, failed, total})
+	})
+
+	if want := []string{"i-1", "i-2"}; !reflect.DeepEqual(processed, want) {
+		t.Errorf("processed = %q, want %q", processed, want)
+	}
+	if len(results) != len(ids) {
+		t.Fatalf("Run() returned %d results, want %d", len(results), len(ids))
+	}
+	if results[0].Err != nil || results[0].Attempts != 1 {
+		t.Errorf("%s: error = %v, attempts = %d, want success in 1 attempt", ids[0], results[0].Err, results[0].Attempts)
+	}
+	// The cancelled item is not retried
+	if !errors.Is(results[1].Err, context.Canceled) || results[1].Attempts != 1 {
+		t.Errorf("%s: error = %v, attempts = %d, want cancelled after 1 attempt", ids[1], results[1].Err, results[1].Attempts)
+	}
+	for i, result := range results[2:] {
+		if result.ID != ids[i+2] {
+			t.Errorf("result %d ID = %q, want %q", i+2, result.ID, ids[i+2])
+		}
+		if !errors.Is(result.Err, context.Canceled) || result.Attempts != 0 {
+			t.Errorf("%s: error = %v, attempts = %d, want cancelled without attempt", result.ID, result.Err, result.Attempts)
+		}
+	}
+
+	// The items not started are reported as failed, the summary adds up to
+	// all the items
+	if len(progress) != len(ids) {
+		t.Fatalf("progress called %d times, want %d: %v", len(progress), len(ids), progress)
+	}
+	if want := [3]int{len(ids), len(ids) - 1, len(ids)}; !slices.Contains(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+}
+
+func TestRunCancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine := NewEngine(slog.New(slog.NewTextHandler(io.Discard, nil)), Options{Concurrency: 1, MaxRetries: 3, Backoff: time.Hour})
+	results := engine.Run(ctx, []string{"i-1"}, func(ctx context.Context, id string) error {
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return errThrottled
+	}, nil)
+
+	if !errors.Is(results[0].Err, context.Canceled) || results[0].Attempts != 1 {
+		t.Errorf("error = %v, attempts = %d, want cancelled after 1 attempt", results[0].Err, results[0].Attempts)
+	}
+}
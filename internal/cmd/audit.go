@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nlamirault/e2c/internal/output"
+	"github.com/nlamirault/e2c/pkg/audit"
+)
+
+// newAuditCommand creates the audit command, listing the mutating actions
+// recorded in the audit log
+func newAuditCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	var (
+		file     string
+		since    time.Duration
+		instance string
+		action   string
+		failed   bool
+		format   string
+		asJSON   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Review the actions recorded in the audit log",
+		Long: `Review the mutating actions taken with e2c (start, stop, reboot, terminate,
+credit specification changes, hook commands), recorded with the caller identity, the account,
+the region, the time and the result in the audit log:
+
+  e2c audit --since 24h --failed`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if asJSON {
+				format = output.FormatJSON
+			}
+			renderer, err := output.New(format, nil)
+			if err != nil {
+				return err
+			}
+
+			_, cfg, _, err := opts.setup(log)
+			if err != nil {
+				return err
+			}
+			defer opts.shutdown()
+
+			if file == "" {
+				file = audit.New(log, cfg.Audit.File, false).Path()
+			}
+
+			entries, err := audit.Read(file)
+			if err != nil {
+				return err
+			}
+
+			var from time.Time
+			if since > 0 {
+				from = time.Now().Add(-since)
+			}
+
+			selected := make([]audit.Entry, 0, len(entries))
+			for _, entry := range entries {
+				switch {
+				case entry.Time.Before(from):
+				case instance != "" && entry.Instance != instance:
+				case action != "" && entry.Action != action:
+				case failed && entry.Result != audit.ResultFailure:
+				default:
+					selected = append(selected, entry)
+				}
+			}
+
+			return renderer.Render(os.Stdout, auditResult(selected, cfg.UI.FormatTime))
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "audit file (default is the configured one)")
+	cmd.Flags().DurationVar(&since, "since", 0, "only list the actions of the given duration, e.g. 24h")
+	cmd.Flags().StringVar(&instance, "instance", "", "only list the actions on an instance")
+	cmd.Flags().StringVar(&action, "action", "", "only list an action, e.g. TerminateInstances")
+	cmd.Flags().BoolVar(&failed, "failed", false, "only list the failed actions")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the entries as JSON")
+	_ = cmd.Flags().MarkDeprecated("json", "use --output json instead")
+	output.AddFlag(cmd, &format, output.FormatTable, nil)
+
+	return cmd
+}
+
+// auditResult returns the output of the audit entries, their times formatted
+// with formatTime
+func auditResult(entries []audit.Entry, formatTime func(time.Time) string) *output.Result {
+	result := &output.Result{
+		Columns: []output.Column{
+			{Name: "Time"},
+			{Name: "User"},
+			{Name: "Account"},
+			{Name: "Region"},
+			{Name: "Profile", Wide: true},
+			{Name: "Action"},
+			{Name: "Instance"},
+			{Name: "Result"},
+			{Name: "Error"},
+			{Name: "Params", Wide: true},
+		},
+		Items: entries,
+	}
+	for _, entry := range entries {
+		result.Rows = append(result.Rows, []string{
+			formatTime(entry.Time),
+			entry.User,
+			entry.Account,
+			entry.Region,
+			entry.Profile,
+			entry.Action,
+			entry.Instance,
+			entry.Result,
+			entry.Error,
+			output.KeyValues(entry.Params),
+		})
+	}
+	return result
+}
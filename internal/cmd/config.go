@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/output"
+)
+
+// setting is a key of the configuration, with its value and its origin
+type setting struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  any    `json:"value" yaml:"value"`
+	Origin string `json:"origin,omitempty" yaml:"origin,omitempty"`
+}
+
+// newConfigCommand creates the config command
+func newConfigCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	cmd.AddCommand(newConfigShowCommand(log, opts))
+
+	return cmd
+}
+
+// newConfigShowCommand creates the config show command, displaying the
+// configuration merged from the defaults, the file, the environment and the
+// flags
+func newConfigShowCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	var (
+		format  string
+		origins bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the configuration in use",
+		Long: `Show the configuration in use, merged from, by increasing precedence: the
+defaults, the config file, the section of the AWS profile in the file, the
+environment variables, the context and the flags.
+
+--origins displays where each value comes from:
+
+  e2c config show --origins
+  E2C_AWS_PROFILE=prod e2c --region eu-west-1 config show --origins`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The configuration is written to stdout, the logs would
+			// interleave with it
+			cfg, err := config.Load(slog.New(slog.NewTextHandler(io.Discard, nil)), opts.configOptions())
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			log.Debug("Showing config", "origins", origins)
+
+			provenance := cfg.Provenance()
+			if format == output.FormatYAML && !origins {
+				// The configuration file equivalent to the one in use
+				return yaml.NewEncoder(os.Stdout).Encode(provenance.Settings())
+			}
+
+			renderer, err := output.New(format, nil)
+			if err != nil {
+				return err
+			}
+			return renderer.Render(os.Stdout, configResult(provenance, origins))
+		},
+	}
+
+	cmd.Flags().BoolVar(&origins, "origins", false, "show where each value comes from: default, file, profile, env, context, session or flag")
+	output.AddFlag(cmd, &format, output.FormatTable, nil)
+
+	return cmd
+}
+
+// configResult returns the output of the keys of the configuration
+func configResult(provenance *config.Provenance, origins bool) *output.Result {
+	result := &output.Result{
+		Columns: []output.Column{{Name: "Key"}, {Name: "Value"}},
+	}
+	if origins {
+		result.Columns = append(result.Columns, output.Column{Name: "Origin"})
+	}
+
+	var settings []setting
+	for _, key := range provenance.Keys() {
+		s := setting{Key: key, Value: provenance.Value(key)}
+		row := []string{key, config.FormatValue(s.Value)}
+		if origins {
+			s.Origin = provenance.Origin(key).String()
+			row = append(row, s.Origin)
+		}
+		settings = append(settings, s)
+		result.Rows = append(result.Rows, row)
+	}
+	result.Items = settings
+	return result
+}
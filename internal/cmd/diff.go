@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nlamirault/e2c/internal/output"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// inventoryNow is the inventory of the instances listed when the command
+// runs, rather than read from a file
+const inventoryNow = "now"
+
+// newDiffCommand creates the diff command, comparing two inventories of
+// instances
+func newDiffCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	var (
+		from    string
+		to      string
+		format  string
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare two inventories of the EC2 instances",
+		Long: `Compare two inventories of the EC2 instances, and report the instances
+added, removed, and those whose type, state or tags changed. An inventory is
+a JSON or YAML file written by e2c list or exported from the UI, or now for
+the instances of the region when the command runs:
+
+  e2c list -o json > before.json
+  e2c diff --from before.json --to now
+  e2c diff --from before.json --to after.yaml -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			renderer, err := output.New(format, nil)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+			defer opts.shutdown()
+
+			// The instances of the region are listed once, if needed
+			var current []model.Instance
+			inventory := func(source string) ([]model.Instance, error) {
+				if source != inventoryNow {
+					return output.ReadInstances(source)
+				}
+				if current != nil {
+					return current, nil
+				}
+				_, _, ec2Client, err := opts.setup(log)
+				if err != nil {
+					return nil, err
+				}
+				current, err = ec2Client.ListInstances(ctx, nil)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list instances: %w", err)
+				}
+				return current, nil
+			}
+
+			before, err := inventory(from)
+			if err != nil {
+				return err
+			}
+			after, err := inventory(to)
+			if err != nil {
+				return err
+			}
+
+			return renderer.Render(os.Stdout, output.Diff(model.DiffInstances(before, after)))
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "inventory before the changes: a .json or .yaml file, or now")
+	_ = cmd.MarkFlagRequired("from")
+	cmd.Flags().StringVar(&to, "to", inventoryNow, "inventory after the changes: a .json or .yaml file, or now")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "maximum duration of the AWS API calls")
+	output.AddFlag(cmd, &format, output.FormatTable, nil)
+
+	return cmd
+}
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/output"
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// redacted replaces the values of the sensitive environment variables
+const redacted = "<redacted>"
+
+// envVar is an environment variable honored by e2c, with its current value
+type envVar struct {
+	Name        string `json:"name" yaml:"name"`
+	Set         bool   `json:"set" yaml:"set"`
+	Value       string `json:"value,omitempty" yaml:"value,omitempty"` // Redacted if sensitive
+	Key         string `json:"key,omitempty" yaml:"key,omitempty"`     // Key of the configuration overridden, if any
+	Description string `json:"description" yaml:"description"`
+}
+
+// newEnvCommand creates the env command, listing the environment variables
+// honored by e2c and their values
+func newEnvCommand() *cobra.Command {
+	var (
+		format string
+		set    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "List the environment variables honored by e2c",
+		Long: `List the environment variables honored by e2c, their current value, the
+secrets being redacted, and the key of the configuration they override.
+
+Every key of the configuration with a scalar value can be overridden by an
+environment variable, e.g. E2C_AWS_PROFILE for aws.profile:
+
+  e2c env --set`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			renderer, err := output.New(format, nil)
+			if err != nil {
+				return err
+			}
+
+			var vars []envVar
+			for _, v := range config.EnvVars() {
+				vars = append(vars, lookupEnv(v.Name, v.Key, v.Description, false))
+			}
+			for _, v := range aws.EnvVars {
+				vars = append(vars, lookupEnv(v.Name, "", v.Description, v.Sensitive))
+			}
+
+			if set {
+				selected := vars[:0]
+				for _, v := range vars {
+					if v.Set {
+						selected = append(selected, v)
+					}
+				}
+				vars = selected
+			}
+
+			return renderer.Render(os.Stdout, envResult(vars))
+		},
+	}
+
+	cmd.Flags().BoolVar(&set, "set", false, "only list the variables set")
+	output.AddFlag(cmd, &format, output.FormatTable, nil)
+
+	return cmd
+}
+
+// lookupEnv returns an environment variable with its current value
+func lookupEnv(name, key, description string, sensitive bool) envVar {
+	value, set := os.LookupEnv(name)
+	if set && sensitive {
+		value = redacted
+	}
+	return envVar{
+		Name:        name,
+		Set:         set,
+		Value:       value,
+		Key:         key,
+		Description: description,
+	}
+}
+
+// envResult returns the output of the environment variables
+func envResult(vars []envVar) *output.Result {
+	result := &output.Result{
+		Columns: []output.Column{
+			{Name: "Name"},
+			{Name: "Value"},
+			{Name: "Key"},
+			{Name: "Description", Wide: true},
+		},
+		Items: vars,
+	}
+	for _, v := range vars {
+		value := v.Value
+		if !v.Set {
+			value = "-"
+		}
+		result.Rows = append(result.Rows, []string{v.Name, value, v.Key, v.Description})
+	}
+	return result
+}
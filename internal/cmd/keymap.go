@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/keymap"
+)
+
+// newKeymapCommand creates the keymap command, sharing the key bindings
+func newKeymapCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keymap",
+		Short: "Export or import the key bindings",
+		Long: `Export the key bindings to a keymap file, or import a keymap file shared by
+a team to replace the current one:
+
+  e2c keymap export --output team-keymap.yaml
+  e2c keymap import team-keymap.yaml`,
+	}
+
+	cmd.AddCommand(newKeymapExportCommand(log, opts))
+	cmd.AddCommand(newKeymapImportCommand(log, opts))
+
+	return cmd
+}
+
+// newKeymapExportCommand creates the keymap export command
+func newKeymapExportCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	var (
+		output    string
+		overrides bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the key bindings to a keymap file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keys, err := loadKeymap(log, opts)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				return keys.Export(os.Stdout, !overrides)
+			}
+
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", output, err)
+			}
+			defer f.Close()
+
+			if err := keys.Export(f, !overrides); err != nil {
+				return err
+			}
+			return f.Close()
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "keymap file to write (default is the standard output)")
+	cmd.Flags().BoolVar(&overrides, "overrides", false, "only export the bindings which differ from the defaults")
+
+	return cmd
+}
+
+// newKeymapImportCommand creates the keymap import command
+func newKeymapImportCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file>",
+		Short: "Replace the key bindings with the ones of a keymap file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(log, opts.configOptions())
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			path := cfg.UI.KeymapFile
+			if path == "" {
+				path = keymap.DefaultPath()
+			}
+
+			keys, err := keymap.Import(args[0], path)
+			if err != nil {
+				return err
+			}
+
+			overridden := 0
+			for _, binding := range keys.Bindings() {
+				if binding.Source == keymap.SourceUser {
+					overridden++
+				}
+			}
+			fmt.Printf("Keymap imported to %s (%d bindings overridden)\n", path, overridden)
+			return nil
+		},
+	}
+}
+
+// loadKeymap loads the keymap configured in ui.keymap_file
+func loadKeymap(log *slog.Logger, opts *globalOptions) (*keymap.Keymap, error) {
+	cfg, err := config.Load(log, opts.configOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return keymap.Load(cfg.UI.KeymapFile)
+}
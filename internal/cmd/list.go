@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nlamirault/e2c/internal/output"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// newListCommand creates the list command, listing the instances without
+// starting the UI
+func newListCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	var (
+		filter  string
+		format  string
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the EC2 instances",
+		Long: `List the EC2 instances of a region without starting the UI, filtered with
+the expressions of the filter of the UI:
+
+  e2c list --filter "state:running tag:Team=payments" --output wide
+  e2c list --output go-template='{{range .}}{{.ID}} {{.PrivateIP}}{{"\n"}}{{end}}'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			renderer, err := output.New(format, nil)
+			if err != nil {
+				return err
+			}
+
+			_, cfg, ec2Client, err := opts.setup(log)
+			if err != nil {
+				return err
+			}
+			defer opts.shutdown()
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			expr := model.ParseFilter(filter)
+			instances, err := ec2Client.ListInstances(ctx, expr.Server)
+			if err != nil {
+				return fmt.Errorf("failed to list instances: %w", err)
+			}
+
+			selected := make([]model.Instance, 0, len(instances))
+			for _, instance := range instances {
+				if matchesText(instance, expr.Text) && expr.MatchesFlags(instance) {
+					selected = append(selected, instance)
+				}
+			}
+
+			return renderer.Render(os.Stdout, output.Instances(selected, cfg.UI.TagColumns, cfg.UI.FormatTime))
+		},
+	}
+
+	cmd.Flags().StringVarP(&filter, "filter", "f", "", "filter expression, e.g. \"state:running web\"")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "maximum duration of the AWS API calls")
+	output.AddFlag(cmd, &format, output.FormatTable, nil)
+
+	return cmd
+}
+
+// matchesText returns true if the free text of a filter is part of the ID,
+// the name, the type, the state or an IP address of an instance
+func matchesText(instance model.Instance, text string) bool {
+	text = strings.ToLower(text)
+	for _, value := range []string{instance.ID, instance.Name, instance.Type, instance.State, instance.PrivateIP, instance.PublicIP} {
+		if strings.Contains(strings.ToLower(value), text) {
+			return true
+		}
+	}
+	return false
+}
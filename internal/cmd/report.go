@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/output"
+	"github.com/nlamirault/e2c/internal/report"
+)
+
+// reportRenderers returns the formats specific to the report, as a document
+// whose dates are formatted with formatTime
+func reportRenderers(formatTime func(time.Time) string) map[string]output.Renderer {
+	return map[string]output.Renderer{
+		report.FormatMarkdown: reportRenderer(report.FormatMarkdown, formatTime),
+		"markdown":            reportRenderer(report.FormatMarkdown, formatTime),
+		report.FormatHTML:     reportRenderer(report.FormatHTML, formatTime),
+	}
+}
+
+// reportRenderer returns the renderer of the report as a document
+func reportRenderer(format string, formatTime func(time.Time) string) output.Renderer {
+	return output.RendererFunc(func(w io.Writer, result *output.Result) error {
+		return report.Render(w, result.Items.(*report.Report), format, formatTime)
+	})
+}
+
+// newReportCommand creates the report command, generating a fleet report
+// without starting the UI
+func newReportCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	var (
+		format       string
+		file         string
+		requiredTags []string
+		timeout      time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Generate a report of the EC2 fleet",
+		Long: `Generate a report of the EC2 fleet of a region: instance counts, cost
+estimate, oldest instances, untagged instances and security findings.
+
+The report is written as Markdown or HTML, to be posted to a wiki or sent by
+email, or in the formats shared by the commands. It runs without the UI, for
+instance from a cron job:
+
+  e2c report --output html --file /var/www/fleet.html`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log, cfg, ec2Client, err := opts.setup(log)
+			if err != nil {
+				return err
+			}
+			defer opts.shutdown()
+
+			renderer, err := output.New(format, reportRenderers(cfg.UI.FormatTime))
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			instances, err := ec2Client.ListInstances(ctx, nil)
+			if err != nil {
+				return fmt.Errorf("failed to list instances: %w", err)
+			}
+
+			// Require the Name tag and the tag columns unless tags are given
+			if !cmd.Flags().Changed("required-tags") {
+				requiredTags = append([]string{"Name"}, cfg.UI.TagColumns...)
+			}
+
+			r := report.Build(instances, ec2Client.GetRegion(), ec2Client.GetProfile(), requiredTags, time.Now())
+
+			var w io.Writer = os.Stdout
+			if file != "" {
+				f, err := os.Create(file)
+				if err != nil {
+					return fmt.Errorf("failed to create report file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			if err := renderer.Render(w, reportResult(r)); err != nil {
+				return fmt.Errorf("failed to render report: %w", err)
+			}
+
+			log.Info("Report generated", "format", format, "instances", r.Total, "file", file)
+			return nil
+		},
+	}
+
+	output.AddFlag(cmd, &format, report.FormatMarkdown, reportRenderers(config.UIConfig{}.FormatTime))
+	cmd.Flags().StringVar(&format, "format", report.FormatMarkdown, "report format")
+	_ = cmd.Flags().MarkDeprecated("format", "use --output instead")
+	cmd.Flags().StringVar(&file, "file", "", "write the report to a file instead of stdout")
+	cmd.Flags().StringSliceVar(&requiredTags, "required-tags", nil, "tags every instance must have (default is Name and the configured tag columns)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "maximum duration of the AWS API calls")
+
+	return cmd
+}
+
+// reportResult returns the output of a report: the counts of its sections in
+// the table formats, the whole report in the others
+func reportResult(r *report.Report) *output.Result {
+	result := &output.Result{
+		Columns: []output.Column{
+			{Name: "Section"},
+			{Name: "Value"},
+			{Name: "Count"},
+		},
+		Items: r,
+	}
+	add := func(section, value string, count int) {
+		result.Rows = append(result.Rows, []string{section, value, strconv.Itoa(count)})
+	}
+
+	add("total", "", r.Total)
+	for _, count := range r.ByState {
+		add("state", count.Value, count.Count)
+	}
+	for _, count := range r.ByType {
+		add("type", count.Value, count.Count)
+	}
+	add("untagged", "", len(r.Untagged))
+	severities := make(map[string]int)
+	for _, finding := range r.Findings {
+		severities[finding.Severity]++
+	}
+	for _, severity := range []string{report.SeverityHigh, report.SeverityMedium, report.SeverityLow} {
+		add("findings", severity, severities[severity])
+	}
+	return result
+}
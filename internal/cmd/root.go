@@ -4,28 +4,136 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/exec"
+	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/nlamirault/e2c/internal/aws"
 	"github.com/nlamirault/e2c/internal/config"
 	"github.com/nlamirault/e2c/internal/logger"
+	"github.com/nlamirault/e2c/internal/session"
+	"github.com/nlamirault/e2c/internal/trace"
 	"github.com/nlamirault/e2c/internal/ui"
 	"github.com/nlamirault/e2c/internal/version"
+	"github.com/nlamirault/e2c/pkg/audit"
+	"github.com/nlamirault/e2c/pkg/aws"
 )
 
+// globalOptions holds the flags shared by all the commands
+type globalOptions struct {
+	cfgFile string
+	context string
+	profile string
+	region  string
+	// Regions of the previous sessions by profile, used without --region
+	// or context
+	savedRegions map[string]string
+	logFormat    string
+	logLevel     string
+	// Endpoint of the OpenTelemetry collector, enabling the telemetry
+	otelEndpoint string
+	// Duration of the loading of the configuration by setup
+	configLoad time.Duration
+	// Exporter of the telemetry recording the calls of the EC2 client
+	// created by setup, nil if the telemetry is disabled
+	exporter *trace.Exporter
+}
+
+// configOptions returns the options of the configuration set by the flags
+func (o *globalOptions) configOptions() config.Options {
+	return config.Options{
+		Path:              o.cfgFile,
+		Context:           o.context,
+		Profile:           o.profile,
+		Region:            o.region,
+		SessionRegions:    o.savedRegions,
+		TelemetryEndpoint: o.otelEndpoint,
+	}
+}
+
+// setup configures the logger from the flags, loads the configuration and
+// creates the EC2 client
+func (o *globalOptions) setup(log *slog.Logger) (*slog.Logger, *config.Config, *aws.EC2Client, error) {
+	// Configure logging if requested via flags
+	if o.logFormat != "" || o.logLevel != "" {
+		logConfig := logger.NewConfig()
+
+		// Set format if specified
+		if o.logFormat != "" {
+			logConfig.Format = logger.ParseFormat(o.logFormat)
+		}
+
+		// Set level if specified
+		if o.logLevel != "" {
+			logConfig.Level = logger.ParseLevel(o.logLevel)
+		}
+
+		// Create and set the new logger
+		log = logger.New(logConfig)
+		logger.SetAsDefault(log)
+	}
+
+	// Load configuration, overridden by the CLI flags
+	start := time.Now()
+	cfg, err := config.Load(log, o.configOptions())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	o.configLoad = time.Since(start)
+	log.Info("Startup phase completed", "phase", "Load configuration", "duration", o.configLoad)
+
+	// Set the levels of the subsystems
+	if len(cfg.Logging.Levels) > 0 {
+		levels := make(map[string]logger.Level, len(cfg.Logging.Levels))
+		for name, level := range cfg.Logging.Levels {
+			levels[name] = logger.ParseLevel(level)
+		}
+		if err := logger.SetLevels(log, levels); err != nil {
+			log.Warn("Failed to set the logging levels of the subsystems", "error", err)
+		}
+	}
+
+	// Create AWS EC2 client, recording its calls for the telemetry
+	start = time.Now()
+	o.exporter = ui.NewExporter(log, cfg.Telemetry)
+	ec2Client, err := aws.NewEC2Client(logger.Subsystem(log, logger.SubsystemAWS), cfg.AWS.DefaultRegion, cfg.AWS.Profile,
+		aws.AssumeRole(cfg.AWS.AssumeRole), aws.CallOptions(cfg.AWS.Calls), ui.Telemetry(o.exporter))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create EC2 client: %w", err)
+	}
+	log.Info("Startup phase completed", "phase", "Create EC2 client", "duration", time.Since(start))
+
+	// Record the mutating actions
+	if cfg.Audit.Enabled {
+		ec2Client.SetAuditLog(audit.New(logger.Subsystem(log, logger.SubsystemAudit), cfg.Audit.File, cfg.Audit.StructuredLogs))
+	}
+
+	return log, cfg, ec2Client, nil
+}
+
+// shutdown sends the last traces and metrics of a command which created the
+// EC2 client with setup, waiting for the collector at most 2 seconds. The
+// UI sends its own once stopped.
+func (o *globalOptions) shutdown() {
+	if o.exporter == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	o.exporter.Shutdown(ctx)
+	o.exporter = nil
+}
+
 // NewRootCommand creates the root command for e2c
 func NewRootCommand(log *slog.Logger) *cobra.Command {
-	var (
-		cfgFile   string
-		profile   string
-		region    string
-		logFormat string
-		logLevel  string
-	)
+	opts := &globalOptions{}
+	var clean bool
+	var filter string
 
 	cmd := &cobra.Command{
 		Use:   "e2c",
@@ -36,64 +144,121 @@ inspired by k9s for Kubernetes and e1s for ECS.
 It provides a simple, intuitive interface for managing EC2 instances
 across multiple regions.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Configure logging if requested via flags
-			if logFormat != "" || logLevel != "" {
-				logConfig := logger.NewConfig()
-
-				// Set format if specified
-				if logFormat != "" {
-					logConfig.Format = logger.ParseFormat(logFormat)
-				}
+			started := time.Now()
 
-				// Set level if specified
-				if logLevel != "" {
-					logConfig.Level = logger.ParseLevel(logLevel)
+			// Restore the state of the previous session of the profile, the
+			// region flag and the context taking precedence over the saved
+			// region
+			saved := &session.File{}
+			if !clean {
+				var err error
+				if saved, err = session.Load(session.DefaultPath()); err != nil {
+					log.Warn("Failed to load the previous session, starting clean", "error", err)
+					saved = &session.File{}
 				}
-
-				// Create and set the new logger
-				log = logger.New(logConfig)
-				logger.SetAsDefault(log)
-			}
-
-			// Load configuration
-			cfg, err := config.LoadConfig(log)
-			if err != nil {
-				return fmt.Errorf("failed to load config: %w", err)
+				opts.savedRegions = saved.Regions()
 			}
 
-			// Override with CLI flags
-			cfg.Override(profile, region)
-
-			// Create AWS EC2 client
-			ec2Client, err := aws.NewEC2Client(log, cfg.AWS.DefaultRegion, cfg.AWS.Profile)
+			log, cfg, ec2Client, err := opts.setup(log)
 			if err != nil {
-				return fmt.Errorf("failed to create EC2 client: %w", err)
+				return err
 			}
+			state := saved.State(cfg.AWS.Profile)
 
 			// Create and start UI
-			app := ui.NewUI(log, ec2Client, cfg)
+			app := ui.NewUI(log, ec2Client, cfg, opts.exporter)
+			app.SetStartTime(started)
+			app.SetConfigLoadDuration(opts.configLoad)
+			if filter != "" {
+				// Scoped to the filter given, rather than the one of the
+				// previous session
+				state.Instances.Filter = ""
+				app.SetLockedFilter(filter)
+			}
+			app.RestoreSession(state)
 			if err := app.Start(); err != nil {
 				return fmt.Errorf("UI error: %w", err)
 			}
 
+			profile, state := app.Session()
+			if err := session.Save(session.DefaultPath(), profile, state); err != nil {
+				log.Error("Failed to save the session", "error", err)
+			}
+
+			// Run again under the external credentials process if requested
+			if command, env := app.Reexec(); command != nil {
+				return reexec(log, command, env)
+			}
+
 			return nil
 		},
 		Version: version.GetVersion(),
 	}
 
 	// Add flags
-	cmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/e2c/config.yaml)")
-	cmd.PersistentFlags().StringVar(&profile, "profile", "", "AWS profile to use")
-	cmd.PersistentFlags().StringVar(&region, "region", "", "AWS region to use")
-	cmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "set log format (json, text)")
-	cmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "set logging level (debug, info, warn, error)")
+	cmd.Flags().BoolVar(&clean, "clean", false, "start with the default filter, sort and view instead of restoring the previous session")
+	cmd.Flags().StringVar(&filter, "filter", "", `open the UI scoped to the instances matching a filter expression, locked until cleared with U (e.g. "tag:service=api state:running")`)
+	cmd.Flags().StringVar(&opts.otelEndpoint, "otel-endpoint", "", "export the traces and the metrics to the OpenTelemetry collector at this OTLP/HTTP endpoint (e.g. http://localhost:4318)")
+	cmd.PersistentFlags().StringVar(&opts.cfgFile, "config", "", "config file (default is $E2C_CONFIG, or $HOME/.config/e2c/config.yaml)")
+	cmd.PersistentFlags().StringVar(&opts.context, "context", "", "context of the config file to use (profile, region, read-only, columns)")
+	cmd.PersistentFlags().StringVar(&opts.profile, "profile", "", "AWS profile to use")
+	cmd.PersistentFlags().StringVar(&opts.region, "region", "", "AWS region to use")
+	cmd.PersistentFlags().StringVar(&opts.logFormat, "log-format", "", "set log format (json, text)")
+	cmd.PersistentFlags().StringVar(&opts.logLevel, "log-level", "", "set logging level (debug, info, warn, error)")
 
 	// Add version command
 	cmd.AddCommand(newVersionCommand())
 
+	// Add list command
+	cmd.AddCommand(newListCommand(log, opts))
+
+	// Add report command
+	cmd.AddCommand(newReportCommand(log, opts))
+
+	// Add diff command
+	cmd.AddCommand(newDiffCommand(log, opts))
+
+	// Add audit command
+	cmd.AddCommand(newAuditCommand(log, opts))
+
+	// Add env command
+	cmd.AddCommand(newEnvCommand())
+
+	// Add config command
+	cmd.AddCommand(newConfigCommand(log, opts))
+
+	// Add schedule command
+	cmd.AddCommand(newScheduleCommand(log, opts))
+
+	// Add keymap command
+	cmd.AddCommand(newKeymapCommand(log, opts))
+
+	// Add selftest command
+	cmd.AddCommand(newSelftestCommand())
+
 	return cmd
 }
 
+// reexec runs a command attached to the terminal, e.g. e2c under aws-vault
+// exec once the credentials have expired, and exits with its status
+func reexec(log *slog.Logger, command []string, env []string) error {
+	log.Info("Running command", "command", command[0])
+
+	c := exec.Command(command[0], command[1:]...)
+	c.Env = env
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run %s: %w", command[0], err)
+	}
+	return nil
+}
+
 // newVersionCommand creates a version command
 func newVersionCommand() *cobra.Command {
 	return &cobra.Command{
@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/logger"
+	"github.com/nlamirault/e2c/internal/output"
+	"github.com/nlamirault/e2c/internal/schedule"
+	"github.com/nlamirault/e2c/internal/ui"
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// scheduleItem is a schedule and its next stop
+type scheduleItem struct {
+	Name     string    `json:"name" yaml:"name"`
+	Tag      string    `json:"tag" yaml:"tag"`
+	Stop     string    `json:"stop" yaml:"stop"`
+	Profile  string    `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Region   string    `json:"region" yaml:"region"`
+	Mode     string    `json:"mode" yaml:"mode"`
+	NextStop time.Time `json:"nextStop" yaml:"nextStop"`
+}
+
+// newScheduleCommand creates the schedule command
+func newScheduleCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Manage the automatic stops of the instances",
+	}
+
+	cmd.AddCommand(newScheduleListCommand(log, opts))
+	cmd.AddCommand(newScheduleApplyCommand(log, opts))
+
+	return cmd
+}
+
+// newScheduleListCommand creates the schedule list command
+func newScheduleListCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the schedules and their next stop",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			renderer, err := output.New(format, nil)
+			if err != nil {
+				return err
+			}
+
+			// The schedules are written to stdout, the logs would
+			// interleave with them
+			quiet := slog.New(slog.NewTextHandler(io.Discard, nil))
+			cfg, err := config.Load(quiet, opts.configOptions())
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			result := &output.Result{
+				Columns: []output.Column{{Name: "Name"}, {Name: "Tag"}, {Name: "Stop"}, {Name: "Target"}, {Name: "Mode"}, {Name: "Next Stop"}},
+			}
+			var items []scheduleItem
+			now := time.Now()
+			schedules := schedule.NewSchedules(log, cfg.Schedules)
+			schedule.SetDefaults(schedules, cfg.AWS.Profile, cfg.AWS.DefaultRegion)
+			for _, s := range schedules {
+				item := scheduleItem{
+					Name:     s.Name,
+					Tag:      s.Selector(),
+					Stop:     s.String(),
+					Profile:  s.Profile,
+					Region:   s.Region,
+					Mode:     s.Mode,
+					NextStop: s.Next(now),
+				}
+				items = append(items, item)
+				result.Rows = append(result.Rows, []string{item.Name, item.Tag, item.Stop, s.Target(), item.Mode, cfg.UI.FormatTime(item.NextStop)})
+			}
+			result.Items = items
+
+			return renderer.Render(os.Stdout, result)
+		},
+	}
+
+	output.AddFlag(cmd, &format, output.FormatTable, nil)
+
+	return cmd
+}
+
+// newScheduleApplyCommand creates the schedule apply command, creating the
+// EventBridge Scheduler schedules
+func newScheduleApplyCommand(log *slog.Logger, opts *globalOptions) *cobra.Command {
+	var (
+		dryRun  bool
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Create the schedules of the eventbridge mode in EventBridge Scheduler",
+		Long: `Create or update, in EventBridge Scheduler, the schedules whose mode is
+eventbridge. Each schedule stops the instances having its tag when it is
+applied: apply again once instances are added or removed. A schedule is
+created in its profile and region, or in the ones of the command if it has
+none.
+
+  e2c schedule apply --dry-run
+  e2c --profile prod schedule apply`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, cfg, ec2Client, err := opts.setup(log)
+			if err != nil {
+				return err
+			}
+			defer opts.shutdown()
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			// Each schedule is created in its profile and region
+			schedules := schedule.NewSchedules(log, cfg.Schedules)
+			schedule.SetDefaults(schedules, cfg.AWS.Profile, ec2Client.GetRegion())
+			for _, s := range schedules {
+				if s.Mode != schedule.ModeEventBridge {
+					continue
+				}
+				client := ec2Client
+				if s.Profile != cfg.AWS.Profile || s.Region != ec2Client.GetRegion() {
+					if client, err = newScheduleClient(log, cfg, s, opts); err != nil {
+						return err
+					}
+					client.SetAuditLog(ec2Client.AuditLog())
+				}
+				if err := applySchedule(ctx, client, s, dryRun); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the schedules without creating them")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "maximum duration of the AWS API calls")
+
+	return cmd
+}
+
+// newScheduleClient creates the EC2 client of the profile and the region of
+// a schedule. The profile of the configuration keeps its assumed role, the
+// other profiles are used as they are.
+func newScheduleClient(log *slog.Logger, cfg *config.Config, s *schedule.Schedule, opts *globalOptions) (*aws.EC2Client, error) {
+	var role aws.AssumeRole
+	if s.Profile == cfg.AWS.Profile {
+		role = aws.AssumeRole(cfg.AWS.AssumeRole)
+	}
+	client, err := aws.NewEC2Client(logger.Subsystem(log, logger.SubsystemAWS), s.Region, s.Profile, role,
+		aws.CallOptions(cfg.AWS.Calls), ui.Telemetry(opts.exporter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the EC2 client of schedule %s: %w", s.Name, err)
+	}
+	return client, nil
+}
+
+// applySchedule creates the EventBridge Scheduler schedule stopping the
+// instances with the tag of a schedule
+func applySchedule(ctx context.Context, ec2Client *aws.EC2Client, s *schedule.Schedule, dryRun bool) error {
+	// The stopped instances are also selected, to be stopped once started
+	instances, err := ec2Client.ListInstances(ctx, map[string][]string{
+		"tag:" + s.TagKey:     {s.TagValue},
+		"instance-state-name": {"pending", "running", "stopping", "stopped"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list instances of schedule %s: %w", s.Name, err)
+	}
+	if len(instances) == 0 {
+		fmt.Printf("%s: no instance with the tag %s, skipped\n", s.Name, s.Selector())
+		return nil
+	}
+
+	ids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		ids = append(ids, instance.ID)
+	}
+
+	fmt.Printf("%s: %s %s stopping %d instances in %s\n", s.Name, s.Expression(), s.Location, len(ids), s.Target())
+	if dryRun {
+		return nil
+	}
+	return ec2Client.PutStopSchedule(ctx, aws.StopSchedule{
+		Name:        "e2c-" + s.Name,
+		Expression:  s.Expression(),
+		Timezone:    s.Location.String(),
+		RoleARN:     s.RoleARN,
+		InstanceIDs: ids,
+		Description: fmt.Sprintf("Stop the instances with the tag %s, created by e2c", s.Selector()),
+	})
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nlamirault/e2c/internal/selftest"
+)
+
+// newSelftestCommand creates the selftest command, running the UI
+// headlessly against a fake backend
+func newSelftestCommand() *cobra.Command {
+	var verbose bool
+
+	cmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "Run a smoke test of the UI against a fake backend",
+		Long: `Run the UI headlessly on a simulated terminal against an in-memory EC2 backend,
+and exercise the main flows: loading and refreshing the instances, filtering,
+opening the details, stopping and starting an instance.
+
+No AWS credentials are needed and no request is sent to AWS. The command exits
+with a non-zero status if a flow fails, e.g. to check a package in CI.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			if verbose {
+				log = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			}
+
+			cmd.SilenceUsage = true
+			return selftest.Run(cmd.Context(), log, os.Stdout)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "write the logs of the UI to the standard error")
+
+	return cmd
+}
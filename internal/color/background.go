@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package color
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/term"
+)
+
+// errNoAnswer is returned when the terminal does not report its background
+var errNoAnswer = errors.New("the terminal does not report its background color")
+
+// QueryBackground asks the terminal for its background color with the OSC 11
+// sequence, before the UI takes over the terminal. The Device Attributes
+// query sent after it is answered by all the terminals, so that the ones not
+// supporting OSC 11 are not waited for until the timeout.
+func QueryBackground(timeout time.Duration) (tcell.Color, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return tcell.ColorDefault, err
+	}
+	defer tty.Close()
+
+	state, err := term.MakeRaw(int(tty.Fd()))
+	if err != nil {
+		return tcell.ColorDefault, err
+	}
+	defer func() { _ = term.Restore(int(tty.Fd()), state) }()
+
+	if err := tty.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return tcell.ColorDefault, err
+	}
+	if _, err := tty.WriteString("\x1b]11;?\x1b\\\x1b[c"); err != nil {
+		return tcell.ColorDefault, err
+	}
+
+	var answer []byte
+	buf := make([]byte, 256)
+	for {
+		n, err := tty.Read(buf)
+		answer = append(answer, buf[:n]...)
+		if err != nil {
+			break
+		}
+		// The answer to the Device Attributes query ends the answers
+		if i := bytes.Index(answer, []byte("\x1b[?")); i >= 0 && bytes.IndexByte(answer[i:], 'c') >= 0 {
+			break
+		}
+	}
+
+	return parseBackground(string(answer))
+}
+
+// parseBackground parses the answer to the OSC 11 query, e.g.
+// ESC ] 11 ; rgb:2e2e/3434/4040 ESC \
+func parseBackground(answer string) (tcell.Color, error) {
+	_, value, found := strings.Cut(answer, "\x1b]11;rgb:")
+	if !found {
+		return tcell.ColorDefault, errNoAnswer
+	}
+	if end := strings.IndexAny(value, "\x1b\x07"); end >= 0 {
+		value = value[:end]
+	}
+
+	components := strings.Split(value, "/")
+	if len(components) != 3 {
+		return tcell.ColorDefault, fmt.Errorf("invalid background color %q", value)
+	}
+	var rgb [3]int32
+	for i, component := range components {
+		// Each component has 1 to 4 hexadecimal digits
+		parsed, err := strconv.ParseUint(component, 16, 16)
+		if err != nil || len(component) == 0 || len(component) > 4 {
+			return tcell.ColorDefault, fmt.Errorf("invalid background color %q", value)
+		}
+		rgb[i] = int32(parsed * 255 / (1<<(4*len(component)) - 1))
+	}
+
+	return tcell.NewRGBColor(rgb[0], rgb[1], rgb[2]), nil
+}
+
+// ThemeOf returns the theme matching a background color: light if the
+// background is bright
+func ThemeOf(background tcell.Color) string {
+	r, g, b := background.RGB()
+	luminance := 0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)
+	if luminance > 127.5 {
+		return ThemeLight
+	}
+	return ThemeDark
+}
@@ -4,8 +4,15 @@
 package color
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
 )
 
 // Colors represents the colors used in the application
@@ -32,8 +39,8 @@ type Colors struct {
 	Secondary tcell.Color
 }
 
-// Global color scheme that uses Nord theme colors
-var AppColors = Colors{
+// Nord is the default color scheme, using the Nord theme colors
+var Nord = Colors{
 	Background: tcell.GetColor("#2E3440"), // Primary background
 	Foreground: tcell.GetColor("#D8DEE9"), // Primary foreground
 	Border:     tcell.GetColor("#81A1C1"), // Normal blue
@@ -49,6 +56,137 @@ var AppColors = Colors{
 	Secondary:  tcell.GetColor("#81A1C1"), // Normal blue
 }
 
+// NordLight is the default color scheme of the light theme, using the Nord
+// Snow Storm colors as background
+var NordLight = Colors{
+	Background: tcell.GetColor("#ECEFF4"), // Snow storm
+	Foreground: tcell.GetColor("#2E3440"), // Polar night
+	Border:     tcell.GetColor("#5E81AC"), // Dark blue
+	Title:      tcell.GetColor("#5E81AC"), // Dark blue
+	Selected:   tcell.GetColor("#D8DEE9"), // Darkest snow storm
+	HeaderFg:   tcell.GetColor("#2E3440"), // Polar night
+	HeaderBg:   tcell.GetColor("#D8DEE9"), // Darkest snow storm
+	Running:    tcell.GetColor("#4F7A3A"), // Darker green
+	Stopped:    tcell.GetColor("#BF616A"), // Normal red
+	Pending:    tcell.GetColor("#B5781F"), // Darker yellow
+	Error:      tcell.GetColor("#BF616A"), // Normal red
+	Highlight:  tcell.GetColor("#D08770"), // Orange
+	Secondary:  tcell.GetColor("#5E81AC"), // Dark blue
+}
+
+// AppColors is the color scheme of the application, Nord unless a skin is loaded
+var AppColors = Nord
+
+// Themes of the application
+const (
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+)
+
+// Palette holds the color schemes of the dark and the light themes
+type Palette struct {
+	Dark  Colors
+	Light Colors
+}
+
+// DefaultPalette is the palette without skin
+var DefaultPalette = Palette{Dark: Nord, Light: NordLight}
+
+// Colors returns the color scheme of a theme, the dark one if unknown
+func (p Palette) Colors(theme string) Colors {
+	if theme == ThemeLight {
+		return p.Light
+	}
+	return p.Dark
+}
+
+// Skin is the content of a skin file: the colors to change, by name, as
+// #RRGGBB values or W3C color names, for the dark theme and for the light
+// theme. The other colors are the Nord ones.
+type Skin struct {
+	Colors map[string]string `yaml:"colors"`
+	Light  map[string]string `yaml:"light"`
+}
+
+// fields returns the colors of a scheme by name in a skin file
+func (c *Colors) fields() map[string]*tcell.Color {
+	return map[string]*tcell.Color{
+		"background": &c.Background,
+		"foreground": &c.Foreground,
+		"border":     &c.Border,
+		"title":      &c.Title,
+		"selected":   &c.Selected,
+		"header_fg":  &c.HeaderFg,
+		"header_bg":  &c.HeaderBg,
+		"running":    &c.Running,
+		"stopped":    &c.Stopped,
+		"pending":    &c.Pending,
+		"error":      &c.Error,
+		"highlight":  &c.Highlight,
+		"secondary":  &c.Secondary,
+	}
+}
+
+// DefaultSkinPath returns the default path of the skin file,
+// ~/.config/e2c/skin.yaml
+func DefaultSkinPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "skin.yaml"
+	}
+	return filepath.Join(home, ".config", "e2c", "skin.yaml")
+}
+
+// LoadSkin reads a skin file and returns the Nord palette with the colors of
+// the skin applied
+func LoadSkin(path string) (Palette, error) {
+	palette := DefaultPalette
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return palette, err
+	}
+
+	var skin Skin
+	if err := yaml.Unmarshal(data, &skin); err != nil {
+		return palette, fmt.Errorf("failed to parse skin %s: %w", path, err)
+	}
+
+	errs := palette.Dark.set(skin.Colors)
+	errs = append(errs, palette.Light.set(skin.Light)...)
+	if len(errs) > 0 {
+		return DefaultPalette, fmt.Errorf("invalid skin %s: %w", path, errors.Join(errs...))
+	}
+
+	return palette, nil
+}
+
+// set changes the colors of a scheme, by name in a skin file
+func (c *Colors) set(values map[string]string) []error {
+	fields := c.fields()
+	var errs []error
+	for name, value := range values {
+		field, ok := fields[strings.ToLower(name)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown color %q", name))
+			continue
+		}
+		parsed := tcell.GetColor(value)
+		if parsed == tcell.ColorDefault {
+			errs = append(errs, fmt.Errorf("invalid value %q of color %s", value, name))
+			continue
+		}
+		*field = parsed
+	}
+	return errs
+}
+
+// Apply makes the given colors the colors of the application
+func Apply(colors Colors) {
+	AppColors = colors
+	InitializeColors()
+}
+
 // InitializeColors applies the application colors to tview components
 func InitializeColors() {
 	// Apply colors to tview global styles
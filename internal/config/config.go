@@ -2,9 +2,9 @@ package config
 
 import (
 	"fmt"
-	"log/slog"
-	"os"
-	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -12,8 +12,40 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	AWS AWSConfig `mapstructure:"aws"`
-	UI  UIConfig  `mapstructure:"ui"`
+	AWS       AWSConfig       `mapstructure:"aws"`
+	UI        UIConfig        `mapstructure:"ui"`
+	Terraform TerraformConfig `mapstructure:"terraform"`
+	Batch     BatchConfig     `mapstructure:"batch"`
+	Plugins   PluginsConfig   `mapstructure:"plugins"`
+	Logs      LogsConfig      `mapstructure:"logs"`
+	Audit     AuditConfig     `mapstructure:"audit"`
+	Telemetry TelemetryConfig `mapstructure:"telemetry"`
+	Watch     WatchConfig     `mapstructure:"watch"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	// Context is the name of the context in use, the default one when set
+	// in the configuration file
+	Context  string                   `mapstructure:"context"`
+	Contexts map[string]ContextConfig `mapstructure:"contexts"`
+	// Accounts are listed together, each with its profile or role, instead
+	// of the account of aws.profile
+	Accounts []AccountConfig `mapstructure:"accounts"`
+	// Schedules stop the instances selected by a tag at a time of the day
+	Schedules []ScheduleConfig `mapstructure:"schedules"`
+	// FeatureFlags roll the features out gradually, by name
+	FeatureFlags map[string]FlagConfig `mapstructure:"feature_flags"`
+
+	// provenance holds the origin of the values, nil if unknown
+	provenance *Provenance
+}
+
+// AccountConfig holds an AWS account of the aggregated instance list
+type AccountConfig struct {
+	Name string `mapstructure:"name"`
+	// Profile defaults to aws.profile
+	Profile string `mapstructure:"profile"`
+	// The role of the account assumed with the credentials of the profile,
+	// if any
+	AssumeRole AssumeRoleConfig `mapstructure:",squash"`
 }
 
 // AWSConfig holds AWS-specific configuration
@@ -21,69 +53,451 @@ type AWSConfig struct {
 	DefaultRegion   string        `mapstructure:"default_region"`
 	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
 	Profile         string        `mapstructure:"profile"`
+	// AssumeRole is the IAM role assumed with the credentials of the profile
+	AssumeRole AssumeRoleConfig `mapstructure:"assume_role"`
+	// Calls holds the endpoint, retries and rate limits of the calls to AWS
+	Calls CallsConfig `mapstructure:",squash"`
+	// BackgroundRegions is the number of regions displayed before whose
+	// instances are kept refreshed in the background, 0 to disable it
+	BackgroundRegions int `mapstructure:"background_regions"`
+}
+
+// CallsConfig holds the endpoint, retries and rate limits of the calls to AWS
+type CallsConfig struct {
+	// EndpointURL replaces the endpoints of all the AWS services, e.g.
+	// http://localhost:4566 to call LocalStack, empty for the AWS ones
+	EndpointURL string `mapstructure:"endpoint_url"`
+	// RetryMode is the retry mode of the SDK: standard, or adaptive which
+	// also slows down the calls once throttled. Defaults to the one of the
+	// profile, standard if not set.
+	RetryMode string `mapstructure:"retry_mode"`
+	// MaxAttempts is the number of attempts of a call, retries included.
+	// Defaults to the one of the profile, 3 if not set.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// APITimeout bounds each call, retries included, 0 for no timeout
+	APITimeout time.Duration `mapstructure:"api_timeout"`
+	// AttributeRate is the maximum number of DescribeInstanceAttribute
+	// calls per second, made to retrieve the protections, 0 for no limit
+	AttributeRate float64 `mapstructure:"attribute_rate"`
+}
+
+// AssumeRoleConfig holds the IAM role to assume, disabled if the ARN is empty
+type AssumeRoleConfig struct {
+	RoleARN    string `mapstructure:"role_arn"`
+	ExternalID string `mapstructure:"external_id"`
+	// MFASerial is the ARN of the MFA device, whose code is asked when the
+	// role is assumed
+	MFASerial   string        `mapstructure:"mfa_serial"`
+	SessionName string        `mapstructure:"session_name"`
+	Duration    time.Duration `mapstructure:"duration"`
 }
 
 // UIConfig holds UI-specific configuration
 type UIConfig struct {
-	Compact bool `mapstructure:"compact"`
+	Compact    bool     `mapstructure:"compact"`
+	TagColumns []string `mapstructure:"tag_columns"`
+	TimeFormat string   `mapstructure:"time_format"`
+	// ImageColumn adds the AMI of the instances, with its name, to the
+	// instances table
+	ImageColumn bool `mapstructure:"image_column"`
+	// Level is the capability level of the user, gating the actions:
+	// viewer (read-only), operator (also start, stop and reboot the
+	// instances) or admin (also terminate them, and edit their protections,
+	// tags and attributes)
+	Level string `mapstructure:"level"`
+	// ConfirmDestructive is the confirmation of the destructive actions:
+	// button (Yes/No), typed (type the name or ID to terminate) or
+	// typed-all (also to stop)
+	ConfirmDestructive string `mapstructure:"confirm_destructive"`
+	// KeymapFile is the file overriding the default key bindings, defaults
+	// to ~/.config/e2c/keymap.yaml
+	KeymapFile string `mapstructure:"keymap_file"`
+	// Skin is the file of the colors, reloaded when it changes, defaults to
+	// ~/.config/e2c/skin.yaml
+	Skin string `mapstructure:"skin"`
+	// Theme is dark, light, time to switch between them at DayStart and
+	// NightStart, or terminal to follow the background of the terminal,
+	// falling back to time if it is unknown
+	Theme string `mapstructure:"theme"`
+	// DayStart and NightStart are the times of the day, HH:MM, when the
+	// time theme switches to light and to dark
+	DayStart   string `mapstructure:"day_start"`
+	NightStart string `mapstructure:"night_start"`
+	// Borders is the style of the borders: unicode box-drawing characters,
+	// ascii for the terminals garbling them, or none
+	Borders string `mapstructure:"borders"`
+	// Trend is the number of refreshes whose instance counts are drawn in
+	// the overview panel, 0 to hide the trend
+	Trend int `mapstructure:"trend"`
+	// NoticeTag is the tag whose value is displayed as a notice in the
+	// details of the instance and in the confirmations, empty to disable it
+	NoticeTag string `mapstructure:"notice_tag"`
+	// MessageHistory is the number of status and error messages kept for
+	// review in the messages view
+	MessageHistory int `mapstructure:"message_history"`
+	// Notify is the notification of the end of the long operations
+	Notify NotifyConfig `mapstructure:"notify"`
 }
 
-// LoadConfig loads the configuration from file and environment variables
-func LoadConfig(log *slog.Logger) (*Config, error) {
-	// Set defaults
-	viper.SetDefault("aws.default_region", "us-west-1")
-	viper.SetDefault("aws.refresh_interval", "30s")
-	viper.SetDefault("aws.profile", "")
-	viper.SetDefault("ui.compact", false)
+// NotifyConfig is the notification of the end of the long operations, the
+// batches, the waiters and the commands, not to watch the status bar
+type NotifyConfig struct {
+	// MinDuration is the duration from which an operation is notified
+	MinDuration time.Duration `mapstructure:"min_duration"`
+	// Bell rings the bell of the terminal, flagging the window in tmux
+	Bell bool `mapstructure:"bell"`
+	// Flash flashes the screen
+	Flash bool `mapstructure:"flash"`
+	// Toast is how long the summary of the operation is displayed, 0 not to
+	// display it
+	Toast time.Duration `mapstructure:"toast"`
+}
 
+// TypedConfirmation returns true if the given action (terminate or stop)
+// must be confirmed by typing the name or the ID of the instance
+func (c UIConfig) TypedConfirmation(action string) bool {
+	switch strings.ToLower(c.ConfirmDestructive) {
+	case "typed":
+		return action == "terminate"
+	case "typed-all":
+		return action == "terminate" || action == "stop"
+	default:
+		return false
+	}
+}
+
+// Capability levels of the user, from the lowest to the highest
+const (
+	LevelViewer   = "viewer"
+	LevelOperator = "operator"
+	LevelAdmin    = "admin"
+)
 
-	// Config file name and paths
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
+// levels are the capability levels, from the lowest to the highest
+var levels = []string{LevelViewer, LevelOperator, LevelAdmin}
 
-	// Add config search paths
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		log.Warn("Could not determine user home directory", "error", err)
-	} else {
-		configDir := filepath.Join(homeDir, ".config", "e2c")
-		viper.AddConfigPath(configDir)
+// Level returns the capability level of the user: viewer in a read-only
+// context, the one of ui.level otherwise, operator if it is unknown
+func (c *Config) Level() string {
+	if c.ReadOnly() {
+		return LevelViewer
+	}
+	level := strings.ToLower(c.UI.Level)
+	if slices.Contains(levels, level) {
+		return level
 	}
+	return LevelOperator
+}
+
+// Allows returns true if the level of the user grants the actions of the
+// given level
+func (c *Config) Allows(level string) bool {
+	return slices.Index(levels, c.Level()) >= slices.Index(levels, level)
+}
+
+// DefaultTimeFormat is the layout used to display timestamps by default
+const DefaultTimeFormat = "2006-01-02 15:04:05"
 
-	// Also look in current directory
-	viper.AddConfigPath(".")
-
-	// Environment variables
-	viper.SetEnvPrefix("E2C")
-	viper.AutomaticEnv()
-
-	// Try to read config file
-	err = viper.ReadInConfig()
-	if err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			log.Info("No config file found, using defaults and environment variables")
-		} else {
-			return nil, fmt.Errorf("error reading config: %w", err)
-		}
-	} else {
-		log.Info("Using config file", "file", viper.ConfigFileUsed())
+// timeFormats maps the time format presets to Go time layouts
+var timeFormats = map[string]string{
+	"default": DefaultTimeFormat,
+	"iso8601": "2006-01-02T15:04:05-0700",
+	"rfc3339": time.RFC3339,
+	"rfc1123": time.RFC1123,
+	"us":      "01/02/2006 03:04:05 PM",
+	"eu":      "02/01/2006 15:04:05",
+}
+
+// TimeLayout returns the Go time layout to use for timestamps. The time
+// format is either a preset name or a custom Go layout.
+func (c UIConfig) TimeLayout() string {
+	if c.TimeFormat == "" {
+		return DefaultTimeFormat
 	}
+	if layout, ok := timeFormats[strings.ToLower(c.TimeFormat)]; ok {
+		return layout
+	}
+	return c.TimeFormat
+}
+
+// FormatTime formats a timestamp in the local time zone with the layout of
+// the time format. All the timestamps displayed or exported are formatted
+// with it.
+func (c UIConfig) FormatTime(t time.Time) string {
+	return t.Local().Format(c.TimeLayout())
+}
+
+// TerraformConfig holds the Terraform state integration configuration
+type TerraformConfig struct {
+	Enabled    bool     `mapstructure:"enabled"`
+	StateFiles []string `mapstructure:"state_files"`
+}
+
+// BatchConfig holds the configuration of the batch actions
+type BatchConfig struct {
+	Concurrency int           `mapstructure:"concurrency"`
+	MaxRetries  int           `mapstructure:"max_retries"`
+	Backoff     time.Duration `mapstructure:"backoff"`
+	Rate        float64       `mapstructure:"rate"`
+}
+
+// PluginsConfig holds the configuration of the plugins
+type PluginsConfig struct {
+	Columns []PluginColumnConfig `mapstructure:"columns"`
+	Hooks   []PluginHookConfig   `mapstructure:"hooks"`
+}
+
+// PluginColumnConfig describes a column of the instances table populated by
+// an external command
+type PluginColumnConfig struct {
+	Name     string        `mapstructure:"name"`
+	Command  []string      `mapstructure:"command"`
+	Interval time.Duration `mapstructure:"interval"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// PluginHookConfig describes a local command run after an action on an
+// instance. Events are start, stop, reboot or terminate.
+type PluginHookConfig struct {
+	Name    string        `mapstructure:"name"`
+	Events  []string      `mapstructure:"events"`
+	Command []string      `mapstructure:"command"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
 
-	// Unmarshal config
-	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("error unmarshalling config: %w", err)
+// LogsConfig holds the mapping of the instances to CloudWatch Logs streams.
+// The group and the stream are read from the instance tags if set, otherwise
+// the default ones are used. {instance_id} and {name} are replaced in them.
+type LogsConfig struct {
+	LogGroup  string `mapstructure:"log_group"`
+	LogStream string `mapstructure:"log_stream"`
+	GroupTag  string `mapstructure:"group_tag"`
+	StreamTag string `mapstructure:"stream_tag"`
+}
+
+// AuditConfig holds the configuration of the audit log of the mutating
+// actions. The file defaults to ~/.config/e2c/audit.jsonl.
+type AuditConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	File           string `mapstructure:"file"`
+	StructuredLogs bool   `mapstructure:"structured_logs"`
+}
+
+// TelemetryConfig holds the configuration of the export of the traces of
+// the user actions, and of the metrics of the calls to AWS and of the
+// refreshes, to an OpenTelemetry collector with OTLP over HTTP, or to a file
+type TelemetryConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Exporter is otlp to send them to the endpoint, or file to append them
+	// to the file, to debug them without a collector
+	Exporter string `mapstructure:"exporter"`
+	Endpoint string `mapstructure:"endpoint"`
+	// File receives the spans and the metrics as JSON, one per line, with
+	// the file exporter
+	File string `mapstructure:"file"`
+	// MetricsInterval is the interval between two exports of the metrics
+	MetricsInterval time.Duration `mapstructure:"metrics_interval"`
+}
+
+// WatchConfig is the monitoring of the instances watched, notified when
+// their state or their status checks change
+type WatchConfig struct {
+	// Interval is the interval between two checks of the instances watched
+	Interval time.Duration `mapstructure:"interval"`
+	// Webhook receives the changes as JSON with POST requests, if set
+	Webhook string `mapstructure:"webhook"`
+}
+
+// LoggingConfig holds the logging levels of the subsystems, overriding the
+// one of E2C_LOG_LEVEL and --log-level for their logs, e.g. aws: debug to
+// debug the calls to AWS without the logs of the UI
+type LoggingConfig struct {
+	// Levels are the levels of the subsystems by name: aws, ui, audit,
+	// plugin, schedule, tunnel or watch
+	Levels map[string]string `mapstructure:"levels"`
+}
+
+// ScheduleConfig describes the automatic stop of the running instances with
+// a tag at a time of the day, e.g. the dev instances at 19:00 on weekdays.
+// The local schedules are applied by e2c while it is running, the
+// eventbridge ones are created in EventBridge Scheduler with e2c schedule
+// apply.
+type ScheduleConfig struct {
+	Name string `mapstructure:"name"`
+	// Tag selects the instances, of the form key=value
+	Tag string `mapstructure:"tag"`
+	// Stop is the time of the stop, HH:MM
+	Stop string `mapstructure:"stop"`
+	// Days are the days of the stop, mon to sun, every day if empty
+	Days []string `mapstructure:"days"`
+	// Timezone is the IANA time zone of the time, the local one if empty
+	Timezone string `mapstructure:"timezone"`
+	// Profile is the AWS profile of the instances, the one e2c is started
+	// with if empty, whatever the profile displayed when the stop is due
+	Profile string `mapstructure:"profile"`
+	// Region is the region of the instances, the one e2c is started with if
+	// empty, whatever the region displayed when the stop is due
+	Region string `mapstructure:"region"`
+	// Mode is local (default) or eventbridge
+	Mode string `mapstructure:"mode"`
+	// RoleARN is the IAM role assumed by EventBridge Scheduler to stop the
+	// instances, required by the eventbridge mode
+	RoleARN string `mapstructure:"role_arn"`
+}
+
+// ContextConfig is a named bundle of the AWS profile, the region and the UI
+// settings, like the contexts of a kubeconfig, selected with --context or the
+// :ctx command. The settings not set in a context keep their current value.
+type ContextConfig struct {
+	Profile    string   `mapstructure:"profile"`
+	Region     string   `mapstructure:"region"`
+	ReadOnly   bool     `mapstructure:"read_only"`
+	TagColumns []string `mapstructure:"tag_columns"`
+}
+
+// FlagConfig is a feature flag, on for the sessions matching all its rules
+type FlagConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Profiles, Regions and Users restrict the flag to the AWS profiles, the
+	// regions and the ARNs of the callers matching one of their patterns,
+	// with * wildcards, all if empty
+	Profiles []string `mapstructure:"profiles"`
+	Regions  []string `mapstructure:"regions"`
+	Users    []string `mapstructure:"users"`
+	// Percentage restricts the flag to a stable share of the callers, all
+	// if 0
+	Percentage int `mapstructure:"percentage"`
+	// Value is the value of the flag when it is on, e.g. an interval
+	Value string `mapstructure:"value"`
+}
+
+// setDefaults sets the default values of the configuration
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("aws.default_region", "us-west-1")
+	v.SetDefault("aws.refresh_interval", "30s")
+	v.SetDefault("aws.background_regions", 3)
+	v.SetDefault("aws.profile", "")
+	v.SetDefault("aws.endpoint_url", "")
+	v.SetDefault("aws.retry_mode", "")
+	v.SetDefault("aws.max_attempts", 0)
+	v.SetDefault("aws.api_timeout", "0s")
+	v.SetDefault("aws.attribute_rate", 10)
+	v.SetDefault("ui.compact", false)
+	v.SetDefault("ui.tag_columns", []string{})
+	v.SetDefault("ui.time_format", "default")
+	v.SetDefault("ui.image_column", false)
+	v.SetDefault("ui.level", LevelOperator)
+	v.SetDefault("ui.confirm_destructive", "button")
+	v.SetDefault("ui.keymap_file", "")
+	v.SetDefault("ui.skin", "")
+	v.SetDefault("ui.theme", "dark")
+	v.SetDefault("ui.day_start", "07:00")
+	v.SetDefault("ui.night_start", "19:00")
+	v.SetDefault("ui.borders", "unicode")
+	v.SetDefault("ui.trend", 20)
+	v.SetDefault("ui.notice_tag", "e2c:notice")
+	v.SetDefault("ui.message_history", 200)
+	v.SetDefault("ui.notify.min_duration", 10*time.Second)
+	v.SetDefault("ui.notify.bell", true)
+	v.SetDefault("ui.notify.flash", false)
+	v.SetDefault("ui.notify.toast", 5*time.Second)
+	v.SetDefault("terraform.enabled", false)
+	v.SetDefault("terraform.state_files", []string{})
+	v.SetDefault("batch.concurrency", 5)
+	v.SetDefault("batch.max_retries", 3)
+	v.SetDefault("batch.backoff", "1s")
+	v.SetDefault("batch.rate", 10)
+	v.SetDefault("plugins.columns", []PluginColumnConfig{})
+	v.SetDefault("plugins.hooks", []PluginHookConfig{})
+	v.SetDefault("logs.log_group", "")
+	v.SetDefault("logs.log_stream", "{instance_id}")
+	v.SetDefault("logs.group_tag", "e2c:log-group")
+	v.SetDefault("logs.stream_tag", "e2c:log-stream")
+	v.SetDefault("audit.enabled", true)
+	v.SetDefault("audit.file", "")
+	v.SetDefault("audit.structured_logs", false)
+	v.SetDefault("telemetry.enabled", false)
+	v.SetDefault("telemetry.exporter", "otlp")
+	v.SetDefault("telemetry.endpoint", "http://localhost:4318")
+	v.SetDefault("telemetry.file", "")
+	v.SetDefault("telemetry.metrics_interval", time.Minute)
+	v.SetDefault("watch.interval", 15*time.Second)
+	v.SetDefault("watch.webhook", "")
+	v.SetDefault("logging.levels", map[string]string{})
+	v.SetDefault("schedules", []ScheduleConfig{})
+	v.SetDefault("context", "")
+	v.SetDefault("contexts", map[string]ContextConfig{})
+	v.SetDefault("feature_flags", map[string]FlagConfig{})
+}
+
+// Default returns the default configuration, ignoring the config file and
+// the environment variables
+func Default() (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	p := newProvenance()
+	p.merge("", v.AllSettings(), Origin{Source: SourceDefault})
+	return decode(p)
+}
+
+// Provenance returns the values of the configuration and their origin
+func (c *Config) Provenance() *Provenance {
+	if c.provenance == nil {
+		c.provenance = newProvenance()
 	}
+	return c.provenance
+}
 
-	return &config, nil
+// Clone returns a copy of the configuration, with its own provenance, which
+// can be modified without changing the configuration. The lists and the maps
+// are shared: they must be replaced rather than modified.
+func (c *Config) Clone() *Config {
+	clone := *c
+	if c.provenance != nil {
+		clone.provenance = c.provenance.clone()
+	}
+	return &clone
 }
 
-// Override allows command-line flags to override config
-func (c *Config) Override(profile, region string) {
-	if profile != "" {
-		c.AWS.Profile = profile
+// UseContext applies the settings of a context over the current ones
+func (c *Config) UseContext(name string) error {
+	context, ok := c.Contexts[name]
+	if !ok {
+		return fmt.Errorf("unknown context %q", name)
+	}
+
+	origin := Origin{Source: SourceContext, Detail: name}
+	p := c.Provenance()
+	c.Context = name
+	p.set("context", name, origin)
+	if context.Profile != "" {
+		c.AWS.Profile = context.Profile
+		p.set("aws.profile", context.Profile, origin)
+	}
+	if context.Region != "" {
+		c.AWS.DefaultRegion = context.Region
+		p.set("aws.default_region", context.Region, origin)
 	}
-	if region != "" {
-		c.AWS.DefaultRegion = region
+	if len(context.TagColumns) > 0 {
+		c.UI.TagColumns = context.TagColumns
+		p.set("ui.tag_columns", context.TagColumns, origin)
+	}
+	return nil
+}
+
+// ReadOnly returns true if the context in use disables the actions changing
+// the instances
+func (c *Config) ReadOnly() bool {
+	return c.Contexts[c.Context].ReadOnly
+}
+
+// ContextNames returns the names of the contexts, sorted
+func (c *Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
 	}
-}
\ No newline at end of file
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the keys
+// of the configuration, e.g. E2C_AWS_PROFILE for aws.profile
+const EnvPrefix = "E2C"
+
+// Environment variables read directly by e2c
+const (
+	EnvConfigFile = "E2C_CONFIG"
+	EnvLogLevel   = "E2C_LOG_LEVEL"
+	EnvLogFormat  = "E2C_LOG_FORMAT"
+	// EnvOTLPEndpoint is the standard variable of the OpenTelemetry SDKs
+	EnvOTLPEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+)
+
+// envKeyReplacer maps the keys of the configuration to the names of the
+// environment variables
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// EnvVar is an environment variable honored by e2c
+type EnvVar struct {
+	Name        string
+	Key         string // Key of the configuration overridden, if any
+	Description string
+}
+
+// envVars are the environment variables read directly by e2c
+var envVars = []EnvVar{
+	{Name: EnvConfigFile, Description: "Path of the configuration file, when --config is not given"},
+	{Name: EnvLogLevel, Description: "Logging level (debug, info, warn, error)"},
+	{Name: EnvLogFormat, Description: "Log format (json, text)"},
+	{Name: EnvOTLPEndpoint, Key: "telemetry.endpoint", Description: "Endpoint of the OpenTelemetry collector, unless E2C_TELEMETRY_ENDPOINT is set, enabling the telemetry"},
+}
+
+// EnvName returns the name of the environment variable overriding a key of
+// the configuration
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// EnvVars returns the environment variables read directly by e2c, then the
+// ones overriding the keys of the configuration, derived from their defaults.
+// The keys whose value is a list of objects or a map, such as plugins.columns
+// or contexts, can only be set in the configuration file.
+func EnvVars() []EnvVar {
+	v := viper.New()
+	setDefaults(v)
+
+	keys := v.AllKeys()
+	sort.Strings(keys)
+
+	vars := append([]EnvVar(nil), envVars...)
+	for _, key := range keys {
+		switch value := reflect.ValueOf(v.Get(key)); value.Kind() {
+		case reflect.Map:
+			continue
+		case reflect.Slice:
+			if value.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+		}
+		vars = append(vars, EnvVar{Name: EnvName(key), Key: key})
+	}
+	return vars
+}
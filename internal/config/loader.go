@@ -0,0 +1,419 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Sources of the values of the configuration, from the lowest precedence to
+// the highest
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceContext = "context"
+	SourceSession = "session"
+	SourceFlag    = "flag"
+)
+
+// Origin is where a value of the configuration comes from
+type Origin struct {
+	Source string `json:"source" yaml:"source"` // One of the Source constants
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// String returns the source and its detail, e.g. env E2C_AWS_PROFILE or
+// file ~/.config/e2c/config.yaml
+func (o Origin) String() string {
+	if o.Detail == "" {
+		return o.Source
+	}
+	return o.Source + " " + o.Detail
+}
+
+// Options are the inputs of the configuration other than the file and the
+// environment, usually the command line flags
+type Options struct {
+	// Path is the configuration file, which must exist. Defaults to the
+	// E2C_CONFIG environment variable, then to config.yaml searched in
+	// ~/.config/e2c and in the current directory.
+	Path string
+	// Context is the context to apply, defaults to the one of the file
+	Context string
+	// Profile and Region override the ones of the configuration
+	Profile string
+	Region  string
+	// SessionRegions are the regions of the previous sessions by AWS
+	// profile, "default" for the one without a profile. The region of the
+	// profile in use is applied without Region nor context.
+	SessionRegions map[string]string
+	// TelemetryEndpoint enables the export of the traces and the metrics to
+	// the OpenTelemetry collector at this endpoint, if set
+	TelemetryEndpoint string
+	// LookupEnv reads the environment variables, os.LookupEnv if nil
+	LookupEnv func(name string) (string, bool)
+}
+
+// Provenance holds the values of the configuration, by key, and where each
+// one comes from
+type Provenance struct {
+	values  map[string]any
+	origins map[string]Origin
+}
+
+// newProvenance creates an empty provenance
+func newProvenance() *Provenance {
+	return &Provenance{
+		values:  make(map[string]any),
+		origins: make(map[string]Origin),
+	}
+}
+
+// clone returns a copy of the provenance
+func (p *Provenance) clone() *Provenance {
+	clone := newProvenance()
+	for key, value := range p.values {
+		clone.values[key] = value
+	}
+	for key, origin := range p.origins {
+		clone.origins[key] = origin
+	}
+	return clone
+}
+
+// set sets the value of a key, replacing the values of its parents and its
+// children, e.g. contexts when contexts.prod.region is set
+func (p *Provenance) set(key string, value any, origin Origin) {
+	for existing := range p.values {
+		if strings.HasPrefix(key, existing+".") || strings.HasPrefix(existing, key+".") {
+			delete(p.values, existing)
+			delete(p.origins, existing)
+		}
+	}
+	p.values[key] = value
+	p.origins[key] = origin
+}
+
+// merge sets the values of a nested map of settings, as read by viper
+func (p *Provenance) merge(prefix string, settings map[string]any, origin Origin) {
+	for name, value := range settings {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			p.merge(key, nested, origin)
+			continue
+		}
+		p.set(key, value, origin)
+	}
+}
+
+// get returns the value of a key, nested in a map if it is not a key of its
+// own, e.g. contexts.prod when contexts.prod.region is set
+func (p *Provenance) get(key string) (any, bool) {
+	if value, ok := p.values[key]; ok {
+		return value, true
+	}
+	nested := make(map[string]any)
+	for existing, value := range p.values {
+		if name, ok := strings.CutPrefix(existing, key+"."); ok {
+			nested[name] = value
+		}
+	}
+	if len(nested) == 0 {
+		return nil, false
+	}
+	return unflatten(nested), true
+}
+
+// Keys returns the keys of the configuration, sorted
+func (p *Provenance) Keys() []string {
+	keys := make([]string, 0, len(p.values))
+	for key := range p.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Value returns the value of a key
+func (p *Provenance) Value(key string) any {
+	return p.values[key]
+}
+
+// Origin returns where the value of a key comes from
+func (p *Provenance) Origin(key string) Origin {
+	return p.origins[key]
+}
+
+// Settings returns the values as nested maps, as in the configuration file
+func (p *Provenance) Settings() map[string]any {
+	return unflatten(p.values)
+}
+
+// unflatten nests the values of dotted keys into maps
+func unflatten(values map[string]any) map[string]any {
+	settings := make(map[string]any)
+	for key, value := range values {
+		names := strings.Split(key, ".")
+		current := settings
+		for _, name := range names[:len(names)-1] {
+			next, ok := current[name].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				current[name] = next
+			}
+			current = next
+		}
+		current[names[len(names)-1]] = value
+	}
+	return settings
+}
+
+// FormatValue formats a value of the configuration on a line, the lists and
+// the objects in JSON
+func FormatValue(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case nil:
+		return ""
+	}
+	if kind := reflect.ValueOf(value).Kind(); kind != reflect.Slice && kind != reflect.Map && kind != reflect.Struct {
+		return fmt.Sprint(value)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// contextKeys are the keys of the configuration set by the settings of a
+// context
+var contextKeys = map[string]string{
+	"profile":     "aws.profile",
+	"region":      "aws.default_region",
+	"tag_columns": "ui.tag_columns",
+}
+
+// Load loads the configuration by merging, from the lowest precedence to the
+// highest: the defaults, the file, the section of the AWS profile in use in
+// the file, the environment variables, the context, the region of the
+// previous session and the flags. The origin of each value is recorded in
+// the provenance of the configuration. Each call is independent: no global
+// state is kept.
+func Load(log *slog.Logger, opts Options) (*Config, error) {
+	lookupEnv := opts.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	p := newProvenance()
+
+	// Defaults
+	defaults := viper.New()
+	setDefaults(defaults)
+	p.merge("", defaults.AllSettings(), Origin{Source: SourceDefault})
+
+	// File
+	file, err := readFile(log, opts.Path, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	var profiles map[string]any
+	if file != nil {
+		path := file.ConfigFileUsed()
+		settings := file.AllSettings()
+		profiles, _ = settings["profiles"].(map[string]any)
+		delete(settings, "profiles")
+		p.merge("", settings, Origin{Source: SourceFile, Detail: path})
+	}
+
+	// Environment variables, e.g. E2C_AWS_PROFILE for aws.profile, applied
+	// once the section of the profile is merged
+	env := make(map[string]EnvVar)
+	for _, v := range EnvVars() {
+		if _, ok := lookupEnv(v.Name); ok && v.Key != "" {
+			env[v.Key] = v
+		}
+	}
+	current := func(key string) string {
+		if v, ok := env[key]; ok {
+			value, _ := lookupEnv(v.Name)
+			return value
+		}
+		value, _ := p.values[key].(string)
+		return value
+	}
+
+	// Section of the AWS profile in use, the given one, the one of the
+	// context or aws.profile
+	context := opts.Context
+	if context == "" {
+		context = current("context")
+	}
+	profile := opts.Profile
+	if profile == "" && context != "" {
+		if value, ok := p.get("contexts." + strings.ToLower(context) + ".profile"); ok {
+			profile, _ = value.(string)
+		}
+	}
+	if profile == "" {
+		profile = current("aws.profile")
+	}
+	if section, ok := profiles[strings.ToLower(profile)].(map[string]any); ok && profile != "" && len(section) > 0 {
+		p.merge("", section, Origin{Source: SourceProfile, Detail: "profiles." + profile})
+		log.Info("Using config of profile", "profile", profile)
+	}
+
+	for key, v := range env {
+		var value any
+		value, _ = lookupEnv(v.Name)
+		if reflect.ValueOf(p.values[key]).Kind() == reflect.Slice {
+			// Lists of strings separated by spaces
+			value = strings.Fields(value.(string))
+		}
+		p.set(key, value, Origin{Source: SourceEnv, Detail: v.Name})
+	}
+
+	// The endpoint of the OpenTelemetry SDKs enables the telemetry, as the
+	// --otel-endpoint flag does, unless E2C_TELEMETRY_ENABLED is set
+	if endpoint, _ := lookupEnv(EnvOTLPEndpoint); endpoint != "" && p.Origin("telemetry.enabled").Source != SourceEnv {
+		p.set("telemetry.enabled", true, Origin{Source: SourceEnv, Detail: EnvOTLPEndpoint})
+	}
+
+	// Deprecated expert mode, the admin level unless a level is set
+	if expert, _ := p.values["ui.expert_mode"].(bool); expert {
+		log.Warn("ui.expert_mode is deprecated, use ui.level: admin")
+		if p.Origin("ui.level").Source == SourceDefault {
+			p.set("ui.level", LevelAdmin, p.Origin("ui.expert_mode"))
+		}
+	}
+
+	// Context
+	if context != "" {
+		value, ok := p.get("contexts." + strings.ToLower(context))
+		settings, _ := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unknown context %q", context)
+		}
+		origin := Origin{Source: SourceContext, Detail: context}
+		p.set("context", context, origin)
+		for name, key := range contextKeys {
+			if value, ok := settings[name]; ok && !isEmpty(value) {
+				p.set(key, value, origin)
+			}
+		}
+		log.Info("Using context", "context", context)
+	}
+
+	// Region of the previous session of the profile, then the flags
+	sessionProfile := profile
+	if sessionProfile == "" {
+		sessionProfile = "default"
+	}
+	if region := opts.SessionRegions[sessionProfile]; region != "" && opts.Region == "" && context == "" {
+		p.set("aws.default_region", region, Origin{Source: SourceSession})
+	}
+	if opts.Profile != "" {
+		p.set("aws.profile", opts.Profile, Origin{Source: SourceFlag, Detail: "--profile"})
+	}
+	if opts.Region != "" {
+		p.set("aws.default_region", opts.Region, Origin{Source: SourceFlag, Detail: "--region"})
+	}
+	if opts.TelemetryEndpoint != "" {
+		origin := Origin{Source: SourceFlag, Detail: "--otel-endpoint"}
+		p.set("telemetry.enabled", true, origin)
+		p.set("telemetry.endpoint", opts.TelemetryEndpoint, origin)
+	}
+
+	config, err := decode(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// readFile reads the configuration file, nil if none was found
+func readFile(log *slog.Logger, path string, lookupEnv func(string) (string, bool)) (*viper.Viper, error) {
+	v := viper.New()
+	if path == "" {
+		path, _ = lookupEnv(EnvConfigFile)
+	}
+
+	if path != "" {
+		// An explicit config file must exist
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("config file %s not found: %w", path, err)
+		}
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName("config")
+		v.SetConfigType("yaml")
+
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			log.Warn("Could not determine user home directory", "error", err)
+		} else {
+			v.AddConfigPath(filepath.Join(homeDir, ".config", "e2c"))
+		}
+
+		// Also look in current directory
+		v.AddConfigPath(".")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			log.Info("No config file found, using defaults and environment variables")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	log.Info("Using config file", "file", v.ConfigFileUsed())
+	return v, nil
+}
+
+// decode decodes the merged values into a configuration
+func decode(p *Provenance) (*Config, error) {
+	v := viper.New()
+	if err := v.MergeConfigMap(p.Settings()); err != nil {
+		return nil, fmt.Errorf("error merging config: %w", err)
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("error unmarshalling config: %w", err)
+	}
+	config.provenance = p
+	return &config, nil
+}
+
+// isEmpty returns true for the zero values and the empty lists, which leave
+// the setting of a context unset
+func isEmpty(value any) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,348 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testConfigFile is a configuration file setting each level of the
+// precedence chain
+const testConfigFile = `
+aws:
+  profile: dev
+  default_region: eu-west-1
+  refresh_interval: 1m
+ui:
+  tag_columns: [Team]
+profiles:
+  prod:
+    aws:
+      refresh_interval: 2m
+contexts:
+  staging:
+    profile: prod
+    region: eu-central-1
+  local:
+    tag_columns: [Owner]
+`
+
+// writeConfig writes a configuration file in a temporary directory and
+// returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// lookupEnv returns a LookupEnv reading the variables of env only
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+// testLogger returns a logger discarding the logs
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// setting is the expected value of a key and its origin
+type setting struct {
+	value  string
+	source string
+	detail string
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, testConfigFile)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		opts Options
+		want map[string]setting
+	}{
+		{
+			name: "default and file",
+			want: map[string]setting{
+				"aws.max_attempts":     {value: "0", source: SourceDefault},
+				"aws.profile":          {value: "dev", source: SourceFile, detail: path},
+				"aws.default_region":   {value: "eu-west-1", source: SourceFile, detail: path},
+				"aws.refresh_interval": {value: "1m", source: SourceFile, detail: path},
+				"ui.tag_columns":       {value: `["Team"]`, source: SourceFile, detail: path},
+			},
+		},
+		{
+			name: "profile over file",
+			env:  map[string]string{"E2C_AWS_PROFILE": "prod"},
+			want: map[string]setting{
+				"aws.profile":          {value: "prod", source: SourceEnv, detail: "E2C_AWS_PROFILE"},
+				"aws.refresh_interval": {value: "2m", source: SourceProfile, detail: "profiles.prod"},
+				"aws.default_region":   {value: "eu-west-1", source: SourceFile, detail: path},
+			},
+		},
+		{
+			name: "env over profile",
+			env:  map[string]string{"E2C_AWS_PROFILE": "prod", "E2C_AWS_REFRESH_INTERVAL": "10s"},
+			want: map[string]setting{
+				"aws.refresh_interval": {value: "10s", source: SourceEnv, detail: "E2C_AWS_REFRESH_INTERVAL"},
+			},
+		},
+		{
+			name: "env list",
+			env:  map[string]string{"E2C_UI_TAG_COLUMNS": "Team Owner"},
+			want: map[string]setting{
+				"ui.tag_columns": {value: `["Team","Owner"]`, source: SourceEnv, detail: "E2C_UI_TAG_COLUMNS"},
+			},
+		},
+		{
+			name: "context over env",
+			env:  map[string]string{"E2C_AWS_DEFAULT_REGION": "us-east-1", "E2C_AWS_PROFILE": "dev"},
+			opts: Options{Context: "staging"},
+			want: map[string]setting{
+				"context":              {value: "staging", source: SourceContext, detail: "staging"},
+				"aws.profile":          {value: "prod", source: SourceContext, detail: "staging"},
+				"aws.default_region":   {value: "eu-central-1", source: SourceContext, detail: "staging"},
+				"aws.refresh_interval": {value: "2m", source: SourceProfile, detail: "profiles.prod"},
+			},
+		},
+		{
+			name: "context from env",
+			env:  map[string]string{"E2C_CONTEXT": "local"},
+			want: map[string]setting{
+				"context":            {value: "local", source: SourceContext, detail: "local"},
+				"ui.tag_columns":     {value: `["Owner"]`, source: SourceContext, detail: "local"},
+				"aws.default_region": {value: "eu-west-1", source: SourceFile, detail: path},
+			},
+		},
+		{
+			name: "session over env",
+			env:  map[string]string{"E2C_AWS_DEFAULT_REGION": "us-east-1"},
+			opts: Options{SessionRegions: map[string]string{"dev": "ap-south-1"}},
+			want: map[string]setting{
+				"aws.default_region": {value: "ap-south-1", source: SourceSession},
+			},
+		},
+		{
+			name: "session of the profile",
+			opts: Options{Profile: "ops", SessionRegions: map[string]string{"dev": "ap-south-1", "ops": "sa-east-1"}},
+			want: map[string]setting{
+				"aws.profile":        {value: "ops", source: SourceFlag, detail: "--profile"},
+				"aws.default_region": {value: "sa-east-1", source: SourceSession},
+			},
+		},
+		{
+			name: "session of another profile",
+			opts: Options{Profile: "ops", SessionRegions: map[string]string{"dev": "ap-south-1"}},
+			want: map[string]setting{
+				"aws.profile":        {value: "ops", source: SourceFlag, detail: "--profile"},
+				"aws.default_region": {value: "eu-west-1", source: SourceFile, detail: path},
+			},
+		},
+		{
+			name: "context over session",
+			opts: Options{Context: "staging", SessionRegions: map[string]string{"dev": "ap-south-1", "prod": "ap-south-1"}},
+			want: map[string]setting{
+				"aws.default_region": {value: "eu-central-1", source: SourceContext, detail: "staging"},
+			},
+		},
+		{
+			name: "flags over context",
+			opts: Options{Context: "staging", Profile: "ops", Region: "us-east-2"},
+			want: map[string]setting{
+				"aws.profile":          {value: "ops", source: SourceFlag, detail: "--profile"},
+				"aws.default_region":   {value: "us-east-2", source: SourceFlag, detail: "--region"},
+				"aws.refresh_interval": {value: "1m", source: SourceFile, detail: path},
+			},
+		},
+		{
+			name: "flag profile selects the profile section",
+			opts: Options{Profile: "prod"},
+			want: map[string]setting{
+				"aws.refresh_interval": {value: "2m", source: SourceProfile, detail: "profiles.prod"},
+			},
+		},
+		{
+			name: "telemetry flag",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"},
+			opts: Options{TelemetryEndpoint: "http://localhost:4318"},
+			want: map[string]setting{
+				"telemetry.enabled":  {value: "true", source: SourceFlag, detail: "--otel-endpoint"},
+				"telemetry.endpoint": {value: "http://localhost:4318", source: SourceFlag, detail: "--otel-endpoint"},
+			},
+		},
+		{
+			name: "telemetry env",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"},
+			want: map[string]setting{
+				"telemetry.enabled":  {value: "true", source: SourceEnv, detail: "OTEL_EXPORTER_OTLP_ENDPOINT"},
+				"telemetry.endpoint": {value: "http://collector:4318", source: SourceEnv, detail: "OTEL_EXPORTER_OTLP_ENDPOINT"},
+			},
+		},
+		{
+			name: "telemetry disabled over env",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318", "E2C_TELEMETRY_ENABLED": "false"},
+			want: map[string]setting{
+				"telemetry.enabled": {value: "false", source: SourceEnv, detail: "E2C_TELEMETRY_ENABLED"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Path = path
+			opts.LookupEnv = lookupEnv(tt.env)
+
+			config, err := Load(testLogger(), opts)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			p := config.Provenance()
+			for key, want := range tt.want {
+				if got := FormatValue(p.Value(key)); got != want.value {
+					t.Errorf("%s = %q, want %q", key, got, want.value)
+				}
+				if got := p.Origin(key); got != (Origin{Source: want.source, Detail: want.detail}) {
+					t.Errorf("%s origin = %q, want %q", key, got, Origin{Source: want.source, Detail: want.detail})
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDecodes(t *testing.T) {
+	config, err := Load(testLogger(), Options{
+		Path:      writeConfig(t, testConfigFile),
+		Context:   "staging",
+		LookupEnv: lookupEnv(map[string]string{"E2C_UI_TAG_COLUMNS": "Team Owner"}),
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if config.Context != "staging" || config.AWS.Profile != "prod" || config.AWS.DefaultRegion != "eu-central-1" {
+		t.Errorf("context, profile, region = %q, %q, %q, want staging, prod, eu-central-1", config.Context, config.AWS.Profile, config.AWS.DefaultRegion)
+	}
+	if want := []string{"Team", "Owner"}; !reflect.DeepEqual(config.UI.TagColumns, want) {
+		t.Errorf("ui.tag_columns = %q, want %q", config.UI.TagColumns, want)
+	}
+	if got := config.Contexts["staging"]; got.Region != "eu-central-1" {
+		t.Errorf("contexts.staging.region = %q, want eu-central-1", got.Region)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeConfig(t, "aws:\n  default_region: eu-west-3\n")
+
+	tests := []struct {
+		name    string
+		path    string
+		env     map[string]string
+		context string
+		wantErr string
+	}{
+		{name: "path", path: path},
+		{name: "env", env: map[string]string{EnvConfigFile: path}},
+		{name: "path over env", path: path, env: map[string]string{EnvConfigFile: filepath.Join(t.TempDir(), "missing.yaml")}},
+		{name: "missing", path: filepath.Join(t.TempDir(), "missing.yaml"), wantErr: "not found"},
+		{name: "unknown context", path: path, context: "prod", wantErr: `unknown context "prod"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := Load(testLogger(), Options{Path: tt.path, Context: tt.context, LookupEnv: lookupEnv(tt.env)})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if origin := config.Provenance().Origin("aws.default_region"); config.AWS.DefaultRegion != "eu-west-3" || origin.Detail != path {
+				t.Errorf("aws.default_region = %q from %q, want eu-west-3 from %s", config.AWS.DefaultRegion, origin, path)
+			}
+		})
+	}
+}
+
+func TestLoadIndependent(t *testing.T) {
+	path := writeConfig(t, testConfigFile)
+
+	prod, err := Load(testLogger(), Options{Path: path, Profile: "prod", LookupEnv: lookupEnv(nil)})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	dev, err := Load(testLogger(), Options{Path: path, LookupEnv: lookupEnv(nil)})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if prod.AWS.Profile != "prod" || dev.AWS.Profile != "dev" {
+		t.Errorf("profiles = %q, %q, want prod, dev", prod.AWS.Profile, dev.AWS.Profile)
+	}
+	if got := dev.Provenance().Origin("aws.refresh_interval").Source; got != SourceFile {
+		t.Errorf("dev aws.refresh_interval source = %q, want %q", got, SourceFile)
+	}
+}
+
+func TestProvenanceSet(t *testing.T) {
+	file := Origin{Source: SourceFile, Detail: "config.yaml"}
+	env := Origin{Source: SourceEnv, Detail: "E2C_CONTEXTS"}
+
+	p := newProvenance()
+	p.merge("", map[string]any{
+		"contexts": map[string]any{
+			"prod":    map[string]any{"region": "eu-west-1", "profile": "prod"},
+			"staging": map[string]any{"region": "eu-west-3"},
+		},
+		"ui": map[string]any{"tag_columns": []string{}},
+	}, file)
+
+	if want := []string{"contexts.prod.profile", "contexts.prod.region", "contexts.staging.region", "ui.tag_columns"}; !reflect.DeepEqual(p.Keys(), want) {
+		t.Errorf("Keys() = %q, want %q", p.Keys(), want)
+	}
+	value, ok := p.get("contexts.prod")
+	if want := map[string]any{"region": "eu-west-1", "profile": "prod"}; !ok || !reflect.DeepEqual(value, want) {
+		t.Errorf("get(contexts.prod) = %v, %t, want %v", value, ok, want)
+	}
+
+	// A parent replaces its children
+	p.set("contexts", map[string]any{"dev": map[string]any{"region": "us-east-1"}}, env)
+	if want := []string{"contexts", "ui.tag_columns"}; !reflect.DeepEqual(p.Keys(), want) {
+		t.Errorf("Keys() after setting the parent = %q, want %q", p.Keys(), want)
+	}
+	if got := p.Origin("contexts"); got != env {
+		t.Errorf("Origin(contexts) = %q, want %q", got, env)
+	}
+
+	// A child replaces its parent
+	p.set("contexts.prod.region", "eu-west-2", file)
+	if want := []string{"contexts.prod.region", "ui.tag_columns"}; !reflect.DeepEqual(p.Keys(), want) {
+		t.Errorf("Keys() after setting a child = %q, want %q", p.Keys(), want)
+	}
+	if _, ok := p.get("contexts.dev"); ok {
+		t.Error("get(contexts.dev) found a value replaced by a child")
+	}
+
+	// Siblings sharing a prefix are kept
+	p.set("ui.tag", "e2c", file)
+	if want := []string{"contexts.prod.region", "ui.tag", "ui.tag_columns"}; !reflect.DeepEqual(p.Keys(), want) {
+		t.Errorf("Keys() after setting a sibling = %q, want %q", p.Keys(), want)
+	}
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nlamirault/e2c/internal/logger"
+)
+
+// Bounds of aws.refresh_interval: the shortest stays far below the EC2 API
+// rate limits
+const (
+	MinRefreshInterval = 5 * time.Second
+	MaxRefreshInterval = time.Hour
+)
+
+// regionPattern matches the names of the AWS regions, e.g. eu-west-1 or
+// us-gov-east-1
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// Values accepted by the settings enumerating their choices, by key, in
+// addition to the empty value selecting the default one
+var choices = map[string][]string{
+	"aws.retry_mode":         {"standard", "adaptive"},
+	"ui.level":               levels,
+	"ui.confirm_destructive": {"button", "typed", "typed-all"},
+	"ui.theme":               {"dark", "light", "time", "terminal"},
+	"ui.borders":             {"unicode", "ascii", "none"},
+	"telemetry.exporter":     {"otlp", "file"},
+}
+
+// ValidationError lists all the invalid settings of a configuration, so that
+// they can be fixed at once rather than failing one by one at runtime
+type ValidationError struct {
+	Problems []string // One per invalid setting, e.g. ui.theme: unknown value "drak"
+}
+
+// Error returns the problems, one per line
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// validator collects the problems of a configuration
+type validator struct {
+	config   *Config
+	problems []string
+}
+
+// addf records a problem of a setting, with the origin of its value if known
+func (v *validator) addf(key, format string, args ...any) {
+	problem := key + ": " + fmt.Sprintf(format, args...)
+	if v.config.provenance != nil {
+		if origin := v.config.provenance.Origin(key); origin.Source != "" && origin.Source != SourceDefault {
+			problem += " (from " + origin.String() + ")"
+		}
+	}
+	v.problems = append(v.problems, problem)
+}
+
+// Validate checks the settings of the configuration whose invalid values
+// would only fail later at runtime, or be silently ignored: the regions, the
+// refresh interval, the choices and the endpoints. It returns a
+// ValidationError listing all the invalid settings, nil if there is none.
+func (c *Config) Validate() error {
+	v := &validator{config: c}
+
+	v.region("aws.default_region", c.AWS.DefaultRegion)
+	contexts := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	for _, name := range contexts {
+		if region := c.Contexts[name].Region; region != "" {
+			v.region("contexts."+name+".region", region)
+		}
+	}
+
+	if interval := c.AWS.RefreshInterval; interval < MinRefreshInterval || interval > MaxRefreshInterval {
+		v.addf("aws.refresh_interval", "%s is out of bounds, it must be between %s and %s", interval, MinRefreshInterval, MaxRefreshInterval)
+	}
+
+	v.choice("aws.retry_mode", c.AWS.Calls.RetryMode)
+	v.choice("ui.level", c.UI.Level)
+	v.choice("ui.confirm_destructive", c.UI.ConfirmDestructive)
+	v.choice("ui.theme", c.UI.Theme)
+	v.choice("ui.borders", c.UI.Borders)
+	v.clock("ui.day_start", c.UI.DayStart)
+	v.clock("ui.night_start", c.UI.NightStart)
+
+	if c.AWS.Calls.EndpointURL != "" {
+		v.endpoint("aws.endpoint_url", c.AWS.Calls.EndpointURL)
+	}
+	if c.Telemetry.Enabled {
+		v.choice("telemetry.exporter", c.Telemetry.Exporter)
+		if !strings.EqualFold(c.Telemetry.Exporter, "file") {
+			v.endpoint("telemetry.endpoint", c.Telemetry.Endpoint)
+		}
+	}
+	if c.Watch.Webhook != "" {
+		v.endpoint("watch.webhook", c.Watch.Webhook)
+	}
+
+	subsystems := make([]string, 0, len(c.Logging.Levels))
+	for name := range c.Logging.Levels {
+		subsystems = append(subsystems, name)
+	}
+	sort.Strings(subsystems)
+	for _, name := range subsystems {
+		key := "logging.levels." + name
+		if !slices.Contains(logger.Subsystems, name) {
+			v.addf(key, "unknown subsystem %q, expected one of %s", name, strings.Join(logger.Subsystems, ", "))
+		}
+		if !logger.Level(strings.ToLower(c.Logging.Levels[name])).Valid() {
+			v.addf(key, "unknown level %q, expected one of debug, info, warn, error", c.Logging.Levels[name])
+		}
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// region checks the name of a region
+func (v *validator) region(key, region string) {
+	if !regionPattern.MatchString(region) {
+		v.addf(key, "invalid region %q, expected a name such as us-east-1 or eu-west-3", region)
+	}
+}
+
+// choice checks that a setting is empty or has one of its accepted values,
+// regardless of the case
+func (v *validator) choice(key, value string) {
+	accepted := choices[key]
+	if value == "" || slices.Contains(accepted, strings.ToLower(value)) {
+		return
+	}
+	v.addf(key, "unknown value %q, expected one of %s", value, strings.Join(accepted, ", "))
+}
+
+// clock checks a time of the day of the form HH:MM
+func (v *validator) clock(key, value string) {
+	if _, err := time.Parse("15:04", strings.TrimSpace(value)); err != nil {
+		v.addf(key, "invalid time %q, expected HH:MM, e.g. 07:00", value)
+	}
+}
+
+// endpoint checks an HTTP endpoint, e.g. http://localhost:4318
+func (v *validator) endpoint(key, value string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(key, "invalid endpoint %q, expected an http or https URL, e.g. http://localhost:4318", value)
+	}
+}
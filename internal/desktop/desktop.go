@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package desktop
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrNoClipboard is returned when no clipboard tool is available
+var ErrNoClipboard = errors.New("no clipboard tool found (pbcopy, wl-copy, xclip, xsel or clip)")
+
+// ErrNoClipboardReader is returned when no tool reading the clipboard is
+// available
+var ErrNoClipboardReader = errors.New("no clipboard tool found (pbpaste, wl-paste, xclip, xsel or powershell)")
+
+// clipboardCommands are the commands used to write to the clipboard, by preference order
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+	{"clip"},
+}
+
+// CopyToClipboard writes text to the system clipboard using the first
+// clipboard tool available
+func CopyToClipboard(text string) error {
+	for _, command := range clipboardCommands {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+
+	return ErrNoClipboard
+}
+
+// pasteCommands are the commands used to read the clipboard, by preference
+// order
+var pasteCommands = [][]string{
+	{"pbpaste"},
+	{"wl-paste", "--no-newline"},
+	{"xclip", "-selection", "clipboard", "-o"},
+	{"xsel", "--clipboard", "--output"},
+	{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+}
+
+// ReadClipboard reads the text of the system clipboard using the first
+// clipboard tool available
+func ReadClipboard() (string, error) {
+	for _, command := range pasteCommands {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+
+		output, err := exec.Command(path, command[1:]...).Output()
+		return string(output), err
+	}
+
+	return "", ErrNoClipboardReader
+}
+
+// OpenURL opens an URL in the default browser
+func OpenURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	return cmd.Start()
+}
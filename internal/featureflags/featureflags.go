@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package featureflags evaluates the feature flags of the configuration
+// against the context of the session: the AWS profile, the region and the
+// caller, to roll a feature out gradually, to some profiles, regions or
+// users first.
+package featureflags
+
+import (
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nlamirault/e2c/internal/config"
+)
+
+// Flags consulted by the UI
+const (
+	// BulkActions enables marking the instances and the actions on several
+	// instances at once, on unless configured
+	BulkActions = "bulk_actions"
+	// RefreshIntervalOverride replaces aws.refresh_interval by its value
+	RefreshIntervalOverride = "refresh_interval_override"
+)
+
+// Context is the session the flags are evaluated against
+type Context struct {
+	Profile string
+	Region  string
+	User    string // ARN of the caller, empty until it is known
+}
+
+// Flags are the feature flags of the configuration, by name
+type Flags struct {
+	flags map[string]config.FlagConfig
+}
+
+// New creates the flags of the configuration
+func New(flags map[string]config.FlagConfig) *Flags {
+	return &Flags{flags: flags}
+}
+
+// Enabled returns whether a flag is on in a context, or the given default
+// if it is not configured
+func (f *Flags) Enabled(name string, ctx Context, def bool) bool {
+	flag, ok := f.flags[name]
+	if !ok {
+		return def
+	}
+	return on(name, flag, ctx)
+}
+
+// Value returns the value of a flag on in a context, empty if it is off or
+// not configured
+func (f *Flags) Value(name string, ctx Context) string {
+	flag, ok := f.flags[name]
+	if !ok || !on(name, flag, ctx) {
+		return ""
+	}
+	return flag.Value
+}
+
+// On returns the names of the configured flags on in a context, sorted
+func (f *Flags) On(ctx Context) []string {
+	var names []string
+	for name, flag := range f.flags {
+		if on(name, flag, ctx) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// on returns whether a flag is on in a context: enabled, and matching all
+// its rules. The rules on the users are not matched until the caller is
+// known.
+func on(name string, flag config.FlagConfig, ctx Context) bool {
+	if !flag.Enabled {
+		return false
+	}
+	if !matchesAny(flag.Profiles, ctx.Profile) ||
+		!matchesAny(flag.Regions, ctx.Region) ||
+		!matchesAny(flag.Users, ctx.User) {
+		return false
+	}
+	if flag.Percentage <= 0 || flag.Percentage >= 100 {
+		return true
+	}
+	return ctx.User != "" && bucket(name, ctx.User) < flag.Percentage
+}
+
+// matchesAny returns true if there is no pattern, or if the value matches
+// one of them, with * matching any characters
+func matchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	if value == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		if matched, _ := regexp.MatchString(expr, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket returns the bucket of a user for a flag, from 0 to 99, stable
+// across the sessions so that the same users keep the flag on as its
+// percentage grows
+func bucket(name, user string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + "/" + user))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package featureflags
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/nlamirault/e2c/internal/config"
+)
+
+const testUser = "arn:aws:iam::123456789012:user/alice"
+
+func TestEnabled(t *testing.T) {
+	flags := New(map[string]config.FlagConfig{
+		"on":  {Enabled: true},
+		"off": {Enabled: false},
+		"prod": {
+			Enabled:  true,
+			Profiles: []string{"prod-*", "ops"},
+		},
+		"europe": {
+			Enabled: true,
+			Regions: []string{"eu-*"},
+		},
+		"admins": {
+			Enabled: true,
+			Users:   []string{"arn:aws:iam::*:role/admin*"},
+		},
+		"all rules": {
+			Enabled:  true,
+			Profiles: []string{"prod-*"},
+			Regions:  []string{"eu-west-1"},
+		},
+		"disabled rules": {
+			Enabled:  false,
+			Profiles: []string{"prod-*"},
+		},
+		"literal": {
+			Enabled:  true,
+			Profiles: []string{"a.b"},
+		},
+	})
+	ctx := Context{Profile: "prod-eu", Region: "eu-west-1", User: testUser}
+
+	tests := []struct {
+		name string
+		flag string
+		ctx  Context
+		def  bool
+		want bool
+	}{
+		{name: "unknown, default off", flag: "unknown", ctx: ctx, def: false, want: false},
+		{name: "unknown, default on", flag: "unknown", ctx: ctx, def: true, want: true},
+		{name: "enabled", flag: "on", ctx: ctx, want: true},
+		{name: "disabled overrides default", flag: "off", ctx: ctx, def: true, want: false},
+		{name: "profile glob", flag: "prod", ctx: ctx, want: true},
+		{name: "profile exact", flag: "prod", ctx: Context{Profile: "ops"}, want: true},
+		{name: "profile mismatch", flag: "prod", ctx: Context{Profile: "dev"}, want: false},
+		{name: "profile glob anchored", flag: "prod", ctx: Context{Profile: "my-prod-eu"}, want: false},
+		{name: "profile unknown", flag: "prod", ctx: Context{}, want: false},
+		{name: "region glob", flag: "europe", ctx: ctx, want: true},
+		{name: "region mismatch", flag: "europe", ctx: Context{Region: "us-east-1"}, want: false},
+		{name: "user glob", flag: "admins", ctx: Context{User: "arn:aws:iam::123456789012:role/administrator"}, want: true},
+		{name: "user mismatch", flag: "admins", ctx: ctx, want: false},
+		{name: "user not known yet", flag: "admins", ctx: Context{}, want: false},
+		{name: "all rules match", flag: "all rules", ctx: ctx, want: true},
+		{name: "one rule mismatch", flag: "all rules", ctx: Context{Profile: "prod-eu", Region: "eu-west-3"}, want: false},
+		{name: "disabled with matching rules", flag: "disabled rules", ctx: ctx, want: false},
+		{name: "pattern metacharacters", flag: "literal", ctx: Context{Profile: "axb"}, want: false},
+		{name: "pattern literal", flag: "literal", ctx: Context{Profile: "a.b"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flags.Enabled(tt.flag, tt.ctx, tt.def); got != tt.want {
+				t.Errorf("Enabled(%q, %+v, %t) = %t, want %t", tt.flag, tt.ctx, tt.def, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	flags := New(map[string]config.FlagConfig{
+		RefreshIntervalOverride: {Enabled: true, Regions: []string{"eu-*"}, Value: "10s"},
+		"off":                   {Enabled: false, Value: "1m"},
+		"no value":              {Enabled: true},
+	})
+
+	tests := []struct {
+		name string
+		flag string
+		ctx  Context
+		want string
+	}{
+		{name: "on", flag: RefreshIntervalOverride, ctx: Context{Region: "eu-west-1"}, want: "10s"},
+		{name: "rule mismatch", flag: RefreshIntervalOverride, ctx: Context{Region: "us-east-1"}, want: ""},
+		{name: "disabled", flag: "off", want: ""},
+		{name: "on without value", flag: "no value", want: ""},
+		{name: "unknown", flag: "unknown", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flags.Value(tt.flag, tt.ctx); got != tt.want {
+				t.Errorf("Value(%q, %+v) = %q, want %q", tt.flag, tt.ctx, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOn(t *testing.T) {
+	flags := New(map[string]config.FlagConfig{
+		"zeta":  {Enabled: true},
+		"alpha": {Enabled: true, Profiles: []string{"prod"}},
+		"beta":  {Enabled: false},
+		"gamma": {Enabled: true, Regions: []string{"us-*"}},
+	})
+
+	if got, want := flags.On(Context{Profile: "prod", Region: "eu-west-1"}), []string{"alpha", "zeta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("On() = %q, want %q", got, want)
+	}
+	if got := New(nil).On(Context{}); len(got) != 0 {
+		t.Errorf("On() without flags = %q, want none", got)
+	}
+}
+
+func TestPercentage(t *testing.T) {
+	users := make([]string, 1000)
+	for i := range users {
+		users[i] = fmt.Sprintf("arn:aws:iam::123456789012:user/user-%d", i)
+	}
+	// count returns the number of users with the flag on at a percentage
+	count := func(name string, percentage int) int {
+		flags := New(map[string]config.FlagConfig{name: {Enabled: true, Percentage: percentage}})
+		n := 0
+		for _, user := range users {
+			if flags.Enabled(name, Context{User: user}, false) {
+				n++
+			}
+		}
+		return n
+	}
+
+	tests := []struct {
+		percentage int
+		min, max   int
+	}{
+		{percentage: 0, min: 1000, max: 1000},
+		{percentage: 100, min: 1000, max: 1000},
+		{percentage: 150, min: 1000, max: 1000},
+		{percentage: 10, min: 50, max: 150},
+		{percentage: 50, min: 420, max: 580},
+		{percentage: 90, min: 850, max: 950},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.percentage), func(t *testing.T) {
+			if got := count("rollout", tt.percentage); got < tt.min || got > tt.max {
+				t.Errorf("%d%% on for %d users, want between %d and %d", tt.percentage, got, tt.min, tt.max)
+			}
+		})
+	}
+
+	t.Run("unknown user", func(t *testing.T) {
+		flags := New(map[string]config.FlagConfig{"rollout": {Enabled: true, Percentage: 99}})
+		if flags.Enabled("rollout", Context{}, true) {
+			t.Error("Enabled() = true for an unknown caller, want false until the caller is known")
+		}
+	})
+
+	t.Run("stable as the percentage grows", func(t *testing.T) {
+		small := New(map[string]config.FlagConfig{"rollout": {Enabled: true, Percentage: 20}})
+		large := New(map[string]config.FlagConfig{"rollout": {Enabled: true, Percentage: 60}})
+		for _, user := range users {
+			ctx := Context{User: user}
+			if small.Enabled("rollout", ctx, false) && !large.Enabled("rollout", ctx, false) {
+				t.Fatalf("%s lost the flag when its percentage grew", user)
+			}
+			if small.Enabled("rollout", ctx, false) != small.Enabled("rollout", ctx, false) {
+				t.Fatalf("%s has an unstable bucket", user)
+			}
+		}
+	})
+}
@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package keymap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SourceDefault is the source of a binding which is not overridden
+	SourceDefault = "default"
+	// SourceUser is the source of a binding set in the keymap file
+	SourceUser = "user"
+)
+
+// Binding is a key bound to an action of the instances view
+type Binding struct {
+	Action      string
+	Key         string
+	Description string
+	Source      string // default or user
+}
+
+// defaults are the actions of the instances view with their default key,
+// in the order they are listed
+var defaults = []Binding{
+	{Action: "help", Key: "?", Description: "Help"},
+	{Action: "quit", Key: "q", Description: "Quit"},
+	{Action: "refresh", Key: "r", Description: "Refresh instances"},
+	{Action: "filter", Key: "f", Description: "Filter instances"},
+	{Action: "find", Key: "ctrl-p", Description: "Find an instance by name, ID or IP and select it"},
+	{Action: "search", Key: "ctrl-f", Description: "Search the instances of all the cached regions and profiles"},
+	{Action: "start", Key: "s", Description: "Start selected instance"},
+	{Action: "stop", Key: "p", Description: "Stop selected instance"},
+	{Action: "reboot", Key: "b", Description: "Reboot selected instance"},
+	{Action: "terminate", Key: "t", Description: "Terminate selected instance"},
+	{Action: "connect", Key: "c", Description: "Connect to selected instance via SSH"},
+	{Action: "logs", Key: "l", Description: "View instance logs/console output"},
+	{Action: "sort", Key: "o", Description: "Cycle sort column"},
+	{Action: "sort-order", Key: "O", Description: "Reverse sort order"},
+	{Action: "group", Key: "g", Description: "Cycle grouping (state, zone, type, stack, ASG, tag columns)"},
+	{Action: "mark", Key: "space", Description: "Mark/unmark instance for batch actions"},
+	{Action: "mark-all", Key: "ctrl-a", Description: "Mark/unmark all displayed instances"},
+	{Action: "cancel-batch", Key: "X", Description: "Cancel the running batch action"},
+	{Action: "vpcs", Key: "V", Description: "Show the VPCs and subnets"},
+	{Action: "start-group", Key: "S", Description: "Start instances tier by tier (e2c:start-order tag)"},
+	{Action: "stop-environment", Key: "E", Description: "Stop all the instances of an environment (tag selector)"},
+	{Action: "restore-snapshot", Key: "B", Description: "Restore the root volume from a snapshot"},
+	{Action: "health", Key: "H", Description: "Show the AWS Health events affecting EC2"},
+	{Action: "refresh-slower", Key: "+", Description: "Increase the auto-refresh interval"},
+	{Action: "refresh-faster", Key: "-", Description: "Decrease the auto-refresh interval"},
+	{Action: "refresh-pause", Key: "R", Description: "Pause/resume the auto-refresh"},
+	{Action: "command", Key: ":", Description: "Command prompt (e.g. :refresh 10s)"},
+	{Action: "latency", Key: "L", Description: "Show/hide the latency of the last action"},
+	{Action: "export", Key: "e", Description: "Export the instances displayed to CSV, JSON or YAML"},
+	{Action: "manifest", Key: "y", Description: "Show the raw JSON/YAML description of selected instance"},
+	{Action: "reachability", Key: "A", Description: "Analyze the reachability of a destination from selected instance"},
+	{Action: "run-command", Key: "!", Description: "Run a shell command on the marked or selected instances with SSM"},
+	{Action: "port-forward", Key: "F", Description: "Forward a local port to selected instance with SSM"},
+	{Action: "sessions", Key: "T", Description: "List the port forwarding sessions"},
+	{Action: "errors", Key: "W", Description: "Review the errors of the session"},
+	{Action: "group-actions", Key: "G", Description: "Stop, tag or protect all the instances of the selected stack or ASG"},
+	{Action: "unlock-filter", Key: "U", Description: "Clear the filter locked with --filter"},
+	{Action: "authorize-key", Key: "K", Description: "Add an SSH public key to the authorized_keys of selected instance with SSM"},
+	{Action: "watch", Key: "w", Description: "Watch selected instance, notified when its state or status checks change"},
+}
+
+// File is the content of a keymap file: the keys of the actions which are
+// not bound to their default key
+type File struct {
+	Keys map[string]string `yaml:"keys"`
+}
+
+// Keymap binds the keys to the actions of the instances view
+type Keymap struct {
+	bindings []Binding
+	actions  map[string]string // Action by key
+}
+
+// DefaultPath returns the default path of the keymap file,
+// ~/.config/e2c/keymap.yaml
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "keymap.yaml"
+	}
+	return filepath.Join(home, ".config", "e2c", "keymap.yaml")
+}
+
+// Default returns the keymap without any override
+func Default() *Keymap {
+	keymap, _ := New(nil)
+	return keymap
+}
+
+// New creates a keymap from the defaults and the keys overridden by the user,
+// by action. It fails on unknown actions, invalid keys, and keys bound to
+// several actions.
+func New(overrides map[string]string) (*Keymap, error) {
+	known := make(map[string]bool, len(defaults))
+	for _, binding := range defaults {
+		known[binding.Action] = true
+	}
+
+	var errs []error
+	for action, key := range overrides {
+		if !known[action] {
+			errs = append(errs, fmt.Errorf("unknown action %q", action))
+		}
+		if err := ValidateKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("action %s: %w", action, err))
+		}
+	}
+
+	k := &Keymap{
+		bindings: make([]Binding, 0, len(defaults)),
+		actions:  make(map[string]string, len(defaults)),
+	}
+	for _, binding := range defaults {
+		if key, ok := overrides[binding.Action]; ok && key != binding.Key {
+			binding.Key = key
+			binding.Source = SourceUser
+		} else {
+			binding.Source = SourceDefault
+		}
+
+		if other, ok := k.actions[binding.Key]; ok {
+			errs = append(errs, fmt.Errorf("key %s is bound to both %s and %s", binding.Key, other, binding.Action))
+		}
+		k.actions[binding.Key] = binding.Action
+		k.bindings = append(k.bindings, binding)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return k, nil
+}
+
+// Load reads the keymap file at the given path, or at the default one if
+// empty. Without a keymap file, the default keymap is returned.
+func Load(path string) (*Keymap, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+
+	file, err := Read(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keymap, err := New(file.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keymap %s: %w", path, err)
+	}
+	return keymap, nil
+}
+
+// Read reads a keymap file
+func Read(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keymap %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Import validates the keymap file at src and installs it at dst, replacing
+// the current one
+func Import(src, dst string) (*Keymap, error) {
+	file, err := Read(src)
+	if err != nil {
+		return nil, err
+	}
+	keymap, err := New(file.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keymap %s: %w", src, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the keymap directory: %w", err)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create keymap %s: %w", dst, err)
+	}
+	defer f.Close()
+
+	if err := keymap.Export(f, false); err != nil {
+		return nil, err
+	}
+	return keymap, f.Close()
+}
+
+// Action returns the action bound to a key, empty if none
+func (k *Keymap) Action(key string) string {
+	return k.actions[key]
+}
+
+// Key returns the key bound to an action
+func (k *Keymap) Key(action string) string {
+	for _, binding := range k.bindings {
+		if binding.Action == action {
+			return binding.Key
+		}
+	}
+	return ""
+}
+
+// Bindings returns the bindings of every action
+func (k *Keymap) Bindings() []Binding {
+	return k.bindings
+}
+
+// Export writes the keymap as a keymap file. With all, every binding is
+// written, otherwise only the ones overridden by the user.
+func (k *Keymap) Export(w io.Writer, all bool) error {
+	var b strings.Builder
+	b.WriteString("# e2c keymap: key bound to each action of the instances view.\n")
+	b.WriteString("# Keys are a character, space, or ctrl-a to ctrl-z.\n")
+	b.WriteString("keys:\n")
+	for _, binding := range k.bindings {
+		if !all && binding.Source != SourceUser {
+			continue
+		}
+		key, err := yaml.Marshal(binding.Key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "  %s: %s", binding.Action, key)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ValidateKey checks that a key can be bound: a single character, space, or
+// ctrl- followed by a letter. ctrl-h, ctrl-i and ctrl-m are not allowed as
+// terminals send them for Backspace, Tab and Enter.
+func ValidateKey(key string) error {
+	if len([]rune(key)) == 1 && key != " " {
+		return nil
+	}
+	if key == "space" {
+		return nil
+	}
+	if letter, ok := strings.CutPrefix(key, "ctrl-"); ok && len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z' {
+		if strings.Contains("him", letter) {
+			return fmt.Errorf("key %s is sent by terminals for another key", key)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid key %q", key)
+}
+
+// Display returns the name of a key as displayed in the help, e.g. Ctrl-A
+func Display(key string) string {
+	switch {
+	case key == "space":
+		return "Space"
+	case strings.HasPrefix(key, "ctrl-"):
+		return "Ctrl-" + strings.ToUpper(strings.TrimPrefix(key, "ctrl-"))
+	default:
+		return key
+	}
+}
@@ -0,0 +1,292 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package keymap
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sources returns the source of the binding of each action
+func sources(k *Keymap) map[string]string {
+	byAction := make(map[string]string, len(k.Bindings()))
+	for _, binding := range k.Bindings() {
+		byAction[binding.Action] = binding.Source
+	}
+	return byAction
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		keys      map[string]string // Key by action
+		user      []string          // Actions overridden by the user
+		wantErr   []string
+	}{
+		{
+			name: "defaults",
+			keys: map[string]string{"refresh": "r", "stop": "p", "mark": "space"},
+		},
+		{
+			name:      "overridden",
+			overrides: map[string]string{"refresh": "ctrl-r", "stop": "x"},
+			keys:      map[string]string{"refresh": "ctrl-r", "stop": "x", "start": "s"},
+			user:      []string{"refresh", "stop"},
+		},
+		{
+			name:      "default key",
+			overrides: map[string]string{"refresh": "r"},
+			keys:      map[string]string{"refresh": "r"},
+		},
+		{
+			name:      "keys swapped",
+			overrides: map[string]string{"start": "p", "stop": "s"},
+			keys:      map[string]string{"start": "p", "stop": "s"},
+			user:      []string{"start", "stop"},
+		},
+		{
+			name:      "unknown action",
+			overrides: map[string]string{"explode": "x"},
+			wantErr:   []string{`unknown action "explode"`},
+		},
+		{
+			name:      "invalid key",
+			overrides: map[string]string{"refresh": "F5"},
+			wantErr:   []string{`action refresh: invalid key "F5"`},
+		},
+		{
+			name:      "key bound twice",
+			overrides: map[string]string{"refresh": "p"},
+			wantErr:   []string{"key p is bound to both refresh and stop"},
+		},
+		{
+			name:      "errors joined",
+			overrides: map[string]string{"explode": "x", "refresh": "ctrl-m"},
+			wantErr:   []string{`unknown action "explode"`, "key ctrl-m is sent by terminals for another key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := New(tt.overrides)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("New() error = nil, want %q", tt.wantErr)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("New() error = %v, want %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			for action, key := range tt.keys {
+				if got := k.Key(action); got != key {
+					t.Errorf("Key(%s) = %q, want %q", action, got, key)
+				}
+				if got := k.Action(key); got != action {
+					t.Errorf("Action(%q) = %q, want %s", key, got, action)
+				}
+			}
+
+			// The sources listed by :keys
+			want := sources(Default())
+			for _, action := range tt.user {
+				want[action] = SourceUser
+			}
+			if got := sources(k); !reflect.DeepEqual(got, want) {
+				t.Errorf("sources = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	k := Default()
+	if got := len(k.Bindings()); got != len(defaults) {
+		t.Fatalf("Bindings() = %d bindings, want %d", got, len(defaults))
+	}
+	for i, binding := range k.Bindings() {
+		if binding.Action != defaults[i].Action || binding.Source != SourceDefault {
+			t.Errorf("binding %d = %+v, want the default of %s", i, binding, defaults[i].Action)
+		}
+		if err := ValidateKey(binding.Key); err != nil {
+			t.Errorf("default key of %s: %v", binding.Action, err)
+		}
+	}
+	if got := k.Action("F"); got != "port-forward" {
+		t.Errorf("Action(F) = %q, want port-forward", got)
+	}
+	if got := k.Action("Z"); got != "" {
+		t.Errorf("Action(Z) = %q, want none", got)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	overrides := map[string]string{"refresh": "ctrl-r", "stop": "x", "mark": ":", "command": "space"}
+	exported, err := New(overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		all  bool
+	}{
+		{name: "overrides"},
+		{name: "all", all: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "shared.yaml")
+			var out bytes.Buffer
+			if err := exported.Export(&out, tt.all); err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			if err := os.WriteFile(src, out.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := Read(src)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			want := len(overrides)
+			if tt.all {
+				want = len(defaults)
+			}
+			if len(file.Keys) != want {
+				t.Errorf("exported %d keys, want %d:\n%s", len(file.Keys), want, out.String())
+			}
+
+			// Imported, then loaded as e2c does at startup
+			dst := filepath.Join(dir, "e2c", "keymap.yaml")
+			imported, err := Import(src, dst)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			loaded, err := Load(dst)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			for _, k := range []*Keymap{imported, loaded} {
+				if !reflect.DeepEqual(k.Bindings(), exported.Bindings()) {
+					t.Errorf("Bindings() = %+v, want %+v", k.Bindings(), exported.Bindings())
+				}
+			}
+
+			// The installed keymap holds the overrides only
+			installed, err := Read(dst)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !reflect.DeepEqual(installed.Keys, overrides) {
+				t.Errorf("installed keys = %v, want %v", installed.Keys, overrides)
+			}
+		})
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "keymap.yaml")
+	if err := os.WriteFile(dst, []byte("keys:\n  refresh: ctrl-r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "yaml", content: "keys: [", want: "failed to parse keymap"},
+		{name: "conflict", content: "keys:\n  refresh: p\n", want: "key p is bound to both refresh and stop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := filepath.Join(dir, tt.name+".yaml")
+			if err := os.WriteFile(src, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Import(src, dst); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Import() error = %v, want %q", err, tt.want)
+			}
+
+			// The current keymap is kept
+			k, err := Load(dst)
+			if err != nil || k.Key("refresh") != "ctrl-r" {
+				t.Errorf("Load() = %v, %v, want the keymap before the import", k, err)
+			}
+		})
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	k, err := Load(filepath.Join(t.TempDir(), "keymap.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(k.Bindings(), Default().Bindings()) {
+		t.Errorf("Load() = %+v, want the default keymap", k.Bindings())
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{key: "r", valid: true},
+		{key: "?", valid: true},
+		{key: "é", valid: true},
+		{key: "space", valid: true},
+		{key: "ctrl-a", valid: true},
+		{key: "ctrl-z", valid: true},
+		{key: ""},
+		{key: " "},
+		{key: "rr"},
+		{key: "ctrl-"},
+		{key: "ctrl-A"},
+		{key: "ctrl-1"},
+		{key: "ctrl-h"},
+		{key: "ctrl-i"},
+		{key: "ctrl-m"},
+		{key: "alt-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if err := ValidateKey(tt.key); (err == nil) != tt.valid {
+				t.Errorf("ValidateKey(%q) error = %v, want valid %v", tt.key, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "r", want: "r"},
+		{key: "space", want: "Space"},
+		{key: "ctrl-p", want: "Ctrl-P"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := Display(tt.key); got != tt.want {
+				t.Errorf("Display(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -27,6 +27,28 @@ const (
 	ErrorLevel Level = "error"
 )
 
+// Valid checks if a level is valid
+func (l Level) Valid() bool {
+	return l == DebugLevel || l == InfoLevel || l == WarnLevel || l == ErrorLevel
+}
+
+// slogLevel converts the level to a slog level, info if it is unknown
+func (l Level) slogLevel() slog.Level {
+	switch l {
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown log level %q, defaulting to info\n", l)
+		return slog.LevelInfo
+	}
+}
+
 // Format represents the log output format
 type Format string
 
@@ -51,6 +73,9 @@ func (f Format) Valid() bool {
 type Config struct {
 	// Level is the logging level (debug, info, warn, error)
 	Level Level
+	// Levels are the logging levels of the subsystems, by name, overriding
+	// Level for their logs
+	Levels map[string]Level
 	// Format is the output format (text, json)
 	Format Format
 	// Output is the destination for logs (defaults to stdout)
@@ -76,39 +101,27 @@ func New(cfg *Config) *slog.Logger {
 		cfg = NewConfig()
 	}
 
-	// Convert string level to slog.Level
-	var level slog.Level
-	switch cfg.Level {
-	case DebugLevel:
-		level = slog.LevelDebug
-	case InfoLevel:
-		level = slog.LevelInfo
-	case WarnLevel:
-		level = slog.LevelWarn
-	case ErrorLevel:
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-		fmt.Fprintf(os.Stderr, "Unknown log level %q, defaulting to info\n", cfg.Level)
-	}
-
-	// Set up handler based on format
+	// Set up handler based on format, the records being filtered by the
+	// level of their subsystem before
 	var handler slog.Handler
 	if cfg.Format == JSONFormat {
 		handler = slog.NewJSONHandler(cfg.Output, &slog.HandlerOptions{
-			Level:     level,
+			Level:     slog.LevelDebug,
 			AddSource: cfg.AddSource,
 		})
 	} else {
 		handler = tint.NewHandler(cfg.Output, &tint.Options{
-			Level:      level,
+			Level:      slog.LevelDebug,
 			AddSource:  cfg.AddSource,
 			TimeFormat: time.RFC3339,
 		})
 	}
 
 	// Create and return logger
-	logger := slog.New(handler)
+	logger := slog.New(&levelHandler{
+		handler: handler,
+		levels:  newLevels(cfg.Level, cfg.Levels),
+	})
 	return logger
 }
 
@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// SubsystemKey is the attribute naming the subsystem of a logger, whose
+// logs are filtered by the level of the subsystem
+const SubsystemKey = "subsystem"
+
+// Subsystems of e2c with a logging level of their own
+const (
+	// SubsystemAWS logs the calls to AWS
+	SubsystemAWS = "aws"
+	// SubsystemUI logs the views and the actions of the user
+	SubsystemUI = "ui"
+	// SubsystemAudit logs the audit log of the mutating actions
+	SubsystemAudit = "audit"
+	// SubsystemPlugin logs the plugin columns and hooks
+	SubsystemPlugin = "plugin"
+	// SubsystemSchedule logs the local schedules
+	SubsystemSchedule = "schedule"
+	// SubsystemTunnel logs the port forwarding sessions
+	SubsystemTunnel = "tunnel"
+	// SubsystemWatch logs the checks of the instances watched
+	SubsystemWatch = "watch"
+)
+
+// Subsystems are the names of the subsystems
+var Subsystems = []string{
+	SubsystemAWS,
+	SubsystemUI,
+	SubsystemAudit,
+	SubsystemPlugin,
+	SubsystemSchedule,
+	SubsystemTunnel,
+	SubsystemWatch,
+}
+
+// Subsystem returns the logger of a subsystem, whose logs are filtered by
+// the level of the subsystem if one is set
+func Subsystem(log *slog.Logger, name string) *slog.Logger {
+	return log.With(SubsystemKey, name)
+}
+
+// SetLevels sets the logging levels of the subsystems of a logger created by
+// New, and of all the loggers derived from it, e.g. once the configuration
+// is loaded. It returns an error if the logger was not created by New.
+func SetLevels(log *slog.Logger, subsystems map[string]Level) error {
+	h, ok := log.Handler().(*levelHandler)
+	if !ok {
+		return fmt.Errorf("the levels of the subsystems cannot be set on a %T", log.Handler())
+	}
+	h.levels.set(subsystems)
+	return nil
+}
+
+// levels are the logging level and the levels of the subsystems, shared by
+// a logger and the loggers derived from it
+type levels struct {
+	mutex      sync.RWMutex
+	level      slog.Level
+	subsystems map[string]slog.Level
+}
+
+// newLevels creates the levels from the logging level and the ones of the
+// subsystems
+func newLevels(level Level, subsystems map[string]Level) *levels {
+	l := &levels{level: level.slogLevel()}
+	l.set(subsystems)
+	return l
+}
+
+// set replaces the levels of the subsystems
+func (l *levels) set(subsystems map[string]Level) {
+	converted := make(map[string]slog.Level, len(subsystems))
+	for name, level := range subsystems {
+		converted[name] = level.slogLevel()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.subsystems = converted
+}
+
+// of returns the level of a subsystem, the logging level if it has none
+func (l *levels) of(subsystem string) slog.Level {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if level, ok := l.subsystems[subsystem]; ok {
+		return level
+	}
+	return l.level
+}
+
+// lowest returns the lowest of the logging level and of the levels of the
+// subsystems
+func (l *levels) lowest() slog.Level {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	lowest := l.level
+	for _, level := range l.subsystems {
+		lowest = min(lowest, level)
+	}
+	return lowest
+}
+
+// levelHandler filters the records by the level of their subsystem, bound
+// to the logger with Subsystem or given as an attribute of the record,
+// before passing them to the handler writing them
+type levelHandler struct {
+	handler   slog.Handler
+	levels    *levels
+	subsystem string // Subsystem of the logger, empty if unknown
+	grouped   bool   // The attributes are in a group, and name no subsystem
+}
+
+// Enabled returns true if a record of the level may be written. Without a
+// subsystem bound to the logger, the records are filtered once their
+// attributes are known.
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.subsystem != "" {
+		return level >= h.levels.of(h.subsystem) && h.handler.Enabled(ctx, level)
+	}
+	return level >= h.levels.lowest() && h.handler.Enabled(ctx, level)
+}
+
+// Handle writes a record if its level is at least the one of its subsystem
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	subsystem := h.subsystem
+	if subsystem == "" {
+		record.Attrs(func(attr slog.Attr) bool {
+			if attr.Key == SubsystemKey {
+				subsystem = attr.Value.String()
+				return false
+			}
+			return true
+		})
+	}
+	if record.Level < h.levels.of(subsystem) {
+		return nil
+	}
+	return h.handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler with the attributes, bound to the subsystem
+// if they name one
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := *h
+	derived.handler = h.handler.WithAttrs(attrs)
+	if !h.grouped {
+		for _, attr := range attrs {
+			if attr.Key == SubsystemKey {
+				derived.subsystem = attr.Value.String()
+			}
+		}
+	}
+	return &derived
+}
+
+// WithGroup returns a handler with a group, the attributes added after it
+// naming no subsystem
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	derived := *h
+	derived.handler = h.handler.WithGroup(name)
+	derived.grouped = true
+	return &derived
+}
@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// newTestLogger returns a logger filtering by the levels as New does, and
+// the buffer the records are written to
+func newTestLogger(level Level, subsystems map[string]Level) (*slog.Logger, *bytes.Buffer) {
+	var out bytes.Buffer
+	handler := slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return slog.New(&levelHandler{handler: handler, levels: newLevels(level, subsystems)}), &out
+}
+
+// messages returns the messages of the records written
+func messages(t *testing.T, out *bytes.Buffer) []string {
+	t.Helper()
+	var msgs []string
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var record struct{ Msg string }
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("record %q is not JSON: %v", scanner.Text(), err)
+		}
+		msgs = append(msgs, record.Msg)
+	}
+	return msgs
+}
+
+func TestLevelHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(log *slog.Logger)
+		want []string
+	}{
+		{
+			name: "subsystem at debug",
+			log: func(log *slog.Logger) {
+				aws := Subsystem(log, SubsystemAWS)
+				aws.Debug("aws debug")
+				aws.Info("aws info")
+			},
+			want: []string{"aws debug", "aws info"},
+		},
+		{
+			name: "subsystem at warn",
+			log: func(log *slog.Logger) {
+				ui := Subsystem(log, SubsystemUI)
+				ui.Debug("ui debug")
+				ui.Info("ui info")
+				ui.Warn("ui warn")
+				ui.Error("ui error")
+			},
+			want: []string{"ui warn", "ui error"},
+		},
+		{
+			name: "subsystems interleaved",
+			log: func(log *slog.Logger) {
+				aws, ui := Subsystem(log, SubsystemAWS), Subsystem(log, SubsystemUI)
+				ui.Info("render")
+				aws.Debug("DescribeInstances")
+				ui.Warn("slow render")
+				aws.Debug("StopInstances")
+			},
+			want: []string{"DescribeInstances", "slow render", "StopInstances"},
+		},
+		{
+			name: "without subsystem",
+			log: func(log *slog.Logger) {
+				log.Debug("debug")
+				log.Info("info")
+			},
+			want: []string{"info"},
+		},
+		{
+			name: "subsystem without level",
+			log: func(log *slog.Logger) {
+				audit := Subsystem(log, SubsystemAudit)
+				audit.Debug("audit debug")
+				audit.Info("audit info")
+			},
+			want: []string{"audit info"},
+		},
+		{
+			name: "subsystem of the record",
+			log: func(log *slog.Logger) {
+				log.Debug("aws debug", SubsystemKey, SubsystemAWS)
+				log.Info("ui info", SubsystemKey, SubsystemUI)
+			},
+			want: []string{"aws debug"},
+		},
+		{
+			name: "derived logger",
+			log: func(log *slog.Logger) {
+				aws := Subsystem(log, SubsystemAWS).With("region", "eu-west-1").WithGroup("call")
+				aws.Debug("aws debug", "operation", "DescribeInstances")
+			},
+			want: []string{"aws debug"},
+		},
+		{
+			name: "subsystem in a group",
+			log: func(log *slog.Logger) {
+				log.WithGroup("request").With(SubsystemKey, SubsystemAWS).Debug("grouped debug")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, out := newTestLogger(InfoLevel, map[string]Level{
+				SubsystemAWS: DebugLevel,
+				SubsystemUI:  WarnLevel,
+			})
+			tt.log(log)
+			if got := messages(t, out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelHandlerEnabled(t *testing.T) {
+	log, _ := newTestLogger(InfoLevel, map[string]Level{
+		SubsystemAWS: DebugLevel,
+		SubsystemUI:  WarnLevel,
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		log   *slog.Logger
+		level slog.Level
+		want  bool
+	}{
+		{name: "aws debug", log: Subsystem(log, SubsystemAWS), level: slog.LevelDebug, want: true},
+		{name: "ui info", log: Subsystem(log, SubsystemUI), level: slog.LevelInfo, want: false},
+		{name: "ui warn", log: Subsystem(log, SubsystemUI), level: slog.LevelWarn, want: true},
+		// Without subsystem, the record may name one at a lower level
+		{name: "debug", log: log, level: slog.LevelDebug, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.log.Enabled(ctx, tt.level); got != tt.want {
+				t.Errorf("Enabled(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLevels(t *testing.T) {
+	log, out := newTestLogger(InfoLevel, nil)
+	aws, ui := Subsystem(log, SubsystemAWS), Subsystem(log, SubsystemUI)
+	aws.Debug("before")
+
+	// The loggers derived before are filtered by the new levels
+	if err := SetLevels(log, map[string]Level{SubsystemAWS: DebugLevel, SubsystemUI: ErrorLevel}); err != nil {
+		t.Fatalf("SetLevels() error = %v", err)
+	}
+	aws.Debug("after")
+	ui.Warn("ui warn")
+	ui.Error("ui error")
+
+	if got, want := messages(t, out), []string{"after", "ui error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+
+	if err := SetLevels(slog.New(slog.NewTextHandler(io.Discard, nil)), nil); err == nil {
+		t.Error("SetLevels() error = nil on a logger not created by New")
+	}
+}
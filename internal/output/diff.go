@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// ReadInstances reads an inventory of instances written in JSON or YAML,
+// depending on its extension, by the list command or the export of the UI
+func ReadInstances(path string) ([]model.Instance, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	var instances []model.Instance
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &instances)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &instances)
+	default:
+		return nil, fmt.Errorf("unsupported inventory extension %q (expected .json or .yaml)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+	}
+	return instances, nil
+}
+
+// Diff returns the result of the comparison of two inventories
+func Diff(diffs []model.InstanceDiff) *Result {
+	result := &Result{
+		Columns: []Column{{Name: "Change"}, {Name: "ID"}, {Name: "Name"}, {Name: "Details"}},
+		Items:   diffs,
+	}
+	for _, diff := range diffs {
+		changes := make([]string, 0, len(diff.Changes))
+		for _, change := range diff.Changes {
+			changes = append(changes, change.String())
+		}
+		result.Rows = append(result.Rows, []string{diff.Kind, diff.ID, diff.Name, strings.Join(changes, ", ")})
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/model/modeltest"
+)
+
+// writeInventory writes an inventory of instances as e2c list does, in JSON
+// or YAML depending on the extension of the file
+func writeInventory(t *testing.T, name string, instances []model.Instance) string {
+	t.Helper()
+	var (
+		data []byte
+		err  error
+	)
+	path := filepath.Join(t.TempDir(), name)
+	if filepath.Ext(name) == ".json" {
+		data, err = json.Marshal(instances)
+	} else {
+		data, err = yaml.Marshal(instances)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiff(t *testing.T) {
+	web := modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("t3.micro").WithTag("Team", "payments")
+
+	tests := []struct {
+		name     string
+		from, to []model.Instance
+		want     [][]string
+	}{
+		{
+			name: "no change",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{web.Build()},
+		},
+		{
+			name: "type",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("m5.large").WithTag("Team", "payments").Build()},
+			want: [][]string{{"changed", "i-0web", "web", "type: t3.micro → m5.large"}},
+		},
+		{
+			name: "state",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("t3.micro").WithTag("Team", "payments").Stopped().Build()},
+			want: [][]string{{"changed", "i-0web", "web", "state: running → stopped"}},
+		},
+		{
+			name: "tags",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("t3.micro").WithTag("Owner", "alice").Build()},
+			want: [][]string{{"changed", "i-0web", "web", "tag:Owner: (none) → alice, tag:Team: payments → (none)"}},
+		},
+		{
+			name: "added and removed",
+			from: []model.Instance{web.Build(), modeltest.NewInstance().WithID("i-0old").WithName("old").Build()},
+			to:   []model.Instance{web.Build(), modeltest.NewInstance().WithID("i-0new").WithName("new").Build()},
+			want: [][]string{
+				{"added", "i-0new", "new", ""},
+				{"removed", "i-0old", "old", ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Compare a JSON inventory with a YAML one, as e2c diff
+			before, err := ReadInstances(writeInventory(t, "before.json", tt.from))
+			if err != nil {
+				t.Fatalf("ReadInstances() error = %v", err)
+			}
+			after, err := ReadInstances(writeInventory(t, "after.yaml", tt.to))
+			if err != nil {
+				t.Fatalf("ReadInstances() error = %v", err)
+			}
+
+			result := Diff(model.DiffInstances(before, after))
+			if !reflect.DeepEqual(result.Rows, tt.want) {
+				t.Errorf("Diff() rows = %q, want %q", result.Rows, tt.want)
+			}
+
+			renderer, err := New(FormatTable, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := renderer.Render(&out, result); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			for _, row := range tt.want {
+				if !strings.Contains(out.String(), row[1]) {
+					t.Errorf("table does not list %s:\n%s", row[1], out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestReadInstancesErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	text := filepath.Join(dir, "inventory.txt")
+	if err := os.WriteFile(text, []byte("[]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "missing", path: filepath.Join(dir, "missing.json"), want: "failed to read inventory"},
+		{name: "invalid", path: invalid, want: "failed to parse inventory"},
+		{name: "extension", path: text, want: "unsupported inventory extension"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadInstances(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadInstances() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tableRenderer writes the rows aligned in columns, with the wide columns in
+// the wide format only
+type tableRenderer struct {
+	wide bool
+}
+
+// Render writes the table
+func (r tableRenderer) Render(w io.Writer, result *Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, 0, len(result.Columns))
+	for _, column := range result.Columns {
+		if r.wide || !column.Wide {
+			headers = append(headers, strings.ToUpper(column.Name))
+		}
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, row := range result.Rows {
+		values := make([]string, 0, len(row))
+		for i, value := range row {
+			if r.wide || !result.Columns[i].Wide {
+				values = append(values, value)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// renderJSON writes the items as indented JSON
+func renderJSON(w io.Writer, result *Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result.Items)
+}
+
+// renderYAML writes the items as YAML
+func renderYAML(w io.Writer, result *Result) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(result.Items); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// renderCSV writes the rows with all the columns as CSV, with a header
+func renderCSV(w io.Writer, result *Result) error {
+	writer := csv.NewWriter(w)
+
+	headers := make([]string, 0, len(result.Columns))
+	for _, column := range result.Columns {
+		headers = append(headers, column.Name)
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(result.Rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// newTemplateRenderer returns a renderer executing a Go template with the
+// items
+func newTemplateRenderer(text string) (Renderer, error) {
+	tmpl, err := template.New("output").Funcs(template.FuncMap{
+		"join":  strings.Join,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	return RendererFunc(func(w io.Writer, result *Result) error {
+		return tmpl.Execute(w, result.Items)
+	}), nil
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package output
+
+import (
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// Instances returns the result of a list of instances, with the tag columns
+// of the configuration, and all the tags in the wide formats. The launch
+// times are formatted with formatTime, e.g. the one of ui.time_format.
+func Instances(instances []model.Instance, tagColumns []string, formatTime func(time.Time) string) *Result {
+	result := &Result{
+		Columns: []Column{
+			{Name: "ID"},
+			{Name: "Name"},
+			{Name: "State"},
+			{Name: "Type"},
+			{Name: "Zone"},
+			{Name: "Private IP"},
+			{Name: "Public IP"},
+			{Name: "Launch Time"},
+			{Name: "Image", Wide: true},
+			{Name: "Key", Wide: true},
+			{Name: "VPC", Wide: true},
+			{Name: "Subnet", Wide: true},
+			{Name: "Tags", Wide: true},
+		},
+		Items: instances,
+	}
+	for _, key := range tagColumns {
+		result.Columns = append(result.Columns, Column{Name: key})
+	}
+
+	for _, instance := range instances {
+		row := []string{
+			instance.ID,
+			instance.Name,
+			instance.State,
+			instance.Type,
+			instance.AvailabilityZone,
+			instance.PrivateIP,
+			instance.PublicIP,
+			formatTime(instance.LaunchTime),
+			instance.ImageID,
+			instance.KeyName,
+			instance.VpcID,
+			instance.SubnetID,
+			KeyValues(instance.Tags),
+		}
+		for _, key := range tagColumns {
+			row = append(row, instance.Tags[key])
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result
+}
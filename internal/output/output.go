@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package output renders the results of the headless commands in the format
+// given with --output: a table, JSON, YAML, CSV or a Go template.
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Formats shared by the commands
+const (
+	FormatTable = "table"
+	FormatWide  = "wide"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatCSV   = "csv"
+
+	// templatePrefix and templateFilePrefix introduce a Go template given
+	// inline or in a file, e.g. go-template={{range .}}{{.ID}}{{end}}
+	templatePrefix     = "go-template="
+	templateFilePrefix = "go-template-file="
+)
+
+// Column is a column of the table formats
+type Column struct {
+	Name string
+	Wide bool // Only displayed by the wide format
+}
+
+// Result is the result of a command: the columns and rows of the table
+// formats, and the items encoded by the structured formats and passed to the
+// templates
+type Result struct {
+	Columns []Column
+	Rows    [][]string // One value per column
+	Items   any
+}
+
+// Renderer writes the result of a command in a format
+type Renderer interface {
+	Render(w io.Writer, result *Result) error
+}
+
+// RendererFunc is a function implementing Renderer
+type RendererFunc func(w io.Writer, result *Result) error
+
+// Render calls the function
+func (f RendererFunc) Render(w io.Writer, result *Result) error {
+	return f(w, result)
+}
+
+// renderers are the renderers of the formats shared by the commands
+var renderers = map[string]Renderer{
+	FormatTable: tableRenderer{},
+	FormatWide:  tableRenderer{wide: true},
+	FormatJSON:  RendererFunc(renderJSON),
+	FormatYAML:  RendererFunc(renderYAML),
+	FormatCSV:   RendererFunc(renderCSV),
+}
+
+// New returns the renderer of a format: a shared one, one of the formats
+// specific to a command, or a Go template
+func New(format string, specific map[string]Renderer) (Renderer, error) {
+	switch {
+	case strings.HasPrefix(format, templatePrefix):
+		return newTemplateRenderer(strings.TrimPrefix(format, templatePrefix))
+	case strings.HasPrefix(format, templateFilePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(format, templateFilePrefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		return newTemplateRenderer(string(data))
+	}
+
+	name := strings.ToLower(format)
+	if renderer, ok := specific[name]; ok {
+		return renderer, nil
+	}
+	if renderer, ok := renderers[name]; ok {
+		return renderer, nil
+	}
+	return nil, fmt.Errorf("unsupported output format: %s (expected %s)", format, strings.Join(Formats(specific), ", "))
+}
+
+// Formats returns the names of the formats of a command, sorted
+func Formats(specific map[string]Renderer) []string {
+	names := make([]string, 0, len(renderers)+len(specific)+2)
+	for name := range renderers {
+		names = append(names, name)
+	}
+	for name := range specific {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, templatePrefix+"...", templateFilePrefix+"...")
+}
+
+// AddFlag adds the --output flag of the format to a command
+func AddFlag(cmd *cobra.Command, format *string, value string, specific map[string]Renderer) {
+	cmd.Flags().StringVarP(format, "output", "o", value, "output format ("+strings.Join(Formats(specific), ", ")+")")
+}
+
+// KeyValues formats a map, e.g. tags, as key=value pairs sorted by key
+func KeyValues(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+values[key])
+	}
+	return strings.Join(pairs, ",")
+}
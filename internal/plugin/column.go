@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlamirault/e2c/internal/config"
+)
+
+// Default settings of the plugin columns
+const (
+	DefaultInterval = 5 * time.Minute
+	DefaultTimeout  = 10 * time.Second
+)
+
+// Column is a column of the instances table populated by an external command.
+//
+// The command receives the instance IDs on its standard input, one per line,
+// and writes a JSON object mapping instance IDs to values on its standard
+// output:
+//
+//	{"i-0123456789abcdef0": "team-payments", "i-0fedcba9876543210": "team-web"}
+//
+// The values are cached and the command is run again once the refresh
+// interval has elapsed, or when new instances are displayed.
+type Column struct {
+	log      *slog.Logger
+	name     string
+	command  []string
+	interval time.Duration
+	timeout  time.Duration
+
+	mutex   sync.Mutex
+	values  map[string]string
+	known   map[string]bool // Instance IDs sent to the command
+	fetched time.Time
+	running bool
+}
+
+// NewColumn creates a plugin column from its configuration
+func NewColumn(log *slog.Logger, cfg config.PluginColumnConfig) (*Column, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("plugin column without name")
+	}
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("plugin column %s without command", cfg.Name)
+	}
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Column{
+		log:      log,
+		name:     cfg.Name,
+		command:  cfg.Command,
+		interval: interval,
+		timeout:  timeout,
+		values:   make(map[string]string),
+		known:    make(map[string]bool),
+	}, nil
+}
+
+// NewColumns creates the configured plugin columns, skipping the invalid ones
+func NewColumns(log *slog.Logger, cfgs []config.PluginColumnConfig) []*Column {
+	columns := make([]*Column, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		column, err := NewColumn(log, cfg)
+		if err != nil {
+			log.Error("Invalid plugin column", "error", err)
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// Name returns the name of the column
+func (c *Column) Name() string {
+	return c.name
+}
+
+// Value returns the cached value of an instance
+func (c *Column) Value(instanceID string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.values[instanceID]
+}
+
+// Refresh runs the command if the cached values are stale or some instances
+// were never sent to it. It returns true if the values were updated.
+func (c *Column) Refresh(ctx context.Context, instanceIDs []string) (bool, error) {
+	c.mutex.Lock()
+	stale := time.Since(c.fetched) >= c.interval
+	for _, id := range instanceIDs {
+		if !c.known[id] {
+			stale = true
+			break
+		}
+	}
+	if !stale || c.running || len(instanceIDs) == 0 {
+		c.mutex.Unlock()
+		return false, nil
+	}
+	c.running = true
+	c.mutex.Unlock()
+
+	values, err := c.run(ctx, instanceIDs)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.running = false
+	if err != nil {
+		return false, err
+	}
+	c.values = values
+	c.known = make(map[string]bool, len(instanceIDs))
+	for _, id := range instanceIDs {
+		c.known[id] = true
+	}
+	c.fetched = time.Now()
+	return true, nil
+}
+
+// run executes the command and decodes its output
+func (c *Column) run(ctx context.Context, instanceIDs []string) (map[string]string, error) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(instanceIDs, "\n") + "\n")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin column %s failed: %w: %s", c.name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(stdout.Bytes(), &raw); err != nil {
+		return nil, fmt.Errorf("plugin column %s returned invalid JSON: %w", c.name, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for id, value := range raw {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			values[id] = v
+		default:
+			values[id] = fmt.Sprint(v)
+		}
+	}
+
+	c.log.Info("Plugin column refreshed", "column", c.name, "instances", len(instanceIDs), "values", len(values), "duration", time.Since(start))
+	return values, nil
+}
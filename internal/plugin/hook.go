@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// Events after which the hooks can run
+const (
+	EventStart     = "start"
+	EventStop      = "stop"
+	EventReboot    = "reboot"
+	EventTerminate = "terminate"
+)
+
+// maxHookOutput is the maximum size of the output of a hook kept for the
+// audit log
+const maxHookOutput = 4096
+
+// Hook is a local command run after e2c performed an action on an instance,
+// e.g. to update /etc/hosts or an SSH config once an instance is started.
+//
+// The command receives the context of the instance as environment variables:
+// E2C_EVENT, E2C_INSTANCE_ID, E2C_INSTANCE_NAME, E2C_INSTANCE_TYPE,
+// E2C_INSTANCE_STATE (before the action), E2C_PRIVATE_IP, E2C_PUBLIC_IP,
+// E2C_REGION, E2C_PROFILE, and E2C_TAG_<KEY> for each tag.
+type Hook struct {
+	log     *slog.Logger
+	name    string
+	events  map[string]bool
+	command []string
+	timeout time.Duration
+}
+
+// NewHook creates a hook from its configuration
+func NewHook(log *slog.Logger, cfg config.PluginHookConfig) (*Hook, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("plugin hook without name")
+	}
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("plugin hook %s without command", cfg.Name)
+	}
+	if len(cfg.Events) == 0 {
+		return nil, fmt.Errorf("plugin hook %s without events", cfg.Name)
+	}
+
+	events := make(map[string]bool, len(cfg.Events))
+	for _, event := range cfg.Events {
+		switch event {
+		case EventStart, EventStop, EventReboot, EventTerminate:
+			events[event] = true
+		default:
+			return nil, fmt.Errorf("plugin hook %s: unknown event %q", cfg.Name, event)
+		}
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Hook{
+		log:     log,
+		name:    cfg.Name,
+		events:  events,
+		command: cfg.Command,
+		timeout: timeout,
+	}, nil
+}
+
+// NewHooks creates the configured hooks, skipping the invalid ones
+func NewHooks(log *slog.Logger, cfgs []config.PluginHookConfig) []*Hook {
+	hooks := make([]*Hook, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		hook, err := NewHook(log, cfg)
+		if err != nil {
+			log.Error("Invalid plugin hook", "error", err)
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// Name returns the name of the hook
+func (h *Hook) Name() string {
+	return h.name
+}
+
+// Handles returns true if the hook runs after the given event
+func (h *Hook) Handles(event string) bool {
+	return h.events[event]
+}
+
+// Run executes the command with the context of the instance, and returns
+// its standard output and error, truncated
+func (h *Hook) Run(ctx context.Context, event, region, profile string, instance model.Instance) (string, error) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.command[0], h.command[1:]...)
+	cmd.Env = append(os.Environ(), HookEnv(event, region, profile, instance)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+
+	text := strings.TrimSpace(output.String())
+	if len(text) > maxHookOutput {
+		// Cut at the start of a rune, not in the middle of a multi-byte one
+		cut := maxHookOutput
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "..."
+	}
+	if err != nil {
+		return text, fmt.Errorf("plugin hook %s failed: %w", h.name, err)
+	}
+
+	h.log.Info("Plugin hook run", "hook", h.name, "event", event, "instanceID", instance.ID, "duration", time.Since(start))
+	return text, nil
+}
+
+// HookEnv returns the environment variables describing an instance and the
+// action performed on it
+func HookEnv(event, region, profile string, instance model.Instance) []string {
+	env := []string{
+		"E2C_EVENT=" + event,
+		"E2C_INSTANCE_ID=" + instance.ID,
+		"E2C_INSTANCE_NAME=" + instance.Name,
+		"E2C_INSTANCE_TYPE=" + instance.Type,
+		"E2C_INSTANCE_STATE=" + instance.State,
+		"E2C_PRIVATE_IP=" + instance.PrivateIP,
+		"E2C_PUBLIC_IP=" + instance.PublicIP,
+		"E2C_REGION=" + region,
+		"E2C_PROFILE=" + profile,
+	}
+	for key, value := range instance.Tags {
+		env = append(env, "E2C_TAG_"+envName(key)+"="+value)
+	}
+	return env
+}
+
+// envName converts a tag key to the name of an environment variable, e.g.
+// aws:autoscaling:groupName to AWS_AUTOSCALING_GROUPNAME
+func envName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
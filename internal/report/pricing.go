@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+// hoursPerMonth is the number of hours used to estimate a monthly cost
+const hoursPerMonth = 730
+
+// hourlyPrices holds the approximate on-demand Linux price per hour in USD of
+// common instance types (us-east-1). It is only used for estimates: prices
+// vary by region, platform and purchase option.
+var hourlyPrices = map[string]float64{
+	"t2.micro":    0.0116,
+	"t2.small":    0.023,
+	"t2.medium":   0.0464,
+	"t2.large":    0.0928,
+	"t3.nano":     0.0052,
+	"t3.micro":    0.0104,
+	"t3.small":    0.0208,
+	"t3.medium":   0.0416,
+	"t3.large":    0.0832,
+	"t3.xlarge":   0.1664,
+	"t3.2xlarge":  0.3328,
+	"t3a.micro":   0.0094,
+	"t3a.small":   0.0188,
+	"t3a.medium":  0.0376,
+	"t3a.large":   0.0752,
+	"t4g.micro":   0.0084,
+	"t4g.small":   0.0168,
+	"t4g.medium":  0.0336,
+	"t4g.large":   0.0672,
+	"m5.large":    0.096,
+	"m5.xlarge":   0.192,
+	"m5.2xlarge":  0.384,
+	"m5.4xlarge":  0.768,
+	"m6i.large":   0.096,
+	"m6i.xlarge":  0.192,
+	"m6i.2xlarge": 0.384,
+	"m6g.large":   0.077,
+	"m6g.xlarge":  0.154,
+	"m7i.large":   0.1008,
+	"m7g.large":   0.0816,
+	"c5.large":    0.085,
+	"c5.xlarge":   0.17,
+	"c5.2xlarge":  0.34,
+	"c6i.large":   0.085,
+	"c6i.xlarge":  0.17,
+	"c6g.large":   0.068,
+	"c7g.large":   0.0725,
+	"r5.large":    0.126,
+	"r5.xlarge":   0.252,
+	"r6i.large":   0.126,
+	"r6g.large":   0.1008,
+}
+
+// HourlyPrice returns the estimated hourly price of an instance type, and
+// false if the type is unknown
+func HourlyPrice(instanceType string) (float64, bool) {
+	price, ok := hourlyPrices[instanceType]
+	return price, ok
+}
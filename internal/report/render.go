@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Report formats
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+)
+
+// templateFuncs returns the helpers available in the report templates, the
+// dates being formatted with formatTime
+func templateFuncs(formatTime func(time.Time) string) map[string]any {
+	return map[string]any{
+		"date": formatTime,
+		"join": strings.Join,
+		"cost": func(value float64) string {
+			return fmt.Sprintf("$%.2f", value)
+		},
+		"age": func(t time.Time, now time.Time) string {
+			return fmt.Sprintf("%dd", int(now.Sub(t).Hours()/24))
+		},
+	}
+}
+
+const markdownTemplate = `# EC2 fleet report
+
+Region **{{ .Region }}**{{ if .Profile }}, profile **{{ .Profile }}**{{ end }}, generated on {{ date .GeneratedAt }}.
+
+## Summary
+
+- Instances: **{{ .Total }}**
+- Estimated cost of the running instances: **{{ cost .HourlyCost }}/hour**, **{{ cost .MonthlyCost }}/month**
+{{- if .UnpricedTypes }}
+- Types without a known price: {{ join .UnpricedTypes ", " }}
+{{- end }}
+- Untagged instances: **{{ len .Untagged }}**
+- Security findings: **{{ len .Findings }}**
+
+## Instances by state
+
+| State | Count |
+| ----- | ----- |
+{{- range .ByState }}
+| {{ .Value }} | {{ .Count }} |
+{{- end }}
+
+## Instances by type
+
+| Type | Count |
+| ---- | ----- |
+{{- range .ByType }}
+| {{ .Value }} | {{ .Count }} |
+{{- end }}
+
+## Oldest instances
+
+| ID | Name | Type | State | Launched | Age |
+| -- | ---- | ---- | ----- | -------- | --- |
+{{- range .Oldest }}
+| {{ .ID }} | {{ .Name }} | {{ .Type }} | {{ .State }} | {{ date .LaunchTime }} | {{ age .LaunchTime $.GeneratedAt }} |
+{{- end }}
+
+## Untagged instances
+{{ if .Untagged }}
+| ID | Name | Missing tags |
+| -- | ---- | ------------ |
+{{- range .Untagged }}
+| {{ .Instance.ID }} | {{ .Instance.Name }} | {{ join .Missing ", " }} |
+{{- end }}
+{{ else }}
+None.
+{{ end }}
+## Security findings
+{{ if .Findings }}
+| Severity | ID | Name | Finding |
+| -------- | -- | ---- | ------- |
+{{- range .Findings }}
+| {{ .Severity }} | {{ .Instance.ID }} | {{ .Instance.Name }} | {{ .Message }} |
+{{- end }}
+{{ else }}
+None.
+{{ end }}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>EC2 fleet report - {{ .Region }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #2e3440; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #d8dee9; padding: 4px 10px; text-align: left; }
+th { background: #eceff4; }
+.high { color: #bf616a; font-weight: bold; }
+.medium { color: #d08770; }
+.low { color: #5e81ac; }
+</style>
+</head>
+<body>
+<h1>EC2 fleet report</h1>
+<p>Region <b>{{ .Region }}</b>{{ if .Profile }}, profile <b>{{ .Profile }}</b>{{ end }}, generated on {{ date .GeneratedAt }}.</p>
+
+<h2>Summary</h2>
+<ul>
+<li>Instances: <b>{{ .Total }}</b></li>
+<li>Estimated cost of the running instances: <b>{{ cost .HourlyCost }}/hour</b>, <b>{{ cost .MonthlyCost }}/month</b></li>
+{{- if .UnpricedTypes }}
+<li>Types without a known price: {{ join .UnpricedTypes ", " }}</li>
+{{- end }}
+<li>Untagged instances: <b>{{ len .Untagged }}</b></li>
+<li>Security findings: <b>{{ len .Findings }}</b></li>
+</ul>
+
+<h2>Instances by state</h2>
+<table>
+<tr><th>State</th><th>Count</th></tr>
+{{- range .ByState }}
+<tr><td>{{ .Value }}</td><td>{{ .Count }}</td></tr>
+{{- end }}
+</table>
+
+<h2>Instances by type</h2>
+<table>
+<tr><th>Type</th><th>Count</th></tr>
+{{- range .ByType }}
+<tr><td>{{ .Value }}</td><td>{{ .Count }}</td></tr>
+{{- end }}
+</table>
+
+<h2>Oldest instances</h2>
+<table>
+<tr><th>ID</th><th>Name</th><th>Type</th><th>State</th><th>Launched</th><th>Age</th></tr>
+{{- range .Oldest }}
+<tr><td>{{ .ID }}</td><td>{{ .Name }}</td><td>{{ .Type }}</td><td>{{ .State }}</td><td>{{ date .LaunchTime }}</td><td>{{ age .LaunchTime $.GeneratedAt }}</td></tr>
+{{- end }}
+</table>
+
+<h2>Untagged instances</h2>
+{{ if .Untagged -}}
+<table>
+<tr><th>ID</th><th>Name</th><th>Missing tags</th></tr>
+{{- range .Untagged }}
+<tr><td>{{ .Instance.ID }}</td><td>{{ .Instance.Name }}</td><td>{{ join .Missing ", " }}</td></tr>
+{{- end }}
+</table>
+{{- else -}}
+<p>None.</p>
+{{- end }}
+
+<h2>Security findings</h2>
+{{ if .Findings -}}
+<table>
+<tr><th>Severity</th><th>ID</th><th>Name</th><th>Finding</th></tr>
+{{- range .Findings }}
+<tr><td class="{{ .Severity }}">{{ .Severity }}</td><td>{{ .Instance.ID }}</td><td>{{ .Instance.Name }}</td><td>{{ .Message }}</td></tr>
+{{- end }}
+</table>
+{{- else -}}
+<p>None.</p>
+{{- end }}
+</body>
+</html>
+`
+
+// Render writes the report in the given format (md or html), its dates
+// formatted with formatTime, e.g. the one of ui.time_format
+func Render(w io.Writer, r *Report, format string, formatTime func(time.Time) string) error {
+	switch strings.ToLower(format) {
+	case FormatMarkdown, "markdown":
+		tmpl, err := template.New("report").Funcs(templateFuncs(formatTime)).Parse(markdownTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to parse markdown template: %w", err)
+		}
+		return tmpl.Execute(w, r)
+	case FormatHTML:
+		tmpl, err := htmltemplate.New("report").Funcs(templateFuncs(formatTime)).Parse(htmlTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to parse HTML template: %w", err)
+		}
+		return tmpl.Execute(w, r)
+	default:
+		return fmt.Errorf("unsupported report format: %s (expected md or html)", format)
+	}
+}
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// maxOldest is the number of oldest instances listed in a report
+const maxOldest = 10
+
+// Severity levels of the security findings
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// Count is the number of instances for a value (state, type, ...)
+type Count struct {
+	Value string
+	Count int
+}
+
+// Untagged is an instance missing some of the required tags
+type Untagged struct {
+	Instance model.Instance
+	Missing  []string
+}
+
+// Finding is a security finding on an instance
+type Finding struct {
+	Instance model.Instance
+	Severity string
+	Message  string
+}
+
+// Report is a summary of the EC2 fleet of a region
+type Report struct {
+	GeneratedAt   time.Time
+	Region        string
+	Profile       string
+	Total         int
+	ByState       []Count
+	ByType        []Count
+	HourlyCost    float64  // Estimated cost per hour of the running instances
+	MonthlyCost   float64  // Estimated cost per month of the running instances
+	UnpricedTypes []string // Types of running instances without a known price
+	Oldest        []model.Instance
+	Untagged      []Untagged
+	Findings      []Finding
+}
+
+// Build builds the report of the instances. Instances missing one of the
+// required tags are listed as untagged.
+func Build(instances []model.Instance, region, profile string, requiredTags []string, now time.Time) *Report {
+	r := &Report{
+		GeneratedAt: now,
+		Region:      region,
+		Profile:     profile,
+		Total:       len(instances),
+	}
+
+	states := make(map[string]int)
+	types := make(map[string]int)
+	unpriced := make(map[string]bool)
+	for _, instance := range instances {
+		states[instance.State]++
+		types[instance.Type]++
+
+		if instance.IsRunning() {
+			if price, ok := HourlyPrice(instance.Type); ok {
+				r.HourlyCost += price
+			} else {
+				unpriced[instance.Type] = true
+			}
+		}
+
+		var missing []string
+		for _, key := range requiredTags {
+			if instance.Tags[key] == "" {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			r.Untagged = append(r.Untagged, Untagged{Instance: instance, Missing: missing})
+		}
+
+		r.Findings = append(r.Findings, findings(instance)...)
+	}
+
+	r.ByState = sortedCounts(states)
+	r.ByType = sortedCounts(types)
+	r.MonthlyCost = r.HourlyCost * hoursPerMonth
+	for instanceType := range unpriced {
+		r.UnpricedTypes = append(r.UnpricedTypes, instanceType)
+	}
+	sort.Strings(r.UnpricedTypes)
+
+	// Oldest instances which are still alive
+	alive := make([]model.Instance, 0, len(instances))
+	for _, instance := range instances {
+		if instance.State != "terminated" {
+			alive = append(alive, instance)
+		}
+	}
+	sort.SliceStable(alive, func(i, j int) bool {
+		return alive[i].LaunchTime.Before(alive[j].LaunchTime)
+	})
+	if len(alive) > maxOldest {
+		alive = alive[:maxOldest]
+	}
+	r.Oldest = alive
+
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		return severityRank(r.Findings[i].Severity) < severityRank(r.Findings[j].Severity)
+	})
+
+	return r
+}
+
+// findings returns the security findings of an instance
+func findings(instance model.Instance) []Finding {
+	if instance.State == "terminated" {
+		return nil
+	}
+
+	var result []Finding
+	if instance.MetadataHTTPTokens == "optional" {
+		result = append(result, Finding{
+			Instance: instance,
+			Severity: SeverityHigh,
+			Message:  "IMDSv1 is allowed, IMDSv2 is not enforced",
+		})
+	}
+	if instance.PublicIP != "" && instance.KeyName != "" {
+		result = append(result, Finding{
+			Instance: instance,
+			Severity: SeverityMedium,
+			Message:  "Public IP address with SSH key pair " + instance.KeyName,
+		})
+	} else if instance.PublicIP != "" {
+		result = append(result, Finding{
+			Instance: instance,
+			Severity: SeverityLow,
+			Message:  "Public IP address " + instance.PublicIP,
+		})
+	}
+	if instance.IAMInstanceProfile == "" {
+		result = append(result, Finding{
+			Instance: instance,
+			Severity: SeverityLow,
+			Message:  "No IAM instance profile",
+		})
+	}
+	return result
+}
+
+// severityRank returns the order of a severity, the highest first
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityHigh:
+		return 0
+	case SeverityMedium:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// sortedCounts returns the counts sorted by decreasing count, then by value
+func sortedCounts(counts map[string]int) []Count {
+	result := make([]Count, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, Count{Value: value, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
@@ -0,0 +1,319 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlamirault/e2c/internal/config"
+)
+
+// Modes of the schedules
+const (
+	// ModeLocal schedules are applied by e2c while it is running
+	ModeLocal = "local"
+	// ModeEventBridge schedules are created in EventBridge Scheduler
+	ModeEventBridge = "eventbridge"
+)
+
+// days are the names of the days of the week, in the order of time.Weekday
+var days = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Schedule stops the running instances with a tag at a time of the day
+type Schedule struct {
+	Name     string
+	TagKey   string
+	TagValue string
+	Hour     int
+	Minute   int
+	// Days are the days of the stop, every day if empty
+	Days     []time.Weekday
+	Location *time.Location
+	// Profile and Region are those of the instances, see SetDefaults
+	Profile string
+	Region  string
+	Mode    string
+	RoleARN string
+}
+
+// New creates a schedule from its configuration
+func New(cfg config.ScheduleConfig) (*Schedule, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("schedule without name")
+	}
+
+	key, value, found := strings.Cut(strings.TrimSpace(cfg.Tag), "=")
+	if !found || key == "" || value == "" {
+		return nil, fmt.Errorf("schedule %s: the tag must be of the form key=value", cfg.Name)
+	}
+
+	stop, err := time.Parse("15:04", strings.TrimSpace(cfg.Stop))
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: the stop time must be of the form HH:MM", cfg.Name)
+	}
+
+	// The machine running EventBridge Scheduler has no local time zone
+	location := time.Local
+	if strings.EqualFold(cfg.Mode, ModeEventBridge) {
+		location = time.UTC
+	}
+	if cfg.Timezone != "" {
+		if location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", cfg.Name, err)
+		}
+	}
+
+	s := &Schedule{
+		Name:     cfg.Name,
+		TagKey:   key,
+		TagValue: value,
+		Hour:     stop.Hour(),
+		Minute:   stop.Minute(),
+		Location: location,
+		Profile:  strings.TrimSpace(cfg.Profile),
+		Region:   strings.TrimSpace(cfg.Region),
+		Mode:     strings.ToLower(cfg.Mode),
+		RoleARN:  cfg.RoleARN,
+	}
+
+	for _, day := range cfg.Days {
+		weekday, err := parseDay(day)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", cfg.Name, err)
+		}
+		s.Days = append(s.Days, weekday)
+	}
+
+	switch s.Mode {
+	case "":
+		s.Mode = ModeLocal
+	case ModeLocal:
+	case ModeEventBridge:
+		if s.RoleARN == "" {
+			return nil, fmt.Errorf("schedule %s: role_arn is required by the eventbridge mode", cfg.Name)
+		}
+	default:
+		return nil, fmt.Errorf("schedule %s: unknown mode %q", cfg.Name, cfg.Mode)
+	}
+
+	return s, nil
+}
+
+// NewSchedules creates the configured schedules, skipping the invalid ones
+func NewSchedules(log *slog.Logger, cfgs []config.ScheduleConfig) []*Schedule {
+	schedules := make([]*Schedule, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		s, err := New(cfg)
+		if err != nil {
+			log.Error("Invalid schedule", "error", err)
+			continue
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules
+}
+
+// SetDefaults sets the profile and the region of the schedules without
+// ones, those e2c is started with: a schedule stops the instances of its
+// profile and region, never the ones displayed when the stop is due
+func SetDefaults(schedules []*Schedule, profile, region string) {
+	for _, s := range schedules {
+		if s.Profile == "" {
+			s.Profile = profile
+		}
+		if s.Region == "" {
+			s.Region = region
+		}
+	}
+}
+
+// parseDay parses the name of a day, e.g. mon or Monday
+func parseDay(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, day := range days {
+		if name == day || name == strings.ToLower(time.Weekday(i).String()) {
+			return time.Weekday(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q", name)
+}
+
+// Filters returns the EC2 API filters selecting the running instances of
+// the schedule
+func (s *Schedule) Filters() map[string][]string {
+	return map[string][]string{
+		"tag:" + s.TagKey:     {s.TagValue},
+		"instance-state-name": {"running"},
+	}
+}
+
+// Selector returns the tag selecting the instances, key=value
+func (s *Schedule) Selector() string {
+	return s.TagKey + "=" + s.TagValue
+}
+
+// Target returns the region of the instances, prefixed by the profile if
+// any, e.g. prod/eu-west-1
+func (s *Schedule) Target() string {
+	if s.Profile == "" {
+		return s.Region
+	}
+	return s.Profile + "/" + s.Region
+}
+
+// runsOn returns true if the schedule stops the instances on a day
+func (s *Schedule) runsOn(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the time of the first stop after the given time
+func (s *Schedule) Next(after time.Time) time.Time {
+	after = after.In(s.Location)
+	for i := 0; i <= 7; i++ {
+		day := after.AddDate(0, 0, i)
+		next := time.Date(day.Year(), day.Month(), day.Day(), s.Hour, s.Minute, 0, 0, s.Location)
+		if next.After(after) && s.runsOn(next.Weekday()) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// Expression returns the cron expression of the schedule in EventBridge
+// Scheduler, e.g. cron(0 19 ? * MON,FRI *), whose time zone is the one of
+// the schedule
+func (s *Schedule) Expression() string {
+	weekdays := "*"
+	if len(s.Days) > 0 {
+		names := make([]string, 0, len(s.Days))
+		for _, day := range s.Days {
+			names = append(names, strings.ToUpper(days[day]))
+		}
+		weekdays = strings.Join(names, ",")
+	}
+	return fmt.Sprintf("cron(%d %d ? * %s *)", s.Minute, s.Hour, weekdays)
+}
+
+// String returns the time and the days of the stop, e.g. 19:00 mon,tue
+func (s *Schedule) String() string {
+	when := fmt.Sprintf("%02d:%02d", s.Hour, s.Minute)
+	if len(s.Days) > 0 {
+		names := make([]string, 0, len(s.Days))
+		for _, day := range s.Days {
+			names = append(names, days[day])
+		}
+		when += " " + strings.Join(names, ",")
+	}
+	if s.Location != time.Local {
+		when += " " + s.Location.String()
+	}
+	return when
+}
+
+// StopFunc stops the running instances of a schedule, returning the number
+// of instances stopped
+type StopFunc func(ctx context.Context, s *Schedule) (int, error)
+
+// Runner applies the local schedules while e2c is running
+type Runner struct {
+	log       *slog.Logger
+	schedules []*Schedule
+	stop      StopFunc
+
+	mutex sync.Mutex
+	next  map[string]time.Time
+}
+
+// NewRunner creates the runner of the local schedules among the given ones
+func NewRunner(log *slog.Logger, schedules []*Schedule, stop StopFunc) *Runner {
+	r := &Runner{
+		log:  log,
+		stop: stop,
+		next: make(map[string]time.Time),
+	}
+	for _, s := range schedules {
+		if s.Mode == ModeLocal {
+			r.schedules = append(r.schedules, s)
+		}
+	}
+	return r
+}
+
+// Schedules returns the local schedules
+func (r *Runner) Schedules() []*Schedule {
+	return r.schedules
+}
+
+// Next returns the time of the next stop of a local schedule, zero if the
+// runner is not running
+func (r *Runner) Next(name string) time.Time {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.next[name]
+}
+
+// Run applies the schedules until the context is done. A stop missed while
+// e2c was not running is not applied once it starts.
+func (r *Runner) Run(ctx context.Context) {
+	if len(r.schedules) == 0 {
+		return
+	}
+
+	now := time.Now()
+	r.mutex.Lock()
+	for _, s := range r.schedules {
+		r.next[s.Name] = s.Next(now)
+	}
+	r.mutex.Unlock()
+	r.log.Info("Applying local schedules", "schedules", len(r.schedules))
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.apply(ctx, now)
+		}
+	}
+}
+
+// apply stops the instances of the schedules due at the given time
+func (r *Runner) apply(ctx context.Context, now time.Time) {
+	for _, s := range r.schedules {
+		r.mutex.Lock()
+		next := r.next[s.Name]
+		due := !now.Before(next)
+		if due {
+			r.next[s.Name] = s.Next(now)
+		}
+		r.mutex.Unlock()
+		if !due {
+			continue
+		}
+
+		r.log.Info("Applying schedule", "schedule", s.Name, "tag", s.Selector())
+		stopped, err := r.stop(ctx, s)
+		if err != nil {
+			r.log.Error("Failed to apply schedule", "schedule", s.Name, "error", err)
+			continue
+		}
+		r.log.Info("Schedule applied", "schedule", s.Name, "stopped", stopped)
+	}
+}
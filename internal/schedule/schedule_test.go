@@ -0,0 +1,343 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package schedule
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // Time zones of the tests, whatever the machine
+
+	"github.com/nlamirault/e2c/internal/config"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// mustLocation loads a time zone
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+func TestNew(t *testing.T) {
+	paris := mustLocation(t, "Europe/Paris")
+
+	tests := []struct {
+		name    string
+		cfg     config.ScheduleConfig
+		want    *Schedule
+		wantErr string
+	}{
+		{
+			name: "local",
+			cfg:  config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00"},
+			want: &Schedule{Name: "dev", TagKey: "Environment", TagValue: "dev", Hour: 19, Location: time.Local, Mode: ModeLocal},
+		},
+		{
+			name: "days and time zone",
+			cfg:  config.ScheduleConfig{Name: "dev", Tag: " Team=data ", Stop: "07:30", Days: []string{"mon", "Friday", " SAT "}, Timezone: "Europe/Paris", Mode: "LOCAL"},
+			want: &Schedule{Name: "dev", TagKey: "Team", TagValue: "data", Hour: 7, Minute: 30, Days: []time.Weekday{time.Monday, time.Friday, time.Saturday}, Location: paris, Mode: ModeLocal},
+		},
+		{
+			name: "profile and region",
+			cfg:  config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00", Profile: " dev ", Region: "eu-west-1"},
+			want: &Schedule{Name: "dev", TagKey: "Environment", TagValue: "dev", Hour: 19, Location: time.Local, Profile: "dev", Region: "eu-west-1", Mode: ModeLocal},
+		},
+		{
+			name: "eventbridge in UTC",
+			cfg:  config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00", Mode: "eventbridge", RoleARN: "arn:aws:iam::123456789012:role/scheduler"},
+			want: &Schedule{Name: "dev", TagKey: "Environment", TagValue: "dev", Hour: 19, Location: time.UTC, Mode: ModeEventBridge, RoleARN: "arn:aws:iam::123456789012:role/scheduler"},
+		},
+		{
+			name: "eventbridge in a time zone",
+			cfg:  config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00", Timezone: "Europe/Paris", Mode: "eventbridge", RoleARN: "arn:aws:iam::123456789012:role/scheduler"},
+			want: &Schedule{Name: "dev", TagKey: "Environment", TagValue: "dev", Hour: 19, Location: paris, Mode: ModeEventBridge, RoleARN: "arn:aws:iam::123456789012:role/scheduler"},
+		},
+		{name: "no name", cfg: config.ScheduleConfig{Tag: "Environment=dev", Stop: "19:00"}, wantErr: "schedule without name"},
+		{name: "tag key only", cfg: config.ScheduleConfig{Name: "dev", Tag: "Environment", Stop: "19:00"}, wantErr: "key=value"},
+		{name: "tag without key", cfg: config.ScheduleConfig{Name: "dev", Tag: "=dev", Stop: "19:00"}, wantErr: "key=value"},
+		{name: "tag without value", cfg: config.ScheduleConfig{Name: "dev", Tag: "Environment=", Stop: "19:00"}, wantErr: "key=value"},
+		{name: "stop time", cfg: config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "7pm"}, wantErr: "HH:MM"},
+		{name: "stop hour", cfg: config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "24:00"}, wantErr: "HH:MM"},
+		{name: "day", cfg: config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00", Days: []string{"weekend"}}, wantErr: `unknown day "weekend"`},
+		{name: "time zone", cfg: config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00", Timezone: "Mars/Olympus"}, wantErr: "Mars/Olympus"},
+		{name: "mode", cfg: config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00", Mode: "cron"}, wantErr: `unknown mode "cron"`},
+		{name: "eventbridge without role", cfg: config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00", Mode: "eventbridge"}, wantErr: "role_arn is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSchedules(t *testing.T) {
+	schedules := NewSchedules(testLogger(), []config.ScheduleConfig{
+		{Name: "dev", Tag: "Environment=dev", Stop: "19:00"},
+		{Name: "invalid", Tag: "Environment", Stop: "19:00"},
+		{Name: "staging", Tag: "Environment=staging", Stop: "20:00"},
+	})
+	if len(schedules) != 2 || schedules[0].Name != "dev" || schedules[1].Name != "staging" {
+		t.Errorf("NewSchedules() = %+v, want the valid schedules", schedules)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	s, err := New(config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"tag:Environment":     {"dev"},
+		"instance-state-name": {"running"},
+	}
+	if got := s.Filters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Filters() = %v, want %v", got, want)
+	}
+	if got := s.Selector(); got != "Environment=dev" {
+		t.Errorf("Selector() = %q, want Environment=dev", got)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	schedules := []*Schedule{
+		{Name: "started"},
+		{Name: "region", Region: "us-east-1"},
+		{Name: "profile", Profile: "dev"},
+		{Name: "both", Profile: "dev", Region: "us-east-1"},
+	}
+	SetDefaults(schedules, "prod", "eu-west-1")
+
+	want := map[string]string{
+		"started": "prod/eu-west-1",
+		"region":  "prod/us-east-1",
+		"profile": "dev/eu-west-1",
+		"both":    "dev/us-east-1",
+	}
+	for _, s := range schedules {
+		if got := s.Target(); got != want[s.Name] {
+			t.Errorf("Target() of %s = %q, want %q", s.Name, got, want[s.Name])
+		}
+	}
+
+	// Without profile, the default credentials are used
+	s := &Schedule{Name: "default"}
+	SetDefaults([]*Schedule{s}, "", "eu-west-1")
+	if got := s.Target(); got != "eu-west-1" {
+		t.Errorf("Target() = %q, want eu-west-1", got)
+	}
+}
+
+func TestNext(t *testing.T) {
+	paris := mustLocation(t, "Europe/Paris")
+	newYork := mustLocation(t, "America/New_York")
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		after    time.Time
+		want     time.Time
+	}{
+		{
+			name:     "later today",
+			schedule: Schedule{Hour: 19, Location: time.UTC},
+			after:    time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 13, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "at the time",
+			schedule: Schedule{Hour: 19, Location: time.UTC},
+			after:    time.Date(2024, 3, 13, 19, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 14, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "just before the time",
+			schedule: Schedule{Hour: 19, Minute: 30, Location: time.UTC},
+			after:    time.Date(2024, 3, 13, 19, 29, 59, 0, time.UTC),
+			want:     time.Date(2024, 3, 13, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "tomorrow",
+			schedule: Schedule{Hour: 19, Location: time.UTC},
+			after:    time.Date(2024, 3, 13, 20, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 14, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "midnight, before the day boundary",
+			schedule: Schedule{Location: time.UTC},
+			after:    time.Date(2024, 3, 13, 23, 59, 59, 0, time.UTC),
+			want:     time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "midnight, at the day boundary",
+			schedule: Schedule{Location: time.UTC},
+			after:    time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "end of the year",
+			schedule: Schedule{Hour: 19, Location: time.UTC},
+			after:    time.Date(2024, 12, 31, 20, 0, 0, 0, time.UTC),
+			want:     time.Date(2025, 1, 1, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "next day of the week",
+			schedule: Schedule{Hour: 19, Days: []time.Weekday{time.Monday, time.Friday}, Location: time.UTC},
+			after:    time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC), // Wednesday
+			want:     time.Date(2024, 3, 15, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "same day next week",
+			schedule: Schedule{Hour: 19, Days: []time.Weekday{time.Wednesday}, Location: time.UTC},
+			after:    time.Date(2024, 3, 13, 19, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 20, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "time zone of the schedule",
+			schedule: Schedule{Hour: 19, Location: paris},
+			after:    time.Date(2024, 3, 13, 17, 30, 0, 0, time.UTC), // 18:30 in Paris
+			want:     time.Date(2024, 3, 13, 19, 0, 0, 0, paris),
+		},
+		{
+			name:     "day of the time zone",
+			schedule: Schedule{Hour: 1, Days: []time.Weekday{time.Thursday}, Location: paris},
+			after:    time.Date(2024, 3, 13, 23, 30, 0, 0, time.UTC), // Thursday 00:30 in Paris
+			want:     time.Date(2024, 3, 14, 1, 0, 0, 0, paris),
+		},
+		{
+			name:     "daylight saving time",
+			schedule: Schedule{Hour: 19, Location: newYork},
+			after:    time.Date(2024, 3, 9, 20, 0, 0, 0, newYork), // The clocks change on the 10th
+			want:     time.Date(2024, 3, 10, 19, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.schedule.Next(tt.after)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.after, got, tt.want)
+			}
+			if got.Location() != tt.schedule.Location {
+				t.Errorf("Next(%v) is in %v, want %v", tt.after, got.Location(), tt.schedule.Location)
+			}
+		})
+	}
+}
+
+func TestNextLocal(t *testing.T) {
+	s, err := New(config.ScheduleConfig{Name: "dev", Tag: "Environment=dev", Stop: "19:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stop is at 19:00 on the clock of the machine running e2c
+	now := time.Now()
+	next := s.Next(now)
+	if next.Location() != time.Local || next.Hour() != 19 || next.Minute() != 0 {
+		t.Errorf("Next() = %v, want 19:00 in the local time zone", next)
+	}
+	if !next.After(now) || next.Sub(now) > 25*time.Hour {
+		t.Errorf("Next() = %v, want the next 19:00 after %v", next, now)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	paris := mustLocation(t, "Europe/Paris")
+
+	tests := []struct {
+		schedule   Schedule
+		expression string
+		text       string
+	}{
+		{schedule: Schedule{Hour: 19, Location: time.Local}, expression: "cron(0 19 ? * * *)", text: "19:00"},
+		{schedule: Schedule{Hour: 7, Minute: 5, Days: []time.Weekday{time.Monday, time.Friday}, Location: time.Local}, expression: "cron(5 7 ? * MON,FRI *)", text: "07:05 mon,fri"},
+		{schedule: Schedule{Hour: 19, Days: []time.Weekday{time.Sunday}, Location: paris}, expression: "cron(0 19 ? * SUN *)", text: "19:00 sun Europe/Paris"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := tt.schedule.Expression(); got != tt.expression {
+				t.Errorf("Expression() = %q, want %q", got, tt.expression)
+			}
+			if got := tt.schedule.String(); got != tt.text {
+				t.Errorf("String() = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestRunnerApply(t *testing.T) {
+	dev := &Schedule{Name: "dev", TagKey: "Environment", TagValue: "dev", Hour: 19, Location: time.UTC, Mode: ModeLocal}
+	failing := &Schedule{Name: "failing", TagKey: "Environment", TagValue: "test", Hour: 19, Location: time.UTC, Mode: ModeLocal}
+	remote := &Schedule{Name: "remote", TagKey: "Environment", TagValue: "staging", Hour: 19, Location: time.UTC, Mode: ModeEventBridge}
+
+	var stopped []string
+	r := NewRunner(testLogger(), []*Schedule{dev, failing, remote}, func(ctx context.Context, s *Schedule) (int, error) {
+		stopped = append(stopped, s.Name)
+		if s == failing {
+			return 0, errors.New("access denied")
+		}
+		return 2, nil
+	})
+	if got := len(r.Schedules()); got != 2 {
+		t.Fatalf("Schedules() = %d schedules, want the local ones only", got)
+	}
+
+	stop := time.Date(2024, 3, 13, 19, 0, 0, 0, time.UTC)
+	for _, s := range r.Schedules() {
+		r.next[s.Name] = stop
+	}
+
+	// Not due yet
+	r.apply(context.Background(), stop.Add(-time.Second))
+	if len(stopped) != 0 {
+		t.Fatalf("stopped %v before the time", stopped)
+	}
+
+	// Due, the next stop being the next day even if the stop failed
+	r.apply(context.Background(), stop.Add(10*time.Second))
+	if want := []string{"dev", "failing"}; !reflect.DeepEqual(stopped, want) {
+		t.Fatalf("stopped %v, want %v", stopped, want)
+	}
+	for _, name := range []string{"dev", "failing"} {
+		if got, want := r.Next(name), stop.AddDate(0, 0, 1); !got.Equal(want) {
+			t.Errorf("Next(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	// Applied once per day
+	r.apply(context.Background(), stop.Add(40*time.Second))
+	if len(stopped) != 2 {
+		t.Errorf("stopped %v, want the schedules applied once", stopped)
+	}
+	if got := r.Next("remote"); !got.IsZero() {
+		t.Errorf("Next(remote) = %v, want zero for a schedule not applied locally", got)
+	}
+}
@@ -0,0 +1,297 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/ui"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/model/modeltest"
+)
+
+const (
+	// region is the region of the fake backend
+	region = "us-east-1"
+
+	// stepTimeout is how long a step waits for the UI to reach the expected state
+	stepTimeout = 10 * time.Second
+
+	// pollInterval is the interval between two checks of the screen
+	pollInterval = 50 * time.Millisecond
+)
+
+// instances are the instances served by the fake backend
+var instances = []model.Instance{
+	modeltest.NewInstance().WithID("i-0selftest0000001").WithName("selftest-web-1").WithType("m5.large").
+		WithTag("Environment", "selftest").Build(),
+	modeltest.NewInstance().WithID("i-0selftest0000002").WithName("selftest-web-2").WithType("m5.large").
+		WithTag("Environment", "selftest").WithPrivateIP("10.0.1.11").Build(),
+	modeltest.NewInstance().WithID("i-0selftest0000003").WithName("selftest-db-1").WithType("r5.xlarge").
+		WithTag("Environment", "selftest").WithPrivateIP("10.0.2.10").Stopped().Build(),
+}
+
+// step is a flow of the UI exercised by the self-test
+type step struct {
+	name string
+	run  func(r *runner) error
+}
+
+// steps are the flows exercised by the self-test, in order
+var steps = []step{
+	{name: "load instances", run: (*runner).loadInstances},
+	{name: "refresh", run: (*runner).refresh},
+	{name: "filter", run: (*runner).filter},
+	{name: "detail", run: (*runner).detail},
+	{name: "stop", run: (*runner).stop},
+	{name: "start", run: (*runner).start},
+	{name: "quit", run: (*runner).quit},
+}
+
+// runner drives the UI through a simulation screen
+type runner struct {
+	screen  tcell.SimulationScreen
+	backend *aws.FakeBackend
+	done    chan error // Result of the UI once stopped
+}
+
+// Run runs the UI headlessly on a simulation screen against the fake
+// backend, exercises the main flows and reports them to out. It returns an
+// error if any flow fails.
+func Run(ctx context.Context, log *slog.Logger, out io.Writer) error {
+	cfg, err := config.Default()
+	if err != nil {
+		return err
+	}
+	cfg.AWS.DefaultRegion = region
+	cfg.UI.KeymapFile = os.DevNull // Default key bindings
+	cfg.UI.Skin = os.DevNull       // Default colors
+	cfg.Audit.Enabled = false
+
+	backend := aws.NewFakeBackend(append([]model.Instance(nil), instances...))
+	client := aws.NewFakeEC2Client(log, region, backend, ui.Telemetry(nil))
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	app := ui.NewUI(log, client, cfg, nil)
+	app.SetScreen(screen)
+	screen.SetSize(160, 50)
+
+	r := &runner{
+		screen:  screen,
+		backend: backend,
+		done:    make(chan error, 1),
+	}
+	go func() {
+		r.done <- app.Start()
+	}()
+
+	var failed error
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			failed = err
+			break
+		}
+
+		start := time.Now()
+		if err := s.run(r); err != nil {
+			fmt.Fprintf(out, "FAIL %s (%s): %v\n", s.name, time.Since(start).Round(time.Millisecond), err)
+			fmt.Fprintf(out, "\nScreen:\n%s\n", r.text())
+			failed = fmt.Errorf("self-test failed at step %q: %w", s.name, err)
+			break
+		}
+		fmt.Fprintf(out, "ok   %s (%s)\n", s.name, time.Since(start).Round(time.Millisecond))
+	}
+
+	if failed != nil {
+		app.Stop()
+		<-r.done
+		return failed
+	}
+	return nil
+}
+
+// loadInstances waits for the splash screen to be replaced by the instances
+func (r *runner) loadInstances() error {
+	if err := r.waitFor("instances listed", func(text string) bool {
+		return strings.Contains(text, "selftest-web-1") &&
+			strings.Contains(text, "selftest-db-1") &&
+			!strings.Contains(text, "Load first page of instances")
+	}); err != nil {
+		return err
+	}
+	return r.expectCalls("DescribeInstances", 1)
+}
+
+// refresh refreshes the instances and checks that they are retrieved again
+func (r *runner) refresh() error {
+	calls := r.backend.Calls("DescribeInstances")
+	r.press('r')
+	return r.expectCalls("DescribeInstances", calls+1)
+}
+
+// filter filters the instances on the name of one of them
+func (r *runner) filter() error {
+	r.press('f')
+	if err := r.waitForText("Filter Instances"); err != nil {
+		return err
+	}
+	r.typeText("web-1")
+	r.pressKey(tcell.KeyEnter) // Focus the Apply button
+	r.pressKey(tcell.KeyEnter)
+
+	return r.waitFor("only selftest-web-1 listed", func(text string) bool {
+		return strings.Contains(text, "selftest-web-1") &&
+			!strings.Contains(text, "selftest-web-2") &&
+			!strings.Contains(text, "selftest-db-1")
+	})
+}
+
+// detail opens the details of the filtered instance, and the Security tab
+// whose data is loaded when it is opened
+func (r *runner) detail() error {
+	r.pressKey(tcell.KeyEnter)
+	if err := r.waitForText("Instance Details"); err != nil {
+		return err
+	}
+
+	r.press('5')
+	if err := r.waitForText("Termination Protection"); err != nil {
+		return err
+	}
+
+	r.pressKey(tcell.KeyEscape)
+	return r.waitFor("details closed", func(text string) bool {
+		return !strings.Contains(text, "Instance Details")
+	})
+}
+
+// stop stops the filtered instance
+func (r *runner) stop() error {
+	return r.changeState('p', "Stop Instance", instances[0].ID, "stopped")
+}
+
+// start starts the instance stopped by the previous step
+func (r *runner) start() error {
+	return r.changeState('s', "Start Instance", instances[0].ID, "running")
+}
+
+// changeState presses the key of an action, confirms it, and waits for the
+// instance to be in the expected state in the backend and in the table
+func (r *runner) changeState(key rune, title, instanceID, state string) error {
+	r.press(key)
+	if err := r.waitForText(title); err != nil {
+		return err
+	}
+	r.pressKey(tcell.KeyEnter) // Yes
+
+	if err := r.waitUntil(fmt.Sprintf("%s %s in the backend", instanceID, state), func() bool {
+		return r.backend.State(instanceID) == state
+	}); err != nil {
+		return err
+	}
+	return r.waitFor(fmt.Sprintf("%s %s in the table", instanceID, state), func(text string) bool {
+		return !strings.Contains(text, title) && rowContains(text, "selftest-web-1", state)
+	})
+}
+
+// quit quits the UI and waits for it to stop
+func (r *runner) quit() error {
+	r.press('q')
+	select {
+	case err := <-r.done:
+		return err
+	case <-time.After(stepTimeout):
+		return errors.New("the UI did not stop")
+	}
+}
+
+// press injects a character key
+func (r *runner) press(key rune) {
+	r.screen.InjectKey(tcell.KeyRune, key, tcell.ModNone)
+}
+
+// pressKey injects a special key
+func (r *runner) pressKey(key tcell.Key) {
+	r.screen.InjectKey(key, 0, tcell.ModNone)
+}
+
+// typeText injects the characters of a text
+func (r *runner) typeText(text string) {
+	for _, char := range text {
+		r.press(char)
+	}
+}
+
+// expectCalls waits for an action to be called at least count times
+func (r *runner) expectCalls(action string, count int) error {
+	return r.waitUntil(fmt.Sprintf("%d calls of %s", count, action), func() bool {
+		return r.backend.Calls(action) >= count
+	})
+}
+
+// waitForText waits for a text to be displayed
+func (r *runner) waitForText(expected string) error {
+	return r.waitFor(fmt.Sprintf("%q displayed", expected), func(text string) bool {
+		return strings.Contains(text, expected)
+	})
+}
+
+// waitFor waits for the text of the screen to match a condition
+func (r *runner) waitFor(description string, match func(text string) bool) error {
+	return r.waitUntil(description, func() bool {
+		return match(r.text())
+	})
+}
+
+// waitUntil waits for a condition to be true, until the step timeout
+func (r *runner) waitUntil(description string, condition func() bool) error {
+	deadline := time.Now().Add(stepTimeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return nil
+		}
+		time.Sleep(pollInterval)
+	}
+	return fmt.Errorf("timed out waiting for %s", description)
+}
+
+// text returns the text displayed on the screen, one line per row
+func (r *runner) text() string {
+	cells, width, height := r.screen.GetContents()
+
+	var b strings.Builder
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			runes := cells[y*width+x].Runes
+			if len(runes) == 0 {
+				b.WriteByte(' ')
+				continue
+			}
+			b.WriteString(string(runes))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// rowContains returns true if a line of the text contains both values
+func rowContains(text, first, second string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Contains(line, first) && strings.Contains(line, second) {
+			return true
+		}
+	}
+	return false
+}
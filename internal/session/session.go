@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfile is the key of the state saved without an AWS profile
+const DefaultProfile = "default"
+
+// File is the content of the state file: the state of the UI saved for each
+// AWS profile, so that the region of a profile is never restored with
+// another one
+type File struct {
+	Profiles map[string]*State `yaml:"profiles,omitempty"`
+}
+
+// State is the state of the UI saved when e2c exits and restored on the next
+// start with the same profile
+type State struct {
+	Region    string         `yaml:"region,omitempty"`
+	View      string         `yaml:"view,omitempty"` // Displayed view, instances or vpcs
+	Instances InstancesState `yaml:"instances"`
+}
+
+// InstancesState is the state of the instances table
+type InstancesState struct {
+	Filter   string `yaml:"filter,omitempty"`
+	Selected string `yaml:"selected,omitempty"` // ID of the selected instance
+	// SortColumn is the name of the sorted column rather than its index, so
+	// that the sort survives a change of the tag and plugin columns
+	SortColumn string       `yaml:"sort_column,omitempty"`
+	SortDesc   bool         `yaml:"sort_desc,omitempty"`
+	GroupBy    string       `yaml:"group_by,omitempty"` // state, zone, type, stack, asg or tag:<key>
+	Columns    ColumnsState `yaml:"columns,omitempty"`
+}
+
+// ColumnsState is the layout of the columns of the instances table, after
+// the default ones
+type ColumnsState struct {
+	Tags    []string `yaml:"tags,omitempty"`    // Keys of the tag columns, in order
+	Plugins []string `yaml:"plugins,omitempty"` // Names of the plugin columns, in order
+}
+
+// profileKey returns the key of the state of a profile
+func profileKey(profile string) string {
+	if profile == "" {
+		return DefaultProfile
+	}
+	return profile
+}
+
+// State returns the state saved with a profile, an empty one if none
+func (f *File) State(profile string) *State {
+	if state, ok := f.Profiles[profileKey(profile)]; ok && state != nil {
+		return state
+	}
+	return &State{}
+}
+
+// Set replaces the state saved with a profile
+func (f *File) Set(profile string, state *State) {
+	if f.Profiles == nil {
+		f.Profiles = make(map[string]*State)
+	}
+	f.Profiles[profileKey(profile)] = state
+}
+
+// Regions returns the regions saved, by profile, DefaultProfile for the one
+// saved without a profile
+func (f *File) Regions() map[string]string {
+	regions := make(map[string]string, len(f.Profiles))
+	for profile, state := range f.Profiles {
+		if state != nil && state.Region != "" {
+			regions[profile] = state.Region
+		}
+	}
+	return regions
+}
+
+// DefaultPath returns the path of the state file, ~/.config/e2c/state.yaml
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "state.yaml"
+	}
+	return filepath.Join(home, ".config", "e2c", "state.yaml")
+}
+
+// Load reads a state file, returning an empty one if it does not exist
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state %s: %w", path, err)
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Save writes the state of a profile to a state file, keeping the states of
+// the other profiles. An unreadable state file is replaced.
+func Save(path, profile string, state *State) error {
+	file, err := Load(path)
+	if err != nil {
+		file = &File{}
+	}
+	file.Set(profile, state)
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create the state directory: %w", err)
+	}
+	// Write a temporary file first so that an interrupted write does not
+	// leave a truncated state behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", path, err)
+	}
+	return nil
+}
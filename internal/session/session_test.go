@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveByProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+
+	dev := &State{
+		Region: "eu-west-1",
+		Instances: InstancesState{
+			Filter:  "web",
+			Columns: ColumnsState{Tags: []string{"Team"}, Plugins: []string{"Cost"}},
+		},
+	}
+	if err := Save(path, "", dev); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	prod := &State{Region: "us-east-1", View: "vpcs"}
+	if err := Save(path, "prod", prod); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	file, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := file.State(""); !reflect.DeepEqual(got, dev) {
+		t.Errorf("State(\"\") = %+v, want %+v", got, dev)
+	}
+	if got := file.State(DefaultProfile); !reflect.DeepEqual(got, dev) {
+		t.Errorf("State(%q) = %+v, want %+v", DefaultProfile, got, dev)
+	}
+	if got := file.State("prod"); !reflect.DeepEqual(got, prod) {
+		t.Errorf("State(\"prod\") = %+v, want %+v", got, prod)
+	}
+	if got := file.State("staging"); !reflect.DeepEqual(got, &State{}) {
+		t.Errorf("State(\"staging\") = %+v, want an empty state", got)
+	}
+
+	want := map[string]string{DefaultProfile: "eu-west-1", "prod": "us-east-1"}
+	if got := file.Regions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Regions() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	file, err := Load(filepath.Join(t.TempDir(), "state.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(file.Profiles) != 0 {
+		t.Errorf("Load() = %+v, want no state", file)
+	}
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sshkey parses the SSH public keys, and builds the shell script
+// adding one to the authorized_keys of a user of an instance.
+package sshkey
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// types are the types of the SSH public keys accepted
+var types = map[string]bool{
+	"ssh-ed25519":                        true,
+	"ssh-rsa":                            true,
+	"ecdsa-sha2-nistp256":                true,
+	"ecdsa-sha2-nistp384":                true,
+	"ecdsa-sha2-nistp521":                true,
+	"sk-ssh-ed25519@openssh.com":         true,
+	"sk-ecdsa-sha2-nistp256@openssh.com": true,
+}
+
+// DefaultFiles are the public keys read, in this order, when none is given
+var DefaultFiles = []string{"id_ed25519.pub", "id_ecdsa.pub", "id_rsa.pub"}
+
+// userPattern matches the names of the users of the instances, a portable
+// subset of the names accepted by useradd
+var userPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,31}$`)
+
+// Key is an SSH public key, as written in the authorized_keys files
+type Key struct {
+	Type    string // e.g. ssh-ed25519
+	Blob    []byte // Key in the SSH wire format
+	Comment string // Usually user@host, may be empty
+}
+
+// Parse parses an SSH public key of the form type base64 [comment], without
+// options
+func Parse(line string) (Key, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Key{}, errors.New("no public key given")
+	}
+	if strings.ContainsAny(line, "\r\n") {
+		return Key{}, errors.New("the public key must be on a single line")
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Key{}, errors.New("the public key must be of the form type base64 [comment]")
+	}
+	if !types[fields[0]] {
+		return Key{}, fmt.Errorf("unsupported key type %q", fields[0])
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	// The wire format starts with the length-prefixed type of the key
+	if len(blob) < 4 {
+		return Key{}, errors.New("invalid public key: too short")
+	}
+	size := binary.BigEndian.Uint32(blob)
+	if uint64(len(blob)) < 4+uint64(size) || string(blob[4:4+size]) != fields[0] {
+		return Key{}, fmt.Errorf("invalid public key: the key is not of type %s", fields[0])
+	}
+
+	return Key{
+		Type:    fields[0],
+		Blob:    blob,
+		Comment: strings.Join(fields[2:], " "),
+	}, nil
+}
+
+// String returns the key as written in the authorized_keys files
+func (k Key) String() string {
+	line := k.Type + " " + base64.StdEncoding.EncodeToString(k.Blob)
+	if k.Comment != "" {
+		line += " " + k.Comment
+	}
+	return line
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key, as displayed by
+// ssh-keygen -l
+func (k Key) Fingerprint() string {
+	sum := sha256.Sum256(k.Blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// ReadDefault returns the first public key of DefaultFiles found in
+// ~/.ssh, and its path
+func ReadDefault() (Key, string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Key{}, "", err
+	}
+	for _, name := range DefaultFiles {
+		path := filepath.Join(home, ".ssh", name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		key, err := Parse(string(data))
+		if err != nil {
+			return Key{}, "", fmt.Errorf("%s: %w", path, err)
+		}
+		return key, path, nil
+	}
+	return Key{}, "", fmt.Errorf("no public key found in ~/.ssh (%s)", strings.Join(DefaultFiles, ", "))
+}
+
+// ValidUser returns an error if a name is not a valid user name
+func ValidUser(user string) error {
+	if !userPattern.MatchString(user) {
+		return fmt.Errorf("invalid user name %q", user)
+	}
+	return nil
+}
+
+// AuthorizeScript returns the shell script appending a key to the
+// authorized_keys of a user, unless it is there already, creating the file
+// with the permissions required by sshd. It prints added or present.
+func AuthorizeScript(user string, key Key) string {
+	return strings.Join([]string{
+		"set -eu",
+		"user=" + quote(user),
+		"key=" + quote(key.String()),
+		`home=$(getent passwd "$user" | cut -d: -f6)`,
+		`if [ -z "$home" ]; then echo "no user $user" >&2; exit 1; fi`,
+		`mkdir -p "$home/.ssh"`,
+		`touch "$home/.ssh/authorized_keys"`,
+		`if grep -qxF "$key" "$home/.ssh/authorized_keys"; then echo present; else printf '%s\n' "$key" >> "$home/.ssh/authorized_keys"; echo added; fi`,
+		`chmod 700 "$home/.ssh"`,
+		`chmod 600 "$home/.ssh/authorized_keys"`,
+		`chown "$user" "$home/.ssh" "$home/.ssh/authorized_keys"`,
+	}, "\n")
+}
+
+// quote quotes a value for the shell
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// s3Scheme prefixes the patterns of the states stored in an S3 backend, e.g.
+// s3://my-states/env/*/terraform.tfstate
+const s3Scheme = "s3://"
+
+// ObjectReader reads the states stored in S3 backends, implemented by the
+// EC2 client
+type ObjectReader interface {
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+	ListObjectKeys(ctx context.Context, bucket, prefix string) ([]string, error)
+}
+
+// Resource represents a Terraform resource managing an EC2 instance
+type Resource struct {
+	Address   string // Resource address (e.g., module.web.aws_instance.this[0])
+	Module    string // Module path, empty for the root module
+	StateFile string // State file declaring the resource, an s3:// URL for an S3 backend
+}
+
+// Index maps EC2 instance IDs to the Terraform resources managing them
+type Index struct {
+	resources map[string]Resource
+}
+
+// Lookup returns the Terraform resource managing an instance
+func (i *Index) Lookup(instanceID string) (Resource, bool) {
+	if i == nil {
+		return Resource{}, false
+	}
+	resource, ok := i.resources[instanceID]
+	return resource, ok
+}
+
+// Len returns the number of instances in the index
+func (i *Index) Len() int {
+	if i == nil {
+		return 0
+	}
+	return len(i.resources)
+}
+
+// state is the subset of the Terraform state format (version 4) used by e2c
+type state struct {
+	Version   int `json:"version"`
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey   json.RawMessage `json:"index_key"`
+			Attributes struct {
+				ID string `json:"id"`
+			} `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// LoadIndex reads the Terraform state files matching the given glob
+// patterns and indexes the aws_instance resources they contain. The patterns
+// are local paths, or s3:// URLs of states stored in S3 backends read with
+// objects.
+func LoadIndex(ctx context.Context, log *slog.Logger, patterns []string, objects ObjectReader) (*Index, error) {
+	index := &Index{
+		resources: make(map[string]Resource),
+	}
+
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, s3Scheme) {
+			if err := index.loadS3(ctx, log, pattern, objects); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		files, err := filepath.Glob(expandHome(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid state file pattern %q: %w", pattern, err)
+		}
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err == nil {
+				err = index.load(file, data)
+			}
+			if err != nil {
+				log.Warn("Failed to read Terraform state", "file", file, "error", err)
+				continue
+			}
+		}
+	}
+
+	log.Info("Loaded Terraform states", "instances", len(index.resources))
+
+	return index, nil
+}
+
+// loadS3 indexes the instances of the states of an S3 backend matching a
+// pattern of the form s3://bucket/key, whose key may contain wildcards
+func (i *Index) loadS3(ctx context.Context, log *slog.Logger, pattern string, objects ObjectReader) error {
+	bucket, keyPattern, _ := strings.Cut(strings.TrimPrefix(pattern, s3Scheme), "/")
+	if bucket == "" || keyPattern == "" {
+		return fmt.Errorf("invalid state file pattern %q: expected s3://bucket/key", pattern)
+	}
+	if _, err := path.Match(keyPattern, ""); err != nil {
+		return fmt.Errorf("invalid state file pattern %q: %w", pattern, err)
+	}
+
+	keys := []string{keyPattern}
+	if wildcard := strings.IndexAny(keyPattern, "*?[\\"); wildcard >= 0 {
+		// List the keys sharing the prefix before the first wildcard
+		listed, err := objects.ListObjectKeys(ctx, bucket, keyPattern[:wildcard])
+		if err != nil {
+			log.Warn("Failed to list Terraform states", "pattern", pattern, "error", err)
+			return nil
+		}
+		keys = keys[:0]
+		for _, key := range listed {
+			if matched, _ := path.Match(keyPattern, key); matched {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		file := s3Scheme + bucket + "/" + key
+		data, err := objects.GetObject(ctx, bucket, key)
+		if err == nil {
+			err = i.load(file, data)
+		}
+		if err != nil {
+			log.Warn("Failed to read Terraform state", "file", file, "error", err)
+		}
+	}
+	return nil
+}
+
+// load indexes the instances of the content of a state file
+func (i *Index) load(file string, data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("failed to parse state: %w", err)
+	}
+	if s.Version != 4 {
+		return fmt.Errorf("unsupported state version %d", s.Version)
+	}
+
+	for _, resource := range s.Resources {
+		if resource.Mode != "managed" || resource.Type != "aws_instance" {
+			continue
+		}
+
+		address := resource.Type + "." + resource.Name
+		if resource.Module != "" {
+			address = resource.Module + "." + address
+		}
+
+		for _, instance := range resource.Instances {
+			if instance.Attributes.ID == "" {
+				continue
+			}
+
+			instanceAddress := address
+			if len(instance.IndexKey) > 0 {
+				instanceAddress += "[" + string(instance.IndexKey) + "]"
+			}
+
+			i.resources[instance.Attributes.ID] = Resource{
+				Address:   instanceAddress,
+				Module:    resource.Module,
+				StateFile: file,
+			}
+		}
+	}
+
+	return nil
+}
+
+// expandHome replaces a leading ~ with the user home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// scope is the instrumentation scope of the traces and the metrics
+const scope = "github.com/nlamirault/e2c/internal/trace"
+
+// Exporter records the traces, and the metrics if any, with the
+// OpenTelemetry SDK, and sends them to a collector with OTLP over HTTP in
+// the background, or appends them to a file for local debugging
+type Exporter struct {
+	log     *slog.Logger
+	target  string // Endpoint or file, for the logs
+	version string
+	traces  *sdktrace.TracerProvider
+	meters  *sdkmetric.MeterProvider // nil if the metrics are not exported
+	metrics *Metrics                 // nil if the metrics are not exported
+	close   func() error             // Closes the file of the file exporter
+}
+
+// NewExporter creates an exporter sending the traces to the OTLP/HTTP
+// endpoint of a collector, e.g. http://localhost:4318, and the metrics at
+// an interval, 0 not exporting them
+func NewExporter(log *slog.Logger, endpoint, service, version string, metricsInterval time.Duration) (*Exporter, error) {
+	ctx := context.Background()
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	spans, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the trace exporter: %w", err)
+	}
+
+	var metrics sdkmetric.Exporter
+	if metricsInterval > 0 {
+		metrics, err = otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the metric exporter: %w", err)
+		}
+	}
+	return newExporter(log, endpoint, service, version, spans, periodicReader(metrics, metricsInterval), nil)
+}
+
+// NewFileExporter creates an exporter appending the traces and the metrics
+// to a file as JSON, one span or collection of metrics per line as the
+// stdout exporters of the SDK, to debug them without a collector
+func NewFileExporter(log *slog.Logger, path, service, version string, metricsInterval time.Duration) (*Exporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	w := &lockedWriter{w: file}
+
+	spans, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create the trace exporter: %w", err)
+	}
+	var metrics sdkmetric.Exporter
+	if metricsInterval > 0 {
+		metrics, err = stdoutmetric.New(stdoutmetric.WithWriter(w))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create the metric exporter: %w", err)
+		}
+	}
+	return newExporter(log, path, service, version, spans, periodicReader(metrics, metricsInterval), file.Close)
+}
+
+// periodicReader returns the reader collecting the metrics at an interval
+// for an exporter, nil if the metrics are not exported
+func periodicReader(exporter sdkmetric.Exporter, interval time.Duration) sdkmetric.Reader {
+	if exporter == nil {
+		return nil
+	}
+	return sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))
+}
+
+// newExporter creates an exporter sending the spans in batches to an
+// exporter of the SDK, and the metrics collected by a reader if not nil
+func newExporter(log *slog.Logger, target, service, version string, spans sdktrace.SpanExporter, reader sdkmetric.Reader, closeFile func() error) (*Exporter, error) {
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(service),
+		semconv.ServiceVersion(version))
+
+	e := &Exporter{
+		log:     log,
+		target:  target,
+		version: version,
+		traces:  sdktrace.NewTracerProvider(sdktrace.WithBatcher(spans), sdktrace.WithResource(res)),
+		close:   closeFile,
+	}
+	if reader != nil {
+		e.meters = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))
+		metrics, err := NewMetrics(e.meters)
+		if err != nil {
+			return nil, err
+		}
+		e.metrics = metrics
+	}
+	return e, nil
+}
+
+// Metrics returns the metrics sent by the exporter, nil if none
+func (e *Exporter) Metrics() *Metrics {
+	if e == nil {
+		return nil
+	}
+	return e.metrics
+}
+
+// Shutdown sends the pending traces and the metrics and waits until they
+// are sent, or the context is done. No trace nor metric must be recorded
+// anymore.
+func (e *Exporter) Shutdown(ctx context.Context) {
+	if err := e.traces.Shutdown(ctx); err != nil {
+		e.log.Warn("Traces not sent before exit", "target", e.target, "error", err)
+	}
+	if e.meters != nil {
+		if err := e.meters.Shutdown(ctx); err != nil {
+			e.log.Warn("Metrics not sent before exit", "target", e.target, "error", err)
+		}
+	}
+	if e.close != nil {
+		if err := e.close(); err != nil {
+			e.log.Warn("Failed to close the telemetry file", "file", e.target, "error", err)
+		}
+	}
+}
+
+// lockedWriter serializes the writes of the trace and the metric exporters
+// to the same file, one line each
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write writes p with the lock held
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package trace
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Names of the metrics
+const (
+	MetricCallDuration    = "e2c.aws.call.duration"
+	MetricCallErrors      = "e2c.aws.call.errors"
+	MetricRefreshDuration = "e2c.refresh.duration"
+)
+
+// durationBounds are the upper bounds of the buckets of the durations, in
+// seconds
+var durationBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics records the latency and the errors of the calls to the AWS APIs,
+// and the duration of the refreshes of the instances, since the start of
+// e2c. Its methods are safe for concurrent use, and do nothing on a nil
+// Metrics.
+type Metrics struct {
+	callDuration    metric.Float64Histogram
+	callErrors      metric.Int64Counter
+	refreshDuration metric.Float64Histogram
+}
+
+// NewMetrics creates the instruments of the metrics with a meter provider
+func NewMetrics(provider metric.MeterProvider) (*Metrics, error) {
+	meter := provider.Meter(scope)
+
+	callDuration, err := meter.Float64Histogram(MetricCallDuration,
+		metric.WithDescription("Duration of the calls to the AWS APIs, retries included"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBounds...))
+	if err != nil {
+		return nil, err
+	}
+	callErrors, err := meter.Int64Counter(MetricCallErrors,
+		metric.WithDescription("Calls to the AWS APIs that failed"),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return nil, err
+	}
+	refreshDuration, err := meter.Float64Histogram(MetricRefreshDuration,
+		metric.WithDescription("Duration of the refreshes of the instances, all the pages included"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBounds...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		callDuration:    callDuration,
+		callErrors:      callErrors,
+		refreshDuration: refreshDuration,
+	}, nil
+}
+
+// RecordCall records a call to an AWS API, retries included, with its error
+// if it failed
+func (m *Metrics) RecordCall(service, operation, region string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	attributes := metric.WithAttributes(
+		attribute.String("rpc.system", "aws-api"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", operation),
+		attribute.String("cloud.region", region),
+	)
+
+	ctx := context.Background()
+	m.callDuration.Record(ctx, d.Seconds(), attributes)
+	if err != nil {
+		m.callErrors.Add(ctx, 1, attributes)
+	}
+}
+
+// RecordRefresh records a refresh of the instances of a region, all their
+// pages included
+func (m *Metrics) RecordRefresh(region string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	m.refreshDuration.Record(context.Background(), d.Seconds(), metric.WithAttributes(
+		attribute.String("cloud.region", region),
+		attribute.String("result", result),
+	))
+}
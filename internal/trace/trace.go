@@ -0,0 +1,410 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package trace records the user actions of the UI as traces: a root span
+// from the keypress until the UI is rendered once the action is done, with
+// child spans for the key handler, the calls to the AWS APIs and the
+// renderings, to tell the time spent waiting for AWS from the time spent
+// drawing. The operations not triggered by the user, e.g. the auto-refresh,
+// are traced in the background. The actions are kept to show the latency
+// breakdown of the last one, and recorded with the OpenTelemetry SDK to
+// export them, with the metrics of the calls to AWS and of the refreshes.
+package trace
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Kinds of spans, as in OpenTelemetry
+const (
+	KindInternal = "internal"
+	KindClient   = "client"
+)
+
+// Span is a timed operation of a user action
+type Span struct {
+	Name       string
+	Kind       string
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]string
+	Err        string // Error of the operation, if it failed
+
+	action *Action
+	span   oteltrace.Span // Span recorded with the SDK
+}
+
+// Duration returns the duration of the span, zero until it has ended
+func (s *Span) Duration() time.Duration {
+	if s.EndTime.IsZero() {
+		return 0
+	}
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// End ends the span with the error of the operation, if any
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	t := s.action.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s.EndTime = time.Now()
+	if err != nil {
+		s.Err = err.Error()
+	}
+	s.action.pending--
+	s.end()
+}
+
+// end ends the span recorded with the SDK at the end time of the span
+func (s *Span) end() {
+	if s.Err != "" {
+		s.span.SetStatus(codes.Error, s.Err)
+	}
+	s.span.End(oteltrace.WithTimestamp(s.EndTime))
+}
+
+// Action is the trace of a user action. It ends at the first rendering once
+// its key handler and all its calls to AWS are done, and is open again if
+// another call starts later with its context.
+type Action struct {
+	tracer     *Tracer
+	root       *Span
+	ctx        context.Context // Context of the root span, parent of the spans
+	spans      []*Span
+	pending    int // Spans started but not ended
+	ended      bool
+	background bool // Not triggered by the user, ended by End
+}
+
+// StartSpan starts a child span of the action, ended by its End method
+func (a *Action) StartSpan(name, kind string, attributes map[string]string) *Span {
+	if a == nil {
+		return nil
+	}
+	t := a.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := t.start(a.ctx, name, kind, time.Now(), attributes)
+	span.action = a
+	a.spans = append(a.spans, span)
+	a.pending++
+	a.ended = false
+	return span
+}
+
+// Tracer records the user actions, one at a time: starting an action exports
+// the previous one
+type Tracer struct {
+	mu        sync.Mutex
+	log       *slog.Logger
+	tracer    oteltrace.Tracer
+	exporter  *Exporter // nil if the traces are not exported
+	current   *Action
+	drawStart time.Time
+}
+
+// NewTracer creates a tracer exporting the actions with the given exporter,
+// or only keeping the last one if it is nil
+func NewTracer(log *slog.Logger, exporter *Exporter) *Tracer {
+	var tracer oteltrace.Tracer = noop.NewTracerProvider().Tracer(scope)
+	if exporter != nil {
+		tracer = exporter.traces.Tracer(scope, oteltrace.WithInstrumentationVersion(exporter.version))
+	}
+	return &Tracer{
+		log:      log,
+		tracer:   tracer,
+		exporter: exporter,
+	}
+}
+
+// StartAction starts the trace of a user action
+func (t *Tracer) StartAction(name string) *Action {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.exportCurrent()
+	action := t.newAction(name, nil)
+	t.current = action
+	return action
+}
+
+// newAction starts the root span of an action. It must be called with the
+// lock held for a user action.
+func (t *Tracer) newAction(name string, attributes map[string]string) *Action {
+	action := &Action{tracer: t}
+	action.root = t.start(context.Background(), name, KindInternal, time.Now(), attributes)
+	action.root.action = action
+	action.ctx = oteltrace.ContextWithSpan(context.Background(), action.root.span)
+	return action
+}
+
+// start starts a span, recorded with the SDK as a child of the span of ctx
+func (t *Tracer) start(ctx context.Context, name, kind string, start time.Time, attributes map[string]string) *Span {
+	_, span := t.tracer.Start(ctx, name,
+		oteltrace.WithSpanKind(spanKind(kind)),
+		oteltrace.WithTimestamp(start),
+		oteltrace.WithAttributes(attributesOf(attributes)...))
+	return &Span{
+		Name:       name,
+		Kind:       kind,
+		StartTime:  start,
+		Attributes: attributes,
+		span:       span,
+	}
+}
+
+// spanKind returns the kind of a span in the SDK
+func spanKind(kind string) oteltrace.SpanKind {
+	if kind == KindClient {
+		return oteltrace.SpanKindClient
+	}
+	return oteltrace.SpanKindInternal
+}
+
+// attributesOf returns string attributes for the SDK, sorted by key
+func attributesOf(values map[string]string) []attribute.KeyValue {
+	attributes := make([]attribute.KeyValue, 0, len(values))
+	for key, value := range values {
+		attributes = append(attributes, attribute.String(key, value))
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Key < attributes[j].Key
+	})
+	return attributes
+}
+
+// StartBackground starts the trace of an operation not triggered by the
+// user, e.g. the auto-refresh, ended by its End method. It is not the current
+// action, and is exported once it has ended.
+func (t *Tracer) StartBackground(name string, attributes map[string]string) *Action {
+	action := t.newAction(name, attributes)
+	action.background = true
+	return action
+}
+
+// End ends the trace of an operation in the background with its error, if
+// any, and exports it. The spans not ended yet are exported once ended.
+func (a *Action) End(err error) {
+	if a == nil || !a.background {
+		return
+	}
+	t := a.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if a.ended {
+		return
+	}
+	a.root.EndTime = time.Now()
+	if err != nil {
+		a.root.Err = err.Error()
+	}
+	a.ended = true
+	t.export(a)
+}
+
+// Current returns the action being traced, nil if it has ended
+func (t *Tracer) Current() *Action {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current == nil || t.current.ended {
+		return nil
+	}
+	return t.current
+}
+
+// BeforeDraw marks the start of a rendering of the UI
+func (t *Tracer) BeforeDraw() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.drawStart = time.Now()
+}
+
+// AfterDraw records the rendering in the current action, and ends the action
+// if it is done
+func (t *Tracer) AfterDraw() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	action := t.current
+	if action == nil || action.ended || t.drawStart.IsZero() {
+		return
+	}
+	now := time.Now()
+	render := t.start(action.ctx, "render", KindInternal, t.drawStart, nil)
+	render.action = action
+	render.EndTime = now
+	render.end()
+	action.spans = append(action.spans, render)
+	if action.pending == 0 {
+		action.root.EndTime = now
+		action.ended = true
+	}
+}
+
+// Shutdown exports the last action and waits for the exporter to send the
+// traces and the metrics, or the context to be done
+func (t *Tracer) Shutdown(ctx context.Context) {
+	t.mu.Lock()
+	t.exportCurrent()
+	t.current = nil
+	exporter := t.exporter
+	t.exporter = nil
+	t.mu.Unlock()
+
+	if exporter != nil {
+		exporter.Shutdown(ctx)
+	}
+}
+
+// exportCurrent sends the current action to the exporter. It must be called
+// with the lock held.
+func (t *Tracer) exportCurrent() {
+	if t.current != nil {
+		t.export(t.current)
+	}
+}
+
+// export ends the root span of an action, for the SDK to export the trace.
+// It must be called with the lock held.
+func (t *Tracer) export(action *Action) {
+	if action.root.EndTime.IsZero() {
+		action.root.EndTime = time.Now()
+	}
+	action.root.end()
+	if t.exporter != nil {
+		t.log.Debug("Exporting trace", "action", action.root.Name,
+			"traceID", action.root.span.SpanContext().TraceID().String(), "spans", len(action.spans)+1)
+	}
+}
+
+// Summary is the latency breakdown of a user action
+type Summary struct {
+	Name   string
+	Open   bool          // The action is not done yet
+	Total  time.Duration // From the keypress to the last rendering
+	Handle time.Duration // Key handler
+	API    time.Duration // Calls to AWS, the concurrent calls counted once
+	Render time.Duration // Renderings of the UI
+	Calls  []Span        // Calls to AWS, by start time
+}
+
+// Other returns the time of the action neither spent in the key handler,
+// waiting for AWS nor rendering, e.g. in a confirmation dialog
+func (s Summary) Other() time.Duration {
+	other := s.Total - s.Handle - s.API - s.Render
+	if other < 0 {
+		return 0
+	}
+	return other
+}
+
+// Last returns the latency breakdown of the last action, false if none was
+// traced
+func (t *Tracer) Last() (Summary, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	action := t.current
+	if action == nil {
+		return Summary{}, false
+	}
+
+	summary := Summary{
+		Name: action.root.Name,
+		Open: !action.ended,
+	}
+	end := action.root.EndTime
+	if summary.Open {
+		end = time.Now()
+	}
+	summary.Total = end.Sub(action.root.StartTime)
+
+	var calls []*Span
+	for _, span := range action.spans {
+		switch {
+		case span.Kind == KindClient:
+			calls = append(calls, span)
+			summary.Calls = append(summary.Calls, *span)
+		case span.Name == "render":
+			summary.Render += span.Duration()
+		case span.Name == "handle":
+			summary.Handle += span.Duration()
+		}
+	}
+	summary.API = union(calls, end)
+	return summary, true
+}
+
+// union returns the time covered by the spans, the spans not ended yet
+// running until end
+func union(spans []*Span, end time.Time) time.Duration {
+	sorted := make([]*Span, len(spans))
+	copy(sorted, spans)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartTime.Before(sorted[j].StartTime)
+	})
+
+	var total time.Duration
+	var from, to time.Time
+	for _, span := range sorted {
+		spanEnd := span.EndTime
+		if spanEnd.IsZero() {
+			spanEnd = end
+		}
+		if span.StartTime.After(to) {
+			total += to.Sub(from)
+			from, to = span.StartTime, spanEnd
+		} else if spanEnd.After(to) {
+			to = spanEnd
+		}
+	}
+	return total + to.Sub(from)
+}
+
+type contextKey struct{}
+
+// WithAction returns a context carrying a user action, so that the calls to
+// AWS made with it are recorded as its spans
+func WithAction(ctx context.Context, action *Action) context.Context {
+	if action == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, action)
+}
+
+// FromContext returns the user action carried by a context, nil if none
+func FromContext(ctx context.Context) *Action {
+	action, _ := ctx.Value(contextKey{}).(*Action)
+	return action
+}
+
+// CallTracer records the calls to AWS made with the context of a user action
+// as client spans of the action
+type CallTracer struct{}
+
+// StartCall starts the client span of a call made with ctx, and returns the
+// function ending it, nil if ctx carries no user action
+func (CallTracer) StartCall(ctx context.Context, name string, attributes map[string]string) func(err error) {
+	action := FromContext(ctx)
+	if action == nil {
+		return nil
+	}
+	return action.StartSpan(name, KindClient, attributes).End
+}
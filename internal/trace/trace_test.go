@@ -0,0 +1,295 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// traceAction traces a user action with a key handler, a call to AWS
+// failing and a rendering
+func traceAction(tracer *Tracer) {
+	action := tracer.StartAction("refresh")
+	handle := action.StartSpan("handle", KindInternal, map[string]string{"key": "r"})
+	end := CallTracer{}.StartCall(WithAction(context.Background(), action), "EC2.DescribeInstances", map[string]string{"cloud.region": "eu-west-1"})
+	end(errors.New("throttled"))
+	handle.End(nil)
+	tracer.BeforeDraw()
+	tracer.AfterDraw()
+}
+
+// collector records the OTLP/HTTP export requests
+type collector struct {
+	mu      sync.Mutex
+	traces  []*coltracepb.ExportTraceServiceRequest
+	metrics []*colmetricpb.ExportMetricsServiceRequest
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if got := r.Header.Get("Content-Type"); got != "application/x-protobuf" {
+		http.Error(w, "unexpected content type "+got, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/traces":
+		request := &coltracepb.ExportTraceServiceRequest{}
+		err = proto.Unmarshal(body, request)
+		c.traces = append(c.traces, request)
+	case "/v1/metrics":
+		request := &colmetricpb.ExportMetricsServiceRequest{}
+		err = proto.Unmarshal(body, request)
+		c.metrics = append(c.metrics, request)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+}
+
+// stringAttributes returns the string attributes by key
+func stringAttributes(attributes []*commonpb.KeyValue) map[string]string {
+	values := make(map[string]string, len(attributes))
+	for _, attribute := range attributes {
+		values[attribute.Key] = attribute.Value.GetStringValue()
+	}
+	return values
+}
+
+func TestExporterOTLP(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	exporter, err := NewExporter(testLogger(), server.URL+"/", "e2c", "1.2.3", time.Hour)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	tracer := NewTracer(testLogger(), exporter)
+	traceAction(tracer)
+	exporter.Metrics().RecordCall("EC2", "DescribeInstances", "eu-west-1", 150*time.Millisecond, errors.New("throttled"))
+	exporter.Metrics().RecordRefresh("eu-west-1", 2*time.Second, nil)
+	tracer.Shutdown(context.Background())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	spans := make(map[string]*tracepb.Span)
+	for _, request := range c.traces {
+		for _, resourceSpans := range request.ResourceSpans {
+			resource := stringAttributes(resourceSpans.Resource.Attributes)
+			if resource["service.name"] != "e2c" || resource["service.version"] != "1.2.3" {
+				t.Errorf("resource = %v, want service e2c 1.2.3", resource)
+			}
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				if scopeSpans.Scope.Name != scope {
+					t.Errorf("scope = %q, want %q", scopeSpans.Scope.Name, scope)
+				}
+				for _, span := range scopeSpans.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+	}
+	if len(spans) != 4 {
+		t.Fatalf("spans = %d, want the action, handle, the call and render", len(spans))
+	}
+
+	root := spans["refresh"]
+	if len(root.ParentSpanId) != 0 || root.Kind != tracepb.Span_SPAN_KIND_INTERNAL {
+		t.Errorf("root span = %v, want an internal span without parent", root)
+	}
+	for _, name := range []string{"handle", "EC2.DescribeInstances", "render"} {
+		span := spans[name]
+		if string(span.TraceId) != string(root.TraceId) || string(span.ParentSpanId) != string(root.SpanId) {
+			t.Errorf("span %s is not a child of the action", name)
+		}
+		if span.EndTimeUnixNano < span.StartTimeUnixNano {
+			t.Errorf("span %s ends before it starts", name)
+		}
+	}
+	call := spans["EC2.DescribeInstances"]
+	if call.Kind != tracepb.Span_SPAN_KIND_CLIENT {
+		t.Errorf("call kind = %v, want client", call.Kind)
+	}
+	if call.Status.Code != tracepb.Status_STATUS_CODE_ERROR || call.Status.Message != "throttled" {
+		t.Errorf("call status = %v, want the error", call.Status)
+	}
+	if got := stringAttributes(call.Attributes)["cloud.region"]; got != "eu-west-1" {
+		t.Errorf("call region = %q, want eu-west-1", got)
+	}
+	if got := stringAttributes(spans["handle"].Attributes)["key"]; got != "r" {
+		t.Errorf("handle key = %q, want r", got)
+	}
+
+	if len(c.metrics) == 0 {
+		t.Fatal("no metrics sent at shutdown")
+	}
+	metrics := c.metrics[len(c.metrics)-1].ResourceMetrics[0].ScopeMetrics[0].Metrics
+	byName := make(map[string]int)
+	for i, metric := range metrics {
+		byName[metric.Name] = i
+	}
+	duration := metrics[byName[MetricCallDuration]].GetHistogram()
+	if duration == nil || len(duration.DataPoints) != 1 {
+		t.Fatalf("%s = %v, want a histogram with a point", MetricCallDuration, duration)
+	}
+	point := duration.DataPoints[0]
+	if point.Count != 1 || len(point.ExplicitBounds) != len(durationBounds) || len(point.BucketCounts) != len(durationBounds)+1 {
+		t.Errorf("%s point = %v, want a call in the duration buckets", MetricCallDuration, point)
+	}
+	if point.BucketCounts[5] != 1 {
+		t.Errorf("%s buckets = %v, want the call in ]0.1, 0.25]", MetricCallDuration, point.BucketCounts)
+	}
+	errorsSum := metrics[byName[MetricCallErrors]].GetSum()
+	if errorsSum == nil || !errorsSum.IsMonotonic || errorsSum.DataPoints[0].GetAsInt() != 1 {
+		t.Errorf("%s = %v, want a monotonic sum of 1", MetricCallErrors, errorsSum)
+	}
+	refresh := metrics[byName[MetricRefreshDuration]].GetHistogram()
+	if refresh == nil || stringAttributes(refresh.DataPoints[0].Attributes)["result"] != "success" {
+		t.Errorf("%s = %v, want a successful refresh", MetricRefreshDuration, refresh)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "e2c", "telemetry.jsonl")
+	exporter, err := NewFileExporter(testLogger(), path, "e2c", "1.2.3", time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+	tracer := NewTracer(testLogger(), exporter)
+	traceAction(tracer)
+	exporter.Metrics().RecordRefresh("eu-west-1", time.Second, nil)
+	tracer.Shutdown(context.Background())
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	names := make(map[string]bool)
+	var metrics int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line struct {
+			Name         string
+			ScopeMetrics []json.RawMessage
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		if line.Name != "" {
+			names[line.Name] = true
+		}
+		metrics += len(line.ScopeMetrics)
+	}
+	for _, name := range []string{"refresh", "handle", "EC2.DescribeInstances", "render"} {
+		if !names[name] {
+			t.Errorf("span %s not written, got %v", name, names)
+		}
+	}
+	if metrics == 0 {
+		t.Error("metrics not written at shutdown")
+	}
+}
+
+func TestLast(t *testing.T) {
+	tracer := NewTracer(testLogger(), nil)
+	if _, ok := tracer.Last(); ok {
+		t.Fatal("Last() = true before any action")
+	}
+
+	action := tracer.StartAction("refresh")
+	handle := action.StartSpan("handle", KindInternal, nil)
+	end := CallTracer{}.StartCall(WithAction(context.Background(), action), "EC2.DescribeInstances", nil)
+	handle.End(nil)
+	tracer.BeforeDraw()
+	tracer.AfterDraw()
+
+	summary, ok := tracer.Last()
+	if !ok || !summary.Open || summary.Name != "refresh" || len(summary.Calls) != 1 {
+		t.Fatalf("Last() = %+v, want the open action with its call", summary)
+	}
+	if tracer.Current() != action {
+		t.Error("Current() is not the action waiting for its call")
+	}
+
+	end(nil)
+	tracer.BeforeDraw()
+	tracer.AfterDraw()
+	summary, _ = tracer.Last()
+	if summary.Open || summary.Calls[0].EndTime.IsZero() {
+		t.Errorf("Last() = %+v, want the action done once rendered after its call", summary)
+	}
+	if tracer.Current() != nil {
+		t.Error("Current() is not nil once the action is done")
+	}
+}
+
+func TestUnion(t *testing.T) {
+	base := time.Now()
+	span := func(from, to int) *Span {
+		s := &Span{StartTime: base.Add(time.Duration(from) * time.Second)}
+		if to >= 0 {
+			s.EndTime = base.Add(time.Duration(to) * time.Second)
+		}
+		return s
+	}
+	end := base.Add(10 * time.Second)
+
+	tests := []struct {
+		name  string
+		spans []*Span
+		want  time.Duration
+	}{
+		{"none", nil, 0},
+		{"one", []*Span{span(1, 3)}, 2 * time.Second},
+		{"disjoint", []*Span{span(5, 6), span(1, 3)}, 3 * time.Second},
+		{"overlapping", []*Span{span(1, 4), span(2, 6)}, 5 * time.Second},
+		{"nested", []*Span{span(1, 6), span(2, 3)}, 5 * time.Second},
+		{"not ended", []*Span{span(1, 2), span(8, -1)}, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := union(tt.spans, end); got != tt.want {
+				t.Errorf("union() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tunnel manages the SSM port forwarding sessions started from e2c,
+// run by the AWS CLI and the Session Manager plugin as child processes.
+package tunnel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// stopTimeout is how long a session is given to close once interrupted,
+	// before it is killed
+	stopTimeout = 5 * time.Second
+
+	// maxOutputLines is the number of lines of output kept per session
+	maxOutputLines = 20
+
+	// readyMessage is printed by the Session Manager plugin once the local
+	// port accepts connections
+	readyMessage = "Waiting for connections"
+)
+
+// ErrNoPlugin is returned when the AWS CLI or the Session Manager plugin is
+// not installed
+var ErrNoPlugin = errors.New("the AWS CLI and the Session Manager plugin are required (aws, session-manager-plugin)")
+
+// Spec describes a port forwarding session
+type Spec struct {
+	InstanceID   string
+	InstanceName string
+	LocalPort    int
+	RemotePort   int
+	RemoteHost   string   // Host reached through the instance, e.g. a database, empty for the instance itself
+	Env          []string // Environment of the AWS CLI, with the credentials and the region
+}
+
+// Target returns the remote end of the session, e.g. i-0123:5432 or
+// db.internal:5432
+func (s Spec) Target() string {
+	host := s.InstanceID
+	if s.RemoteHost != "" {
+		host = s.RemoteHost
+	}
+	return net.JoinHostPort(host, strconv.Itoa(s.RemotePort))
+}
+
+// Tunnel is a port forwarding session
+type Tunnel struct {
+	Spec
+	ID      int
+	Started time.Time
+
+	cmd  *exec.Cmd
+	done chan struct{} // Closed once the process has exited
+
+	mu      sync.Mutex
+	err     error    // Why the process exited, if it did
+	output  []string // Last lines of output
+	partial string   // Output not ended by a newline yet
+	ready   bool
+	stopped bool // Stopped from e2c
+}
+
+// Status returns the state of the session: starting, ready, stopped or the
+// reason it exited
+func (t *Tunnel) Status() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		switch {
+		case t.stopped:
+			return "stopped"
+		case t.err != nil:
+			return "exited: " + t.err.Error()
+		default:
+			return "exited"
+		}
+	default:
+	}
+	if t.ready {
+		return "ready"
+	}
+	return "starting"
+}
+
+// Running returns true until the process has exited
+func (t *Tunnel) Running() bool {
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Output returns the last lines of output of the session
+func (t *Tunnel) Output() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lines := append([]string(nil), t.output...)
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Write records the output of the process, keeping the last lines
+func (t *Tunnel) Write(data []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := strings.Split(t.partial+string(data), "\n")
+	t.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, readyMessage) {
+			t.ready = true
+		}
+		t.output = append(t.output, line)
+	}
+	if len(t.output) > maxOutputLines {
+		t.output = t.output[len(t.output)-maxOutputLines:]
+	}
+	return len(data), nil
+}
+
+// Manager starts, lists and stops the port forwarding sessions
+type Manager struct {
+	log      *slog.Logger
+	onChange func() // Called when a session starts, is ready or exits
+
+	mu      sync.Mutex
+	tunnels []*Tunnel
+	nextID  int
+}
+
+// NewManager creates a manager of port forwarding sessions, calling onChange
+// from any goroutine when the state of a session changes
+func NewManager(log *slog.Logger, onChange func()) *Manager {
+	return &Manager{
+		log:      log,
+		onChange: onChange,
+		nextID:   1,
+	}
+}
+
+// Start starts a port forwarding session with the AWS CLI, once the local
+// port is checked to be free
+func (m *Manager) Start(spec Spec) (*Tunnel, error) {
+	if _, err := exec.LookPath("session-manager-plugin"); err != nil {
+		return nil, ErrNoPlugin
+	}
+	aws, err := exec.LookPath("aws")
+	if err != nil {
+		return nil, ErrNoPlugin
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(spec.LocalPort)))
+	if err != nil {
+		return nil, fmt.Errorf("local port %d is not available: %w", spec.LocalPort, err)
+	}
+	listener.Close()
+
+	document := "AWS-StartPortForwardingSession"
+	parameters := map[string][]string{
+		"portNumber":      {strconv.Itoa(spec.RemotePort)},
+		"localPortNumber": {strconv.Itoa(spec.LocalPort)},
+	}
+	if spec.RemoteHost != "" {
+		document = "AWS-StartPortForwardingSessionToRemoteHost"
+		parameters["host"] = []string{spec.RemoteHost}
+	}
+	encoded, err := json.Marshal(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tunnel{
+		Spec:    spec,
+		Started: time.Now(),
+		done:    make(chan struct{}),
+	}
+	t.cmd = exec.Command(aws, "ssm", "start-session",
+		"--target", spec.InstanceID,
+		"--document-name", document,
+		"--parameters", string(encoded))
+	t.cmd.Env = spec.Env
+	t.cmd.Stdout = t
+	t.cmd.Stderr = t
+
+	if err := t.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start the session: %w", err)
+	}
+
+	m.mu.Lock()
+	t.ID = m.nextID
+	m.nextID++
+	m.tunnels = append(m.tunnels, t)
+	m.mu.Unlock()
+
+	m.log.Info("Port forwarding session started", "id", t.ID, "instanceID", spec.InstanceID,
+		"localPort", spec.LocalPort, "target", spec.Target(), "pid", t.cmd.Process.Pid)
+
+	go m.wait(t)
+	go m.watchReady(t)
+	m.changed()
+
+	return t, nil
+}
+
+// wait waits for the process of a session to exit
+func (m *Manager) wait(t *Tunnel) {
+	err := t.cmd.Wait()
+
+	t.mu.Lock()
+	t.err = err
+	stopped := t.stopped
+	t.mu.Unlock()
+	close(t.done)
+
+	if err != nil && !stopped {
+		m.log.Error("Port forwarding session exited", "id", t.ID, "instanceID", t.InstanceID, "error", err, "output", t.Output())
+	} else {
+		m.log.Info("Port forwarding session exited", "id", t.ID, "instanceID", t.InstanceID)
+	}
+	m.changed()
+}
+
+// watchReady reports the session once the local port accepts connections
+func (m *Manager) watchReady(t *Tunnel) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		}
+		if t.Status() == "ready" {
+			m.changed()
+			return
+		}
+	}
+}
+
+// changed notifies the change of a session
+func (m *Manager) changed() {
+	if m.onChange != nil {
+		m.onChange()
+	}
+}
+
+// List returns the sessions, running or exited, in the order they were
+// started
+func (m *Manager) List() []*Tunnel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Tunnel(nil), m.tunnels...)
+}
+
+// Active returns the number of sessions running
+func (m *Manager) Active() int {
+	active := 0
+	for _, t := range m.List() {
+		if t.Running() {
+			active++
+		}
+	}
+	return active
+}
+
+// Stop stops a running session, or removes an exited one from the list
+func (m *Manager) Stop(id int) error {
+	m.mu.Lock()
+	var t *Tunnel
+	for i, tunnel := range m.tunnels {
+		if tunnel.ID == id {
+			t = tunnel
+			if !tunnel.Running() {
+				m.tunnels = append(m.tunnels[:i], m.tunnels[i+1:]...)
+			}
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if t == nil {
+		return fmt.Errorf("no session %d", id)
+	}
+	if !t.Running() {
+		m.changed()
+		return nil
+	}
+	return m.stop(t)
+}
+
+// stop interrupts the process of a session so that it closes the session,
+// and kills it if it does not exit in time
+func (m *Manager) stop(t *Tunnel) error {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+
+	// The AWS CLI terminates the session on interrupt, which Windows does not
+	// support
+	if runtime.GOOS == "windows" || t.cmd.Process.Signal(os.Interrupt) != nil {
+		return t.cmd.Process.Kill()
+	}
+
+	select {
+	case <-t.done:
+		return nil
+	case <-time.After(stopTimeout):
+		m.log.Warn("Port forwarding session did not stop, killing it", "id", t.ID)
+		return t.cmd.Process.Kill()
+	}
+}
+
+// StopAll stops all the running sessions, e.g. when e2c exits
+func (m *Manager) StopAll() {
+	var wg sync.WaitGroup
+	for _, t := range m.List() {
+		if !t.Running() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.stop(t); err != nil {
+				m.log.Error("Failed to stop port forwarding session", "id", t.ID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
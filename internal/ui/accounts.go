@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// account is an AWS account of the aggregated instance list, with its own
+// client
+type account struct {
+	name   string
+	client *aws.EC2Client
+	err    error // Error creating the client, if any
+}
+
+// accountResult is the outcome of the listing of the instances of an account
+type accountResult struct {
+	account   *account
+	instances []model.Instance
+	err       error
+}
+
+// setupAccounts creates the clients of the configured accounts in a region,
+// replacing the previous ones. A client which cannot be created is reported
+// as a failure of its account at each refresh.
+func (ui *UI) setupAccounts(region string) {
+	accounts := make([]*account, 0, len(ui.config().Accounts))
+	for _, cfg := range ui.config().Accounts {
+		profile := cfg.Profile
+		if profile == "" {
+			profile = ui.config().AWS.Profile
+		}
+
+		// The name identifies the account of the instances
+		name := cfg.Name
+		if name == "" {
+			name = cmp.Or(cfg.AssumeRole.RoleARN, profile, "default")
+		}
+
+		a := &account{name: name}
+		a.client, a.err = aws.NewEC2Client(ui.awsLog, region, profile, aws.AssumeRole(cfg.AssumeRole), aws.CallOptions(ui.config().AWS.Calls), ui.telemetry())
+		if a.err != nil {
+			ui.log.Error("Failed to create the client of account", "account", name, "error", a.err)
+		} else {
+			a.client.SetAuditLog(ui.ec2Client().AuditLog())
+			a.client.SetMFAPrompt(ui.promptMFA)
+			a.client.SetMutationHook(ui.instanceMutated)
+		}
+		accounts = append(accounts, a)
+	}
+
+	ui.accountsMutex.Lock()
+	ui.accounts = accounts
+	ui.accountsMutex.Unlock()
+}
+
+// clientFor returns the client of the account of an instance
+func (ui *UI) clientFor(instance model.Instance) *aws.EC2Client {
+	if instance.Account == "" {
+		return ui.ec2Client()
+	}
+
+	ui.accountsMutex.Lock()
+	defer ui.accountsMutex.Unlock()
+	for _, a := range ui.accounts {
+		if a.name == instance.Account && a.client != nil {
+			return a.client
+		}
+	}
+	return ui.ec2Client()
+}
+
+// clientForID returns the client of the account of an instance of the last
+// refresh, given its ID
+func (ui *UI) clientForID(id string) *aws.EC2Client {
+	for _, instance := range ui.store.Snapshot().Instances {
+		if instance.ID == id {
+			return ui.clientFor(instance)
+		}
+	}
+	return ui.ec2Client()
+}
+
+// clientsForIDs groups the IDs of instances by the client of their account
+func (ui *UI) clientsForIDs(ids []string) map[*aws.EC2Client][]string {
+	clients := make(map[*aws.EC2Client][]string)
+	for _, id := range ids {
+		client := ui.clientForID(id)
+		clients[client] = append(clients[client], id)
+	}
+	return clients
+}
+
+// clientsOf returns the clients of the accounts of instances which may not
+// be displayed, by instance ID
+func (ui *UI) clientsOf(instances []model.Instance) map[string]*aws.EC2Client {
+	clients := make(map[string]*aws.EC2Client, len(instances))
+	for _, instance := range instances {
+		clients[instance.ID] = ui.clientFor(instance)
+	}
+	return clients
+}
+
+// listAccountsInstances lists the instances of all the accounts concurrently.
+// The instances of the accounts which succeeded are returned, the failures
+// are reported per account; the listing fails only if all the accounts
+// failed.
+func (ui *UI) listAccountsInstances(ctx context.Context, filters map[string][]string) ([]model.Instance, error) {
+	ui.accountsMutex.Lock()
+	accounts := ui.accounts
+	ui.accountsMutex.Unlock()
+
+	results := make([]accountResult, len(accounts))
+	var wg sync.WaitGroup
+	for i, a := range accounts {
+		results[i].account = a
+		if a.err != nil {
+			results[i].err = a.err
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *accountResult) {
+			defer wg.Done()
+			result.instances, result.err = result.account.client.ListInstances(ctx, filters)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var instances []model.Instance
+	var failures []string
+	var lastErr error
+	for _, result := range results {
+		if result.err != nil {
+			ui.log.Error("Failed to list the instances of account", "account", result.account.name, "error", result.err)
+			failures = append(failures, result.account.name)
+			lastErr = result.err
+			continue
+		}
+		for _, instance := range result.instances {
+			instance.Account = result.account.name
+			instances = append(instances, instance)
+		}
+	}
+
+	if len(failures) == len(accounts) {
+		return nil, lastErr
+	}
+	if len(failures) > 0 {
+		ui.app.QueueUpdateDraw(func() {
+			ui.statusBar.SetError(fmt.Sprintf("Error: failed to list the instances of %d/%d accounts: %s (see the logs)",
+				len(failures), len(accounts), strings.Join(failures, ", ")))
+		})
+	}
+	return instances, nil
+}
+
+// listAccountsEvents retrieves the scheduled events of the instances of all
+// the accounts
+func (ui *UI) listAccountsEvents(ctx context.Context) map[string][]model.ScheduledEvent {
+	ui.accountsMutex.Lock()
+	accounts := ui.accounts
+	ui.accountsMutex.Unlock()
+
+	events := make(map[string][]model.ScheduledEvent)
+	for _, a := range accounts {
+		feature := featureEvents + " of " + a.name
+		if a.client == nil || !ui.featureEnabled(feature) {
+			continue
+		}
+		accountEvents, err := a.client.ListScheduledEvents(ctx)
+		if err != nil {
+			if ctx.Err() != nil || ui.checkFeatureError(feature, err) {
+				continue
+			}
+			ui.log.Error("Failed to list scheduled events", "account", a.name, "error", err)
+			continue
+		}
+		maps.Copy(events, accountEvents)
+	}
+	return events
+}
+
+// waitInstancesRunning waits for instances, possibly of several accounts, to
+// be running
+func (ui *UI) waitInstancesRunning(ctx context.Context, ids []string, timeout time.Duration) error {
+	for client, clientIDs := range ui.clientsForIDs(ids) {
+		if err := client.WaitInstancesRunning(ctx, clientIDs, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
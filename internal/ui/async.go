@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// asyncTTL is how long the data fetched by the async tabs is cached
+const asyncTTL = time.Minute
+
+// asyncEntry is data fetched from AWS, cached by key
+type asyncEntry struct {
+	value     any
+	err       error
+	fetchedAt time.Time
+}
+
+// asyncCache caches the data of the async tabs per instance, so that
+// opening the details of an instance again does not fetch everything again.
+// It is only accessed from the UI goroutine.
+type asyncCache struct {
+	ttl     time.Duration
+	entries map[string]asyncEntry
+}
+
+// newAsyncCache creates a cache keeping the entries for the given TTL
+func newAsyncCache(ttl time.Duration) *asyncCache {
+	return &asyncCache{
+		ttl:     ttl,
+		entries: make(map[string]asyncEntry),
+	}
+}
+
+// get returns the entry of a key if it has not expired
+func (c *asyncCache) get(key string) (asyncEntry, bool) {
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.fetchedAt) > c.ttl {
+		return asyncEntry{}, false
+	}
+	return entry, true
+}
+
+// set stores the entry of a key
+func (c *asyncCache) set(key string, entry asyncEntry) {
+	c.entries[key] = entry
+}
+
+// deletePrefix removes the entries whose key starts with the prefix, e.g.
+// all the entries of an instance
+func (c *asyncCache) deletePrefix(prefix string) {
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// asyncLoader is async data of any type, loaded when its tab is displayed
+type asyncLoader interface {
+	Load(force bool)
+	Status() string
+}
+
+// asyncData is data of a tab fetched from AWS when the tab is displayed:
+// it renders its loading state while fetching, can be fetched again on
+// demand, and is cached per instance in the cache of the UI.
+type asyncData[T any] struct {
+	ui       *UI
+	key      string                               // Cache key, e.g. i-0123/status
+	fetch    func(ctx context.Context) (T, error) // Fetches the data from AWS
+	onUpdate func()                               // Renders the data once fetched
+
+	value     T
+	err       error
+	loaded    bool
+	loading   bool
+	fetchedAt time.Time
+}
+
+// newAsyncData creates the async data of a tab, rendered by onUpdate from
+// the UI goroutine once fetched
+func newAsyncData[T any](ui *UI, key string, fetch func(ctx context.Context) (T, error), onUpdate func()) *asyncData[T] {
+	return &asyncData[T]{
+		ui:       ui,
+		key:      key,
+		fetch:    fetch,
+		onUpdate: onUpdate,
+	}
+}
+
+// Load fetches the data unless it was loaded already, or is cached and has
+// not expired. With force, the data is fetched again in any case.
+func (a *asyncData[T]) Load(force bool) {
+	if a.loading || (a.loaded && !force) {
+		return
+	}
+	// The data is cached in the session it was fetched in, not in the one
+	// displayed once fetched
+	cache := a.ui.asyncCache()
+	if !force {
+		if entry, ok := cache.get(a.key); ok {
+			a.apply(entry)
+			return
+		}
+	}
+
+	a.loading = true
+	go func() {
+		value, err := a.fetch(a.ui.ctx)
+		if err != nil {
+			a.ui.log.Error("Failed to fetch tab data", "key", a.key, "error", err)
+		}
+		entry := asyncEntry{value: value, err: err, fetchedAt: time.Now()}
+
+		a.ui.app.QueueUpdateDraw(func() {
+			a.loading = false
+			cache.set(a.key, entry)
+			a.apply(entry)
+		})
+	}()
+	a.onUpdate()
+}
+
+// apply sets the data from a cache entry and renders it
+func (a *asyncData[T]) apply(entry asyncEntry) {
+	a.value, _ = entry.value.(T)
+	a.err = entry.err
+	a.fetchedAt = entry.fetchedAt
+	a.loaded = true
+	a.onUpdate()
+}
+
+// Value returns the data, and whether it was loaded without error
+func (a *asyncData[T]) Value() (T, bool) {
+	return a.value, a.loaded && a.err == nil
+}
+
+// Render writes the state of the data: not loaded, loading, the error, or
+// the data itself rendered by render
+func (a *asyncData[T]) Render(b *strings.Builder, render func(value T)) {
+	switch {
+	case a.loading && !a.loaded:
+		b.WriteString("  [gray]Loading...[-]\n")
+	case !a.loaded:
+		b.WriteString("  [gray]Not loaded[-]\n")
+	case a.err != nil:
+		fmt.Fprintf(b, "  [red]%s[-]\n", tview.Escape(a.err.Error()))
+	default:
+		render(a.value)
+	}
+}
+
+// Status returns a short description of the freshness of the data
+func (a *asyncData[T]) Status() string {
+	switch {
+	case a.loading:
+		return "loading..."
+	case a.loaded:
+		return "updated " + a.fetchedAt.Format("15:04:05")
+	default:
+		return "not loaded"
+	}
+}
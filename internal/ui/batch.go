@@ -0,0 +1,443 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/batch"
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/plugin"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// batchAction describes a lifecycle action which can be applied to several instances
+type batchAction struct {
+	name   string                                             // Action name (e.g., Stop)
+	event  string                                             // Event of the hooks run after the action
+	target string                                             // State of the instances after the action
+	change string                                             // Change displayed in the plan, empty for the change of state
+	audit  map[string]string                                  // Parameters added to the audit entries, e.g. the group
+	check  func(instance model.Instance) string               // Returns why the action does not apply, or ""
+	run    func(ctx context.Context, instanceID string) error // Applies the action to an instance
+}
+
+// verb returns the action, e.g. stop, without the instances it applies to
+// in its name, e.g. Stop env=staging
+func (a batchAction) verb() string {
+	verb, _, _ := strings.Cut(strings.ToLower(a.name), " ")
+	return verb
+}
+
+// batchResult is the outcome of a batch action on an instance
+type batchResult struct {
+	instance model.Instance
+	err      error
+	attempts int
+	duration time.Duration
+}
+
+// planRow is a row of the batch plan
+type planRow struct {
+	instance model.Instance
+	reason   string // Why the action does not apply, empty if it does
+	included bool
+}
+
+// startAction returns the batch action starting instances
+func (ui *UI) startAction() batchAction {
+	return batchAction{
+		name:   "Start",
+		event:  plugin.EventStart,
+		target: "running",
+		check: func(instance model.Instance) string {
+			if !instance.IsStopped() {
+				return "not stopped"
+			}
+			return ""
+		},
+		run: func(ctx context.Context, id string) error { return ui.clientForID(id).StartInstance(ctx, id) },
+	}
+}
+
+// stopAction returns the batch action stopping instances
+func (ui *UI) stopAction() batchAction {
+	return batchAction{
+		name:   "Stop",
+		event:  plugin.EventStop,
+		target: "stopped",
+		check: func(instance model.Instance) string {
+			if !instance.IsRunning() {
+				return "not running"
+			}
+			return ""
+		},
+		run: func(ctx context.Context, id string) error { return ui.clientForID(id).StopInstance(ctx, id) },
+	}
+}
+
+// rebootAction returns the batch action rebooting instances
+func (ui *UI) rebootAction() batchAction {
+	return batchAction{
+		name:   "Reboot",
+		event:  plugin.EventReboot,
+		target: "running",
+		check: func(instance model.Instance) string {
+			if !instance.IsRunning() {
+				return "not running"
+			}
+			return ""
+		},
+		run: func(ctx context.Context, id string) error { return ui.clientForID(id).RebootInstance(ctx, id) },
+	}
+}
+
+// terminateAction returns the batch action terminating instances
+func (ui *UI) terminateAction() batchAction {
+	return batchAction{
+		name:   "Terminate",
+		event:  plugin.EventTerminate,
+		target: "terminated",
+		check: func(instance model.Instance) string {
+			if instance.State == "terminated" || instance.State == "shutting-down" {
+				return "already terminated"
+			}
+			return ""
+		},
+		run: func(ctx context.Context, id string) error { return ui.clientForID(id).TerminateInstance(ctx, id) },
+	}
+}
+
+// ShowBatchPlan displays the plan of a batch action: each instance, its
+// current state and the intended change. Rows can be excluded before
+// executing the action.
+func (ui *UI) ShowBatchPlan(action batchAction, instances []model.Instance) {
+	rows := make([]*planRow, 0, len(instances))
+	for _, instance := range instances {
+		reason := action.check(instance)
+		rows = append(rows, &planRow{
+			instance: instance,
+			reason:   reason,
+			included: reason == "",
+		})
+	}
+
+	table := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	summary := tview.NewTextView().SetDynamicColors(true)
+
+	render := func() {
+		for i, header := range []string{"", "ID", "Name", "State", "Change"} {
+			table.SetCell(0, i,
+				tview.NewTableCell(" "+header+" ").
+					SetTextColor(color.AppColors.Title).
+					SetSelectable(false).
+					SetAttributes(tcell.AttrBold).
+					SetBackgroundColor(color.AppColors.HeaderBg))
+		}
+
+		included := 0
+		for i, row := range rows {
+			mark := "[ ]"
+			change := fmt.Sprintf("%s → %s", row.instance.State, action.target)
+			if action.change != "" {
+				change = action.change
+			}
+			changeColor := color.AppColors.Foreground
+			if notice := ui.notice(row.instance); notice != "" {
+				change += "  ⚠ " + notice
+				changeColor = color.AppColors.Pending
+			}
+			switch {
+			case row.reason != "":
+				mark = " - "
+				change = "skip: " + row.reason
+				changeColor = color.AppColors.Secondary
+			case row.included:
+				mark = "[✓]"
+				included++
+			default:
+				change = "excluded"
+				changeColor = color.AppColors.Secondary
+			}
+
+			table.SetCell(i+1, 0, tview.NewTableCell(" "+mark+" ").SetTextColor(color.AppColors.Highlight))
+			table.SetCell(i+1, 1, tview.NewTableCell(" "+row.instance.ID+" ").SetTextColor(color.AppColors.Foreground))
+			table.SetCell(i+1, 2, tview.NewTableCell(" "+row.instance.Name+" ").SetTextColor(color.AppColors.Foreground))
+			table.SetCell(i+1, 3, tview.NewTableCell(" "+getStateEmoji(row.instance.State)+" "+row.instance.State+" ").SetTextColor(getStateColor(row.instance.State)))
+			table.SetCell(i+1, 4, tview.NewTableCell(" "+change+" ").SetTextColor(changeColor).SetExpansion(1))
+		}
+
+		summary.SetText(fmt.Sprintf(" [yellow]%s[-] %d of %d instances   [yellow]Space[-]: include/exclude   [yellow]Enter[-]: execute   [yellow]Esc[-]: cancel",
+			action.name, included, len(rows)))
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyRune && event.Rune() == ' ':
+			row, _ := table.GetSelection()
+			if row > 0 && row-1 < len(rows) && rows[row-1].reason == "" {
+				rows[row-1].included = !rows[row-1].included
+				render()
+			}
+			return nil
+		case event.Key() == tcell.KeyEnter:
+			selected := make([]model.Instance, 0, len(rows))
+			for _, row := range rows {
+				if row.included {
+					selected = append(selected, row.instance)
+				}
+			}
+			if len(selected) == 0 {
+				ui.statusBar.SetError("No instance included in the plan")
+				return nil
+			}
+			ui.pages.RemovePage("modal")
+			ui.confirmBatch(action, selected)
+			return nil
+		}
+		return event
+	})
+
+	render()
+	if len(rows) > 0 {
+		table.Select(1, 0)
+	}
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 0, 1, true).
+		AddItem(summary, 1, 0, false)
+	layout.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Plan: %s %d instances ", action.name, len(rows))).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, 100, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// confirmBatch asks for a confirmation before terminating instances, or
+// stopping them if the configuration requires a typed confirmation, or if
+// instances have a notice. Stopping or terminating the instance e2c runs on
+// always requires a typed confirmation. Other actions are executed right away.
+func (ui *UI) confirmBatch(action batchAction, instances []model.Instance) {
+	name := action.verb()
+	prompt := fmt.Sprintf("Are you sure you want to %s %d instances?", strings.ToLower(action.name), len(instances))
+	if name == "terminate" {
+		prompt = fmt.Sprintf("Are you sure you want to TERMINATE %d instances? This action cannot be undone!", len(instances))
+	}
+	message, noticed := ui.withNotices(prompt, instances)
+	self := false
+	if name == "stop" || name == "terminate" {
+		message, self = ui.withSelfWarning(message, instances...)
+	}
+
+	switch {
+	case name == "terminate" && !self:
+		ui.confirmDestructive(
+			name,
+			"Terminate Instances",
+			message,
+			[]string{fmt.Sprintf("%s %d", name, len(instances))},
+			func() {
+				ui.executeBatch(action, instances)
+			},
+		)
+	case self || ui.config().UI.TypedConfirmation(name):
+		ui.ShowTypedConfirmDialog(
+			action.name+" Instances",
+			message,
+			[]string{fmt.Sprintf("%s %d", name, len(instances))},
+			func() {
+				ui.executeBatch(action, instances)
+			},
+		)
+	case noticed:
+		ui.ShowConfirmDialog(action.name+" Instances", message, func() {
+			ui.executeBatch(action, instances)
+		})
+	default:
+		ui.executeBatch(action, instances)
+	}
+}
+
+// newBatchEngine creates a batch engine from the configuration, retrying
+// the throttled and network errors only
+func (ui *UI) newBatchEngine() *batch.Engine {
+	options := batch.DefaultOptions()
+	if ui.config().Batch.Concurrency > 0 {
+		options.Concurrency = ui.config().Batch.Concurrency
+	}
+	if ui.config().Batch.MaxRetries >= 0 {
+		options.MaxRetries = ui.config().Batch.MaxRetries
+	}
+	if ui.config().Batch.Backoff > 0 {
+		options.Backoff = ui.config().Batch.Backoff
+	}
+	if ui.config().Batch.Rate > 0 {
+		options.Rate = ui.config().Batch.Rate
+	}
+	options.Retryable = func(err error) bool {
+		kind := aws.ClassifyError(err)
+		return kind == aws.ErrorThrottling || kind == aws.ErrorNetwork
+	}
+
+	return batch.NewEngine(ui.log, options)
+}
+
+// executeBatch applies a batch action to the instances with the batch
+// engine, displays its progress in the status bar and a report at the end
+func (ui *UI) executeBatch(action batchAction, instances []model.Instance) {
+	if ui.batchCancel != nil {
+		ui.statusBar.SetError("A batch is already running, press X to cancel it")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(aws.WithAuditParams(ui.actionCtx(), action.audit))
+	ui.batchCancel = cancel
+
+	ids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		ids = append(ids, instance.ID)
+	}
+
+	ui.statusBar.SetStatus(fmt.Sprintf("%s: running, press X to cancel", action.name))
+	ui.statusBar.SetProgress(action.name, 0, 0, len(ids))
+	started := time.Now()
+
+	go func() {
+		defer cancel()
+
+		results := ui.newBatchEngine().Run(ctx, ids, action.run, func(done, failed, total int) {
+			ui.app.QueueUpdateDraw(func() {
+				ui.statusBar.SetProgress(action.name, done, failed, total)
+			})
+		})
+
+		report := make([]batchResult, 0, len(results))
+		for i, result := range results {
+			if result.Err != nil {
+				ui.log.Error("Batch action failed", "action", action.name, "instanceID", result.ID, "attempts", result.Attempts, "error", result.Err)
+			}
+			report = append(report, batchResult{
+				instance: instances[i],
+				err:      result.Err,
+				attempts: result.Attempts,
+				duration: result.Duration,
+			})
+		}
+
+		ui.app.QueueUpdateDraw(func() {
+			ui.batchCancel = nil
+			ui.statusBar.ClearProgress()
+			ui.instancesView.ClearMarks()
+			ui.showBatchReport(action, report, started)
+			ui.RefreshInstances()
+		})
+
+		for _, result := range report {
+			if result.err == nil {
+				ui.runHooks(action.event, result.instance)
+			}
+		}
+	}()
+}
+
+// cancelBatch cancels the running batch, the instances not processed yet
+// are reported as cancelled
+func (ui *UI) cancelBatch() {
+	if ui.batchCancel == nil {
+		ui.statusBar.SetStatus("No batch running")
+		return
+	}
+	ui.batchCancel()
+	ui.statusBar.SetStatus("Cancelling batch...")
+}
+
+// showBatchReport displays the result of a batch action begun at started
+// for each instance
+func (ui *UI) showBatchReport(action batchAction, results []batchResult, started time.Time) {
+	failures := 0
+	for _, result := range results {
+		if result.err != nil {
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		summary := fmt.Sprintf("%s: %d of %d instances failed", action.name, failures, len(results))
+		ui.statusBar.SetError(summary)
+		ui.notifyDone(started, summary, true)
+	} else {
+		summary := fmt.Sprintf("%s: %d instances done", action.name, len(results))
+		ui.statusBar.SetStatus(summary)
+		ui.notifyDone(started, summary, false)
+	}
+
+	table := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	for i, header := range []string{"ID", "Name", "Result", "Attempts", "Duration", "Error"} {
+		table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	for i, result := range results {
+		status, statusColor, message := "✅ ok", color.AppColors.Running, ""
+		switch {
+		case errors.Is(result.err, context.Canceled):
+			status, statusColor, message = "⏹ cancelled", color.AppColors.Pending, "cancelled"
+		case result.err != nil:
+			status, statusColor, message = "❌ failed", color.AppColors.Error, result.err.Error()
+		}
+
+		table.SetCell(i+1, 0, tview.NewTableCell(" "+result.instance.ID+" ").SetTextColor(color.AppColors.Foreground))
+		table.SetCell(i+1, 1, tview.NewTableCell(" "+result.instance.Name+" ").SetTextColor(color.AppColors.Foreground))
+		table.SetCell(i+1, 2, tview.NewTableCell(" "+status+" ").SetTextColor(statusColor))
+		table.SetCell(i+1, 3, tview.NewTableCell(fmt.Sprintf(" %d ", result.attempts)).SetTextColor(color.AppColors.Foreground).SetAlign(tview.AlignRight))
+		table.SetCell(i+1, 4, tview.NewTableCell(" "+result.duration.Round(time.Millisecond).String()+" ").SetTextColor(color.AppColors.Foreground).SetAlign(tview.AlignRight))
+		table.SetCell(i+1, 5, tview.NewTableCell(" "+message+" ").SetTextColor(statusColor).SetExpansion(1))
+	}
+	if len(results) > 0 {
+		table.Select(1, 0)
+	}
+
+	summary := tview.NewTextView().SetDynamicColors(true).
+		SetText(fmt.Sprintf(" [green]Succeeded:[-] %d   [red]Failed:[-] %d   [yellow]Esc[-]: close",
+			len(results)-failures, failures))
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 0, 1, true).
+		AddItem(summary, 1, 0, false)
+	layout.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Batch Result: %s ", action.name)).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, 110, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// Styles of the borders, configured in ui.borders
+const (
+	bordersUnicode = "unicode" // Box-drawing characters, the default
+	bordersASCII   = "ascii"   // +, - and |, for serial consoles and old terminals
+	bordersNone    = "none"    // Blank borders, keeping the titles and the layout
+)
+
+// unicodeBorders are the box-drawing characters drawn by tview by default
+var unicodeBorders = tview.Borders
+
+// applyBorders sets the characters drawing the borders of all the views,
+// boxes, grids and tables, before the views are created
+func (ui *UI) applyBorders() {
+	borders := unicodeBorders
+
+	switch style := strings.ToLower(ui.config().UI.Borders); style {
+	case "", bordersUnicode:
+	case bordersASCII:
+		borders.Horizontal, borders.Vertical = '-', '|'
+		borders.TopLeft, borders.TopRight, borders.BottomLeft, borders.BottomRight = '+', '+', '+', '+'
+		borders.LeftT, borders.RightT, borders.TopT, borders.BottomT, borders.Cross = '+', '+', '+', '+', '+'
+		borders.HorizontalFocus, borders.VerticalFocus = '=', '|'
+		borders.TopLeftFocus, borders.TopRightFocus, borders.BottomLeftFocus, borders.BottomRightFocus = '+', '+', '+', '+'
+	case bordersNone:
+		borders.Horizontal, borders.Vertical = ' ', ' '
+		borders.TopLeft, borders.TopRight, borders.BottomLeft, borders.BottomRight = ' ', ' ', ' ', ' '
+		borders.LeftT, borders.RightT, borders.TopT, borders.BottomT, borders.Cross = ' ', ' ', ' ', ' ', ' '
+		borders.HorizontalFocus, borders.VerticalFocus = ' ', ' '
+		borders.TopLeftFocus, borders.TopRightFocus, borders.BottomLeftFocus, borders.BottomRightFocus = ' ', ' ', ' ', ' '
+	default:
+		ui.log.Warn("Unknown border style, using unicode", "borders", style)
+	}
+
+	tview.Borders = borders
+}
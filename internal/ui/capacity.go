@@ -0,0 +1,368 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+const (
+	// capacityRetries is the number of attempts to start an instance when
+	// retrying with backoff after an insufficient capacity error
+	capacityRetries = 6
+	// capacityMinBackoff and capacityMaxBackoff bound the delay between the
+	// attempts, doubled after each of them
+	capacityMinBackoff = 30 * time.Second
+	capacityMaxBackoff = 5 * time.Minute
+)
+
+// Remediations of an insufficient capacity error
+const (
+	remediateRetry     = "Retry with backoff"
+	remediatePlacement = "Leave placement group"
+	remediateReplace   = "Launch replacement"
+	remediateCancel    = "Cancel"
+)
+
+// showCapacityRemediation offers the remediations of an instance which could
+// not be started for lack of capacity: retrying later, leaving its placement
+// group, or launching a replacement in a subnet of another availability zone
+func (ui *UI) showCapacityRemediation(instance model.Instance) {
+	message := fmt.Sprintf("AWS has not enough %s capacity in %s to start %s.\n\n",
+		instance.Type, valueOrNone(instance.AvailabilityZone), instance.DisplayName())
+	buttons := []string{remediateRetry}
+	if instance.PlacementGroup != "" {
+		message += fmt.Sprintf("The instance is in the placement group %s, which constrains its placement. ", instance.PlacementGroup)
+		buttons = append(buttons, remediatePlacement)
+	}
+	message += "An instance cannot move to another availability zone: a replacement can be launched from the same AMI in another subnet, without the data of the volumes."
+	buttons = append(buttons, remediateReplace, remediateCancel)
+
+	modal := tview.NewModal().
+		SetText(message).
+		AddButtons(buttons).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ui.pages.RemovePage("modal")
+			switch buttonLabel {
+			case remediateRetry:
+				ui.traceAction("capacity retry", nil, func() { ui.retryStart(instance) })
+			case remediatePlacement:
+				ui.traceAction("capacity placement", nil, func() { ui.startOutsidePlacementGroup(instance) })
+			case remediateReplace:
+				ui.traceAction("capacity replace", nil, func() { ui.handleLaunchReplacement(instance) })
+			}
+		})
+	modal.SetBorder(true).
+		SetTitle(" Insufficient Capacity ").
+		SetBorderColor(color.AppColors.Error).
+		SetTitleColor(color.AppColors.Error)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(modal, 80, 1, true).
+			AddItem(nil, 0, 1, false), 0, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// startFailed displays the error of the start of an instance, offering the
+// remediations if it lacks capacity
+func (ui *UI) startFailed(instance model.Instance, err error) {
+	ui.log.Error("Failed to start instance", "instanceID", instance.ID, "error", err)
+	ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+	if aws.IsInsufficientCapacity(err) {
+		ui.showCapacityRemediation(instance)
+	}
+}
+
+// retryStart starts an instance again in the background, with an increasing
+// delay between the attempts while the capacity is insufficient. The retries
+// can be cancelled with X like a batch.
+func (ui *UI) retryStart(instance model.Instance) {
+	if ui.batchCancel != nil {
+		ui.statusBar.SetError("A batch is already running, press X to cancel it")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ui.actionCtx())
+	ui.batchCancel = cancel
+
+	go func() {
+		defer cancel()
+
+		backoff := capacityMinBackoff
+		var err error
+		for attempt := 1; attempt <= capacityRetries; attempt++ {
+			ui.app.QueueUpdateDraw(func() {
+				ui.statusBar.SetStatus(fmt.Sprintf("Starting %s in %s (attempt %d/%d), press X to cancel",
+					instance.DisplayName(), backoff, attempt, capacityRetries))
+			})
+			if !sleepContext(ctx, backoff) {
+				err = ctx.Err()
+				break
+			}
+			err = ui.clientFor(instance).StartInstance(ctx, instance.ID)
+			if !aws.IsInsufficientCapacity(err) {
+				break
+			}
+			ui.log.Warn("Insufficient capacity to start instance", "instanceID", instance.ID, "attempt", attempt)
+			backoff = min(2*backoff, capacityMaxBackoff)
+		}
+
+		ui.app.QueueUpdateDraw(func() {
+			ui.batchCancel = nil
+			switch {
+			case errors.Is(err, context.Canceled):
+				ui.statusBar.SetStatus(fmt.Sprintf("Cancelled the start of %s", instance.DisplayName()))
+			case err != nil:
+				ui.startFailed(instance, err)
+			default:
+				ui.statusBar.SetStatus(fmt.Sprintf("Started instance %s", instance.ID))
+				ui.RefreshInstances()
+			}
+		})
+	}()
+}
+
+// startOutsidePlacementGroup removes a stopped instance from its placement
+// group, then starts it
+func (ui *UI) startOutsidePlacementGroup(instance model.Instance) {
+	ui.statusBar.SetStatus(fmt.Sprintf("Removing %s from the placement group %s...", instance.DisplayName(), instance.PlacementGroup))
+
+	ctx := ui.actionCtx()
+	go func() {
+		err := ui.clientFor(instance).RemoveFromPlacementGroup(ctx, instance.ID, instance.PlacementGroup)
+		if err != nil {
+			ui.app.QueueUpdateDraw(func() {
+				ui.log.Error("Failed to leave placement group", "instanceID", instance.ID, "error", err)
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+			})
+			return
+		}
+
+		instance.PlacementGroup = ""
+		err = ui.clientFor(instance).StartInstance(ctx, instance.ID)
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.startFailed(instance, err)
+				return
+			}
+			ui.statusBar.SetStatus(fmt.Sprintf("Started instance %s outside of its placement group", instance.ID))
+			ui.RefreshInstances()
+		})
+	}()
+}
+
+// handleLaunchReplacement lists the subnets of the VPC of an instance in the
+// other availability zones, and the zones offering its type, to launch a
+// replacement in one of them
+func (ui *UI) handleLaunchReplacement(instance model.Instance) {
+	ui.statusBar.SetStatus(fmt.Sprintf("Listing the subnets of %s...", instance.VpcID))
+
+	ctx := ui.actionCtx()
+	go func() {
+		vpcs, err := ui.clientFor(instance).ListVPCs(ctx)
+		var zones map[string]bool
+		if err == nil {
+			var offeringsErr error
+			if zones, offeringsErr = ui.clientFor(instance).ListTypeZones(ctx, instance.Type); offeringsErr != nil {
+				// The launch fails later if the type is not offered
+				ui.log.Warn("Failed to list instance type offerings", "instanceType", instance.Type, "error", offeringsErr)
+			}
+		}
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.log.Error("Failed to list VPCs", "error", err)
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+
+			var subnets []model.Subnet
+			for _, vpc := range vpcs {
+				if vpc.ID != instance.VpcID {
+					continue
+				}
+				for _, subnet := range vpc.Subnets {
+					if subnet.AvailabilityZone != instance.AvailabilityZone && subnet.State == "available" {
+						subnets = append(subnets, subnet)
+					}
+				}
+			}
+			if len(subnets) == 0 {
+				ui.statusBar.SetError(fmt.Sprintf("No subnet of %s in another availability zone than %s", instance.VpcID, instance.AvailabilityZone))
+				return
+			}
+			ui.statusBar.SetStatus(fmt.Sprintf("%d subnets in other availability zones", len(subnets)))
+			ui.showSubnetPicker(instance, subnets, zones)
+		})
+	}()
+}
+
+// showSubnetPicker displays the subnets in which a replacement of an
+// instance can be launched, with whether the type of the instance is offered
+// in their zone (nil zones if unknown), and confirms the launch in the
+// selected one
+func (ui *UI) showSubnetPicker(instance model.Instance, subnets []model.Subnet, zones map[string]bool) {
+	// Resolve the name of the AMI while a subnet is picked, for the confirmation
+	ui.names().lookup(nil, nil, ui.clientFor(instance), aws.ImageResource, instance.ImageID)
+
+	table := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	for i, header := range []string{"Subnet", "Name", "Zone", "CIDR", "Free IPs", instance.Type} {
+		table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+	for i, subnet := range subnets {
+		table.SetCell(i+1, 0, tview.NewTableCell(" "+subnet.ID+" ").SetTextColor(color.AppColors.Highlight))
+		table.SetCell(i+1, 1, tview.NewTableCell(" "+subnet.Name+" ").SetTextColor(color.AppColors.Foreground).SetExpansion(1))
+		table.SetCell(i+1, 2, tview.NewTableCell(" "+subnet.AvailabilityZone+" ").SetTextColor(color.AppColors.Foreground))
+		table.SetCell(i+1, 3, tview.NewTableCell(" "+subnet.CIDR+" ").SetTextColor(color.AppColors.Secondary))
+		table.SetCell(i+1, 4, tview.NewTableCell(fmt.Sprintf(" %d ", subnet.AvailableIPs)).SetTextColor(color.AppColors.Foreground).SetAlign(tview.AlignRight))
+		switch {
+		case zones == nil:
+			table.SetCell(i+1, 5, tview.NewTableCell(" ? ").SetTextColor(color.AppColors.Secondary))
+		case zones[subnet.AvailabilityZone]:
+			table.SetCell(i+1, 5, tview.NewTableCell(" offered ").SetTextColor(color.AppColors.Running))
+		default:
+			table.SetCell(i+1, 5, tview.NewTableCell(" not offered ").SetTextColor(color.AppColors.Error))
+		}
+	}
+	table.Select(1, 0)
+
+	table.SetSelectedFunc(func(row, column int) {
+		if row <= 0 || row-1 >= len(subnets) {
+			return
+		}
+		subnet := subnets[row-1]
+		ui.pages.RemovePage("modal")
+
+		if zones != nil && !zones[subnet.AvailabilityZone] {
+			ui.handleTypeAlternatives(instance, subnet)
+			return
+		}
+		ui.confirmReplacement(instance, subnet, instance.Type)
+	})
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Subnets for a replacement of %s - Enter: launch ", instance.DisplayName())).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(table, 100, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// handleTypeAlternatives lists the types of the family of an instance offered
+// in the zone of a subnet which does not offer its type, to launch the
+// replacement with one of them
+func (ui *UI) handleTypeAlternatives(instance model.Instance, subnet model.Subnet) {
+	family, _, _ := strings.Cut(instance.Type, ".")
+	ui.statusBar.SetStatus(fmt.Sprintf("Listing the %s types offered in %s...", family, subnet.AvailabilityZone))
+
+	ctx := ui.actionCtx()
+	go func() {
+		alternatives, err := ui.clientFor(instance).ListZoneTypes(ctx, subnet.AvailabilityZone, family)
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.log.Error("Failed to list instance types", "zone", subnet.AvailabilityZone, "error", err)
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			if len(alternatives) == 0 {
+				ui.statusBar.SetError(fmt.Sprintf("%s is not offered in %s, nor any %s type", instance.Type, subnet.AvailabilityZone, family))
+				return
+			}
+			ui.statusBar.SetError(fmt.Sprintf("%s is not offered in %s", instance.Type, subnet.AvailabilityZone))
+			ui.showTypeAlternatives(instance, subnet, alternatives)
+		})
+	}()
+}
+
+// showTypeAlternatives displays a form to pick the type of the replacement
+// among the alternatives offered in the zone of the subnet
+func (ui *UI) showTypeAlternatives(instance model.Instance, subnet model.Subnet, alternatives []string) {
+	form := tview.NewForm()
+	form.AddDropDown("Type:", alternatives, 0, nil)
+	form.AddButton("Launch", func() {
+		_, instanceType := form.GetFormItem(0).(*tview.DropDown).GetCurrentOption()
+		ui.pages.RemovePage("modal")
+		ui.confirmReplacement(instance, subnet, instanceType)
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+	form.SetBorder(true).
+		SetTitle(fmt.Sprintf(" %s is not offered in %s ", instance.Type, subnet.AvailabilityZone)).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 7, 1, true).
+			AddItem(nil, 0, 1, false), 60, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// confirmReplacement confirms the launch of a replacement of an instance of
+// a type in a subnet
+func (ui *UI) confirmReplacement(instance model.Instance, subnet model.Subnet, instanceType string) {
+	message := fmt.Sprintf("Launch a %s replacement of %s from %s in %s (%s)?\n\n"+
+		"It has the key pair, security groups, instance profile and tags of the instance, but not the data of its volumes. The instance is left stopped.",
+		instanceType, instance.DisplayName(), ui.names().resourceLabel(nil, nil, ui.clientFor(instance), aws.ImageResource, instance.ImageID, "deregistered"),
+		subnet.ID, subnet.AvailabilityZone)
+	ui.ShowConfirmDialog("Launch Replacement", message, func() {
+		ui.launchReplacement(instance, subnet, instanceType)
+	})
+}
+
+// launchReplacement launches a replacement of an instance of a type in a
+// subnet
+func (ui *UI) launchReplacement(instance model.Instance, subnet model.Subnet, instanceType string) {
+	ui.statusBar.SetStatus(fmt.Sprintf("Launching a replacement of %s in %s...", instance.DisplayName(), subnet.AvailabilityZone))
+
+	ctx := ui.actionCtx()
+	go func() {
+		replacement, err := ui.clientFor(instance).LaunchReplacement(ctx, instance, subnet.ID, instanceType)
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.log.Error("Failed to launch replacement", "instanceID", instance.ID, "subnetID", subnet.ID, "error", err)
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			ui.statusBar.SetStatus(fmt.Sprintf("Launched %s replacing %s in %s", replacement, instance.ID, subnet.AvailabilityZone))
+			ui.RefreshInstances()
+		})
+	}()
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+)
+
+// command is a command which can be typed in the command prompt
+type command struct {
+	usage string                            // Arguments and description displayed on errors
+	run   func(ui *UI, args []string) error // Runs the command from the UI goroutine
+	// action is the action of the instances view run by the command, if any,
+	// whose feature flag and capability level gate the command
+	action string
+}
+
+// commands are the commands available in the command prompt, by name
+var commands = map[string]command{
+	"ctx": {
+		usage: "ctx [name] - list the contexts of the config file, or switch to one",
+		run:   (*UI).runContextCommand,
+	},
+	"diff": {
+		usage: "diff <file> - compare an inventory exported before (.json or .yaml) with the instances loaded",
+		run:   (*UI).runDiffCommand,
+	},
+	"errors": {
+		usage:  "errors [clear] - review the errors of the session, or clear the messages",
+		run:    (*UI).runErrorsCommand,
+		action: "errors",
+	},
+	"export": {
+		usage:  "export [file] - export the instances displayed to a .csv, .json or .yaml file",
+		run:    (*UI).runExportCommand,
+		action: "export",
+	},
+	"group": {
+		usage:  "group [state|zone|type|stack|asg|tag:<key>|none] - show the grouping of the instances, or group them",
+		run:    (*UI).runGroupCommand,
+		action: "group",
+	},
+	"keys": {
+		usage: "keys - list the key bindings",
+		run:   (*UI).runKeysCommand,
+	},
+	"messages": {
+		usage: "messages [clear] - review the status and error messages of the session, or clear them",
+		run:   (*UI).runMessagesCommand,
+	},
+	"reach": {
+		usage:  "reach [destination [port [tcp|udp]]] - analyze the path from the selected instance to an IP or a resource",
+		run:    (*UI).runReachCommand,
+		action: "reachability",
+	},
+	"run": {
+		usage:  "run [command] - run a shell command on the marked or selected instances with SSM (admin level)",
+		run:    (*UI).runRunCommand,
+		action: "run-command",
+	},
+	"forward": {
+		usage:  "forward [remote-port [local-port [host]]] - forward a local port to the selected instance, or a host behind it, with SSM (admin level)",
+		run:    (*UI).runForwardCommand,
+		action: "port-forward",
+	},
+	"sessions": {
+		usage:  "sessions - list the port forwarding sessions, and stop them",
+		run:    (*UI).runSessionsCommand,
+		action: "sessions",
+	},
+	"schedules": {
+		usage: "schedules - list the automatic stops of the config file, and their next stop",
+		run:   (*UI).runSchedulesCommand,
+	},
+	"theme": {
+		usage: "theme [dark|light|time|auto] - show the theme, or force one for the session (auto for ui.theme)",
+		run:   (*UI).runThemeCommand,
+	},
+	"regions": {
+		usage: "regions - measure the latency of the regions, and switch to one",
+		run:   (*UI).runRegionsCommand,
+	},
+	"refresh": {
+		usage: "refresh <interval>|pause|resume - change the auto-refresh",
+		run:   (*UI).runRefreshCommand,
+	},
+}
+
+// ShowCommandPrompt displays the prompt to type a command, e.g. :refresh 10s
+func (ui *UI) ShowCommandPrompt() {
+	input := tview.NewInputField().
+		SetLabel(":").
+		SetFieldBackgroundColor(color.AppColors.Background).
+		SetPlaceholder(strings.Join(commandNames(), ", "))
+
+	input.SetAutocompleteFunc(func(text string) []string {
+		if text == "" || strings.Contains(text, " ") {
+			return nil
+		}
+		var entries []string
+		for _, name := range commandNames() {
+			if strings.HasPrefix(name, text) {
+				entries = append(entries, name)
+			}
+		}
+		return entries
+	})
+
+	input.SetDoneFunc(func(key tcell.Key) {
+		ui.pages.RemovePage("modal")
+		if key != tcell.KeyEnter {
+			return
+		}
+		if err := ui.runCommand(input.GetText()); err != nil {
+			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		}
+	})
+
+	input.SetBorder(true).
+		SetBorderColor(color.AppColors.Border)
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(input, 60, 1, true).
+			AddItem(nil, 0, 1, false), 3, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// runCommand parses and runs a command line. The commands running an action
+// of the instances view are refused like its key when the feature flag or
+// the level of the user does not grant the action.
+func (ui *UI) runCommand(line string) error {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), ":"))
+	if len(fields) == 0 {
+		return nil
+	}
+
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q (available: %s)", fields[0], strings.Join(commandNames(), ", "))
+	}
+
+	if cmd.action != "" {
+		if err := ui.actionError(cmd.action); err != nil {
+			return err
+		}
+	}
+
+	ui.log.Info("Running command", "command", fields[0], "args", fields[1:])
+	if err := cmd.run(ui, fields[1:]); err != nil {
+		return fmt.Errorf("%w, usage: %s", err, cmd.usage)
+	}
+	return nil
+}
+
+// commandNames returns the names of the commands, sorted
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// confirmDestructive asks for the confirmation of a destructive action. If
+// the configuration requires it for this action, the user must type one of
+// the expected values, e.g. the name or the ID of the instance, rather than
+// pressing a button.
+func (ui *UI) confirmDestructive(action, title, message string, expected []string, onConfirm func()) {
+	if !ui.config().UI.TypedConfirmation(action) {
+		ui.ShowConfirmDialog(title, message, onConfirm)
+		return
+	}
+	ui.ShowTypedConfirmDialog(title, message, expected, onConfirm)
+}
+
+// ShowTypedConfirmDialog shows a confirmation dialog in which one of the
+// expected values must be typed to confirm
+func (ui *UI) ShowTypedConfirmDialog(title, message string, expected []string, onConfirm func()) {
+	quoted := make([]string, len(expected))
+	for i, value := range expected {
+		quoted[i] = fmt.Sprintf("[yellow]%s[-]", tview.Escape(value))
+	}
+
+	text := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(true).
+		SetText(fmt.Sprintf("%s\n\nType %s to confirm.", tview.Escape(message), strings.Join(quoted, " or ")))
+
+	input := tview.NewInputField().
+		SetLabel("> ").
+		SetFieldBackgroundColor(color.AppColors.HeaderBg)
+
+	confirm := func() {
+		typed := strings.TrimSpace(input.GetText())
+		for _, value := range expected {
+			if typed == value {
+				ui.pages.RemovePage("modal")
+				ui.traceAction("confirm "+title, nil, onConfirm)
+				return
+			}
+		}
+		input.SetLabel("[red]> [-]")
+		ui.statusBar.SetError("Error: the typed value does not match, the action was not confirmed")
+	}
+
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			confirm()
+		case tcell.KeyEscape:
+			ui.pages.RemovePage("modal")
+		}
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(text, 0, 1, false).
+		AddItem(input, 1, 0, true)
+	layout.SetBorder(true).
+		SetTitle(" " + title + " ").
+		SetBorderColor(color.AppColors.Error).
+		SetTitleColor(color.AppColors.Error)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, 12, 1, true).
+			AddItem(nil, 0, 1, false), 70, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// instanceConfirmValues returns the values accepted to confirm an action on
+// an instance: its name, if any, and its ID
+func instanceConfirmValues(instance model.Instance) []string {
+	if instance.Name != "" {
+		return []string{instance.Name, instance.ID}
+	}
+	return []string{instance.ID}
+}
@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// followInterval is the delay between two fetches of the console output in follow mode
+const followInterval = 5 * time.Second
+
+// anchorLines is the number of displayed lines searched in a new snapshot to
+// find where the new lines start
+const anchorLines = 5
+
+// ConsoleView displays the console output of an instance, optionally
+// following it
+type ConsoleView struct {
+	ui       *UI
+	instance model.Instance
+	view     *tview.TextView
+	layout   *tview.Flex
+	lines    []string
+	follow   bool
+	cancel   context.CancelFunc // Stops the follow mode
+}
+
+// NewConsoleView creates a new console output view for an instance
+func NewConsoleView(ui *UI, instance model.Instance) *ConsoleView {
+	v := &ConsoleView{
+		ui:       ui,
+		instance: instance,
+		view: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true),
+	}
+
+	v.view.SetBorder(true).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+	v.updateTitle()
+
+	v.view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case 'f':
+			v.ToggleFollow()
+			return nil
+		case 'w':
+			v.stopFollow()
+			v.ui.showCloudWatchLogs(v.instance)
+			return nil
+		}
+		return event
+	})
+
+	// Center the text view
+	v.layout = tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(v.view, 0, 8, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	return v
+}
+
+// Show fetches the console output and displays it
+func (v *ConsoleView) Show() {
+	v.ui.statusBar.SetStatus(fmt.Sprintf("Fetching console output for instance %s...", v.instance.ID))
+
+	ctx := v.ui.actionCtx()
+	go func() {
+		output, err := v.ui.clientFor(v.instance).GetInstanceConsoleOutput(ctx, v.instance.ID, false)
+		v.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				v.ui.log.Error("Failed to get console output", "error", err)
+				v.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+
+			v.ui.statusBar.SetStatus("Showing console output, f: follow, w: CloudWatch Logs")
+			v.lines = splitLines(output)
+			v.view.SetText(tview.Escape(strings.Join(v.lines, "\n")))
+			v.view.ScrollToEnd()
+			v.ui.pages.AddPage("modal", v.layout, true, true)
+		})
+	}()
+}
+
+// ToggleFollow starts or stops following the console output
+func (v *ConsoleView) ToggleFollow() {
+	if v.follow {
+		v.stopFollow()
+		v.ui.statusBar.SetStatus("Stopped following console output")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(v.ui.ctx)
+	v.follow = true
+	v.cancel = cancel
+	v.updateTitle()
+	v.ui.statusBar.SetStatus(fmt.Sprintf("Following console output every %s", followInterval))
+
+	go v.followLoop(ctx)
+}
+
+// stopFollow stops the follow mode
+func (v *ConsoleView) stopFollow() {
+	if v.cancel != nil {
+		v.cancel()
+	}
+	v.follow = false
+	v.cancel = nil
+	v.updateTitle()
+}
+
+// followLoop fetches the latest console output periodically and appends the
+// new lines, until the follow mode is stopped or the view is closed
+func (v *ConsoleView) followLoop(ctx context.Context) {
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		output, err := v.ui.clientFor(v.instance).GetInstanceConsoleOutput(ctx, v.instance.ID, true)
+		if ctx.Err() != nil {
+			return
+		}
+
+		v.ui.app.QueueUpdateDraw(func() {
+			// Stop following once the view was closed
+			if _, front := v.ui.pages.GetFrontPage(); front != v.layout {
+				v.stopFollow()
+				return
+			}
+
+			if err != nil {
+				v.ui.log.Error("Failed to follow console output", "instanceID", v.instance.ID, "error", err)
+				v.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+
+			added := newLines(v.lines, splitLines(output))
+			if len(added) == 0 {
+				return
+			}
+			v.lines = append(v.lines, added...)
+			fmt.Fprint(v.view, "\n"+tview.Escape(strings.Join(added, "\n")))
+			v.view.ScrollToEnd()
+			v.ui.statusBar.SetStatus(fmt.Sprintf("%d new lines of console output at %s", len(added), time.Now().Format("15:04:05")))
+		})
+	}
+}
+
+// updateTitle displays the follow state in the title
+func (v *ConsoleView) updateTitle() {
+	title := fmt.Sprintf(" Console Output: %s ", v.instance.DisplayName())
+	if v.follow {
+		title += "[following] "
+	}
+	v.view.SetTitle(title)
+}
+
+// splitLines splits an output in lines, without the trailing empty line
+func splitLines(output string) []string {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	return strings.Split(strings.TrimRight(output, "\n"), "\n")
+}
+
+// newLines returns the lines of the latest snapshot which follow the
+// displayed ones. The last displayed lines are searched in the snapshot;
+// if they are not found, the whole snapshot is considered new.
+func newLines(displayed, latest []string) []string {
+	if len(displayed) == 0 {
+		return latest
+	}
+
+	anchor := displayed[max(0, len(displayed)-anchorLines):]
+	for start := len(latest) - len(anchor); start >= 0; start-- {
+		match := true
+		for i, line := range anchor {
+			if latest[start+i] != line {
+				match = false
+				break
+			}
+		}
+		if match {
+			return latest[start+len(anchor):]
+		}
+	}
+
+	return append([]string{"--- output truncated ---"}, latest...)
+}
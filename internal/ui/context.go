@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nlamirault/e2c/internal/config"
+)
+
+// actionLevels are the capability levels required by the actions of the
+// instances view changing the instances, the other ones being available to
+// the viewers
+var actionLevels = map[string]string{
+	"start":            config.LevelOperator,
+	"stop":             config.LevelOperator,
+	"reboot":           config.LevelOperator,
+	"start-group":      config.LevelOperator,
+	"stop-environment": config.LevelOperator,
+	"group-actions":    config.LevelOperator,
+	"terminate":        config.LevelAdmin,
+	"restore-snapshot": config.LevelAdmin,
+	"run-command":      config.LevelAdmin,
+	"port-forward":     config.LevelAdmin,
+	"authorize-key":    config.LevelAdmin,
+}
+
+// actionLevel returns the capability level required by an action of the
+// instances view
+func actionLevel(action string) string {
+	if level, ok := actionLevels[action]; ok {
+		return level
+	}
+	return config.LevelViewer
+}
+
+// actionError returns an error if the feature flag gating an action of the
+// instances view is off, or the level of the user does not grant it
+func (ui *UI) actionError(action string) error {
+	if err := ui.flagError(action); err != nil {
+		return err
+	}
+	return ui.levelError(action, actionLevel(action))
+}
+
+// checkLevel returns false, and displays an error, if the level of the user
+// does not grant an action, or the context in use is read-only
+func (ui *UI) checkLevel(action, level string) bool {
+	if err := ui.levelError(action, level); err != nil {
+		ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return false
+	}
+	return true
+}
+
+// levelError returns an error if the level of the user does not grant an
+// action, or the context in use is read-only
+func (ui *UI) levelError(action, level string) error {
+	switch {
+	case ui.config().Allows(level):
+		return nil
+	case ui.config().ReadOnly():
+		return fmt.Errorf("%s is disabled in the read-only context %s", action, ui.config().Context)
+	default:
+		return fmt.Errorf("%s requires the %s level, set ui.level: %s in the config file (ui.level is %s)", action, level, level, ui.config().Level())
+	}
+}
+
+// runContextCommand runs the ctx command, listing the contexts or switching
+// to one
+func (ui *UI) runContextCommand(args []string) error {
+	names := ui.config().ContextNames()
+	if len(names) == 0 {
+		return errors.New("no context in the config file")
+	}
+
+	switch len(args) {
+	case 0:
+		for i, name := range names {
+			if name == ui.config().Context {
+				names[i] = "*" + name
+			}
+		}
+		ui.statusBar.SetStatus("Contexts: " + strings.Join(names, ", "))
+		return nil
+	case 1:
+		return ui.useContext(args[0])
+	default:
+		return errors.New("at most one context expected")
+	}
+}
+
+// useContext switches to a context of the config file: the instances are
+// loaded again with its profile and region, and its columns displayed
+func (ui *UI) useContext(name string) error {
+	// Apply the context to a copy, kept if the client can be created
+	cfg := ui.config().Clone()
+	if err := cfg.UseContext(name); err != nil {
+		return fmt.Errorf("%w (available: %s)", err, strings.Join(cfg.ContextNames(), ", "))
+	}
+
+	ui.statusBar.SetStatus(fmt.Sprintf("Switching to context %s...", name))
+	if err := ui.switchConfig(cfg); err != nil {
+		return err
+	}
+
+	ui.instancesView.SetTagColumns(cfg.UI.TagColumns)
+	ui.statusBar.SetContext(name, cfg.ReadOnly())
+	ui.statusBar.SetRegion(cfg.AWS.DefaultRegion)
+	ui.pages.RemovePage("error")
+	ui.RefreshInstances()
+	return nil
+}
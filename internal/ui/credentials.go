@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"os"
+
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// setCredentialSource records where the credentials come from, and displays
+// the external process supplying them in the status bar
+func (ui *UI) setCredentialSource(source *aws.CredentialSource) {
+	ui.credentials = source
+	if source.IsExternal() || source.Role != "" {
+		ui.statusBar.SetCredentials(source.String())
+	} else {
+		ui.statusBar.SetCredentials("")
+	}
+}
+
+// resolveCredentials resolves the credentials of the current client in the
+// background, e.g. after switching profile
+func (ui *UI) resolveCredentials() {
+	source, err := ui.ec2Client().ResolveCredentials(ui.ctx)
+	if err != nil {
+		ui.log.Warn("Failed to resolve credentials", "error", err)
+		return
+	}
+	ui.app.QueueUpdateDraw(func() {
+		ui.setCredentialSource(source)
+	})
+}
+
+// reexecWithCredentials stops the UI to run e2c again under aws-vault exec,
+// which exports new credentials in the environment
+func (ui *UI) reexecWithCredentials() {
+	if ui.credentials == nil || !ui.credentials.CanReexec() {
+		return
+	}
+
+	ui.reexec, ui.reexecEnv = ui.credentials.ReexecCommand(os.Args)
+	ui.log.Info("Running again with new credentials", "process", ui.credentials.Process, "profile", ui.credentials.Profile)
+	ui.Stop()
+}
+
+// Reexec returns the command line to run once the UI is stopped, and its
+// environment, or nil if e2c should exit
+func (ui *UI) Reexec() ([]string, []string) {
+	return ui.reexec, ui.reexecEnv
+}
@@ -0,0 +1,1290 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/desktop"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// Names of the tabs of the detail view
+var detailTabs = []string{"Overview", "Tags", "Network", "Storage", "Security", "Monitoring"}
+
+// Time range and resolution of the metrics of the Monitoring tab
+const (
+	metricsDuration = 3 * time.Hour
+	metricsPeriod   = 5 * time.Minute
+)
+
+// Labels of the metrics of the Monitoring tab, by name
+var metricLabels = map[string]string{
+	"CPUUtilization": "CPU",
+	"NetworkIn":      "Network in",
+	"NetworkOut":     "Network out",
+}
+
+// DetailView represents the tabbed detail page of an instance
+type DetailView struct {
+	ui       *UI
+	instance model.Instance
+	layout   *tview.Flex
+	tabBar   *tview.TextView
+	pages    *tview.Pages
+	views    map[string]*tview.TextView
+	tags     *tview.Table
+	tagKeys  []string
+	tagMarks map[string]bool // Tags marked to be propagated
+	current  int
+
+	// Data fetched when the tabs displaying it are opened
+	status     *asyncData[*model.InstanceStatus]
+	protection *asyncData[*model.Protection]
+	history    *asyncData[*model.ProtectionHistory] // Changes of the protections
+	credits    *asyncData[*model.CPUCredits]
+	agent      *asyncData[bool] // The CloudWatch agent publishes metrics
+	routing    *asyncData[*model.SubnetRouting]
+	stack      *asyncData[*model.Stack]           // CloudFormation stack managing the instance
+	metrics    *asyncData[*model.InstanceMetrics] // CPU and network metrics from CloudWatch
+}
+
+// detailTabData returns the async data displayed in a tab
+func (d *DetailView) detailTabData(name string) []asyncLoader {
+	switch name {
+	case "Overview":
+		if d.instance.CloudFormationStack() == "" {
+			return nil
+		}
+		return []asyncLoader{d.stack}
+	case "Network":
+		if d.instance.SubnetID == "" {
+			return nil
+		}
+		return []asyncLoader{d.routing}
+	case "Security":
+		return []asyncLoader{d.protection, d.history}
+	case "Monitoring":
+		if d.instance.IsBurstable() {
+			return []asyncLoader{d.status, d.agent, d.metrics, d.credits}
+		}
+		return []asyncLoader{d.status, d.agent, d.metrics}
+	default:
+		return nil
+	}
+}
+
+// NewDetailView creates a new detail view for an instance
+func NewDetailView(ui *UI, instance model.Instance) *DetailView {
+	d := &DetailView{
+		ui:       ui,
+		instance: instance,
+		layout:   tview.NewFlex().SetDirection(tview.FlexRow),
+		tabBar:   tview.NewTextView().SetDynamicColors(true).SetRegions(true),
+		pages:    tview.NewPages(),
+		views:    make(map[string]*tview.TextView),
+		tags:     tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		tagMarks: make(map[string]bool),
+	}
+
+	d.status = newAsyncData(ui, instance.ID+"/status", func(ctx context.Context) (*model.InstanceStatus, error) {
+		return ui.clientFor(instance).GetInstanceStatus(ctx, instance.ID)
+	}, d.render)
+	d.protection = newAsyncData(ui, instance.ID+"/protection", func(ctx context.Context) (*model.Protection, error) {
+		return ui.fetchProtection(ctx, ui.clientFor(instance), instance.ID, false)
+	}, d.render)
+	d.history = newAsyncData(ui, instance.ID+"/protection-history", func(ctx context.Context) (*model.ProtectionHistory, error) {
+		return ui.fetchProtectionHistory(ctx, ui.clientFor(instance), instance.ID)
+	}, d.render)
+	d.credits = newAsyncData(ui, instance.ID+"/credits", func(ctx context.Context) (*model.CPUCredits, error) {
+		credits, err := ui.clientFor(instance).GetCPUCredits(ctx, instance.ID, ui.featureEnabled(featureCloudWatch))
+		switch {
+		case err != nil:
+			return nil, err
+		case !ui.featureEnabled(featureCloudWatch):
+			credits.BalanceErr = fmt.Errorf("%s %w", featureCloudWatch, errFeatureDisabled)
+		case credits.BalanceErr != nil:
+			ui.checkFeatureError(featureCloudWatch, credits.BalanceErr)
+		}
+		return credits, nil
+	}, d.render)
+	d.routing = newAsyncData(ui, instance.ID+"/routing", func(ctx context.Context) (*model.SubnetRouting, error) {
+		return ui.clientFor(instance).GetSubnetRouting(ctx, instance.SubnetID, instance.VpcID)
+	}, d.render)
+	d.agent = newAsyncData(ui, instance.ID+"/agent", func(ctx context.Context) (bool, error) {
+		if !ui.featureEnabled(featureCloudWatch) {
+			return false, fmt.Errorf("%s %w", featureCloudWatch, errFeatureDisabled)
+		}
+		agent, err := ui.clientFor(instance).HasCloudWatchAgent(ctx, instance.ID)
+		ui.checkFeatureError(featureCloudWatch, err)
+		return agent, err
+	}, d.render)
+	d.metrics = newAsyncData(ui, instance.ID+"/metrics", func(ctx context.Context) (*model.InstanceMetrics, error) {
+		if !ui.featureEnabled(featureCloudWatch) {
+			return nil, fmt.Errorf("%s %w", featureCloudWatch, errFeatureDisabled)
+		}
+		metrics, err := ui.clientFor(instance).GetInstanceMetrics(ctx, instance.ID, metricsDuration, metricsPeriod)
+		ui.checkFeatureError(featureCloudWatch, err)
+		return metrics, err
+	}, d.render)
+	d.stack = newAsyncData(ui, instance.ID+"/stack", func(ctx context.Context) (*model.Stack, error) {
+		stack := instance.Tags["aws:cloudformation:stack-id"]
+		if stack == "" {
+			stack = instance.CloudFormationStack()
+		}
+		return ui.clientFor(instance).DescribeStack(ctx, stack)
+	}, d.render)
+
+	for i, name := range detailTabs {
+		if name == "Tags" {
+			d.pages.AddPage(name, d.tags, true, i == 0)
+			continue
+		}
+
+		view := tview.NewTextView().
+			SetDynamicColors(true).
+			SetTextAlign(tview.AlignLeft).
+			SetScrollable(true).
+			SetWrap(true)
+		d.views[name] = view
+		d.pages.AddPage(name, view, true, i == 0)
+	}
+
+	d.tags.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case 'y':
+			d.copySelectedTag(false)
+			return nil
+		case 'Y':
+			d.copySelectedTag(true)
+			return nil
+		case 'o':
+			d.openSelectedTag()
+			return nil
+		case 'f':
+			d.findSelectedTag()
+			return nil
+		case 'a':
+			d.editTag()
+			return nil
+		case 'x':
+			d.deleteTag()
+			return nil
+		case ' ':
+			d.toggleTagMark()
+			return nil
+		case 'p':
+			d.propagateTags()
+			return nil
+		}
+		return event
+	})
+
+	// The notice of the instance stays displayed above the tabs
+	if notice := ui.notice(instance); notice != "" {
+		banner := tview.NewTextView().
+			SetDynamicColors(true).
+			SetText(fmt.Sprintf(" [yellow::b]⚠ NOTICE:[-::-] %s", tview.Escape(notice)))
+		d.layout.AddItem(banner, 1, 0, false)
+	}
+	d.layout.
+		AddItem(d.tabBar, 1, 0, false).
+		AddItem(d.pages, 0, 1, true)
+
+	d.layout.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Instance: %s ", instance.DisplayName())).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	d.layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTab:
+			d.SelectTab((d.current + 1) % len(detailTabs))
+			return nil
+		case tcell.KeyBacktab:
+			d.SelectTab((d.current + len(detailTabs) - 1) % len(detailTabs))
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'v':
+				d.showVPC()
+				return nil
+			case 'e':
+				d.copyDetails(false)
+				return nil
+			case 'E':
+				d.copyDetails(true)
+				return nil
+			case 'w':
+				d.saveDetails()
+				return nil
+			case 'C':
+				d.switchCreditSpecification()
+				return nil
+			case 'D':
+				d.switchSourceDestCheck()
+				return nil
+			case 'M':
+				d.switchDetailedMonitoring()
+				return nil
+			case 'P':
+				d.switchProtection(model.ProtectionTermination)
+				return nil
+			case 'S':
+				d.switchProtection(model.ProtectionStop)
+				return nil
+			case 'R':
+				d.refreshTab()
+				return nil
+			}
+			if index, err := strconv.Atoi(string(event.Rune())); err == nil && index >= 1 && index <= len(detailTabs) {
+				d.SelectTab(index - 1)
+				return nil
+			}
+		}
+		return event
+	})
+
+	d.render()
+	d.renderTags()
+
+	return d
+}
+
+// Show displays the detail view
+func (d *DetailView) Show() {
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(d.layout, 90, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	d.ui.pages.AddPage("modal", flex, true, true)
+	d.SelectTab(d.current)
+}
+
+// SelectTab displays the tab at the given index
+func (d *DetailView) SelectTab(index int) {
+	d.current = index
+	d.pages.SwitchToPage(detailTabs[index])
+	d.renderTabBar()
+
+	// Fetch the data of the tab the first time it is displayed
+	for _, data := range d.detailTabData(detailTabs[index]) {
+		data.Load(false)
+	}
+}
+
+// refreshTab fetches the data of the current tab again
+func (d *DetailView) refreshTab() {
+	for _, data := range d.detailTabData(detailTabs[d.current]) {
+		if data == asyncLoader(d.protection) {
+			d.ui.invalidateProtection(d.instance.ID)
+		}
+		data.Load(true)
+	}
+}
+
+// render renders the tab bar and the content of every tab
+func (d *DetailView) render() {
+	d.renderTabBar()
+	d.views["Overview"].SetText(d.renderOverview())
+	d.views["Network"].SetText(d.renderNetwork())
+	d.views["Storage"].SetText(d.renderStorage())
+	d.views["Security"].SetText(d.renderSecurity())
+	d.views["Monitoring"].SetText(d.renderMonitoring())
+}
+
+// renderTabBar renders the names of the tabs, highlighting the current one
+func (d *DetailView) renderTabBar() {
+	var b strings.Builder
+	for i, name := range detailTabs {
+		fmt.Fprintf(&b, ` ["%d"][yellow]%d[-] %s[""] `, i, i+1, name)
+	}
+	b.WriteString(" [gray]Tab: next  e/E: copy text/Markdown  w: save  Esc: close[-]")
+	if data := d.detailTabData(detailTabs[d.current]); len(data) > 0 {
+		fmt.Fprintf(&b, " [gray]R: refresh (%s)[-]", data[0].Status())
+	}
+	if detailTabs[d.current] == "Tags" {
+		b.WriteString(" [gray]y: copy value  Y: copy key=value  o: open in console  f: find others[-]")
+		if d.ui.config().Allows(config.LevelAdmin) {
+			b.WriteString(" [gray]a: add/edit  x: delete  Space: mark  p: propagate[-]")
+		}
+	}
+
+	d.tabBar.SetText(b.String())
+	d.tabBar.Highlight(strconv.Itoa(d.current))
+}
+
+// renderOverview renders the general information of the instance
+func (d *DetailView) renderOverview() string {
+	instance := d.instance
+
+	// Format instance details
+	baseDetails := fmt.Sprintf(`
+[::b][yellow]Instance Details[-][::-]
+  [blue]ID:[-]            %s
+  [blue]Name:[-]          %s
+  [blue]Type:[-]          %s
+  [blue]State:[-]         %s %s
+  [blue]Region:[-]        %s
+  [blue]Launch Time:[-]   %s
+  [blue]Age:[-]           %s
+  [blue]Private IP:[-]    %s
+  [blue]Public IP:[-]     %s
+  [blue]IPv6:[-]          %s
+  [blue]Platform:[-]      %s
+  [blue]Architecture:[-]  %s
+`,
+		instance.ID,
+		instance.Name,
+		instance.Type,
+		getStateEmoji(instance.State), instance.State,
+		instance.Region,
+		d.ui.formatTime(instance.LaunchTime),
+		formatDuration(instance.Age),
+		instance.PrivateIP,
+		instance.PublicIP,
+		valueOrNone(instance.IPv6Address),
+		instance.Platform,
+		instance.Architecture,
+	)
+
+	return baseDetails + d.renderHardware() + d.renderEventsWarning() + d.renderStack() + d.renderTerraform()
+}
+
+// renderHardware renders the hardware and placement attributes of the
+// instance, which are easy to overlook until they cause problems
+func (d *DetailView) renderHardware() string {
+	instance := d.instance
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+[::b][yellow]Hardware & Placement[-][::-]
+  [blue]Nitro Enclaves:[-] %s
+  [blue]Hibernation:[-]    %s
+  [blue]Lifecycle:[-]      %s
+  [blue]Zone:[-]           %s
+  [blue]Tenancy:[-]        %s
+  [blue]Boot Mode:[-]      %s
+  [blue]NitroTPM:[-]       %s
+`,
+		formatBool(instance.Enclave),
+		formatBool(instance.Hibernation),
+		valueOrDefault(instance.Lifecycle, "on-demand"),
+		valueOrNone(instance.AvailabilityZone),
+		valueOrDefault(instance.Tenancy, "default"),
+		valueOrNone(instance.BootMode),
+		valueOrNone(instance.TPMSupport),
+	)
+	if instance.PlacementGroup != "" {
+		fmt.Fprintf(&b, "  [blue]Placement Group:[-] %s\n", instance.PlacementGroup)
+	}
+	if instance.HostID != "" {
+		fmt.Fprintf(&b, "  [blue]Host:[-]           %s\n", instance.HostID)
+	}
+	if instance.CapacityReservation != "" {
+		fmt.Fprintf(&b, "  [blue]Capacity Reservation:[-] %s\n", instance.CapacityReservation)
+	}
+	if instance.OutpostARN != "" {
+		fmt.Fprintf(&b, "  [blue]Outpost:[-]        %s\n", instance.OutpostARN)
+	}
+	for _, accelerator := range instance.Accelerators {
+		fmt.Fprintf(&b, "  [blue]Accelerator:[-]    %s\n", accelerator)
+	}
+	for _, license := range instance.Licenses {
+		fmt.Fprintf(&b, "  [blue]License:[-]        %s\n", license)
+	}
+	if flags := instance.Flags(); len(flags) > 0 {
+		fmt.Fprintf(&b, "  [blue]Flags:[-]          %s [gray](filter with flag:<name>)[-]\n", strings.Join(flags, ", "))
+	}
+
+	return b.String()
+}
+
+// renderEventsWarning warns about the active events scheduled on the instance
+func (d *DetailView) renderEventsWarning() string {
+	events := d.activeEvents()
+	if len(events) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n[::b][red]⚠ Scheduled Events[-][::-]\n")
+	for _, event := range events {
+		fmt.Fprintf(&b, "  [red]%s[-] from %s\n", event.Code, d.ui.formatTime(event.NotBefore))
+	}
+	b.WriteString("  [gray]See the Monitoring tab for details[-]\n")
+	return b.String()
+}
+
+// activeEvents returns the active scheduled events of the instance, from its
+// status if loaded or from the events listed with the instances
+func (d *DetailView) activeEvents() []model.ScheduledEvent {
+	status, ok := d.status.Value()
+	if !ok || status == nil {
+		return d.ui.store.Snapshot().Events[d.instance.ID]
+	}
+
+	var events []model.ScheduledEvent
+	for _, event := range status.Events {
+		if event.IsActive() {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// renderTerraform renders the Terraform resource managing the instance
+func (d *DetailView) renderTerraform() string {
+	resource, ok := d.ui.terraform.Lookup(d.instance.ID)
+	if !ok {
+		return ""
+	}
+
+	module := resource.Module
+	if module == "" {
+		module = "root module"
+	}
+
+	return fmt.Sprintf(`
+[::b][yellow]Terraform[-][::-]
+  [blue]Managed by:[-]    %s
+  [blue]Address:[-]       %s
+  [blue]State:[-]         %s
+  [orange]Manual changes to this instance will cause Terraform drift[-]
+`,
+		tview.Escape(module),
+		tview.Escape(resource.Address),
+		tview.Escape(resource.StateFile),
+	)
+}
+
+// renderStack renders the CloudFormation stack managing the instance: its
+// status, the resources it manages, and the other instances of the stack
+func (d *DetailView) renderStack() string {
+	stack := d.instance.CloudFormationStack()
+	if stack == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+[::b][yellow]CloudFormation[-][::-]
+  [blue]Stack:[-]         %s
+  [blue]Logical ID:[-]    %s
+`,
+		tview.Escape(stack),
+		valueOrNone(d.instance.CloudFormationLogicalID()),
+	)
+	d.stack.Render(&b, func(stack *model.Stack) {
+		statusColor := "green"
+		switch {
+		case stack.Failed():
+			statusColor = "red"
+		case stack.InProgress():
+			statusColor = "yellow"
+		}
+		fmt.Fprintf(&b, "  [blue]Stack ID:[-]      %s\n", tview.Escape(stack.ID))
+		fmt.Fprintf(&b, "  [blue]Status:[-]        [%s]%s[-]\n", statusColor, stack.Status)
+		if stack.StatusReason != "" {
+			fmt.Fprintf(&b, "  [blue]Reason:[-]        %s\n", tview.Escape(stack.StatusReason))
+		}
+		if stack.Drift != "" {
+			fmt.Fprintf(&b, "  [blue]Drift:[-]         %s\n", stack.Drift)
+		}
+
+		fmt.Fprintf(&b, "\n  [::b]Resources (%d)[::-]\n", len(stack.Resources))
+		for _, resource := range stack.Resources {
+			marker := " "
+			if resource.PhysicalID == d.instance.ID {
+				marker = "*"
+			}
+			resourceColor := "white"
+			switch {
+			case strings.HasSuffix(resource.Status, "_FAILED"):
+				resourceColor = "red"
+			case strings.HasSuffix(resource.Status, "_IN_PROGRESS"):
+				resourceColor = "yellow"
+			}
+			fmt.Fprintf(&b, "  %s %-24s %-32s [%s]%-18s[-] %s\n",
+				marker,
+				tview.Escape(resource.LogicalID),
+				resource.Type,
+				resourceColor,
+				resource.Status,
+				tview.Escape(resource.PhysicalID),
+			)
+			if resource.StatusReason != "" && resourceColor == "red" {
+				fmt.Fprintf(&b, "      [red]%s[-]\n", tview.Escape(resource.StatusReason))
+			}
+		}
+	})
+
+	b.WriteString(`
+  [orange]Changes made outside of CloudFormation will cause the stack to drift[-]
+
+  [::b]Instances in the stack[::-]
+`)
+	for _, sibling := range d.ui.store.Snapshot().Instances {
+		if sibling.CloudFormationStack() != stack {
+			continue
+		}
+		marker := " "
+		if sibling.ID == d.instance.ID {
+			marker = "*"
+		}
+		fmt.Fprintf(&b, "  %s %s %-20s %-22s %s %s\n",
+			marker,
+			getStateEmoji(sibling.State),
+			sibling.ID,
+			tview.Escape(sibling.CloudFormationLogicalID()),
+			sibling.Type,
+			tview.Escape(sibling.Name),
+		)
+	}
+
+	return b.String()
+}
+
+// tagCategories are the categories used to group the tags, in display order
+var tagCategories = []string{"Resource", "Business", "Technical", "Other"}
+
+// renderTags fills the tags table, grouping the tags by category
+func (d *DetailView) renderTags() {
+	d.tags.Clear()
+	d.tagKeys = d.tagKeys[:0]
+
+	for i, header := range []string{"Category", "Key", "Value"} {
+		d.tags.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	if len(d.instance.Tags) == 0 {
+		d.tags.SetCell(1, 0,
+			tview.NewTableCell(" No tags found on this instance ").
+				SetTextColor(color.AppColors.Secondary).
+				SetSelectable(false))
+		return
+	}
+
+	// Group tags by category for better organization
+	tagsByCategory := make(map[string][]string)
+	for key := range d.instance.Tags {
+		category := tagCategory(key)
+		tagsByCategory[category] = append(tagsByCategory[category], key)
+	}
+
+	row := 1
+	for _, category := range tagCategories {
+		keys := tagsByCategory[category]
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			keyColor := color.AppColors.Foreground
+			if _, ok := aws.TagConsoleURL(d.instance.Region, key, d.instance.Tags[key]); ok {
+				// Highlight the tags which can be opened in the console
+				keyColor = color.AppColors.Highlight
+			}
+
+			d.tags.SetCell(row, 0,
+				tview.NewTableCell(" "+category+" ").
+					SetTextColor(color.AppColors.Secondary))
+			marker := " "
+			if d.tagMarks[key] {
+				marker = "●"
+			}
+			d.tags.SetCell(row, 1,
+				tview.NewTableCell(marker+key+" ").
+					SetTextColor(keyColor))
+			d.tags.SetCell(row, 2,
+				tview.NewTableCell(" "+d.instance.Tags[key]+" ").
+					SetTextColor(color.AppColors.Foreground).
+					SetExpansion(1))
+			d.tagKeys = append(d.tagKeys, key)
+			row++
+		}
+	}
+
+	d.tags.Select(1, 0)
+}
+
+// selectedTag returns the key of the selected tag
+func (d *DetailView) selectedTag() (string, bool) {
+	row, _ := d.tags.GetSelection()
+	if row <= 0 || row-1 >= len(d.tagKeys) {
+		return "", false
+	}
+	return d.tagKeys[row-1], true
+}
+
+// copySelectedTag copies the value of the selected tag to the clipboard, or
+// the key=value pair if withKey is set
+func (d *DetailView) copySelectedTag(withKey bool) {
+	key, ok := d.selectedTag()
+	if !ok {
+		return
+	}
+
+	text := d.instance.Tags[key]
+	if withKey {
+		text = key + "=" + text
+	}
+
+	if err := desktop.CopyToClipboard(text); err != nil {
+		d.ui.log.Error("Failed to copy tag", "key", key, "error", err)
+		d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	d.ui.statusBar.SetStatus(fmt.Sprintf("Copied tag %s to clipboard", key))
+}
+
+// openSelectedTag opens the console page of the resource referenced by the selected tag
+func (d *DetailView) openSelectedTag() {
+	key, ok := d.selectedTag()
+	if !ok {
+		return
+	}
+
+	consoleURL, ok := aws.TagConsoleURL(d.instance.Region, key, d.instance.Tags[key])
+	if !ok {
+		d.ui.statusBar.SetError(fmt.Sprintf("No console page known for tag %s", key))
+		return
+	}
+
+	if err := desktop.OpenURL(consoleURL); err != nil {
+		d.ui.log.Error("Failed to open console", "url", consoleURL, "error", err)
+		d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	d.ui.statusBar.SetStatus(fmt.Sprintf("Opened %s in the browser", key))
+}
+
+// findSelectedTag filters the instances table on the selected tag and goes
+// back to it, to find the other instances with the same tag
+func (d *DetailView) findSelectedTag() {
+	key, ok := d.selectedTag()
+	if !ok {
+		return
+	}
+
+	d.ui.pages.RemovePage("modal")
+	d.ui.SetFilter(model.TagFilter(key, d.instance.Tags[key]))
+}
+
+// resourceLabel returns the ID of a resource of the instance with its name,
+// rendering the details again once it is resolved
+func (d *DetailView) resourceLabel(kind, id, missing string) string {
+	return d.ui.names().resourceLabel(d, d.render, d.ui.clientFor(d.instance), kind, id, missing)
+}
+
+// renderNetwork renders the VPC, network interfaces and security groups of the instance
+func (d *DetailView) renderNetwork() string {
+	instance := d.instance
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+[::b][yellow]Network[-][::-]
+  [blue]VPC:[-]              %s
+  [blue]Subnet:[-]           %s
+  [blue]Private DNS:[-]      %s
+  [blue]Public DNS:[-]       %s
+  [blue]Source/Dest Check:[-] %s
+`,
+		valueOrNone(instance.VpcID),
+		valueOrNone(d.resourceLabel(aws.SubnetResource, instance.SubnetID, "not found")),
+		valueOrNone(instance.PrivateDNSName),
+		valueOrNone(instance.PublicDNSName),
+		formatBool(instance.SourceDestCheck),
+	)
+	if d.ui.config().Allows(config.LevelAdmin) {
+		b.WriteString("  [gray]D: switch the source/dest check, disabled for NAT and router instances[-]\n")
+	}
+
+	if instance.VpcID != "" {
+		b.WriteString("  [yellow]Press v to show the VPC and subnets[-]\n")
+	}
+
+	b.WriteString("\n[::b][yellow]Security Groups[-][::-]\n")
+	writeSecurityGroups(&b, instance.SecurityGroups, "  ")
+
+	b.WriteString("\n[::b][yellow]Network Interfaces[-][::-]\n")
+	if len(instance.NetworkInterfaces) == 0 {
+		b.WriteString("  None\n")
+	}
+	for _, eni := range instance.NetworkInterfaces {
+		fmt.Fprintf(&b, `  [::b]%s[::-] %s
+    [blue]Status:[-]       %s
+    [blue]Subnet:[-]       %s
+    [blue]Private IP:[-]   %s
+    [blue]Public IP:[-]    %s
+    [blue]MAC Address:[-]  %s
+    [blue]Src/Dst Check:[-] %s
+    [blue]Groups:[-]
+`,
+			eni.ID,
+			tview.Escape(eni.Description),
+			eni.Status,
+			tview.Escape(d.resourceLabel(aws.SubnetResource, eni.SubnetID, "not found")),
+			valueOrNone(eni.PrivateIP),
+			valueOrNone(eni.PublicIP),
+			eni.MACAddress,
+			formatBool(eni.SourceDestCheck),
+		)
+		writeSecurityGroups(&b, eni.SecurityGroups, "      ")
+		b.WriteString("\n")
+	}
+
+	if instance.SubnetID != "" {
+		if len(instance.NetworkInterfaces) == 0 {
+			b.WriteString("\n")
+		}
+		d.renderRouting(&b)
+	}
+
+	return b.String()
+}
+
+// renderRouting renders the route table and the network ACL of the subnet
+// of the instance, the blackhole routes and the deny rules in red
+func (d *DetailView) renderRouting(b *strings.Builder) {
+	b.WriteString("[::b][yellow]Route Table[-][::-]\n")
+	d.routing.Render(b, func(routing *model.SubnetRouting) {
+		if routing.RouteTableID == "" {
+			b.WriteString("  None\n")
+			return
+		}
+		fmt.Fprintf(b, "  [::b]%s[::-] %s", routing.RouteTableID, tview.Escape(routing.RouteTableName))
+		if routing.MainRouteTable {
+			b.WriteString(" [gray](main route table of the VPC)[-]")
+		}
+		b.WriteString("\n")
+		for _, route := range routing.Routes {
+			stateColor := "-"
+			if route.State != "active" {
+				stateColor = "red"
+			}
+			fmt.Fprintf(b, "    %-22s → %-24s [%s]%s[-]\n", route.Destination, route.Target, stateColor, route.State)
+		}
+	})
+
+	b.WriteString("\n[::b][yellow]Network ACL[-][::-]\n")
+	d.routing.Render(b, func(routing *model.SubnetRouting) {
+		if routing.NetworkACLID == "" {
+			b.WriteString("  None\n")
+			return
+		}
+		fmt.Fprintf(b, "  [::b]%s[::-] %s\n", routing.NetworkACLID, tview.Escape(routing.NetworkACLName))
+		for i, entry := range routing.ACLEntries {
+			if i == 0 || entry.Egress != routing.ACLEntries[i-1].Egress {
+				direction := "Inbound"
+				if entry.Egress {
+					direction = "Outbound"
+				}
+				fmt.Fprintf(b, "    [blue]%s:[-]\n", direction)
+			}
+			rule := strconv.Itoa(entry.RuleNumber)
+			if entry.RuleNumber == 32767 {
+				rule = "*"
+			}
+			actionColor := "green"
+			if entry.Action != "allow" {
+				actionColor = "red"
+			}
+			fmt.Fprintf(b, "      %-6s %-7s %-12s %-20s [%s]%s[-]\n",
+				rule, entry.Protocol, valueOrDefault(entry.Ports, "all"), entry.CIDR, actionColor, entry.Action)
+		}
+	})
+}
+
+// showVPC opens the VPC view on the subnet of the instance
+func (d *DetailView) showVPC() {
+	id := d.instance.SubnetID
+	if id == "" {
+		id = d.instance.VpcID
+	}
+	if id == "" {
+		d.ui.statusBar.SetError("Instance is not in a VPC")
+		return
+	}
+	NewVPCView(d.ui, id).Show()
+}
+
+// renderStorage renders the root device and block device mappings of the instance
+func (d *DetailView) renderStorage() string {
+	instance := d.instance
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+[::b][yellow]Storage[-][::-]
+  [blue]Root Device:[-]      %s
+  [blue]Root Device Type:[-] %s
+  [blue]EBS Optimized:[-]    %s
+`,
+		valueOrNone(instance.RootDeviceName),
+		valueOrNone(instance.RootDeviceType),
+		formatBool(instance.EBSOptimized),
+	)
+
+	b.WriteString("\n[::b][yellow]Block Devices[-][::-]\n")
+	if len(instance.BlockDevices) == 0 {
+		b.WriteString("  None\n")
+	}
+	for _, device := range instance.BlockDevices {
+		fmt.Fprintf(&b, `  [::b]%s[::-]
+    [blue]Volume:[-]                 %s
+    [blue]Status:[-]                 %s
+    [blue]Delete on Termination:[-]  %s
+    [blue]Attached:[-]               %s
+
+`,
+			device.DeviceName,
+			valueOrNone(device.VolumeID),
+			valueOrNone(device.Status),
+			formatBool(device.DeleteOnTermination),
+			d.ui.formatTime(device.AttachTime),
+		)
+	}
+
+	return b.String()
+}
+
+// renderSecurity renders the IAM profile, key pair and protections of the instance
+func (d *DetailView) renderSecurity() string {
+	instance := d.instance
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+[::b][yellow]Security[-][::-]
+  [blue]IAM Instance Profile:[-] %s
+  [blue]Key Pair:[-]             %s
+  [blue]AMI:[-]                  %s
+  [blue]IMDS Tokens:[-]          %s
+`,
+		valueOrNone(instance.IAMInstanceProfile),
+		valueOrNone(d.resourceLabel(aws.KeyPairResource, instance.KeyName, "deleted")),
+		valueOrNone(d.resourceLabel(aws.ImageResource, instance.ImageID, "deregistered")),
+		valueOrNone(instance.MetadataHTTPTokens),
+	)
+
+	b.WriteString("\n[::b][yellow]Protections[-][::-]\n")
+	d.protection.Render(&b, func(protection *model.Protection) {
+		fmt.Fprintf(&b, "  [blue]Termination Protection:[-] %s\n", formatBool(protection.Termination))
+		fmt.Fprintf(&b, "  [blue]Stop Protection:[-]        %s\n", formatBool(protection.Stop))
+		if d.ui.config().Allows(config.LevelAdmin) {
+			b.WriteString("  [gray]P: enable or disable the termination protection, S: the stop protection[-]\n")
+		}
+	})
+
+	b.WriteString("\n[::b][yellow]Protection History[-][::-]\n")
+	d.history.Render(&b, func(history *model.ProtectionHistory) {
+		if history.CloudTrailErr != nil {
+			fmt.Fprintf(&b, "  [red]CloudTrail: %s[-]\n", tview.Escape(history.CloudTrailErr.Error()))
+		}
+		if len(history.Changes) == 0 {
+			b.WriteString("  No change in the last 90 days\n")
+			return
+		}
+		for _, change := range history.Changes {
+			state := "[red]disabled[-]"
+			if change.Enabled {
+				state = "[green]enabled[-]"
+			}
+			fmt.Fprintf(&b, "  %s  %-11s %s by %s [gray](%s)[-]\n",
+				d.ui.formatTime(change.Time), change.Protection, state, tview.Escape(valueOrDefault(change.User, "unknown")), change.Source)
+		}
+	})
+
+	return b.String()
+}
+
+// switchProtection enables or disables the termination or the stop
+// protection of the instance, at the admin level only
+func (d *DetailView) switchProtection(protection string) {
+	current, ok := d.protection.Value()
+	if !ok || current == nil {
+		return
+	}
+	if !d.ui.checkLevel("switching the protections", config.LevelAdmin) {
+		return
+	}
+
+	instance := d.instance
+	enabled := !current.Termination
+	if protection == model.ProtectionStop {
+		enabled = !current.Stop
+	}
+	action, state := "Disable", "disabled"
+	if enabled {
+		action, state = "Enable", "enabled"
+	}
+	message := fmt.Sprintf("%s the %s protection of %s?", action, protection, instance.DisplayName())
+	if !enabled {
+		consequence := "stopped"
+		if protection == model.ProtectionTermination {
+			consequence = "terminated"
+		}
+		message += fmt.Sprintf("\n\nThe instance can then be %s by anyone allowed to.", consequence)
+	}
+	d.ui.ShowConfirmDialog("Protection", message, func() {
+		d.ui.statusBar.SetStatus(fmt.Sprintf("Switching the %s protection of %s...", protection, instance.ID))
+		ctx := d.ui.actionCtx()
+		go func() {
+			err := d.ui.clientFor(instance).SetProtection(ctx, instance.ID, protection, enabled)
+			d.ui.app.QueueUpdateDraw(func() {
+				if err != nil {
+					d.ui.log.Error("Failed to switch protection", "instanceID", instance.ID, "protection", protection, "error", err)
+					d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+					return
+				}
+				d.ui.statusBar.SetStatus(fmt.Sprintf("The %s protection of %s is %s", protection, instance.ID, state))
+				d.protection.Load(true)
+				d.history.Load(true)
+			})
+		}()
+	})
+}
+
+// renderMonitoring renders the status checks and monitoring state of the instance
+func (d *DetailView) renderMonitoring() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+[::b][yellow]Monitoring[-][::-]
+  [blue]Detailed Monitoring:[-] %s
+`,
+		formatMonitoring(d.instance.Monitoring),
+	)
+	if d.ui.config().Allows(config.LevelAdmin) {
+		b.WriteString("  [gray]M: enable or disable the detailed monitoring, charged per metric[-]\n")
+	}
+	d.agent.Render(&b, func(agent bool) {
+		if agent {
+			b.WriteString("  [blue]CloudWatch Agent:[-]    [green]detected[-] [gray](CWAgent metrics in the last 3 hours)[-]\n")
+		} else {
+			b.WriteString("  [blue]CloudWatch Agent:[-]    [gray]not detected[-]\n")
+		}
+	})
+
+	b.WriteString(d.renderMetrics())
+	b.WriteString(d.renderCPU())
+
+	b.WriteString("\n[::b][yellow]Status Checks[-][::-]\n")
+	d.status.Render(&b, func(status *model.InstanceStatus) {
+		fmt.Fprintf(&b, "  [blue]System:[-]   %s\n", formatStatusCheck(status.System))
+		fmt.Fprintf(&b, "  [blue]Instance:[-] %s\n", formatStatusCheck(status.Instance))
+		fmt.Fprintf(&b, "  [blue]EBS:[-]      %s\n", formatStatusCheck(status.EBS))
+	})
+
+	b.WriteString("\n[::b][yellow]Scheduled Events[-][::-]\n")
+	d.status.Render(&b, func(status *model.InstanceStatus) {
+		if len(status.Events) == 0 {
+			b.WriteString("  None\n")
+			return
+		}
+		for _, event := range status.Events {
+			codeColor := "red"
+			if !event.IsActive() {
+				codeColor = "gray"
+			}
+			fmt.Fprintf(&b, "  [%s::b]%s[-::-] %s\n", codeColor, event.Code, tview.Escape(event.Description))
+			fmt.Fprintf(&b, "    [blue]Not Before:[-] %s\n", d.ui.formatTime(event.NotBefore))
+			if !event.NotAfter.IsZero() {
+				fmt.Fprintf(&b, "    [blue]Not After:[-]  %s\n", d.ui.formatTime(event.NotAfter))
+			}
+			if !event.Deadline.IsZero() {
+				fmt.Fprintf(&b, "    [blue]Deadline:[-]   %s\n", d.ui.formatTime(event.Deadline))
+			}
+		}
+	})
+
+	return b.String()
+}
+
+// renderMetrics renders the CPU utilization and the network traffic of the
+// instance over the last hours, as sparklines
+func (d *DetailView) renderMetrics() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n[::b][yellow]Metrics[-][::-] [gray](last %d hours, every %d minutes)[-]\n", int(metricsDuration.Hours()), int(metricsPeriod.Minutes()))
+	d.metrics.Render(&b, func(metrics *model.InstanceMetrics) {
+		for _, series := range metrics.Series {
+			label := metricLabels[series.Name]
+			last, ok := series.Last()
+			if !ok {
+				fmt.Fprintf(&b, "  [blue]%-12s[-] [gray]No datapoint[-]\n", label+":")
+				continue
+			}
+			fmt.Fprintf(&b, "  [blue]%-12s[-] %s [gray]last[-] %s [gray]avg[-] %s [gray]max[-] %s\n",
+				label+":",
+				sparkline(series.Values),
+				formatMetric(last, series.Unit),
+				formatMetric(series.Average(), series.Unit),
+				formatMetric(series.Max(), series.Unit),
+			)
+		}
+	})
+	return b.String()
+}
+
+// formatMetric formats the value of a metric in its unit, e.g. 42.0% or
+// 1.5 MB
+func formatMetric(value float64, unit string) string {
+	switch unit {
+	case "Percent":
+		return fmt.Sprintf("%.1f%%", value)
+	case "Bytes":
+		units := []string{"B", "KB", "MB", "GB", "TB"}
+		i := 0
+		for value >= 1000 && i < len(units)-1 {
+			value /= 1000
+			i++
+		}
+		return fmt.Sprintf("%.1f %s", value, units[i])
+	default:
+		return fmt.Sprintf("%.1f", value)
+	}
+}
+
+// renderCPU renders the CPU options, and the CPU credits of a burstable instance
+func (d *DetailView) renderCPU() string {
+	var b strings.Builder
+	b.WriteString("\n[::b][yellow]CPU[-][::-]\n")
+	if d.instance.CPUCores > 0 {
+		fmt.Fprintf(&b, "  [blue]Cores:[-]             %d\n", d.instance.CPUCores)
+		fmt.Fprintf(&b, "  [blue]Threads per Core:[-]  %d\n", d.instance.CPUThreads)
+	}
+	if !d.instance.IsBurstable() {
+		return b.String()
+	}
+
+	d.credits.Render(&b, func(credits *model.CPUCredits) {
+		fmt.Fprintf(&b, "  [blue]Credits:[-]           %s\n", valueOrNone(credits.Specification))
+		switch {
+		case credits.BalanceErr != nil:
+			fmt.Fprintf(&b, "  [blue]Credit Balance:[-]    [red]%s[-]\n", tview.Escape(credits.BalanceErr.Error()))
+		case credits.HasBalance():
+			fmt.Fprintf(&b, "  [blue]Credit Balance:[-]    %.1f [gray](%s)[-]\n", credits.Balance, d.ui.formatTime(credits.BalanceTime))
+		default:
+			b.WriteString("  [blue]Credit Balance:[-]    [gray]No datapoint in the last hour[-]\n")
+		}
+		if d.ui.config().Allows(config.LevelAdmin) {
+			b.WriteString("  [gray]C: switch between standard and unlimited[-]\n")
+		}
+	})
+
+	return b.String()
+}
+
+// switchCreditSpecification switches the credit specification of a burstable
+// instance between standard and unlimited, at the admin level only
+func (d *DetailView) switchCreditSpecification() {
+	credits, ok := d.credits.Value()
+	if !d.instance.IsBurstable() || !ok || credits == nil {
+		return
+	}
+	if !d.ui.checkLevel("switching the CPU credits", config.LevelAdmin) {
+		return
+	}
+
+	specification := model.CreditsUnlimited
+	if credits.Specification == model.CreditsUnlimited {
+		specification = model.CreditsStandard
+	}
+
+	instance := d.instance
+	message := fmt.Sprintf("Switch the CPU credits of %s from %s to %s?", instance.DisplayName(), credits.Specification, specification)
+	if specification == model.CreditsUnlimited {
+		message += "\n\nSurplus credits spent beyond the baseline are charged."
+	}
+	d.ui.ShowConfirmDialog("Switch CPU Credits", message, func() {
+		d.ui.statusBar.SetStatus(fmt.Sprintf("Switching the CPU credits of %s to %s...", instance.ID, specification))
+		go func() {
+			err := d.ui.clientFor(instance).SetCreditSpecification(d.ui.ctx, instance.ID, specification)
+			d.ui.app.QueueUpdateDraw(func() {
+				if err != nil {
+					d.ui.log.Error("Failed to switch CPU credits", "instanceID", instance.ID, "error", err)
+					d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+					return
+				}
+				d.ui.statusBar.SetStatus(fmt.Sprintf("CPU credits of %s switched to %s", instance.ID, specification))
+				d.credits.Load(true)
+			})
+		}()
+	})
+}
+
+// switchDetailedMonitoring enables or disables the detailed monitoring of
+// the instance, at the admin level only
+func (d *DetailView) switchDetailedMonitoring() {
+	if !d.ui.checkLevel("switching the detailed monitoring", config.LevelAdmin) {
+		return
+	}
+
+	instance := d.instance
+	enabled := instance.Monitoring != "enabled" && instance.Monitoring != "pending"
+	message := fmt.Sprintf("Disable the detailed monitoring of %s?\n\nIts metrics are then published every 5 minutes.", instance.DisplayName())
+	if enabled {
+		message = fmt.Sprintf("Enable the detailed monitoring of %s?\n\nIts metrics are then published every minute, and charged as custom metrics.", instance.DisplayName())
+	}
+	d.ui.ShowConfirmDialog("Detailed Monitoring", message, func() {
+		d.ui.statusBar.SetStatus(fmt.Sprintf("Switching the detailed monitoring of %s...", instance.ID))
+		ctx := d.ui.actionCtx()
+		go func() {
+			state, err := d.ui.clientFor(instance).SetDetailedMonitoring(ctx, instance.ID, enabled)
+			d.ui.app.QueueUpdateDraw(func() {
+				if err != nil {
+					d.ui.log.Error("Failed to switch detailed monitoring", "instanceID", instance.ID, "error", err)
+					d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+					return
+				}
+				d.ui.statusBar.SetStatus(fmt.Sprintf("Detailed monitoring of %s %s", instance.ID, state))
+
+				// Display the new state, the instances being loaded again
+				d.instance.Monitoring = state
+				d.views["Monitoring"].SetText(d.renderMonitoring())
+				d.ui.RefreshInstances()
+			})
+		}()
+	})
+}
+
+// switchSourceDestCheck enables or disables the source/destination check of
+// the primary network interface of the instance, at the admin level only
+func (d *DetailView) switchSourceDestCheck() {
+	if !d.ui.checkLevel("switching the source/dest check", config.LevelAdmin) {
+		return
+	}
+
+	instance := d.instance
+	enabled := !instance.SourceDestCheck
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	message := fmt.Sprintf("Disable the source/destination check of %s?\n\nThe instance can then send and receive traffic for other addresses, as a NAT or a router.", instance.DisplayName())
+	if enabled {
+		message = fmt.Sprintf("Enable the source/destination check of %s?\n\nThe instance can then no longer route traffic for other addresses.", instance.DisplayName())
+	}
+	d.ui.ShowConfirmDialog("Source/Dest Check", message, func() {
+		d.ui.statusBar.SetStatus(fmt.Sprintf("Switching the source/dest check of %s...", instance.ID))
+		ctx := d.ui.actionCtx()
+		go func() {
+			err := d.ui.clientFor(instance).SetSourceDestCheck(ctx, instance.ID, enabled)
+			d.ui.app.QueueUpdateDraw(func() {
+				if err != nil {
+					d.ui.log.Error("Failed to switch source/dest check", "instanceID", instance.ID, "error", err)
+					d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+					return
+				}
+				d.ui.statusBar.SetStatus(fmt.Sprintf("Source/dest check of %s %s", instance.ID, state))
+
+				// Display the new value, the instances being loaded again
+				d.instance.SourceDestCheck = enabled
+				d.instance.NetworkInterfaces = append([]model.NetworkInterface(nil), d.instance.NetworkInterfaces...)
+				for i := range d.instance.NetworkInterfaces {
+					if d.instance.NetworkInterfaces[i].Primary {
+						d.instance.NetworkInterfaces[i].SourceDestCheck = enabled
+					}
+				}
+				d.views["Network"].SetText(d.renderNetwork())
+				d.ui.RefreshInstances()
+			})
+		}()
+	})
+}
+
+// tagCategory returns the category used to group a tag in the details
+func tagCategory(key string) string {
+	switch strings.ToLower(key) {
+	case "name", "aws:cloudformation:stack-name", "aws:cloudformation:logical-id", "aws:autoscaling:groupname":
+		return "Resource"
+	case "environment", "env", "project", "owner", "team", "cost-center", "application", "app", "service", "product", "costcenter", "business-unit":
+		return "Business"
+	case "role", "version", "tier", "type", "platform", "auto-delete", "auto-stop", "backup", "cluster", "scheduler":
+		return "Technical"
+	default:
+		return "Other"
+	}
+}
+
+// writeSecurityGroups writes a list of security groups with the given indentation
+func writeSecurityGroups(b *strings.Builder, groups []model.SecurityGroup, indent string) {
+	if len(groups) == 0 {
+		b.WriteString(indent + "None\n")
+		return
+	}
+	for _, group := range groups {
+		fmt.Fprintf(b, "%s[blue]%s[-] %s\n", indent, group.ID, tview.Escape(group.Name))
+	}
+}
+
+// formatStatusCheck formats a status check result with a color
+func formatStatusCheck(status string) string {
+	switch status {
+	case "ok":
+		return "[green]ok[-]"
+	case "impaired", "insufficient-data":
+		return "[red]" + status + "[-]"
+	case "":
+		return "n/a"
+	default:
+		return "[yellow]" + status + "[-]"
+	}
+}
+
+// formatMonitoring formats the detailed monitoring state of an instance with
+// the granularity of its metrics
+func formatMonitoring(state string) string {
+	switch state {
+	case "enabled":
+		return "[green]enabled[-] [gray](1-minute metrics)[-]"
+	case "disabled":
+		return "disabled [gray](5-minute metrics)[-]"
+	default:
+		return valueOrNone(state)
+	}
+}
+
+// formatBool formats a boolean as a human-readable value
+func formatBool(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
+
+// valueOrDefault returns the value, or the given default if it is empty
+func valueOrDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// valueOrNone returns the value or a placeholder if it is empty
+func valueOrNone(value string) string {
+	if value == "" {
+		return "[gray]-[-]"
+	}
+	return tview.Escape(value)
+}
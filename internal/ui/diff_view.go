@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/output"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// runDiffCommand runs the diff command, comparing an inventory exported
+// before with the instances loaded
+func (ui *UI) runDiffCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("an inventory file (.json or .yaml) expected")
+	}
+
+	before, err := output.ReadInstances(args[0])
+	if err != nil {
+		return err
+	}
+	diffs := model.DiffInstances(before, ui.store.Snapshot().Instances)
+	if len(diffs) == 0 {
+		ui.statusBar.SetStatus(fmt.Sprintf("No change since %s", args[0]))
+		return nil
+	}
+
+	ui.ShowDiffView(filepath.Base(args[0]), diffs)
+	return nil
+}
+
+// ShowDiffView displays the instances added, removed and changed since an
+// inventory
+func (ui *UI) ShowDiffView(source string, diffs []model.InstanceDiff) {
+	table := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Diff: %s → now (%d instances) ", source, len(diffs))).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	for i, header := range []string{"Change", "ID", "Name", "Details"} {
+		table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	for i, diff := range diffs {
+		kindColor := color.AppColors.Pending
+		switch diff.Kind {
+		case model.DiffAdded:
+			kindColor = color.AppColors.Running
+		case model.DiffRemoved:
+			kindColor = color.AppColors.Stopped
+		}
+
+		changes := make([]string, 0, len(diff.Changes))
+		for _, change := range diff.Changes {
+			changes = append(changes, change.String())
+		}
+
+		row := i + 1
+		table.SetCell(row, 0, tview.NewTableCell(" "+diff.Kind+" ").SetTextColor(kindColor).SetAttributes(tcell.AttrBold))
+		table.SetCell(row, 1, tview.NewTableCell(" "+diff.ID+" ").SetTextColor(color.AppColors.Foreground))
+		table.SetCell(row, 2, tview.NewTableCell(" "+diff.Name+" ").SetTextColor(color.AppColors.Foreground))
+		table.SetCell(row, 3, tview.NewTableCell(" "+strings.Join(changes, ", ")+" ").SetTextColor(color.AppColors.Foreground).SetExpansion(1))
+	}
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(table, 120, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// ShowStopEnvironmentDialog asks for the tag selector of the environment to stop
+func (ui *UI) ShowStopEnvironmentDialog() {
+	form := tview.NewForm()
+	form.AddInputField("Tag:", "", 30, nil, nil)
+	form.GetFormItem(0).(*tview.InputField).SetPlaceholder("env=staging")
+	form.AddButton("Plan", func() {
+		selector := form.GetFormItem(0).(*tview.InputField).GetText()
+		key, value, found := strings.Cut(strings.TrimSpace(selector), "=")
+		if !found || key == "" || value == "" {
+			ui.statusBar.SetError("The tag selector must be of the form key=value")
+			return
+		}
+		ui.pages.RemovePage("modal")
+		ui.planStopEnvironment(key, value)
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	form.SetBorder(true).SetTitle("Stop Environment")
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 44, 1, true).
+			AddItem(nil, 0, 1, false), 0, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// planStopEnvironment lists the instances with the tag and their stop
+// protection, and shows the plan stopping them
+func (ui *UI) planStopEnvironment(key, value string) {
+	ui.statusBar.SetStatus(fmt.Sprintf("Planning the stop of %s=%s...", key, value))
+
+	go func() {
+		filters := map[string][]string{"tag:" + key: {value}}
+		var instances []model.Instance
+		var err error
+		if active := ui.active.Load(); len(active.config.Accounts) > 0 {
+			instances, err = ui.listAccountsInstances(ui.ctx, filters)
+		} else {
+			instances, err = active.client.ListInstances(ui.ctx, filters)
+		}
+		if err != nil {
+			ui.log.Error("Failed to list environment instances", "key", key, "value", value, "error", err)
+			ui.app.QueueUpdateDraw(func() {
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+			})
+			return
+		}
+
+		protected := ui.stopProtections(instances)
+
+		ui.app.QueueUpdateDraw(func() {
+			if len(instances) == 0 {
+				ui.statusBar.SetError(fmt.Sprintf("No instance with the tag %s=%s", key, value))
+				return
+			}
+
+			action := ui.stopAction()
+			action.name = fmt.Sprintf("Stop %s=%s", key, value)
+			// The instances of the environment are not necessarily displayed
+			clients := ui.clientsOf(instances)
+			action.run = func(ctx context.Context, id string) error {
+				return clients[id].StopInstance(ctx, id)
+			}
+			action.check = skipProtected(action.check, protected)
+			ui.ShowBatchPlan(action, instances)
+		})
+	}()
+}
+
+// stopProtections returns why the running instances cannot be stopped, for
+// those with the stop protection enabled or whose protection is unknown
+func (ui *UI) stopProtections(instances []model.Instance) map[string]string {
+	var running []model.Instance
+	for _, instance := range instances {
+		if instance.IsRunning() {
+			running = append(running, instance)
+		}
+	}
+	return ui.blockingProtections(running, model.ProtectionStop)
+}
+
+// blockingProtections returns why the instances cannot be stopped or
+// terminated, for those with the stop or the termination protection enabled
+// or whose protection is unknown
+func (ui *UI) blockingProtections(instances []model.Instance, kind string) map[string]string {
+	ids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		ids = append(ids, instance.ID)
+	}
+	clients := ui.clientsOf(instances)
+
+	var mutex sync.Mutex
+	protected := make(map[string]string)
+	ui.newBatchEngine().Run(ui.ctx, ids, func(ctx context.Context, id string) error {
+		protection, err := ui.fetchProtection(ctx, clients[id], id, true)
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch {
+		case err != nil:
+			protected[id] = "protection unknown"
+		case kind == model.ProtectionStop && protection.Stop,
+			kind == model.ProtectionTermination && protection.Termination:
+			protected[id] = kind + " protection"
+		}
+		return err
+	}, nil)
+
+	return protected
+}
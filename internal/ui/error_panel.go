@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/store"
+)
+
+// ShowErrorPanel displays a centered panel explaining why the instances
+// could not be loaded, with the actions available to recover
+func (ui *UI) ShowErrorPanel(err error) {
+	kind := aws.ClassifyError(err)
+
+	profile := ui.ec2Client().GetProfile()
+	if profile == "" {
+		profile = "default credentials chain"
+	}
+
+	// Describe the external process supplying the credentials, and how to
+	// renew them
+	var credentials string
+	actions := "[yellow]r[-]: Retry    [yellow]P[-]: Switch profile    [yellow]q[-]: Quit"
+	if ui.credentials != nil && ui.credentials.IsExternal() {
+		credentials = fmt.Sprintf("\n[blue]Credentials:[-] %s", tview.Escape(ui.credentials.String()))
+		if ui.credentials.CanReexec() {
+			actions = fmt.Sprintf("[yellow]x[-]: Re-run with %s    %s", ui.credentials.Process, actions)
+		} else if kind == aws.ErrorExpiredCredentials || kind == aws.ErrorCredentials {
+			actions = fmt.Sprintf("[yellow]r[-]: Run %s again    [yellow]P[-]: Switch profile    [yellow]q[-]: Quit",
+				ui.credentials.Process)
+		}
+	}
+
+	// Offer to log in again when the profile uses IAM Identity Center
+	if kind == aws.ErrorExpiredCredentials || kind == aws.ErrorCredentials {
+		if sso := ui.ec2Client().SSOConfig(ui.ctx); sso != nil {
+			credentials += fmt.Sprintf("\n[blue]SSO:[-] %s", tview.Escape(ssoDescription(sso)))
+			actions = "[yellow]l[-]: SSO login    " + actions
+		}
+	}
+
+	text := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignCenter).
+		SetWrap(true)
+
+	text.SetText(fmt.Sprintf(`
+[::b][red]%s[-][::-]
+
+%s
+
+[blue]Profile:[-] %s
+[blue]Region:[-]  %s%s
+
+[gray]%s[-]
+
+%s
+`,
+		kind,
+		kind.Hint(),
+		profile,
+		ui.ec2Client().GetRegion(),
+		credentials,
+		tview.Escape(err.Error()),
+		actions,
+	))
+
+	text.SetBorder(true).
+		SetTitle(" Unable to load instances ").
+		SetBorderColor(color.AppColors.Error).
+		SetTitleColor(color.AppColors.Error)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(text, 17, 1, true).
+			AddItem(nil, 0, 1, false), 80, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	// Replace a previous panel
+	ui.pages.RemovePage("error")
+	ui.pages.AddPage("error", flex, true, true)
+}
+
+// retryFromErrorPanel closes the error panel and refreshes the instances,
+// retrieving the credentials again from their provider
+func (ui *UI) retryFromErrorPanel() {
+	ui.ec2Client().InvalidateCredentials()
+	ui.pages.RemovePage("error")
+	ui.RefreshInstances()
+}
+
+// ShowProfileDialog displays a dialog to switch the AWS profile
+func (ui *UI) ShowProfileDialog() {
+	profiles := aws.ListProfiles()
+
+	form := tview.NewForm()
+	if len(profiles) > 0 {
+		current := 0
+		for i, profile := range profiles {
+			if profile == ui.ec2Client().GetProfile() {
+				current = i
+			}
+		}
+		form.AddDropDown("Profile:", profiles, current, nil)
+	} else {
+		form.AddInputField("Profile:", ui.ec2Client().GetProfile(), 30, nil, nil)
+	}
+
+	form.AddButton("Switch", func() {
+		var profile string
+		switch item := form.GetFormItem(0).(type) {
+		case *tview.DropDown:
+			_, profile = item.GetCurrentOption()
+		case *tview.InputField:
+			profile = item.GetText()
+		}
+
+		ui.pages.RemovePage("modal")
+		ui.switchProfile(profile)
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	form.SetBorder(true).SetTitle("Switch AWS Profile")
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	// Center the form
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 50, 1, true).
+			AddItem(nil, 0, 1, false), 0, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// switchProfile creates a new EC2 client using the given profile and reloads
+// the instances with it
+func (ui *UI) switchProfile(profile string) {
+	ui.statusBar.SetStatus(fmt.Sprintf("Switching to profile %s...", profile))
+
+	if err := ui.switchClient(profile, ui.ec2Client().GetRegion()); err != nil {
+		ui.log.Error("Failed to switch profile", "profile", profile, "error", err)
+		ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	ui.pages.RemovePage("error")
+	ui.RefreshInstances()
+}
+
+// switchClient replaces the EC2 client with one using the given profile and
+// region, and drops the data fetched with the previous one
+func (ui *UI) switchClient(profile, region string) error {
+	cfg := ui.config().Clone()
+	cfg.AWS.Profile = profile
+	cfg.AWS.DefaultRegion = region
+	return ui.switchConfig(cfg)
+}
+
+// switchConfig replaces the EC2 client with one using the profile and the
+// region of a configuration, which is applied, and drops the data fetched
+// with the previous one. The client, the configuration and the caches are
+// published at once, as a new session.
+func (ui *UI) switchConfig(cfg *config.Config) error {
+	profile, region := cfg.AWS.Profile, cfg.AWS.DefaultRegion
+	client, err := aws.NewEC2Client(ui.awsLog, region, profile, aws.AssumeRole(cfg.AWS.AssumeRole), aws.CallOptions(cfg.AWS.Calls), ui.telemetry())
+	if err != nil {
+		return err
+	}
+	client.SetMFAPrompt(ui.promptMFA)
+	client.SetMutationHook(ui.instanceMutated)
+
+	previous := ui.active.Load()
+	client.SetAuditLog(previous.client.AuditLog())
+	if profile != previous.config.AWS.Profile {
+		// The regions refreshed in the background are those of the profile,
+		// and the caller of the feature flags may change
+		left := workspace{profile: previous.config.AWS.Profile, region: previous.client.GetRegion()}
+		limit := cfg.AWS.BackgroundRegions
+		if !ui.backgroundRegionsEnabled() {
+			limit = 0
+		}
+		ui.regions.changeProfile(left, previous.client, ui.store.Snapshot().Instances, workspace{profile: profile, region: region}, limit)
+		ui.caller.Store(nil)
+		go ui.resolveCaller(client)
+	}
+	// Stop the refreshes of the previous session, the instances they would
+	// still dispatch are dropped by the store
+	session := ui.newSession(client, cfg)
+	ui.active.Store(session)
+	previous.cancel()
+	ui.store.Dispatch(store.SessionStarted{Session: session.id})
+	ui.setupAccounts(region)
+	ui.store.Dispatch(store.ProtectionsCleared{})
+	go ui.resolveCredentials()
+	if ui.featureEnabled(featureHealth) {
+		// The events of the previous region were dropped
+		go ui.refreshHealth()
+	}
+	ui.applyRefreshOverride()
+	return nil
+}
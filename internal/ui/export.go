@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/desktop"
+	"github.com/nlamirault/e2c/internal/output"
+)
+
+// tagPattern matches the tview color tags, and the escaped square brackets
+// produced by tview.Escape
+var tagPattern = regexp.MustCompile(`\[[a-zA-Z0-9_,;: \-\."#]+\[*\[\]|\[[a-zA-Z0-9_,;:\-\.#"]*\]`)
+
+// stripColorTags removes the tview color tags from a text and unescapes the
+// escaped square brackets
+func stripColorTags(text string) string {
+	return tagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		if strings.HasSuffix(tag, "[]") {
+			return tag[:len(tag)-2] + "]"
+		}
+		return ""
+	})
+}
+
+// detailSections returns the name and plain text content of each tab of the
+// detail view
+func (d *DetailView) detailSections() [][2]string {
+	sections := make([][2]string, 0, len(detailTabs))
+	for _, name := range detailTabs {
+		var content string
+		if name == "Tags" {
+			content = d.tagsText()
+		} else {
+			content = stripColorTags(d.views[name].GetText(false))
+		}
+		sections = append(sections, [2]string{name, strings.Trim(content, "\n")})
+	}
+	return sections
+}
+
+// tagsText renders the tags of the instance as plain text
+func (d *DetailView) tagsText() string {
+	if len(d.instance.Tags) == 0 {
+		return "No tags"
+	}
+
+	keys := make([]string, 0, len(d.instance.Tags))
+	for key := range d.instance.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "  %s = %s\n", key, d.instance.Tags[key])
+	}
+	return b.String()
+}
+
+// PlainText renders the details of the instance as plain text, without
+// color tags
+func (d *DetailView) PlainText() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Instance: %s (%s)\n", d.instance.DisplayName(), d.instance.ID)
+	if notice := d.ui.notice(d.instance); notice != "" {
+		fmt.Fprintf(&b, "Notice: %s\n", notice)
+	}
+	for _, section := range d.detailSections() {
+		fmt.Fprintf(&b, "\n== %s ==\n%s\n", section[0], section[1])
+	}
+	return b.String()
+}
+
+// Markdown renders the details of the instance as Markdown. The content of
+// each section is kept in a code block to preserve its alignment.
+func (d *DetailView) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Instance %s (`%s`)\n", d.instance.DisplayName(), d.instance.ID)
+	if notice := d.ui.notice(d.instance); notice != "" {
+		fmt.Fprintf(&b, "\n> **Notice:** %s\n", notice)
+	}
+	for _, section := range d.detailSections() {
+		fmt.Fprintf(&b, "\n## %s\n\n```\n%s\n```\n", section[0], section[1])
+	}
+	return b.String()
+}
+
+// copyDetails copies the details of the instance to the clipboard, as
+// Markdown or plain text
+func (d *DetailView) copyDetails(markdown bool) {
+	text, format := d.PlainText(), "plain text"
+	if markdown {
+		text, format = d.Markdown(), "Markdown"
+	}
+
+	if err := desktop.CopyToClipboard(text); err != nil {
+		d.ui.log.Error("Failed to copy instance details", "instanceID", d.instance.ID, "error", err)
+		d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	d.ui.statusBar.SetStatus(fmt.Sprintf("Copied details of %s to clipboard as %s", d.instance.ID, format))
+}
+
+// saveDetails writes the details of the instance as Markdown to a file in
+// the current directory
+func (d *DetailView) saveDetails() {
+	filename := d.instance.ID + ".md"
+	if err := os.WriteFile(filename, []byte(d.Markdown()), 0o644); err != nil {
+		d.ui.log.Error("Failed to save instance details", "file", filename, "error", err)
+		d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	d.ui.statusBar.SetStatus(fmt.Sprintf("Saved details of %s to %s", d.instance.ID, filename))
+}
+
+// exportFormats are the formats of the exported instances, by file extension
+var exportFormats = map[string]string{
+	".csv":  output.FormatCSV,
+	".json": output.FormatJSON,
+	".yaml": output.FormatYAML,
+	".yml":  output.FormatYAML,
+}
+
+// runExportCommand runs the export command, writing the instances displayed
+// to a file
+func (ui *UI) runExportCommand(args []string) error {
+	switch len(args) {
+	case 0:
+		ui.ShowExportDialog()
+		return nil
+	case 1:
+		return ui.exportInstances(args[0])
+	default:
+		return errors.New("at most one file expected")
+	}
+}
+
+// ShowExportDialog displays the dialog asking for the file the instances
+// displayed are exported to
+func (ui *UI) ShowExportDialog() {
+	filename := fmt.Sprintf("instances-%s.csv", time.Now().Format("20060102-150405"))
+
+	form := tview.NewForm()
+	form.AddInputField("File:", filename, 40, nil, nil)
+	form.AddButton("Export", func() {
+		ui.pages.RemovePage("modal")
+		if err := ui.exportInstances(form.GetFormItem(0).(*tview.InputField).GetText()); err != nil {
+			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		}
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	form.SetBorder(true).SetTitle("Export Instances (.csv, .json, .yaml)")
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 52, 1, true).
+			AddItem(nil, 0, 1, false), 7, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// exportInstances writes the instances displayed, filtered and sorted, with
+// their tags to a file, in the format of its extension
+func (ui *UI) exportInstances(filename string) error {
+	format, ok := exportFormats[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return fmt.Errorf("unsupported file extension %q (expected .csv, .json or .yaml)", filepath.Ext(filename))
+	}
+	renderer, err := output.New(format, nil)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	instances := ui.instancesView.instances
+	if err := renderer.Render(file, output.Instances(instances, ui.instancesView.tagColumns, ui.formatTime)); err != nil {
+		return fmt.Errorf("failed to export instances: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to export instances: %w", err)
+	}
+
+	ui.log.Info("Exported instances", "file", filename, "instances", len(instances))
+	ui.statusBar.SetStatus(fmt.Sprintf("Exported %d instances to %s", len(instances), filename))
+	return nil
+}
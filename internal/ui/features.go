@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// Optional features, disabled for the session once the caller is found not
+// allowed to use them
+const (
+	featureEvents      = "scheduled events" // ec2:DescribeInstanceStatus
+	featureProtections = "protections scan" // ec2:DescribeInstanceAttribute
+	featureCloudWatch  = "CloudWatch"       // cloudwatch:ListMetrics
+	featureLogs        = "CloudWatch Logs"  // logs:FilterLogEvents
+	featureHealth      = "AWS Health"       // health:DescribeEvents, and a support plan
+	featureCloudTrail  = "CloudTrail"       // cloudtrail:LookupEvents
+)
+
+// errFeatureDisabled is returned instead of calling AWS for a feature
+// disabled for the session
+var errFeatureDisabled = errors.New("disabled for the session, the permissions are missing")
+
+// features holds the optional features disabled for the session, with the
+// reason
+type features struct {
+	mutex    sync.Mutex
+	disabled map[string]string
+}
+
+// featureEnabled returns false once a feature was disabled for the session
+func (ui *UI) featureEnabled(name string) bool {
+	ui.features.mutex.Lock()
+	defer ui.features.mutex.Unlock()
+	_, disabled := ui.features.disabled[name]
+	return !disabled
+}
+
+// checkFeatureError disables a feature for the session if the error of one of
+// its calls shows that the caller is not allowed to use it, and returns true
+// in that case. The feature is reported once, rather than on every refresh.
+func (ui *UI) checkFeatureError(name string, err error) bool {
+	if aws.ClassifyError(err) != aws.ErrorPermissions {
+		return false
+	}
+	ui.disableFeature(name, err.Error())
+	return true
+}
+
+// disableFeature disables a feature for the session, and notes it in the
+// status bar the first time
+func (ui *UI) disableFeature(name, reason string) {
+	ui.features.mutex.Lock()
+	if _, disabled := ui.features.disabled[name]; disabled {
+		ui.features.mutex.Unlock()
+		return
+	}
+	if ui.features.disabled == nil {
+		ui.features.disabled = make(map[string]string)
+	}
+	ui.features.disabled[name] = reason
+	ui.features.mutex.Unlock()
+
+	ui.log.Warn("Feature disabled for the session", "feature", name, "reason", reason)
+	ui.app.QueueUpdateDraw(func() {
+		ui.statusBar.SetError(fmt.Sprintf("%s disabled for the session: permissions missing, see the help", name))
+		panel := ui.overviewPanel
+		panel.Update(panel.instanceCount, panel.instancesRunning, panel.instancesStopped, panel.region)
+	})
+}
+
+// disabledFeatures returns the features disabled for the session, sorted,
+// with the reason
+func (ui *UI) disabledFeatures() [][2]string {
+	ui.features.mutex.Lock()
+	defer ui.features.mutex.Unlock()
+
+	names := make([]string, 0, len(ui.features.disabled))
+	for name := range ui.features.disabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	features := make([][2]string, 0, len(names))
+	for _, name := range names {
+		features = append(features, [2]string{name, ui.features.disabled[name]})
+	}
+	return features
+}
+
+// disabledFeatureNames returns the names of the features disabled for the
+// session, e.g. "CloudWatch, protections scan"
+func (ui *UI) disabledFeatureNames() string {
+	var names []string
+	for _, feature := range ui.disabledFeatures() {
+		names = append(names, feature[0])
+	}
+	return strings.Join(names, ", ")
+}
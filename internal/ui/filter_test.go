@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/model/modeltest"
+)
+
+// newFilterUI creates a UI with only the configuration needed to filter the
+// instances, displaying the tag columns
+func newFilterUI(tagColumns ...string) *UI {
+	ui := &UI{}
+	ui.active.Store(&activeSession{config: &config.Config{UI: config.UIConfig{TagColumns: tagColumns}}})
+	return ui
+}
+
+func TestApplyFilter(t *testing.T) {
+	instances := []model.Instance{
+		modeltest.NewInstance().WithID("i-0web1").WithName("web-1").WithPublicIP("54.1.2.3").WithTag("Team", "payments").Build(),
+		modeltest.NewInstance().WithID("i-0web2").WithName("web-2").Stopped().Spot().Build(),
+		modeltest.NewInstance().WithID("i-0db1").WithName("db-1").WithType("r5.xlarge").WithPrivateIP("10.0.2.20").Build(),
+		modeltest.NewInstance().WithID("i-0bastion").WithName("bastion").Arm().WithAccount("210987654321").Build(),
+	}
+
+	tests := []struct {
+		name       string
+		expr       string
+		tagColumns []string
+		want       []string
+	}{
+		{name: "empty", expr: "", want: []string{"i-0web1", "i-0web2", "i-0db1", "i-0bastion"}},
+		{name: "server terms only", expr: "state:running", want: []string{"i-0web1", "i-0web2", "i-0db1", "i-0bastion"}},
+		{name: "name", expr: "web", want: []string{"i-0web1", "i-0web2"}},
+		{name: "case insensitive", expr: "BASTION", want: []string{"i-0bastion"}},
+		{name: "state", expr: "stopped", want: []string{"i-0web2"}},
+		{name: "type", expr: "r5.", want: []string{"i-0db1"}},
+		{name: "private IP", expr: "10.0.2.20", want: []string{"i-0db1"}},
+		{name: "public IP", expr: "54.1.2", want: []string{"i-0web1"}},
+		{name: "account", expr: "210987654321", want: []string{"i-0bastion"}},
+		{name: "hidden tag", expr: "payments", want: []string{}},
+		{name: "tag column", expr: "payments", tagColumns: []string{"Team"}, want: []string{"i-0web1"}},
+		{name: "flag", expr: "flag:spot", want: []string{"i-0web2"}},
+		{name: "flag and text", expr: "flag:spot db", want: []string{}},
+		{name: "no match", expr: "cache", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := newFilterUI(tt.tagColumns...).applyFilter(instances, model.ParseFilter(tt.expr))
+
+			got := make([]string, 0, len(filtered))
+			for _, instance := range filtered {
+				got = append(got, instance.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyFilter(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkApplyFilter(b *testing.B) {
+	instances := modeltest.NewGenerator(42).Instances(5000)
+	ui := newFilterUI("Environment", "Team")
+
+	for _, expr := range []string{"web", "payments", "flag:spot", "no-match"} {
+		filter := model.ParseFilter(expr)
+		b.Run(expr, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				ui.applyFilter(instances, filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// finderResults is the maximum number of instances listed by the finder
+const finderResults = 15
+
+// wordSeparators separate the words of the names, a match at the start of a
+// word scoring higher
+const wordSeparators = "-_./: "
+
+// finderMatch is an instance matched by the finder, with its score
+type finderMatch struct {
+	instance model.Instance
+	score    int
+}
+
+// fuzzyScore returns whether the characters of a pattern appear in order in
+// a text, case-insensitively, and the score of the match: consecutive
+// characters and characters starting a word score higher
+func fuzzyScore(pattern, text string) (int, bool) {
+	pattern, text = strings.ToLower(pattern), strings.ToLower(text)
+
+	score, previous, position := 0, -2, 0
+	for _, r := range pattern {
+		index := strings.IndexRune(text[position:], r)
+		if index < 0 {
+			return 0, false
+		}
+		index += position
+
+		switch {
+		case index == previous+1:
+			score += 5
+		case index == 0 || strings.IndexByte(wordSeparators, text[index-1]) >= 0:
+			score += 3
+		default:
+			score++
+		}
+		previous = index
+		position = index + utf8.RuneLen(r)
+	}
+	return score, true
+}
+
+// matchInstance returns whether a pattern matches the name, the ID or an IP
+// address of an instance, and the score of the best match. An empty pattern
+// matches all the instances.
+func matchInstance(instance model.Instance, pattern string) (int, bool) {
+	best, found := 0, pattern == ""
+	for _, value := range []string{instance.Name, instance.ID, instance.PrivateIP, instance.PublicIP} {
+		if value == "" || pattern == "" {
+			continue
+		}
+		if score, ok := fuzzyScore(pattern, value); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+// findInstances returns the instances matching a pattern on their name, ID
+// or IP addresses, the best matches first, the instances in the order of the
+// table if the pattern is empty
+func findInstances(instances []model.Instance, pattern string) []finderMatch {
+	pattern = strings.TrimSpace(pattern)
+
+	var matches []finderMatch
+	for _, instance := range instances {
+		if score, ok := matchInstance(instance, pattern); ok {
+			matches = append(matches, finderMatch{instance: instance, score: score})
+		}
+	}
+
+	// Shorter names first among the matches of the same score
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].instance.Name) < len(matches[j].instance.Name)
+	})
+	if len(matches) > finderResults {
+		matches = matches[:finderResults]
+	}
+	return matches
+}
+
+// ShowFinder displays the fuzzy finder over the instances displayed: the
+// selection jumps to the instance picked, without changing the filter
+func (ui *UI) ShowFinder() {
+	input := tview.NewInputField().
+		SetLabel("> ").
+		SetFieldBackgroundColor(color.AppColors.Background).
+		SetPlaceholder("name, ID or IP address")
+	results := tview.NewTable().SetSelectable(true, false)
+
+	var matches []finderMatch
+	render := func(pattern string) {
+		matches = findInstances(ui.instancesView.instances, pattern)
+		results.Clear()
+		for i, match := range matches {
+			instance := match.instance
+			results.SetCell(i, 0, tview.NewTableCell(" "+getStateEmoji(instance.State)+" "+instance.ID+" ").SetTextColor(color.AppColors.Foreground))
+			results.SetCell(i, 1, tview.NewTableCell(" "+instance.Name+" ").SetTextColor(color.AppColors.Highlight).SetExpansion(1))
+			results.SetCell(i, 2, tview.NewTableCell(" "+instance.PrivateIP+" ").SetTextColor(color.AppColors.Secondary))
+			results.SetCell(i, 3, tview.NewTableCell(" "+instance.PublicIP+" ").SetTextColor(color.AppColors.Secondary))
+		}
+		results.Select(0, 0)
+		results.ScrollToBeginning()
+	}
+
+	jump := func() {
+		row, _ := results.GetSelection()
+		if row < 0 || row >= len(matches) {
+			return
+		}
+		ui.pages.RemovePage("modal")
+		ui.instancesView.SelectInstance(matches[row].instance.ID)
+	}
+
+	input.SetChangedFunc(render)
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := results.GetSelection()
+		switch event.Key() {
+		case tcell.KeyDown, tcell.KeyCtrlN:
+			if row+1 < len(matches) {
+				results.Select(row+1, 0)
+			}
+			return nil
+		case tcell.KeyUp, tcell.KeyCtrlP:
+			if row > 0 {
+				results.Select(row-1, 0)
+			}
+			return nil
+		case tcell.KeyEnter:
+			jump()
+			return nil
+		}
+		return event
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(results, finderResults, 0, false)
+	layout.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Find an instance (%d displayed) ", len(ui.instancesView.instances))).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, 90, 1, true).
+			AddItem(nil, 0, 1, false), finderResults+3, 1, true).
+		AddItem(nil, 0, 2, false)
+
+	render("")
+	ui.pages.AddPage("modal", flex, true, true)
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nlamirault/e2c/internal/featureflags"
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// flaggedActions are the actions of the instances view gated by a feature
+// flag, by name
+var flaggedActions = map[string]string{
+	"mark":             featureflags.BulkActions,
+	"mark-all":         featureflags.BulkActions,
+	"start-group":      featureflags.BulkActions,
+	"stop-environment": featureflags.BulkActions,
+	"group-actions":    featureflags.BulkActions,
+}
+
+// flagContext returns the context the feature flags are evaluated against:
+// the profile and the region in use, and the caller once it is known
+func (ui *UI) flagContext() featureflags.Context {
+	active := ui.active.Load()
+	ctx := featureflags.Context{
+		Profile: active.config.AWS.Profile,
+		Region:  active.client.GetRegion(),
+	}
+	if caller := ui.caller.Load(); caller != nil {
+		ctx.User = *caller
+	}
+	return ctx
+}
+
+// actionFlagged returns false if the feature flag gating an action of the
+// instances view, if any, is off
+func (ui *UI) actionFlagged(action string) bool {
+	name, ok := flaggedActions[action]
+	return !ok || ui.flags.Enabled(name, ui.flagContext(), true)
+}
+
+// checkFlag returns false, and displays an error, if the feature flag gating
+// an action is off
+func (ui *UI) checkFlag(action string) bool {
+	if err := ui.flagError(action); err != nil {
+		ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return false
+	}
+	return true
+}
+
+// flagError returns an error if the feature flag gating an action is off
+func (ui *UI) flagError(action string) error {
+	if ui.actionFlagged(action) {
+		return nil
+	}
+	return fmt.Errorf("%s is disabled by the feature flag %s", action, flaggedActions[action])
+}
+
+// setCaller records the ARN of the caller, and applies the feature flags
+// depending on it
+func (ui *UI) setCaller(arn string) {
+	ui.caller.Store(&arn)
+	ui.app.QueueUpdateDraw(ui.applyRefreshOverride)
+}
+
+// resolveCaller retrieves the caller of the client of the profile switched
+// to, for the feature flags
+func (ui *UI) resolveCaller(client *aws.EC2Client) {
+	identity, err := client.GetCallerIdentity(ui.ctx)
+	if err != nil {
+		ui.log.Warn("Failed to retrieve the caller for the feature flags", "error", err)
+		return
+	}
+	ui.setCaller(identity.ARN)
+}
+
+// refreshOverride returns the auto-refresh interval of the
+// refresh_interval_override flag, 0 if it is off or invalid
+func (ui *UI) refreshOverride() time.Duration {
+	value := ui.flags.Value(featureflags.RefreshIntervalOverride, ui.flagContext())
+	if value == "" {
+		return 0
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		ui.log.Warn("Invalid value of the feature flag", "flag", featureflags.RefreshIntervalOverride, "value", value, "error", err)
+		return 0
+	}
+	return interval
+}
+
+// applyRefreshOverride switches the auto-refresh to the interval of the
+// refresh_interval_override flag when it changes with the context, keeping
+// the interval changed with + and - otherwise
+func (ui *UI) applyRefreshOverride() {
+	interval := ui.refreshOverride()
+	if interval == ui.refreshFlag || ui.refreshTicker == nil {
+		return
+	}
+	ui.refreshFlag = interval
+	if interval > 0 && !ui.refreshPaused {
+		ui.log.Info("Auto-refresh interval set by the feature flag", "flag", featureflags.RefreshIntervalOverride, "interval", interval)
+		ui.setRefreshInterval(interval)
+	}
+}
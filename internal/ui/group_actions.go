@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// Actions applying to all the instances of a stack or an Auto Scaling group
+const (
+	groupActionStop    = "Stop all"
+	groupActionTag     = "Tag all"
+	groupActionProtect = "Protections on"
+)
+
+// groupKinds are the names of the groupings whose groups can be acted on
+var groupKinds = map[string]string{
+	groupByStack: "stack",
+	groupByASG:   "ASG",
+}
+
+// ShowGroupActions displays the actions applying to all the displayed
+// instances of the stack or the Auto Scaling group whose header is selected
+func (ui *UI) ShowGroupActions() {
+	groupBy := ui.nav.StateOf(viewInstances).GroupBy
+	kind, ok := groupKinds[groupBy]
+	if !ok {
+		ui.statusBar.SetError("Group the instances by stack or asg (g or :group) to act on a group")
+		return
+	}
+	group, ok := ui.instancesView.selectedGroup()
+	if !ok {
+		ui.statusBar.SetError(fmt.Sprintf("Select the header of a %s to act on its instances", kind))
+		return
+	}
+	if group == "" {
+		ui.statusBar.SetError(fmt.Sprintf("The instances of %s are not in a %s", noGroup, kind))
+		return
+	}
+	members := ui.instancesView.groupMembers(group)
+
+	// Editing the tags and the protections requires the admin level
+	buttons := []string{groupActionStop}
+	if ui.config().Allows(config.LevelAdmin) {
+		buttons = append(buttons, groupActionTag, groupActionProtect)
+	}
+
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("%s %s: %d instances", kind, group, len(members))).
+		AddButtons(append(buttons, "Cancel")).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ui.pages.RemovePage("modal")
+			scope := groupScope{groupBy: groupBy, kind: kind, name: group}
+			switch buttonLabel {
+			case groupActionStop:
+				ui.planGroupStop(scope, members)
+			case groupActionTag:
+				ui.showGroupTagForm(scope, members)
+			case groupActionProtect:
+				ui.ShowBatchPlan(ui.groupProtectAction(scope), members)
+			}
+		})
+	modal.SetBorder(true).SetTitle("Group Actions").SetBorderColor(tcell.ColorBlue)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(modal, 70, 1, true).
+			AddItem(nil, 0, 1, false), 0, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// groupScope is the stack or the Auto Scaling group a batch action applies
+// to
+type groupScope struct {
+	groupBy string // stack or asg
+	kind    string // Displayed name of the grouping
+	name    string
+}
+
+// action returns the name of a batch action on the group, e.g. Stop stack web
+func (s groupScope) action(verb string) string {
+	return fmt.Sprintf("%s %s %s", verb, s.kind, s.name)
+}
+
+// audit returns the parameters recording the group in the audit log
+func (s groupScope) audit() map[string]string {
+	return map[string]string{s.groupBy: s.name}
+}
+
+// planGroupStop retrieves the stop protection of the running instances of a
+// group, and shows the plan stopping them
+func (ui *UI) planGroupStop(scope groupScope, members []model.Instance) {
+	ui.statusBar.SetStatus(fmt.Sprintf("Planning the stop of %s %s...", scope.kind, scope.name))
+	go func() {
+		protected := ui.stopProtections(members)
+		ui.app.QueueUpdateDraw(func() {
+			action := ui.stopAction()
+			action.name = scope.action("Stop")
+			action.audit = scope.audit()
+			action.check = skipProtected(action.check, protected)
+			ui.ShowBatchPlan(action, members)
+		})
+	}()
+}
+
+// showGroupTagForm asks for the tag to set on all the instances of a group,
+// at the admin level only as the tag editor
+func (ui *UI) showGroupTagForm(scope groupScope, members []model.Instance) {
+	if !ui.checkLevel("editing the tags", config.LevelAdmin) {
+		return
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("Key:", "", 40, nil, nil)
+	form.AddInputField("Value:", "", 40, nil, nil)
+	form.AddButton("Plan", func() {
+		key := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		value := form.GetFormItem(1).(*tview.InputField).GetText()
+		if key == "" {
+			ui.statusBar.SetError("Error: no tag key given")
+			return
+		}
+		ui.pages.RemovePage("modal")
+		ui.ShowBatchPlan(ui.groupTagAction(scope, key, value), members)
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	form.SetBorder(true).SetTitle(scope.action("Tag"))
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 70, 1, true).
+			AddItem(nil, 0, 1, false), 9, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// groupTagAction returns the batch action setting a tag on the instances of
+// a group, skipping those already tagged
+func (ui *UI) groupTagAction(scope groupScope, key, value string) batchAction {
+	return batchAction{
+		name:   scope.action("Tag"),
+		change: fmt.Sprintf("tag %s=%s", key, value),
+		audit:  scope.audit(),
+		check: func(instance model.Instance) string {
+			if current, ok := instance.Tags[key]; ok && current == value {
+				return "already tagged"
+			}
+			return ""
+		},
+		run: func(ctx context.Context, id string) error {
+			return ui.clientForID(id).SetTags(ctx, id, nil, map[string]string{key: value})
+		},
+	}
+}
+
+// groupProtectAction returns the batch action enabling the stop and the
+// termination protections of the instances of a group
+func (ui *UI) groupProtectAction(scope groupScope) batchAction {
+	return batchAction{
+		name:   scope.action("Protect"),
+		change: "stop and termination protections on",
+		audit:  scope.audit(),
+		check: func(instance model.Instance) string {
+			if instance.State == "terminated" || instance.State == "shutting-down" {
+				return "terminated"
+			}
+			return ""
+		},
+		run: func(ctx context.Context, id string) error {
+			client := ui.clientForID(id)
+			for _, protection := range []string{model.ProtectionStop, model.ProtectionTermination} {
+				if err := client.SetProtection(ctx, id, protection, true); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// skipProtected returns the check of a batch action also skipping the
+// instances whose protection blocks it, with the reason
+func skipProtected(check func(instance model.Instance) string, protected map[string]string) func(instance model.Instance) string {
+	return func(instance model.Instance) string {
+		if reason := check(instance); reason != "" {
+			return reason
+		}
+		return protected[instance.ID]
+	}
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	tcell "github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// Groupings of the instances table, in addition to tag:<key>
+const (
+	groupByState = "state"
+	groupByZone  = "zone"
+	groupByType  = "type"
+	groupByStack = "stack" // CloudFormation stack
+	groupByASG   = "asg"   // Auto Scaling group
+)
+
+// noGroup is the label of the group of the instances without a value, e.g.
+// without the tag the instances are grouped by
+const noGroup = "(none)"
+
+// tableRow is a row of the instances table below the headers: an instance,
+// or the header of a group
+type tableRow struct {
+	instance int    // Index of the instance in the displayed instances, -1 for a group header
+	group    string // Group of the row, empty if the instances are not grouped
+}
+
+// parseGroupBy validates a grouping: state, zone, type, stack, asg,
+// tag:<key>, or none which is returned as an empty grouping
+func parseGroupBy(value string) (string, error) {
+	switch lower := strings.ToLower(value); lower {
+	case "", "none":
+		return "", nil
+	case groupByState, groupByZone, groupByType, groupByStack, groupByASG:
+		return lower, nil
+	}
+	if key, ok := strings.CutPrefix(value, "tag:"); ok && key != "" {
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown grouping %q, expected state, zone, type, stack, asg, tag:<key> or none", value)
+}
+
+// groupOf returns the group of an instance, empty if it has no value
+func groupOf(instance model.Instance, groupBy string) string {
+	switch groupBy {
+	case groupByState:
+		return instance.State
+	case groupByZone:
+		return instance.AvailabilityZone
+	case groupByType:
+		return instance.Type
+	case groupByStack:
+		return instance.CloudFormationStack()
+	case groupByASG:
+		return instance.AutoScalingGroup()
+	}
+	if key, ok := strings.CutPrefix(groupBy, "tag:"); ok {
+		return instance.Tags[key]
+	}
+	return ""
+}
+
+// groupInstances orders the instances by group, keeping their order within
+// each group. The groups are sorted by name, the one without a value last.
+func groupInstances(instances []model.Instance, groupBy string) []model.Instance {
+	if groupBy == "" {
+		return instances
+	}
+
+	grouped := make([]model.Instance, len(instances))
+	copy(grouped, instances)
+	sort.SliceStable(grouped, func(i, j int) bool {
+		a, b := groupOf(grouped[i], groupBy), groupOf(grouped[j], groupBy)
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		return a < b
+	})
+	return grouped
+}
+
+// groupCells returns the cells of the header of a group, with its number of
+// instances and whether it is collapsed
+func (v *InstancesView) groupCells(group string, count int) []cellSpec {
+	marker := "▾"
+	if v.collapsed[group] {
+		marker = "▸"
+	}
+	cells := make([]cellSpec, len(v.headers))
+	for i := range cells {
+		cells[i] = cellSpec{text: "", color: v.headerColor, attrs: tcell.AttrBold, align: tview.AlignLeft}
+	}
+	cells[0].text = fmt.Sprintf("%s %s (%d) ", marker, valueOrDefault(group, noGroup), count)
+	return cells
+}
+
+// groupMembers returns the displayed instances of a group
+func (v *InstancesView) groupMembers(group string) []model.Instance {
+	var members []model.Instance
+	for _, instance := range v.instances {
+		if groupOf(instance, v.state().GroupBy) == group {
+			members = append(members, instance)
+		}
+	}
+	return members
+}
+
+// ToggleGroup collapses or expands a group of instances
+func (v *InstancesView) ToggleGroup(group string) {
+	if v.collapsed[group] {
+		delete(v.collapsed, group)
+	} else {
+		v.collapsed[group] = true
+	}
+	v.redraw()
+}
+
+// SetGroupBy groups the instances of the table, expanding all the groups
+func (v *InstancesView) SetGroupBy(groupBy string) {
+	v.state().GroupBy = groupBy
+	v.collapsed = make(map[string]bool)
+	v.redraw()
+}
+
+// groupings returns the groupings cycled through by the group key: none,
+// state, zone, type, stack, Auto Scaling group, then the tag columns
+func (v *InstancesView) groupings() []string {
+	groupings := []string{"", groupByState, groupByZone, groupByType, groupByStack, groupByASG}
+	for _, key := range v.tagColumns {
+		groupings = append(groupings, "tag:"+key)
+	}
+	return groupings
+}
+
+// CycleGroupBy groups the instances by the next grouping, going back to the
+// ungrouped table after the last one
+func (v *InstancesView) CycleGroupBy() {
+	groupings := v.groupings()
+	next := 0
+	for i, groupBy := range groupings {
+		if groupBy == v.state().GroupBy {
+			next = (i + 1) % len(groupings)
+			break
+		}
+	}
+	v.SetGroupBy(groupings[next])
+	v.ui.statusBar.SetStatus("Group by: " + valueOrDefault(groupings[next], "none"))
+}
+
+// runGroupCommand runs the group command, showing or changing the grouping
+// of the instances table
+func (ui *UI) runGroupCommand(args []string) error {
+	switch len(args) {
+	case 0:
+		ui.statusBar.SetStatus("Group by: " + valueOrDefault(ui.nav.StateOf(viewInstances).GroupBy, "none"))
+		return nil
+	case 1:
+	default:
+		return errors.New("at most one grouping expected")
+	}
+
+	groupBy, err := parseGroupBy(args[0])
+	if err != nil {
+		return err
+	}
+	ui.instancesView.SetGroupBy(groupBy)
+	ui.statusBar.SetStatus("Group by: " + valueOrDefault(groupBy, "none"))
+	return nil
+}
@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/store"
+)
+
+// healthInterval is the interval between two retrievals of the AWS Health events
+const healthInterval = 5 * time.Minute
+
+// HealthView represents the notifications panel listing the AWS Health
+// events affecting EC2 in the region
+type HealthView struct {
+	ui          *UI
+	table       *tview.Table
+	description *tview.TextView
+	events      []model.HealthEvent
+}
+
+// NewHealthView creates a new notifications panel
+func NewHealthView(ui *UI) *HealthView {
+	v := &HealthView{
+		ui:          ui,
+		table:       tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		description: tview.NewTextView().SetDynamicColors(true).SetWrap(true).SetScrollable(true),
+	}
+
+	v.table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" AWS Health (%s) ", ui.ec2Client().GetRegion())).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	v.description.SetBorder(true).
+		SetTitle(" Description ").
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+	v.description.SetText(" [gray]Enter: show the description of the selected event[-]")
+
+	v.table.SetSelectedFunc(func(row, column int) {
+		if row > 0 && row-1 < len(v.events) {
+			v.showDescription(v.events[row-1])
+		}
+	})
+
+	return v
+}
+
+// Show displays the notifications panel and retrieves the events again
+func (v *HealthView) Show() {
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(v.table, 0, 1, true).
+		AddItem(v.description, 10, 0, false)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, 110, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	v.ui.pages.AddPage("modal", flex, true, true)
+
+	v.render(v.ui.store.Snapshot().Health)
+
+	// Render the events each time they are loaded, until the panel is closed
+	var unsubscribe func()
+	unsubscribe = v.ui.store.Subscribe(func(state *store.State, action store.Action) {
+		switch action.(type) {
+		case store.HealthEventsLoaded, store.SessionStarted:
+		default:
+			return
+		}
+		v.ui.app.QueueUpdateDraw(func() {
+			if _, front := v.ui.pages.GetFrontPage(); front != flex {
+				unsubscribe()
+				return
+			}
+			v.render(state.Health)
+		})
+	})
+
+	go v.ui.refreshHealth()
+}
+
+// render fills the table with the events, the open issues first
+func (v *HealthView) render(events []model.HealthEvent) {
+	v.events = events
+	v.table.Clear()
+
+	for i, header := range []string{"Status", "Type", "Region", "AZ", "Start", "Last Update"} {
+		v.table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	if len(events) == 0 {
+		v.table.SetCell(1, 0, tview.NewTableCell(" No open AWS Health event for EC2").SetSelectable(false))
+		return
+	}
+
+	for i, event := range events {
+		row := i + 1
+		statusColor := color.AppColors.Pending
+		if event.IsIssue() {
+			statusColor = color.AppColors.Error
+		}
+		v.table.SetCell(row, 0, tview.NewTableCell(" "+event.Status+" ").SetTextColor(statusColor).SetAttributes(tcell.AttrBold))
+		v.table.SetCell(row, 1, tview.NewTableCell(" "+event.TypeCode+" ").SetTextColor(color.AppColors.Foreground))
+		v.table.SetCell(row, 2, tview.NewTableCell(" "+event.Region+" ").SetTextColor(color.AppColors.Foreground))
+		v.table.SetCell(row, 3, tview.NewTableCell(" "+event.AvailabilityZone+" ").SetTextColor(color.AppColors.Foreground))
+		v.table.SetCell(row, 4, tview.NewTableCell(" "+v.ui.formatTime(event.StartTime)+" ").SetTextColor(color.AppColors.Foreground))
+		v.table.SetCell(row, 5, tview.NewTableCell(" "+v.ui.formatTime(event.LastUpdated)+" ").SetTextColor(color.AppColors.Foreground).SetExpansion(1))
+	}
+}
+
+// showDescription retrieves and displays the description of an event
+func (v *HealthView) showDescription(event model.HealthEvent) {
+	v.description.SetText(" [gray]Loading...[-]")
+
+	go func() {
+		description, err := v.ui.ec2Client().GetHealthEventDescription(v.ui.ctx, event.ARN)
+		v.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				v.ui.log.Error("Failed to get health event description", "arn", event.ARN, "error", err)
+				v.description.SetText(fmt.Sprintf(" [red]%s[-]", tview.Escape(err.Error())))
+				return
+			}
+			v.description.SetText(tview.Escape(description)).ScrollToBeginning()
+		})
+	}()
+}
+
+// refreshHealth retrieves the AWS Health events of the region and dispatches
+// them to the store. Without a support plan giving access to the AWS Health
+// API, the retrieval is disabled.
+func (ui *UI) refreshHealth() {
+	active := ui.active.Load()
+	events, err := active.client.ListHealthEvents(active.ctx, []string{active.client.GetRegion()})
+	if err != nil {
+		if active.ctx.Err() != nil {
+			// Another session is displayed, whose events are retrieved
+			// on the switch
+			return
+		}
+		if aws.IsHealthUnavailable(err) {
+			ui.disableFeature(featureHealth, "not available with the support plan of the account")
+			return
+		}
+		if ui.checkFeatureError(featureHealth, err) {
+			return
+		}
+		ui.log.Error("Failed to list health events", "error", err)
+		return
+	}
+
+	ui.store.Dispatch(store.HealthEventsLoaded{Events: events, Session: active.id})
+}
+
+// pollHealth retrieves the AWS Health events periodically, until the UI is
+// stopped or the AWS Health API is found unavailable
+func (ui *UI) pollHealth() {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	for {
+		ui.refreshHealth()
+		if !ui.featureEnabled(featureHealth) {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ui.ctx.Done():
+			return
+		}
+	}
+}
@@ -5,30 +5,56 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/rivo/tview"
 
 	"github.com/nlamirault/e2c/internal/color"
 )
 
+// helpEntries are the keys displayed in the help bar, with the action they
+// run
+var helpEntries = []struct {
+	key, label, action string
+}{
+	{"?", "Help", "help"},
+	{"q", "Quit", "quit"},
+	{"r", "Refresh", "refresh"},
+	{"f", "Filter", "filter"},
+	{"s", "Start", "start"},
+	{"p", "Stop", "stop"},
+	{"b", "Reboot", "reboot"},
+	{"t", "Terminate", "terminate"},
+	{"c", "Connect", "connect"},
+	{"l", "Logs", "logs"},
+	{"o", "Sort", "sort"},
+}
+
 // HelpView represents the help bar at the bottom of the UI
 type HelpView struct {
 	view *tview.TextView
 }
 
-// NewHelpView creates a new help view
-func NewHelpView() *HelpView {
+// NewHelpView creates a new help view, listing the keys of the actions
+// granted by the capability level of the user
+func NewHelpView(allows func(level string) bool) *HelpView {
 	view := tview.NewTextView().
 		SetDynamicColors(true).
 		SetTextAlign(tview.AlignCenter)
 
 	// Set background color from theme
 	view.SetBackgroundColor(color.AppColors.HeaderBg)
+	view.SetTextColor(color.AppColors.HeaderFg)
 
 	// Update help text
-	helpText := "[yellow]?[white]:Help  [yellow]q[white]:Quit  [yellow]r[white]:Refresh  [yellow]f[white]:Filter  [yellow]s[white]:Start  [yellow]p[white]:Stop  [yellow]b[white]:Reboot  [yellow]t[white]:Terminate  [yellow]c[white]:Connect  [yellow]l[white]:Logs"
+	entries := make([]string, 0, len(helpEntries))
+	for _, entry := range helpEntries {
+		if allows(actionLevel(entry.action)) {
+			entries = append(entries, fmt.Sprintf("[yellow]%s[-]:%s", entry.key, entry.label))
+		}
+	}
 
-	view.SetText(helpText)
+	view.SetText(strings.Join(entries, "  "))
 
 	return &HelpView{
 		view: view,
@@ -42,6 +68,12 @@ func (h *HelpView) SetText(text string) {
 	h.view.SetBackgroundColor(color.AppColors.HeaderBg)
 }
 
+// UpdateTheme applies the colors of the theme to the help bar
+func (h *HelpView) UpdateTheme() {
+	h.view.SetBackgroundColor(color.AppColors.HeaderBg)
+	h.view.SetTextColor(color.AppColors.HeaderFg)
+}
+
 // Clear clears the help text
 func (h *HelpView) Clear() {
 	h.view.SetText("")
@@ -51,7 +83,7 @@ func (h *HelpView) Clear() {
 func (h *HelpView) Update(context string) {
 	// Use standard color names for simplicity
 	highlightColor := "yellow"
-	textColor := "white"
+	textColor := "-"
 
 	switch context {
 	case "main":
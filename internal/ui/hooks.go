@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// runHooks runs in order the hooks of an event after an action succeeded on
+// an instance, and records their output in the audit log. It blocks until
+// the hooks are done and must not be called from the UI goroutine. The
+// hooks get the profile and the region of the account of the instance.
+func (ui *UI) runHooks(event string, instance model.Instance) {
+	ui.runHooksWith(ui.clientFor(instance), event, instance)
+}
+
+// runHooksWith runs the hooks of an event with the profile and the region
+// of the client of an instance which may not be displayed
+func (ui *UI) runHooksWith(client *aws.EC2Client, event string, instance model.Instance) {
+	for _, hook := range ui.hooks {
+		if !hook.Handles(event) {
+			continue
+		}
+
+		output, err := hook.Run(ui.ctx, event, client.GetRegion(), client.GetProfile(), instance)
+		client.RecordHook(ui.ctx, instance.ID, map[string]string{
+			"hook":  hook.Name(),
+			"event": event,
+		}, output, err)
+		if err != nil {
+			ui.log.Error("Failed to run hook", "hook", hook.Name(), "instanceID", instance.ID, "error", err, "output", output)
+			ui.app.QueueUpdateDraw(func() {
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+			})
+		}
+	}
+}
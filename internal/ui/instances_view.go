@@ -5,33 +5,43 @@ package ui
 
 import (
 	"fmt"
-	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	tcell "github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
 	"github.com/nlamirault/e2c/internal/color"
-	"github.com/nlamirault/e2c/internal/model"
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/plugin"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/store"
 )
 
-// InstancesView represents the instances table view
+// InstancesView represents the instances table view. It is only accessed
+// from the UI goroutine, the shared data is read from the store.
 type InstancesView struct {
 	ui           *UI
 	table        *tview.Table
 	instances    []model.Instance
-	instancesM   sync.Mutex
-	selected     int
 	headers      []string
+	cells        [][]cellSpec    // Cells displayed in the table, headers included
+	rows         []tableRow      // Rows displayed below the headers
+	collapsed    map[string]bool // Groups whose instances are hidden
+	tagColumns   []string
+	plugins      []*plugin.Column
+	images       bool                        // The AMIs of the instances are displayed, with their names
+	protections  map[string]model.Protection // Protections scanned so far, nil below the admin level
+	accounts     bool                        // The instances of several accounts are listed
+	marked       map[string]bool             // IDs of the instances marked for batch actions
+	focus        string                      // ID of the instance to select once loaded
 	headerColor  tcell.Color
 	textColor    tcell.Color
 	tagColor     tcell.Color
 	runningColor tcell.Color
 	stoppedColor tcell.Color
 	pendingColor tcell.Color
-	// Theme support will be added in future versions
 }
 
 // NewInstancesView creates a new instances view
@@ -40,8 +50,12 @@ func NewInstancesView(ui *UI) *InstancesView {
 		ui:           ui,
 		table:        tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
 		instances:    make([]model.Instance, 0),
-		selected:     0,
-		headers:      []string{"ID", "Name", "State", "Type", "Region", "Private IP", "Public IP", "Age"},
+		tagColumns:   ui.config().UI.TagColumns,
+		plugins:      ui.plugins,
+		images:       ui.config().UI.ImageColumn,
+		accounts:     len(ui.config().Accounts) > 0,
+		marked:       make(map[string]bool),
+		collapsed:    make(map[string]bool),
 		headerColor:  color.AppColors.Title,
 		textColor:    color.AppColors.Foreground,
 		tagColor:     color.AppColors.Secondary,
@@ -50,6 +64,11 @@ func NewInstancesView(ui *UI) *InstancesView {
 		pendingColor: color.AppColors.Pending,
 	}
 
+	if ui.config().Allows(config.LevelAdmin) {
+		v.protections = map[string]model.Protection{}
+	}
+	v.setupHeaders()
+
 	// Set up table
 	v.table.SetBorder(true).
 		SetTitle("EC2 Instances").
@@ -58,8 +77,39 @@ func NewInstancesView(ui *UI) *InstancesView {
 
 	// Set up cell selection handler
 	v.table.SetSelectedFunc(func(row, column int) {
-		if row > 0 && row-1 < len(v.instances) {
-			v.ShowInstanceDetails(v.instances[row-1])
+		if row <= 0 || row-1 >= len(v.rows) {
+			return
+		}
+		if r := v.rows[row-1]; r.instance < 0 {
+			v.ToggleGroup(r.group)
+		} else {
+			v.ShowInstanceDetails(v.instances[r.instance])
+		}
+	})
+
+	// Keep track of the selected row in the view state
+	v.table.SetSelectionChangedFunc(func(row, column int) {
+		if row > 0 {
+			v.state().Selected = row - 1
+		}
+	})
+
+	// Render the instances when they are loaded, the badges when the
+	// scheduled events change, and the protections as they are scanned
+	ui.store.Subscribe(func(state *store.State, action store.Action) {
+		switch action.(type) {
+		case store.InstancesLoaded, store.EventsLoaded:
+			ui.app.QueueUpdateDraw(func() {
+				v.render(state)
+			})
+		case store.ProtectionLoaded, store.ProtectionsLoaded, store.ProtectionInvalidated, store.ProtectionsCleared:
+			if v.protections == nil {
+				return
+			}
+			ui.app.QueueUpdateDraw(func() {
+				v.protections = state.Protections
+				v.redraw()
+			})
 		}
 	})
 
@@ -67,232 +117,480 @@ func NewInstancesView(ui *UI) *InstancesView {
 	return v
 }
 
-// UpdateInstances updates the instances table with new data
-func (v *InstancesView) UpdateInstances(instances []model.Instance) {
-	v.instancesM.Lock()
-	defer v.instancesM.Unlock()
-
-	v.instances = instances
+// UpdateTheme applies the colors of the theme to the table, and renders all
+// its cells again
+func (v *InstancesView) UpdateTheme() {
+	v.headerColor = color.AppColors.Title
+	v.textColor = color.AppColors.Foreground
+	v.tagColor = color.AppColors.Secondary
+	v.runningColor = color.AppColors.Running
+	v.stoppedColor = color.AppColors.Stopped
+	v.pendingColor = color.AppColors.Pending
+
+	v.table.SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title).
+		SetBackgroundColor(color.AppColors.Background)
+
+	// The header background is only set when a cell is created
 	v.table.Clear()
+	v.cells = nil
+	v.UpdateInstances(v.instances)
+}
 
-	// Add headers
-	// Set headers
-	for i, header := range v.headers {
-		v.table.SetCell(0, i,
-			tview.NewTableCell(" "+header+" ").
-				SetTextColor(v.headerColor).
-				SetSelectable(false).
-				SetAlign(tview.AlignCenter).
-				SetAttributes(tcell.AttrBold).
-				SetBackgroundColor(color.AppColors.HeaderBg))
+// render displays the instances of the state matching the text filter
+func (v *InstancesView) render(state *store.State) {
+	// Once the instances are displayed, keep them until all the pages of a
+	// refresh are loaded rather than shrinking the table to the first page
+	if state.Loading && v.ui.loaded {
+		v.ui.statusBar.SetStatus(fmt.Sprintf("Refreshing instances... %d so far", len(state.Instances)))
+		return
 	}
 
-	// Add instances
-	for i, instance := range instances {
-		row := i + 1
-		stateColor := getStateColor(instance.State)
-
-		// Set ID
-		v.table.SetCell(row, 0,
-			tview.NewTableCell(" "+instance.ID+" ").
-				SetTextColor(v.textColor).
-				SetAlign(tview.AlignLeft))
-
-		// Set Name
-		v.table.SetCell(row, 1,
-			tview.NewTableCell(" "+instance.Name+" ").
-				SetTextColor(v.textColor).
-				SetAlign(tview.AlignLeft))
-
-		// Set State with color and emoji before state name
-		v.table.SetCell(row, 2,
-			tview.NewTableCell(" "+getStateEmoji(instance.State)+" "+instance.State+" ").
-				SetTextColor(stateColor).
-				SetAlign(tview.AlignLeft))
-
-		// Set Type
-		v.table.SetCell(row, 3,
-			tview.NewTableCell(" "+instance.Type+" ").
-				SetTextColor(v.textColor).
-				SetAlign(tview.AlignLeft))
-
-		// Set Region
-		v.table.SetCell(row, 4,
-			tview.NewTableCell(" "+instance.Region+" ").
-				SetTextColor(v.textColor).
-				SetAlign(tview.AlignLeft))
-
-		// Set Private IP
-		v.table.SetCell(row, 5,
-			tview.NewTableCell(" "+instance.PrivateIP+" ").
-				SetTextColor(v.textColor).
-				SetAlign(tview.AlignLeft))
-
-		// Set Public IP
-		v.table.SetCell(row, 6,
-			tview.NewTableCell(" "+instance.PublicIP+" ").
-				SetTextColor(v.textColor).
-				SetAlign(tview.AlignLeft))
-
-		// Set Age
-		v.table.SetCell(row, 7,
-			tview.NewTableCell(" "+formatDuration(instance.Age)+" ").
-				SetTextColor(v.textColor).
-				SetAlign(tview.AlignRight))
-	}
-
-	// Restore selection if possible
-	if v.selected < len(instances) {
-		v.table.Select(v.selected+1, 0)
-	} else if len(instances) > 0 {
-		v.table.Select(1, 0)
-		v.selected = 0
+	if v.protections != nil {
+		v.protections = state.Protections
 	}
-}
 
-// GetSelectedInstance returns the currently selected instance
-func (v *InstancesView) GetSelectedInstance() *model.Instance {
-	v.instancesM.Lock()
-	defer v.instancesM.Unlock()
+	instances := v.ui.applyFilters(state.Instances)
+	v.UpdateInstances(instances)
 
-	row, _ := v.table.GetSelection()
-	if row <= 0 || row-1 >= len(v.instances) {
-		return nil
+	if state.Loading {
+		v.ui.statusBar.SetStatus(fmt.Sprintf("Loading instances... %d so far", len(state.Instances)))
+	} else {
+		v.ui.statusBar.SetStatus(fmt.Sprintf("Found %d instances", len(instances)))
 	}
+}
 
-	v.selected = row - 1
+// skeletonRows is the number of placeholder rows displayed while the first
+// page of instances is loading
+const skeletonRows = 8
+
+// ShowSkeleton fills the table with placeholder rows until the first page of
+// instances is loaded
+func (v *InstancesView) ShowSkeleton() {
+	rows := make([][]cellSpec, 0, skeletonRows+1)
+	header := make([]cellSpec, len(v.headers))
+	for i, name := range v.headers {
+		header[i] = cellSpec{text: " " + name + " ", color: v.headerColor, attrs: tcell.AttrBold, align: tview.AlignCenter, header: true}
+	}
+	rows = append(rows, header)
+
+	for r := range skeletonRows {
+		row := make([]cellSpec, len(v.headers))
+		for c, name := range v.headers {
+			// Vary the widths so that the placeholders look like data
+			width := max(len(name), 4) + (r*3+c*5)%7
+			row[c] = cellSpec{text: " " + strings.Repeat("░", width) + " ", color: v.tagColor, align: tview.AlignLeft}
+		}
+		rows = append(rows, row)
+	}
 
-	// Highlight the selected row is handled by tview automatically
+	v.applyCells(rows)
+}
 
-	return &v.instances[v.selected]
+// cellSpec is the content of a cell of the table. The specs of two refreshes
+// are compared to update only the cells which changed.
+type cellSpec struct {
+	text   string
+	color  tcell.Color
+	attrs  tcell.AttrMask
+	align  int
+	header bool
 }
 
-// ShowInstanceDetails displays a detailed view of an instance
-func (v *InstancesView) ShowInstanceDetails(instance model.Instance) {
-	detailsText := tview.NewTextView().
-		SetDynamicColors(true).
-		SetTextAlign(tview.AlignLeft).
-		SetScrollable(true).
-		SetWrap(true)
-
-	// Format instance details
-	baseDetails := fmt.Sprintf(`
-[::b][yellow]Instance Details[white][::-]
-  [blue]ID:[white]            %s
-  [blue]Name:[white]          %s
-  [blue]Type:[white]          %s
-  [blue]State:[white]         %s %s
-  [blue]Region:[white]        %s
-  [blue]Launch Time:[white]   %s
-  [blue]Age:[white]           %s
-  [blue]Private IP:[white]    %s
-  [blue]Public IP:[white]     %s
-  [blue]Platform:[white]      %s
-  [blue]Architecture:[white]  %s
-`,
-		instance.ID,
-		instance.Name,
-		instance.Type,
-		getStateEmoji(instance.State), instance.State,
-		instance.Region,
-		instance.LaunchTime.Format("2006-01-02 15:04:05"),
-		formatDuration(instance.Age),
-		instance.PrivateIP,
-		instance.PublicIP,
-		instance.Platform,
-		instance.Architecture,
-	)
+// UpdateInstances updates the instances table with new data. Only the cells
+// which changed are updated, and the selected instance and the scroll
+// position are preserved, so that a refresh does not make the table flicker.
+func (v *InstancesView) UpdateInstances(instances []model.Instance) {
+	state := v.state()
+	instances = v.sortInstances(instances, state.SortColumn, state.SortDesc)
+	instances = groupInstances(instances, state.GroupBy)
+
+	// Remember the selected instance to select it again at its new row
+	var selectedID string
+	if selected := v.GetSelectedInstance(); selected != nil {
+		selectedID = selected.ID
+	}
+	if v.focus != "" && len(instances) > 0 {
+		selectedID, v.focus = v.focus, ""
+	}
+	rowOffset, columnOffset := v.table.GetOffset()
 
-	// Format tags section with a more prominent header
-	tagsSection := "\n[::b][yellow]AWS Tags[white][::-]\n"
-	if len(instance.Tags) > 0 {
-		// Sort tags by key for consistent display
-		keys := make([]string, 0, len(instance.Tags))
-		for k := range instance.Tags {
-			keys = append(keys, k)
+	v.instances = instances
+
+	// Headers
+	rows := make([][]cellSpec, 0, len(instances)+1)
+	header := make([]cellSpec, len(v.headers))
+	for i, name := range v.headers {
+		if i == state.SortColumn {
+			if state.SortDesc {
+				name += " ▼"
+			} else {
+				name += " ▲"
+			}
 		}
-		sort.Strings(keys)
-
-		// Group tags by category for better organization
-		categories := map[string]map[string]string{
-			"Resource":  {}, // Resource-related tags (Name, stack info)
-			"Business":  {}, // Business context tags (env, project, owner)
-			"Technical": {}, // Technical tags (role, version, tier)
-			"Other":     {}, // Any other tags that don't fit above categories
+		header[i] = cellSpec{
+			text:   " " + name + " ",
+			color:  v.headerColor,
+			attrs:  tcell.AttrBold,
+			align:  tview.AlignCenter,
+			header: true,
 		}
+	}
+	rows = append(rows, header)
 
-		for _, key := range keys {
-			value := instance.Tags[key]
-
-			// Categorize tags
-			switch strings.ToLower(key) {
-			case "name", "aws:cloudformation:stack-name", "aws:cloudformation:logical-id", "aws:autoscaling:groupname":
-				categories["Resource"][key] = value
-			case "environment", "env", "project", "owner", "team", "cost-center", "application", "app", "service", "product", "costcenter", "business-unit":
-				categories["Business"][key] = value
-			case "role", "version", "tier", "type", "platform", "auto-delete", "auto-stop", "backup", "cluster", "scheduler":
-				categories["Technical"][key] = value
-			default:
-				categories["Other"][key] = value
-			}
+	// Instances, below the header of their group if they are grouped
+	counts := make(map[string]int)
+	for _, instance := range instances {
+		counts[groupOf(instance, state.GroupBy)]++
+	}
+	events := v.ui.store.Snapshot().Events
+	v.rows = v.rows[:0]
+	for i, instance := range instances {
+		group := groupOf(instance, state.GroupBy)
+		if state.GroupBy != "" && (i == 0 || groupOf(instances[i-1], state.GroupBy) != group) {
+			rows = append(rows, v.groupCells(group, counts[group]))
+			v.rows = append(v.rows, tableRow{instance: -1, group: group})
+		}
+		if v.collapsed[group] {
+			continue
 		}
+		rows = append(rows, v.instanceCells(instance, len(events[instance.ID]) > 0))
+		v.rows = append(v.rows, tableRow{instance: i, group: group})
+	}
 
-		// Display tags by category
-		for category, tagMap := range categories {
-			if len(tagMap) == 0 {
-				continue
-			}
+	v.applyCells(rows)
 
-			tagsSection += fmt.Sprintf("  [::b][yellow]%s Tags[white][::-]\n", category)
+	// Select the same instance, or the same row if it is not displayed anymore
+	selected := -1
+	for i, row := range v.rows {
+		if selectedID != "" && row.instance >= 0 && instances[row.instance].ID == selectedID {
+			selected = i
+			break
+		}
+	}
+	if selected < 0 {
+		selected = state.Selected
+	}
+	if selected >= len(v.rows) {
+		selected = len(v.rows) - 1
+	}
+	if selected >= 0 {
+		v.table.Select(selected+1, 0)
+		state.Selected = selected
+	}
+	v.table.SetOffset(rowOffset, columnOffset)
+}
 
-			// Sort keys within category
-			catKeys := make([]string, 0, len(tagMap))
-			for k := range tagMap {
-				catKeys = append(catKeys, k)
-			}
-			sort.Strings(catKeys)
+// instanceCells returns the cells of the row of an instance
+func (v *InstancesView) instanceCells(instance model.Instance, hasEvents bool) []cellSpec {
+	text := func(value string) cellSpec {
+		return cellSpec{text: " " + value + " ", color: v.textColor, align: tview.AlignLeft}
+	}
+
+	cells := make([]cellSpec, 0, len(v.headers))
+
+	// ID, with a marker if the instance is marked
+	if v.marked[instance.ID] {
+		cells = append(cells, cellSpec{
+			text:  "✓" + instance.ID + " ",
+			color: color.AppColors.Highlight,
+			attrs: tcell.AttrBold,
+			align: tview.AlignLeft,
+		})
+	} else {
+		cells = append(cells, text(instance.ID))
+	}
+
+	cells = append(cells, text(instance.Name))
 
-			// Calculate the longest key for alignment
-			longestKey := 0
-			for _, key := range catKeys {
-				if len(key) > longestKey {
-					longestKey = len(key)
+	// State with color and emoji before state name, and a warning badge if
+	// AWS scheduled an event on the instance
+	stateText := " " + getStateEmoji(instance.State) + " " + instance.State + " "
+	if hasEvents {
+		stateText += "⚠ "
+	}
+	cells = append(cells, cellSpec{text: stateText, color: getStateColor(instance.State), align: tview.AlignLeft})
+
+	cells = append(cells,
+		text(instance.Type),
+		text(instance.Region),
+		text(instance.AvailabilityZone),
+		text(instance.PrivateIP),
+		text(instance.PublicIP),
+		cellSpec{text: " " + formatDuration(instance.Age) + " ", color: v.textColor, align: tview.AlignRight},
+	)
+
+	// Tag columns, then plugin columns
+	for _, key := range v.tagColumns {
+		cells = append(cells, cellSpec{text: " " + instance.Tags[key] + " ", color: v.tagColor, align: tview.AlignLeft})
+	}
+	for _, column := range v.plugins {
+		cells = append(cells, cellSpec{text: " " + column.Value(instance.ID) + " ", color: v.tagColor, align: tview.AlignLeft})
+	}
+	if v.images {
+		cells = append(cells, cellSpec{text: " " + v.imageName(instance) + " ", color: v.tagColor, align: tview.AlignLeft})
+	}
+	if v.protections != nil {
+		if protection, ok := v.protections[instance.ID]; ok {
+			cells = append(cells, text(protection.String()))
+		} else {
+			cells = append(cells, cellSpec{text: " unknown ", color: tcell.ColorGray, align: tview.AlignLeft})
+		}
+	}
+	if v.accounts {
+		cells = append(cells, text(instance.Account))
+	}
+
+	return cells
+}
+
+// applyCells updates the table with the given cells, only modifying the
+// cells which differ from the ones displayed and removing the extra rows
+func (v *InstancesView) applyCells(rows [][]cellSpec) {
+	for r, row := range rows {
+		for c, spec := range row {
+			if r < len(v.cells) && c < len(v.cells[r]) {
+				if v.cells[r][c] == spec {
+					continue
 				}
+				v.table.GetCell(r, c).
+					SetText(spec.text).
+					SetTextColor(spec.color).
+					SetAttributes(spec.attrs).
+					SetAlign(spec.align)
+				continue
 			}
 
-			for _, key := range catKeys {
-				// Add padding for alignment
-				padding := strings.Repeat(" ", longestKey-len(key))
-				tagsSection += fmt.Sprintf("    [blue]%s%s:[white] %s\n", key, padding, tagMap[key])
+			cell := tview.NewTableCell(spec.text).
+				SetTextColor(spec.color).
+				SetAttributes(spec.attrs).
+				SetAlign(spec.align)
+			if spec.header {
+				cell.SetSelectable(false).
+					SetBackgroundColor(color.AppColors.HeaderBg)
 			}
+			v.table.SetCell(r, c, cell)
+		}
+	}
+
+	for r := len(v.cells) - 1; r >= len(rows); r-- {
+		v.table.RemoveRow(r)
+	}
+
+	v.cells = rows
+}
 
-			// Add a blank line between categories
-			tagsSection += "\n"
+// ToggleMark marks or unmarks the selected instance for batch actions, or
+// all the instances of the selected group
+func (v *InstancesView) ToggleMark() {
+	var instances []model.Instance
+	if instance := v.GetSelectedInstance(); instance != nil {
+		instances = []model.Instance{*instance}
+	} else if group, ok := v.selectedGroup(); ok {
+		instances = v.groupMembers(group)
+	}
+	if len(instances) == 0 {
+		return
+	}
+
+	// Unmark the instances if they are all marked already
+	allMarked := true
+	for _, instance := range instances {
+		allMarked = allMarked && v.marked[instance.ID]
+	}
+	for _, instance := range instances {
+		if allMarked {
+			delete(v.marked, instance.ID)
+		} else {
+			v.marked[instance.ID] = true
 		}
+	}
+
+	// Move to the next row to mark several instances in a row
+	row, _ := v.table.GetSelection()
+	v.redraw()
+	if row+1 < v.table.GetRowCount() {
+		v.table.Select(row+1, 0)
+	}
+}
+
+// ToggleMarkAll marks all the displayed instances, or clears the marks if
+// they are all marked already
+func (v *InstancesView) ToggleMarkAll() {
+	allMarked := len(v.instances) > 0
+	for _, instance := range v.instances {
+		if !v.marked[instance.ID] {
+			allMarked = false
+			break
+		}
+	}
+	if allMarked {
+		v.marked = make(map[string]bool)
 	} else {
-		tagsSection += "  No tags found on this instance\n"
+		for _, instance := range v.instances {
+			v.marked[instance.ID] = true
+		}
 	}
 
-	// Combine all sections
-	details := baseDetails + tagsSection + "\n[yellow]Press Esc to close[-]"
+	v.redraw()
+}
 
-	detailsText.SetText(details)
-	detailsText.SetBorder(true).
-		SetTitle(fmt.Sprintf(" Instance: %s ", instance.DisplayName())).
-		SetBorderColor(color.AppColors.Border).
-		SetTitleColor(color.AppColors.Title)
+// ClearMarks removes all the marks
+func (v *InstancesView) ClearMarks() {
+	v.marked = make(map[string]bool)
+	v.redraw()
+}
+
+// GetMarkedInstances returns the marked instances which are displayed
+func (v *InstancesView) GetMarkedInstances() []model.Instance {
+	marked := make([]model.Instance, 0, len(v.marked))
+	for _, instance := range v.instances {
+		if v.marked[instance.ID] {
+			marked = append(marked, instance)
+		}
+	}
+	return marked
+}
+
+// imageName returns the name of the AMI of an instance, its ID until the
+// name is resolved, redrawing the table once it is
+func (v *InstancesView) imageName(instance model.Instance) string {
+	name, ok := v.ui.names().lookup(v, v.redraw, v.ui.clientFor(instance), aws.ImageResource, instance.ImageID)
+	if !ok || name.err != nil || name.name == "" {
+		return instance.ImageID
+	}
+	if !name.found {
+		return instance.ImageID + " (deregistered)"
+	}
+	return name.name
+}
+
+// setupHeaders sets the headers of the table: the default columns, the tag
+// columns, then the plugin columns, the AMI if configured, the protections
+// scanned at the admin level, and the account when several are listed
+func (v *InstancesView) setupHeaders() {
+	v.headers = []string{"ID", "Name", "State", "Type", "Region", "Zone", "Private IP", "Public IP", "Age"}
+	v.headers = append(v.headers, v.tagColumns...)
+	for _, column := range v.plugins {
+		v.headers = append(v.headers, column.Name())
+	}
+	if v.images {
+		v.headers = append(v.headers, "AMI")
+	}
+	if v.protections != nil {
+		v.headers = append(v.headers, "Protection")
+	}
+	if v.accounts {
+		v.headers = append(v.headers, "Account")
+	}
+}
+
+// SetTagColumns replaces the tag columns of the table, keeping the sort on
+// the same column if it is still displayed
+func (v *InstancesView) SetTagColumns(keys []string) {
+	state := v.state()
+	sorted := ""
+	if state.SortColumn >= 0 && state.SortColumn < len(v.headers) {
+		sorted = v.headers[state.SortColumn]
+	}
+
+	v.tagColumns = keys
+	v.setupHeaders()
+
+	// Render all the cells again, the columns displayed after fewer tag
+	// columns would otherwise keep the text of the previous ones
+	v.table.Clear()
+	v.cells = nil
+
+	state.SortColumn = -1
+	for i, header := range v.headers {
+		if header == sorted {
+			state.SortColumn = i
+			break
+		}
+	}
+	v.redraw()
+}
+
+// state returns the session state of the instances view
+func (v *InstancesView) state() *ViewState {
+	return v.ui.nav.StateOf(viewInstances)
+}
+
+// redraw renders the current instances again, applying the view state
+func (v *InstancesView) redraw() {
+	instances := make([]model.Instance, len(v.instances))
+	copy(instances, v.instances)
+
+	v.UpdateInstances(instances)
+}
+
+// VisibleIDs returns the IDs of the instances of the rows visible in the table
+func (v *InstancesView) VisibleIDs() []string {
+	offset, _ := v.table.GetOffset()
+	_, _, _, height := v.table.GetInnerRect()
+
+	var ids []string
+	for i := offset; i < offset+height-1 && i < len(v.rows); i++ {
+		if v.rows[i].instance >= 0 {
+			ids = append(ids, v.instances[v.rows[i].instance].ID)
+		}
+	}
+	return ids
+}
+
+// GetSelectedInstance returns the currently selected instance, nil if none
+// or if the header of a group is selected
+func (v *InstancesView) GetSelectedInstance() *model.Instance {
+	row, _ := v.table.GetSelection()
+	if row <= 0 || row-1 >= len(v.rows) {
+		return nil
+	}
+
+	v.state().Selected = row - 1
 
-		// Create a modal that fills most of the screen
-		// Make the detail view wider to accommodate tags better
-	flex := tview.NewFlex().
-		AddItem(nil, 0, 1, false).
-		AddItem(tview.NewFlex().
-			AddItem(nil, 0, 1, false).
-			AddItem(detailsText, 80, 1, true).
-			AddItem(nil, 0, 1, false), 0, 8, true).
-		AddItem(nil, 0, 1, false)
-
-	v.ui.pages.AddPage("modal", flex, true, true)
+	// Highlight the selected row is handled by tview automatically
+
+	if index := v.rows[row-1].instance; index >= 0 {
+		return &v.instances[index]
+	}
+	return nil
+}
+
+// SelectInstance selects the row of a displayed instance, expanding its
+// group if it is collapsed
+func (v *InstancesView) SelectInstance(id string) {
+	for _, instance := range v.instances {
+		if instance.ID != id {
+			continue
+		}
+		if group := groupOf(instance, v.state().GroupBy); v.collapsed[group] {
+			delete(v.collapsed, group)
+			v.redraw()
+		}
+		break
+	}
+
+	for i, row := range v.rows {
+		if row.instance >= 0 && v.instances[row.instance].ID == id {
+			v.table.Select(i+1, 0)
+			return
+		}
+	}
+}
+
+// selectedGroup returns the group whose header is selected, if any
+func (v *InstancesView) selectedGroup() (string, bool) {
+	row, _ := v.table.GetSelection()
+	if row <= 0 || row-1 >= len(v.rows) || v.rows[row-1].instance >= 0 {
+		return "", false
+	}
+	return v.rows[row-1].group, true
+}
+
+// ShowInstanceDetails displays a detailed view of an instance
+func (v *InstancesView) ShowInstanceDetails(instance model.Instance) {
+	NewDetailView(v.ui, instance).Show()
 }
 
 // getStateEmoji returns an emoji representing the instance state
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/keymap"
+)
+
+// mainActions are the actions of the instances view which can be bound to a
+// key in the keymap, by name
+var mainActions = map[string]func(ui *UI){
+	"help":             (*UI).ShowHelpDialog,
+	"quit":             (*UI).Stop,
+	"refresh":          (*UI).RefreshInstances,
+	"filter":           (*UI).ShowFilterDialog,
+	"find":             (*UI).ShowFinder,
+	"search":           (*UI).ShowSearch,
+	"start":            (*UI).handleStartInstance,
+	"stop":             (*UI).handleStopInstance,
+	"reboot":           (*UI).handleRebootInstance,
+	"terminate":        (*UI).handleTerminateInstance,
+	"connect":          (*UI).handleConnectInstance,
+	"logs":             (*UI).handleViewLogs,
+	"sort":             func(ui *UI) { ui.instancesView.CycleSortColumn() },
+	"sort-order":       func(ui *UI) { ui.instancesView.ToggleSortOrder() },
+	"group":            func(ui *UI) { ui.instancesView.CycleGroupBy() },
+	"mark":             func(ui *UI) { ui.instancesView.ToggleMark() },
+	"mark-all":         func(ui *UI) { ui.instancesView.ToggleMarkAll() },
+	"cancel-batch":     (*UI).cancelBatch,
+	"vpcs":             func(ui *UI) { NewVPCView(ui, "").Show() },
+	"start-group":      (*UI).handleStartGroup,
+	"stop-environment": (*UI).ShowStopEnvironmentDialog,
+	"restore-snapshot": (*UI).handleRestoreSnapshot,
+	"health":           func(ui *UI) { NewHealthView(ui).Show() },
+	"refresh-slower":   func(ui *UI) { ui.stepRefreshInterval(1) },
+	"refresh-faster":   func(ui *UI) { ui.stepRefreshInterval(-1) },
+	"refresh-pause":    (*UI).toggleRefresh,
+	"command":          (*UI).ShowCommandPrompt,
+	"latency":          (*UI).toggleLatencyOverlay,
+	"export":           (*UI).ShowExportDialog,
+	"manifest":         (*UI).handleViewManifest,
+	"reachability":     (*UI).ShowReachabilityDialog,
+	"run-command":      (*UI).handleRunCommand,
+	"port-forward":     (*UI).ShowPortForwardDialog,
+	"sessions":         (*UI).handleSessions,
+	"errors":           func(ui *UI) { ui.ShowMessagesView(true) },
+	"group-actions":    (*UI).ShowGroupActions,
+	"authorize-key":    (*UI).handleAuthorizeKey,
+	"watch":            (*UI).toggleWatch,
+	"unlock-filter":    (*UI).unlockFilter,
+}
+
+// loadKeymap loads the keymap file configured in ui.keymap_file, falling
+// back to the default keymap if it is invalid
+func (ui *UI) loadKeymap() {
+	keys, err := keymap.Load(ui.config().UI.KeymapFile)
+	if err != nil {
+		ui.log.Error("Failed to load the keymap, using the default one", "error", err)
+		ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		keys = keymap.Default()
+	}
+	ui.keymap = keys
+}
+
+// runKeyAction runs the action bound to the key of an event in the
+// instances view, and returns false if none is bound to it. The action is
+// traced, from the keypress until it is rendered.
+func (ui *UI) runKeyAction(event *tcell.EventKey) bool {
+	action := ui.keymap.Action(keyName(event))
+	run, ok := mainActions[action]
+	if !ok {
+		return false
+	}
+	if !ui.checkFlag(action) || !ui.checkLevel(action, actionLevel(action)) {
+		return true
+	}
+	// Keep the last action displayed in the latency overlay
+	if action == "latency" {
+		run(ui)
+		return true
+	}
+	ui.traceAction("key "+action, map[string]string{"e2c.key": keyName(event)}, func() {
+		run(ui)
+	})
+	return true
+}
+
+// keyName returns the name of the key of an event as written in the keymap:
+// a character, space, or ctrl-a to ctrl-z
+func keyName(event *tcell.EventKey) string {
+	switch key := event.Key(); {
+	case key == tcell.KeyRune && event.Rune() == ' ':
+		return "space"
+	case key == tcell.KeyRune:
+		return string(event.Rune())
+	case key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ:
+		return fmt.Sprintf("ctrl-%c", 'a'+rune(key-tcell.KeyCtrlA))
+	default:
+		return ""
+	}
+}
+
+// runKeysCommand runs the keys command, listing the key bindings
+func (ui *UI) runKeysCommand(args []string) error {
+	if len(args) != 0 {
+		return errors.New("no argument expected")
+	}
+	ui.ShowKeysView()
+	return nil
+}
+
+// ShowKeysView displays every key binding of the instances view with its
+// action, the capability level it requires, in red if the level of the user
+// does not grant it, and whether it is the default one or set in the keymap
+// file
+func (ui *UI) ShowKeysView() {
+	table := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	table.SetBorder(true).
+		SetTitle(" Key Bindings ").
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	for i, header := range []string{"Key", "Action", "Level", "Description", "Source"} {
+		table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	for i, binding := range ui.keymap.Bindings() {
+		row := i + 1
+		sourceColor := color.AppColors.Foreground
+		if binding.Source == keymap.SourceUser {
+			sourceColor = color.AppColors.Running
+		}
+		table.SetCell(row, 0, tview.NewTableCell(" "+keymap.Display(binding.Key)+" ").SetTextColor(color.AppColors.Running).SetAttributes(tcell.AttrBold))
+		table.SetCell(row, 1, tview.NewTableCell(" "+binding.Action+" ").SetTextColor(color.AppColors.Foreground))
+		level := actionLevel(binding.Action)
+		levelColor := color.AppColors.Foreground
+		if !ui.config().Allows(level) {
+			levelColor = color.AppColors.Stopped
+		}
+		table.SetCell(row, 2, tview.NewTableCell(" "+level+" ").SetTextColor(levelColor))
+		table.SetCell(row, 3, tview.NewTableCell(" "+binding.Description+" ").SetTextColor(color.AppColors.Foreground).SetExpansion(1))
+		table.SetCell(row, 4, tview.NewTableCell(" "+binding.Source+" ").SetTextColor(sourceColor))
+	}
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(table, 120, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/go-logr/logr"
+	"github.com/rivo/tview"
+	"go.opentelemetry.io/otel"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/trace"
+	"github.com/nlamirault/e2c/internal/version"
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// maxOverlayCalls is the number of calls to AWS listed in the latency
+// overlay
+const maxOverlayCalls = 5
+
+// NewExporter creates the exporter of the traces of the user actions and of
+// the metrics of the calls to AWS and of the refreshes, to the collector or
+// to a file with the file exporter, nil if the telemetry is disabled or the
+// exporter cannot be created
+func NewExporter(log *slog.Logger, cfg config.TelemetryConfig) *trace.Exporter {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	// The SDK reports its errors on stderr by default, over the UI
+	otel.SetLogger(logr.FromSlogHandler(log.Handler()))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Error("Failed to export the telemetry", "error", err)
+	}))
+
+	var exporter *trace.Exporter
+	var err error
+	switch cfg.Exporter {
+	case "file":
+		path := cfg.File
+		if path == "" {
+			path = defaultTelemetryFile()
+		}
+		log.Info("Writing the traces of the user actions and the metrics", "file", path, "metricsInterval", cfg.MetricsInterval)
+		exporter, err = trace.NewFileExporter(log, path, "e2c", version.GetVersion(), cfg.MetricsInterval)
+	default:
+		if cfg.Exporter != "otlp" && cfg.Exporter != "" {
+			log.Warn("Unknown telemetry exporter, falling back to otlp", "exporter", cfg.Exporter)
+		}
+		log.Info("Exporting the traces of the user actions and the metrics", "endpoint", cfg.Endpoint, "metricsInterval", cfg.MetricsInterval)
+		exporter, err = trace.NewExporter(log, cfg.Endpoint, "e2c", version.GetVersion(), cfg.MetricsInterval)
+	}
+	if err != nil {
+		log.Error("Failed to create the telemetry exporter, telemetry disabled", "error", err)
+		return nil
+	}
+	return exporter
+}
+
+// Telemetry returns the telemetry of the EC2 clients of the UI, recording
+// their calls in the traces of the user actions, and in the metrics of the
+// exporter if not nil
+func Telemetry(exporter *trace.Exporter) aws.Telemetry {
+	telemetry := aws.Telemetry{Tracer: trace.CallTracer{}}
+	if metrics := exporter.Metrics(); metrics != nil {
+		telemetry.Metrics = metrics
+	}
+	return telemetry
+}
+
+// telemetry returns the telemetry of the EC2 clients created by the UI
+func (ui *UI) telemetry() aws.Telemetry {
+	return Telemetry(ui.exporter)
+}
+
+// newTracer creates the tracer of the user actions, exporting the traces
+// with the exporter of the UI, if any
+func newTracer(ui *UI) *trace.Tracer {
+	tracer := trace.NewTracer(ui.log, ui.exporter)
+
+	// Time the renderings, and draw the overlay and the notifications on
+	// top of the UI
+	ui.app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		tracer.BeforeDraw()
+		return false
+	})
+	ui.app.SetAfterDrawFunc(func(screen tcell.Screen) {
+		tracer.AfterDraw()
+		if ui.latencyOverlay.Load() {
+			ui.drawLatencyOverlay(screen)
+		}
+		ui.drawNotification(screen)
+	})
+	return tracer
+}
+
+// defaultTelemetryFile returns the default file of the file exporter,
+// ~/.config/e2c/telemetry.jsonl
+func defaultTelemetryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "telemetry.jsonl"
+	}
+	return filepath.Join(home, ".config", "e2c", "telemetry.jsonl")
+}
+
+// shutdownTelemetry sends the last traces and metrics
+func (ui *UI) shutdownTelemetry(ctx context.Context) {
+	ui.tracer.Shutdown(ctx)
+}
+
+// traceAction runs the handler of a user action, e.g. a keypress, tracing
+// the action until it is rendered
+func (ui *UI) traceAction(name string, attributes map[string]string, handle func()) {
+	span := ui.tracer.StartAction(name).StartSpan("handle", trace.KindInternal, attributes)
+	defer span.End(nil)
+	handle()
+}
+
+// actionCtx returns the context of the calls to AWS made for the user action
+// being traced, if any
+func (ui *UI) actionCtx() context.Context {
+	return trace.WithAction(ui.ctx, ui.tracer.Current())
+}
+
+// sessionCtx returns the context of the calls to AWS made for the user
+// action being traced, if any, to retrieve the data of a session: it is
+// cancelled once another session is displayed
+func (ui *UI) sessionCtx(active *activeSession) context.Context {
+	return trace.WithAction(active.ctx, ui.tracer.Current())
+}
+
+// operationCtx returns the context of the calls to AWS made for an
+// operation: the one of the user action being traced, if any, or of a trace
+// of the operation in the background, ended by the returned function
+func (ui *UI) operationCtx(name string, attributes map[string]string) (context.Context, func(error)) {
+	if action := ui.tracer.Current(); action != nil {
+		return trace.WithAction(ui.ctx, action), func(error) {}
+	}
+	action := ui.tracer.StartBackground(name, attributes)
+	return trace.WithAction(ui.ctx, action), action.End
+}
+
+// toggleLatencyOverlay shows or hides the latency breakdown of the last
+// action
+func (ui *UI) toggleLatencyOverlay() {
+	ui.latencyOverlay.Store(!ui.latencyOverlay.Load())
+}
+
+// drawLatencyOverlay draws the latency breakdown of the last action in the
+// top right corner of the screen
+func (ui *UI) drawLatencyOverlay(screen tcell.Screen) {
+	summary, ok := ui.tracer.Last()
+	if !ok {
+		return
+	}
+
+	status := "done"
+	if summary.Open {
+		status = "running"
+	}
+	lines := []string{
+		fmt.Sprintf("[::b]%s[::-] (%s)", tview.Escape(summary.Name), status),
+		fmt.Sprintf("total   %8s", formatLatency(summary.Total)),
+		fmt.Sprintf("handler %8s", formatLatency(summary.Handle)),
+		fmt.Sprintf("aws     %8s", formatLatency(summary.API)),
+		fmt.Sprintf("render  %8s", formatLatency(summary.Render)),
+		fmt.Sprintf("other   %8s", formatLatency(summary.Other())),
+	}
+	for i, call := range summary.Calls {
+		if i == maxOverlayCalls {
+			lines = append(lines, fmt.Sprintf("  ... %d more calls", len(summary.Calls)-i))
+			break
+		}
+		duration := "..."
+		if !call.EndTime.IsZero() {
+			duration = formatLatency(call.Duration())
+		}
+		line := fmt.Sprintf("  %-24s %8s", call.Name, duration)
+		if call.Err != "" {
+			line = "[red]" + line + "[-]"
+		}
+		lines = append(lines, line)
+	}
+
+	const width = 40
+	screenWidth, screenHeight := screen.Size()
+	x := screenWidth - width - 1
+	if x < 0 || len(lines)+2 > screenHeight {
+		return
+	}
+	style := tcell.StyleDefault.Background(color.AppColors.HeaderBg).Foreground(color.AppColors.Foreground)
+	for row := 0; row < len(lines)+2; row++ {
+		for col := 0; col < width; col++ {
+			screen.SetContent(x+col, 1+row, ' ', nil, style)
+		}
+	}
+	tview.Print(screen, " Latency ", x, 1, width, tview.AlignCenter, color.AppColors.Title)
+	for i, line := range lines {
+		tview.Print(screen, line, x+1, 2+i, width-2, tview.AlignLeft, color.AppColors.Foreground)
+	}
+}
+
+// formatLatency formats a latency with a millisecond precision
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return fmt.Sprintf("%dµs", d.Microseconds())
+	}
+	return d.Round(time.Millisecond).String()
+}
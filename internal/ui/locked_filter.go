@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// SetLockedFilter scopes the instances to a filter expression given with
+// --filter, e.g. for a launcher of the instances of a team: the filter of the
+// UI applies within it, until it is cleared with the unlock-filter action
+func (ui *UI) SetLockedFilter(expr string) {
+	ui.lockedFilter = expr
+	ui.statusBar.SetLockedFilter(expr, ui.keymap.Key("unlock-filter"))
+}
+
+// unlockFilter clears the locked filter, listing all the instances matching
+// the filter of the UI
+func (ui *UI) unlockFilter() {
+	if ui.lockedFilter == "" {
+		ui.statusBar.SetStatus("No locked filter")
+		return
+	}
+	ui.log.Info("Clearing the locked filter", "filter", ui.lockedFilter)
+	ui.SetLockedFilter("")
+	ui.RefreshInstances()
+}
+
+// serverFilters returns the EC2 API filters of the instances listed: those
+// of the filter of the UI, within the locked filter
+func (ui *UI) serverFilters() map[string][]string {
+	filter := model.ParseFilter(ui.nav.StateOf(viewInstances).Filter)
+	return filter.ServerWithin(model.ParseFilter(ui.lockedFilter))
+}
+
+// applyFilters applies the client side part of the locked filter, then of
+// the filter of the UI, to instances
+func (ui *UI) applyFilters(instances []model.Instance) []model.Instance {
+	instances = ui.applyFilter(instances, model.ParseFilter(ui.lockedFilter))
+	return ui.applyFilter(instances, model.ParseFilter(ui.nav.StateOf(viewInstances).Filter))
+}
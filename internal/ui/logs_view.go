@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// logsBacklog is how far back the events are retrieved when the tail starts
+const logsBacklog = 10 * time.Minute
+
+// LogsView tails the CloudWatch Logs stream associated with an instance
+type LogsView struct {
+	ui       *UI
+	instance model.Instance
+	group    string
+	stream   string
+	view     *tview.TextView
+	layout   *tview.Flex
+	since    time.Time       // Timestamp of the last event displayed
+	seen     map[string]bool // IDs of the events displayed at the last timestamp
+	sinceM   sync.Mutex
+}
+
+// NewLogsView creates a new CloudWatch Logs view for an instance
+func NewLogsView(ui *UI, instance model.Instance, group, stream string) *LogsView {
+	v := &LogsView{
+		ui:       ui,
+		instance: instance,
+		group:    group,
+		stream:   stream,
+		view: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true),
+		since: time.Now().Add(-logsBacklog),
+		seen:  make(map[string]bool),
+	}
+
+	title := fmt.Sprintf(" CloudWatch Logs: %s ", group)
+	if stream != "" {
+		title = fmt.Sprintf(" CloudWatch Logs: %s / %s ", group, stream)
+	}
+	v.view.SetBorder(true).
+		SetTitle(title).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	v.layout = tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(v.view, 0, 8, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	return v
+}
+
+// Show displays the view and tails the stream until the view is closed
+func (v *LogsView) Show() {
+	v.ui.pages.AddPage("modal", v.layout, true, true)
+	v.ui.statusBar.SetStatus(fmt.Sprintf("Tailing CloudWatch Logs %s every %s", v.group, followInterval))
+
+	ctx, cancel := context.WithCancel(v.ui.ctx)
+	go v.tail(ctx, cancel)
+}
+
+// tail fetches the new events periodically until the view is closed
+func (v *LogsView) tail(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	for {
+		v.sinceM.Lock()
+		since := v.since
+		v.sinceM.Unlock()
+
+		events, err := v.ui.ec2Client().FilterLogEvents(ctx, v.group, v.stream, since)
+		if ctx.Err() != nil {
+			return
+		}
+		if v.ui.checkFeatureError(featureLogs, err) {
+			// Not allowed, tailing again would fail the same way
+			cancel()
+			return
+		}
+
+		v.ui.app.QueueUpdateDraw(func() {
+			// Stop tailing once the view was closed
+			if _, front := v.ui.pages.GetFrontPage(); front != v.layout {
+				cancel()
+				return
+			}
+
+			if err != nil {
+				v.ui.log.Error("Failed to tail CloudWatch Logs", "group", v.group, "stream", v.stream, "error", err)
+				v.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			v.append(events)
+		})
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// append displays the events which were not displayed yet
+func (v *LogsView) append(events []model.LogEvent) {
+	v.sinceM.Lock()
+	defer v.sinceM.Unlock()
+
+	var b strings.Builder
+	for _, event := range events {
+		if event.Timestamp.Before(v.since) || v.seen[event.ID] {
+			continue
+		}
+		if event.Timestamp.After(v.since) {
+			v.since = event.Timestamp
+			v.seen = make(map[string]bool)
+		}
+		v.seen[event.ID] = true
+
+		fmt.Fprintf(&b, "[gray]%s[-] ", v.ui.formatTime(event.Timestamp))
+		if v.stream == "" {
+			fmt.Fprintf(&b, "[blue]%s[-] ", tview.Escape(event.Stream))
+		}
+		b.WriteString(tview.Escape(event.Message))
+		b.WriteString("\n")
+	}
+
+	if b.Len() > 0 {
+		fmt.Fprint(v.view, b.String())
+		v.view.ScrollToEnd()
+	}
+}
+
+// logsTarget returns the CloudWatch Logs group and stream of an instance,
+// from its tags or the configured defaults
+func (ui *UI) logsTarget(instance model.Instance) (string, string, bool) {
+	cfg := ui.config().Logs
+
+	group := cfg.LogGroup
+	if value := instance.Tags[cfg.GroupTag]; cfg.GroupTag != "" && value != "" {
+		group = value
+	}
+	stream := cfg.LogStream
+	if value := instance.Tags[cfg.StreamTag]; cfg.StreamTag != "" && value != "" {
+		stream = value
+	}
+	if group == "" {
+		return "", "", false
+	}
+
+	replacer := strings.NewReplacer("{instance_id}", instance.ID, "{name}", instance.Name)
+	return replacer.Replace(group), replacer.Replace(stream), true
+}
+
+// showCloudWatchLogs tails the CloudWatch Logs stream of an instance
+func (ui *UI) showCloudWatchLogs(instance model.Instance) {
+	group, stream, ok := ui.logsTarget(instance)
+	if !ok {
+		ui.statusBar.SetError(fmt.Sprintf("No CloudWatch Logs group configured for %s (logs.log_group or tag %s)", instance.ID, ui.config().Logs.GroupTag))
+		return
+	}
+	if !ui.featureEnabled(featureLogs) {
+		ui.statusBar.SetError(fmt.Sprintf("Error: %s %v", featureLogs, errFeatureDisabled))
+		return
+	}
+	NewLogsView(ui, instance, group, stream).Show()
+}
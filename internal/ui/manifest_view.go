@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/desktop"
+	"github.com/nlamirault/e2c/internal/output"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// manifestLine splits a line of JSON or YAML in its indentation, key and
+// value, e.g. `  "InstanceId": "i-0123",` or `  - InstanceId: i-0123`
+var manifestLine = regexp.MustCompile(`^(\s*(?:- )?)(?:("[^"]*"|[A-Za-z0-9_.-]+)(:))?(\s*)(.*)$`)
+
+// ManifestView displays the raw description of an instance, as returned by
+// DescribeInstances, in JSON or YAML
+type ManifestView struct {
+	ui       *UI
+	instance model.Instance
+	view     *tview.TextView
+	layout   *tview.Flex
+	manifest map[string]any
+	format   string // output.FormatJSON or output.FormatYAML
+	text     string // Manifest encoded in the format, copied to the clipboard
+}
+
+// NewManifestView creates a new manifest view for an instance
+func NewManifestView(ui *UI, instance model.Instance) *ManifestView {
+	v := &ManifestView{
+		ui:       ui,
+		instance: instance,
+		format:   output.FormatJSON,
+		view: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true).
+			SetWrap(false),
+	}
+
+	v.view.SetBorder(true).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	v.view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case 'y':
+			v.copy()
+			return nil
+		case 'f':
+			if v.format == output.FormatJSON {
+				v.format = output.FormatYAML
+			} else {
+				v.format = output.FormatJSON
+			}
+			v.render()
+			return nil
+		}
+		return event
+	})
+
+	// Center the text view
+	v.layout = tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(v.view, 0, 8, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	return v
+}
+
+// Show fetches the manifest of the instance and displays it
+func (v *ManifestView) Show() {
+	v.ui.statusBar.SetStatus(fmt.Sprintf("Fetching manifest of instance %s...", v.instance.ID))
+
+	ctx := v.ui.actionCtx()
+	go func() {
+		manifest, err := v.ui.clientFor(v.instance).GetInstanceManifest(ctx, v.instance.ID)
+		v.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				v.ui.log.Error("Failed to get manifest", "instanceID", v.instance.ID, "error", err)
+				v.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+
+			v.manifest = manifest
+			v.render()
+			v.ui.statusBar.SetStatus("Showing manifest, y: copy to clipboard, f: switch JSON/YAML")
+			v.ui.pages.AddPage("modal", v.layout, true, true)
+		})
+	}()
+}
+
+// render encodes the manifest in the format and displays it highlighted
+func (v *ManifestView) render() {
+	text, err := encodeManifest(v.manifest, v.format)
+	if err != nil {
+		v.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	v.text = text
+
+	v.view.SetTitle(fmt.Sprintf(" Manifest: %s (%s) ", v.instance.DisplayName(), strings.ToUpper(v.format)))
+	v.view.SetText(highlightManifest(text)).ScrollToBeginning()
+}
+
+// copy copies the manifest, in the format displayed, to the clipboard
+func (v *ManifestView) copy() {
+	if err := desktop.CopyToClipboard(v.text); err != nil {
+		v.ui.log.Error("Failed to copy manifest", "instanceID", v.instance.ID, "error", err)
+		v.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	v.ui.statusBar.SetStatus(fmt.Sprintf("Copied the %s manifest of %s to clipboard", strings.ToUpper(v.format), v.instance.ID))
+}
+
+// encodeManifest encodes a manifest in JSON or YAML, indented
+func encodeManifest(manifest map[string]any, format string) (string, error) {
+	var b bytes.Buffer
+	switch format {
+	case output.FormatYAML:
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(manifest); err != nil {
+			return "", err
+		}
+		if err := encoder.Close(); err != nil {
+			return "", err
+		}
+	default:
+		encoder := json.NewEncoder(&b)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(manifest); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// highlightManifest colors the keys and the values of a JSON or YAML
+// manifest, line by line
+func highlightManifest(text string) string {
+	lines := splitLines(text)
+	for i, line := range lines {
+		match := manifestLine.FindStringSubmatch(line)
+		if match == nil {
+			lines[i] = tview.Escape(line)
+			continue
+		}
+		indent, key, colon, space, value := match[1], match[2], match[3], match[4], match[5]
+
+		var b strings.Builder
+		b.WriteString(indent)
+		if key != "" {
+			fmt.Fprintf(&b, "[blue]%s[-]%s", tview.Escape(key), colon)
+		}
+		b.WriteString(space)
+		b.WriteString(highlightValue(value))
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// highlightValue colors a scalar value by its type, leaving the brackets and
+// the trailing comma of JSON uncolored
+func highlightValue(value string) string {
+	scalar := strings.TrimSuffix(value, ",")
+	trailer := value[len(scalar):]
+
+	var valueColor string
+	switch {
+	case scalar == "" || scalar == "{" || scalar == "}" || scalar == "[" || scalar == "]" || scalar == "{}" || scalar == "[]":
+		return tview.Escape(value)
+	case strings.HasPrefix(scalar, `"`):
+		valueColor = "green"
+	case scalar == "true" || scalar == "false" || scalar == "null":
+		valueColor = "yellow"
+	case strings.IndexFunc(scalar, func(r rune) bool { return !strings.ContainsRune("0123456789.-+eE", r) }) < 0:
+		valueColor = "yellow"
+	default:
+		valueColor = "-"
+	}
+	return fmt.Sprintf("[%s]%s[-]%s", valueColor, tview.Escape(scalar), trailer)
+}
+
+// handleViewManifest shows the manifest of the selected instance
+func (ui *UI) handleViewManifest() {
+	selectedInstance := ui.instancesView.GetSelectedInstance()
+	if selectedInstance == nil {
+		ui.statusBar.SetError("No instance selected")
+		return
+	}
+
+	NewManifestView(ui, *selectedInstance).Show()
+}
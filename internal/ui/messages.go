@@ -0,0 +1,246 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/desktop"
+	"github.com/nlamirault/e2c/pkg/audit"
+)
+
+// auditWindow is the time around a message within which the audit entries
+// are displayed with it
+const auditWindow = time.Minute
+
+// statusMessage is a status or error message of the status bar, kept for
+// review
+type statusMessage struct {
+	time  time.Time // Time of the last occurrence
+	text  string
+	error bool
+	count int // Number of consecutive occurrences
+}
+
+// format returns the message with its time formatted with formatTime, e.g.
+// to copy it
+func (m statusMessage) format(formatTime func(time.Time) string) string {
+	level := "info"
+	if m.error {
+		level = "error"
+	}
+	text := fmt.Sprintf("%s %s %s", formatTime(m.time), level, m.text)
+	if m.count > 1 {
+		text += fmt.Sprintf(" (×%d)", m.count)
+	}
+	return text
+}
+
+// recordMessage keeps a message of the status bar for review, counting the
+// repetitions of the previous message rather than keeping them, and drops
+// the oldest messages beyond ui.message_history
+func (b *StatusBar) recordMessage(text string, isError bool) {
+	if isError {
+		b.unread++
+	}
+
+	if n := len(b.messages); n > 0 && b.messages[n-1].text == text && b.messages[n-1].error == isError {
+		b.messages[n-1].time = time.Now()
+		b.messages[n-1].count++
+		return
+	}
+	b.messages = append(b.messages, statusMessage{time: time.Now(), text: text, error: isError, count: 1})
+	if limit := b.ui.config().UI.MessageHistory; limit > 0 && len(b.messages) > limit {
+		b.messages = b.messages[len(b.messages)-limit:]
+	}
+}
+
+// ShowMessagesView displays the messages of the status bar, the most recent
+// first, or only the errors, marking the errors as read. The audit entries
+// recorded around the selected message are displayed below it.
+func (ui *UI) ShowMessagesView(errorsOnly bool) {
+	var messages []statusMessage
+	for i := len(ui.statusBar.messages) - 1; i >= 0; i-- {
+		if message := ui.statusBar.messages[i]; message.error || !errorsOnly {
+			messages = append(messages, message)
+		}
+	}
+
+	title := fmt.Sprintf(" Messages (%d) ", len(messages))
+	if errorsOnly {
+		title = fmt.Sprintf(" Errors (%d) ", len(messages))
+	}
+	table := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	table.SetBorder(true).
+		SetTitle(title).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	for i, header := range []string{"Time", "Message"} {
+		table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	if len(messages) == 0 {
+		table.SetCell(1, 0,
+			tview.NewTableCell(" No messages in this session ").
+				SetTextColor(color.AppColors.Secondary).
+				SetSelectable(false))
+	}
+	for i, message := range messages {
+		textColor := color.AppColors.Foreground
+		if message.error {
+			textColor = color.AppColors.Error
+		}
+		text := tview.Escape(message.text)
+		if message.count > 1 {
+			text += fmt.Sprintf(" (×%d)", message.count)
+		}
+		table.SetCell(i+1, 0, tview.NewTableCell(" "+ui.formatTime(message.time)+" ").SetTextColor(color.AppColors.Secondary))
+		table.SetCell(i+1, 1, tview.NewTableCell(" "+text+" ").SetTextColor(textColor).SetExpansion(1))
+	}
+
+	// The audit entries are read once, to follow the selection
+	var entries []audit.Entry
+	auditErr := errors.New("audit log disabled (audit.enabled)")
+	if log := ui.ec2Client().AuditLog(); log != nil {
+		entries, auditErr = audit.Read(log.Path())
+	}
+	auditView := tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+	auditView.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Audit log (±%s) ", formatInterval(auditWindow))).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	selected := func() (statusMessage, bool) {
+		row, _ := table.GetSelection()
+		if row <= 0 || row-1 >= len(messages) {
+			return statusMessage{}, false
+		}
+		return messages[row-1], true
+	}
+	table.SetSelectionChangedFunc(func(row, column int) {
+		message, ok := selected()
+		switch {
+		case !ok:
+			auditView.SetText("")
+		case auditErr != nil:
+			auditView.SetText(fmt.Sprintf(" [gray]%s[-]", tview.Escape(auditErr.Error())))
+		default:
+			auditView.SetText(renderAuditEntries(entries, message.time, ui.formatTime))
+		}
+	})
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case 'y':
+			if message, ok := selected(); ok {
+				ui.copyMessages([]statusMessage{message})
+			}
+			return nil
+		case 'Y':
+			ui.copyMessages(messages)
+			return nil
+		}
+		return event
+	})
+
+	ui.statusBar.unread = 0
+	ui.statusBar.update()
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(table, 0, 3, true).
+		AddItem(auditView, 8, 0, false)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, 120, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+	table.Select(1, 0)
+}
+
+// renderAuditEntries renders the audit entries recorded within auditWindow
+// of a time, the oldest first, their times formatted with formatTime
+func renderAuditEntries(entries []audit.Entry, at time.Time, formatTime func(time.Time) string) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.Time.Before(at.Add(-auditWindow)) || entry.Time.After(at.Add(auditWindow)) {
+			continue
+		}
+		resultColor := "green"
+		if entry.Result != audit.ResultSuccess {
+			resultColor = "red"
+		}
+		fmt.Fprintf(&b, " %s  %-28s %-20s [%s]%s[-]", formatTime(entry.Time), entry.Action, entry.Instance, resultColor, entry.Result)
+		if entry.Error != "" {
+			fmt.Fprintf(&b, "  [gray]%s[-]", tview.Escape(entry.Error))
+		}
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		return fmt.Sprintf(" [gray]No action recorded within %s of this message[-]", formatInterval(auditWindow))
+	}
+	return b.String()
+}
+
+// copyMessages copies messages to the clipboard, one per line, with their
+// time and level
+func (ui *UI) copyMessages(messages []statusMessage) {
+	lines := make([]string, 0, len(messages))
+	for _, message := range messages {
+		lines = append(lines, message.format(ui.formatTime))
+	}
+	if err := desktop.CopyToClipboard(strings.Join(lines, "\n")); err != nil {
+		ui.log.Error("Failed to copy messages", "error", err)
+		ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+	ui.statusBar.SetStatus(fmt.Sprintf("Copied %d messages to the clipboard", len(messages)))
+}
+
+// runMessagesCommand runs the messages command, showing the messages of the
+// session, or clearing them
+func (ui *UI) runMessagesCommand(args []string) error {
+	return ui.messagesCommand(args, false)
+}
+
+// runErrorsCommand runs the errors command, showing the errors of the
+// session, or clearing the messages
+func (ui *UI) runErrorsCommand(args []string) error {
+	return ui.messagesCommand(args, true)
+}
+
+// messagesCommand shows the messages, or only the errors, or clears them
+func (ui *UI) messagesCommand(args []string, errorsOnly bool) error {
+	switch {
+	case len(args) == 0:
+		ui.ShowMessagesView(errorsOnly)
+		return nil
+	case len(args) == 1 && args[0] == "clear":
+		ui.statusBar.messages = nil
+		ui.statusBar.unread = 0
+		ui.statusBar.update()
+		return nil
+	default:
+		return errors.New("usage: messages|errors [clear]")
+	}
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+)
+
+// errMFACancelled is returned when the MFA prompt is cancelled
+var errMFACancelled = errors.New("MFA code prompt cancelled")
+
+// mfaCode is the answer of the MFA prompt
+type mfaCode struct {
+	code string
+	err  error
+}
+
+// promptMFA asks for the code of an MFA device in a dialog, each time the
+// credentials of the assumed role are retrieved or refreshed. It is called
+// from the goroutine of an AWS call, and blocks until the code is entered or
+// the prompt cancelled.
+func (ui *UI) promptMFA(role, serial string) (string, error) {
+	answer := make(chan mfaCode, 1)
+
+	ui.app.QueueUpdateDraw(func() {
+		input := tview.NewInputField().
+			SetLabel("MFA code: ").
+			SetFieldWidth(8).
+			SetFieldBackgroundColor(color.AppColors.HeaderBg).
+			SetAcceptanceFunc(func(text string, last rune) bool {
+				return len(text) <= 6 && unicode.IsDigit(last)
+			})
+
+		input.SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter:
+				code := strings.TrimSpace(input.GetText())
+				if len(code) != 6 {
+					input.SetLabel("[red]MFA code: [-]")
+					return
+				}
+				answer <- mfaCode{code: code}
+			case tcell.KeyEscape:
+				answer <- mfaCode{err: errMFACancelled}
+			default:
+				return
+			}
+			ui.pages.RemovePage("mfa")
+		})
+
+		text := tview.NewTextView().
+			SetDynamicColors(true).
+			SetWrap(true).
+			SetText(fmt.Sprintf("Assuming the role [yellow]%s[-] requires the code of the MFA device\n[blue]%s[-]\n\n[yellow]Esc[-]: Cancel",
+				tview.Escape(role), tview.Escape(serial)))
+
+		layout := tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(text, 0, 1, false).
+			AddItem(input, 1, 0, true)
+		layout.SetBorder(true).
+			SetTitle(" MFA ").
+			SetBorderColor(color.AppColors.Border).
+			SetTitleColor(color.AppColors.Title)
+
+		flex := tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(layout, 8, 1, true).
+				AddItem(nil, 0, 1, false), 80, 1, true).
+			AddItem(nil, 0, 1, false)
+
+		ui.pages.AddPage("mfa", flex, true, true)
+		ui.app.SetFocus(input)
+	})
+
+	select {
+	case answer := <-answer:
+		return answer.code, answer.err
+	case <-ui.ctx.Done():
+		return "", ui.ctx.Err()
+	}
+}
@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// consoleOutput is the console output of one of the instances of a
+// MultiConsoleView
+type consoleOutput struct {
+	lines   []string
+	err     error
+	fetched bool
+}
+
+// MultiConsoleView displays the console outputs of several instances, e.g.
+// of an auto scaling group failing to boot, fetched concurrently. The first
+// tab combines them in one section per instance, the next ones show each
+// instance alone, and the grep box filters the lines of all of them.
+type MultiConsoleView struct {
+	ui        *UI
+	instances []model.Instance
+	outputs   map[string]*consoleOutput // By instance ID
+	tab       int                       // 0 for all the instances, i+1 for the instance i
+	pattern   string                    // Lines filter, case insensitive
+	tabs      *tview.TextView
+	view      *tview.TextView
+	grep      *tview.InputField
+	layout    *tview.Flex
+}
+
+// NewMultiConsoleView creates a view of the console outputs of instances
+func NewMultiConsoleView(ui *UI, instances []model.Instance) *MultiConsoleView {
+	v := &MultiConsoleView{
+		ui:        ui,
+		instances: instances,
+		outputs:   make(map[string]*consoleOutput, len(instances)),
+		tabs:      tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		view: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true),
+		grep: tview.NewInputField().
+			SetLabel("grep: ").
+			SetFieldBackgroundColor(color.AppColors.HeaderBg),
+	}
+	for _, instance := range instances {
+		v.outputs[instance.ID] = &consoleOutput{}
+	}
+
+	v.view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyTab:
+			v.selectTab(v.tab + 1)
+			return nil
+		case event.Key() == tcell.KeyBacktab:
+			v.selectTab(v.tab - 1)
+			return nil
+		case event.Key() == tcell.KeyRune && event.Rune() == '/':
+			v.ui.app.SetFocus(v.grep)
+			return nil
+		}
+		return event
+	})
+
+	v.grep.SetChangedFunc(func(text string) {
+		v.pattern = strings.TrimSpace(text)
+		v.render()
+	})
+	v.grep.SetDoneFunc(func(key tcell.Key) {
+		v.ui.app.SetFocus(v.view)
+	})
+
+	content := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(v.tabs, 1, 0, false).
+		AddItem(v.view, 0, 1, true).
+		AddItem(v.grep, 1, 0, false)
+	content.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Console Output: %d instances ", len(instances))).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	// Center the view
+	v.layout = tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(content, 0, 8, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	return v
+}
+
+// Show displays the view and fetches the console outputs concurrently, with
+// the concurrency, the rate and the retries of the batch actions
+func (v *MultiConsoleView) Show() {
+	v.render()
+	v.ui.pages.AddPage("modal", v.layout, true, true)
+	v.ui.statusBar.SetStatus("Showing console outputs, Tab: next instance, /: grep")
+
+	ids := make([]string, len(v.instances))
+	for i, instance := range v.instances {
+		ids[i] = instance.ID
+	}
+
+	ctx := v.ui.actionCtx()
+	var mu sync.Mutex
+	fetched := make(map[string]*consoleOutput, len(ids))
+	go func() {
+		results := v.ui.newBatchEngine().Run(ctx, ids, func(ctx context.Context, id string) error {
+			output, err := v.ui.clientForID(id).GetInstanceConsoleOutput(ctx, id, false)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			fetched[id] = &consoleOutput{lines: splitLines(output), fetched: true}
+			mu.Unlock()
+			return nil
+		}, func(done, failed, total int) {
+			mu.Lock()
+			outputs := make(map[string]*consoleOutput, len(fetched))
+			for id, output := range fetched {
+				outputs[id] = output
+			}
+			mu.Unlock()
+			v.ui.app.QueueUpdateDraw(func() {
+				v.ui.statusBar.SetProgress("console", done, failed, total)
+				for id, output := range outputs {
+					v.outputs[id] = output
+				}
+				v.render()
+			})
+		})
+
+		v.ui.app.QueueUpdateDraw(func() {
+			v.ui.statusBar.ClearProgress()
+			for _, result := range results {
+				if result.Err != nil {
+					v.ui.log.Error("Failed to get console output", "instanceID", result.ID, "error", result.Err)
+					v.outputs[result.ID] = &consoleOutput{err: result.Err, fetched: true}
+				}
+			}
+			v.render()
+		})
+	}()
+}
+
+// selectTab displays the tab of the given index, wrapping around
+func (v *MultiConsoleView) selectTab(tab int) {
+	count := len(v.instances) + 1
+	v.tab = (tab + count) % count
+	v.render()
+	v.view.ScrollToBeginning()
+}
+
+// render displays the tabs and the outputs of the selected tab, filtered
+func (v *MultiConsoleView) render() {
+	var tabs strings.Builder
+	names := append([]string{"All"}, make([]string, len(v.instances))...)
+	for i, instance := range v.instances {
+		names[i+1] = instance.DisplayName()
+		output := v.outputs[instance.ID]
+		switch {
+		case output.err != nil:
+			names[i+1] += " [red]![-]"
+		case !output.fetched:
+			names[i+1] += " [gray]...[-]"
+		case v.pattern != "":
+			names[i+1] += fmt.Sprintf(" (%d)", len(v.matches(output.lines)))
+		}
+	}
+	for i, name := range names {
+		if i == v.tab {
+			fmt.Fprintf(&tabs, " [::r] %s [::-]", name)
+		} else {
+			fmt.Fprintf(&tabs, "  %s ", name)
+		}
+	}
+	v.tabs.SetText(tabs.String())
+
+	instances := v.instances
+	if v.tab > 0 {
+		instances = v.instances[v.tab-1 : v.tab]
+	}
+
+	var b strings.Builder
+	for i, instance := range instances {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[yellow::b]=== %s (%s) ===[-::-]\n", tview.Escape(instance.DisplayName()), instance.ID)
+
+		output := v.outputs[instance.ID]
+		switch {
+		case output.err != nil:
+			fmt.Fprintf(&b, "[red]%s[-]\n", tview.Escape(output.err.Error()))
+		case !output.fetched:
+			b.WriteString("[gray]Fetching...[-]\n")
+		case v.pattern == "":
+			for _, line := range output.lines {
+				b.WriteString(tview.Escape(line) + "\n")
+			}
+		default:
+			matches := v.matches(output.lines)
+			if len(matches) == 0 {
+				b.WriteString("[gray]No matching line[-]\n")
+			}
+			for _, line := range matches {
+				b.WriteString(highlight(line, v.pattern) + "\n")
+			}
+		}
+	}
+	v.view.SetText(b.String())
+}
+
+// matches returns the lines containing the grep pattern
+func (v *MultiConsoleView) matches(lines []string) []string {
+	var matches []string
+	for _, line := range lines {
+		if strings.Contains(strings.ToLower(line), strings.ToLower(v.pattern)) {
+			matches = append(matches, line)
+		}
+	}
+	return matches
+}
+
+// highlight escapes a line and highlights the occurrences of a pattern,
+// ignoring case
+func highlight(line, pattern string) string {
+	lower, lowerPattern := strings.ToLower(line), strings.ToLower(pattern)
+	if len(lower) != len(line) || pattern == "" {
+		// The lowercase line does not map byte per byte to the line
+		return tview.Escape(line)
+	}
+
+	var b strings.Builder
+	for {
+		index := strings.Index(lower, lowerPattern)
+		if index < 0 {
+			b.WriteString(tview.Escape(line))
+			return b.String()
+		}
+		end := index + len(pattern)
+		b.WriteString(tview.Escape(line[:index]))
+		b.WriteString("[black:yellow]" + tview.Escape(line[index:end]) + "[-:-]")
+		line, lower = line[end:], lower[end:]
+	}
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import "github.com/rivo/tview"
+
+// Names of the views reachable through the navigation stack
+const (
+	viewInstances = "instances"
+	viewVPCs      = "vpcs"
+	viewRegions   = "regions"
+)
+
+// ViewState holds the session state of a view, restored when navigating back to it
+type ViewState struct {
+	Filter     string // Current filter
+	SortColumn int    // Index of the sorted column, -1 keeps the default order
+	SortDesc   bool   // Sort in descending order
+	Selected   int    // Index of the selected row
+	GroupBy    string // Grouping of the rows: state, zone, type, stack, asg or tag:<key>, empty if not grouped
+}
+
+// newViewState creates the initial state of a view
+func newViewState() *ViewState {
+	return &ViewState{
+		SortColumn: -1,
+	}
+}
+
+// Navigation keeps track of the visited views and their session state
+type Navigation struct {
+	stack  []string
+	states map[string]*ViewState
+}
+
+// NewNavigation creates a navigation stack starting on the given view
+func NewNavigation(root string) *Navigation {
+	return &Navigation{
+		stack:  []string{root},
+		states: map[string]*ViewState{root: newViewState()},
+	}
+}
+
+// Current returns the name of the view on top of the stack
+func (n *Navigation) Current() string {
+	return n.stack[len(n.stack)-1]
+}
+
+// State returns the session state of the current view
+func (n *Navigation) State() *ViewState {
+	return n.StateOf(n.Current())
+}
+
+// StateOf returns the session state of a view, creating it on first access
+func (n *Navigation) StateOf(view string) *ViewState {
+	state, ok := n.states[view]
+	if !ok {
+		state = newViewState()
+		n.states[view] = state
+	}
+	return state
+}
+
+// Push navigates to a view and returns its saved state
+func (n *Navigation) Push(view string) *ViewState {
+	if n.Current() != view {
+		n.stack = append(n.stack, view)
+	}
+	return n.State()
+}
+
+// Pop goes back to the previous view and returns its saved state.
+// The root view is never removed from the stack.
+func (n *Navigation) Pop() *ViewState {
+	if len(n.stack) > 1 {
+		n.stack = n.stack[:len(n.stack)-1]
+	}
+	return n.State()
+}
+
+// navPage is the modal page of a view pushed on the navigation stack. The
+// view is popped from the stack when its page is closed with Esc, unlike the
+// other modal pages such as the dialogs.
+type navPage struct {
+	tview.Primitive
+	closed func() // Called once the page is closed with Esc
+}
+
+// showNavPage displays the page of a view as the modal page and pushes the
+// view on the navigation stack. closed is called after the view was popped
+// when the page is closed with Esc. The page displayed is returned, to check
+// whether it is still in front.
+func (ui *UI) showNavPage(view string, page tview.Primitive, closed func()) tview.Primitive {
+	wrapped := &navPage{
+		Primitive: page,
+		closed: func() {
+			ui.nav.Pop()
+			if closed != nil {
+				closed()
+			}
+		},
+	}
+	ui.pages.AddPage("modal", wrapped, true, true)
+	ui.nav.Push(view)
+	return wrapped
+}
+
+// closeModal removes the modal page, popping its view from the navigation
+// stack if it was pushed on it
+func (ui *UI) closeModal() {
+	name, front := ui.pages.GetFrontPage()
+	ui.pages.RemovePage("modal")
+	if page, ok := front.(*navPage); ok && name == "modal" {
+		page.closed()
+	}
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// notice returns the notice of an instance, the value of its ui.notice_tag
+// tag, e.g. "Do not reboot during migration", empty if none
+func (ui *UI) notice(instance model.Instance) string {
+	if ui.config().UI.NoticeTag == "" {
+		return ""
+	}
+	return strings.TrimSpace(instance.Tags[ui.config().UI.NoticeTag])
+}
+
+// withNotice appends the notice of an instance, if any, to the message of a
+// confirmation
+func (ui *UI) withNotice(message string, instance model.Instance) string {
+	if notice := ui.notice(instance); notice != "" {
+		message += fmt.Sprintf("\n\n⚠ NOTICE: %s", notice)
+	}
+	return message
+}
+
+// withNotices appends the notices of instances to the message of the
+// confirmation of a batch, and returns whether any instance has one
+func (ui *UI) withNotices(message string, instances []model.Instance) (string, bool) {
+	var notices []string
+	for _, instance := range instances {
+		if notice := ui.notice(instance); notice != "" {
+			notices = append(notices, fmt.Sprintf("%s: %s", instance.DisplayName(), notice))
+		}
+	}
+	if len(notices) == 0 {
+		return message, false
+	}
+	return message + "\n\n⚠ NOTICES:\n" + strings.Join(notices, "\n"), true
+}
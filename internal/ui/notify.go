@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+)
+
+// flashDuration is how long the screen is displayed in reverse video by the
+// flash
+const flashDuration = 150 * time.Millisecond
+
+// toast is the summary of a long operation done, or of a change of an
+// instance watched, displayed in the bottom right corner of the screen
+type toast struct {
+	title   string
+	summary string
+	failed  bool
+}
+
+// notifyDone notifies the end of an operation begun at started, if it lasted
+// at least ui.notify.min_duration. It must be called from the UI goroutine.
+func (ui *UI) notifyDone(started time.Time, summary string, failed bool) {
+	duration := time.Since(started)
+	if duration < ui.config().UI.Notify.MinDuration {
+		return
+	}
+	ui.log.Debug("Notifying the end of a long operation", "summary", summary, "duration", duration)
+	title := "Done"
+	if failed {
+		title = "Failed"
+	}
+	ui.notify(title, summary+" ("+duration.Round(time.Second).String()+")", failed)
+}
+
+// notify rings the bell of the terminal, flashes the screen and displays a
+// toast with a title and a summary, in red if failed, as configured in
+// ui.notify. It must be called from the UI goroutine.
+func (ui *UI) notify(title, summary string, failed bool) {
+	cfg := ui.config().UI.Notify
+	ui.bell = cfg.Bell
+	if cfg.Flash {
+		ui.flashUntil = time.Now().Add(flashDuration)
+		time.AfterFunc(flashDuration, func() { ui.app.Draw() })
+	}
+	if cfg.Toast > 0 {
+		t := &toast{title: title, summary: summary, failed: failed}
+		ui.toast = t
+		time.AfterFunc(cfg.Toast, func() {
+			ui.app.QueueUpdateDraw(func() {
+				if ui.toast == t {
+					ui.toast = nil
+				}
+			})
+		})
+	}
+}
+
+// drawNotification rings the bell, flashes the screen and draws the last
+// toast, if any
+func (ui *UI) drawNotification(screen tcell.Screen) {
+	if ui.bell {
+		ui.bell = false
+		if err := screen.Beep(); err != nil {
+			ui.log.Debug("Failed to ring the bell", "error", err)
+		}
+	}
+
+	screenWidth, screenHeight := screen.Size()
+	if time.Now().Before(ui.flashUntil) {
+		for y := 0; y < screenHeight; y++ {
+			for x := 0; x < screenWidth; x++ {
+				r, combining, style, _ := screen.GetContent(x, y)
+				screen.SetContent(x, y, r, combining, style.Reverse(true))
+			}
+		}
+	}
+
+	if ui.toast == nil {
+		return
+	}
+	titleColor := color.AppColors.Running
+	if ui.toast.failed {
+		titleColor = color.AppColors.Error
+	}
+	line := ui.toast.summary
+
+	// Above the status bar and the help
+	width := min(tview.TaggedStringWidth(line)+4, screenWidth-2)
+	x, y := screenWidth-width-1, screenHeight-6
+	if x < 0 || y < 0 {
+		return
+	}
+	style := tcell.StyleDefault.Background(color.AppColors.HeaderBg).Foreground(color.AppColors.Foreground)
+	for row := 0; row < 3; row++ {
+		for col := 0; col < width; col++ {
+			screen.SetContent(x+col, y+row, ' ', nil, style)
+		}
+	}
+	tview.Print(screen, " "+tview.Escape(ui.toast.title)+" ", x, y, width, tview.AlignCenter, titleColor)
+	tview.Print(screen, tview.Escape(line), x+2, y+1, width-4, tview.AlignLeft, color.AppColors.Foreground)
+}
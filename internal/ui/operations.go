@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// spinnerFrames are the frames of the spinner displayed while operations are
+// in flight
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const (
+	// spinnerInterval is the interval between two frames of the spinner
+	spinnerInterval = 100 * time.Millisecond
+
+	// displayedOperations is the number of in-flight operations listed in
+	// the status bar, the others being counted
+	displayedOperations = 3
+)
+
+// Operation is an asynchronous operation in flight, e.g. a refresh or the
+// start of an instance, displayed in the status bar until it is done. Its
+// methods must be called from the UI goroutine, e.g. in QueueUpdateDraw.
+type Operation struct {
+	bar     *StatusBar
+	label   string
+	started time.Time
+}
+
+// StartOperation adds an operation to the in-flight operations, starting the
+// spinner if it is the first one
+func (b *StatusBar) StartOperation(label string) *Operation {
+	op := &Operation{bar: b, label: label, started: time.Now()}
+	b.operations = append(b.operations, op)
+	if b.stopSpinner == nil {
+		b.stopSpinner = make(chan struct{})
+		go b.spin(b.stopSpinner)
+	}
+	b.update()
+	return op
+}
+
+// Update changes the label of the operation, e.g. to display its progress
+func (o *Operation) Update(label string) {
+	o.label = label
+	o.bar.update()
+}
+
+// Done removes the operation from the in-flight operations, stopping the
+// spinner if it was the last one
+func (o *Operation) Done() {
+	b := o.bar
+	for i, op := range b.operations {
+		if op == o {
+			b.operations = append(b.operations[:i], b.operations[i+1:]...)
+			break
+		}
+	}
+	if len(b.operations) == 0 && b.stopSpinner != nil {
+		close(b.stopSpinner)
+		b.stopSpinner = nil
+	}
+	b.update()
+}
+
+// spin animates the spinner until stopped
+func (b *StatusBar) spin(stop <-chan struct{}) {
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			b.ui.app.QueueUpdateDraw(func() {
+				b.frame = (b.frame + 1) % len(spinnerFrames)
+				b.update()
+			})
+		}
+	}
+}
+
+// operationsInfo returns the spinner and the in-flight operations, the
+// oldest first, empty if none
+func (b *StatusBar) operationsInfo() string {
+	if len(b.operations) == 0 {
+		return ""
+	}
+
+	labels := make([]string, 0, displayedOperations)
+	for _, op := range b.operations[:min(len(b.operations), displayedOperations)] {
+		labels = append(labels, op.label)
+	}
+	info := fmt.Sprintf("[yellow]%s[-] %s", spinnerFrames[b.frame], strings.Join(labels, ", "))
+	if more := len(b.operations) - displayedOperations; more > 0 {
+		info += fmt.Sprintf(" (+%d)", more)
+	}
+	return info
+}
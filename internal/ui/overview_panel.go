@@ -5,11 +5,14 @@ package ui
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
 	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/store"
 )
 
 // OverviewPanel represents the overview panel at the top of the UI
@@ -20,9 +23,15 @@ type OverviewPanel struct {
 	region           string
 	instancesRunning int
 	instancesStopped int
-	// Currently not using theme
+	zones            map[string]int // Instances per availability zone
+	totals           []int          // Total instances of the last refreshes, oldest first
+	running          []int          // Running instances of the last refreshes, oldest first
 }
 
+// sparkBlocks are the blocks drawing a sparkline, from the lowest value to
+// the highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
 // NewOverviewPanel creates a new overview panel
 func NewOverviewPanel(ui *UI) *OverviewPanel {
 	panel := &OverviewPanel{
@@ -39,6 +48,33 @@ func NewOverviewPanel(ui *UI) *OverviewPanel {
 	// Set initial content
 	panel.Update(0, 0, 0, "Unknown")
 
+	// Count the instances once they are all loaded
+	ui.store.Subscribe(func(state *store.State, action store.Action) {
+		if loaded, ok := action.(store.InstancesLoaded); ok && loaded.Complete {
+			running, stopped := 0, 0
+			zones := make(map[string]int)
+			for _, instance := range state.Instances {
+				if instance.AvailabilityZone != "" {
+					zones[instance.AvailabilityZone]++
+				}
+				if instance.IsRunning() {
+					running++
+				} else if instance.IsStopped() {
+					stopped++
+				}
+			}
+			ui.app.QueueUpdateDraw(func() {
+				region := ui.ec2Client().GetRegion()
+				if region != panel.region {
+					panel.resetTrend()
+				}
+				panel.recordTrend(len(state.Instances), running)
+				panel.zones = zones
+				panel.Update(len(state.Instances), running, stopped, region)
+			})
+		}
+	})
+
 	return panel
 }
 
@@ -59,12 +95,12 @@ func (p *OverviewPanel) Update(total, running, stopped int, region string) {
 	otherColor := "yellow"
 	regionColor := "blue"
 	keyColor := "blue"
-	textColor := "white"
+	textColor := "-"
 
 	// Format the overview text
 	text := fmt.Sprintf(`
- [::b][%s]EC2 INSTANCES[%s][::-]
- [%s]Total:[%s] %d     [%s]Running:[%s] %d     [%s]Stopped:[%s] %d     [%s]Other:[%s] %d
+ [::b][%s]EC2 INSTANCES[%s][::-]%s
+ [%s]Total:[%s] %d     [%s]Running:[%s] %d     [%s]Stopped:[%s] %d     [%s]Other:[%s] %d%s%s
 
  [::b][%s]AWS REGION[%s][::-]
  [%s]%s[%s]
@@ -74,11 +110,11 @@ func (p *OverviewPanel) Update(total, running, stopped int, region string) {
  [%s]s[%s]: Start      [%s]p[%s]: Stop       [%s]b[%s]: Reboot      [%s]t[%s]: Terminate
  [%s]c[%s]: Connect    [%s]l[%s]: Logs       [%s]Esc[%s]: Back
 `,
-		headerColor, textColor,
+		headerColor, textColor, p.disabledNote(),
 		headerColor, textColor, p.instanceCount,
 		runningColor, textColor, p.instancesRunning,
 		stoppedColor, textColor, p.instancesStopped,
-		otherColor, textColor, other,
+		otherColor, textColor, other, p.trend(keyColor, textColor), p.zoneCounts(keyColor, textColor),
 		headerColor, textColor,
 		regionColor, p.region, textColor,
 		headerColor, textColor,
@@ -90,6 +126,95 @@ func (p *OverviewPanel) Update(total, running, stopped int, region string) {
 	p.view.SetText(text)
 }
 
+// disabledNote returns the note listing the features disabled for the
+// session, empty if none
+func (p *OverviewPanel) disabledNote() string {
+	names := p.ui.disabledFeatureNames()
+	if names == "" {
+		return ""
+	}
+	return fmt.Sprintf("   [gray]disabled: %s (see the help)[-]", tview.Escape(names))
+}
+
+// recordTrend adds the counts of a refresh to the trend, keeping the last
+// ones only
+func (p *OverviewPanel) recordTrend(total, running int) {
+	size := p.ui.config().UI.Trend
+	if size <= 0 {
+		return
+	}
+	p.totals = append(p.totals, total)
+	p.running = append(p.running, running)
+	if len(p.totals) > size {
+		p.totals = p.totals[len(p.totals)-size:]
+		p.running = p.running[len(p.running)-size:]
+	}
+}
+
+// resetTrend drops the counts of the trend, e.g. after switching to another
+// region
+func (p *OverviewPanel) resetTrend() {
+	p.totals = nil
+	p.running = nil
+}
+
+// trend returns the sparklines of the total and running instances with
+// their change over the trend, empty until two refreshes are recorded
+func (p *OverviewPanel) trend(keyColor, textColor string) string {
+	if len(p.totals) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("     [%s]Trend:[%s] total %s %s  running %s %s",
+		keyColor, textColor,
+		sparkline(p.totals), formatChange(p.totals),
+		sparkline(p.running), formatChange(p.running),
+	)
+}
+
+// zoneCounts returns the number of instances per availability zone, sorted
+// by zone, to spot an unbalanced fleet. Empty if no instance is listed.
+func (p *OverviewPanel) zoneCounts(keyColor, textColor string) string {
+	if len(p.zones) == 0 {
+		return ""
+	}
+	zones := make([]string, 0, len(p.zones))
+	for zone := range p.zones {
+		zones = append(zones, zone)
+	}
+	slices.Sort(zones)
+
+	counts := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		counts = append(counts, fmt.Sprintf("%s %d", zone, p.zones[zone]))
+	}
+	return fmt.Sprintf("\n [%s]Zones:[%s] %s", keyColor, textColor, strings.Join(counts, "   "))
+}
+
+// sparkline draws values with blocks scaled between their minimum and
+// maximum, so that small changes are visible
+func sparkline[T int | float64](values []T) string {
+	if len(values) == 0 {
+		return ""
+	}
+	low, high := slices.Min(values), slices.Max(values)
+
+	blocks := make([]rune, len(values))
+	for i, value := range values {
+		level := 0
+		if high > low {
+			level = int((value - low) * T(len(sparkBlocks)-1) / (high - low))
+		}
+		blocks[i] = sparkBlocks[level]
+	}
+	return string(blocks)
+}
+
+// formatChange formats the change between the first and the last values,
+// e.g. +3
+func formatChange(values []int) string {
+	return fmt.Sprintf("%+d", values[len(values)-1]-values[0])
+}
+
 // UpdateStats updates just the instance statistics
 func (p *OverviewPanel) UpdateStats(total, running, stopped int) {
 	p.Update(total, running, stopped, p.region)
@@ -105,6 +230,8 @@ func (p *OverviewPanel) UpdateTheme() {
 	// Update border and title colors
 	p.view.SetBorderColor(color.AppColors.Border)
 	p.view.SetTitleColor(color.AppColors.Title)
+	p.view.SetBackgroundColor(color.AppColors.Background)
+	p.view.SetTextColor(color.AppColors.Foreground)
 
 	// Refresh the panel with new colors
 	p.Update(p.instanceCount, p.instancesRunning, p.instancesStopped, p.region)
@@ -0,0 +1,425 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/pkg/audit"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/store"
+)
+
+const (
+	// protectionTTL is how long the protections retrieved are considered up
+	// to date, before being retrieved again by the next scan
+	protectionTTL = 10 * time.Minute
+
+	// protectionWorkers is the number of instances scanned at once, the
+	// calls being paced by the rate limit of the attribute calls
+	protectionWorkers = 4
+
+	// protectionMinBackoff and protectionMaxBackoff bound the delay before
+	// retrying an instance once the calls are throttled
+	protectionMinBackoff = time.Second
+	protectionMaxBackoff = 30 * time.Second
+
+	// protectionVisibleEvery is the number of instances scanned before the
+	// rows visible in the table are checked again
+	protectionVisibleEvery = 10
+
+	// protectionFlushEvery is the number of protections retrieved by a scan
+	// before they are dispatched to the store at once
+	protectionFlushEvery = 20
+)
+
+// protectionCall is a retrieval of the protections of an instance, shared by
+// the callers asking for them while it runs
+type protectionCall struct {
+	done       chan struct{}
+	protection *model.Protection
+	err        error
+}
+
+// fetchProtection returns the protections of an instance, those retrieved
+// already unless they expired, were invalidated, or force is set. The
+// concurrent calls for the same instance share a single retrieval, whose
+// result is dispatched to the store.
+func (ui *UI) fetchProtection(ctx context.Context, client *aws.EC2Client, id string, force bool) (*model.Protection, error) {
+	return ui.loadProtection(ctx, client, id, force, func(protection model.Protection) {
+		ui.store.Dispatch(store.ProtectionLoaded{InstanceID: id, Protection: protection})
+	})
+}
+
+// loadProtection is fetchProtection, passing the protections retrieved to
+// loaded rather than dispatching them, e.g. to dispatch them in batches
+func (ui *UI) loadProtection(ctx context.Context, client *aws.EC2Client, id string, force bool, loaded func(model.Protection)) (*model.Protection, error) {
+	ui.protectionMutex.Lock()
+	if !force && !ui.protectionStale[id] {
+		if protection, ok := ui.store.Snapshot().Protections[id]; ok && protectionFresh(protection) {
+			ui.protectionMutex.Unlock()
+			return &protection, nil
+		}
+	}
+	call, running := ui.protectionCalls[id]
+	if !running {
+		call = &protectionCall{done: make(chan struct{})}
+		if ui.protectionCalls == nil {
+			ui.protectionCalls = make(map[string]*protectionCall)
+		}
+		ui.protectionCalls[id] = call
+		delete(ui.protectionStale, id)
+	}
+	ui.protectionMutex.Unlock()
+
+	if running {
+		select {
+		case <-call.done:
+			return call.protection, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	call.protection, call.err = client.GetInstanceProtection(ctx, id)
+	if call.err == nil {
+		loaded(*call.protection)
+	}
+
+	ui.protectionMutex.Lock()
+	delete(ui.protectionCalls, id)
+	ui.protectionMutex.Unlock()
+	close(call.done)
+
+	return call.protection, call.err
+}
+
+// invalidateProtection drops the protections retrieved for an instance, so
+// that they are retrieved again
+func (ui *UI) invalidateProtection(id string) {
+	ui.protectionMutex.Lock()
+	if ui.protectionStale == nil {
+		ui.protectionStale = make(map[string]bool)
+	}
+	ui.protectionStale[id] = true
+	ui.protectionMutex.Unlock()
+
+	ui.store.Dispatch(store.ProtectionInvalidated{InstanceID: id})
+}
+
+// instanceMutated is called after a mutating action on an instance
+// succeeded. It drops the data cached for the instance, which may have
+// changed, and scans its protections again at the admin level.
+func (ui *UI) instanceMutated(action, id string) {
+	ui.log.Debug("Invalidating the data of instance", "action", action, "instanceID", id)
+	ui.invalidateProtection(id)
+	go ui.app.QueueUpdate(func() {
+		ui.asyncCache().deletePrefix(id + "/")
+	})
+	if ui.config().Allows(config.LevelAdmin) {
+		ui.startProtectionScan()
+	}
+}
+
+// protectionFresh returns true if the protections were retrieved less than
+// protectionTTL ago
+func protectionFresh(protection model.Protection) bool {
+	return time.Since(protection.FetchedAt) < protectionTTL
+}
+
+// startProtectionScan scans the protections of the instances not scanned
+// yet, or whose protections expired. Only one scan runs at a time: the
+// running one picks up the instances of the later refreshes.
+func (ui *UI) startProtectionScan() {
+	if !ui.featureEnabled(featureProtections) {
+		return
+	}
+	if !ui.scanning.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer ui.scanning.Store(false)
+		ui.scanProtections(ui.ctx)
+	}()
+}
+
+// protectionScan is the progress of a protections scan, shared by its workers
+type protectionScan struct {
+	mutex    sync.Mutex
+	scanned  map[string]bool             // Instances scanned, or failed, by this scan
+	inflight map[string]bool             // Instances being scanned
+	visible  []string                    // Rows visible in the table
+	count    int                         // Instances picked so far
+	backoff  time.Duration               // Delay before retrying once throttled
+	loaded   map[string]model.Protection // Protections retrieved, not dispatched yet
+}
+
+// add records the protections retrieved for an instance, and returns the
+// ones to dispatch once enough were retrieved
+func (s *protectionScan) add(id string, protection model.Protection) map[string]model.Protection {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.loaded[id] = protection
+	if len(s.loaded) < protectionFlushEvery {
+		return nil
+	}
+	return s.take()
+}
+
+// take returns the protections not dispatched yet, and resets them. The
+// mutex of the scan must be held.
+func (s *protectionScan) take() map[string]model.Protection {
+	loaded := s.loaded
+	s.loaded = make(map[string]model.Protection)
+	return loaded
+}
+
+// scanProtections retrieves the protections of the instances with several
+// workers, the rows visible in the table first, and dispatches them in
+// batches of protectionFlushEvery, to copy the protections of the state once
+// per batch rather than once per instance. The scan slows down when the calls are throttled, and stops
+// when the caller is not allowed to read the attributes of the instances.
+func (ui *UI) scanProtections(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scan := &protectionScan{
+		scanned:  make(map[string]bool),
+		inflight: make(map[string]bool),
+		backoff:  protectionMinBackoff,
+		loaded:   make(map[string]model.Protection),
+	}
+
+	var wg sync.WaitGroup
+	for range protectionWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ui.scanProtectionsWorker(ctx, cancel, scan)
+		}()
+	}
+	wg.Wait()
+
+	scan.mutex.Lock()
+	loaded := scan.take()
+	scan.mutex.Unlock()
+	ui.dispatchProtections(loaded)
+}
+
+// dispatchProtections dispatches the protections retrieved by a scan, if any
+func (ui *UI) dispatchProtections(loaded map[string]model.Protection) {
+	if len(loaded) > 0 {
+		ui.store.Dispatch(store.ProtectionsLoaded{Protections: loaded})
+	}
+}
+
+// scanProtectionsWorker scans the next instance pending until none is left
+func (ui *UI) scanProtectionsWorker(ctx context.Context, cancel context.CancelFunc, scan *protectionScan) {
+	for {
+		scan.mutex.Lock()
+		refresh := scan.count%protectionVisibleEvery == 0
+		scan.mutex.Unlock()
+		visible := []string(nil)
+		if refresh {
+			visible = ui.visibleInstanceIDs()
+		}
+
+		scan.mutex.Lock()
+		if refresh {
+			scan.visible = visible
+		}
+		scan.count++
+		id, done, total := nextProtectionScan(ui.store.Snapshot(), scan.scanned, scan.inflight, scan.visible)
+		if id != "" {
+			scan.inflight[id] = true
+		}
+		scan.mutex.Unlock()
+
+		ui.app.QueueUpdateDraw(func() {
+			ui.statusBar.SetProtections(done, total)
+		})
+		if id == "" {
+			return
+		}
+
+		_, err := ui.loadProtection(ctx, ui.clientForID(id), id, false, func(protection model.Protection) {
+			ui.dispatchProtections(scan.add(id, protection))
+		})
+
+		scan.mutex.Lock()
+		delete(scan.inflight, id)
+		var backoff time.Duration
+		switch {
+		case ctx.Err() != nil:
+		case err == nil:
+			scan.scanned[id] = true
+			scan.backoff = protectionMinBackoff
+		case aws.ClassifyError(err) == aws.ErrorThrottling:
+			backoff = scan.backoff
+			scan.backoff = min(2*scan.backoff, protectionMaxBackoff)
+		case aws.ClassifyError(err) == aws.ErrorPermissions:
+		default:
+			// Skip the instance until the next scan, e.g. it was terminated
+			ui.log.Error("Failed to scan protections", "instanceID", id, "error", err)
+			scan.scanned[id] = true
+		}
+		scan.mutex.Unlock()
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case backoff > 0:
+			ui.log.Warn("Protections scan throttled", "instanceID", id, "backoff", backoff)
+			if !sleepContext(ctx, backoff) {
+				return
+			}
+		case err != nil && aws.ClassifyError(err) == aws.ErrorPermissions:
+			// Not scanned again on the next refreshes
+			cancel()
+			ui.app.QueueUpdateDraw(func() {
+				ui.statusBar.SetProtections(0, 0)
+			})
+			ui.checkFeatureError(featureProtections, err)
+			return
+		}
+	}
+}
+
+// nextProtectionScan returns the next instance to scan, a visible one if
+// any, empty once all were scanned or are being scanned, with the number of
+// instances scanned out of all the instances
+func nextProtectionScan(state *store.State, scanned, inflight map[string]bool, visible []string) (string, int, int) {
+	pending := func(id string) bool {
+		protection, known := state.Protections[id]
+		return !(known && protectionFresh(protection)) && !scanned[id] && !inflight[id]
+	}
+
+	done := 0
+	var next string
+	for _, instance := range state.Instances {
+		switch {
+		case inflight[instance.ID]:
+		case !pending(instance.ID):
+			done++
+		case next == "":
+			next = instance.ID
+		}
+	}
+
+	for _, id := range visible {
+		if pending(id) && containsInstance(state.Instances, id) {
+			next = id
+			break
+		}
+	}
+
+	return next, done, len(state.Instances)
+}
+
+// containsInstance returns true if the instance is part of the list
+func containsInstance(instances []model.Instance, id string) bool {
+	for _, instance := range instances {
+		if instance.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleInstanceIDs returns the IDs of the rows visible in the table, read
+// from the UI goroutine
+func (ui *UI) visibleInstanceIDs() []string {
+	ids := make(chan []string, 1)
+	go ui.app.QueueUpdate(func() {
+		ids <- ui.instancesView.VisibleIDs()
+	})
+
+	select {
+	case visible := <-ids:
+		return visible
+	case <-ui.ctx.Done():
+		return nil
+	}
+}
+
+// sleepContext waits for a delay, and returns false if the context was
+// cancelled in the meantime
+func sleepContext(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// trailDelay is the delay before CloudTrail delivers an event, during which
+// the changes made with e2c are only in the audit log
+const trailDelay = 15 * time.Minute
+
+// fetchProtectionHistory returns the changes of the protections of an
+// instance, looked up in CloudTrail and in the audit log of the changes made
+// with e2c. The history is returned without the CloudTrail events if the
+// lookup failed.
+func (ui *UI) fetchProtectionHistory(ctx context.Context, client *aws.EC2Client, id string) (*model.ProtectionHistory, error) {
+	history := &model.ProtectionHistory{}
+
+	var trail []model.ProtectionChange
+	if ui.featureEnabled(featureCloudTrail) {
+		var err error
+		if trail, err = client.LookupProtectionChanges(ctx, id); err != nil {
+			ui.checkFeatureError(featureCloudTrail, err)
+			history.CloudTrailErr = err
+		}
+	} else {
+		history.CloudTrailErr = fmt.Errorf("%s %w", featureCloudTrail, errFeatureDisabled)
+	}
+
+	var local []model.ProtectionChange
+	if log := client.AuditLog(); log != nil {
+		entries, err := audit.Read(log.Path())
+		if err != nil {
+			return nil, err
+		}
+		local = aws.AuditProtectionChanges(entries, id)
+	}
+
+	history.Changes = mergeProtectionChanges(trail, local)
+	return history, nil
+}
+
+// mergeProtectionChanges merges the changes of CloudTrail and of the audit
+// log, the most recent first. A change of the audit log is dropped once
+// CloudTrail delivered it.
+func mergeProtectionChanges(trail, local []model.ProtectionChange) []model.ProtectionChange {
+	changes := append([]model.ProtectionChange(nil), trail...)
+	for _, change := range local {
+		delivered := false
+		for _, event := range trail {
+			delta := event.Time.Sub(change.Time)
+			if event.Protection == change.Protection && event.Enabled == change.Enabled && delta > -time.Minute && delta < trailDelay {
+				delivered = true
+				break
+			}
+		}
+		if !delivered {
+			changes = append(changes, change)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Time.After(changes[j].Time)
+	})
+	return changes
+}
@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// reachabilityProtocols are the protocols of the paths analyzed by the VPC
+// Reachability Analyzer
+var reachabilityProtocols = []string{"tcp", "udp"}
+
+// runReachCommand runs the reach command, analyzing the path from the
+// selected instance to a destination
+func (ui *UI) runReachCommand(args []string) error {
+	if len(args) == 0 {
+		ui.ShowReachabilityDialog()
+		return nil
+	}
+	if len(args) > 3 {
+		return errors.New("at most a destination, a port and a protocol expected")
+	}
+
+	instance := ui.instancesView.GetSelectedInstance()
+	if instance == nil {
+		return errors.New("no instance selected")
+	}
+
+	port, protocol := "22", "tcp"
+	if len(args) > 1 {
+		port = args[1]
+	}
+	if len(args) > 2 {
+		protocol = args[2]
+	}
+	return ui.analyzeReachability(*instance, args[0], port, protocol)
+}
+
+// ShowReachabilityDialog displays the dialog asking for the destination of
+// the path from the selected instance to analyze
+func (ui *UI) ShowReachabilityDialog() {
+	instance := ui.instancesView.GetSelectedInstance()
+	if instance == nil {
+		ui.statusBar.SetError("No instance selected")
+		return
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("Destination:", "", 30, nil, nil)
+	form.AddInputField("Port:", "22", 6, tview.InputFieldInteger, nil)
+	form.AddDropDown("Protocol:", reachabilityProtocols, 0, nil)
+	form.AddButton("Analyze", func() {
+		destination := form.GetFormItem(0).(*tview.InputField).GetText()
+		port := form.GetFormItem(1).(*tview.InputField).GetText()
+		_, protocol := form.GetFormItem(2).(*tview.DropDown).GetCurrentOption()
+		ui.pages.RemovePage("modal")
+		if err := ui.analyzeReachability(*instance, destination, port, protocol); err != nil {
+			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		}
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Reachability from %s (IP or resource ID)", instance.DisplayName()))
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 60, 1, true).
+			AddItem(nil, 0, 1, false), 11, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// analyzeReachability runs a VPC Reachability Analyzer analysis of the path
+// from an instance to a destination in the background, and displays its
+// verdict once done
+func (ui *UI) analyzeReachability(instance model.Instance, destination, port, protocol string) error {
+	destination = strings.TrimSpace(destination)
+	if destination == "" {
+		return errors.New("no destination given")
+	}
+	number, err := strconv.ParseInt(port, 10, 32)
+	if err != nil || number < 1 || number > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	protocol = strings.ToLower(protocol)
+	if protocol != "tcp" && protocol != "udp" {
+		return fmt.Errorf("invalid protocol %q (expected tcp or udp)", protocol)
+	}
+
+	target := fmt.Sprintf("%s:%d/%s", destination, number, protocol)
+	ui.statusBar.SetStatus(fmt.Sprintf("Analyzing the path from %s to %s, this takes a minute or so...", instance.ID, target))
+
+	ctx := ui.actionCtx()
+	start := time.Now()
+	go func() {
+		result, err := ui.clientFor(instance).AnalyzeReachability(ctx, instance.ID, destination, int32(number), protocol)
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.log.Error("Failed to analyze reachability", "instanceID", instance.ID, "destination", target, "error", err)
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			ui.statusBar.SetStatus(fmt.Sprintf("Analyzed the path from %s to %s in %s", instance.ID, target, time.Since(start).Round(time.Second)))
+			ui.showReachability(instance, target, result)
+		})
+	}()
+	return nil
+}
+
+// showReachability displays the verdict of an analysis, and the components
+// blocking the path if the destination is not reachable
+func (ui *UI) showReachability(instance model.Instance, target string, result *model.Reachability) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n [blue]From:[-]     %s (%s)\n", tview.Escape(instance.DisplayName()), instance.ID)
+	fmt.Fprintf(&b, " [blue]To:[-]       %s\n", tview.Escape(target))
+	fmt.Fprintf(&b, " [blue]Analysis:[-] %s (path %s)\n\n", result.AnalysisID, result.PathID)
+
+	switch {
+	case result.Status != "succeeded":
+		fmt.Fprintf(&b, " [red]Analysis %s[-]: %s\n", result.Status, tview.Escape(result.StatusDetail))
+	case result.Reachable:
+		b.WriteString(" [green::b]✅ Reachable[-::-]\n")
+	default:
+		b.WriteString(" [red::b]❌ Not reachable[-::-]\n\n")
+		if len(result.Explanations) == 0 {
+			b.WriteString(" No explanation given, see the analysis in the AWS console\n")
+		}
+		for _, explanation := range result.Explanations {
+			fmt.Fprintf(&b, " [yellow]%s[-]\n", tview.Escape(explanation.Component))
+			fmt.Fprintf(&b, "   %s\n", tview.Escape(explanation.Code))
+			if explanation.Detail != "" {
+				fmt.Fprintf(&b, "   [gray]%s[-]\n", tview.Escape(explanation.Detail))
+			}
+		}
+	}
+	b.WriteString("\n [yellow]Press Esc to close[-]\n")
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true).
+		SetText(b.String())
+	view.SetBorder(true).
+		SetTitle(" Reachability Analyzer ").
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(view, 0, 3, true).
+			AddItem(nil, 0, 1, false), 90, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nlamirault/e2c/internal/config"
+)
+
+// minRefreshInterval is the shortest auto-refresh interval, to stay far
+// below the EC2 API rate limits
+const minRefreshInterval = config.MinRefreshInterval
+
+// refreshSteps are the intervals selected with + and -
+var refreshSteps = []time.Duration{
+	5 * time.Second,
+	10 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+}
+
+// startRefreshTicker starts a ticker to refresh instances periodically
+func (ui *UI) startRefreshTicker() {
+	ui.refresh = ui.config().AWS.RefreshInterval
+	if ui.refresh <= 0 {
+		ui.refresh = 30 * time.Second
+	}
+	if interval := ui.refreshOverride(); interval > 0 {
+		ui.refresh = interval
+		ui.refreshFlag = interval
+	}
+
+	ui.refreshTicker = time.NewTicker(ui.refresh)
+	ui.statusBar.SetRefresh(ui.refresh, false)
+
+	go func() {
+		for {
+			select {
+			case <-ui.refreshTicker.C:
+				ui.app.QueueUpdateDraw(ui.RefreshInstances)
+				ui.refreshBackgroundRegion()
+			case <-ui.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// setRefreshInterval changes the interval of the auto-refresh, and resumes
+// it if it was paused
+func (ui *UI) setRefreshInterval(interval time.Duration) {
+	if interval < minRefreshInterval {
+		interval = minRefreshInterval
+	}
+
+	ui.refresh = interval
+	ui.refreshPaused = false
+	ui.refreshTicker.Reset(interval)
+	ui.statusBar.SetRefresh(interval, false)
+	ui.log.Info("Auto-refresh interval changed", "interval", interval)
+}
+
+// toggleRefresh pauses or resumes the auto-refresh
+func (ui *UI) toggleRefresh() {
+	if ui.refreshPaused {
+		ui.setRefreshInterval(ui.refresh)
+		return
+	}
+
+	ui.refreshPaused = true
+	ui.refreshTicker.Stop()
+	ui.statusBar.SetRefresh(ui.refresh, true)
+	ui.log.Info("Auto-refresh paused")
+}
+
+// stepRefreshInterval selects the next longer (direction > 0) or shorter
+// (direction < 0) interval of refreshSteps
+func (ui *UI) stepRefreshInterval(direction int) {
+	next := ui.refresh
+	if direction > 0 {
+		next = refreshSteps[len(refreshSteps)-1]
+		for _, step := range refreshSteps {
+			if step > ui.refresh {
+				next = step
+				break
+			}
+		}
+	} else {
+		next = refreshSteps[0]
+		for i := len(refreshSteps) - 1; i >= 0; i-- {
+			if refreshSteps[i] < ui.refresh {
+				next = refreshSteps[i]
+				break
+			}
+		}
+	}
+	ui.setRefreshInterval(next)
+}
+
+// runRefreshCommand runs the refresh command: an interval such as 10s or
+// 2m, pause or resume
+func (ui *UI) runRefreshCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("one argument expected")
+	}
+
+	switch args[0] {
+	case "pause", "off":
+		if !ui.refreshPaused {
+			ui.toggleRefresh()
+		}
+	case "resume", "on":
+		if ui.refreshPaused {
+			ui.toggleRefresh()
+		}
+	default:
+		interval, err := time.ParseDuration(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid interval %q", args[0])
+		}
+		ui.setRefreshInterval(interval)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/store"
+)
+
+const (
+	// recentRegionWindow is how long a region left is considered recently
+	// displayed, and refreshed at the faster background cadence
+	recentRegionWindow = 15 * time.Minute
+
+	// recentRegionFactor and idleRegionFactor are the intervals between two
+	// refreshes of a region in the background, recently displayed or idle,
+	// in auto-refresh intervals
+	recentRegionFactor = 3
+	idleRegionFactor   = 10
+)
+
+// backgroundRegion is a region displayed before, whose instances are kept
+// refreshed in the background to switch back to it instantly
+type backgroundRegion struct {
+	client     *aws.EC2Client
+	instances  []model.Instance
+	refreshed  time.Time // Last refresh of the instances, zero if unknown
+	displayed  time.Time // When the region was left
+	refreshing bool
+}
+
+// cadence returns the interval between two refreshes of the region in the
+// background: the regions displayed recently are refreshed more often than
+// the idle ones
+func (r *backgroundRegion) cadence(interval time.Duration, now time.Time) time.Duration {
+	if now.Sub(r.displayed) < recentRegionWindow {
+		return recentRegionFactor * interval
+	}
+	return idleRegionFactor * interval
+}
+
+// workspace is a profile and a region whose instances are cached
+type workspace struct {
+	profile string
+	region  string
+}
+
+// cachedInstances are the instances of a workspace as of their last refresh
+type cachedInstances struct {
+	workspace
+	instances []model.Instance
+	refreshed time.Time // Zero for the region displayed
+}
+
+// regionScheduler tracks the staleness of the regions refreshed in the
+// background, the region displayed being refreshed by the auto-refresh
+type regionScheduler struct {
+	mu        sync.Mutex
+	regions   map[string]*backgroundRegion    // Regions refreshed in the background, by name
+	others    map[workspace]*backgroundRegion // Regions of the other profiles used, not refreshed
+	refreshed time.Time                       // Last complete refresh of the region displayed
+}
+
+// newRegionScheduler creates a scheduler without any background region
+func newRegionScheduler() *regionScheduler {
+	return &regionScheduler{
+		regions: make(map[string]*backgroundRegion),
+		others:  make(map[workspace]*backgroundRegion),
+	}
+}
+
+// leave keeps the region left in the background with its client and its
+// instances, dropping the regions left the longest ago beyond limit
+func (s *regionScheduler) leave(region string, client *aws.EC2Client, instances []model.Instance, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit <= 0 {
+		return
+	}
+	s.regions[region] = &backgroundRegion{
+		client:    client,
+		instances: instances,
+		refreshed: s.refreshed,
+		displayed: time.Now(),
+	}
+	s.refreshed = time.Time{}
+
+	if len(s.regions) <= limit {
+		return
+	}
+	names := make([]string, 0, len(s.regions))
+	for name := range s.regions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return s.regions[names[i]].displayed.After(s.regions[names[j]].displayed)
+	})
+	for _, name := range names[limit:] {
+		delete(s.regions, name)
+	}
+}
+
+// enter removes the region displayed from the background regions, and
+// returns its instances if they were refreshed in the background
+func (s *regionScheduler) enter(region string) (*backgroundRegion, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.regions[region]
+	delete(s.regions, region)
+	if !ok || r.refreshed.IsZero() {
+		return nil, false
+	}
+	return r, true
+}
+
+// next returns the background region refreshed the longest ago among those
+// due for a refresh, marking it as refreshing, or false if none is due
+func (s *regionScheduler) next(interval time.Duration, now time.Time) (string, *aws.EC2Client, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stalest string
+	for name, r := range s.regions {
+		if r.refreshing || now.Sub(r.refreshed) < r.cadence(interval, now) {
+			continue
+		}
+		if stalest == "" || r.refreshed.Before(s.regions[stalest].refreshed) {
+			stalest = name
+		}
+	}
+	if stalest == "" {
+		return "", nil, false
+	}
+	s.regions[stalest].refreshing = true
+	return stalest, s.regions[stalest].client, true
+}
+
+// done records the refresh of a background region, keeping its previous
+// instances if it failed
+func (s *regionScheduler) done(region string, instances []model.Instance, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.regions[region]
+	if !ok {
+		// Displayed or dropped meanwhile
+		return
+	}
+	r.refreshing = false
+	if err == nil {
+		r.instances = instances
+		r.refreshed = time.Now()
+	}
+}
+
+// loaded records the complete refresh of the region displayed
+func (s *regionScheduler) loaded() {
+	s.mu.Lock()
+	s.refreshed = time.Now()
+	s.mu.Unlock()
+}
+
+// changeProfile keeps the regions of the profile left, the one displayed
+// included, until this profile is used again, and refreshes in the
+// background the regions kept of the profile entered but the one displayed.
+// Nothing is kept if limit is 0.
+func (s *regionScheduler) changeProfile(left workspace, client *aws.EC2Client, instances []model.Instance, entered workspace, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit <= 0 {
+		s.regions = make(map[string]*backgroundRegion)
+		s.others = make(map[workspace]*backgroundRegion)
+		s.refreshed = time.Time{}
+		return
+	}
+	for name, r := range s.regions {
+		r.refreshing = false
+		s.others[workspace{profile: left.profile, region: name}] = r
+	}
+	if !s.refreshed.IsZero() {
+		s.others[left] = &backgroundRegion{
+			client:    client,
+			instances: instances,
+			refreshed: s.refreshed,
+			displayed: time.Now(),
+		}
+	}
+
+	s.regions = make(map[string]*backgroundRegion)
+	for ws, r := range s.others {
+		if ws.profile != entered.profile {
+			continue
+		}
+		delete(s.others, ws)
+		if ws.region != entered.region {
+			s.regions[ws.region] = r
+		}
+	}
+	s.refreshed = time.Time{}
+}
+
+// cached returns the instances of the regions refreshed in the background,
+// of the profile in use and of the other ones, the stalest last
+func (s *regionScheduler) cached(profile string) []cachedInstances {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cached []cachedInstances
+	add := func(ws workspace, r *backgroundRegion) {
+		if !r.refreshed.IsZero() {
+			cached = append(cached, cachedInstances{workspace: ws, instances: r.instances, refreshed: r.refreshed})
+		}
+	}
+	for name, r := range s.regions {
+		add(workspace{profile: profile, region: name}, r)
+	}
+	for ws, r := range s.others {
+		add(ws, r)
+	}
+	sort.Slice(cached, func(i, j int) bool {
+		return cached[i].refreshed.After(cached[j].refreshed)
+	})
+	return cached
+}
+
+// backgroundRegionsEnabled returns whether the regions displayed before are
+// refreshed in the background: not with several accounts, whose clients are
+// per region
+func (ui *UI) backgroundRegionsEnabled() bool {
+	return ui.config().AWS.BackgroundRegions > 0 && len(ui.config().Accounts) == 0
+}
+
+// refreshBackgroundRegion refreshes the background region refreshed the
+// longest ago, if one is due. A single region is refreshed per tick of the
+// auto-refresh, to spread the calls.
+func (ui *UI) refreshBackgroundRegion() {
+	if !ui.backgroundRegionsEnabled() {
+		return
+	}
+	region, client, ok := ui.regions.next(ui.refresh, time.Now())
+	if !ok {
+		return
+	}
+
+	filters := ui.serverFilters()
+	ctx, end := ui.operationCtx("refresh background region", map[string]string{"cloud.region": region})
+	start := time.Now()
+	go func() {
+		ui.log.Debug("Refreshing background region", "region", region)
+		instances, err := client.ListInstances(ctx, filters)
+		ui.exporter.Metrics().RecordRefresh(region, time.Since(start), err)
+		end(err)
+		if err != nil {
+			ui.log.Warn("Failed to refresh background region", "region", region, "error", err)
+		}
+		ui.regions.done(region, instances, err)
+	}()
+}
+
+// enterRegion keeps the region left refreshed in the background, and
+// displays the instances of the region entered refreshed in the background,
+// if any, until they are refreshed. It returns the status describing them,
+// empty if none.
+func (ui *UI) enterRegion(left string, client *aws.EC2Client, instances []model.Instance, entered string) string {
+	if !ui.backgroundRegionsEnabled() {
+		return ""
+	}
+	ui.regions.leave(left, client, instances, ui.config().AWS.BackgroundRegions)
+
+	cached, ok := ui.regions.enter(entered)
+	if !ok {
+		return ""
+	}
+	ui.store.Dispatch(store.InstancesLoaded{Instances: cached.instances, Page: 1, Session: ui.active.Load().id})
+	return fmt.Sprintf("Instances of %s as of %s ago, refreshing...",
+		entered, time.Since(cached.refreshed).Round(time.Second))
+}
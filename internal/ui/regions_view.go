@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+const (
+	// regionProbes is the number of regions probed at once
+	regionProbes = 4
+
+	// regionFastLatency and regionSlowLatency are the round trips below
+	// which a region is displayed as fast, or as acceptable
+	regionFastLatency = 100 * time.Millisecond
+	regionSlowLatency = 300 * time.Millisecond
+)
+
+// defaultRegions are the regions probed when they cannot be listed, e.g.
+// without the ec2:DescribeRegions permission
+var defaultRegions = []string{
+	"ap-northeast-1", "ap-northeast-2", "ap-northeast-3", "ap-south-1",
+	"ap-southeast-1", "ap-southeast-2", "ca-central-1", "eu-central-1",
+	"eu-north-1", "eu-west-1", "eu-west-2", "eu-west-3", "sa-east-1",
+	"us-east-1", "us-east-2", "us-west-1", "us-west-2",
+}
+
+// RegionsView measures and displays the latency of the EC2 endpoint of each
+// region, to pick the closest one and spot a slow VPN or proxy
+type RegionsView struct {
+	ui      *UI
+	table   *tview.Table
+	page    tview.Primitive              // Page displayed, to stop probing once closed
+	regions []string                     // Regions probed
+	results map[string]aws.RegionLatency // Latency of the regions probed so far
+	rows    []string                     // Regions of the rows, sorted by latency
+	cancel  context.CancelFunc           // Cancels the running probe, nil if none
+}
+
+// runRegionsCommand runs the regions command, showing the latency of the
+// regions
+func (ui *UI) runRegionsCommand(args []string) error {
+	if len(args) != 0 {
+		return errors.New("no argument expected")
+	}
+	NewRegionsView(ui).Show()
+	return nil
+}
+
+// NewRegionsView creates a new regions view
+func NewRegionsView(ui *UI) *RegionsView {
+	v := &RegionsView{
+		ui:      ui,
+		table:   tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		results: make(map[string]aws.RegionLatency),
+	}
+
+	v.table.SetBorder(true).
+		SetTitle(" Regions latency ").
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	// Switch to the selected region
+	v.table.SetSelectedFunc(func(row, column int) {
+		if row <= 0 || row-1 >= len(v.rows) {
+			return
+		}
+		v.close()
+		if err := ui.switchRegion(v.rows[row-1]); err != nil {
+			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		}
+	})
+
+	v.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'r' {
+			v.probe()
+			return nil
+		}
+		return event
+	})
+
+	v.table.SetCell(0, 0, tview.NewTableCell(" Listing the regions...").SetSelectable(false))
+
+	return v
+}
+
+// Show displays the regions view and probes the regions
+func (v *RegionsView) Show() {
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(v.table, 80, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	v.page = v.ui.showNavPage(viewRegions, flex, v.stop)
+	v.probe()
+}
+
+// close closes the view, stopping the probe
+func (v *RegionsView) close() {
+	v.ui.pages.RemovePage("modal")
+	v.ui.nav.Pop()
+	v.stop()
+}
+
+// stop stops the probe
+func (v *RegionsView) stop() {
+	if v.cancel != nil {
+		v.cancel()
+	}
+}
+
+// probe measures the latency of the regions again, a few at a time, and
+// renders them as they are measured
+func (v *RegionsView) probe() {
+	if v.cancel != nil {
+		v.cancel()
+	}
+	ctx, cancel := context.WithCancel(v.ui.ctx)
+	v.cancel = cancel
+	v.results = make(map[string]aws.RegionLatency)
+	v.ui.statusBar.SetStatus("Probing the regions...")
+
+	client := v.ui.ec2Client()
+	go func() {
+		regions, err := client.ListRegions(ctx)
+		if err != nil {
+			v.ui.log.Warn("Failed to list regions, probing the default ones", "error", err)
+			regions = defaultRegions
+		}
+		v.ui.app.QueueUpdateDraw(func() {
+			v.regions = regions
+			v.render()
+		})
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, regionProbes)
+		for _, region := range regions {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				result := client.ProbeRegion(ctx, region)
+				if ctx.Err() != nil {
+					return
+				}
+				v.ui.app.QueueUpdateDraw(func() {
+					// Stop probing once the view is closed
+					if _, front := v.ui.pages.GetFrontPage(); front != v.page {
+						cancel()
+						return
+					}
+					v.results[region] = result
+					v.render()
+				})
+			}()
+		}
+		wg.Wait()
+	}()
+}
+
+// render fills the table with the regions, the fastest first, then those
+// which failed and those not probed yet
+func (v *RegionsView) render() {
+	v.table.Clear()
+
+	for i, header := range []string{"Region", "Round Trip", "Connect", "Status"} {
+		v.table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	v.rows = append(v.rows[:0], v.regions...)
+	rank := func(region string) int {
+		result, ok := v.results[region]
+		switch {
+		case !ok:
+			return 2
+		case result.Err != nil:
+			return 1
+		default:
+			return 0
+		}
+	}
+	sort.SliceStable(v.rows, func(i, j int) bool {
+		ri, rj := rank(v.rows[i]), rank(v.rows[j])
+		if ri != rj || ri != 0 {
+			return ri < rj
+		}
+		return v.results[v.rows[i]].RoundTrip < v.results[v.rows[j]].RoundTrip
+	})
+
+	current := v.ui.ec2Client().GetRegion()
+	for i, region := range v.rows {
+		row := i + 1
+		name := " " + region + " "
+		if region == current {
+			name = "*" + region + " "
+		}
+		v.table.SetCell(row, 0, tview.NewTableCell(name).SetTextColor(color.AppColors.Highlight).SetAttributes(tcell.AttrBold))
+
+		result, ok := v.results[region]
+		switch {
+		case !ok:
+			v.table.SetCell(row, 1, tview.NewTableCell(" - ").SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 2, tview.NewTableCell(" - ").SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 3, tview.NewTableCell(" probing... ").SetTextColor(color.AppColors.Secondary).SetExpansion(1))
+		case result.Err != nil:
+			v.table.SetCell(row, 1, tview.NewTableCell(" - ").SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 2, tview.NewTableCell(" - ").SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 3, tview.NewTableCell(" "+result.Err.Error()+" ").SetTextColor(color.AppColors.Error).SetExpansion(1))
+		default:
+			v.table.SetCell(row, 1, tview.NewTableCell(" "+formatLatency(result.RoundTrip)+" ").SetTextColor(latencyColor(result.RoundTrip)).SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 2, tview.NewTableCell(" "+formatLatency(result.Connect)+" ").SetTextColor(color.AppColors.Foreground).SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 3, tview.NewTableCell(" ok ").SetTextColor(color.AppColors.Running).SetExpansion(1))
+		}
+	}
+
+	if len(v.results) == len(v.regions) && len(v.regions) > 0 {
+		fastest := "none reachable"
+		if rank(v.rows[0]) == 0 {
+			fastest = "fastest " + v.rows[0]
+		}
+		v.ui.statusBar.SetStatus(fmt.Sprintf("%d regions probed, %s, Enter: switch region, r: probe again", len(v.regions), fastest))
+	}
+}
+
+// latencyColor returns the color of a round trip, from fast to slow
+func latencyColor(latency time.Duration) tcell.Color {
+	switch {
+	case latency < regionFastLatency:
+		return color.AppColors.Running
+	case latency < regionSlowLatency:
+		return color.AppColors.Pending
+	default:
+		return color.AppColors.Error
+	}
+}
+
+// switchRegion loads the instances of another region, with the profile in
+// use
+func (ui *UI) switchRegion(region string) error {
+	ui.statusBar.SetStatus(fmt.Sprintf("Switching to region %s...", region))
+	previous := ui.ec2Client()
+	instances := ui.store.Snapshot().Instances
+	if err := ui.switchClient(ui.config().AWS.Profile, region); err != nil {
+		return err
+	}
+	cached := ui.enterRegion(previous.GetRegion(), previous, instances, region)
+
+	ui.statusBar.SetRegion(region)
+	ui.pages.RemovePage("error")
+	ui.RefreshInstances()
+	if cached != "" {
+		ui.statusBar.SetStatus(cached)
+	}
+	return nil
+}
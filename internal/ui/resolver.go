@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// resolveTTL is how long the names of the resources are cached, the AMIs,
+// subnets and key pairs rarely being renamed
+const resolveTTL = 10 * time.Minute
+
+// resolveKey is a resource of an account and region, by kind and ID
+type resolveKey struct {
+	client *aws.EC2Client
+	kind   string
+	id     string
+}
+
+// resolvedName is the name of a resource, or the error describing it
+type resolvedName struct {
+	name       string
+	found      bool // False if the resource does not exist anymore
+	err        error
+	resolvedAt time.Time
+}
+
+// nameResolver resolves the names of the resources referenced by ID by the
+// instances, for the table, the details and the replacements: each ID is
+// described at most once per TTL across the views. The names are cached,
+// the IDs being described are not described again, and the IDs looked up
+// during a draw are described in a single call per client and kind. It is
+// only accessed from the UI goroutine.
+type nameResolver struct {
+	ui       *UI
+	ttl      time.Duration
+	names    map[resolveKey]resolvedName
+	pending  map[resolveKey]bool // To describe on the next flush
+	inFlight map[resolveKey]bool // Being described
+	waiters  map[any]func()      // Views to render again once names are resolved, by view
+	queued   bool                // A flush is queued
+}
+
+// newNameResolver creates a resolver caching the names for the given TTL
+func newNameResolver(ui *UI, ttl time.Duration) *nameResolver {
+	return &nameResolver{
+		ui:       ui,
+		ttl:      ttl,
+		names:    make(map[resolveKey]resolvedName),
+		pending:  make(map[resolveKey]bool),
+		inFlight: make(map[resolveKey]bool),
+		waiters:  make(map[any]func()),
+	}
+}
+
+// lookup returns the name of a resource if it is resolved and has not
+// expired. Otherwise the resource is described, and onResolved, if any, is
+// called once per view once the names being described are resolved.
+func (r *nameResolver) lookup(view any, onResolved func(), client *aws.EC2Client, kind, id string) (resolvedName, bool) {
+	if id == "" {
+		return resolvedName{}, false
+	}
+	key := resolveKey{client: client, kind: kind, id: id}
+	if name, ok := r.names[key]; ok && time.Since(name.resolvedAt) <= r.ttl {
+		return name, true
+	}
+
+	if onResolved != nil {
+		r.waiters[view] = onResolved
+	}
+	if r.inFlight[key] || r.pending[key] {
+		return resolvedName{}, false
+	}
+	r.pending[key] = true
+	if !r.queued {
+		// Flush once the current draw looked up all its names
+		r.queued = true
+		go r.ui.app.QueueUpdate(r.flush)
+	}
+	return resolvedName{}, false
+}
+
+// flush describes the pending resources, in one call per client and kind
+func (r *nameResolver) flush() {
+	r.queued = false
+	batches := make(map[resolveKey][]string)
+	for key := range r.pending {
+		batch := resolveKey{client: key.client, kind: key.kind}
+		batches[batch] = append(batches[batch], key.id)
+		r.inFlight[key] = true
+	}
+	r.pending = make(map[resolveKey]bool)
+
+	for batch, ids := range batches {
+		go r.describe(batch.client, batch.kind, ids)
+	}
+}
+
+// describe describes resources of a kind, and stores their names
+func (r *nameResolver) describe(client *aws.EC2Client, kind string, ids []string) {
+	names, err := client.DescribeNames(r.ui.ctx, kind, ids)
+	if err != nil {
+		r.ui.log.Warn("Failed to resolve resource names", "kind", kind, "count", len(ids), "error", err)
+	}
+	resolvedAt := time.Now()
+
+	r.ui.app.QueueUpdateDraw(func() {
+		for _, id := range ids {
+			key := resolveKey{client: client, kind: kind, id: id}
+			name, found := names[id]
+			r.names[key] = resolvedName{name: name, found: found, err: err, resolvedAt: resolvedAt}
+			delete(r.inFlight, key)
+		}
+
+		waiters := r.waiters
+		r.waiters = make(map[any]func())
+		for _, onResolved := range waiters {
+			onResolved()
+		}
+	})
+}
+
+// resourceLabel returns the ID of a resource followed by its name once
+// resolved, or by missing if it does not exist anymore
+func (r *nameResolver) resourceLabel(view any, onResolved func(), client *aws.EC2Client, kind, id, missing string) string {
+	name, ok := r.lookup(view, onResolved, client, kind, id)
+	switch {
+	case !ok, name.err != nil:
+		return id
+	case !name.found:
+		return id + " (" + missing + ")"
+	case name.name == "":
+		return id
+	default:
+		return id + " (" + name.name + ")"
+	}
+}
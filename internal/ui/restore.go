@@ -0,0 +1,521 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// restoreTimeout is the maximum time waited for a volume or the instance to
+// reach the state expected by a step of a restore
+const restoreTimeout = 10 * time.Minute
+
+// Status of the steps of a restore
+const (
+	stepPending = "pending"
+	stepRunning = "running"
+	stepDone    = "done"
+	stepFailed  = "failed"
+	stepSkipped = "skipped"
+)
+
+// restoreStep is a step of the restore of a root volume
+type restoreStep struct {
+	name   string
+	run    func(ctx context.Context) error
+	status string
+	err    error
+}
+
+// snapshotRestore replaces the root volume of an instance with a volume
+// created from a snapshot. The original volume is kept, detached, so that it
+// can be attached again if the restored one is not the expected one.
+type snapshotRestore struct {
+	ui       *UI
+	instance model.Instance
+	snapshot model.Snapshot
+	device   string // Root device name
+	original string // ID of the original root volume
+	running  bool   // The instance was running before the restore
+	total    int    // Number of steps to run, the start being skipped if not running
+	started  time.Time
+
+	mutex    sync.Mutex
+	steps    []*restoreStep
+	rollback []*restoreStep // Steps undoing the restore after a failure or an abort
+	volume   string         // ID of the volume created from the snapshot
+	stopped  bool           // The instance was stopped by the restore
+	detached bool           // The original volume was detached
+	attached bool           // The restored volume was attached
+	finished bool
+	view     *tview.TextView
+}
+
+// handleRestoreSnapshot lists the snapshots of the root volume of the
+// selected instance to restore one of them
+func (ui *UI) handleRestoreSnapshot() {
+	selected := ui.instancesView.GetSelectedInstance()
+	if selected == nil {
+		ui.statusBar.SetError("No instance selected")
+		return
+	}
+	instance := *selected
+
+	volumeID := instance.RootVolume()
+	if volumeID == "" {
+		ui.statusBar.SetError(fmt.Sprintf("Instance %s has no EBS root volume", instance.DisplayName()))
+		return
+	}
+	if ui.batchCancel != nil {
+		ui.statusBar.SetError("A batch is already running, press X to cancel it")
+		return
+	}
+
+	ui.statusBar.SetStatus(fmt.Sprintf("Listing the snapshots of %s...", volumeID))
+
+	ctx := ui.actionCtx()
+	go func() {
+		snapshots, err := ui.clientFor(instance).ListSnapshots(ctx, volumeID)
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.log.Error("Failed to list snapshots", "volumeID", volumeID, "error", err)
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			if len(snapshots) == 0 {
+				ui.statusBar.SetError(fmt.Sprintf("No completed snapshot of the root volume %s", volumeID))
+				return
+			}
+			ui.statusBar.SetStatus(fmt.Sprintf("%d snapshots of %s", len(snapshots), volumeID))
+			ui.showSnapshotPicker(instance, volumeID, snapshots)
+		})
+	}()
+}
+
+// showSnapshotPicker displays the snapshots of the root volume, the most
+// recent first, and confirms the restore of the selected one
+func (ui *UI) showSnapshotPicker(instance model.Instance, volumeID string, snapshots []model.Snapshot) {
+	table := tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	for i, header := range []string{"Snapshot", "Started", "Size", "Description"} {
+		table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+	for i, snapshot := range snapshots {
+		table.SetCell(i+1, 0, tview.NewTableCell(" "+snapshot.ID+" ").SetTextColor(color.AppColors.Highlight))
+		table.SetCell(i+1, 1, tview.NewTableCell(" "+ui.formatTime(snapshot.StartTime)+" ").SetTextColor(color.AppColors.Foreground))
+		table.SetCell(i+1, 2, tview.NewTableCell(fmt.Sprintf(" %d GiB ", snapshot.Size)).SetTextColor(color.AppColors.Foreground).SetAlign(tview.AlignRight))
+		table.SetCell(i+1, 3, tview.NewTableCell(" "+snapshot.Description+" ").SetTextColor(color.AppColors.Secondary).SetExpansion(1))
+	}
+	table.Select(1, 0)
+
+	table.SetSelectedFunc(func(row, column int) {
+		if row <= 0 || row-1 >= len(snapshots) {
+			return
+		}
+		snapshot := snapshots[row-1]
+		ui.pages.RemovePage("modal")
+
+		message := fmt.Sprintf("Restore the root volume of %s from %s (%s)?\n\n"+
+			"The instance is stopped, its root volume %s is replaced with a new volume created from the snapshot, and the instance is started again. "+
+			"The original volume is kept, detached.",
+			instance.DisplayName(), snapshot.ID, ui.formatTime(snapshot.StartTime), volumeID)
+		ui.confirmDestructiveOn(instance, "stop", "Restore Root Volume", ui.withNotice(message, instance), instanceConfirmValues(instance), func() {
+			ui.executeRestore(instance, volumeID, snapshot)
+		})
+	})
+
+	table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Snapshots of %s (%s) - Enter: restore ", volumeID, instance.DisplayName())).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(table, 110, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// executeRestore runs the steps of the restore in the background, and shows
+// their progress. The restore can be aborted with a, or X like a batch.
+func (ui *UI) executeRestore(instance model.Instance, volumeID string, snapshot model.Snapshot) {
+	if ui.batchCancel != nil {
+		ui.statusBar.SetError("A batch is already running, press X to cancel it")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ui.ctx)
+	ui.batchCancel = cancel
+
+	r := &snapshotRestore{
+		ui:       ui,
+		instance: instance,
+		snapshot: snapshot,
+		device:   instance.RootDeviceName,
+		original: volumeID,
+		running:  instance.IsRunning(),
+		started:  time.Now(),
+	}
+	r.steps = r.plan()
+	for _, step := range r.steps {
+		if step.status != stepSkipped {
+			r.total++
+		}
+	}
+	r.show(cancel)
+	ui.statusBar.SetProgress("Restore", 0, 0, r.total)
+
+	go func() {
+		defer cancel()
+		r.run(ctx)
+	}()
+}
+
+// plan returns the steps of the restore
+func (r *snapshotRestore) plan() []*restoreStep {
+	client := r.ui.clientFor(r.instance)
+	id := r.instance.ID
+
+	steps := []*restoreStep{
+		{
+			name: fmt.Sprintf("Create a volume from %s", r.snapshot.ID),
+			run: func(ctx context.Context) error {
+				source, err := client.GetVolume(ctx, r.original)
+				if err != nil {
+					return err
+				}
+				volume, err := client.CreateVolumeFromSnapshot(ctx, id, r.snapshot.ID, source)
+				if err != nil {
+					return err
+				}
+				r.set(func() { r.volume = volume })
+				return client.WaitVolumeAvailable(ctx, volume, restoreTimeout)
+			},
+		},
+		{
+			name: "Stop the instance",
+			run: func(ctx context.Context) error {
+				if r.instance.State == "running" {
+					if err := client.StopInstance(ctx, id); err != nil {
+						return err
+					}
+					r.set(func() { r.stopped = true })
+				}
+				return client.WaitInstancesStopped(ctx, []string{id}, restoreTimeout)
+			},
+		},
+		{
+			name: fmt.Sprintf("Detach the root volume %s", r.original),
+			run: func(ctx context.Context) error {
+				if err := client.DetachVolume(ctx, id, r.original); err != nil {
+					return err
+				}
+				r.set(func() { r.detached = true })
+				return client.WaitVolumeAvailable(ctx, r.original, restoreTimeout)
+			},
+		},
+		{
+			name: fmt.Sprintf("Attach the restored volume as %s", r.device),
+			run: func(ctx context.Context) error {
+				volume := r.get(func() string { return r.volume })
+				if err := client.AttachVolume(ctx, id, volume, r.device); err != nil {
+					return err
+				}
+				r.set(func() { r.attached = true })
+				return client.WaitVolumeInUse(ctx, volume, restoreTimeout)
+			},
+		},
+		{
+			name: "Start the instance",
+			run: func(ctx context.Context) error {
+				if err := client.StartInstance(ctx, id); err != nil {
+					return err
+				}
+				return client.WaitInstancesRunning(ctx, []string{id}, restoreTimeout)
+			},
+		},
+	}
+
+	for _, step := range steps {
+		step.status = stepPending
+	}
+	if !r.running {
+		// A stopped instance is left stopped
+		steps[len(steps)-1].status = stepSkipped
+	}
+	return steps
+}
+
+// planRollback returns the steps undoing what the restore did so far: the
+// original volume is attached again, the restored one deleted, and the
+// instance started again if the restore stopped it. Once the restored volume
+// is attached, it is kept and the instance is only started again.
+func (r *snapshotRestore) planRollback() []*restoreStep {
+	client := r.ui.clientFor(r.instance)
+	id := r.instance.ID
+
+	var steps []*restoreStep
+	if r.detached && !r.attached {
+		steps = append(steps, &restoreStep{
+			name: fmt.Sprintf("Attach the original volume %s as %s", r.original, r.device),
+			run: func(ctx context.Context) error {
+				if err := client.WaitVolumeAvailable(ctx, r.original, restoreTimeout); err != nil {
+					return err
+				}
+				if err := client.AttachVolume(ctx, id, r.original, r.device); err != nil {
+					return err
+				}
+				return client.WaitVolumeInUse(ctx, r.original, restoreTimeout)
+			},
+		})
+	}
+	if r.volume != "" && !r.attached {
+		volume := r.volume
+		steps = append(steps, &restoreStep{
+			name: fmt.Sprintf("Delete the restored volume %s", volume),
+			run: func(ctx context.Context) error {
+				if err := client.WaitVolumeAvailable(ctx, volume, restoreTimeout); err != nil {
+					return err
+				}
+				return client.DeleteVolume(ctx, id, volume)
+			},
+		})
+	}
+	if r.stopped {
+		// The restore may have been aborted while the restored volume was
+		// being attached, the instance starts once it is in use
+		attached := ""
+		if r.attached {
+			attached = r.volume
+		}
+		steps = append(steps, &restoreStep{
+			name: "Start the instance again",
+			run: func(ctx context.Context) error {
+				if attached != "" {
+					if err := client.WaitVolumeInUse(ctx, attached, restoreTimeout); err != nil {
+						return err
+					}
+				}
+				if err := client.StartInstance(ctx, id); err != nil {
+					return err
+				}
+				return client.WaitInstancesRunning(ctx, []string{id}, restoreTimeout)
+			},
+		})
+	}
+
+	for _, step := range steps {
+		step.status = stepPending
+	}
+	return steps
+}
+
+// run runs the steps in order, and rolls the restore back at the first
+// failure or once aborted
+func (r *snapshotRestore) run(ctx context.Context) {
+	failed := r.runSteps(ctx, r.steps)
+	if failed != nil {
+		r.ui.log.Error("Restore failed", "instanceID", r.instance.ID, "snapshotID", r.snapshot.ID, "error", failed)
+
+		// The rollback is not cancelled by the abort, only when e2c exits
+		r.mutex.Lock()
+		r.rollback = r.planRollback()
+		r.mutex.Unlock()
+		if err := r.runSteps(r.ui.ctx, r.rollback); err != nil {
+			r.ui.log.Error("Restore rollback failed", "instanceID", r.instance.ID, "error", err)
+		}
+	}
+
+	r.set(func() { r.finished = true })
+	r.ui.app.QueueUpdateDraw(func() {
+		r.ui.batchCancel = nil
+		r.ui.statusBar.ClearProgress()
+		switch {
+		case ctx.Err() != nil:
+			r.ui.statusBar.SetError(fmt.Sprintf("Restore of %s aborted", r.instance.DisplayName()))
+		case failed != nil:
+			r.ui.statusBar.SetError(fmt.Sprintf("Error: %v", failed))
+			r.ui.notifyDone(r.started, fmt.Sprintf("Restore of %s failed", r.instance.DisplayName()), true)
+		default:
+			r.ui.statusBar.SetStatus(fmt.Sprintf("Restored %s from %s, the original volume %s is detached", r.instance.DisplayName(), r.snapshot.ID, r.original))
+			r.ui.notifyDone(r.started, fmt.Sprintf("Restored %s from %s", r.instance.DisplayName(), r.snapshot.ID), false)
+		}
+		r.render()
+		r.ui.RefreshInstances()
+	})
+}
+
+// runSteps runs steps in order until one fails, and returns its error
+func (r *snapshotRestore) runSteps(ctx context.Context, steps []*restoreStep) error {
+	for i, step := range steps {
+		if step.status == stepSkipped {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			r.skip(steps[i:])
+			return err
+		}
+
+		r.update(step, stepRunning, nil)
+		if err := step.run(ctx); err != nil {
+			r.update(step, stepFailed, err)
+			r.skip(steps[i+1:])
+			return err
+		}
+		r.update(step, stepDone, nil)
+	}
+	return nil
+}
+
+// skip marks the steps which were not run as skipped
+func (r *snapshotRestore) skip(steps []*restoreStep) {
+	r.mutex.Lock()
+	for _, step := range steps {
+		if step.status == stepPending {
+			step.status = stepSkipped
+		}
+	}
+	r.mutex.Unlock()
+}
+
+// update sets the status of a step and renders the progress of the steps
+// being run: the ones of the restore, or of its rollback
+func (r *snapshotRestore) update(step *restoreStep, status string, err error) {
+	r.mutex.Lock()
+	step.status = status
+	step.err = err
+
+	label, steps, total := "Restore", r.steps, r.total
+	if slices.Contains(r.rollback, step) {
+		label, steps, total = "Rollback", r.rollback, len(r.rollback)
+	}
+	done := 0
+	for _, s := range steps {
+		if s.status == stepDone {
+			done++
+		}
+	}
+	r.mutex.Unlock()
+
+	r.ui.app.QueueUpdateDraw(func() {
+		r.ui.statusBar.SetProgress(label, done, 0, total)
+		r.render()
+	})
+}
+
+// set changes the state of the restore under its lock
+func (r *snapshotRestore) set(change func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	change()
+}
+
+// get reads the state of the restore under its lock
+func (r *snapshotRestore) get(read func() string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return read()
+}
+
+// show displays the progress view of the restore
+func (r *snapshotRestore) show(abort context.CancelFunc) {
+	r.view = tview.NewTextView().SetDynamicColors(true).SetWrap(true)
+	r.view.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Restore %s from %s ", r.instance.DisplayName(), r.snapshot.ID)).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	r.view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'a' {
+			if !r.isFinished() {
+				abort()
+				r.ui.statusBar.SetStatus("Aborting the restore...")
+			}
+			return nil
+		}
+		return event
+	})
+	r.render()
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(r.view, 20, 1, true).
+			AddItem(nil, 0, 1, false), 90, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	r.ui.pages.AddPage("modal", flex, true, true)
+}
+
+// isFinished returns true once the restore and its rollback are done
+func (r *snapshotRestore) isFinished() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.finished
+}
+
+// render displays the status of the steps of the restore and of its rollback
+func (r *snapshotRestore) render() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var b strings.Builder
+	b.WriteString("\n")
+	writeRestoreSteps(&b, r.steps)
+	if len(r.rollback) > 0 {
+		b.WriteString("\n [yellow]Rollback[-]\n\n")
+		writeRestoreSteps(&b, r.rollback)
+	}
+
+	b.WriteString("\n")
+	switch {
+	case !r.finished:
+		b.WriteString(" [yellow]a[-]: abort and roll back   [yellow]Esc[-]: hide (the restore continues)\n")
+	case r.attached:
+		fmt.Fprintf(&b, " The original volume [yellow]%s[-] is detached, delete it once the restored volume is checked.\n", r.original)
+		b.WriteString(" [yellow]Esc[-]: close\n")
+	default:
+		b.WriteString(" [yellow]Esc[-]: close\n")
+	}
+
+	r.view.SetText(b.String())
+}
+
+// writeRestoreSteps writes a line for each step with its status
+func writeRestoreSteps(b *strings.Builder, steps []*restoreStep) {
+	for _, step := range steps {
+		switch step.status {
+		case stepRunning:
+			fmt.Fprintf(b, " [yellow]▶ %s...[-]\n", step.name)
+		case stepDone:
+			fmt.Fprintf(b, " [green]✓[-] %s\n", step.name)
+		case stepFailed:
+			fmt.Fprintf(b, " [red]✗ %s[-]\n   [red]%v[-]\n", step.name, step.err)
+		case stepSkipped:
+			fmt.Fprintf(b, " [gray]- %s (skipped)[-]\n", step.name)
+		default:
+			fmt.Fprintf(b, " [gray]· %s[-]\n", step.name)
+		}
+	}
+}
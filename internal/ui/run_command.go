@@ -0,0 +1,326 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// RunCommandView runs a shell command on instances with SSM Run Command and
+// displays the status of each instance, and the output of the selected one
+// as the instances complete
+type RunCommandView struct {
+	ui          *UI
+	command     string
+	instances   []model.Instance
+	invocations map[string]model.CommandInvocation // Latest state, by instance
+	errors      map[string]error                   // Errors sending or checking the command, by instance
+	table       *tview.Table
+	output      *tview.TextView
+	layout      *tview.Flex
+	cancel      context.CancelFunc // Stops checking the commands
+	started     time.Time
+}
+
+// runRunCommand runs the run command, running a shell command on the marked
+// instances, or the selected one
+func (ui *UI) runRunCommand(args []string) error {
+	if len(args) == 0 {
+		ui.handleRunCommand()
+		return nil
+	}
+	instances, err := ui.runCommandTargets()
+	if err != nil {
+		return err
+	}
+	NewRunCommandView(ui, instances, strings.Join(args, " ")).Show()
+	return nil
+}
+
+// handleRunCommand asks for the shell command to run on the marked
+// instances, or the selected one
+func (ui *UI) handleRunCommand() {
+	instances, err := ui.runCommandTargets()
+	if err != nil {
+		ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("Command:", "", 50, nil, nil)
+	form.AddButton("Run", func() {
+		command := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		ui.pages.RemovePage("modal")
+		if command == "" {
+			ui.statusBar.SetError("Error: no command given")
+			return
+		}
+		NewRunCommandView(ui, instances, command).Show()
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	target := instances[0].DisplayName()
+	if len(instances) > 1 {
+		target = fmt.Sprintf("%d instances", len(instances))
+	}
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Run a command on %s (SSM)", target))
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 70, 1, true).
+			AddItem(nil, 0, 1, false), 7, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// runCommandTargets returns the instances a command is run on, the marked
+// ones or the selected one
+func (ui *UI) runCommandTargets() ([]model.Instance, error) {
+	if marked := ui.instancesView.GetMarkedInstances(); len(marked) > 0 {
+		return marked, nil
+	}
+	if selected := ui.instancesView.GetSelectedInstance(); selected != nil {
+		return []model.Instance{*selected}, nil
+	}
+	return nil, errors.New("no instance selected")
+}
+
+// NewRunCommandView creates the view running a command on instances
+func NewRunCommandView(ui *UI, instances []model.Instance, command string) *RunCommandView {
+	v := &RunCommandView{
+		ui:          ui,
+		command:     command,
+		instances:   instances,
+		invocations: make(map[string]model.CommandInvocation),
+		errors:      make(map[string]error),
+		table:       tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		output: tview.NewTextView().
+			SetDynamicColors(true).
+			SetScrollable(true),
+	}
+
+	v.table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Run Command: %s ", tview.Escape(command))).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+	v.output.SetBorder(true).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	v.table.SetSelectionChangedFunc(func(row, column int) {
+		v.renderOutput()
+	})
+	// Tab moves the focus to the output, to scroll it
+	v.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			ui.app.SetFocus(v.output)
+			return nil
+		}
+		return event
+	})
+	v.output.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			ui.app.SetFocus(v.table)
+			return nil
+		}
+		return event
+	})
+
+	v.layout = tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(v.table, min(len(instances)+3, 12), 0, true).
+				AddItem(v.output, 0, 1, false), 0, 8, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	return v
+}
+
+// Show displays the view and runs the command
+func (v *RunCommandView) Show() {
+	v.render()
+	v.table.Select(1, 0)
+	v.ui.pages.AddPage("modal", v.layout, true, true)
+	v.ui.statusBar.SetStatus(fmt.Sprintf("Running %q on %d instances, Tab: switch to the output", v.command, len(v.instances)))
+	v.ui.log.Info("Running command", "command", v.command, "instances", len(v.instances))
+
+	ctx, cancel := context.WithCancel(v.ui.actionCtx())
+	v.cancel = cancel
+	v.started = time.Now()
+	go v.run(ctx)
+}
+
+// run sends the command, once per account and platform, and checks each
+// instance until the command is done on all of them
+func (v *RunCommandView) run(ctx context.Context) {
+	type target struct {
+		client  *aws.EC2Client
+		windows bool
+	}
+	targets := make(map[target][]string)
+	for _, instance := range v.instances {
+		key := target{v.ui.clientFor(instance), strings.Contains(instance.Platform, "Windows")}
+		targets[key] = append(targets[key], instance.ID)
+	}
+
+	var wg sync.WaitGroup
+	for key, ids := range targets {
+		commandID, err := key.client.SendCommand(ctx, ids, v.command, key.windows)
+		if err != nil {
+			v.ui.log.Error("Failed to send command", "error", err)
+			v.update(func() {
+				for _, id := range ids {
+					v.errors[id] = err
+				}
+			})
+			continue
+		}
+
+		for _, id := range ids {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := key.client.WaitCommandInvocation(ctx, commandID, id, func(invocation model.CommandInvocation) {
+					v.update(func() {
+						v.invocations[id] = invocation
+					})
+				})
+				if err != nil && ctx.Err() == nil {
+					v.ui.log.Error("Failed to check command", "instanceID", id, "error", err)
+					v.update(func() {
+						v.errors[id] = err
+					})
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if ctx.Err() == nil {
+		v.update(v.renderSummary)
+	}
+}
+
+// update changes the state of the view from the UI goroutine and renders
+// it, unless the view was closed, which stops checking the commands
+func (v *RunCommandView) update(change func()) {
+	v.ui.app.QueueUpdateDraw(func() {
+		if _, front := v.ui.pages.GetFrontPage(); front != v.layout {
+			v.cancel()
+			return
+		}
+		change()
+		v.render()
+	})
+}
+
+// render fills the table with the status of the command on each instance,
+// and the output of the selected one
+func (v *RunCommandView) render() {
+	for i, header := range []string{"Instance", "ID", "Status", "Exit"} {
+		v.table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	for i, instance := range v.instances {
+		row := i + 1
+		status, statusColor, exit := v.status(instance.ID)
+		v.table.SetCell(row, 0, tview.NewTableCell(" "+instance.DisplayName()+" ").SetTextColor(color.AppColors.Highlight))
+		v.table.SetCell(row, 1, tview.NewTableCell(" "+instance.ID+" ").SetTextColor(color.AppColors.Foreground))
+		v.table.SetCell(row, 2, tview.NewTableCell(" "+status+" ").SetTextColor(statusColor).SetExpansion(1))
+		v.table.SetCell(row, 3, tview.NewTableCell(" "+exit+" ").SetTextColor(statusColor).SetAlign(tview.AlignRight))
+	}
+
+	v.renderOutput()
+}
+
+// status returns the status of the command on an instance, its color and
+// the exit code once known
+func (v *RunCommandView) status(id string) (string, tcell.Color, string) {
+	if err, ok := v.errors[id]; ok {
+		return "❌ " + err.Error(), color.AppColors.Error, ""
+	}
+	invocation, ok := v.invocations[id]
+	switch {
+	case !ok:
+		return "sending...", color.AppColors.Secondary, ""
+	case !invocation.Done():
+		return "⏳ " + invocation.Status, color.AppColors.Pending, ""
+	case invocation.Status == model.CommandSuccess:
+		return "✅ " + invocation.Status, color.AppColors.Running, strconv.Itoa(invocation.ExitCode)
+	default:
+		return "❌ " + valueOrDefault(invocation.StatusDetail, invocation.Status), color.AppColors.Error, strconv.Itoa(invocation.ExitCode)
+	}
+}
+
+// renderOutput displays the output of the command on the selected instance
+func (v *RunCommandView) renderOutput() {
+	row, _ := v.table.GetSelection()
+	if row <= 0 || row-1 >= len(v.instances) {
+		return
+	}
+	instance := v.instances[row-1]
+	v.output.SetTitle(fmt.Sprintf(" Output: %s ", instance.DisplayName()))
+
+	invocation, ok := v.invocations[instance.ID]
+	if !ok || (invocation.Output == "" && invocation.Error == "") {
+		if ok && invocation.Done() {
+			v.output.SetText("[gray]No output[-]")
+		} else {
+			v.output.SetText("[gray]Waiting for the output...[-]")
+		}
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(tview.Escape(invocation.Output))
+	if invocation.Error != "" {
+		if invocation.Output != "" {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[red]%s[-]", tview.Escape(invocation.Error))
+	}
+	v.output.SetText(b.String())
+}
+
+// renderSummary displays the number of instances the command succeeded on
+func (v *RunCommandView) renderSummary() {
+	succeeded := 0
+	for _, invocation := range v.invocations {
+		if invocation.Status == model.CommandSuccess {
+			succeeded++
+		}
+	}
+	summary := fmt.Sprintf("Command %q succeeded on %d/%d instances", v.command, succeeded, len(v.instances))
+	v.ui.statusBar.SetStatus(summary)
+	v.ui.notifyDone(v.started, summary, succeeded < len(v.instances))
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/plugin"
+	"github.com/nlamirault/e2c/internal/schedule"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// scheduleResult is the outcome of the stop of the instances of a schedule
+type scheduleResult struct {
+	stopped int
+	self    *model.Instance // Instance e2c runs on, selected but not stopped
+	errs    []error
+}
+
+// applySchedule stops the running instances of a local schedule, as a batch
+// whose actions are recorded in the audit log
+func (ui *UI) applySchedule(ctx context.Context, s *schedule.Schedule) (int, error) {
+	result, err := ui.stopSchedule(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+
+	ui.app.QueueUpdateDraw(func() {
+		message := fmt.Sprintf("Schedule %s stopped %d instances with the tag %s in %s", s.Name, result.stopped, s.Selector(), s.Target())
+		if result.self != nil {
+			message += fmt.Sprintf(", not %s which e2c runs on", result.self.DisplayName())
+		}
+		if len(result.errs) > 0 {
+			ui.statusBar.SetError(fmt.Sprintf("%s, %d failed", message, len(result.errs)))
+			return
+		}
+		ui.statusBar.SetStatus(message)
+	})
+
+	return result.stopped, errors.Join(result.errs...)
+}
+
+// stopSchedule stops the running instances of a schedule in its profile
+// and region, whatever the ones displayed, except the instance e2c runs on
+func (ui *UI) stopSchedule(ctx context.Context, s *schedule.Schedule) (*scheduleResult, error) {
+	if err := ui.levelError("stopping the instances of a schedule", config.LevelOperator); err != nil {
+		return nil, err
+	}
+
+	client, err := ui.scheduleClient(s)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := client.ListInstances(ctx, s.Filters())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &scheduleResult{}
+	ids := make([]string, 0, len(instances))
+	byID := make(map[string]model.Instance, len(instances))
+	for _, instance := range instances {
+		if ui.isSelf(instance) {
+			ui.log.Warn("Instance e2c runs on not stopped by schedule", "schedule", s.Name, "instanceID", instance.ID)
+			result.self = &instance
+			continue
+		}
+		ids = append(ids, instance.ID)
+		byID[instance.ID] = instance
+	}
+
+	results := ui.newBatchEngine().Run(ctx, ids, func(ctx context.Context, id string) error {
+		return client.StopInstance(ctx, id)
+	}, nil)
+	for _, r := range results {
+		if r.Err != nil {
+			result.errs = append(result.errs, fmt.Errorf("%s: %w", r.ID, r.Err))
+			continue
+		}
+		result.stopped++
+		ui.runHooksWith(client, plugin.EventStop, byID[r.ID])
+	}
+	return result, nil
+}
+
+// scheduleClient returns the EC2 client of the profile and the region of a
+// schedule, created on first use. The profile e2c is started with keeps its
+// assumed role, the other profiles are used as they are.
+func (ui *UI) scheduleClient(s *schedule.Schedule) (*aws.EC2Client, error) {
+	ui.scheduleMutex.Lock()
+	defer ui.scheduleMutex.Unlock()
+
+	key := workspace{profile: s.Profile, region: s.Region}
+	if client, ok := ui.scheduleClients[key]; ok {
+		return client, nil
+	}
+
+	cfg := ui.scheduleConfig
+	var role aws.AssumeRole
+	if s.Profile == cfg.AWS.Profile {
+		role = aws.AssumeRole(cfg.AWS.AssumeRole)
+	}
+	client, err := aws.NewEC2Client(ui.awsLog, s.Region, s.Profile, role, aws.CallOptions(cfg.AWS.Calls), ui.telemetry())
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", s.Name, err)
+	}
+	client.SetAuditLog(ui.ec2Client().AuditLog())
+	client.SetMFAPrompt(ui.promptMFA)
+	client.SetMutationHook(ui.instanceMutated)
+	ui.scheduleClients[key] = client
+	return client, nil
+}
+
+// runSchedulesCommand lists the schedules of the configuration and their
+// next stop
+func (ui *UI) runSchedulesCommand(args []string) error {
+	if len(args) != 0 {
+		return errors.New("no argument expected")
+	}
+
+	if len(ui.scheduled) == 0 {
+		return errors.New("no schedule in the config file")
+	}
+
+	descriptions := make([]string, 0, len(ui.scheduled))
+	for _, s := range ui.scheduled {
+		description := fmt.Sprintf("%s (%s in %s at %s", s.Name, s.Selector(), s.Target(), s)
+		switch next := ui.schedules.Next(s.Name); {
+		case s.Mode == schedule.ModeEventBridge:
+			description += ", in EventBridge Scheduler"
+		case !next.IsZero():
+			description += ", next " + ui.formatTime(next)
+		}
+		descriptions = append(descriptions, description+")")
+	}
+	ui.statusBar.SetStatus("Schedules: " + strings.Join(descriptions, ", "))
+	return nil
+}
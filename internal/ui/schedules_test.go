@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/internal/config"
+	"github.com/nlamirault/e2c/internal/schedule"
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// runningInstance returns a running EC2 instance with a tag
+func runningInstance(id, key, value string) types.Instance {
+	return types.Instance{
+		InstanceId:   awssdk.String(id),
+		InstanceType: types.InstanceTypeT3Micro,
+		State:        &types.InstanceState{Name: types.InstanceStateNameRunning},
+		Tags:         []types.Tag{{Key: awssdk.String(key), Value: awssdk.String(value)}},
+	}
+}
+
+func TestStopScheduleTarget(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// The session displayed is another profile and region than the one of
+	// the schedule, e.g. after a switch
+	displayed := &aws.MockEC2API{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+			t.Error("the schedule listed the instances of the session displayed")
+			return &ec2.DescribeInstancesOutput{}, nil
+		},
+		StopInstancesFunc: func(ctx context.Context, params *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error) {
+			t.Errorf("the schedule stopped %v in the session displayed", params.InstanceIds)
+			return &ec2.StopInstancesOutput{}, nil
+		},
+	}
+
+	var (
+		mutex   sync.Mutex
+		stopped []string
+		filters []types.Filter
+	)
+	target := &aws.MockEC2API{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+			filters = params.Filters
+			return &ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: []types.Instance{
+				runningInstance("i-0dev1", "env", "dev"),
+				runningInstance("i-0self", "env", "dev"),
+				runningInstance("i-0dev2", "env", "dev"),
+			}}}}, nil
+		},
+		StopInstancesFunc: func(ctx context.Context, params *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			stopped = append(stopped, params.InstanceIds...)
+			return &ec2.StopInstancesOutput{}, nil
+		},
+	}
+
+	ui := &UI{log: log}
+	ui.active.Store(&activeSession{
+		client: aws.NewEC2ClientWithAPI(log, "us-east-1", displayed),
+		config: &config.Config{AWS: config.AWSConfig{Profile: "prod", DefaultRegion: "us-east-1"}},
+	})
+	ui.scheduleClients = map[workspace]*aws.EC2Client{
+		{profile: "dev", region: "eu-west-1"}: aws.NewEC2ClientWithAPI(log, "eu-west-1", target),
+	}
+	self := "i-0self"
+	ui.selfID.Store(&self)
+
+	s := &schedule.Schedule{Name: "dev-evening", TagKey: "env", TagValue: "dev", Hour: 19, Location: time.UTC, Profile: "dev", Region: "eu-west-1", Mode: schedule.ModeLocal}
+	result, err := ui.stopSchedule(context.Background(), s)
+	if err != nil {
+		t.Fatalf("stopSchedule() error = %v", err)
+	}
+
+	names := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		names = append(names, awssdk.ToString(filter.Name))
+	}
+	if !slices.Contains(names, "tag:env") {
+		t.Errorf("instances listed with the filters %v, want the tag of the schedule", names)
+	}
+
+	// The instance e2c runs on is never stopped
+	slices.Sort(stopped)
+	if want := []string{"i-0dev1", "i-0dev2"}; !slices.Equal(stopped, want) {
+		t.Errorf("stopped %v, want %v", stopped, want)
+	}
+	if result.stopped != 2 || len(result.errs) != 0 {
+		t.Errorf("stopSchedule() = %d stopped, errors %v, want 2 stopped", result.stopped, result.errs)
+	}
+	if result.self == nil || result.self.ID != self {
+		t.Errorf("stopSchedule() self = %v, want %s", result.self, self)
+	}
+}
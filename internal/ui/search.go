@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+)
+
+// searchMatch is an instance of a cached workspace matched by the search
+type searchMatch struct {
+	finderMatch
+	cachedInstances
+	exact bool // The pattern is the ID, the name or an IP address of the instance
+}
+
+// searchWorkspaces returns the workspaces whose instances are cached: the
+// region displayed first, then the regions refreshed in the background, of
+// the profile in use and of the other ones
+func (ui *UI) searchWorkspaces() []cachedInstances {
+	current := ui.active.Load().workspace()
+	displayed := cachedInstances{
+		workspace: current,
+		instances: ui.store.Snapshot().Instances,
+	}
+	return append([]cachedInstances{displayed}, ui.regions.cached(current.profile)...)
+}
+
+// searchInstances returns the instances of the workspaces matching a pattern
+// on their name, ID or IP addresses: the exact matches first, then the best
+// ones, those of the workspaces in their order among the matches of the same
+// score. An empty pattern matches none.
+func searchInstances(workspaces []cachedInstances, pattern string) []searchMatch {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil
+	}
+
+	var matches []searchMatch
+	for _, ws := range workspaces {
+		for _, instance := range ws.instances {
+			score, ok := matchInstance(instance, pattern)
+			if !ok {
+				continue
+			}
+			exact := false
+			for _, value := range []string{instance.Name, instance.ID, instance.PrivateIP, instance.PublicIP} {
+				exact = exact || strings.EqualFold(value, pattern)
+			}
+			matches = append(matches, searchMatch{
+				finderMatch:     finderMatch{instance: instance, score: score},
+				cachedInstances: ws,
+				exact:           exact,
+			})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].exact != matches[j].exact {
+			return matches[i].exact
+		}
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > finderResults {
+		matches = matches[:finderResults]
+	}
+	return matches
+}
+
+// source returns the account or the profile of an instance found, and its
+// region
+func (m searchMatch) source() string {
+	return cmp.Or(m.instance.Account, m.profile, "default") + "/" + m.region
+}
+
+// age returns how long ago the instances of the workspace were refreshed
+func (m searchMatch) age() string {
+	if m.refreshed.IsZero() {
+		return "displayed"
+	}
+	return time.Since(m.refreshed).Round(time.Second).String() + " ago"
+}
+
+// ShowSearch displays the search over the instances of all the workspaces
+// cached, the region displayed and the ones refreshed in the background of
+// all the profiles used: picking an instance switches to its profile and its
+// region, and selects it
+func (ui *UI) ShowSearch() {
+	workspaces := ui.searchWorkspaces()
+	total := 0
+	for _, ws := range workspaces {
+		total += len(ws.instances)
+	}
+
+	input := tview.NewInputField().
+		SetLabel("> ").
+		SetFieldBackgroundColor(color.AppColors.Background).
+		SetPlaceholder("name, ID or IP address")
+	results := tview.NewTable().SetSelectable(true, false)
+
+	var matches []searchMatch
+	render := func(pattern string) {
+		matches = searchInstances(workspaces, pattern)
+		results.Clear()
+		for i, match := range matches {
+			instance := match.instance
+			results.SetCell(i, 0, tview.NewTableCell(" "+getStateEmoji(instance.State)+" "+instance.ID+" ").SetTextColor(color.AppColors.Foreground))
+			results.SetCell(i, 1, tview.NewTableCell(" "+instance.Name+" ").SetTextColor(color.AppColors.Highlight).SetExpansion(1))
+			results.SetCell(i, 2, tview.NewTableCell(" "+instance.PrivateIP+" ").SetTextColor(color.AppColors.Secondary))
+			results.SetCell(i, 3, tview.NewTableCell(" "+instance.PublicIP+" ").SetTextColor(color.AppColors.Secondary))
+			results.SetCell(i, 4, tview.NewTableCell(" "+match.source()+" ").SetTextColor(color.AppColors.Foreground))
+			results.SetCell(i, 5, tview.NewTableCell(" "+match.age()+" ").SetTextColor(color.AppColors.Secondary).SetAlign(tview.AlignRight))
+		}
+		results.Select(0, 0)
+		results.ScrollToBeginning()
+	}
+
+	jump := func() {
+		row, _ := results.GetSelection()
+		if row < 0 || row >= len(matches) {
+			return
+		}
+		ui.pages.RemovePage("modal")
+		if err := ui.jumpToWorkspace(matches[row].workspace, matches[row].instance.ID); err != nil {
+			ui.log.Error("Failed to switch to the instance found", "profile", matches[row].profile, "region", matches[row].region, "error", err)
+			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		}
+	}
+
+	input.SetChangedFunc(render)
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := results.GetSelection()
+		switch event.Key() {
+		case tcell.KeyDown, tcell.KeyCtrlN:
+			if row+1 < len(matches) {
+				results.Select(row+1, 0)
+			}
+			return nil
+		case tcell.KeyUp, tcell.KeyCtrlP:
+			if row > 0 {
+				results.Select(row-1, 0)
+			}
+			return nil
+		case tcell.KeyEnter:
+			jump()
+			return nil
+		}
+		return event
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(results, finderResults, 0, false)
+	layout.SetBorder(true).
+		SetTitle(fmt.Sprintf(" Search the cached regions (%d instances, %d regions) ", total, len(workspaces))).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, 120, 1, true).
+			AddItem(nil, 0, 1, false), finderResults+3, 1, true).
+		AddItem(nil, 0, 2, false)
+
+	render("")
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// jumpToWorkspace selects an instance, switching first to the profile and
+// the region of its workspace if they are not displayed
+func (ui *UI) jumpToWorkspace(ws workspace, id string) error {
+	current := ui.active.Load().workspace()
+	if ws == current {
+		ui.instancesView.SelectInstance(id)
+		return nil
+	}
+
+	if ws.profile == current.profile {
+		if err := ui.switchRegion(ws.region); err != nil {
+			return err
+		}
+		ui.instancesView.focus = id
+		return nil
+	}
+
+	ui.statusBar.SetStatus(fmt.Sprintf("Switching to profile %s in %s...", cmp.Or(ws.profile, "default"), ws.region))
+	if err := ui.switchClient(ws.profile, ws.region); err != nil {
+		return err
+	}
+	ui.instancesView.focus = id
+	ui.statusBar.SetRegion(ws.region)
+	ui.pages.RemovePage("error")
+	ui.RefreshInstances()
+	return nil
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// selfWarning is appended to the confirmations of the actions cutting the
+// session of e2c, when it runs on one of the instances
+const selfWarning = "🛑 e2c IS RUNNING ON %s: this ends your session, and you may not be able to reconnect to it."
+
+// detectSelf looks up the instance e2c runs on in the background, to warn
+// before stopping or terminating it, e.g. a jump host
+func (ui *UI) detectSelf() {
+	id, err := aws.SelfInstanceID(ui.ctx)
+	if err != nil {
+		ui.log.Debug("Not running on an EC2 instance", "error", err)
+		return
+	}
+	ui.log.Info("Running on an EC2 instance", "instanceID", id)
+	ui.selfID.Store(&id)
+}
+
+// isSelf returns whether e2c runs on an instance
+func (ui *UI) isSelf(instance model.Instance) bool {
+	id := ui.selfID.Load()
+	return id != nil && *id == instance.ID
+}
+
+// withSelfWarning appends a warning to the confirmation of an action on
+// instances if e2c runs on one of them, and returns whether it does
+func (ui *UI) withSelfWarning(message string, instances ...model.Instance) (string, bool) {
+	for _, instance := range instances {
+		if ui.isSelf(instance) {
+			return message + "\n\n" + fmt.Sprintf(selfWarning, instance.DisplayName()), true
+		}
+	}
+	return message, false
+}
+
+// confirmDestructiveOn asks for the confirmation of a destructive action on
+// an instance, always typed if e2c runs on it
+func (ui *UI) confirmDestructiveOn(instance model.Instance, action, title, message string, expected []string, onConfirm func()) {
+	message, self := ui.withSelfWarning(message, instance)
+	if self {
+		ui.ShowTypedConfirmDialog(title, message, expected, onConfirm)
+		return
+	}
+	ui.confirmDestructive(action, title, message, expected, onConfirm)
+}
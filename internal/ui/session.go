@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"slices"
+
+	"github.com/nlamirault/e2c/internal/plugin"
+	"github.com/nlamirault/e2c/internal/session"
+)
+
+// RestoreSession restores the columns, the filter, the sort, the grouping,
+// the selected instance and the view of a previous session. It must be
+// called before the UI is started.
+func (ui *UI) RestoreSession(saved *session.State) {
+	ui.restoreColumns(saved.Instances.Columns)
+
+	state := ui.nav.StateOf(viewInstances)
+	state.Filter = saved.Instances.Filter
+	for i, name := range ui.instancesView.headers {
+		if name == saved.Instances.SortColumn {
+			state.SortColumn = i
+			state.SortDesc = saved.Instances.SortDesc
+			break
+		}
+	}
+
+	if groupBy, err := parseGroupBy(saved.Instances.GroupBy); err == nil {
+		state.GroupBy = groupBy
+	}
+
+	ui.instancesView.focus = saved.Instances.Selected
+	ui.restoreView = saved.View
+}
+
+// restoreColumns applies the tag columns and the order of the plugin columns
+// of a previous session. The tag columns of a context given on the command
+// line take precedence, and the plugin columns configured since the session
+// are displayed after the saved ones.
+func (ui *UI) restoreColumns(saved session.ColumnsState) {
+	v := ui.instancesView
+	if saved.Tags != nil && ui.config().Context == "" {
+		v.tagColumns = saved.Tags
+	}
+
+	if len(saved.Plugins) > 0 {
+		plugins := make([]*plugin.Column, 0, len(v.plugins))
+		for _, name := range saved.Plugins {
+			for _, column := range v.plugins {
+				if column.Name() == name {
+					plugins = append(plugins, column)
+				}
+			}
+		}
+		for _, column := range v.plugins {
+			if !slices.Contains(saved.Plugins, column.Name()) {
+				plugins = append(plugins, column)
+			}
+		}
+		v.plugins = plugins
+	}
+
+	v.setupHeaders()
+}
+
+// restoreSessionView opens the view of the previous session once the
+// instances are loaded
+func (ui *UI) restoreSessionView() {
+	view := ui.restoreView
+	ui.restoreView = ""
+
+	if view == viewVPCs && !ui.pages.HasPage("error") {
+		NewVPCView(ui, "").Show()
+	}
+}
+
+// Session returns the AWS profile displayed, and the state of the UI to
+// restore on the next start with it
+func (ui *UI) Session() (string, *session.State) {
+	state := ui.nav.StateOf(viewInstances)
+	v := ui.instancesView
+
+	saved := &session.State{
+		Region: ui.ec2Client().GetRegion(),
+		View:   ui.nav.Current(),
+		Instances: session.InstancesState{
+			Filter:  state.Filter,
+			GroupBy: state.GroupBy,
+			Columns: session.ColumnsState{
+				Tags: v.tagColumns,
+			},
+		},
+	}
+	for _, column := range v.plugins {
+		saved.Instances.Columns.Plugins = append(saved.Instances.Columns.Plugins, column.Name())
+	}
+	if state.SortColumn >= 0 && state.SortColumn < len(v.headers) {
+		saved.Instances.SortColumn = v.headers[state.SortColumn]
+		saved.Instances.SortDesc = state.SortDesc
+	}
+	if selected := v.GetSelectedInstance(); selected != nil {
+		saved.Instances.Selected = selected.ID
+	}
+	return ui.config().AWS.Profile, saved
+}
@@ -0,0 +1,328 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/tunnel"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// SessionsView lists the port forwarding sessions started from e2c, and
+// stops them
+type SessionsView struct {
+	ui      *UI
+	table   *tview.Table
+	flex    *tview.Flex
+	tunnels []*tunnel.Tunnel // Sessions of the rows
+}
+
+// runForwardCommand runs the forward command, forwarding a local port to a
+// port of the selected instance, or of a host reached through it
+func (ui *UI) runForwardCommand(args []string) error {
+	if len(args) == 0 {
+		ui.ShowPortForwardDialog()
+		return nil
+	}
+	if len(args) > 3 {
+		return errors.New("at most a remote port, a local port and a remote host expected")
+	}
+
+	instance := ui.instancesView.GetSelectedInstance()
+	if instance == nil {
+		return errors.New("no instance selected")
+	}
+
+	local, host := args[0], ""
+	if len(args) > 1 {
+		local = args[1]
+	}
+	if len(args) > 2 {
+		host = args[2]
+	}
+	return ui.startPortForward(*instance, args[0], local, host)
+}
+
+// runSessionsCommand runs the sessions command, listing the port forwarding
+// sessions
+func (ui *UI) runSessionsCommand(args []string) error {
+	if len(args) != 0 {
+		return errors.New("no argument expected")
+	}
+	NewSessionsView(ui).Show()
+	return nil
+}
+
+// handleSessions shows the port forwarding sessions
+func (ui *UI) handleSessions() {
+	NewSessionsView(ui).Show()
+}
+
+// ShowPortForwardDialog displays the dialog asking for the ports of the
+// session forwarding a local port to the selected instance
+func (ui *UI) ShowPortForwardDialog() {
+	instance := ui.instancesView.GetSelectedInstance()
+	if instance == nil {
+		ui.statusBar.SetError("No instance selected")
+		return
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("Remote port:", "", 6, tview.InputFieldInteger, nil)
+	form.AddInputField("Local port:", "", 6, tview.InputFieldInteger, nil)
+	form.AddInputField("Remote host:", "", 30, nil, nil)
+	form.AddButton("Forward", func() {
+		remote := form.GetFormItem(0).(*tview.InputField).GetText()
+		local := form.GetFormItem(1).(*tview.InputField).GetText()
+		host := form.GetFormItem(2).(*tview.InputField).GetText()
+		ui.pages.RemovePage("modal")
+		if local == "" {
+			local = remote
+		}
+		if err := ui.startPortForward(*instance, remote, local, host); err != nil {
+			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+		}
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Forward a port of %s (SSM)", instance.DisplayName()))
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 60, 1, true).
+			AddItem(nil, 0, 1, false), 11, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// startPortForward starts a session forwarding a local port to a port of an
+// instance, or of a host reached through it when given, in the background
+func (ui *UI) startPortForward(instance model.Instance, remote, local, host string) error {
+	if instance.State != "running" {
+		return fmt.Errorf("instance %s is %s", instance.ID, instance.State)
+	}
+	remotePort, err := parsePort(remote)
+	if err != nil {
+		return err
+	}
+	localPort, err := parsePort(local)
+	if err != nil {
+		return err
+	}
+
+	spec := tunnel.Spec{
+		InstanceID:   instance.ID,
+		InstanceName: instance.DisplayName(),
+		LocalPort:    localPort,
+		RemotePort:   remotePort,
+		RemoteHost:   strings.TrimSpace(host),
+	}
+	ui.statusBar.SetStatus(fmt.Sprintf("Forwarding localhost:%d to %s...", localPort, spec.Target()))
+
+	ctx := ui.actionCtx()
+	go func() {
+		// The credentials may be refreshed, and ask for an MFA code
+		env, err := ui.clientFor(instance).ChildEnv(ctx)
+		if err == nil {
+			spec.Env = env
+			_, err = ui.tunnels.Start(spec)
+		}
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.log.Error("Failed to start port forwarding", "instanceID", instance.ID, "error", err)
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			ui.statusBar.SetStatus(fmt.Sprintf("Forwarding localhost:%d to %s, T: list the sessions", localPort, spec.Target()))
+		})
+	}()
+	return nil
+}
+
+// lastLine returns the last line of an output which is not empty
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// parsePort parses a TCP port
+func parsePort(port string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimSpace(port))
+	if err != nil || number < 1 || number > 65535 {
+		return 0, fmt.Errorf("invalid port %q", port)
+	}
+	return number, nil
+}
+
+// tunnelsChanged renders the sessions view, if displayed, when a session
+// starts, is ready or exits. It is called from any goroutine.
+func (ui *UI) tunnelsChanged() {
+	ui.app.QueueUpdateDraw(func() {
+		if v := ui.sessionsView; v != nil {
+			if _, front := ui.pages.GetFrontPage(); front == v.flex {
+				v.render()
+			}
+		}
+	})
+}
+
+// NewSessionsView creates a new sessions view
+func NewSessionsView(ui *UI) *SessionsView {
+	v := &SessionsView{
+		ui:    ui,
+		table: tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+	}
+
+	v.table.SetBorder(true).
+		SetTitle(" Port forwarding sessions ").
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	// Display why the selected session exited, e.g. the agent is not
+	// connected
+	v.table.SetSelectionChangedFunc(func(row, column int) {
+		if row <= 0 || row-1 >= len(v.tunnels) {
+			return
+		}
+		if t := v.tunnels[row-1]; !t.Running() {
+			if output := lastLine(t.Output()); output != "" {
+				ui.statusBar.SetStatus(fmt.Sprintf("Session %d: %s", t.ID, output))
+			}
+		}
+	})
+
+	v.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyDelete || (event.Key() == tcell.KeyRune && event.Rune() == 'd') {
+			v.stopSelected()
+			return nil
+		}
+		return event
+	})
+
+	return v
+}
+
+// Show displays the sessions view, and refreshes the uptime of the sessions
+// until it is closed
+func (v *SessionsView) Show() {
+	v.flex = tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(v.table, 0, 3, true).
+			AddItem(nil, 0, 1, false), 100, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	v.render()
+	v.ui.sessionsView = v
+	v.ui.pages.AddPage("modal", v.flex, true, true)
+	v.ui.statusBar.SetStatus("d: stop the selected session, or remove it once exited")
+
+	ctx, cancel := context.WithCancel(v.ui.ctx)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			v.ui.app.QueueUpdateDraw(func() {
+				if _, front := v.ui.pages.GetFrontPage(); front != v.flex {
+					cancel()
+					return
+				}
+				v.render()
+			})
+		}
+	}()
+}
+
+// stopSelected stops the selected session in the background, or removes it
+// from the list once exited
+func (v *SessionsView) stopSelected() {
+	row, _ := v.table.GetSelection()
+	if row <= 0 || row-1 >= len(v.tunnels) {
+		return
+	}
+	t := v.tunnels[row-1]
+	v.ui.statusBar.SetStatus(fmt.Sprintf("Stopping the session to %s...", t.Target()))
+
+	go func() {
+		err := v.ui.tunnels.Stop(t.ID)
+		v.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				v.ui.log.Error("Failed to stop port forwarding", "id", t.ID, "error", err)
+				v.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			v.ui.statusBar.SetStatus(fmt.Sprintf("Stopped the session to %s", t.Target()))
+		})
+	}()
+}
+
+// render fills the table with the sessions
+func (v *SessionsView) render() {
+	v.table.Clear()
+	v.tunnels = v.ui.tunnels.List()
+
+	for i, header := range []string{"ID", "Instance", "Local", "Remote", "Status", "Uptime"} {
+		v.table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+	if len(v.tunnels) == 0 {
+		v.table.SetCell(1, 0, tview.NewTableCell(" No session, F: forward a port of the selected instance").
+			SetTextColor(color.AppColors.Secondary).
+			SetSelectable(false))
+		return
+	}
+
+	for i, t := range v.tunnels {
+		row := i + 1
+		status := t.Status()
+		statusColor := color.AppColors.Error
+		switch status {
+		case "ready":
+			statusColor = color.AppColors.Running
+		case "starting":
+			statusColor = color.AppColors.Pending
+		case "stopped":
+			statusColor = color.AppColors.Secondary
+		}
+		uptime := "-"
+		if t.Running() {
+			uptime = formatDuration(time.Since(t.Started))
+		}
+
+		v.table.SetCell(row, 0, tview.NewTableCell(" "+strconv.Itoa(t.ID)+" ").SetTextColor(color.AppColors.Foreground).SetAlign(tview.AlignRight))
+		v.table.SetCell(row, 1, tview.NewTableCell(" "+t.InstanceName+" ").SetTextColor(color.AppColors.Highlight))
+		v.table.SetCell(row, 2, tview.NewTableCell(fmt.Sprintf(" localhost:%d ", t.LocalPort)).SetTextColor(color.AppColors.Foreground))
+		v.table.SetCell(row, 3, tview.NewTableCell(" "+t.Target()+" ").SetTextColor(color.AppColors.Foreground))
+		v.table.SetCell(row, 4, tview.NewTableCell(" "+status+" ").SetTextColor(statusColor).SetExpansion(1))
+		v.table.SetCell(row, 5, tview.NewTableCell(" "+uptime+" ").SetTextColor(color.AppColors.Foreground).SetAlign(tview.AlignRight))
+	}
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"sort"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// CycleSortColumn sorts the table on the next column, going back to the
+// default order after the last one
+func (v *InstancesView) CycleSortColumn() {
+	state := v.state()
+	state.SortColumn++
+	if state.SortColumn >= len(v.headers) {
+		state.SortColumn = -1
+	}
+	v.redraw()
+}
+
+// ToggleSortOrder reverses the sort order of the table
+func (v *InstancesView) ToggleSortOrder() {
+	state := v.state()
+	state.SortDesc = !state.SortDesc
+	v.redraw()
+}
+
+// sortInstances returns the instances sorted on the given column
+func (v *InstancesView) sortInstances(instances []model.Instance, column int, desc bool) []model.Instance {
+	if column < 0 || column >= len(v.headers) {
+		return instances
+	}
+
+	sorted := make([]model.Instance, len(instances))
+	copy(sorted, instances)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if desc {
+			a, b = b, a
+		}
+		if column == 8 {
+			// Sort the age column on the duration rather than its display value
+			return a.Age < b.Age
+		}
+		return v.columnValue(a, column) < v.columnValue(b, column)
+	})
+
+	return sorted
+}
+
+// columnValue returns the raw value of an instance for a table column
+func (v *InstancesView) columnValue(instance model.Instance, column int) string {
+	switch column {
+	case 0:
+		return instance.ID
+	case 1:
+		return instance.Name
+	case 2:
+		return instance.State
+	case 3:
+		return instance.Type
+	case 4:
+		return instance.Region
+	case 5:
+		return instance.AvailabilityZone
+	case 6:
+		return instance.PrivateIP
+	case 7:
+		return instance.PublicIP
+	default:
+		if column-9 < len(v.tagColumns) {
+			return instance.Tags[v.tagColumns[column-9]]
+		}
+		if index := column - 9 - len(v.tagColumns); index < len(v.plugins) {
+			return v.plugins[index].Value(instance.ID)
+		}
+		next := 9 + len(v.tagColumns) + len(v.plugins)
+		if v.images {
+			if column == next {
+				return v.imageName(instance)
+			}
+			next++
+		}
+		if v.protections != nil && column == next {
+			if protection, ok := v.protections[instance.ID]; ok {
+				return protection.String()
+			}
+			return ""
+		}
+		return instance.Account
+	}
+}
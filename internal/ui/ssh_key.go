@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/desktop"
+	"github.com/nlamirault/e2c/internal/sshkey"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// defaultPublicKey returns the public key proposed to add to an instance:
+// the one of the clipboard, or the first one found in ~/.ssh, and where it
+// comes from. The key is empty if none was found.
+func defaultPublicKey() (string, string) {
+	if text, err := desktop.ReadClipboard(); err == nil {
+		if key, err := sshkey.Parse(text); err == nil {
+			return key.String(), "clipboard"
+		}
+	}
+	key, path, err := sshkey.ReadDefault()
+	if err != nil {
+		return "", err.Error()
+	}
+	return key.String(), path
+}
+
+// handleAuthorizeKey asks for the user of the selected instance and the
+// public key to add to its authorized_keys, the one of the clipboard or of
+// ~/.ssh by default
+func (ui *UI) handleAuthorizeKey() {
+	selected := ui.instancesView.GetSelectedInstance()
+	if selected == nil {
+		ui.statusBar.SetError("No instance selected")
+		return
+	}
+	instance := *selected
+	if !instance.IsRunning() {
+		ui.statusBar.SetError("Instance must be running to add an SSH key")
+		return
+	}
+	if containsIgnoreCase(instance.Platform, "windows") {
+		ui.statusBar.SetError("Error: adding an SSH key is not supported on Windows instances")
+		return
+	}
+
+	key, source := defaultPublicKey()
+
+	form := tview.NewForm()
+	form.AddInputField("User:", defaultSSHUser(instance), 20, nil, nil)
+	form.AddInputField("Public key:", key, 70, nil, nil)
+	form.AddTextView("Source:", tview.Escape(source), 70, 1, false, false)
+	form.AddButton("Add", func() {
+		user := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		if err := sshkey.ValidUser(user); err != nil {
+			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+			return
+		}
+		key, err := sshkey.Parse(form.GetFormItem(1).(*tview.InputField).GetText())
+		if err != nil {
+			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+			return
+		}
+		ui.pages.RemovePage("modal")
+		ui.authorizeKey(instance, user, key)
+	})
+	form.AddButton("Cancel", func() {
+		ui.pages.RemovePage("modal")
+	})
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Add an SSH key to %s (SSM)", instance.DisplayName()))
+	form.SetCancelFunc(func() {
+		ui.pages.RemovePage("modal")
+	})
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 90, 1, true).
+			AddItem(nil, 0, 1, false), 11, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	ui.pages.AddPage("modal", flex, true, true)
+}
+
+// authorizeKey appends a public key to the authorized_keys of a user of an
+// instance with SSM Run Command, unless it is there already. The user, the
+// key and its fingerprint are recorded in the audit entry of the command.
+func (ui *UI) authorizeKey(instance model.Instance, user string, key sshkey.Key) {
+	client := ui.clientFor(instance)
+	ctx := aws.WithAuditParams(ui.actionCtx(), map[string]string{
+		"ssh_user":            user,
+		"ssh_key":             key.String(),
+		"ssh_key_fingerprint": key.Fingerprint(),
+	})
+	op := ui.statusBar.StartOperation("authorize key " + instance.ID)
+	started := time.Now()
+	ui.log.Info("Adding SSH key", "instanceID", instance.ID, "user", user, "fingerprint", key.Fingerprint())
+
+	go func() {
+		var invocation *model.CommandInvocation
+		commandID, err := client.SendCommand(ctx, []string{instance.ID}, sshkey.AuthorizeScript(user, key), false)
+		if err == nil {
+			invocation, err = client.WaitCommandInvocation(ctx, commandID, instance.ID, nil)
+		}
+
+		ui.app.QueueUpdateDraw(func() {
+			op.Done()
+			summary, failed := "", true
+			switch {
+			case err != nil:
+				ui.log.Error("Failed to add SSH key", "instanceID", instance.ID, "error", err)
+				summary = fmt.Sprintf("Error: %v", err)
+			case invocation.Status != model.CommandSuccess:
+				ui.log.Error("Failed to add SSH key", "instanceID", instance.ID, "status", invocation.Status, "error", invocation.Error)
+				summary = fmt.Sprintf("Error: adding the key to %s on %s: %s %s", user, instance.DisplayName(), invocation.Status, strings.TrimSpace(invocation.Error))
+			case strings.TrimSpace(invocation.Output) == "present":
+				summary, failed = fmt.Sprintf("Key %s already authorized for %s on %s", key.Fingerprint(), user, instance.DisplayName()), false
+			default:
+				summary, failed = fmt.Sprintf("Added the key %s to the authorized_keys of %s on %s", key.Fingerprint(), user, instance.DisplayName()), false
+			}
+
+			if failed {
+				ui.statusBar.SetError(summary)
+			} else {
+				ui.statusBar.SetStatus(summary)
+			}
+			ui.notifyDone(started, summary, failed)
+		})
+	}()
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/desktop"
+	"github.com/nlamirault/e2c/pkg/aws"
+)
+
+// ssoLogin runs the IAM Identity Center device authorization of the profile
+// from the error panel: the verification page is opened in the browser, and
+// the instances are loaded again once the access is approved
+func (ui *UI) ssoLogin() {
+	sso := ui.ec2Client().SSOConfig(ui.ctx)
+	if sso == nil {
+		return
+	}
+
+	text := tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignCenter).
+		SetWrap(true).
+		SetText("\n[yellow]Starting the IAM Identity Center login...[-]")
+	text.SetBorder(true).
+		SetTitle(" SSO Login ").
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	ctx, cancel := context.WithCancel(ui.ctx)
+	text.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			cancel()
+			ui.pages.RemovePage("sso")
+			return nil
+		}
+		return event
+	})
+
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(text, 13, 1, true).
+			AddItem(nil, 0, 1, false), 80, 1, true).
+		AddItem(nil, 0, 1, false)
+	ui.pages.AddPage("sso", flex, true, true)
+
+	go func() {
+		defer cancel()
+
+		login, err := ui.ec2Client().StartSSOLogin(ctx, sso)
+		if err == nil {
+			if err := desktop.OpenURL(login.VerificationURL); err != nil {
+				ui.log.Warn("Failed to open the browser", "error", err)
+			}
+			ui.app.QueueUpdateDraw(func() {
+				text.SetText(fmt.Sprintf(`
+Open the following URL and approve the access, if it was not opened in the browser:
+
+[blue]%s[-]
+
+Check that the code displayed is [::b]%s[::-]
+
+[gray]Waiting for the approval until %s...[-]
+
+[yellow]Esc[-]: Cancel`,
+					tview.Escape(login.VerificationURL), login.UserCode, login.ExpiresAt.Format("15:04:05")))
+			})
+			err = login.Wait(ctx)
+		}
+
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			ui.log.Error("IAM Identity Center login failed", "startURL", sso.StartURL, "error", err)
+			ui.app.QueueUpdateDraw(func() {
+				text.SetText(fmt.Sprintf("\n[red]Login failed[-]\n\n[gray]%s[-]\n\n[yellow]Esc[-]: Close", tview.Escape(err.Error())))
+			})
+			return
+		}
+
+		ui.log.Info("IAM Identity Center login succeeded", "startURL", sso.StartURL)
+		ui.app.QueueUpdateDraw(func() {
+			ui.pages.RemovePage("sso")
+			ui.statusBar.SetStatus("Logged in to IAM Identity Center")
+			ui.retryFromErrorPanel()
+			go ui.resolveCredentials()
+		})
+	}()
+}
+
+// ssoDescription returns the IAM Identity Center configuration displayed in
+// the error panel
+func ssoDescription(sso *aws.SSOConfig) string {
+	if sso.Session != "" {
+		return fmt.Sprintf("%s (session %s)", sso.StartURL, sso.Session)
+	}
+	return sso.StartURL
+}
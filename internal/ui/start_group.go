@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// tierTimeout is the maximum time waited for the instances of a tier to be
+// running before starting the next one
+const tierTimeout = 10 * time.Minute
+
+// handleStartGroup starts the marked instances, or the displayed instances
+// with a start order, tier by tier
+func (ui *UI) handleStartGroup() {
+	instances := ui.instancesView.GetMarkedInstances()
+	if len(instances) == 0 {
+		for _, instance := range ui.instancesView.instances {
+			if _, ok := instance.StartOrder(); ok {
+				instances = append(instances, instance)
+			}
+		}
+	}
+	if len(instances) == 0 {
+		ui.statusBar.SetError(fmt.Sprintf("No marked instance and no instance with the %s tag", model.StartOrderTag))
+		return
+	}
+
+	tiers := model.StartTiers(instances)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Start %d instances in %d tiers, waiting for each tier to be running?\n\n", len(instances), len(tiers))
+	for _, tier := range tiers {
+		names := make([]string, 0, len(tier.Instances))
+		for _, instance := range tier.Instances {
+			names = append(names, instance.DisplayName())
+		}
+		fmt.Fprintf(&b, "%s: %s\n", tierName(tier), strings.Join(names, ", "))
+	}
+
+	ui.ShowConfirmDialog("Start Group", b.String(), func() {
+		ui.executeStartGroup(tiers)
+	})
+}
+
+// executeStartGroup starts the tiers in order, waiting for the instances of
+// a tier to be running before starting the next one. It stops at the first
+// tier with a failure.
+func (ui *UI) executeStartGroup(tiers []model.StartTier) {
+	if ui.batchCancel != nil {
+		ui.statusBar.SetError("A batch is already running, press X to cancel it")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ui.ctx)
+	ui.batchCancel = cancel
+	action := ui.startAction()
+	action.name = "Start group"
+	started := time.Now()
+
+	go func() {
+		defer cancel()
+
+		var report []batchResult
+		engine := ui.newBatchEngine()
+
+		for i, tier := range tiers {
+			label := fmt.Sprintf("%s (%d/%d)", tierName(tier), i+1, len(tiers))
+
+			// Instances already running are not started again
+			var pending []model.Instance
+			ids := make([]string, 0, len(tier.Instances))
+			for _, instance := range tier.Instances {
+				if action.check(instance) != "" {
+					continue
+				}
+				pending = append(pending, instance)
+				ids = append(ids, instance.ID)
+			}
+			if len(ids) == 0 {
+				continue
+			}
+
+			results := engine.Run(ctx, ids, action.run, func(done, failed, total int) {
+				ui.app.QueueUpdateDraw(func() {
+					ui.statusBar.SetProgress(label, done, failed, total)
+				})
+			})
+
+			failed := false
+			for j, result := range results {
+				report = append(report, batchResult{
+					instance: pending[j],
+					err:      result.Err,
+					attempts: result.Attempts,
+					duration: result.Duration,
+				})
+				failed = failed || result.Err != nil
+			}
+			if failed {
+				ui.log.Error("Start group stopped on a failed tier", "tier", label)
+				break
+			}
+
+			ui.app.QueueUpdateDraw(func() {
+				ui.statusBar.SetStatus(fmt.Sprintf("%s: waiting for %d instances to be running...", label, len(ids)))
+			})
+			if err := ui.waitInstancesRunning(ctx, ids, tierTimeout); err != nil {
+				ui.log.Error("Tier did not reach running", "tier", label, "error", err)
+				for j := len(report) - len(ids); j < len(report); j++ {
+					report[j].err = err
+				}
+				break
+			}
+		}
+
+		ui.app.QueueUpdateDraw(func() {
+			ui.batchCancel = nil
+			ui.statusBar.ClearProgress()
+			ui.instancesView.ClearMarks()
+			ui.showBatchReport(action, report, started)
+			ui.RefreshInstances()
+		})
+	}()
+}
+
+// tierName returns the display name of a start tier
+func tierName(tier model.StartTier) string {
+	if tier.Untagged {
+		return "Untagged"
+	}
+	return fmt.Sprintf("Tier %d", tier.Order)
+}
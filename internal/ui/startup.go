@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// warmupTask is a startup task whose progress is displayed in the status bar
+type warmupTask struct {
+	name     string
+	run      func(ctx context.Context) (string, error)
+	done     bool
+	result   string
+	err      error
+	duration time.Duration
+}
+
+// Startup runs the startup tasks concurrently while the UI is displayed
+type Startup struct {
+	ui     *UI
+	tasks  []*warmupTask
+	table  *warmupTask // Task loading the table, which then displays its own status
+	tasksM sync.Mutex
+}
+
+// NewStartup creates the startup of the given tasks, the progress being
+// displayed until the table task is completed
+func NewStartup(ui *UI, tasks []*warmupTask, table *warmupTask) *Startup {
+	return &Startup{
+		ui:    ui,
+		tasks: tasks,
+		table: table,
+	}
+}
+
+// status returns the progress of the tasks, e.g. "Starting: ✅ config (3ms)
+// ✅ credentials (84ms) ⏳ identity ⏳ feature flags ⏳ instances"
+func (s *Startup) status() string {
+	s.tasksM.Lock()
+	defer s.tasksM.Unlock()
+
+	var b strings.Builder
+	b.WriteString("Starting:")
+	for _, task := range s.tasks {
+		switch {
+		case !task.done:
+			fmt.Fprintf(&b, " ⏳ %s", task.name)
+		case task.err != nil:
+			fmt.Fprintf(&b, " ❌ %s", task.name)
+		default:
+			fmt.Fprintf(&b, " ✅ %s (%s)", task.name, task.duration.Round(time.Millisecond))
+		}
+	}
+	return b.String()
+}
+
+// update displays the progress of the tasks, until the first page of
+// instances is displayed or failed to load
+func (s *Startup) update() {
+	s.tasksM.Lock()
+	done := s.table.done
+	s.tasksM.Unlock()
+
+	if !done && !s.ui.store.Snapshot().Loading {
+		s.ui.statusBar.SetStatus(s.status())
+	}
+}
+
+// Run runs the tasks concurrently, logs the duration of each of them and
+// calls onDone from the UI goroutine once each one is completed. The tasks
+// already done, completed before the UI was created, are only displayed.
+func (s *Startup) Run(ctx context.Context, onDone func(task *warmupTask)) {
+	for _, task := range s.tasks {
+		if task.done {
+			continue
+		}
+		go func() {
+			start := time.Now()
+			result, err := task.run(ctx)
+			duration := time.Since(start)
+
+			if err != nil {
+				s.ui.log.Warn("Startup phase failed", "phase", task.name, "duration", duration, "error", err)
+			} else {
+				s.ui.log.Info("Startup phase completed", "phase", task.name, "duration", duration, "result", result)
+			}
+
+			s.tasksM.Lock()
+			task.done = true
+			task.result = result
+			task.err = err
+			task.duration = duration
+			s.tasksM.Unlock()
+
+			s.ui.app.QueueUpdateDraw(func() {
+				s.update()
+				onDone(task)
+			})
+		}()
+	}
+}
+
+// warmup displays the UI with a skeleton of the instances table, and warms
+// up the AWS credentials, the caller identity, the feature flags depending on
+// it and the first page of instances concurrently, after the configuration
+// loaded. The table is usable as soon as the first page is loaded, without
+// waiting for the other tasks.
+func (ui *UI) warmup() {
+	instances := &warmupTask{
+		name: "instances",
+		run: func(ctx context.Context) (string, error) {
+			ui.app.QueueUpdate(ui.RefreshInstances)
+			select {
+			case err := <-ui.firstPage:
+				return "", err
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		},
+	}
+	identified := make(chan struct{}) // Closed once the caller is known, or failed to be
+	tasks := []*warmupTask{
+		{
+			name: "credentials",
+			run: func(ctx context.Context) (string, error) {
+				source, err := ui.ec2Client().ResolveCredentials(ctx)
+				if err != nil {
+					return "", err
+				}
+				ui.app.QueueUpdateDraw(func() {
+					ui.setCredentialSource(source)
+				})
+				return source.String(), nil
+			},
+		},
+		{
+			name: "identity",
+			run: func(ctx context.Context) (string, error) {
+				defer close(identified)
+				identity, err := ui.ec2Client().GetCallerIdentity(ctx)
+				if err != nil {
+					return "", err
+				}
+				ui.setCaller(identity.ARN)
+				return identity.ARN, nil
+			},
+		},
+		{
+			// The flags depending on the caller are evaluated once it is
+			// known, the others without it if it cannot be
+			name: "feature flags",
+			run: func(ctx context.Context) (string, error) {
+				select {
+				case <-identified:
+				case <-ctx.Done():
+					return "", ctx.Err()
+				}
+				on := ui.flags.On(ui.flagContext())
+				if len(on) == 0 {
+					return "none on", nil
+				}
+				return strings.Join(on, ", ") + " on", nil
+			},
+		},
+		instances,
+	}
+	if ui.configLoad > 0 {
+		config := &warmupTask{name: "config", done: true, duration: ui.configLoad}
+		tasks = append([]*warmupTask{config}, tasks...)
+	}
+
+	ui.instancesView.ShowSkeleton()
+	startup := NewStartup(ui, tasks, instances)
+	startup.update()
+
+	startup.Run(ui.ctx, func(task *warmupTask) {
+		if task != instances {
+			return
+		}
+		ui.log.Info("Startup phase completed", "phase", "First usable table", "duration", time.Since(ui.started))
+		ui.restoreSessionView()
+	})
+}
+
+// signalFirstPage notifies the startup that the first page of instances was
+// loaded, or failed to load
+func (ui *UI) signalFirstPage(err error) {
+	select {
+	case ui.firstPage <- err:
+	default:
+	}
+}
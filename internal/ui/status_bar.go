@@ -11,6 +11,8 @@ import (
 	"github.com/rivo/tview"
 
 	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/internal/keymap"
+	"github.com/nlamirault/e2c/pkg/store"
 )
 
 // StatusBar represents the status bar at the bottom of the UI
@@ -21,6 +23,23 @@ type StatusBar struct {
 	region   string
 	lastSync time.Time
 	mode     string // Current UI mode
+	pages    int    // Number of pages of instances loaded
+	loading  bool   // More pages are being loaded
+	progress string // Progress of the running batch, empty if none
+	creds    string // External process supplying the credentials, if any
+	refresh  string // Interval of the auto-refresh, or paused
+	issues   int    // Number of open AWS Health issues
+	context  string // Context of the config file in use, empty if none
+	locked   string // Filter locked with --filter, with the key clearing it
+	watching int    // Number of instances watched
+
+	// In-flight operations, and messages kept for review
+	operations  []*Operation
+	scan        *Operation    // Protections scan, nil if none
+	stopSpinner chan struct{} // Stops the spinner, nil if not spinning
+	frame       int           // Frame of the spinner
+	messages    []statusMessage
+	unread      int // Errors not reviewed yet
 }
 
 // NewStatusBar creates a new status bar
@@ -34,10 +53,37 @@ func NewStatusBar(ui *UI) *StatusBar {
 
 	// Set background color from theme
 	bar.view.SetBackgroundColor(color.AppColors.Background)
+	bar.view.SetTextColor(color.AppColors.Foreground)
 
 	// Update the view
 	bar.update()
 
+	// Display the number of pages of instances loaded, and warn about the
+	// open AWS Health issues
+	ui.store.Subscribe(func(state *store.State, action store.Action) {
+		switch action.(type) {
+		case store.InstancesLoaded:
+			ui.app.QueueUpdateDraw(func() {
+				bar.SetPages(state.Pages, state.Loading)
+			})
+		case store.SessionStarted:
+			ui.app.QueueUpdateDraw(func() {
+				bar.SetPages(state.Pages, state.Loading)
+				bar.SetHealthIssues(0)
+			})
+		case store.HealthEventsLoaded:
+			issues := 0
+			for _, event := range state.Health {
+				if event.IsIssue() {
+					issues++
+				}
+			}
+			ui.app.QueueUpdateDraw(func() {
+				bar.SetHealthIssues(issues)
+			})
+		}
+	})
+
 	return bar
 }
 
@@ -45,6 +91,7 @@ func NewStatusBar(ui *UI) *StatusBar {
 func (b *StatusBar) UpdateTheme() {
 	// Update the background color based on theme
 	b.view.SetBackgroundColor(color.AppColors.Background)
+	b.view.SetTextColor(color.AppColors.Foreground)
 	b.update()
 }
 
@@ -52,6 +99,7 @@ func (b *StatusBar) UpdateTheme() {
 func (b *StatusBar) SetStatus(status string) {
 	b.status = status
 	b.lastSync = time.Now()
+	b.recordMessage(status, false)
 	b.update()
 }
 
@@ -59,6 +107,7 @@ func (b *StatusBar) SetStatus(status string) {
 func (b *StatusBar) SetError(err string) {
 	// Use standard color name for simplicity
 	b.status = fmt.Sprintf("[red]%s[-]", err)
+	b.recordMessage(err, true)
 	b.update()
 }
 
@@ -68,6 +117,99 @@ func (b *StatusBar) SetRegion(region string) {
 	b.update()
 }
 
+// SetContext sets the context of the config file in use, and whether it is
+// read-only
+func (b *StatusBar) SetContext(name string, readOnly bool) {
+	b.context = name
+	if readOnly {
+		b.context += " [red](read-only)[-]"
+	}
+	b.update()
+}
+
+// SetLockedFilter sets the filter locked with --filter, empty if none, and
+// the key clearing it
+func (b *StatusBar) SetLockedFilter(expr, key string) {
+	b.locked = ""
+	if expr != "" {
+		b.locked = fmt.Sprintf("%s [yellow](locked, %s to clear)[-]", tview.Escape(expr), keymap.Display(key))
+	}
+	b.update()
+}
+
+// SetWatching sets the number of instances watched
+func (b *StatusBar) SetWatching(count int) {
+	b.watching = count
+	b.update()
+}
+
+// SetCredentials sets the external process supplying the credentials
+func (b *StatusBar) SetCredentials(creds string) {
+	b.creds = creds
+	b.update()
+}
+
+// SetRefresh sets the interval of the auto-refresh, and whether it is paused
+func (b *StatusBar) SetRefresh(interval time.Duration, paused bool) {
+	if paused {
+		b.refresh = "[red]paused[-]"
+	} else {
+		b.refresh = formatInterval(interval)
+	}
+	b.update()
+}
+
+// SetHealthIssues sets the number of open AWS Health issues
+func (b *StatusBar) SetHealthIssues(issues int) {
+	b.issues = issues
+	b.update()
+}
+
+// SetPages sets the number of pages of instances loaded, and whether more
+// pages are being loaded
+func (b *StatusBar) SetPages(pages int, loading bool) {
+	b.pages = pages
+	b.loading = loading
+	b.update()
+}
+
+// SetProgress displays the progress bar of a running batch
+func (b *StatusBar) SetProgress(label string, done, failed, total int) {
+	const width = 20
+
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+
+	b.progress = fmt.Sprintf("[yellow]%s:[-] %s %d/%d", label, bar, done, total)
+	if failed > 0 {
+		b.progress += fmt.Sprintf(" [red](%d failed)[-]", failed)
+	}
+	b.update()
+}
+
+// SetProtections displays the progress of the protections scan, removed
+// once the protections of all the instances are known
+func (b *StatusBar) SetProtections(done, total int) {
+	switch {
+	case done < total && b.scan == nil:
+		b.scan = b.StartOperation(fmt.Sprintf("protections %d/%d", done, total))
+	case done < total:
+		b.scan.Update(fmt.Sprintf("protections %d/%d", done, total))
+	case b.scan != nil:
+		b.scan.Done()
+		b.scan = nil
+	}
+}
+
+// ClearProgress removes the progress bar
+func (b *StatusBar) ClearProgress() {
+	b.progress = ""
+	b.update()
+}
+
 // SetMode sets the current UI mode
 func (b *StatusBar) SetMode(mode string) {
 	b.mode = mode
@@ -78,7 +220,7 @@ func (b *StatusBar) SetMode(mode string) {
 func (b *StatusBar) update() {
 	// Use standard color names for simplicity
 	labelColor := "yellow"
-	valueColor := "white"
+	valueColor := "-"
 	modeValueColor := "blue"
 
 	var regionInfo string
@@ -101,6 +243,14 @@ func (b *StatusBar) update() {
 		modeInfo = fmt.Sprintf("[%s]Mode:[%s] [%s]Normal[%s]", labelColor, valueColor, modeValueColor, valueColor)
 	}
 
+	var pagesInfo string
+	if b.pages > 1 || b.loading {
+		pagesInfo = fmt.Sprintf("[%s]Pages:[%s] %d", labelColor, valueColor, b.pages)
+		if b.loading {
+			pagesInfo += "+"
+		}
+	}
+
 	status := b.status
 	if status == "" {
 		status = "Ready"
@@ -109,14 +259,54 @@ func (b *StatusBar) update() {
 	// Build status text with all components
 	components := []string{status}
 
+	if b.progress != "" {
+		components = append(components, b.progress)
+	}
+
+	if operations := b.operationsInfo(); operations != "" {
+		components = append(components, operations)
+	}
+
+	if b.unread > 0 {
+		components = append(components, fmt.Sprintf("[red]Errors: %d (W)[-]", b.unread))
+	}
+
+	if b.issues > 0 {
+		components = append(components, fmt.Sprintf("[red]AWS Health: %d issues (H)[-]", b.issues))
+	}
+
+	if b.context != "" {
+		components = append(components, fmt.Sprintf("[%s]Context:[%s] %s", labelColor, valueColor, b.context))
+	}
+
+	if b.locked != "" {
+		components = append(components, fmt.Sprintf("[%s]Filter:[%s] %s", labelColor, valueColor, b.locked))
+	}
+
+	if b.watching > 0 {
+		components = append(components, fmt.Sprintf("[%s]Watching:[%s] %d", labelColor, valueColor, b.watching))
+	}
+
 	if regionInfo != "" {
 		components = append(components, regionInfo)
 	}
 
+	if b.creds != "" {
+		components = append(components, fmt.Sprintf("[%s]Creds:[%s] %s", labelColor, valueColor, b.creds))
+	}
+
 	if modeInfo != "" {
 		components = append(components, modeInfo)
 	}
 
+	if pagesInfo != "" {
+		components = append(components, pagesInfo)
+	}
+
+	if b.refresh != "" {
+		components = append(components, fmt.Sprintf("[%s]Refresh:[%s] %s", labelColor, valueColor, b.refresh))
+	}
+
 	if lastSyncInfo != "" {
 		components = append(components, lastSyncInfo)
 	}
@@ -132,3 +322,12 @@ func (b *StatusBar) Clear() {
 	b.status = ""
 	b.update()
 }
+
+// formatInterval formats a duration without the trailing zero units, e.g. 2m
+// rather than 2m0s
+func formatInterval(d time.Duration) string {
+	if d >= time.Minute && d%time.Minute == 0 {
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return d.String()
+}
@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/config"
+)
+
+// checkTagEditing returns whether the tags can be edited: at the admin
+// level only, and not in a read-only context
+func (d *DetailView) checkTagEditing() bool {
+	return d.ui.checkLevel("editing the tags", config.LevelAdmin)
+}
+
+// toggleTagMark marks or unmarks the selected tag, to propagate several tags
+// at once
+func (d *DetailView) toggleTagMark() {
+	key, ok := d.selectedTag()
+	if !ok {
+		return
+	}
+
+	if d.tagMarks[key] {
+		delete(d.tagMarks, key)
+	} else {
+		d.tagMarks[key] = true
+	}
+	row, _ := d.tags.GetSelection()
+	d.renderTags()
+	d.tags.Select(min(row+1, len(d.tagKeys)), 0)
+}
+
+// markedTags returns the marked tags, or the selected tag if none is marked
+func (d *DetailView) markedTags() map[string]string {
+	tags := make(map[string]string)
+	for key := range d.tagMarks {
+		if value, ok := d.instance.Tags[key]; ok {
+			tags[key] = value
+		}
+	}
+	if len(tags) == 0 {
+		if key, ok := d.selectedTag(); ok {
+			tags[key] = d.instance.Tags[key]
+		}
+	}
+	return tags
+}
+
+// editTag displays the form to add a tag, or change the value of the
+// selected one, offering to propagate it to the related resources
+func (d *DetailView) editTag() {
+	if !d.checkTagEditing() {
+		return
+	}
+
+	key, _ := d.selectedTag()
+	form := tview.NewForm()
+	form.AddInputField("Key:", key, 40, nil, nil)
+	form.AddInputField("Value:", d.instance.Tags[key], 40, nil, nil)
+	form.AddCheckbox("Propagate to volumes, ENIs and EIPs:", false, nil)
+	form.AddButton("Save", func() {
+		key := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		value := form.GetFormItem(1).(*tview.InputField).GetText()
+		propagate := form.GetFormItem(2).(*tview.Checkbox).IsChecked()
+		if key == "" {
+			d.ui.statusBar.SetError("Error: no tag key given")
+			return
+		}
+		d.Show()
+		tags := map[string]string{key: value}
+		if propagate {
+			d.propagate(tags)
+			return
+		}
+		d.setTags(tags, nil)
+	})
+	form.AddButton("Cancel", d.Show)
+
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Tag %s", d.instance.DisplayName()))
+	form.SetCancelFunc(d.Show)
+
+	flex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(form, 70, 1, true).
+			AddItem(nil, 0, 1, false), 11, 1, true).
+		AddItem(nil, 0, 1, false)
+
+	d.ui.pages.AddPage("modal", flex, true, true)
+}
+
+// deleteTag deletes the selected tag from the instance, after confirmation
+func (d *DetailView) deleteTag() {
+	key, ok := d.selectedTag()
+	if !ok || !d.checkTagEditing() {
+		return
+	}
+
+	instance := d.instance
+	message := fmt.Sprintf("Delete the tag %s=%s of %s?", key, instance.Tags[key], instance.DisplayName())
+	d.ui.ShowConfirmDialog("Delete Tag", message, func() {
+		d.Show()
+		d.ui.statusBar.SetStatus(fmt.Sprintf("Deleting the tag %s of %s...", key, instance.ID))
+		ctx := d.ui.actionCtx()
+		go func() {
+			err := d.ui.clientFor(instance).DeleteTags(ctx, instance.ID, []string{key})
+			d.ui.app.QueueUpdateDraw(func() {
+				if err != nil {
+					d.ui.log.Error("Failed to delete tag", "instanceID", instance.ID, "key", key, "error", err)
+					d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+					return
+				}
+				d.ui.statusBar.SetStatus(fmt.Sprintf("Deleted the tag %s of %s", key, instance.ID))
+				d.updateTags(nil, key)
+			})
+		}()
+	})
+}
+
+// propagateTags propagates the marked tags, or the selected one, to the
+// resources attached to the instance
+func (d *DetailView) propagateTags() {
+	if !d.checkTagEditing() {
+		return
+	}
+	if tags := d.markedTags(); len(tags) > 0 {
+		d.propagate(tags)
+	}
+}
+
+// propagate lists the resources attached to the instance, then sets tags on
+// the instance and on them in a single call, after confirmation
+func (d *DetailView) propagate(tags map[string]string) {
+	instance := d.instance
+	d.ui.statusBar.SetStatus(fmt.Sprintf("Listing the resources attached to %s...", instance.ID))
+	ctx := d.ui.actionCtx()
+	go func() {
+		resources, err := d.ui.clientFor(instance).ListRelatedResources(ctx, instance)
+		d.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				d.ui.log.Error("Failed to list related resources", "instanceID", instance.ID, "error", err)
+				d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			if len(resources) == 0 {
+				// Nothing to propagate to, the tags are only set on the instance
+				d.setTags(tags, nil)
+				return
+			}
+
+			message := fmt.Sprintf("Set %s on %s and on:\n\n%s",
+				formatTags(tags), instance.DisplayName(), strings.Join(resources, "\n"))
+			d.ui.ShowConfirmDialog("Propagate Tags", message, func() {
+				d.Show()
+				d.setTags(tags, resources)
+			})
+		})
+	}()
+}
+
+// setTags sets tags on the instance, and on related resources
+func (d *DetailView) setTags(tags map[string]string, resources []string) {
+	instance := d.instance
+	d.ui.statusBar.SetStatus(fmt.Sprintf("Tagging %s...", instance.ID))
+	ctx := d.ui.actionCtx()
+	go func() {
+		err := d.ui.clientFor(instance).SetTags(ctx, instance.ID, resources, tags)
+		d.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				d.ui.log.Error("Failed to set tags", "instanceID", instance.ID, "error", err)
+				d.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			if len(resources) > 0 {
+				d.ui.statusBar.SetStatus(fmt.Sprintf("Set %s on %s and %d related resources", formatTags(tags), instance.ID, len(resources)))
+			} else {
+				d.ui.statusBar.SetStatus(fmt.Sprintf("Set %s on %s", formatTags(tags), instance.ID))
+			}
+			d.tagMarks = make(map[string]bool)
+			d.updateTags(tags, "")
+		})
+	}()
+}
+
+// updateTags displays the tags set or deleted, the instances being loaded
+// again
+func (d *DetailView) updateTags(set map[string]string, deleted string) {
+	tags := make(map[string]string, len(d.instance.Tags)+len(set))
+	for key, value := range d.instance.Tags {
+		tags[key] = value
+	}
+	for key, value := range set {
+		tags[key] = value
+	}
+	delete(tags, deleted)
+	delete(d.tagMarks, deleted)
+
+	d.instance.Tags = tags
+	d.renderTags()
+	d.ui.RefreshInstances()
+}
+
+// formatTags returns tags as key=value pairs sorted by key
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
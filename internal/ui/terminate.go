@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nlamirault/e2c/internal/plugin"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// handleTerminateInstance handles terminating the selected instance, or the
+// marked ones. The termination protection is checked first: the protected
+// instances are skipped by the batches, and the protection of the selected
+// instance is disabled before terminating it once confirmed.
+func (ui *UI) handleTerminateInstance() {
+	// Apply the action to the marked instances if any
+	if marked := ui.instancesView.GetMarkedInstances(); len(marked) > 0 {
+		ui.planTerminate(marked)
+		return
+	}
+
+	selectedInstance := ui.instancesView.GetSelectedInstance()
+	if selectedInstance == nil {
+		ui.statusBar.SetError("No instance selected")
+		return
+	}
+	instance := *selectedInstance
+
+	ui.statusBar.SetStatus(fmt.Sprintf("Checking the termination protection of %s...", instance.ID))
+	ctx := ui.actionCtx()
+	go func() {
+		protection, err := ui.fetchProtection(ctx, ui.clientFor(instance), instance.ID, true)
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.log.Warn("Failed to check the termination protection", "instanceID", instance.ID, "error", err)
+			}
+			ui.confirmTerminate(instance, protection, err)
+		})
+	}()
+}
+
+// confirmTerminate asks to confirm the termination of an instance, showing
+// its termination protection. A protected instance must be confirmed by
+// typing its name or ID, whatever ui.confirm_destructive.
+func (ui *UI) confirmTerminate(instance model.Instance, protection *model.Protection, protectionErr error) {
+	protected := protectionErr == nil && protection.Termination
+
+	message := fmt.Sprintf("Are you sure you want to TERMINATE instance %s? This action cannot be undone!", instance.DisplayName())
+	switch {
+	case protectionErr != nil:
+		message += fmt.Sprintf("\n\nTermination protection: unknown (%v).", protectionErr)
+	case protected:
+		message += "\n\nTermination protection: ENABLED. It will be disabled to terminate the instance."
+	default:
+		message += "\n\nTermination protection: disabled."
+	}
+	message = ui.withNotice(message, instance)
+	if stack := instance.CloudFormationStack(); stack != "" {
+		message += fmt.Sprintf("\n\nThis instance is managed by the CloudFormation stack %s, terminating it will cause the stack to drift.", stack)
+	}
+	if resource, ok := ui.terraform.Lookup(instance.ID); ok {
+		message += fmt.Sprintf("\n\nThis instance is managed by Terraform (%s), terminating it will cause drift.", resource.Address)
+	}
+
+	terminate := func() {
+		ui.statusBar.SetStatus(fmt.Sprintf("Terminating instance %s...", instance.ID))
+		op := ui.statusBar.StartOperation("terminate " + instance.ID)
+
+		ctx := ui.actionCtx()
+		go func() {
+			err := ui.terminate(ctx, instance, protected)
+			if err != nil {
+				ui.app.QueueUpdateDraw(func() {
+					op.Done()
+					ui.log.Error("Failed to terminate instance", "error", err)
+					ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				})
+				return
+			}
+
+			ui.app.QueueUpdateDraw(func() {
+				op.Done()
+				ui.statusBar.SetStatus(fmt.Sprintf("Terminated instance %s", instance.ID))
+				ui.RefreshInstances()
+			})
+
+			ui.runHooks(plugin.EventTerminate, instance)
+		}()
+	}
+
+	message, self := ui.withSelfWarning(message, instance)
+	if protected || self {
+		title := "Terminate Instance"
+		if protected {
+			title = "Terminate Protected Instance"
+		}
+		ui.ShowTypedConfirmDialog(title, message, instanceConfirmValues(instance), terminate)
+		return
+	}
+	ui.confirmDestructive("terminate", "Terminate Instance", message, instanceConfirmValues(instance), terminate)
+}
+
+// terminate terminates an instance, disabling its termination protection
+// first if enabled. The protection is enabled again if the termination
+// fails.
+func (ui *UI) terminate(ctx context.Context, instance model.Instance, protected bool) error {
+	client := ui.clientFor(instance)
+	if protected {
+		if err := client.SetProtection(ctx, instance.ID, model.ProtectionTermination, false); err != nil {
+			return err
+		}
+	}
+
+	err := client.TerminateInstance(ctx, instance.ID)
+	if err != nil && protected {
+		if restoreErr := client.SetProtection(ctx, instance.ID, model.ProtectionTermination, true); restoreErr != nil {
+			ui.log.Error("Failed to enable the termination protection again", "instanceID", instance.ID, "error", restoreErr)
+			return fmt.Errorf("%w, and the termination protection is left disabled: %v", err, restoreErr)
+		}
+	}
+	return err
+}
+
+// planTerminate checks the termination protection of the instances, and
+// shows the plan terminating them without the protected ones
+func (ui *UI) planTerminate(instances []model.Instance) {
+	ui.statusBar.SetStatus(fmt.Sprintf("Checking the termination protection of %d instances...", len(instances)))
+
+	go func() {
+		var candidates []model.Instance
+		for _, instance := range instances {
+			if instance.State != "terminated" && instance.State != "shutting-down" {
+				candidates = append(candidates, instance)
+			}
+		}
+		protected := ui.blockingProtections(candidates, model.ProtectionTermination)
+
+		ui.app.QueueUpdateDraw(func() {
+			action := ui.terminateAction()
+			check := action.check
+			action.check = func(instance model.Instance) string {
+				if reason := check(instance); reason != "" {
+					return reason
+				}
+				return protected[instance.ID]
+			}
+			ui.ShowBatchPlan(action, instances)
+		})
+	}()
+}
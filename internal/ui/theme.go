@@ -0,0 +1,240 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nlamirault/e2c/internal/color"
+)
+
+const (
+	// skinInterval is the interval between two checks of the skin file
+	skinInterval = 2 * time.Second
+
+	// themeInterval is the interval between two checks of the time theme
+	themeInterval = time.Minute
+
+	// backgroundTimeout bounds the wait for the terminal to report its
+	// background color
+	backgroundTimeout = 300 * time.Millisecond
+)
+
+// Themes switching between dark and light, in addition to the ones of the
+// color package
+const (
+	themeTime     = "time"     // Light between ui.day_start and ui.night_start
+	themeTerminal = "terminal" // Following the background of the terminal
+	themeAuto     = "auto"     // Back to ui.theme, with :theme
+)
+
+// skinPath returns the path of the skin file, configured in ui.skin
+func (ui *UI) skinPath() string {
+	if ui.config().UI.Skin != "" {
+		return ui.config().UI.Skin
+	}
+	return color.DefaultSkinPath()
+}
+
+// loadSkin applies the colors of the theme, from the skin file if any,
+// before the views are created. The background of the terminal is queried
+// here, the UI not having taken over the terminal yet.
+func (ui *UI) loadSkin() {
+	palette, err := color.LoadSkin(ui.skinPath())
+	switch {
+	case err == nil:
+		ui.log.Info("Skin loaded", "path", ui.skinPath())
+	case !errors.Is(err, os.ErrNotExist):
+		ui.log.Error("Failed to load the skin, using the default colors", "error", err)
+	}
+	ui.palette = palette
+
+	if strings.EqualFold(ui.config().UI.Theme, themeTerminal) {
+		background, err := color.QueryBackground(backgroundTimeout)
+		if err != nil {
+			ui.log.Warn("Failed to detect the background of the terminal, switching the theme on time", "error", err)
+		} else {
+			ui.terminalTheme = color.ThemeOf(background)
+			ui.log.Info("Background of the terminal detected", "background", background.CSS(), "theme", ui.terminalTheme)
+		}
+	}
+
+	ui.theme = ui.resolveTheme(time.Now())
+	color.Apply(ui.palette.Colors(ui.theme))
+}
+
+// resolveTheme returns the theme to apply at a time: the one forced with
+// :theme, or the one of ui.theme
+func (ui *UI) resolveTheme(now time.Time) string {
+	mode := ui.themeOverride
+	if mode == "" {
+		mode = strings.ToLower(ui.config().UI.Theme)
+	}
+
+	switch mode {
+	case color.ThemeLight:
+		return color.ThemeLight
+	case themeTerminal:
+		if ui.terminalTheme != "" {
+			return ui.terminalTheme
+		}
+		return ui.timeTheme(now)
+	case themeTime:
+		return ui.timeTheme(now)
+	default:
+		return color.ThemeDark
+	}
+}
+
+// timeTheme returns the light theme between ui.day_start and
+// ui.night_start, the dark one otherwise
+func (ui *UI) timeTheme(now time.Time) string {
+	day := minuteOfDay(ui.config().UI.DayStart, 7*60)
+	night := minuteOfDay(ui.config().UI.NightStart, 19*60)
+	minute := now.Hour()*60 + now.Minute()
+
+	light := minute >= day && minute < night
+	if day > night {
+		// e.g. from 22:00 to 06:00 for a night shift
+		light = minute >= day || minute < night
+	}
+	if light {
+		return color.ThemeLight
+	}
+	return color.ThemeDark
+}
+
+// minuteOfDay returns the minute of the day of a time, HH:MM, or the default
+// one if it is invalid
+func minuteOfDay(value string, def int) int {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return def
+	}
+	return parsed.Hour()*60 + parsed.Minute()
+}
+
+// applyTheme switches to the theme to apply now, if it changed, and
+// re-renders all the views with its colors. It runs in the UI goroutine.
+func (ui *UI) applyTheme() {
+	theme := ui.resolveTheme(time.Now())
+	if theme == ui.theme {
+		return
+	}
+	ui.theme = theme
+	ui.log.Info("Theme switched", "theme", theme)
+	ui.UpdateTheme(ui.palette.Colors(theme))
+	ui.statusBar.SetStatus(fmt.Sprintf("Switched to the %s theme", theme))
+}
+
+// watchTheme switches the time theme at ui.day_start and ui.night_start,
+// until the UI is stopped
+func (ui *UI) watchTheme() {
+	ticker := time.NewTicker(themeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ui.app.QueueUpdateDraw(ui.applyTheme)
+		case <-ui.ctx.Done():
+			return
+		}
+	}
+}
+
+// runThemeCommand displays the theme, or forces the dark or the light one
+// for the session, auto going back to ui.theme
+func (ui *UI) runThemeCommand(args []string) error {
+	switch len(args) {
+	case 0:
+		mode := ui.themeOverride
+		if mode == "" {
+			mode = valueOrDefault(strings.ToLower(ui.config().UI.Theme), color.ThemeDark)
+		}
+		ui.statusBar.SetStatus(fmt.Sprintf("Theme: %s (%s)", ui.theme, mode))
+		return nil
+	case 1:
+	default:
+		return errors.New("at most one theme expected")
+	}
+
+	switch theme := strings.ToLower(args[0]); theme {
+	case color.ThemeDark, color.ThemeLight, themeTime:
+		ui.themeOverride = theme
+	case themeAuto:
+		ui.themeOverride = ""
+	default:
+		return fmt.Errorf("unknown theme %q", args[0])
+	}
+
+	ui.applyTheme()
+	return nil
+}
+
+// watchSkin applies the colors of the skin file each time it is modified,
+// until the UI is stopped
+func (ui *UI) watchSkin() {
+	path := ui.skinPath()
+	modified := skinModTime(path)
+
+	ticker := time.NewTicker(skinInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current := skinModTime(path)
+			if current.Equal(modified) {
+				continue
+			}
+			modified = current
+
+			palette, err := color.LoadSkin(path)
+			if errors.Is(err, os.ErrNotExist) {
+				// The skin was removed, go back to the default colors
+				palette, err = color.DefaultPalette, nil
+			}
+			ui.app.QueueUpdateDraw(func() {
+				if err != nil {
+					ui.log.Error("Failed to reload the skin", "error", err)
+					ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+					return
+				}
+				ui.log.Info("Skin reloaded", "path", path)
+				ui.palette = palette
+				ui.UpdateTheme(palette.Colors(ui.theme))
+				ui.statusBar.SetStatus("Skin reloaded")
+			})
+		case <-ui.ctx.Done():
+			return
+		}
+	}
+}
+
+// skinModTime returns the modification time of the skin file, zero if it
+// does not exist
+func skinModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// UpdateTheme applies new colors to all the views. The dialogs which are
+// open keep their colors until they are opened again.
+func (ui *UI) UpdateTheme(colors color.Colors) {
+	color.Apply(colors)
+
+	ui.pages.SetBackgroundColor(color.AppColors.Background)
+	ui.instancesView.UpdateTheme()
+	ui.overviewPanel.UpdateTheme()
+	ui.statusBar.UpdateTheme()
+	ui.helpView.UpdateTheme()
+}
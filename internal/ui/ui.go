@@ -7,39 +7,169 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
-	"github.com/nlamirault/e2c/internal/aws"
 	"github.com/nlamirault/e2c/internal/color"
 	"github.com/nlamirault/e2c/internal/config"
-	"github.com/nlamirault/e2c/internal/model"
+	"github.com/nlamirault/e2c/internal/featureflags"
+	"github.com/nlamirault/e2c/internal/keymap"
+	"github.com/nlamirault/e2c/internal/logger"
+	"github.com/nlamirault/e2c/internal/plugin"
+	"github.com/nlamirault/e2c/internal/schedule"
+	"github.com/nlamirault/e2c/internal/terraform"
+	"github.com/nlamirault/e2c/internal/trace"
+	"github.com/nlamirault/e2c/internal/tunnel"
+	"github.com/nlamirault/e2c/internal/watch"
+	"github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/store"
 )
 
 // UI manages the terminal UI for e2c
 type UI struct {
-	app           *tview.Application
-	pages         *tview.Pages
-	instancesView *InstancesView
-	overviewPanel *OverviewPanel
-	statusBar     *StatusBar
-	helpView      *HelpView
-	log           *slog.Logger
-	ec2Client     *aws.EC2Client
-	config        *config.Config
-	ctx           context.Context
-	cancel        context.CancelFunc
-	refreshTicker *time.Ticker
-	refreshMutex  sync.Mutex
-	filter        string
-}
-
-// NewUI creates a new UI instance
-func NewUI(log *slog.Logger, ec2Client *aws.EC2Client, cfg *config.Config) *UI {
+	app             *tview.Application
+	pages           *tview.Pages
+	instancesView   *InstancesView
+	overviewPanel   *OverviewPanel
+	statusBar       *StatusBar
+	helpView        *HelpView
+	log             *slog.Logger
+	awsLog          *slog.Logger                  // Logger of the EC2 clients created by the UI
+	active          atomic.Pointer[activeSession] // Replaced from the UI goroutine, read from any
+	sessions        atomic.Uint64                 // Last ID given to a session
+	ctx             context.Context
+	cancel          context.CancelFunc
+	refreshTicker   *time.Ticker
+	refresh         time.Duration // Interval of the auto-refresh
+	refreshPaused   bool          // Auto-refresh is paused
+	refreshFlag     time.Duration // Interval set by the refresh_interval_override flag, 0 if none
+	refreshMutex    sync.Mutex
+	nav             *Navigation
+	loaded          bool          // Instances were loaded at least once
+	firstPage       chan error    // Signals the first page of instances to the startup
+	started         time.Time     // Start of e2c, to measure the startup
+	configLoad      time.Duration // Duration of the loading of the configuration, 0 if unknown
+	terraform       *terraform.Index
+	batchCancel     context.CancelFunc         // Cancels the running batch, nil if none
+	plugins         []*plugin.Column           // Columns populated by external commands
+	hooks           []*plugin.Hook             // Commands run after the actions on the instances
+	store           *store.Store               // Data shared between the AWS client and the views
+	credentials     *aws.CredentialSource      // Source of the credentials, nil until resolved
+	reexec          []string                   // Command line to run once the UI is stopped, if any
+	reexecEnv       []string                   // Environment of the command to run
+	features        features                   // Optional features disabled for the session
+	keymap          *keymap.Keymap             // Keys bound to the actions of the instances view
+	restoreView     string                     // View of the previous session, opened once loaded
+	scanning        atomic.Bool                // The protections scan is running
+	protectionCalls map[string]*protectionCall // Protections being retrieved, by instance
+	protectionStale map[string]bool            // Protections invalidated, by instance
+	protectionMutex sync.Mutex
+	tracer          *trace.Tracer   // Traces of the user actions
+	exporter        *trace.Exporter // Exporter of the traces and of the metrics, nil if the telemetry is disabled
+	latencyOverlay  atomic.Bool     // The latency of the last action is displayed
+	accounts        []*account      // Accounts of the aggregated instance list, if configured
+	accountsMutex   sync.Mutex
+	tunnels         *tunnel.Manager              // Port forwarding sessions
+	sessionsView    *SessionsView                // Last sessions view displayed, nil if none
+	schedules       *schedule.Runner             // Local schedules stopping the instances
+	scheduled       []*schedule.Schedule         // Schedules of the configuration e2c is started with
+	scheduleConfig  *config.Config               // Configuration e2c is started with, of the clients of the schedules
+	scheduleClients map[workspace]*aws.EC2Client // Clients of the schedules, by profile and region
+	scheduleMutex   sync.Mutex
+	watcher         *watch.Watcher   // Instances watched at a fast cadence
+	palette         color.Palette    // Colors of the dark and light themes
+	theme           string           // Theme applied, dark or light
+	themeOverride   string           // Theme forced with :theme, empty for ui.theme
+	terminalTheme   string           // Theme of the background of the terminal, empty if unknown
+	regions         *regionScheduler // Regions displayed before, refreshed in the background
+	lockedFilter    string           // Filter given with --filter, empty if none or cleared
+	toast           *toast           // Summary of the last long operation done, nil once hidden
+	bell            bool             // The bell rings at the next draw
+	flashUntil      time.Time        // End of the flash of the screen
+
+	// ID of the instance e2c runs on, nil if none or unknown yet
+	selfID atomic.Pointer[string]
+
+	// Feature flags, evaluated against the profile, the region and the ARN
+	// of the caller, nil until it is known
+	flags  *featureflags.Flags
+	caller atomic.Pointer[string]
+}
+
+// activeSession is the AWS session displayed: the EC2 client, the
+// configuration with its profile and region, and the caches of the data
+// fetched with the client. It is never modified once published: switching
+// the profile, the region or the context publishes a new one, so that the
+// goroutines reading it never see a client with the configuration or the
+// caches of another one.
+type activeSession struct {
+	id     uint64 // Tags the data dispatched to the store for the session
+	client *aws.EC2Client
+	config *config.Config
+	cache  *asyncCache   // Data of the detail tabs, by instance
+	names  *nameResolver // Names of the AMIs, subnets and key pairs, shared by the views
+
+	// Cancelled once another session is displayed, to stop its refreshes
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newSession returns a session of a client and a configuration, with a new
+// ID and empty caches
+func (ui *UI) newSession(client *aws.EC2Client, cfg *config.Config) *activeSession {
+	ctx, cancel := context.WithCancel(ui.ctx)
+	return &activeSession{
+		id:     ui.sessions.Add(1),
+		client: client,
+		config: cfg,
+		cache:  newAsyncCache(asyncTTL),
+		names:  newNameResolver(ui, resolveTTL),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// workspace returns the profile and the region of the session
+func (s *activeSession) workspace() workspace {
+	return workspace{profile: s.config.AWS.Profile, region: s.client.GetRegion()}
+}
+
+// ec2Client returns the EC2 client of the profile and region displayed
+func (ui *UI) ec2Client() *aws.EC2Client {
+	return ui.active.Load().client
+}
+
+// config returns the configuration of the session displayed, which must not
+// be modified
+func (ui *UI) config() *config.Config {
+	return ui.active.Load().config
+}
+
+// asyncCache returns the cache of the data of the detail tabs of the session
+// displayed
+func (ui *UI) asyncCache() *asyncCache {
+	return ui.active.Load().cache
+}
+
+// names returns the resolver of the names of the resources of the session
+// displayed
+func (ui *UI) names() *nameResolver {
+	return ui.active.Load().names
+}
+
+// NewUI creates a new UI instance. The exporter of the telemetry, nil if it
+// is disabled, must be the one recording the calls of the EC2 client, see
+// Telemetry.
+func NewUI(log *slog.Logger, ec2Client *aws.EC2Client, cfg *config.Config, exporter *trace.Exporter) *UI {
 	ctx, cancel := context.WithCancel(context.Background())
+	root := log
+	log = logger.Subsystem(root, logger.SubsystemUI)
 
 	// Initialize colors
 	color.InitializeColors()
@@ -48,27 +178,99 @@ func NewUI(log *slog.Logger, ec2Client *aws.EC2Client, cfg *config.Config) *UI {
 		app:       tview.NewApplication(),
 		pages:     tview.NewPages(),
 		log:       log,
-		ec2Client: ec2Client,
-		config:    cfg,
+		awsLog:    logger.Subsystem(root, logger.SubsystemAWS),
 		ctx:       ctx,
 		cancel:    cancel,
+		nav:       NewNavigation(viewInstances),
+		firstPage: make(chan error, 1),
+		started:   time.Now(),
+		plugins:   plugin.NewColumns(logger.Subsystem(root, logger.SubsystemPlugin), cfg.Plugins.Columns),
+		hooks:     plugin.NewHooks(logger.Subsystem(root, logger.SubsystemPlugin), cfg.Plugins.Hooks),
+		store:     store.New(ctx, log),
+		regions:   newRegionScheduler(),
+		exporter:  exporter,
 	}
 
+	// Apply the actions on the shared data
+	go ui.store.Run()
+
+	session := ui.newSession(ec2Client, cfg)
+	ui.active.Store(session)
+	ui.store.Dispatch(store.SessionStarted{Session: session.id})
+
+	// Run the port forwarding sessions as child processes
+	ui.tunnels = tunnel.NewManager(logger.Subsystem(root, logger.SubsystemTunnel), ui.tunnelsChanged)
+
+	// Stop the instances of the local schedules, in the profile and the
+	// region e2c is started with unless they have their own
+	scheduleLog := logger.Subsystem(root, logger.SubsystemSchedule)
+	schedules := schedule.NewSchedules(scheduleLog, cfg.Schedules)
+	schedule.SetDefaults(schedules, cfg.AWS.Profile, ec2Client.GetRegion())
+	ui.schedules = schedule.NewRunner(scheduleLog, schedules, ui.applySchedule)
+	ui.scheduled = schedules
+	ui.scheduleConfig = cfg
+	ui.scheduleClients = map[workspace]*aws.EC2Client{
+		{profile: cfg.AWS.Profile, region: ec2Client.GetRegion()}: ec2Client,
+	}
+
+	// Check the instances watched
+	ui.watcher = watch.New(logger.Subsystem(root, logger.SubsystemWatch), cfg.Watch.Interval, cfg.Watch.Webhook, ui.watchedChanged)
+
+	// Trace the user actions
+	ui.tracer = newTracer(ui)
+
+	// Roll the features out gradually
+	ui.flags = featureflags.New(cfg.FeatureFlags)
+
+	// Ask for the MFA codes of the assumed role in the UI
+	ec2Client.SetMFAPrompt(ui.promptMFA)
+
+	// Drop the data cached for the instances changed by the actions
+	ec2Client.SetMutationHook(ui.instanceMutated)
+
+	// Create the clients of the accounts whose instances are aggregated
+	ui.setupAccounts(ec2Client.GetRegion())
+
+	// Apply the skin and the border style before creating the views
+	ui.loadSkin()
+	ui.applyBorders()
+
 	// Initialize components
 	ui.instancesView = NewInstancesView(ui)
 	ui.overviewPanel = NewOverviewPanel(ui)
 	ui.statusBar = NewStatusBar(ui)
-	ui.helpView = NewHelpView()
+	ui.helpView = NewHelpView(cfg.Allows)
 
-	// Set initial region in status bar
+	// Set initial region and context in status bar
 	ui.statusBar.SetRegion(ec2Client.GetRegion())
+	if cfg.Context != "" {
+		ui.statusBar.SetContext(cfg.Context, cfg.ReadOnly())
+	}
 
 	// Set up the main layout
 	ui.setupLayout()
 
 	// Set up key bindings
+	ui.loadKeymap()
 	ui.setupKeyBindings()
 
+	// Track the refreshes of the region displayed, kept in the background
+	// once left
+	ui.store.Subscribe(func(state *store.State, action store.Action) {
+		if loaded, ok := action.(store.InstancesLoaded); ok && loaded.Complete {
+			ui.regions.loaded()
+		}
+	})
+
+	// Scan the protections of the instances once they are loaded
+	if cfg.Allows(config.LevelAdmin) {
+		ui.store.Subscribe(func(state *store.State, action store.Action) {
+			if loaded, ok := action.(store.InstancesLoaded); ok && loaded.Complete {
+				ui.startProtectionScan()
+			}
+		})
+	}
+
 	return ui
 }
 
@@ -80,11 +282,44 @@ func (ui *UI) Start() error {
 	// Start refresh ticker
 	ui.startRefreshTicker()
 
-	// Initial data load
-	ui.RefreshInstances()
+	// Warm up credentials and load the initial data while the UI is displayed
+	ui.warmup()
+
+	// Watch the AWS Health events affecting EC2
+	go ui.pollHealth()
+
+	// Look up the instance e2c runs on, to guard against stopping it
+	go ui.detectSelf()
+
+	// Apply the changes of the skin file
+	go ui.watchSkin()
+
+	// Switch the time theme at the start of the day and of the night
+	go ui.watchTheme()
+
+	// Apply the local schedules while running
+	go ui.schedules.Run(ui.ctx)
+
+	// Check the instances watched while running
+	go ui.watcher.Run(ui.ctx)
+
+	// Index the instances managed by Terraform
+	if ui.config().Terraform.Enabled {
+		go ui.loadTerraformIndex(ui.ec2Client())
+	}
 
 	// Run the application
-	if err := ui.app.Run(); err != nil {
+	err := ui.app.Run()
+
+	// Close the port forwarding sessions with e2c
+	ui.tunnels.StopAll()
+
+	// Send the last traces and metrics
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ui.shutdownTelemetry(ctx)
+
+	if err != nil {
 		return fmt.Errorf("error running application: %w", err)
 	}
 
@@ -102,11 +337,29 @@ func (ui *UI) Stop() {
 	ui.app.Stop()
 }
 
+// SetStartTime sets the time e2c started, before loading the configuration,
+// from which the startup is measured
+func (ui *UI) SetStartTime(started time.Time) {
+	ui.started = started
+}
+
+// SetConfigLoadDuration sets how long the configuration took to load before
+// the UI was created, displayed as the first phase of the startup
+func (ui *UI) SetConfigLoadDuration(duration time.Duration) {
+	ui.configLoad = duration
+}
+
+// SetScreen sets the screen the UI is drawn on, e.g. a simulation screen to
+// run the UI headlessly
+func (ui *UI) SetScreen(screen tcell.Screen) {
+	ui.app.SetScreen(screen)
+}
+
 // setupLayout sets up the main layout of the application
 func (ui *UI) setupLayout() {
 	// Create main layout
 	grid := tview.NewGrid().
-		SetRows(5, 0, 1, 1). // Overview panel, main content, status bar, help
+		SetRows(6, 0, 1, 1). // Overview panel, main content, status bar, help
 		SetColumns(0).       // Full width
 		SetBorders(false)
 
@@ -138,7 +391,7 @@ func (ui *UI) setupKeyBindings() {
 		case tcell.KeyEscape:
 			// Go back to main page if on a modal
 			if ui.pages.HasPage("modal") {
-				ui.pages.RemovePage("modal")
+				ui.closeModal()
 				return nil
 			}
 		}
@@ -147,41 +400,25 @@ func (ui *UI) setupKeyBindings() {
 		name, _ := ui.pages.GetFrontPage()
 		switch {
 		case ui.pages.HasPage("main") && name == "main":
-			switch event.Key() {
-			case tcell.KeyRune:
+			if ui.runKeyAction(event) {
+				return nil
+			}
+		case name == "error":
+			if event.Key() == tcell.KeyRune {
 				switch event.Rune() {
-				case 'q':
-					ui.Stop()
-					return nil
 				case 'r':
-					ui.RefreshInstances()
-					return nil
-				case 'f':
-					ui.ShowFilterDialog()
-					return nil
-				case '?':
-					ui.ShowHelpDialog()
-					return nil
-				case 's':
-					ui.handleStartInstance()
-					return nil
-				case 'p':
-					ui.handleStopInstance()
-					return nil
-				case 'b':
-					ui.handleRebootInstance()
-					return nil
-				case 't':
-					ui.handleTerminateInstance()
-					return nil
-				case 'c':
-					ui.handleConnectInstance()
-					return nil
+					ui.retryFromErrorPanel()
+				case 'P':
+					ui.ShowProfileDialog()
+				case 'x':
+					ui.reexecWithCredentials()
 				case 'l':
-					ui.handleViewLogs()
-					return nil
+					ui.ssoLogin()
+				case 'q':
+					ui.Stop()
 				}
 			}
+			return nil
 		}
 		return event
 	})
@@ -193,69 +430,169 @@ func (ui *UI) RefreshInstances() {
 	defer ui.refreshMutex.Unlock()
 
 	ui.statusBar.SetStatus("Refreshing instances...")
+	op := ui.statusBar.StartOperation("refresh")
+
+	filters := ui.serverFilters()
+	ctx, end := ui.operationCtx("refresh", nil)
+	// The refresh is stopped by a switch of profile or region, and the
+	// instances it already retrieved are tagged with its session, dropped
+	// by the store once another one is displayed
+	active := ui.active.Load()
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(active.ctx, cancel)
+	region := active.client.GetRegion()
+	start := time.Now()
 
 	go func() {
-		instances, err := ui.ec2Client.ListInstances(ui.ctx)
+		defer cancel()
+		defer stop()
+
+		// Dispatch the pages as they are retrieved, the views subscribed to
+		// the store render them while the next pages are loading
+		pages := 0
+		var instances []model.Instance
+		var err error
+		if len(active.config.Accounts) > 0 {
+			instances, err = ui.listAccountsInstances(ctx, filters)
+		} else {
+			instances, err = active.client.ListInstancesPages(ctx, filters, func(page int, instances []model.Instance) {
+				pages = page
+				ui.signalFirstPage(nil)
+				ui.store.Dispatch(store.InstancesLoaded{Instances: instances, Page: page, Session: active.id})
+			})
+		}
+		if active.ctx.Err() != nil {
+			// Another session is displayed, and refreshed on its own
+			end(nil)
+			ui.app.QueueUpdateDraw(op.Done)
+			return
+		}
+		ui.exporter.Metrics().RecordRefresh(region, time.Since(start), err)
+		end(err)
 		if err != nil {
 			ui.log.Error("Failed to list instances", "error", err)
-			ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+			ui.signalFirstPage(err)
+			ui.app.QueueUpdateDraw(func() {
+				op.Done()
+				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				// Without any instance to display, or once the credentials
+				// have expired, explain how to recover
+				if !ui.loaded || aws.ClassifyError(err) == aws.ErrorExpiredCredentials {
+					ui.ShowErrorPanel(err)
+				}
+			})
 			return
 		}
 
-		// Count running and stopped instances
-		running := 0
-		stopped := 0
-		for _, instance := range instances {
-			if instance.IsRunning() {
-				running++
-			} else if instance.IsStopped() {
-				stopped++
-			}
+		// The last page is not reported by onPage but returned
+		if len(active.config.Accounts) == 0 {
+			pages++
 		}
+		ui.signalFirstPage(nil)
+		ui.store.Dispatch(store.InstancesLoaded{
+			Instances: instances,
+			Page:      pages,
+			Complete:  true,
+			Session:   active.id,
+		})
 
-		// Apply filter if present
-		filteredInstances := ui.applyFilter(instances)
-
-		// Update UI with instances
 		ui.app.QueueUpdateDraw(func() {
-			ui.instancesView.UpdateInstances(filteredInstances)
-			ui.overviewPanel.Update(len(instances), running, stopped, ui.ec2Client.GetRegion())
-			ui.statusBar.SetRegion(ui.ec2Client.GetRegion())
-			ui.statusBar.SetStatus(fmt.Sprintf("Found %d instances", len(filteredInstances)))
+			op.Done()
+			if ui.active.Load() != active {
+				return
+			}
+			ui.loaded = true
+			ui.pages.RemovePage("error")
+			ui.statusBar.SetRegion(region)
 		})
+
+		ui.refreshPluginColumns(instances)
+		ui.refreshScheduledEvents(active)
 	}()
 }
 
-// startRefreshTicker starts a ticker to refresh instances periodically
-func (ui *UI) startRefreshTicker() {
-	interval := ui.config.AWS.RefreshInterval
-	if interval <= 0 {
-		interval = 30 * time.Second
+// refreshScheduledEvents retrieves the scheduled events of the instances of
+// a session to flag them in the table
+func (ui *UI) refreshScheduledEvents(active *activeSession) {
+	var events map[string][]model.ScheduledEvent
+	if len(active.config.Accounts) > 0 {
+		events = ui.listAccountsEvents(active.ctx)
+	} else {
+		if !ui.featureEnabled(featureEvents) {
+			return
+		}
+		var err error
+		events, err = active.client.ListScheduledEvents(active.ctx)
+		if err != nil {
+			if active.ctx.Err() != nil || ui.checkFeatureError(featureEvents, err) {
+				return
+			}
+			ui.log.Error("Failed to list scheduled events", "error", err)
+			return
+		}
+	}
+	if active.ctx.Err() != nil {
+		return
 	}
 
-	ui.refreshTicker = time.NewTicker(interval)
+	ui.store.Dispatch(store.EventsLoaded{Events: events, Session: active.id})
+	if len(events) > 0 {
+		ui.app.QueueUpdateDraw(func() {
+			ui.statusBar.SetStatus(fmt.Sprintf("⚠ %d instances have scheduled events", len(events)))
+		})
+	}
+}
 
-	go func() {
-		for {
-			select {
-			case <-ui.refreshTicker.C:
-				ui.RefreshInstances()
-			case <-ui.ctx.Done():
+// refreshPluginColumns runs the commands of the stale plugin columns and
+// redraws the table when values were updated
+func (ui *UI) refreshPluginColumns(instances []model.Instance) {
+	if len(ui.plugins) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		ids = append(ids, instance.ID)
+	}
+
+	for _, column := range ui.plugins {
+		go func(column *plugin.Column) {
+			updated, err := column.Refresh(ui.ctx, ids)
+			if err != nil {
+				ui.log.Error("Failed to refresh plugin column", "column", column.Name(), "error", err)
 				return
 			}
-		}
-	}()
+			if updated {
+				ui.app.QueueUpdateDraw(ui.instancesView.redraw)
+			}
+		}(column)
+	}
 }
 
-// applyFilter applies the current filter to instances
-func (ui *UI) applyFilter(instances []model.Instance) []model.Instance {
-	if ui.filter == "" {
+// loadTerraformIndex reads the configured Terraform state files, the ones
+// stored in S3 backends with the client
+func (ui *UI) loadTerraformIndex(client *aws.EC2Client) {
+	index, err := terraform.LoadIndex(ui.ctx, ui.log, ui.config().Terraform.StateFiles, client)
+	if err != nil {
+		ui.log.Error("Failed to load Terraform states", "error", err)
+		return
+	}
+
+	ui.app.QueueUpdate(func() {
+		ui.terraform = index
+	})
+}
+
+// applyFilter applies the client side part of the filter to instances: the
+// free text and the flags
+func (ui *UI) applyFilter(instances []model.Instance, filter model.Filter) []model.Instance {
+	if filter.Text == "" && len(filter.Flags) == 0 {
 		return instances
 	}
 
 	filtered := make([]model.Instance, 0)
 	for _, instance := range instances {
-		if ui.matchesFilter(instance) {
+		if ui.matchesFilter(instance, filter.Text) && filter.MatchesFlags(instance) {
 			filtered = append(filtered, instance)
 		}
 	}
@@ -263,25 +600,36 @@ func (ui *UI) applyFilter(instances []model.Instance) []model.Instance {
 	return filtered
 }
 
-// matchesFilter checks if an instance matches the current filter
-func (ui *UI) matchesFilter(instance model.Instance) bool {
-	filter := ui.filter
+// matchesFilter checks if an instance matches a free text filter
+func (ui *UI) matchesFilter(instance model.Instance, filter string) bool {
 	if filter == "" {
 		return true
 	}
 
 	// Match against various fields
-	return containsIgnoreCase(instance.ID, filter) ||
+	if containsIgnoreCase(instance.ID, filter) ||
 		containsIgnoreCase(instance.Name, filter) ||
 		containsIgnoreCase(instance.Type, filter) ||
 		containsIgnoreCase(instance.State, filter) ||
 		containsIgnoreCase(instance.PrivateIP, filter) ||
-		containsIgnoreCase(instance.PublicIP, filter)
+		containsIgnoreCase(instance.PublicIP, filter) ||
+		containsIgnoreCase(instance.Account, filter) {
+		return true
+	}
+
+	// Match against the tags displayed as columns
+	for _, key := range ui.config().UI.TagColumns {
+		if containsIgnoreCase(instance.Tags[key], filter) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // SetFilter sets the instance filter
 func (ui *UI) SetFilter(filter string) {
-	ui.filter = filter
+	ui.nav.StateOf(viewInstances).Filter = filter
 	ui.RefreshInstances()
 }
 
@@ -291,7 +639,8 @@ func (ui *UI) ShowFilterDialog() {
 	ui.statusBar.SetMode("filtering")
 
 	form := tview.NewForm()
-	form.AddInputField("Filter:", ui.filter, 30, nil, nil)
+	form.AddInputField("Filter:", ui.nav.StateOf(viewInstances).Filter, 30, nil, nil)
+	form.GetFormItem(0).(*tview.InputField).SetPlaceholder("name:web-* state:running text")
 	form.AddButton("Apply", func() {
 		filter := form.GetFormItem(0).(*tview.InputField).GetText()
 		ui.SetFilter(filter)
@@ -331,6 +680,15 @@ func (ui *UI) ShowFilterDialog() {
 
 // ShowHelpDialog displays the help dialog
 
+// formatTime formats a timestamp in the local time zone using the
+// configured time format
+func (ui *UI) formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return ui.config().UI.FormatTime(t)
+}
+
 // GetColors returns the application colors
 func (ui *UI) GetColors() color.Colors {
 	return color.AppColors
@@ -341,24 +699,36 @@ func (ui *UI) ShowHelpDialog() {
 		SetDynamicColors(true).
 		SetTextAlign(tview.AlignLeft)
 
-	helpText.SetText(`
-[::b]e2c - AWS EC2 Terminal UI Manager[::-]
-
-[yellow]Keyboard Shortcuts:[-]
-  [green]?[white]      Help (this screen)[-]
-  [green]q[white]      Quit[-]
-  [green]r[white]      Refresh instances[-]
-  [green]f[white]      Filter instances[-]
-  [green]s[white]      Start selected instance[-]
-  [green]p[white]      Stop selected instance[-]
-  [green]b[white]      Reboot selected instance[-]
-  [green]t[white]      Terminate selected instance[-]
-  [green]c[white]      Connect to selected instance via SSH[-]
-  [green]l[white]      View instance logs/console output[-]
-  [green]Esc[white]    Close dialogs[-]
-
-[yellow]Press Esc to close this help[-]
-`)
+	var b strings.Builder
+	b.WriteString("\n[::b]e2c - AWS EC2 Terminal UI Manager[::-]\n\n")
+	b.WriteString("[yellow]Keyboard Shortcuts:[-]\n")
+	hidden := 0
+	for _, binding := range ui.keymap.Bindings() {
+		// Only the actions granted by the level of the user, and enabled
+		if !ui.actionFlagged(binding.Action) {
+			continue
+		}
+		if !ui.config().Allows(actionLevel(binding.Action)) {
+			hidden++
+			continue
+		}
+		fmt.Fprintf(&b, "  [green]%-6s[-] %s[-]\n", keymap.Display(binding.Key), binding.Description)
+	}
+	b.WriteString("  [green]Esc[-]    Close dialogs[-]\n")
+	fmt.Fprintf(&b, "\n[yellow]Level:[-] %s", ui.config().Level())
+	if hidden > 0 {
+		fmt.Fprintf(&b, " [gray](%d actions require a higher level)[-]", hidden)
+	}
+	b.WriteString("\n")
+	b.WriteString("\n[gray]:keys lists the bindings, the level they require and where they come from[-]\n")
+	if disabled := ui.disabledFeatures(); len(disabled) > 0 {
+		b.WriteString("\n[yellow]Disabled features (for the session):[-]\n")
+		for _, feature := range disabled {
+			fmt.Fprintf(&b, "  [red]%s[-]: %s[-]\n", feature[0], tview.Escape(feature[1]))
+		}
+	}
+	b.WriteString("\n[yellow]Press Esc to close this help[-]\n")
+	helpText.SetText(b.String())
 
 	helpText.SetBorder(true).SetTitle("Help")
 
@@ -389,7 +759,7 @@ func (ui *UI) ShowConfirmDialog(title, message string, onConfirm func()) {
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
 			ui.pages.RemovePage("modal")
 			if buttonLabel == "Yes" {
-				onConfirm()
+				ui.traceAction("confirm "+title, nil, onConfirm)
 			}
 		})
 
@@ -435,6 +805,12 @@ func (ui *UI) ShowInfoDialog(title, message string) {
 
 // handleStartInstance handles starting the selected instance
 func (ui *UI) handleStartInstance() {
+	// Apply the action to the marked instances if any
+	if marked := ui.instancesView.GetMarkedInstances(); len(marked) > 0 {
+		ui.ShowBatchPlan(ui.startAction(), marked)
+		return
+	}
+
 	selectedInstance := ui.instancesView.GetSelectedInstance()
 	if selectedInstance == nil {
 		ui.statusBar.SetError("No instance selected")
@@ -451,21 +827,26 @@ func (ui *UI) handleStartInstance() {
 		fmt.Sprintf("Are you sure you want to start instance %s?", selectedInstance.DisplayName()),
 		func() {
 			ui.statusBar.SetStatus(fmt.Sprintf("Starting instance %s...", selectedInstance.ID))
+			op := ui.statusBar.StartOperation("start " + selectedInstance.ID)
 
+			ctx := ui.actionCtx()
 			go func() {
-				err := ui.ec2Client.StartInstance(ui.ctx, selectedInstance.ID)
+				err := ui.clientFor(*selectedInstance).StartInstance(ctx, selectedInstance.ID)
 				if err != nil {
 					ui.app.QueueUpdateDraw(func() {
-						ui.log.Error("Failed to start instance", "error", err)
-						ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+						op.Done()
+						ui.startFailed(*selectedInstance, err)
 					})
 					return
 				}
 
 				ui.app.QueueUpdateDraw(func() {
+					op.Done()
 					ui.statusBar.SetStatus(fmt.Sprintf("Started instance %s", selectedInstance.ID))
 					ui.RefreshInstances()
 				})
+
+				ui.runHooks(plugin.EventStart, *selectedInstance)
 			}()
 		},
 	)
@@ -473,6 +854,12 @@ func (ui *UI) handleStartInstance() {
 
 // handleStopInstance handles stopping the selected instance
 func (ui *UI) handleStopInstance() {
+	// Apply the action to the marked instances if any
+	if marked := ui.instancesView.GetMarkedInstances(); len(marked) > 0 {
+		ui.ShowBatchPlan(ui.stopAction(), marked)
+		return
+	}
+
 	selectedInstance := ui.instancesView.GetSelectedInstance()
 	if selectedInstance == nil {
 		ui.statusBar.SetError("No instance selected")
@@ -484,16 +871,22 @@ func (ui *UI) handleStopInstance() {
 		return
 	}
 
-	ui.ShowConfirmDialog(
+	ui.confirmDestructiveOn(
+		*selectedInstance,
+		"stop",
 		"Stop Instance",
-		fmt.Sprintf("Are you sure you want to stop instance %s?", selectedInstance.DisplayName()),
+		ui.withNotice(fmt.Sprintf("Are you sure you want to stop instance %s?", selectedInstance.DisplayName()), *selectedInstance),
+		instanceConfirmValues(*selectedInstance),
 		func() {
 			ui.statusBar.SetStatus(fmt.Sprintf("Stopping instance %s...", selectedInstance.ID))
+			op := ui.statusBar.StartOperation("stop " + selectedInstance.ID)
 
+			ctx := ui.actionCtx()
 			go func() {
-				err := ui.ec2Client.StopInstance(ui.ctx, selectedInstance.ID)
+				err := ui.clientFor(*selectedInstance).StopInstance(ctx, selectedInstance.ID)
 				if err != nil {
 					ui.app.QueueUpdateDraw(func() {
+						op.Done()
 						ui.log.Error("Failed to stop instance", "error", err)
 						ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
 					})
@@ -501,9 +894,12 @@ func (ui *UI) handleStopInstance() {
 				}
 
 				ui.app.QueueUpdateDraw(func() {
+					op.Done()
 					ui.statusBar.SetStatus(fmt.Sprintf("Stopped instance %s", selectedInstance.ID))
 					ui.RefreshInstances()
 				})
+
+				ui.runHooks(plugin.EventStop, *selectedInstance)
 			}()
 		},
 	)
@@ -511,6 +907,12 @@ func (ui *UI) handleStopInstance() {
 
 // handleRebootInstance handles rebooting the selected instance
 func (ui *UI) handleRebootInstance() {
+	// Apply the action to the marked instances if any
+	if marked := ui.instancesView.GetMarkedInstances(); len(marked) > 0 {
+		ui.ShowBatchPlan(ui.rebootAction(), marked)
+		return
+	}
+
 	selectedInstance := ui.instancesView.GetSelectedInstance()
 	if selectedInstance == nil {
 		ui.statusBar.SetError("No instance selected")
@@ -524,14 +926,17 @@ func (ui *UI) handleRebootInstance() {
 
 	ui.ShowConfirmDialog(
 		"Reboot Instance",
-		fmt.Sprintf("Are you sure you want to reboot instance %s?", selectedInstance.DisplayName()),
+		ui.withNotice(fmt.Sprintf("Are you sure you want to reboot instance %s?", selectedInstance.DisplayName()), *selectedInstance),
 		func() {
 			ui.statusBar.SetStatus(fmt.Sprintf("Rebooting instance %s...", selectedInstance.ID))
+			op := ui.statusBar.StartOperation("reboot " + selectedInstance.ID)
 
+			ctx := ui.actionCtx()
 			go func() {
-				err := ui.ec2Client.RebootInstance(ui.ctx, selectedInstance.ID)
+				err := ui.clientFor(*selectedInstance).RebootInstance(ctx, selectedInstance.ID)
 				if err != nil {
 					ui.app.QueueUpdateDraw(func() {
+						op.Done()
 						ui.log.Error("Failed to reboot instance", "error", err)
 						ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
 					})
@@ -539,42 +944,12 @@ func (ui *UI) handleRebootInstance() {
 				}
 
 				ui.app.QueueUpdateDraw(func() {
+					op.Done()
 					ui.statusBar.SetStatus(fmt.Sprintf("Rebooted instance %s", selectedInstance.ID))
 					ui.RefreshInstances()
 				})
-			}()
-		},
-	)
-}
-
-// handleTerminateInstance handles terminating the selected instance
-func (ui *UI) handleTerminateInstance() {
-	selectedInstance := ui.instancesView.GetSelectedInstance()
-	if selectedInstance == nil {
-		ui.statusBar.SetError("No instance selected")
-		return
-	}
-
-	ui.ShowConfirmDialog(
-		"Terminate Instance",
-		fmt.Sprintf("Are you sure you want to TERMINATE instance %s? This action cannot be undone!", selectedInstance.DisplayName()),
-		func() {
-			ui.statusBar.SetStatus(fmt.Sprintf("Terminating instance %s...", selectedInstance.ID))
 
-			go func() {
-				err := ui.ec2Client.TerminateInstance(ui.ctx, selectedInstance.ID)
-				if err != nil {
-					ui.app.QueueUpdateDraw(func() {
-						ui.log.Error("Failed to terminate instance", "error", err)
-						ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
-					})
-					return
-				}
-
-				ui.app.QueueUpdateDraw(func() {
-					ui.statusBar.SetStatus(fmt.Sprintf("Terminated instance %s", selectedInstance.ID))
-					ui.RefreshInstances()
-				})
+				ui.runHooks(plugin.EventReboot, *selectedInstance)
 			}()
 		},
 	)
@@ -593,20 +968,8 @@ func (ui *UI) handleConnectInstance() {
 		return
 	}
 
-	// Default username based on platform
-	defaultUser := "ec2-user"
-	if selectedInstance.Platform != "" {
-		if containsIgnoreCase(selectedInstance.Platform, "ubuntu") {
-			defaultUser = "ubuntu"
-		} else if containsIgnoreCase(selectedInstance.Platform, "debian") {
-			defaultUser = "admin"
-		} else if containsIgnoreCase(selectedInstance.Platform, "windows") {
-			defaultUser = "Administrator"
-		}
-	}
-
 	form := tview.NewForm()
-	form.AddInputField("Username:", defaultUser, 20, nil, nil)
+	form.AddInputField("Username:", defaultSSHUser(*selectedInstance), 20, nil, nil)
 	form.AddButton("Connect", func() {
 		username := form.GetFormItem(0).(*tview.InputField).GetText()
 		sshCommand := selectedInstance.GetSSHCommand(username)
@@ -631,56 +994,37 @@ func (ui *UI) handleConnectInstance() {
 	ui.pages.AddPage("modal", flex, true, true)
 }
 
-// handleViewLogs handles viewing the console output of the selected instance
+// defaultSSHUser returns the default user of an instance, based on its
+// platform
+func defaultSSHUser(instance model.Instance) string {
+	switch {
+	case containsIgnoreCase(instance.Platform, "ubuntu"):
+		return "ubuntu"
+	case containsIgnoreCase(instance.Platform, "debian"):
+		return "admin"
+	case containsIgnoreCase(instance.Platform, "windows"):
+		return "Administrator"
+	default:
+		return "ec2-user"
+	}
+}
+
+// handleViewLogs handles viewing the console output of the selected instance,
+// or of the marked instances
 func (ui *UI) handleViewLogs() {
+	// Show the console outputs of the marked instances if any
+	if marked := ui.instancesView.GetMarkedInstances(); len(marked) > 0 {
+		NewMultiConsoleView(ui, marked).Show()
+		return
+	}
+
 	selectedInstance := ui.instancesView.GetSelectedInstance()
 	if selectedInstance == nil {
 		ui.statusBar.SetError("No instance selected")
 		return
 	}
 
-	ui.statusBar.SetStatus(fmt.Sprintf("Fetching console output for instance %s...", selectedInstance.ID))
-
-	go func() {
-		output, err := ui.ec2Client.GetInstanceConsoleOutput(ui.ctx, selectedInstance.ID)
-		if err != nil {
-			ui.app.QueueUpdateDraw(func() {
-				ui.log.Error("Failed to get console output", "error", err)
-				ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
-			})
-			return
-		}
-
-		ui.app.QueueUpdateDraw(func() {
-			ui.statusBar.SetStatus("Showing console output")
-
-			textView := tview.NewTextView().
-				SetDynamicColors(true).
-				SetScrollable(true).
-				SetText(output)
-
-			textView.SetBorder(true).SetTitle(fmt.Sprintf("Console Output: %s", selectedInstance.DisplayName()))
-
-			// Center the text view
-			flex := tview.NewFlex().
-				AddItem(nil, 0, 1, false).
-				AddItem(tview.NewFlex().
-					AddItem(nil, 0, 1, false).
-					AddItem(textView, 80, 1, true).
-					AddItem(nil, 0, 1, false), 0, 8, true).
-				AddItem(nil, 0, 1, false)
-
-			flex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-				if event.Key() == tcell.KeyEscape {
-					ui.pages.RemovePage("modal")
-					return nil
-				}
-				return event
-			})
-
-			ui.pages.AddPage("modal", flex, true, true)
-		})
-	}()
+	NewConsoleView(ui, *selectedInstance).Show()
 }
 
 // containsIgnoreCase checks if a string contains another string, ignoring case
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/nlamirault/e2c/internal/color"
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/store"
+)
+
+// vpcRow is a row of the VPC view, either a VPC or one of its subnets
+type vpcRow struct {
+	vpc    *model.VPC
+	subnet *model.Subnet
+}
+
+// VPCView represents the read-only view of the VPCs and their subnets
+type VPCView struct {
+	ui    *UI
+	table *tview.Table
+	rows  []vpcRow
+	focus string // ID of the VPC or subnet to select once loaded
+}
+
+// NewVPCView creates a new VPC view, selecting the given VPC or subnet once loaded
+func NewVPCView(ui *UI, selectID string) *VPCView {
+	v := &VPCView{
+		ui:    ui,
+		table: tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		focus: selectID,
+	}
+
+	v.table.SetBorder(true).
+		SetTitle(fmt.Sprintf(" VPCs (%s) ", ui.ec2Client().GetRegion())).
+		SetBorderColor(color.AppColors.Border).
+		SetTitleColor(color.AppColors.Title)
+
+	v.table.SetSelectionChangedFunc(func(row, column int) {
+		if row > 0 {
+			ui.nav.StateOf(viewVPCs).Selected = row - 1
+		}
+	})
+
+	// Jump to the instances of the selected VPC or subnet
+	v.table.SetSelectedFunc(func(row, column int) {
+		if row <= 0 || row-1 >= len(v.rows) {
+			return
+		}
+		selected := v.rows[row-1]
+		ui.pages.RemovePage("modal")
+		ui.nav.Pop()
+		if selected.subnet != nil {
+			ui.SetFilter("subnet:" + selected.subnet.ID)
+		} else {
+			ui.SetFilter("vpc:" + selected.vpc.ID)
+		}
+	})
+
+	v.table.SetCell(0, 0, tview.NewTableCell(" Loading VPCs...").SetSelectable(false))
+
+	return v
+}
+
+// Show displays the VPC view and loads the VPCs
+func (v *VPCView) Show() {
+	flex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(nil, 0, 1, false).
+			AddItem(v.table, 110, 1, true).
+			AddItem(nil, 0, 1, false), 0, 8, true).
+		AddItem(nil, 0, 1, false)
+
+	page := v.ui.showNavPage(viewVPCs, flex, nil)
+
+	// Display the VPCs loaded previously while they are refreshed
+	if vpcs := v.ui.store.Snapshot().VPCs; vpcs != nil {
+		v.render(vpcs)
+	}
+
+	// Render the VPCs each time they are loaded, until the view is closed
+	var unsubscribe func()
+	unsubscribe = v.ui.store.Subscribe(func(state *store.State, action store.Action) {
+		switch action.(type) {
+		case store.VPCsLoaded, store.SessionStarted:
+		default:
+			return
+		}
+		v.ui.app.QueueUpdateDraw(func() {
+			if _, front := v.ui.pages.GetFrontPage(); front != page {
+				unsubscribe()
+				return
+			}
+			v.render(state.VPCs)
+		})
+	})
+
+	// The VPCs of the session are dropped once another one is displayed
+	active := v.ui.active.Load()
+	ctx := v.ui.sessionCtx(active)
+	go func() {
+		vpcs, err := active.client.ListVPCs(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			v.ui.app.QueueUpdateDraw(func() {
+				v.ui.log.Error("Failed to list VPCs", "error", err)
+				v.ui.statusBar.SetError(fmt.Sprintf("Error: %v", err))
+				if v.rows == nil {
+					v.table.SetCell(0, 0, tview.NewTableCell(" Failed to load the VPCs").
+						SetTextColor(color.AppColors.Error).
+						SetSelectable(false))
+				}
+			})
+			return
+		}
+		v.ui.store.Dispatch(store.VPCsLoaded{VPCs: vpcs, Session: active.id})
+	}()
+}
+
+// render fills the table with the VPCs, each followed by its subnets
+func (v *VPCView) render(vpcs []model.VPC) {
+	v.table.Clear()
+	v.rows = v.rows[:0]
+
+	for i, header := range []string{"ID", "Name", "CIDR", "AZ", "Free IPs", "State"} {
+		v.table.SetCell(0, i,
+			tview.NewTableCell(" "+header+" ").
+				SetTextColor(color.AppColors.Title).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetBackgroundColor(color.AppColors.HeaderBg))
+	}
+
+	selected := -1
+	for i := range vpcs {
+		vpc := &vpcs[i]
+		if vpc.ID == v.focus {
+			selected = len(v.rows)
+		}
+		v.rows = append(v.rows, vpcRow{vpc: vpc})
+
+		name := vpc.Name
+		if vpc.IsDefault {
+			name += " (default)"
+		}
+		row := len(v.rows)
+		v.table.SetCell(row, 0, tview.NewTableCell(" "+vpc.ID+" ").SetTextColor(color.AppColors.Highlight).SetAttributes(tcell.AttrBold))
+		v.table.SetCell(row, 1, tview.NewTableCell(" "+name+" ").SetTextColor(color.AppColors.Foreground).SetAttributes(tcell.AttrBold))
+		v.table.SetCell(row, 2, tview.NewTableCell(" "+vpc.CIDR+" ").SetTextColor(color.AppColors.Foreground))
+		v.table.SetCell(row, 3, tview.NewTableCell(fmt.Sprintf(" %d subnets ", len(vpc.Subnets))).SetTextColor(color.AppColors.Secondary))
+		v.table.SetCell(row, 4, tview.NewTableCell(fmt.Sprintf(" %d ", vpc.AvailableIPs())).SetTextColor(color.AppColors.Foreground).SetAlign(tview.AlignRight))
+		v.table.SetCell(row, 5, tview.NewTableCell(" "+vpc.State+" ").SetTextColor(color.AppColors.Foreground).SetExpansion(1))
+
+		for j := range vpc.Subnets {
+			subnet := &vpc.Subnets[j]
+			if subnet.ID == v.focus {
+				selected = len(v.rows)
+			}
+			v.rows = append(v.rows, vpcRow{vpc: vpc, subnet: subnet})
+
+			row := len(v.rows)
+			v.table.SetCell(row, 0, tview.NewTableCell("   └ "+subnet.ID+" ").SetTextColor(color.AppColors.Foreground))
+			v.table.SetCell(row, 1, tview.NewTableCell(" "+subnet.Name+" ").SetTextColor(color.AppColors.Foreground))
+			v.table.SetCell(row, 2, tview.NewTableCell(" "+subnet.CIDR+" ").SetTextColor(color.AppColors.Foreground))
+			v.table.SetCell(row, 3, tview.NewTableCell(" "+subnet.AvailabilityZone+" ").SetTextColor(color.AppColors.Foreground))
+			v.table.SetCell(row, 4, tview.NewTableCell(fmt.Sprintf(" %d ", subnet.AvailableIPs)).SetTextColor(freeIPsColor(subnet.AvailableIPs)).SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 5, tview.NewTableCell(" "+subnet.State+" ").SetTextColor(color.AppColors.Foreground).SetExpansion(1))
+		}
+	}
+
+	if len(v.rows) == 0 {
+		v.table.SetCell(1, 0, tview.NewTableCell(" No VPC found").SetSelectable(false))
+		return
+	}
+
+	// Select the requested resource once, then restore the previous selection
+	if selected < 0 {
+		selected = v.ui.nav.StateOf(viewVPCs).Selected
+	}
+	v.focus = ""
+	if selected >= len(v.rows) {
+		selected = 0
+	}
+	v.table.Select(selected+1, 0)
+	v.ui.statusBar.SetStatus(fmt.Sprintf("%d VPCs, Enter: show instances", len(vpcs)))
+}
+
+// freeIPsColor returns the color of a free IP count, warning when a subnet
+// is close to exhaustion
+func freeIPsColor(count int) tcell.Color {
+	switch {
+	case count == 0:
+		return color.AppColors.Error
+	case count < 16:
+		return color.AppColors.Pending
+	default:
+		return color.AppColors.Foreground
+	}
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nlamirault/e2c/internal/watch"
+)
+
+// toggleWatch watches the selected instance, or stops watching it. The
+// instance is checked with the client of its account and region, whether it
+// is displayed or not.
+func (ui *UI) toggleWatch() {
+	instance := ui.instancesView.GetSelectedInstance()
+	if instance == nil {
+		ui.statusBar.SetError("No instance selected")
+		return
+	}
+
+	client := ui.clientFor(*instance)
+	check := func(ctx context.Context, id string) (watch.Status, error) {
+		status, err := client.GetInstanceStatus(ctx, id)
+		if err != nil {
+			return watch.Status{}, err
+		}
+		return watch.Status{State: status.State, System: status.System, Instance: status.Instance}, nil
+	}
+
+	if ui.watcher.Toggle(instance.ID, instance.Name, client.GetRegion(), instance.State, check) {
+		ui.log.Info("Watching instance", "instanceID", instance.ID, "interval", ui.config().Watch.Interval)
+		ui.statusBar.SetStatus(fmt.Sprintf("Watching %s every %s", instance.DisplayName(), ui.config().Watch.Interval))
+	} else {
+		ui.log.Info("Stopped watching instance", "instanceID", instance.ID)
+		ui.statusBar.SetStatus(fmt.Sprintf("Stopped watching %s", instance.DisplayName()))
+	}
+	ui.statusBar.SetWatching(len(ui.watcher.Watched()))
+}
+
+// watchedChanged notifies the change of the state or of the status checks
+// of an instance watched
+func (ui *UI) watchedChanged(change watch.Change) {
+	ui.app.QueueUpdateDraw(func() {
+		summary := "Watch: " + change.Summary()
+		failed := change.Current.Failed()
+		if failed {
+			ui.statusBar.SetError(summary)
+		} else {
+			ui.statusBar.SetStatus(summary)
+		}
+		ui.statusBar.SetWatching(len(ui.watcher.Watched()))
+		ui.notify("Watch", change.Summary(), failed)
+	})
+}
@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package watch checks the instances watched at a fast cadence, and reports
+// the changes of their state and of their status checks.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// impaired is the result of a failed status check
+const impaired = "impaired"
+
+// Status is the state of an instance and the results of its status checks
+type Status struct {
+	State    string `json:"state"`
+	System   string `json:"system_status,omitempty"`
+	Instance string `json:"instance_status,omitempty"`
+}
+
+// Failed returns true if a status check of the instance failed
+func (s Status) Failed() bool {
+	return s.System == impaired || s.Instance == impaired
+}
+
+// Change is a change of the state or of the status checks of an instance
+// watched
+type Change struct {
+	InstanceID string    `json:"instance_id"`
+	Name       string    `json:"name,omitempty"`
+	Region     string    `json:"region"`
+	Previous   Status    `json:"previous"`
+	Current    Status    `json:"current"`
+	Time       time.Time `json:"time"`
+}
+
+// Summary describes the change on a line, e.g. web-1 (i-0123456789abcdef0):
+// running -> stopped
+func (c Change) Summary() string {
+	name := c.InstanceID
+	if c.Name != "" {
+		name = fmt.Sprintf("%s (%s)", c.Name, c.InstanceID)
+	}
+	switch {
+	case c.Previous.State != c.Current.State:
+		return fmt.Sprintf("%s: %s -> %s", name, c.Previous.State, c.Current.State)
+	case c.Current.Failed():
+		return fmt.Sprintf("%s: status checks failed (system %s, instance %s)", name, c.Current.System, c.Current.Instance)
+	default:
+		return fmt.Sprintf("%s: status checks passed", name)
+	}
+}
+
+// CheckFunc retrieves the status of an instance watched
+type CheckFunc func(ctx context.Context, instanceID string) (Status, error)
+
+// instance is an instance watched, with its last status
+type instance struct {
+	name   string
+	region string
+	check  CheckFunc
+	status Status
+}
+
+// Watcher checks the instances watched until they are unwatched or
+// terminated, and notifies the changes of their status, and posts them to
+// the webhook if any
+type Watcher struct {
+	log      *slog.Logger
+	interval time.Duration
+	webhook  string
+	client   *http.Client
+	notify   func(Change)
+
+	mutex     sync.Mutex
+	instances map[string]*instance
+}
+
+// New creates a watcher checking the instances watched at an interval
+func New(log *slog.Logger, interval time.Duration, webhook string, notify func(Change)) *Watcher {
+	return &Watcher{
+		log:       log,
+		interval:  interval,
+		webhook:   webhook,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		notify:    notify,
+		instances: make(map[string]*instance),
+	}
+}
+
+// Toggle watches an instance of a region, in the given state, with the
+// function retrieving its status, or unwatches it. It returns true if the
+// instance is watched.
+func (w *Watcher) Toggle(instanceID, name, region, state string, check CheckFunc) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.instances[instanceID]; ok {
+		delete(w.instances, instanceID)
+		return false
+	}
+	w.instances[instanceID] = &instance{
+		name:   name,
+		region: region,
+		check:  check,
+		status: Status{State: state},
+	}
+	return true
+}
+
+// Watched returns the IDs of the instances watched, sorted
+func (w *Watcher) Watched() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	ids := make([]string, 0, len(w.instances))
+	for id := range w.instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Run checks the instances watched at the interval until the context is
+// done
+func (w *Watcher) Run(ctx context.Context) {
+	if w.interval <= 0 {
+		w.log.Warn("The instances are not watched, watch.interval is not positive", "interval", w.interval)
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.checkAll(ctx)
+		}
+	}
+}
+
+// checkAll checks the instances watched, reporting the changes of their
+// state and of the result of their status checks
+func (w *Watcher) checkAll(ctx context.Context) {
+	for _, id := range w.Watched() {
+		w.mutex.Lock()
+		watched, ok := w.instances[id]
+		w.mutex.Unlock()
+		if !ok {
+			continue
+		}
+
+		status, err := watched.check(ctx, id)
+		if err != nil {
+			w.log.Warn("Failed to check instance watched", "instanceID", id, "error", err)
+			continue
+		}
+		if status.State == "" {
+			w.log.Warn("Instance watched not found", "instanceID", id)
+			continue
+		}
+
+		w.mutex.Lock()
+		previous := watched.status
+		watched.status = status
+		if status.State == "terminated" {
+			delete(w.instances, id)
+		}
+		w.mutex.Unlock()
+
+		if previous.State == status.State && previous.Failed() == status.Failed() {
+			continue
+		}
+		change := Change{
+			InstanceID: id,
+			Name:       watched.name,
+			Region:     watched.region,
+			Previous:   previous,
+			Current:    status,
+			Time:       time.Now(),
+		}
+		w.log.Info("Instance watched changed", "instanceID", id, "change", change.Summary())
+		w.notify(change)
+		if w.webhook != "" {
+			if err := w.post(ctx, change); err != nil {
+				w.log.Warn("Failed to post the change to the webhook", "instanceID", id, "error", err)
+			}
+		}
+	}
+}
+
+// post sends a change to the webhook as JSON
+func (w *Watcher) post(ctx context.Context, change Change) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
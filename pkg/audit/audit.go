@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package audit records the mutating actions on the instances to an
+// append-only log.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// ResultSuccess is the result of an action which succeeded
+	ResultSuccess = "success"
+	// ResultFailure is the result of an action which failed
+	ResultFailure = "failure"
+)
+
+// Entry is a mutating action recorded in the audit log
+type Entry struct {
+	Time     time.Time         `json:"time"`
+	User     string            `json:"user"`    // ARN of the caller
+	Account  string            `json:"account"` // AWS account ID
+	Region   string            `json:"region"`
+	Profile  string            `json:"profile,omitempty"`
+	Action   string            `json:"action"` // EC2 API action, e.g. StopInstances
+	Instance string            `json:"instance"`
+	Params   map[string]string `json:"params,omitempty"` // Parameters of the action, if any
+	Result   string            `json:"result"`           // success or failure
+	Error    string            `json:"error,omitempty"`
+	Output   string            `json:"output,omitempty"` // Output of a hook command
+}
+
+// Log records the mutating actions to an append-only JSON Lines file, and
+// optionally as structured log records using the CloudTrail field names
+type Log struct {
+	log        *slog.Logger
+	path       string
+	structured bool
+	mu         sync.Mutex
+}
+
+// DefaultPath returns the default path of the audit file,
+// ~/.config/e2c/audit.jsonl
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "audit.jsonl"
+	}
+	return filepath.Join(home, ".config", "e2c", "audit.jsonl")
+}
+
+// New creates an audit log writing to the given file, or to the default one
+// if empty
+func New(log *slog.Logger, path string, structured bool) *Log {
+	if path == "" {
+		path = DefaultPath()
+	}
+	return &Log{
+		log:        log,
+		path:       path,
+		structured: structured,
+	}
+}
+
+// Path returns the path of the audit file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends an entry to the audit file
+func (l *Log) Record(entry Entry) error {
+	if l.structured {
+		l.logEntry(entry)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// logEntry logs an entry with the field names of the CloudTrail events, so
+// that log pipelines can process them like CloudTrail records
+func (l *Log) logEntry(entry Entry) {
+	attrs := []any{
+		"eventTime", entry.Time.UTC().Format(time.RFC3339),
+		"eventSource", "ec2.amazonaws.com",
+		"eventName", entry.Action,
+		"awsRegion", entry.Region,
+		"recipientAccountId", entry.Account,
+		slog.Group("userIdentity", "arn", entry.User),
+		slog.Group("requestParameters", "instanceId", entry.Instance),
+		"userAgent", "e2c",
+	}
+	if entry.Error != "" {
+		attrs = append(attrs, "errorMessage", entry.Error)
+	}
+	if entry.Output != "" {
+		attrs = append(attrs, "output", entry.Output)
+	}
+	l.log.Info("Audit event", slog.Group("audit", attrs...))
+}
+
+// Read reads the entries of an audit file, the oldest first
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid audit entry at line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit file: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	// assumeRoleSessionName is the default name of the sessions of the
+	// assumed roles, displayed in CloudTrail
+	assumeRoleSessionName = "e2c"
+	// assumeRoleExpiryWindow is the time before the expiration of the
+	// credentials of the role from which they are refreshed
+	assumeRoleExpiryWindow = 5 * time.Minute
+)
+
+// AssumeRole is the IAM role assumed by the client with the credentials of
+// the profile
+type AssumeRole struct {
+	RoleARN     string
+	ExternalID  string
+	MFASerial   string        // ARN or serial number of the MFA device, if required by the role
+	SessionName string        // Defaults to e2c
+	Duration    time.Duration // Defaults to 1 hour
+}
+
+// MFAPrompt asks the user for the current code of an MFA device, to assume a
+// role
+type MFAPrompt func(role, serial string) (string, error)
+
+// SetMFAPrompt sets the prompt of the MFA codes required to assume the role,
+// read from the standard input by default
+func (c *EC2Client) SetMFAPrompt(prompt MFAPrompt) {
+	c.mfaMutex.Lock()
+	defer c.mfaMutex.Unlock()
+	c.mfaPrompt = prompt
+}
+
+// mfaToken returns the code of the MFA device of the role, asked each time
+// the credentials are retrieved or refreshed
+func (c *EC2Client) mfaToken() (string, error) {
+	c.mfaMutex.Lock()
+	prompt := c.mfaPrompt
+	c.mfaMutex.Unlock()
+
+	if prompt == nil {
+		return stscreds.StdinTokenProvider()
+	}
+	return prompt(c.role.RoleARN, c.role.MFASerial)
+}
+
+// withAssumeRole replaces the credentials of the configuration with the
+// temporary ones of the role, assumed with the original credentials and
+// refreshed before they expire
+func (c *EC2Client) withAssumeRole(cfg *aws.Config) {
+	c.log.Info("Assuming IAM role", "roleARN", c.role.RoleARN, "mfa", c.role.MFASerial != "")
+
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(*cfg), c.role.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = assumeRoleSessionName
+		if c.role.SessionName != "" {
+			o.RoleSessionName = c.role.SessionName
+		}
+		if c.role.ExternalID != "" {
+			o.ExternalID = aws.String(c.role.ExternalID)
+		}
+		if c.role.MFASerial != "" {
+			o.SerialNumber = aws.String(c.role.MFASerial)
+			o.TokenProvider = c.mfaToken
+		}
+		if c.role.Duration > 0 {
+			o.Duration = c.role.Duration
+		}
+	})
+	cfg.Credentials = aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = assumeRoleExpiryWindow
+	})
+}
+
+// roleName returns the name of a role from its ARN
+func roleName(arn string) string {
+	if _, name, ok := strings.Cut(arn, ":role/"); ok {
+		return name[strings.LastIndex(name, "/")+1:]
+	}
+	return arn
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/audit"
+)
+
+// SetAuditLog sets the audit log recording the mutating actions of the client
+func (c *EC2Client) SetAuditLog(log *audit.Log) {
+	c.audit = log
+}
+
+// MutationFunc is called after a mutating action on an instance succeeded
+type MutationFunc func(action, instanceID string)
+
+// SetMutationHook sets the function called after each mutating action on an
+// instance which succeeded, e.g. to invalidate the data cached for it
+func (c *EC2Client) SetMutationHook(hook MutationFunc) {
+	c.onMutation = hook
+}
+
+// AuditLog returns the audit log of the client, nil if none
+func (c *EC2Client) AuditLog() *audit.Log {
+	return c.audit
+}
+
+// RecordHook records the run of a hook command after an action on an
+// instance in the audit log, with its output
+func (c *EC2Client) RecordHook(ctx context.Context, instanceID string, params map[string]string, output string, err error) {
+	c.recordOutput(ctx, "RunHook", instanceID, params, output, err)
+}
+
+type auditParamsKey struct{}
+
+// WithAuditParams returns a context adding parameters to the audit entries
+// of the actions made with it, e.g. the group of instances of a batch action
+func WithAuditParams(ctx context.Context, params map[string]string) context.Context {
+	if len(params) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, auditParamsKey{}, params)
+}
+
+// record records a mutating action on an instance in the audit log, with
+// the identity of the caller
+func (c *EC2Client) record(ctx context.Context, action, instanceID string, params map[string]string, err error) {
+	c.recordOutput(ctx, action, instanceID, params, "", err)
+}
+
+// recordOutput records an action and its output in the audit log
+func (c *EC2Client) recordOutput(ctx context.Context, action, instanceID string, params map[string]string, output string, err error) {
+	if err == nil && c.onMutation != nil {
+		c.onMutation(action, instanceID)
+	}
+	if c.audit == nil {
+		return
+	}
+
+	if extra, _ := ctx.Value(auditParamsKey{}).(map[string]string); len(extra) > 0 {
+		merged := make(map[string]string, len(params)+len(extra))
+		for key, value := range extra {
+			merged[key] = value
+		}
+		for key, value := range params {
+			merged[key] = value
+		}
+		params = merged
+	}
+
+	entry := audit.Entry{
+		Time:     time.Now(),
+		User:     "unknown",
+		Region:   c.region,
+		Profile:  c.profile,
+		Action:   action,
+		Instance: instanceID,
+		Params:   params,
+		Result:   audit.ResultSuccess,
+		Output:   output,
+	}
+	if err != nil {
+		entry.Result = audit.ResultFailure
+		entry.Error = err.Error()
+	}
+	if identity := c.cachedIdentity(ctx); identity != nil {
+		entry.User = identity.ARN
+		entry.Account = identity.Account
+	}
+
+	if err := c.audit.Record(entry); err != nil {
+		c.log.Error("Failed to record audit entry", "action", action, "instanceID", instanceID, "error", err)
+	}
+}
+
+// cachedIdentity returns the identity of the caller, retrieved once
+func (c *EC2Client) cachedIdentity(ctx context.Context) *Identity {
+	c.identityOnce.Do(func() {
+		identity, err := c.GetCallerIdentity(context.WithoutCancel(ctx))
+		if err != nil {
+			c.log.Warn("Failed to get the caller identity for the audit log", "error", err)
+			return
+		}
+		c.identity = identity
+	})
+	return c.identity
+}
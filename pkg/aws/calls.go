@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// CallOptions holds the endpoint, retries and rate limits of the calls of a
+// client
+type CallOptions struct {
+	EndpointURL   string        // Endpoint of all the services, e.g. LocalStack, empty for the AWS ones
+	RetryMode     string        // standard, or adaptive which also slows down the calls once throttled, empty for the one of the profile
+	MaxAttempts   int           // Attempts of a call, retries included, 0 for the one of the profile
+	APITimeout    time.Duration // Timeout of a call, retries included, 0 for none
+	AttributeRate float64       // Maximum DescribeInstanceAttribute calls per second, 0 for no limit
+}
+
+// loadOptions returns the options of the loading of the AWS config setting
+// the retries, overriding the retry_mode and max_attempts of the profile
+func (o CallOptions) loadOptions() []func(*config.LoadOptions) error {
+	var options []func(*config.LoadOptions) error
+	switch strings.ToLower(o.RetryMode) {
+	case string(aws.RetryModeAdaptive):
+		options = append(options, config.WithRetryMode(aws.RetryModeAdaptive))
+	case string(aws.RetryModeStandard):
+		options = append(options, config.WithRetryMode(aws.RetryModeStandard))
+	}
+	if o.MaxAttempts > 0 {
+		options = append(options, config.WithRetryMaxAttempts(o.MaxAttempts))
+	}
+	return options
+}
+
+// withTimeout bounds the calls of the clients created from an AWS config,
+// retries included
+func withTimeout(cfg *aws.Config, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	timeoutMiddleware := middleware.InitializeMiddlewareFunc("E2CTimeout", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return next.HandleInitialize(ctx, in)
+	})
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(timeoutMiddleware, middleware.Before)
+	})
+}
+
+// rateLimiter spaces out calls to respect a maximum rate, shared by all the
+// goroutines using the client
+type rateLimiter struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	next     time.Time // Time of the next call allowed
+}
+
+// newRateLimiter creates a limiter allowing rate calls per second, nil
+// without limit
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// Wait waits until the next call is allowed, or the context is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	at := time.Now()
+	if l.next.After(at) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mutex.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
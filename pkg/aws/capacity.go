@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// IsInsufficientCapacity returns true if an instance could not be started
+// or launched because AWS has not enough capacity of its type in its
+// availability zone
+func IsInsufficientCapacity(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InsufficientInstanceCapacity"
+}
+
+// RemoveFromPlacementGroup removes a stopped EC2 instance from its placement
+// group, whose capacity is constrained for cluster groups
+func (c *EC2Client) RemoveFromPlacementGroup(ctx context.Context, instanceID, group string) error {
+	c.log.Info("Removing EC2 instance from placement group", "instanceID", instanceID, "group", group)
+
+	// An empty group name removes the instance from its group
+	input := &ec2.ModifyInstancePlacementInput{
+		InstanceId: aws.String(instanceID),
+		GroupName:  aws.String(""),
+	}
+
+	_, err := c.client.ModifyInstancePlacement(ctx, input)
+	c.record(ctx, "ModifyInstancePlacement", instanceID, map[string]string{"groupName": ""}, err)
+	if err != nil {
+		return fmt.Errorf("failed to remove instance %s from placement group %s: %w", instanceID, group, err)
+	}
+
+	return nil
+}
+
+// LaunchReplacement launches a new EC2 instance of a type in a subnet, from
+// the AMI and with the key pair, security groups, instance profile and tags
+// of an instance. The data of its volumes is not copied. It returns the ID of
+// the new instance.
+func (c *EC2Client) LaunchReplacement(ctx context.Context, instance model.Instance, subnetID, instanceType string) (string, error) {
+	c.log.Info("Launching replacement EC2 instance", "instanceID", instance.ID, "imageID", instance.ImageID, "subnetID", subnetID, "instanceType", instanceType)
+
+	groups := make([]string, 0, len(instance.SecurityGroups))
+	for _, group := range instance.SecurityGroups {
+		groups = append(groups, group.ID)
+	}
+	tags := make([]types.Tag, 0, len(instance.Tags)+1)
+	for key, value := range instance.Tags {
+		// The tags reserved by AWS, e.g. of CloudFormation, cannot be set
+		if strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	tags = append(tags, types.Tag{Key: aws.String("e2c:replaces"), Value: aws.String(instance.ID)})
+
+	input := &ec2.RunInstancesInput{
+		ImageId:          aws.String(instance.ImageID),
+		InstanceType:     types.InstanceType(instanceType),
+		MinCount:         aws.Int32(1),
+		MaxCount:         aws.Int32(1),
+		SubnetId:         aws.String(subnetID),
+		SecurityGroupIds: groups,
+		TagSpecifications: []types.TagSpecification{
+			{ResourceType: types.ResourceTypeInstance, Tags: tags},
+		},
+	}
+	if instance.KeyName != "" {
+		input.KeyName = aws.String(instance.KeyName)
+	}
+	if instance.IAMInstanceProfile != "" {
+		input.IamInstanceProfile = &types.IamInstanceProfileSpecification{
+			Arn: aws.String(instance.IAMInstanceProfile),
+		}
+	}
+	if instance.MetadataHTTPTokens != "" {
+		input.MetadataOptions = &types.InstanceMetadataOptionsRequest{
+			HttpTokens: types.HttpTokensState(instance.MetadataHTTPTokens),
+		}
+	}
+
+	output, err := c.client.RunInstances(ctx, input)
+	var replacement string
+	if err == nil && len(output.Instances) > 0 {
+		replacement = aws.ToString(output.Instances[0].InstanceId)
+	}
+	c.record(ctx, "RunInstances", instance.ID, map[string]string{"subnetId": subnetID, "instanceType": instanceType, "replacement": replacement}, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to launch a replacement of instance %s: %w", instance.ID, err)
+	}
+
+	return replacement, nil
+}
+
+// ListTypeZones returns the availability zones of the region in which an
+// instance type is offered
+func (c *EC2Client) ListTypeZones(ctx context.Context, instanceType string) (map[string]bool, error) {
+	c.log.Info("Listing instance type offerings", "instanceType", instanceType, "region", c.region)
+
+	zones := make(map[string]bool)
+	paginator := ec2.NewDescribeInstanceTypeOfferingsPaginator(c.client, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: types.LocationTypeAvailabilityZone,
+		Filters: []types.Filter{
+			{Name: aws.String("instance-type"), Values: []string{instanceType}},
+		},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instance type offerings: %w", err)
+		}
+		for _, offering := range output.InstanceTypeOfferings {
+			zones[aws.ToString(offering.Location)] = true
+		}
+	}
+
+	return zones, nil
+}
+
+// ListZoneTypes returns the instance types of a family, e.g. m5, offered in
+// an availability zone, sorted
+func (c *EC2Client) ListZoneTypes(ctx context.Context, zone, family string) ([]string, error) {
+	c.log.Info("Listing instance types offered in zone", "zone", zone, "family", family)
+
+	var instanceTypes []string
+	paginator := ec2.NewDescribeInstanceTypeOfferingsPaginator(c.client, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: types.LocationTypeAvailabilityZone,
+		Filters: []types.Filter{
+			{Name: aws.String("location"), Values: []string{zone}},
+			{Name: aws.String("instance-type"), Values: []string{family + ".*"}},
+		},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instance type offerings in %s: %w", zone, err)
+		}
+		for _, offering := range output.InstanceTypeOfferings {
+			instanceTypes = append(instanceTypes, string(offering.InstanceType))
+		}
+	}
+	sort.Strings(instanceTypes)
+
+	return instanceTypes, nil
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// DescribeStack retrieves the status of a CloudFormation stack, by name or
+// ID, and the resources it manages
+func (c *EC2Client) DescribeStack(ctx context.Context, stack string) (*model.Stack, error) {
+	c.log.Debug("Describing CloudFormation stack", "stack", stack)
+
+	stacks, err := c.cloudformation.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stack),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe stack %s: %w", stack, err)
+	}
+	if len(stacks.Stacks) == 0 {
+		return nil, fmt.Errorf("stack %s not found", stack)
+	}
+	described := stacks.Stacks[0]
+	result := &model.Stack{
+		Name:         aws.ToString(described.StackName),
+		ID:           aws.ToString(described.StackId),
+		Status:       string(described.StackStatus),
+		StatusReason: aws.ToString(described.StackStatusReason),
+	}
+	if described.DriftInformation != nil {
+		result.Drift = string(described.DriftInformation.StackDriftStatus)
+	}
+
+	paginator := cloudformation.NewListStackResourcesPaginator(c.cloudformation, &cloudformation.ListStackResourcesInput{
+		StackName: aws.String(result.ID),
+	})
+	for paginator.HasMorePages() {
+		resources, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the resources of stack %s: %w", stack, err)
+		}
+		for _, resource := range resources.StackResourceSummaries {
+			result.Resources = append(result.Resources, model.StackResource{
+				LogicalID:    aws.ToString(resource.LogicalResourceId),
+				PhysicalID:   aws.ToString(resource.PhysicalResourceId),
+				Type:         aws.ToString(resource.ResourceType),
+				Status:       string(resource.ResourceStatus),
+				StatusReason: aws.ToString(resource.ResourceStatusReason),
+			})
+		}
+	}
+
+	return result, nil
+}
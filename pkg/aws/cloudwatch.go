@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// Datapoint is a value of a CloudWatch metric
+type Datapoint struct {
+	Timestamp time.Time
+	Average   float64
+}
+
+// instanceDimensions returns the dimensions of the metrics of an instance
+func instanceDimensions(instanceID string) []types.Dimension {
+	return []types.Dimension{{Name: aws.String("InstanceId"), Value: aws.String(instanceID)}}
+}
+
+// GetInstanceMetric retrieves the average of an AWS/EC2 metric of an instance
+// over the given duration, one datapoint per period, sorted by time
+func (c *EC2Client) GetInstanceMetric(ctx context.Context, instanceID, metric string, duration, period time.Duration) ([]Datapoint, error) {
+	c.log.Debug("Getting instance metric", "instanceID", instanceID, "metric", metric)
+
+	end := time.Now().UTC()
+	output, err := c.cloudwatch.GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/EC2"),
+		MetricName: aws.String(metric),
+		Dimensions: instanceDimensions(instanceID),
+		StartTime:  aws.Time(end.Add(-duration)),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int32(int32(period.Seconds())),
+		Statistics: []types.Statistic{types.StatisticAverage},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metric %s of instance %s: %w", metric, instanceID, err)
+	}
+
+	datapoints := make([]Datapoint, 0, len(output.Datapoints))
+	for _, point := range output.Datapoints {
+		datapoints = append(datapoints, Datapoint{
+			Timestamp: aws.ToTime(point.Timestamp),
+			Average:   aws.ToFloat64(point.Average),
+		})
+	}
+	sort.Slice(datapoints, func(i, j int) bool {
+		return datapoints[i].Timestamp.Before(datapoints[j].Timestamp)
+	})
+
+	return datapoints, nil
+}
+
+// instanceMetrics are the AWS/EC2 metrics of the instances retrieved by
+// GetInstanceMetrics, with their statistic and unit
+var instanceMetrics = []struct {
+	id, name, stat, unit string
+}{
+	{"cpu", "CPUUtilization", "Average", "Percent"},
+	{"network_in", "NetworkIn", "Sum", "Bytes"},
+	{"network_out", "NetworkOut", "Sum", "Bytes"},
+}
+
+// GetInstanceMetrics retrieves the CPU utilization and the network traffic
+// of an instance over the given duration, one datapoint per period, in a
+// single GetMetricData call per page of datapoints
+func (c *EC2Client) GetInstanceMetrics(ctx context.Context, instanceID string, duration, period time.Duration) (*model.InstanceMetrics, error) {
+	c.log.Debug("Getting instance metrics", "instanceID", instanceID, "duration", duration, "period", period)
+
+	queries := make([]types.MetricDataQuery, 0, len(instanceMetrics))
+	metrics := &model.InstanceMetrics{Period: period}
+	series := make(map[string]*model.MetricSeries, len(instanceMetrics))
+	for _, m := range instanceMetrics {
+		queries = append(queries, types.MetricDataQuery{
+			Id: aws.String(m.id),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String("AWS/EC2"),
+					MetricName: aws.String(m.name),
+					Dimensions: instanceDimensions(instanceID),
+				},
+				Period: aws.Int32(int32(period.Seconds())),
+				Stat:   aws.String(m.stat),
+			},
+		})
+		metrics.Series = append(metrics.Series, model.MetricSeries{Name: m.name, Stat: m.stat, Unit: m.unit})
+	}
+	for i := range metrics.Series {
+		series[instanceMetrics[i].id] = &metrics.Series[i]
+	}
+
+	end := time.Now().UTC().Truncate(period)
+	paginator := cloudwatch.NewGetMetricDataPaginator(c.cloudwatch, &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: queries,
+		StartTime:         aws.Time(end.Add(-duration)),
+		EndTime:           aws.Time(end),
+		ScanBy:            types.ScanByTimestampAscending,
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the metrics of instance %s: %w", instanceID, err)
+		}
+		for _, result := range output.MetricDataResults {
+			s, ok := series[aws.ToString(result.Id)]
+			if !ok {
+				continue
+			}
+			for i, timestamp := range result.Timestamps {
+				if i >= len(result.Values) {
+					break
+				}
+				s.Times = append(s.Times, timestamp)
+				s.Values = append(s.Values, result.Values[i])
+			}
+		}
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// consoleBaseURL returns the base URL of the AWS console for a region
+func consoleBaseURL(region string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com", region)
+}
+
+// InstanceConsoleURL returns the URL of an instance in the AWS console
+func InstanceConsoleURL(region, instanceID string) string {
+	return fmt.Sprintf("%s/ec2/home?region=%s#InstanceDetails:instanceId=%s",
+		consoleBaseURL(region), region, url.QueryEscape(instanceID))
+}
+
+// TagConsoleURL returns the URL of the AWS console page of the resource
+// referenced by a tag set by AWS services, if the tag is known
+func TagConsoleURL(region, key, value string) (string, bool) {
+	switch key {
+	case "aws:cloudformation:stack-name":
+		return fmt.Sprintf("%s/cloudformation/home?region=%s#/stacks?filteringText=%s",
+			consoleBaseURL(region), region, url.QueryEscape(value)), true
+	case "aws:cloudformation:stack-id":
+		return fmt.Sprintf("%s/cloudformation/home?region=%s#/stacks/stackinfo?stackId=%s",
+			consoleBaseURL(region), region, url.QueryEscape(value)), true
+	case "aws:autoscaling:groupName":
+		return fmt.Sprintf("%s/ec2/home?region=%s#AutoScalingGroupDetails:id=%s",
+			consoleBaseURL(region), region, url.QueryEscape(value)), true
+	case "aws:ec2launchtemplate:id":
+		return fmt.Sprintf("%s/ec2/home?region=%s#LaunchTemplateDetails:launchTemplateId=%s",
+			consoleBaseURL(region), region, url.QueryEscape(value)), true
+	case "aws:ec2spot:fleet-request-id":
+		return fmt.Sprintf("%s/ec2/home?region=%s#SpotInstancesDetails:id=%s",
+			consoleBaseURL(region), region, url.QueryEscape(value)), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,211 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+const (
+	// processProviderName is the source of the credentials returned by a
+	// credential_process command
+	processProviderName = "ProcessProvider"
+
+	// awsVaultEnv is set by aws-vault exec to the name of the profile
+	awsVaultEnv = "AWS_VAULT"
+)
+
+// credentialsEnv are the environment variables holding the credentials
+// exported by an external process, removed before running it again
+var credentialsEnv = []string{
+	awsVaultEnv,
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_SECURITY_TOKEN",
+	envCredentialExpiration,
+	"AWS_SESSION_EXPIRATION",
+}
+
+// CredentialSource describes where the credentials of the client come from
+type CredentialSource struct {
+	Provider string    // Name of the SDK provider which supplied the credentials
+	Process  string    // Name of the external process, empty if none
+	Command  string    // Command line of the credential_process, if any
+	Profile  string    // Profile given to aws-vault exec, if any
+	Role     string    // ARN of the role assumed by e2c, if any
+	Expires  time.Time // Expiration of the credentials, zero if unknown
+}
+
+// IsExternal returns true if the credentials are supplied by an external process
+func (s *CredentialSource) IsExternal() bool {
+	return s.Process != ""
+}
+
+// CanReexec returns true if e2c must run again under the external process to
+// renew the credentials. Credentials exported by aws-vault exec are fixed in
+// the environment, while a credential_process is run again by the SDK.
+func (s *CredentialSource) CanReexec() bool {
+	return s.Process == "aws-vault" && s.Profile != ""
+}
+
+// ReexecCommand returns the command line running the given arguments under
+// aws-vault exec, and the environment without the expired credentials
+func (s *CredentialSource) ReexecCommand(args []string) ([]string, []string) {
+	command := append([]string{"aws-vault", "exec", s.Profile, "--"}, args...)
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		keep := true
+		for _, removed := range credentialsEnv {
+			if name == removed {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			env = append(env, kv)
+		}
+	}
+	return command, env
+}
+
+// String returns a short description of the source
+func (s *CredentialSource) String() string {
+	var text string
+	switch {
+	case s.Role != "":
+		text = "role " + roleName(s.Role)
+	case s.Process != "" && s.Profile != "":
+		text = fmt.Sprintf("%s (%s)", s.Process, s.Profile)
+	case s.Process != "":
+		text = s.Process
+	default:
+		text = s.Provider
+	}
+	if !s.Expires.IsZero() {
+		text += ", expires " + s.Expires.Local().Format("15:04")
+	}
+	return text
+}
+
+// ResolveCredentials retrieves the credentials from the configured provider
+// chain and returns where they come from
+func (c *EC2Client) ResolveCredentials(ctx context.Context) (*CredentialSource, error) {
+	c.log.Info("Resolving AWS credentials")
+
+	if c.cfg.Credentials == nil {
+		return nil, errors.New("no credentials provider configured")
+	}
+
+	creds, err := c.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+
+	source := &CredentialSource{Provider: creds.Source, Role: c.role.RoleARN}
+	if creds.CanExpire {
+		source.Expires = creds.Expires
+	}
+
+	switch {
+	case creds.Source == config.CredentialsSourceName && os.Getenv(awsVaultEnv) != "":
+		// Running under aws-vault exec, which exports the credentials
+		source.Process = "aws-vault"
+		source.Profile = os.Getenv(awsVaultEnv)
+		if expires, err := time.Parse(time.RFC3339, os.Getenv(envCredentialExpiration)); err == nil {
+			source.Expires = expires
+		}
+	case creds.Source == processProviderName:
+		source.Command = c.credentialProcess(ctx)
+		source.Process = processName(source.Command)
+	}
+
+	c.log.Info("AWS credentials resolved",
+		"provider", source.Provider,
+		"process", source.Process,
+	)
+	return source, nil
+}
+
+// InvalidateCredentials discards the cached credentials, so that they are
+// retrieved again from the provider on the next call
+func (c *EC2Client) InvalidateCredentials() {
+	if cache, ok := c.cfg.Credentials.(*aws.CredentialsCache); ok {
+		cache.Invalidate()
+	}
+}
+
+// ChildEnv returns the environment of a child process calling AWS, such as
+// the AWS CLI: the environment of e2c with the credentials and the region of
+// the client, which may come from an assumed role or an account of the
+// aggregated list rather than from the profile
+func (c *EC2Client) ChildEnv(ctx context.Context) ([]string, error) {
+	creds, err := c.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+
+	var env []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if name == envProfile || name == "AWS_REGION" || name == "AWS_DEFAULT_REGION" || slices.Contains(credentialsEnv, name) {
+			continue
+		}
+		env = append(env, variable)
+	}
+	env = append(env,
+		"AWS_ACCESS_KEY_ID="+creds.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY="+creds.SecretAccessKey,
+		"AWS_REGION="+c.region,
+	)
+	if creds.SessionToken != "" {
+		env = append(env, "AWS_SESSION_TOKEN="+creds.SessionToken)
+	}
+	return env, nil
+}
+
+// credentialProcess returns the credential_process command of the profile,
+// or of its source profile when a role is assumed
+func (c *EC2Client) credentialProcess(ctx context.Context) string {
+	profile := c.profile
+	if profile == "" {
+		profile = os.Getenv(envProfile)
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	shared, err := config.LoadSharedConfigProfile(ctx, profile)
+	if err != nil {
+		c.log.Debug("Failed to load the shared profile", "profile", profile, "error", err)
+		return ""
+	}
+	for sc := &shared; sc != nil; sc = sc.Source {
+		if sc.CredentialProcess != "" {
+			return sc.CredentialProcess
+		}
+	}
+	return ""
+}
+
+// processName returns the name of the executable of a command line
+func processName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "credential_process"
+	}
+	return filepath.Base(strings.Trim(fields[0], `"'`))
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// GetCPUCredits retrieves the credit specification of a burstable instance,
+// and its latest credit balance from CloudWatch. A failure to retrieve the
+// balance, e.g. without the cloudwatch:GetMetricStatistics permission, is
+// reported in the BalanceErr field. The balance is not retrieved when
+// balance is false, e.g. once CloudWatch is known not to be allowed.
+func (c *EC2Client) GetCPUCredits(ctx context.Context, instanceID string, balance bool) (*model.CPUCredits, error) {
+	c.log.Info("Getting CPU credits", "instanceID", instanceID)
+
+	output, err := c.client.DescribeInstanceCreditSpecifications(ctx, &ec2.DescribeInstanceCreditSpecificationsInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe credit specification of %s: %w", instanceID, err)
+	}
+
+	credits := &model.CPUCredits{}
+	if len(output.InstanceCreditSpecifications) > 0 {
+		credits.Specification = aws.ToString(output.InstanceCreditSpecifications[0].CpuCredits)
+	}
+	if !balance {
+		return credits, nil
+	}
+
+	// The balance is published every 5 minutes
+	datapoints, err := c.GetInstanceMetric(ctx, instanceID, "CPUCreditBalance", time.Hour, 5*time.Minute)
+	if err != nil {
+		credits.BalanceErr = err
+		return credits, nil
+	}
+	if len(datapoints) > 0 {
+		latest := datapoints[len(datapoints)-1]
+		credits.Balance = latest.Average
+		credits.BalanceTime = latest.Timestamp
+	}
+
+	return credits, nil
+}
+
+// SetCreditSpecification switches the credit specification of a burstable
+// instance to standard or unlimited
+func (c *EC2Client) SetCreditSpecification(ctx context.Context, instanceID, specification string) error {
+	c.log.Info("Setting credit specification", "instanceID", instanceID, "specification", specification)
+
+	output, err := c.client.ModifyInstanceCreditSpecification(ctx, &ec2.ModifyInstanceCreditSpecificationInput{
+		InstanceCreditSpecifications: []types.InstanceCreditSpecificationRequest{
+			{
+				InstanceId: aws.String(instanceID),
+				CpuCredits: aws.String(specification),
+			},
+		},
+	})
+	if err == nil {
+		for _, item := range output.UnsuccessfulInstanceCreditSpecifications {
+			if item.Error != nil {
+				err = errors.New(aws.ToString(item.Error.Message))
+				break
+			}
+		}
+	}
+	c.record(ctx, "ModifyInstanceCreditSpecification", instanceID, map[string]string{"cpuCredits": specification}, err)
+	if err != nil {
+		return fmt.Errorf("failed to modify credit specification of %s: %w", instanceID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// GetInstanceStatus retrieves the status checks of an EC2 instance
+func (c *EC2Client) GetInstanceStatus(ctx context.Context, instanceID string) (*model.InstanceStatus, error) {
+	c.log.Info("Getting status of EC2 instance", "instanceID", instanceID)
+
+	input := &ec2.DescribeInstanceStatusInput{
+		InstanceIds:         []string{instanceID},
+		IncludeAllInstances: aws.Bool(true),
+	}
+
+	output, err := c.client.DescribeInstanceStatus(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe status of instance %s: %w", instanceID, err)
+	}
+
+	status := &model.InstanceStatus{}
+	if len(output.InstanceStatuses) == 0 {
+		return status, nil
+	}
+
+	result := output.InstanceStatuses[0]
+	if result.InstanceState != nil {
+		status.State = string(result.InstanceState.Name)
+	}
+	if result.SystemStatus != nil {
+		status.System = string(result.SystemStatus.Status)
+	}
+	if result.InstanceStatus != nil {
+		status.Instance = string(result.InstanceStatus.Status)
+	}
+	if result.AttachedEbsStatus != nil {
+		status.EBS = string(result.AttachedEbsStatus.Status)
+	}
+	status.Events = convertEvents(result.Events)
+
+	return status, nil
+}
+
+// ListScheduledEvents returns the active scheduled events of the instances
+// of the region, indexed by instance ID
+func (c *EC2Client) ListScheduledEvents(ctx context.Context) (map[string][]model.ScheduledEvent, error) {
+	c.log.Info("Listing scheduled events", "region", c.region)
+
+	events := make(map[string][]model.ScheduledEvent)
+	paginator := ec2.NewDescribeInstanceStatusPaginator(c.client, &ec2.DescribeInstanceStatusInput{
+		IncludeAllInstances: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instance statuses: %w", err)
+		}
+		for _, status := range output.InstanceStatuses {
+			var active []model.ScheduledEvent
+			for _, event := range convertEvents(status.Events) {
+				if event.IsActive() {
+					active = append(active, event)
+				}
+			}
+			if len(active) > 0 {
+				events[aws.ToString(status.InstanceId)] = active
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// convertEvents converts the scheduled events of an instance status
+func convertEvents(events []types.InstanceStatusEvent) []model.ScheduledEvent {
+	result := make([]model.ScheduledEvent, 0, len(events))
+	for _, event := range events {
+		result = append(result, model.ScheduledEvent{
+			ID:          aws.ToString(event.InstanceEventId),
+			Code:        string(event.Code),
+			Description: aws.ToString(event.Description),
+			NotBefore:   aws.ToTime(event.NotBefore),
+			NotAfter:    aws.ToTime(event.NotAfter),
+			Deadline:    aws.ToTime(event.NotBeforeDeadline),
+		})
+	}
+	return result
+}
+
+// GetInstanceProtection retrieves the termination and stop protections of an
+// EC2 instance, with two concurrent calls
+func (c *EC2Client) GetInstanceProtection(ctx context.Context, instanceID string) (*model.Protection, error) {
+	c.log.Info("Getting protections of EC2 instance", "instanceID", instanceID)
+
+	attribute := func(name types.InstanceAttributeName) (*ec2.DescribeInstanceAttributeOutput, error) {
+		// The protections of many instances are retrieved at once by the scans
+		if err := c.attributeLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		return c.client.DescribeInstanceAttribute(ctx, &ec2.DescribeInstanceAttributeInput{
+			InstanceId: aws.String(instanceID),
+			Attribute:  name,
+		})
+	}
+
+	var stop *ec2.DescribeInstanceAttributeOutput
+	var stopErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stop, stopErr = attribute(types.InstanceAttributeNameDisableApiStop)
+	}()
+	termination, err := attribute(types.InstanceAttributeNameDisableApiTermination)
+	<-done
+	if err != nil {
+		return nil, fmt.Errorf("failed to get termination protection of instance %s: %w", instanceID, err)
+	}
+	if stopErr != nil {
+		return nil, fmt.Errorf("failed to get stop protection of instance %s: %w", instanceID, stopErr)
+	}
+
+	protection := &model.Protection{FetchedAt: time.Now()}
+	if termination.DisableApiTermination != nil {
+		protection.Termination = aws.ToBool(termination.DisableApiTermination.Value)
+	}
+	if stop.DisableApiStop != nil {
+		protection.Stop = aws.ToBool(stop.DisableApiStop.Value)
+	}
+
+	return protection, nil
+}
@@ -0,0 +1,490 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/audit"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// EC2Client handles interactions with AWS EC2 API
+type EC2Client struct {
+	client  EC2API
+	cfg     aws.Config
+	log     *slog.Logger
+	region  string
+	profile string
+	role    AssumeRole // Role assumed with the credentials of the profile, if any
+
+	// Prompt of the MFA codes required to assume the role
+	mfaPrompt MFAPrompt
+	mfaMutex  sync.Mutex
+
+	// Clients of the other AWS services, created from the same configuration
+	services
+
+	// Limits of the calls
+	attributeLimiter *rateLimiter // Limits the DescribeInstanceAttribute calls, nil without limit
+
+	// Audit log of the mutating actions, nil if disabled
+	audit        *audit.Log
+	onMutation   MutationFunc // Called after the mutating actions, nil if none
+	identity     *Identity
+	identityOnce sync.Once
+}
+
+// GetRegion returns the current AWS region
+func (c *EC2Client) GetRegion() string {
+	return c.region
+}
+
+// GetProfile returns the AWS profile used by the client
+func (c *EC2Client) GetProfile() string {
+	return c.profile
+}
+
+// NewEC2Client creates a new EC2 client, assuming the given role if its ARN
+// is set, retrying and limiting its calls with the given options, and
+// recording them with the telemetry
+func NewEC2Client(log *slog.Logger, region, profile string, role AssumeRole, calls CallOptions, telemetry Telemetry) (*EC2Client, error) {
+	log.Info("Creating new EC2 client",
+		"region", region,
+		"profile", profile,
+		"role", role.RoleARN,
+		"retryMode", calls.RetryMode,
+		"maxAttempts", calls.MaxAttempts,
+	)
+
+	// Configure AWS SDK
+	var cfg aws.Config
+	var err error
+
+	options := append(calls.loadOptions(), config.WithRegion(region))
+	if profile != "" {
+		log.Info("Loading AWS config with profile", "profile", profile)
+		cfg, err = config.LoadDefaultConfig(
+			context.Background(),
+			append(options, config.WithSharedConfigProfile(profile))...,
+		)
+	} else {
+		log.Info("Loading AWS config without profile", "region", region)
+		cfg, err = config.LoadDefaultConfig(
+			context.Background(),
+			options...,
+		)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	// Call an AWS compatible endpoint, such as LocalStack
+	if calls.EndpointURL != "" {
+		log.Info("Using custom endpoint", "endpoint", calls.EndpointURL)
+		cfg.BaseEndpoint = aws.String(calls.EndpointURL)
+	}
+
+	// Trace the calls made for the user actions, and measure all the calls
+	withTracing(&cfg, telemetry)
+	withTimeout(&cfg, calls.APITimeout)
+
+	c := &EC2Client{
+		log:              log,
+		region:           region,
+		profile:          profile,
+		role:             role,
+		attributeLimiter: newRateLimiter(calls.AttributeRate),
+	}
+	if role.RoleARN != "" {
+		c.withAssumeRole(&cfg)
+	}
+
+	// Create EC2 client, and the clients of the other services
+	c.client = ec2.NewFromConfig(cfg)
+	c.cfg = cfg
+	c.services = newServices(cfg)
+
+	return c, nil
+}
+
+// pageSize is the number of instances requested per DescribeInstances call
+const pageSize = 1000
+
+// PageFunc is called each time a page of instances has been retrieved and
+// more pages remain, with the page number and all the instances retrieved so
+// far
+type PageFunc func(page int, instances []model.Instance)
+
+// ListInstances retrieves the EC2 instances in the region matching the given
+// EC2 API filters, or all of them if no filter is given
+func (c *EC2Client) ListInstances(ctx context.Context, filters map[string][]string) ([]model.Instance, error) {
+	return c.ListInstancesPages(ctx, filters, nil)
+}
+
+// ListInstancesPages retrieves the EC2 instances in the region matching the
+// given EC2 API filters, following the pagination tokens. If onPage is not
+// nil, it is called after each page but the last so that results can be
+// displayed while the next pages are loading: the complete list is the one
+// returned.
+func (c *EC2Client) ListInstancesPages(ctx context.Context, filters map[string][]string, onPage PageFunc) ([]model.Instance, error) {
+	c.log.Info("Listing EC2 instances", "filters", len(filters))
+
+	input := &ec2.DescribeInstancesInput{
+		Filters:    toEC2Filters(filters),
+		MaxResults: aws.Int32(pageSize),
+	}
+
+	instances := make([]model.Instance, 0)
+	paginator := ec2.NewDescribeInstancesPaginator(c.client, input)
+
+	page := 0
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances (page %d): %w", page+1, err)
+		}
+		page++
+
+		for _, reservation := range result.Reservations {
+			for _, instance := range reservation.Instances {
+				i := convertToModelInstance(instance, c.region)
+				instances = append(instances, i)
+			}
+		}
+
+		c.log.Debug("Retrieved page of EC2 instances", "page", page, "count", len(instances))
+
+		if onPage != nil && paginator.HasMorePages() {
+			partial := make([]model.Instance, len(instances))
+			copy(partial, instances)
+			sortInstances(partial)
+			onPage(page, partial)
+		}
+	}
+
+	sortInstances(instances)
+
+	c.log.Info("Retrieved EC2 instances", "count", len(instances), "pages", page)
+
+	return instances, nil
+}
+
+// sortInstances sorts instances by name or instance ID if name not available
+func sortInstances(instances []model.Instance) {
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Name == "" && instances[j].Name == "" {
+			return instances[i].ID < instances[j].ID
+		}
+		if instances[i].Name == "" {
+			return false
+		}
+		if instances[j].Name == "" {
+			return true
+		}
+		return instances[i].Name < instances[j].Name
+	})
+}
+
+// StartInstance starts an EC2 instance
+func (c *EC2Client) StartInstance(ctx context.Context, instanceID string) error {
+	c.log.Info("Starting EC2 instance", "instanceID", instanceID)
+
+	input := &ec2.StartInstancesInput{
+		InstanceIds: []string{instanceID},
+	}
+
+	_, err := c.client.StartInstances(ctx, input)
+	c.record(ctx, "StartInstances", instanceID, nil, err)
+	if err != nil {
+		return fmt.Errorf("failed to start instance %s: %w", instanceID, err)
+	}
+
+	return nil
+}
+
+// WaitInstancesRunning waits until the EC2 instances are running, or the
+// timeout has elapsed
+func (c *EC2Client) WaitInstancesRunning(ctx context.Context, instanceIDs []string, timeout time.Duration) error {
+	c.log.Info("Waiting for EC2 instances to be running", "instances", len(instanceIDs), "timeout", timeout)
+
+	waiter := ec2.NewInstanceRunningWaiter(c.client)
+	err := waiter.Wait(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to wait for instances to be running: %w", err)
+	}
+
+	return nil
+}
+
+// WaitInstancesStopped waits until the EC2 instances are stopped, or the
+// timeout has elapsed
+func (c *EC2Client) WaitInstancesStopped(ctx context.Context, instanceIDs []string, timeout time.Duration) error {
+	c.log.Info("Waiting for EC2 instances to be stopped", "instances", len(instanceIDs), "timeout", timeout)
+
+	waiter := ec2.NewInstanceStoppedWaiter(c.client)
+	err := waiter.Wait(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to wait for instances to be stopped: %w", err)
+	}
+
+	return nil
+}
+
+// StopInstance stops an EC2 instance
+func (c *EC2Client) StopInstance(ctx context.Context, instanceID string) error {
+	c.log.Info("Stopping EC2 instance", "instanceID", instanceID)
+
+	input := &ec2.StopInstancesInput{
+		InstanceIds: []string{instanceID},
+	}
+
+	_, err := c.client.StopInstances(ctx, input)
+	c.record(ctx, "StopInstances", instanceID, nil, err)
+	if err != nil {
+		return fmt.Errorf("failed to stop instance %s: %w", instanceID, err)
+	}
+
+	return nil
+}
+
+// RebootInstance reboots an EC2 instance
+func (c *EC2Client) RebootInstance(ctx context.Context, instanceID string) error {
+	c.log.Info("Rebooting EC2 instance", "instanceID", instanceID)
+
+	input := &ec2.RebootInstancesInput{
+		InstanceIds: []string{instanceID},
+	}
+
+	_, err := c.client.RebootInstances(ctx, input)
+	c.record(ctx, "RebootInstances", instanceID, nil, err)
+	if err != nil {
+		return fmt.Errorf("failed to reboot instance %s: %w", instanceID, err)
+	}
+
+	return nil
+}
+
+// TerminateInstance terminates an EC2 instance
+func (c *EC2Client) TerminateInstance(ctx context.Context, instanceID string) error {
+	c.log.Info("Terminating EC2 instance", "instanceID", instanceID)
+
+	input := &ec2.TerminateInstancesInput{
+		InstanceIds: []string{instanceID},
+	}
+
+	_, err := c.client.TerminateInstances(ctx, input)
+	c.record(ctx, "TerminateInstances", instanceID, nil, err)
+	if err != nil {
+		return fmt.Errorf("failed to terminate instance %s: %w", instanceID, err)
+	}
+
+	return nil
+}
+
+// GetInstanceConsoleOutput retrieves the console output of an EC2 instance,
+// or its most recent output if latest is set (Nitro instances only)
+func (c *EC2Client) GetInstanceConsoleOutput(ctx context.Context, instanceID string, latest bool) (string, error) {
+	c.log.Info("Getting console output for EC2 instance", "instanceID", instanceID, "latest", latest)
+
+	input := &ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(instanceID),
+	}
+	if latest {
+		input.Latest = aws.Bool(true)
+	}
+
+	output, err := c.client.GetConsoleOutput(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to get console output for instance %s: %w", instanceID, err)
+	}
+
+	if output.Output == nil {
+		return "No console output available", nil
+	}
+
+	// The output is base64 encoded
+	decoded, err := base64.StdEncoding.DecodeString(*output.Output)
+	if err != nil {
+		return *output.Output, nil
+	}
+
+	return string(decoded), nil
+}
+
+// toEC2Filters converts filters indexed by name to EC2 API filters
+func toEC2Filters(filters map[string][]string) []types.Filter {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]types.Filter, 0, len(names))
+	for _, name := range names {
+		result = append(result, types.Filter{
+			Name:   aws.String(name),
+			Values: filters[name],
+		})
+	}
+
+	return result
+}
+
+// convertToModelInstance converts an EC2 instance to our internal model
+func convertToModelInstance(instance types.Instance, region string) model.Instance {
+	i := model.Instance{
+		ID:           *instance.InstanceId,
+		Type:         string(instance.InstanceType),
+		State:        string(instance.State.Name),
+		Region:       region,
+		LaunchTime:   aws.ToTime(instance.LaunchTime),
+		PrivateIP:    aws.ToString(instance.PrivateIpAddress),
+		PublicIP:     aws.ToString(instance.PublicIpAddress),
+		IPv6Address:  aws.ToString(instance.Ipv6Address),
+		Platform:     aws.ToString(instance.PlatformDetails),
+		Architecture: string(instance.Architecture),
+		Tags:         make(map[string]string),
+	}
+
+	// Hardware and placement
+	i.Enclave = instance.EnclaveOptions != nil && aws.ToBool(instance.EnclaveOptions.Enabled)
+	i.Hibernation = instance.HibernationOptions != nil && aws.ToBool(instance.HibernationOptions.Configured)
+	for _, accelerator := range instance.ElasticInferenceAcceleratorAssociations {
+		i.Accelerators = append(i.Accelerators, aws.ToString(accelerator.ElasticInferenceAcceleratorArn))
+	}
+	for _, gpu := range instance.ElasticGpuAssociations {
+		i.Accelerators = append(i.Accelerators, aws.ToString(gpu.ElasticGpuId))
+	}
+	i.Lifecycle = string(instance.InstanceLifecycle)
+	i.CapacityReservation = aws.ToString(instance.CapacityReservationId)
+	i.OutpostARN = aws.ToString(instance.OutpostArn)
+	if instance.Placement != nil {
+		i.Tenancy = string(instance.Placement.Tenancy)
+		i.AvailabilityZone = aws.ToString(instance.Placement.AvailabilityZone)
+		i.PlacementGroup = aws.ToString(instance.Placement.GroupName)
+		i.HostID = aws.ToString(instance.Placement.HostId)
+	}
+	i.BootMode = string(instance.CurrentInstanceBootMode)
+	i.TPMSupport = aws.ToString(instance.TpmSupport)
+	for _, license := range instance.Licenses {
+		i.Licenses = append(i.Licenses, aws.ToString(license.LicenseConfigurationArn))
+	}
+
+	if instance.CpuOptions != nil {
+		i.CPUCores = int(aws.ToInt32(instance.CpuOptions.CoreCount))
+		i.CPUThreads = int(aws.ToInt32(instance.CpuOptions.ThreadsPerCore))
+	}
+
+	// Extract all tags
+	for _, tag := range instance.Tags {
+		key := aws.ToString(tag.Key)
+		value := aws.ToString(tag.Value)
+		i.Tags[key] = value
+
+		// Set name separately for backwards compatibility
+		if key == "Name" {
+			i.Name = value
+		}
+	}
+
+	// Network
+	i.VpcID = aws.ToString(instance.VpcId)
+	i.SubnetID = aws.ToString(instance.SubnetId)
+	i.PrivateDNSName = aws.ToString(instance.PrivateDnsName)
+	i.PublicDNSName = aws.ToString(instance.PublicDnsName)
+	i.SecurityGroups = convertSecurityGroups(instance.SecurityGroups)
+	i.SourceDestCheck = aws.ToBool(instance.SourceDestCheck)
+	for _, eni := range instance.NetworkInterfaces {
+		ni := model.NetworkInterface{
+			ID:              aws.ToString(eni.NetworkInterfaceId),
+			Description:     aws.ToString(eni.Description),
+			SubnetID:        aws.ToString(eni.SubnetId),
+			VpcID:           aws.ToString(eni.VpcId),
+			PrivateIP:       aws.ToString(eni.PrivateIpAddress),
+			MACAddress:      aws.ToString(eni.MacAddress),
+			Status:          string(eni.Status),
+			SourceDestCheck: aws.ToBool(eni.SourceDestCheck),
+			SecurityGroups:  convertSecurityGroups(eni.Groups),
+		}
+		if eni.Attachment != nil {
+			ni.Primary = aws.ToInt32(eni.Attachment.DeviceIndex) == 0
+		}
+		if eni.Association != nil {
+			ni.PublicIP = aws.ToString(eni.Association.PublicIp)
+		}
+		i.NetworkInterfaces = append(i.NetworkInterfaces, ni)
+	}
+
+	// Storage
+	i.RootDeviceName = aws.ToString(instance.RootDeviceName)
+	i.RootDeviceType = string(instance.RootDeviceType)
+	i.EBSOptimized = aws.ToBool(instance.EbsOptimized)
+	for _, mapping := range instance.BlockDeviceMappings {
+		device := model.BlockDevice{
+			DeviceName: aws.ToString(mapping.DeviceName),
+		}
+		if mapping.Ebs != nil {
+			device.VolumeID = aws.ToString(mapping.Ebs.VolumeId)
+			device.Status = string(mapping.Ebs.Status)
+			device.DeleteOnTermination = aws.ToBool(mapping.Ebs.DeleteOnTermination)
+			device.AttachTime = aws.ToTime(mapping.Ebs.AttachTime)
+		}
+		i.BlockDevices = append(i.BlockDevices, device)
+	}
+
+	// Security
+	i.ImageID = aws.ToString(instance.ImageId)
+	i.KeyName = aws.ToString(instance.KeyName)
+	if instance.IamInstanceProfile != nil {
+		i.IAMInstanceProfile = aws.ToString(instance.IamInstanceProfile.Arn)
+	}
+	if instance.MetadataOptions != nil {
+		i.MetadataHTTPTokens = string(instance.MetadataOptions.HttpTokens)
+	}
+
+	// Monitoring
+	if instance.Monitoring != nil {
+		i.Monitoring = string(instance.Monitoring.State)
+	}
+
+	// Set age
+	if !i.LaunchTime.IsZero() {
+		i.Age = time.Since(i.LaunchTime).Round(time.Second)
+	}
+
+	return i
+}
+
+// convertSecurityGroups converts EC2 group identifiers to our internal model
+func convertSecurityGroups(groups []types.GroupIdentifier) []model.SecurityGroup {
+	result := make([]model.SecurityGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, model.SecurityGroup{
+			ID:   aws.ToString(group.GroupId),
+			Name: aws.ToString(group.GroupName),
+		})
+	}
+	return result
+}
@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+
+	e2caws "github.com/nlamirault/e2c/pkg/aws"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// newTestClient creates an EC2 client calling the mock, without logs
+func newTestClient(api *e2caws.MockEC2API) *e2caws.EC2Client {
+	return e2caws.NewEC2ClientWithAPI(slog.New(slog.NewTextHandler(io.Discard, nil)), "eu-west-1", api)
+}
+
+// testInstance returns a running EC2 instance named by its Name tag, if any
+func testInstance(id, name string) types.Instance {
+	instance := types.Instance{
+		InstanceId:   aws.String(id),
+		InstanceType: types.InstanceTypeT3Micro,
+		State:        &types.InstanceState{Name: types.InstanceStateNameRunning},
+	}
+	if name != "" {
+		instance.Tags = []types.Tag{{Key: aws.String("Name"), Value: aws.String(name)}}
+	}
+	return instance
+}
+
+// instanceIDs returns the IDs of instances, in order
+func instanceIDs(instances []model.Instance) []string {
+	ids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		ids = append(ids, instance.ID)
+	}
+	return ids
+}
+
+func TestListInstancesPages(t *testing.T) {
+	pages := map[string]*ec2.DescribeInstancesOutput{
+		"": {
+			Reservations: []types.Reservation{{Instances: []types.Instance{
+				testInstance("i-3", "web"),
+				testInstance("i-2", ""),
+			}}},
+			NextToken: aws.String("page-2"),
+		},
+		"page-2": {
+			Reservations: []types.Reservation{
+				{Instances: []types.Instance{testInstance("i-1", "api")}},
+				{Instances: []types.Instance{testInstance("i-0", "")}},
+			},
+		},
+	}
+	var tokens []string
+	api := &e2caws.MockEC2API{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+			if aws.ToInt32(params.MaxResults) == 0 {
+				t.Error("DescribeInstances called without MaxResults")
+			}
+			token := aws.ToString(params.NextToken)
+			tokens = append(tokens, token)
+			return pages[token], nil
+		},
+	}
+
+	var partials [][]string
+	instances, err := newTestClient(api).ListInstancesPages(context.Background(), nil, func(page int, instances []model.Instance) {
+		partials = append(partials, instanceIDs(instances))
+	})
+	if err != nil {
+		t.Fatalf("ListInstancesPages() error = %v", err)
+	}
+
+	if want := []string{"", "page-2"}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("NextToken of the calls = %q, want %q", tokens, want)
+	}
+	if calls := api.Calls("DescribeInstances"); calls != 2 {
+		t.Errorf("DescribeInstances calls = %d, want 2", calls)
+	}
+	// Sorted by name, the unnamed instances last by ID
+	if got, want := instanceIDs(instances), []string{"i-1", "i-3", "i-0", "i-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListInstancesPages() = %q, want %q", got, want)
+	}
+	// Only the intermediate pages are reported, the last one is returned
+	wantPartials := [][]string{{"i-3", "i-2"}}
+	if !reflect.DeepEqual(partials, wantPartials) {
+		t.Errorf("pages = %q, want %q", partials, wantPartials)
+	}
+}
+
+func TestListInstancesFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters map[string][]string
+		want    []types.Filter
+	}{
+		{
+			name: "no filter",
+		},
+		{
+			name:    "empty filters",
+			filters: map[string][]string{},
+		},
+		{
+			name: "sorted by name",
+			filters: map[string][]string{
+				"tag:Environment":     {"prod", "staging"},
+				"instance-state-name": {"running"},
+			},
+			want: []types.Filter{
+				{Name: aws.String("instance-state-name"), Values: []string{"running"}},
+				{Name: aws.String("tag:Environment"), Values: []string{"prod", "staging"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []types.Filter
+			api := &e2caws.MockEC2API{
+				DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+					got = params.Filters
+					return &ec2.DescribeInstancesOutput{}, nil
+				},
+			}
+
+			if _, err := newTestClient(api).ListInstances(context.Background(), tt.filters); err != nil {
+				t.Fatalf("ListInstances() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filters = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListInstancesError(t *testing.T) {
+	throttled := &smithy.GenericAPIError{Code: "RequestLimitExceeded", Message: "Request limit exceeded."}
+	api := &e2caws.MockEC2API{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+			if params.NextToken == nil {
+				return &ec2.DescribeInstancesOutput{
+					Reservations: []types.Reservation{{Instances: []types.Instance{testInstance("i-1", "web")}}},
+					NextToken:    aws.String("page-2"),
+				}, nil
+			}
+			return nil, throttled
+		},
+	}
+
+	instances, err := newTestClient(api).ListInstances(context.Background(), nil)
+	if err == nil {
+		t.Fatalf("ListInstances() = %v, want an error", instances)
+	}
+	if !strings.Contains(err.Error(), "page 2") {
+		t.Errorf("ListInstances() error = %q, want the failed page", err)
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "RequestLimitExceeded" {
+		t.Errorf("ListInstances() error = %v, want the API error wrapped", err)
+	}
+}
+
+func TestInstanceActionsErrors(t *testing.T) {
+	denied := &smithy.GenericAPIError{Code: "UnauthorizedOperation", Message: "You are not authorized to perform this operation."}
+	api := &e2caws.MockEC2API{
+		StartInstancesFunc: func(ctx context.Context, params *ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error) {
+			return nil, denied
+		},
+		StopInstancesFunc: func(ctx context.Context, params *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error) {
+			return nil, denied
+		},
+		// RebootInstancesFunc is not set: the mock fails as if not authorized
+		TerminateInstancesFunc: func(ctx context.Context, params *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+			return &ec2.TerminateInstancesOutput{}, nil
+		},
+	}
+	client := newTestClient(api)
+
+	tests := []struct {
+		name      string
+		operation string
+		action    func(ctx context.Context, instanceID string) error
+		wantErr   string // Prefix of the error, empty if none
+	}{
+		{name: "start", operation: "StartInstances", action: client.StartInstance, wantErr: "failed to start instance i-1: "},
+		{name: "stop", operation: "StopInstances", action: client.StopInstance, wantErr: "failed to stop instance i-1: "},
+		{name: "reboot", operation: "RebootInstances", action: client.RebootInstance, wantErr: "failed to reboot instance i-1: "},
+		{name: "terminate", operation: "TerminateInstances", action: client.TerminateInstance},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.action(context.Background(), "i-1")
+			if calls := api.Calls(tt.operation); calls != 1 {
+				t.Errorf("%s calls = %d, want 1", tt.operation, calls)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want prefix %q", err, tt.wantErr)
+			}
+			var apiErr smithy.APIError
+			if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "UnauthorizedOperation" {
+				t.Errorf("error = %v, want the API error wrapped", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+// Environment variables read by e2c, besides the AWS SDK
+const (
+	envProfile               = "AWS_PROFILE"
+	envConfigFile            = "AWS_CONFIG_FILE"
+	envSharedCredentialsFile = "AWS_SHARED_CREDENTIALS_FILE"
+	envCredentialExpiration  = "AWS_CREDENTIAL_EXPIRATION"
+)
+
+// EnvVar is an environment variable read by e2c or the AWS SDK
+type EnvVar struct {
+	Name        string
+	Description string
+	Sensitive   bool // The value is a secret
+}
+
+// EnvVars are the AWS environment variables honored by e2c. The region is
+// always the one of the configuration, AWS_REGION is not read.
+var EnvVars = []EnvVar{
+	{Name: envProfile, Description: "Profile used when neither --profile nor aws.profile is set"},
+	{Name: envConfigFile, Description: "Shared config file, listing the profiles"},
+	{Name: envSharedCredentialsFile, Description: "Shared credentials file, listing the profiles"},
+	{Name: "AWS_ACCESS_KEY_ID", Description: "Access key of the static credentials", Sensitive: true},
+	{Name: "AWS_SECRET_ACCESS_KEY", Description: "Secret key of the static credentials", Sensitive: true},
+	{Name: "AWS_SESSION_TOKEN", Description: "Session token of the temporary credentials", Sensitive: true},
+	{Name: envCredentialExpiration, Description: "Expiration of the credentials exported by aws-vault, displayed in the status bar"},
+	{Name: awsVaultEnv, Description: "Profile of aws-vault exec, run again once the credentials have expired"},
+	{Name: "AWS_RETRY_MODE", Description: "Retry mode, unless aws.retry_mode is set"},
+	{Name: "AWS_MAX_ATTEMPTS", Description: "Attempts of each call, unless aws.max_attempts is set"},
+	{Name: "AWS_CA_BUNDLE", Description: "Certificates of the TLS connections to AWS, e.g. behind a proxy"},
+	{Name: "HTTPS_PROXY", Description: "Proxy of the connections to AWS"},
+	{Name: "AWS_EC2_METADATA_DISABLED", Description: "Disables the lookup of the instance e2c runs on, when true"},
+	{Name: "AWS_EC2_METADATA_SERVICE_ENDPOINT", Description: "Endpoint of the instance metadata service"},
+}
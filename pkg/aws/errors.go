@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// ErrorKind classifies an AWS error into a cause the user can act on
+type ErrorKind int
+
+const (
+	// ErrorUnknown is an error which could not be classified
+	ErrorUnknown ErrorKind = iota
+	// ErrorCredentials means no valid credentials could be found
+	ErrorCredentials
+	// ErrorExpiredCredentials means the credentials or the session have expired
+	ErrorExpiredCredentials
+	// ErrorPermissions means the caller is not allowed to perform the operation
+	ErrorPermissions
+	// ErrorNetwork means the AWS endpoint could not be reached
+	ErrorNetwork
+	// ErrorThrottling means the API calls are rate limited
+	ErrorThrottling
+)
+
+// String returns a short description of the error kind
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorCredentials:
+		return "Invalid or missing credentials"
+	case ErrorExpiredCredentials:
+		return "Expired credentials"
+	case ErrorPermissions:
+		return "Insufficient permissions"
+	case ErrorNetwork:
+		return "Network error"
+	case ErrorThrottling:
+		return "Request throttled"
+	default:
+		return "Unexpected error"
+	}
+}
+
+// Hint returns a suggestion to fix the error
+func (k ErrorKind) Hint() string {
+	switch k {
+	case ErrorCredentials:
+		return "Check the AWS profile and credentials configuration, or switch to another profile."
+	case ErrorExpiredCredentials:
+		return "Renew the credentials (e.g. aws sso login) and retry."
+	case ErrorPermissions:
+		return "The IAM identity needs the ec2:DescribeInstances permission."
+	case ErrorNetwork:
+		return "Check the network connection, proxy settings and the selected region."
+	case ErrorThrottling:
+		return "Too many requests were sent to the EC2 API, wait a moment and retry."
+	default:
+		return "See the logs for more details."
+	}
+}
+
+// credentialsErrorCodes are the API error codes returned for invalid credentials
+var credentialsErrorCodes = map[string]struct{}{
+	"AuthFailure":                 {},
+	"InvalidClientTokenId":        {},
+	"UnrecognizedClientException": {},
+	"SignatureDoesNotMatch":       {},
+	"IncompleteSignature":         {},
+	"MissingAuthenticationToken":  {},
+}
+
+// expiredErrorCodes are the API error codes returned for expired credentials
+var expiredErrorCodes = map[string]struct{}{
+	"ExpiredToken":          {},
+	"ExpiredTokenException": {},
+	"RequestExpired":        {},
+}
+
+// permissionsErrorCodes are the API error codes returned for denied operations
+var permissionsErrorCodes = map[string]struct{}{
+	"UnauthorizedOperation": {},
+	"AccessDenied":          {},
+	"AccessDeniedException": {},
+	"OptInRequired":         {},
+}
+
+// throttlingErrorCodes are the API error codes returned when rate limited
+var throttlingErrorCodes = map[string]struct{}{
+	"Throttling":               {},
+	"ThrottlingException":      {},
+	"RequestLimitExceeded":     {},
+	"TooManyRequestsException": {},
+}
+
+// ClassifyError returns the kind of an error returned by the AWS SDK
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorUnknown
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		if _, ok := credentialsErrorCodes[code]; ok {
+			return ErrorCredentials
+		}
+		if _, ok := expiredErrorCodes[code]; ok {
+			return ErrorExpiredCredentials
+		}
+		if _, ok := permissionsErrorCodes[code]; ok {
+			return ErrorPermissions
+		}
+		if _, ok := throttlingErrorCodes[code]; ok {
+			return ErrorThrottling
+		}
+	}
+
+	// Errors raised while resolving credentials are not API errors
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "token has expired"), strings.Contains(msg, "refresh cached SSO token failed"):
+		return ErrorExpiredCredentials
+	case strings.Contains(msg, "failed to retrieve credentials"), strings.Contains(msg, "failed to refresh cached credentials"):
+		return ErrorCredentials
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorNetwork
+	}
+
+	return ErrorUnknown
+}
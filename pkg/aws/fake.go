@@ -0,0 +1,490 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// FakeBackend is an in-memory implementation of the EC2 and STS APIs used by
+// e2c selftest to run the UI without AWS. It is plugged in as the HTTP client
+// of the SDK, so that the requests never leave the process. The actions which
+// are not implemented, and the other services, fail as if not authorized.
+type FakeBackend struct {
+	mu          sync.Mutex
+	instances   []model.Instance
+	calls       map[string]int
+	protections map[string]map[string]bool // Attributes enabled, by instance
+}
+
+// NewFakeBackend creates a fake backend serving the given instances, e.g.
+// built with the modeltest package
+func NewFakeBackend(instances []model.Instance) *FakeBackend {
+	return &FakeBackend{
+		instances:   instances,
+		calls:       make(map[string]int),
+		protections: make(map[string]map[string]bool),
+	}
+}
+
+// NewFakeEC2Client creates an EC2 client calling the fake backend with static
+// credentials, recording its calls with the telemetry
+func NewFakeEC2Client(log *slog.Logger, region string, backend *FakeBackend, telemetry Telemetry) *EC2Client {
+	cfg := aws.Config{
+		Region:     region,
+		HTTPClient: backend,
+		Credentials: aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     "AKIAFAKEBACKEND",
+				SecretAccessKey: "fake",
+				Source:          "FakeBackend",
+			}, nil
+		})),
+		RetryMaxAttempts: 1,
+	}
+	withTracing(&cfg, telemetry)
+
+	return &EC2Client{
+		client:   ec2.NewFromConfig(cfg),
+		cfg:      cfg,
+		services: newServices(cfg),
+		log:      log,
+		region:   region,
+	}
+}
+
+// Calls returns the number of calls of an action
+func (b *FakeBackend) Calls(action string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls[action]
+}
+
+// State returns the state of an instance, empty if unknown
+func (b *FakeBackend) State(instanceID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, instance := range b.instances {
+		if instance.ID == instanceID {
+			return instance.State
+		}
+	}
+	return ""
+}
+
+// Protected returns whether a protection attribute of an instance is
+// enabled, e.g. disableApiStop
+func (b *FakeBackend) Protected(instanceID, attribute string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.protections[instanceID][attribute]
+}
+
+// Do serves a request of the SDK
+func (b *FakeBackend) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+	params, err := url.ParseQuery(string(body))
+	if err != nil {
+		params = url.Values{}
+	}
+	action := params.Get("Action")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls[action]++
+
+	switch {
+	case strings.HasPrefix(req.URL.Host, "ec2."):
+		return b.serveEC2(req, action, params)
+	case strings.HasPrefix(req.URL.Host, "sts.") && action == "GetCallerIdentity":
+		return fakeResponse(req, http.StatusOK, "text/xml", fakeCallerIdentity{
+			ARN:     "arn:aws:iam::123456789012:user/selftest",
+			UserID:  "AIDAFAKEBACKEND",
+			Account: "123456789012",
+		})
+	case strings.Contains(req.Header.Get("Content-Type"), "json"):
+		data := []byte(`{"__type":"SubscriptionRequiredException","message":"not available in the fake backend"}`)
+		return fakeRawResponse(req, http.StatusBadRequest, "application/x-amz-json-1.1", data), nil
+	default:
+		return fakeError(req, "UnauthorizedOperation", "not available in the fake backend")
+	}
+}
+
+// serveEC2 serves an action of the EC2 API
+func (b *FakeBackend) serveEC2(req *http.Request, action string, params url.Values) (*http.Response, error) {
+	ids := fakeInstanceIDs(params)
+
+	switch action {
+	case "DescribeInstances":
+		output := fakeDescribeInstances{RequestID: "fake"}
+		for _, instance := range b.instances {
+			if len(ids) > 0 && !ids[instance.ID] {
+				continue
+			}
+			output.Reservations = append(output.Reservations, fakeReservation{
+				ReservationID: "r-" + strings.TrimPrefix(instance.ID, "i-"),
+				Instances:     []fakeInstanceXML{toFakeInstanceXML(instance)},
+			})
+		}
+		return fakeResponse(req, http.StatusOK, "text/xml", output)
+
+	case "DescribeInstanceStatus":
+		output := fakeDescribeInstanceStatus{RequestID: "fake"}
+		for _, instance := range b.instances {
+			if len(ids) > 0 && !ids[instance.ID] {
+				continue
+			}
+			status := fakeInstanceStatusXML{
+				InstanceID:       instance.ID,
+				AvailabilityZone: valueOr(instance.AvailabilityZone, "us-east-1a"),
+				State:            toFakeState(instance.State),
+				System:           "not-applicable",
+				Instance:         "not-applicable",
+			}
+			if instance.State == "running" {
+				status.System, status.Instance = "ok", "ok"
+			}
+			output.Statuses = append(output.Statuses, status)
+		}
+		return fakeResponse(req, http.StatusOK, "text/xml", output)
+
+	case "DescribeInstanceAttribute":
+		output := fakeDescribeInstanceAttribute{
+			RequestID:  "fake",
+			InstanceID: params.Get("InstanceId"),
+		}
+		protections := b.protections[output.InstanceID]
+		switch params.Get("Attribute") {
+		case "disableApiTermination":
+			output.Termination = &fakeBool{Value: protections["disableApiTermination"]}
+		case "disableApiStop":
+			output.Stop = &fakeBool{Value: protections["disableApiStop"]}
+		}
+		return fakeResponse(req, http.StatusOK, "text/xml", output)
+
+	case "ModifyInstanceAttribute":
+		instanceID := params.Get("InstanceId")
+		if b.protections[instanceID] == nil {
+			b.protections[instanceID] = make(map[string]bool)
+		}
+		for param, attribute := range map[string]string{
+			"DisableApiTermination.Value": "disableApiTermination",
+			"DisableApiStop.Value":        "disableApiStop",
+		} {
+			if value := params.Get(param); value != "" {
+				b.protections[instanceID][attribute] = value == "true"
+			}
+		}
+		return fakeResponse(req, http.StatusOK, "text/xml", fakeReturn{XMLName: xml.Name{Local: action + "Response"}, Return: true})
+
+	case "StartInstances", "StopInstances", "RebootInstances", "TerminateInstances":
+		target := map[string]string{
+			"StartInstances":     "running",
+			"StopInstances":      "stopped",
+			"RebootInstances":    "running",
+			"TerminateInstances": "terminated",
+		}[action]
+
+		output := fakeStateChanges{XMLName: xml.Name{Local: action + "Response"}, RequestID: "fake"}
+		for i := range b.instances {
+			instance := &b.instances[i]
+			if !ids[instance.ID] {
+				continue
+			}
+			output.Changes = append(output.Changes, fakeStateChange{
+				InstanceID: instance.ID,
+				Current:    toFakeState(target),
+				Previous:   toFakeState(instance.State),
+			})
+			instance.State = target
+		}
+		if len(output.Changes) < len(ids) {
+			return fakeError(req, "InvalidInstanceID.NotFound", "unknown instance")
+		}
+		if action == "RebootInstances" {
+			return fakeResponse(req, http.StatusOK, "text/xml", fakeReturn{XMLName: xml.Name{Local: action + "Response"}, Return: true})
+		}
+		return fakeResponse(req, http.StatusOK, "text/xml", output)
+
+	case "CreateTags", "DeleteTags":
+		resources := make(map[string]bool)
+		tags := make(map[string]string)
+		for name, values := range params {
+			if strings.HasPrefix(name, "ResourceId.") && len(values) > 0 {
+				resources[values[0]] = true
+			}
+			if index, ok := strings.CutSuffix(name, ".Key"); ok && strings.HasPrefix(index, "Tag.") && len(values) > 0 {
+				tags[values[0]] = params.Get(index + ".Value")
+			}
+		}
+		for i := range b.instances {
+			instance := &b.instances[i]
+			if !resources[instance.ID] {
+				continue
+			}
+			updated := make(map[string]string, len(instance.Tags)+len(tags))
+			for key, value := range instance.Tags {
+				updated[key] = value
+			}
+			for key, value := range tags {
+				if action == "CreateTags" {
+					updated[key] = value
+				} else {
+					delete(updated, key)
+				}
+			}
+			instance.Tags = updated
+		}
+		return fakeResponse(req, http.StatusOK, "text/xml", fakeReturn{XMLName: xml.Name{Local: action + "Response"}, Return: true})
+
+	case "DescribeVpcs", "DescribeSubnets", "DescribeAddresses", "DescribeImages", "DescribeKeyPairs":
+		return fakeResponse(req, http.StatusOK, "text/xml", fakeEmpty{XMLName: xml.Name{Local: action + "Response"}, RequestID: "fake"})
+
+	default:
+		return fakeError(req, "UnauthorizedOperation", fmt.Sprintf("%s is not available in the fake backend", action))
+	}
+}
+
+// fakeInstanceIDs returns the instance IDs of the InstanceId.N parameters
+func fakeInstanceIDs(params url.Values) map[string]bool {
+	ids := make(map[string]bool)
+	for name, values := range params {
+		if strings.HasPrefix(name, "InstanceId.") && len(values) > 0 {
+			ids[values[0]] = true
+		}
+	}
+	return ids
+}
+
+// fakeResponse returns a response with the XML encoding of the output
+func fakeResponse(req *http.Request, status int, contentType string, output any) (*http.Response, error) {
+	data, err := xml.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	return fakeRawResponse(req, status, contentType, data), nil
+}
+
+// fakeRawResponse returns a response with the given body
+func fakeRawResponse(req *http.Request, status int, contentType string, data []byte) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
+
+// fakeError returns an EC2 error response
+func fakeError(req *http.Request, code, message string) (*http.Response, error) {
+	return fakeResponse(req, http.StatusBadRequest, "text/xml", fakeErrorXML{
+		Code:      code,
+		Message:   message,
+		RequestID: "fake",
+	})
+}
+
+// fakeStateCodes are the codes of the instance states
+var fakeStateCodes = map[string]int{
+	"pending":       0,
+	"running":       16,
+	"shutting-down": 32,
+	"terminated":    48,
+	"stopping":      64,
+	"stopped":       80,
+}
+
+func toFakeState(state string) fakeStateXML {
+	return fakeStateXML{Code: fakeStateCodes[state], Name: state}
+}
+
+// toFakeInstanceXML converts an instance, with defaults for the attributes
+// not set
+func toFakeInstanceXML(instance model.Instance) fakeInstanceXML {
+	launchTime := instance.LaunchTime
+	if launchTime.IsZero() {
+		launchTime = time.Now().Add(-24 * time.Hour)
+	}
+	result := fakeInstanceXML{
+		InstanceID:       instance.ID,
+		ImageID:          valueOr(instance.ImageID, "ami-0fakebackend"),
+		State:            toFakeState(instance.State),
+		InstanceType:     instance.Type,
+		LaunchTime:       launchTime.UTC().Format(time.RFC3339),
+		AvailabilityZone: valueOr(instance.AvailabilityZone, "us-east-1a"),
+		Tenancy:          valueOr(instance.Tenancy, "default"),
+		PlacementGroup:   instance.PlacementGroup,
+		HostID:           instance.HostID,
+		PrivateIP:        valueOr(instance.PrivateIP, "10.0.0.10"),
+		PrivateDNSName:   instance.PrivateDNSName,
+		PublicIP:         instance.PublicIP,
+		PublicDNSName:    instance.PublicDNSName,
+		IPv6Address:      instance.IPv6Address,
+		VpcID:            valueOr(instance.VpcID, "vpc-0fakebackend"),
+		SubnetID:         valueOr(instance.SubnetID, "subnet-0fakebackend"),
+		Architecture:     valueOr(instance.Architecture, "x86_64"),
+		RootDeviceType:   valueOr(instance.RootDeviceType, "ebs"),
+		PlatformDetails:  valueOr(instance.Platform, "Linux/UNIX"),
+		Lifecycle:        instance.Lifecycle,
+		CoreCount:        max(instance.CPUCores, 1),
+		ThreadsPerCore:   max(instance.CPUThreads, 1),
+	}
+	if strings.HasPrefix(instance.Platform, "Windows") {
+		result.Platform = "windows"
+	}
+	if _, ok := instance.Tags["Name"]; !ok && instance.Name != "" {
+		result.Tags = append(result.Tags, fakeTagXML{Key: "Name", Value: instance.Name})
+	}
+	for key, value := range instance.Tags {
+		result.Tags = append(result.Tags, fakeTagXML{Key: key, Value: value})
+	}
+	return result
+}
+
+// valueOr returns the value, or the default one if empty
+func valueOr(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// XML documents of the EC2 and STS responses
+
+type fakeStateXML struct {
+	Code int    `xml:"code"`
+	Name string `xml:"name"`
+}
+
+type fakeTagXML struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+type fakeInstanceXML struct {
+	InstanceID       string       `xml:"instanceId"`
+	ImageID          string       `xml:"imageId"`
+	State            fakeStateXML `xml:"instanceState"`
+	InstanceType     string       `xml:"instanceType"`
+	LaunchTime       string       `xml:"launchTime"`
+	AvailabilityZone string       `xml:"placement>availabilityZone"`
+	Tenancy          string       `xml:"placement>tenancy"`
+	PlacementGroup   string       `xml:"placement>groupName,omitempty"`
+	HostID           string       `xml:"placement>hostId,omitempty"`
+	PrivateIP        string       `xml:"privateIpAddress"`
+	PrivateDNSName   string       `xml:"privateDnsName,omitempty"`
+	PublicIP         string       `xml:"ipAddress,omitempty"`
+	PublicDNSName    string       `xml:"dnsName,omitempty"`
+	IPv6Address      string       `xml:"ipv6Address,omitempty"`
+	VpcID            string       `xml:"vpcId"`
+	SubnetID         string       `xml:"subnetId"`
+	Architecture     string       `xml:"architecture"`
+	RootDeviceType   string       `xml:"rootDeviceType"`
+	PlatformDetails  string       `xml:"platformDetails"`
+	Platform         string       `xml:"platform,omitempty"`
+	Lifecycle        string       `xml:"instanceLifecycle,omitempty"`
+	CoreCount        int          `xml:"cpuOptions>coreCount"`
+	ThreadsPerCore   int          `xml:"cpuOptions>threadsPerCore"`
+	Tags             []fakeTagXML `xml:"tagSet>item"`
+}
+
+type fakeReservation struct {
+	ReservationID string            `xml:"reservationId"`
+	Instances     []fakeInstanceXML `xml:"instancesSet>item"`
+}
+
+type fakeDescribeInstances struct {
+	XMLName      xml.Name          `xml:"DescribeInstancesResponse"`
+	RequestID    string            `xml:"requestId"`
+	Reservations []fakeReservation `xml:"reservationSet>item"`
+}
+
+type fakeInstanceStatusXML struct {
+	InstanceID       string       `xml:"instanceId"`
+	AvailabilityZone string       `xml:"availabilityZone"`
+	State            fakeStateXML `xml:"instanceState"`
+	System           string       `xml:"systemStatus>status"`
+	Instance         string       `xml:"instanceStatus>status"`
+}
+
+type fakeDescribeInstanceStatus struct {
+	XMLName   xml.Name                `xml:"DescribeInstanceStatusResponse"`
+	RequestID string                  `xml:"requestId"`
+	Statuses  []fakeInstanceStatusXML `xml:"instanceStatusSet>item"`
+}
+
+type fakeBool struct {
+	Value bool `xml:"value"`
+}
+
+type fakeDescribeInstanceAttribute struct {
+	XMLName     xml.Name  `xml:"DescribeInstanceAttributeResponse"`
+	RequestID   string    `xml:"requestId"`
+	InstanceID  string    `xml:"instanceId"`
+	Termination *fakeBool `xml:"disableApiTermination,omitempty"`
+	Stop        *fakeBool `xml:"disableApiStop,omitempty"`
+}
+
+type fakeStateChange struct {
+	InstanceID string       `xml:"instanceId"`
+	Current    fakeStateXML `xml:"currentState"`
+	Previous   fakeStateXML `xml:"previousState"`
+}
+
+type fakeStateChanges struct {
+	XMLName   xml.Name
+	RequestID string            `xml:"requestId"`
+	Changes   []fakeStateChange `xml:"instancesSet>item"`
+}
+
+type fakeReturn struct {
+	XMLName xml.Name
+	Return  bool `xml:"return"`
+}
+
+type fakeEmpty struct {
+	XMLName   xml.Name
+	RequestID string `xml:"requestId"`
+}
+
+type fakeErrorXML struct {
+	XMLName   xml.Name `xml:"Response"`
+	Code      string   `xml:"Errors>Error>Code"`
+	Message   string   `xml:"Errors>Error>Message"`
+	RequestID string   `xml:"RequestID"`
+}
+
+type fakeCallerIdentity struct {
+	XMLName xml.Name `xml:"GetCallerIdentityResponse"`
+	ARN     string   `xml:"GetCallerIdentityResult>Arn"`
+	UserID  string   `xml:"GetCallerIdentityResult>UserId"`
+	Account string   `xml:"GetCallerIdentityResult>Account"`
+}
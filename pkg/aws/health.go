@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/health"
+	"github.com/aws/aws-sdk-go-v2/service/health/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+const (
+	// healthRegion is the region of the global endpoint of the AWS Health API
+	healthRegion = "us-east-1"
+
+	// maxHealthPages is the maximum number of pages of events retrieved at once
+	maxHealthPages = 5
+)
+
+// ListHealthEvents retrieves the open and upcoming AWS Health events of EC2
+// in the given regions, and the global ones.
+//
+// The AWS Health API requires a Business, Enterprise On-Ramp or Enterprise
+// support plan, it returns a SubscriptionRequiredException otherwise.
+func (c *EC2Client) ListHealthEvents(ctx context.Context, regions []string) ([]model.HealthEvent, error) {
+	c.log.Info("Listing AWS Health events", "regions", regions)
+
+	var events []model.HealthEvent
+	paginator := health.NewDescribeEventsPaginator(c.health, &health.DescribeEventsInput{
+		Filter: &types.EventFilter{
+			Services:         []string{"EC2"},
+			Regions:          append(append([]string{}, regions...), "global"),
+			EventStatusCodes: []types.EventStatusCode{types.EventStatusCodeOpen, types.EventStatusCodeUpcoming},
+		},
+	})
+	for page := 0; page < maxHealthPages && paginator.HasMorePages(); page++ {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe health events: %w", err)
+		}
+
+		for _, event := range output.Events {
+			events = append(events, model.HealthEvent{
+				ARN:              aws.ToString(event.Arn),
+				Service:          aws.ToString(event.Service),
+				TypeCode:         aws.ToString(event.EventTypeCode),
+				Category:         string(event.EventTypeCategory),
+				Region:           aws.ToString(event.Region),
+				AvailabilityZone: aws.ToString(event.AvailabilityZone),
+				Status:           string(event.StatusCode),
+				StartTime:        aws.ToTime(event.StartTime),
+				EndTime:          aws.ToTime(event.EndTime),
+				LastUpdated:      aws.ToTime(event.LastUpdatedTime),
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// GetHealthEventDescription retrieves the latest description of an AWS
+// Health event
+func (c *EC2Client) GetHealthEventDescription(ctx context.Context, arn string) (string, error) {
+	c.log.Info("Getting AWS Health event details", "arn", arn)
+
+	output, err := c.health.DescribeEventDetails(ctx, &health.DescribeEventDetailsInput{
+		EventArns: []string{arn},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe health event details: %w", err)
+	}
+
+	if len(output.FailedSet) > 0 {
+		return "", fmt.Errorf("failed to describe health event details: %s", aws.ToString(output.FailedSet[0].ErrorMessage))
+	}
+	if len(output.SuccessfulSet) == 0 || output.SuccessfulSet[0].EventDescription == nil {
+		return "", nil
+	}
+	return aws.ToString(output.SuccessfulSet[0].EventDescription.LatestDescription), nil
+}
+
+// IsHealthUnavailable returns true if the AWS Health API is not available
+// with the support plan of the account
+func IsHealthUnavailable(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "SubscriptionRequiredException"
+}
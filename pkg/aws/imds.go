@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+)
+
+// imdsTimeout bounds the lookup of the instance e2c runs on, the metadata
+// service not answering outside of EC2
+const imdsTimeout = 2 * time.Second
+
+// SelfInstanceID returns the ID of the EC2 instance e2c runs on, from the
+// instance metadata service, or an error if e2c does not run on EC2. The
+// lookup is disabled by AWS_EC2_METADATA_DISABLED=true.
+func SelfInstanceID(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, imdsTimeout)
+	defer cancel()
+
+	client := imds.New(imds.Options{Retryer: aws.NopRetryer{}})
+	output, err := client.GetMetadata(ctx, &imds.GetMetadataInput{Path: "instance-id"})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the instance ID from the metadata service: %w", err)
+	}
+	defer output.Content.Close()
+
+	data, err := io.ReadAll(output.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the instance ID from the metadata service: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
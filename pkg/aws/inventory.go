@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package aws is the EC2 inventory of e2c: it lists the instances of a
+// region, changes their state, and enriches them with their status checks,
+// scheduled events and protections, retrying and rate limiting the calls and
+// recording the mutating actions in an audit log.
+//
+// Other Go tools can embed it through the interfaces below, implemented by
+// EC2Client, rather than depending on the whole client:
+//
+//	client, err := aws.NewEC2Client(slog.Default(), "eu-west-1", "prod", aws.AssumeRole{}, aws.CallOptions{}, aws.Telemetry{})
+//	if err != nil {
+//		return err
+//	}
+//	var inventory aws.Inventory = client
+//	instances, err := inventory.ListInstances(ctx, map[string][]string{"instance-state-name": {"running"}})
+//
+// The instances are model.Instance values, and the store package caches them
+// with their enrichments to share them between goroutines.
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// Lister lists the instances of a region
+type Lister interface {
+	// GetRegion returns the region of the instances
+	GetRegion() string
+
+	// ListInstances lists the instances matching EC2 API filters, e.g.
+	// instance-state-name or tag:Team, all of them without filter
+	ListInstances(ctx context.Context, filters map[string][]string) ([]model.Instance, error)
+
+	// ListInstancesPages lists the instances like ListInstances, calling
+	// onPage as each page but the last is retrieved
+	ListInstancesPages(ctx context.Context, filters map[string][]string, onPage PageFunc) ([]model.Instance, error)
+}
+
+// Actions changes the state of the instances. The actions are recorded in
+// the audit log of the client, if any.
+type Actions interface {
+	StartInstance(ctx context.Context, instanceID string) error
+	StopInstance(ctx context.Context, instanceID string) error
+	RebootInstance(ctx context.Context, instanceID string) error
+	TerminateInstance(ctx context.Context, instanceID string) error
+
+	// WaitInstancesRunning and WaitInstancesStopped wait for instances to
+	// reach a state, until the timeout
+	WaitInstancesRunning(ctx context.Context, instanceIDs []string, timeout time.Duration) error
+	WaitInstancesStopped(ctx context.Context, instanceIDs []string, timeout time.Duration) error
+}
+
+// Enricher retrieves the data completing the instances listed, which
+// require more calls
+type Enricher interface {
+	// GetInstanceStatus returns the status checks and the scheduled events
+	// of an instance
+	GetInstanceStatus(ctx context.Context, instanceID string) (*model.InstanceStatus, error)
+
+	// ListScheduledEvents returns the active scheduled events of the
+	// instances of the region, by instance ID
+	ListScheduledEvents(ctx context.Context) (map[string][]model.ScheduledEvent, error)
+
+	// GetInstanceProtection returns the termination and stop protections of
+	// an instance
+	GetInstanceProtection(ctx context.Context, instanceID string) (*model.Protection, error)
+
+	// GetCPUCredits returns the credit specification of a burstable
+	// instance, and its credit balance if asked
+	GetCPUCredits(ctx context.Context, instanceID string, balance bool) (*model.CPUCredits, error)
+}
+
+// Inventory lists, changes and enriches the instances of a region
+type Inventory interface {
+	Lister
+	Actions
+	Enricher
+}
+
+// EC2Client implements Inventory
+var _ Inventory = (*EC2Client)(nil)
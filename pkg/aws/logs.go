@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// maxLogPages is the maximum number of pages of log events retrieved at once
+const maxLogPages = 10
+
+// FilterLogEvents retrieves the events of a CloudWatch Logs group since the
+// given time, restricted to a stream if not empty
+func (c *EC2Client) FilterLogEvents(ctx context.Context, group, stream string, since time.Time) ([]model.LogEvent, error) {
+	c.log.Debug("Filtering log events", "group", group, "stream", stream, "since", since)
+
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(group),
+		StartTime:    aws.Int64(since.UnixMilli()),
+	}
+	if stream != "" {
+		input.LogStreamNames = []string{stream}
+	}
+
+	var events []model.LogEvent
+	paginator := cloudwatchlogs.NewFilterLogEventsPaginator(c.logs, input)
+	for page := 0; page < maxLogPages && paginator.HasMorePages(); page++ {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter log events of %s: %w", group, err)
+		}
+
+		for _, event := range output.Events {
+			events = append(events, model.LogEvent{
+				ID:        aws.ToString(event.EventId),
+				Timestamp: time.UnixMilli(aws.ToInt64(event.Timestamp)),
+				Stream:    aws.ToString(event.LogStreamName),
+				Message:   strings.TrimRight(aws.ToString(event.Message), "\n"),
+			})
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// GetInstanceManifest retrieves the raw description of an EC2 instance, as
+// returned by DescribeInstances, with the fields not set left out. The
+// result can be encoded to JSON or YAML.
+func (c *EC2Client) GetInstanceManifest(ctx context.Context, instanceID string) (map[string]any, error) {
+	c.log.Info("Getting manifest of EC2 instance", "instanceID", instanceID)
+
+	output, err := c.client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instance %s: %w", instanceID, err)
+	}
+	if len(output.Reservations) == 0 || len(output.Reservations[0].Instances) == 0 {
+		return nil, fmt.Errorf("instance %s not found", instanceID)
+	}
+
+	// Go through JSON to get the field names of the API
+	data, err := json.Marshal(output.Reservations[0].Instances[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode instance %s: %w", instanceID, err)
+	}
+	var manifest map[string]any
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode instance %s: %w", instanceID, err)
+	}
+	pruneUnset(manifest)
+
+	return manifest, nil
+}
+
+// pruneUnset removes the fields of a decoded JSON value which are null, or
+// empty strings such as the enums not set, recursively
+func pruneUnset(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if field == nil || field == "" {
+				delete(v, key)
+				continue
+			}
+			pruneUnset(field)
+		}
+	case []any:
+		for _, item := range v {
+			pruneUnset(item)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
+)
+
+// MockEC2API is an implementation of EC2API whose operations are functions
+// set by the caller, to test the code calling EC2 without AWS. The
+// operations whose function is not set fail as if not authorized. The calls
+// are counted by operation.
+type MockEC2API struct {
+	DescribeInstancesFunc                    func(ctx context.Context, params *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error)
+	DescribeInstanceStatusFunc               func(ctx context.Context, params *ec2.DescribeInstanceStatusInput) (*ec2.DescribeInstanceStatusOutput, error)
+	DescribeInstanceAttributeFunc            func(ctx context.Context, params *ec2.DescribeInstanceAttributeInput) (*ec2.DescribeInstanceAttributeOutput, error)
+	ModifyInstanceAttributeFunc              func(ctx context.Context, params *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error)
+	StartInstancesFunc                       func(ctx context.Context, params *ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error)
+	StopInstancesFunc                        func(ctx context.Context, params *ec2.StopInstancesInput) (*ec2.StopInstancesOutput, error)
+	RebootInstancesFunc                      func(ctx context.Context, params *ec2.RebootInstancesInput) (*ec2.RebootInstancesOutput, error)
+	TerminateInstancesFunc                   func(ctx context.Context, params *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error)
+	RunInstancesFunc                         func(ctx context.Context, params *ec2.RunInstancesInput) (*ec2.RunInstancesOutput, error)
+	GetConsoleOutputFunc                     func(ctx context.Context, params *ec2.GetConsoleOutputInput) (*ec2.GetConsoleOutputOutput, error)
+	CreateTagsFunc                           func(ctx context.Context, params *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
+	DeleteTagsFunc                           func(ctx context.Context, params *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error)
+	DescribeInstanceCreditSpecificationsFunc func(ctx context.Context, params *ec2.DescribeInstanceCreditSpecificationsInput) (*ec2.DescribeInstanceCreditSpecificationsOutput, error)
+	ModifyInstanceCreditSpecificationFunc    func(ctx context.Context, params *ec2.ModifyInstanceCreditSpecificationInput) (*ec2.ModifyInstanceCreditSpecificationOutput, error)
+	MonitorInstancesFunc                     func(ctx context.Context, params *ec2.MonitorInstancesInput) (*ec2.MonitorInstancesOutput, error)
+	UnmonitorInstancesFunc                   func(ctx context.Context, params *ec2.UnmonitorInstancesInput) (*ec2.UnmonitorInstancesOutput, error)
+	ModifyInstancePlacementFunc              func(ctx context.Context, params *ec2.ModifyInstancePlacementInput) (*ec2.ModifyInstancePlacementOutput, error)
+	DescribeInstanceTypeOfferingsFunc        func(ctx context.Context, params *ec2.DescribeInstanceTypeOfferingsInput) (*ec2.DescribeInstanceTypeOfferingsOutput, error)
+	DescribeVolumesFunc                      func(ctx context.Context, params *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error)
+	CreateVolumeFunc                         func(ctx context.Context, params *ec2.CreateVolumeInput) (*ec2.CreateVolumeOutput, error)
+	AttachVolumeFunc                         func(ctx context.Context, params *ec2.AttachVolumeInput) (*ec2.AttachVolumeOutput, error)
+	DetachVolumeFunc                         func(ctx context.Context, params *ec2.DetachVolumeInput) (*ec2.DetachVolumeOutput, error)
+	DeleteVolumeFunc                         func(ctx context.Context, params *ec2.DeleteVolumeInput) (*ec2.DeleteVolumeOutput, error)
+	DescribeSnapshotsFunc                    func(ctx context.Context, params *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error)
+	DescribeRegionsFunc                      func(ctx context.Context, params *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error)
+	DescribeVpcsFunc                         func(ctx context.Context, params *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error)
+	DescribeSubnetsFunc                      func(ctx context.Context, params *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error)
+	DescribeAddressesFunc                    func(ctx context.Context, params *ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error)
+	DescribeRouteTablesFunc                  func(ctx context.Context, params *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error)
+	DescribeNetworkAclsFunc                  func(ctx context.Context, params *ec2.DescribeNetworkAclsInput) (*ec2.DescribeNetworkAclsOutput, error)
+	DescribeImagesFunc                       func(ctx context.Context, params *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error)
+	DescribeKeyPairsFunc                     func(ctx context.Context, params *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error)
+	CreateNetworkInsightsPathFunc            func(ctx context.Context, params *ec2.CreateNetworkInsightsPathInput) (*ec2.CreateNetworkInsightsPathOutput, error)
+	StartNetworkInsightsAnalysisFunc         func(ctx context.Context, params *ec2.StartNetworkInsightsAnalysisInput) (*ec2.StartNetworkInsightsAnalysisOutput, error)
+	DescribeNetworkInsightsAnalysesFunc      func(ctx context.Context, params *ec2.DescribeNetworkInsightsAnalysesInput) (*ec2.DescribeNetworkInsightsAnalysesOutput, error)
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// NewEC2ClientWithAPI creates an EC2 client calling the given implementation
+// of the EC2 API, e.g. a MockEC2API. The other services, such as STS or
+// CloudWatch, are not available.
+func NewEC2ClientWithAPI(log *slog.Logger, region string, api EC2API) *EC2Client {
+	cfg := aws.Config{
+		Region:      region,
+		Credentials: aws.AnonymousCredentials{},
+	}
+
+	return &EC2Client{
+		client:   api,
+		cfg:      cfg,
+		services: newServices(cfg),
+		log:      log,
+		region:   region,
+	}
+}
+
+// Calls returns the number of calls of an operation
+func (m *MockEC2API) Calls(operation string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[operation]
+}
+
+// mockCall counts a call of an operation, and calls its function if set
+func mockCall[In, Out any](m *MockEC2API, operation string, fn func(context.Context, *In) (*Out, error), ctx context.Context, params *In) (*Out, error) {
+	m.mu.Lock()
+	if m.calls == nil {
+		m.calls = make(map[string]int)
+	}
+	m.calls[operation]++
+	m.mu.Unlock()
+
+	if fn == nil {
+		return nil, &smithy.GenericAPIError{
+			Code:    "UnauthorizedOperation",
+			Message: operation + " is not implemented by the mock",
+		}
+	}
+	return fn(ctx, params)
+}
+
+// DescribeInstances calls DescribeInstancesFunc
+func (m *MockEC2API) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return mockCall(m, "DescribeInstances", m.DescribeInstancesFunc, ctx, params)
+}
+
+// DescribeInstanceStatus calls DescribeInstanceStatusFunc
+func (m *MockEC2API) DescribeInstanceStatus(ctx context.Context, params *ec2.DescribeInstanceStatusInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceStatusOutput, error) {
+	return mockCall(m, "DescribeInstanceStatus", m.DescribeInstanceStatusFunc, ctx, params)
+}
+
+// DescribeInstanceAttribute calls DescribeInstanceAttributeFunc
+func (m *MockEC2API) DescribeInstanceAttribute(ctx context.Context, params *ec2.DescribeInstanceAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceAttributeOutput, error) {
+	return mockCall(m, "DescribeInstanceAttribute", m.DescribeInstanceAttributeFunc, ctx, params)
+}
+
+// ModifyInstanceAttribute calls ModifyInstanceAttributeFunc
+func (m *MockEC2API) ModifyInstanceAttribute(ctx context.Context, params *ec2.ModifyInstanceAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error) {
+	return mockCall(m, "ModifyInstanceAttribute", m.ModifyInstanceAttributeFunc, ctx, params)
+}
+
+// StartInstances calls StartInstancesFunc
+func (m *MockEC2API) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	return mockCall(m, "StartInstances", m.StartInstancesFunc, ctx, params)
+}
+
+// StopInstances calls StopInstancesFunc
+func (m *MockEC2API) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	return mockCall(m, "StopInstances", m.StopInstancesFunc, ctx, params)
+}
+
+// RebootInstances calls RebootInstancesFunc
+func (m *MockEC2API) RebootInstances(ctx context.Context, params *ec2.RebootInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RebootInstancesOutput, error) {
+	return mockCall(m, "RebootInstances", m.RebootInstancesFunc, ctx, params)
+}
+
+// TerminateInstances calls TerminateInstancesFunc
+func (m *MockEC2API) TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	return mockCall(m, "TerminateInstances", m.TerminateInstancesFunc, ctx, params)
+}
+
+// RunInstances calls RunInstancesFunc
+func (m *MockEC2API) RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	return mockCall(m, "RunInstances", m.RunInstancesFunc, ctx, params)
+}
+
+// GetConsoleOutput calls GetConsoleOutputFunc
+func (m *MockEC2API) GetConsoleOutput(ctx context.Context, params *ec2.GetConsoleOutputInput, optFns ...func(*ec2.Options)) (*ec2.GetConsoleOutputOutput, error) {
+	return mockCall(m, "GetConsoleOutput", m.GetConsoleOutputFunc, ctx, params)
+}
+
+// CreateTags calls CreateTagsFunc
+func (m *MockEC2API) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	return mockCall(m, "CreateTags", m.CreateTagsFunc, ctx, params)
+}
+
+// DeleteTags calls DeleteTagsFunc
+func (m *MockEC2API) DeleteTags(ctx context.Context, params *ec2.DeleteTagsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error) {
+	return mockCall(m, "DeleteTags", m.DeleteTagsFunc, ctx, params)
+}
+
+// DescribeInstanceCreditSpecifications calls DescribeInstanceCreditSpecificationsFunc
+func (m *MockEC2API) DescribeInstanceCreditSpecifications(ctx context.Context, params *ec2.DescribeInstanceCreditSpecificationsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceCreditSpecificationsOutput, error) {
+	return mockCall(m, "DescribeInstanceCreditSpecifications", m.DescribeInstanceCreditSpecificationsFunc, ctx, params)
+}
+
+// ModifyInstanceCreditSpecification calls ModifyInstanceCreditSpecificationFunc
+func (m *MockEC2API) ModifyInstanceCreditSpecification(ctx context.Context, params *ec2.ModifyInstanceCreditSpecificationInput, optFns ...func(*ec2.Options)) (*ec2.ModifyInstanceCreditSpecificationOutput, error) {
+	return mockCall(m, "ModifyInstanceCreditSpecification", m.ModifyInstanceCreditSpecificationFunc, ctx, params)
+}
+
+// MonitorInstances calls MonitorInstancesFunc
+func (m *MockEC2API) MonitorInstances(ctx context.Context, params *ec2.MonitorInstancesInput, optFns ...func(*ec2.Options)) (*ec2.MonitorInstancesOutput, error) {
+	return mockCall(m, "MonitorInstances", m.MonitorInstancesFunc, ctx, params)
+}
+
+// UnmonitorInstances calls UnmonitorInstancesFunc
+func (m *MockEC2API) UnmonitorInstances(ctx context.Context, params *ec2.UnmonitorInstancesInput, optFns ...func(*ec2.Options)) (*ec2.UnmonitorInstancesOutput, error) {
+	return mockCall(m, "UnmonitorInstances", m.UnmonitorInstancesFunc, ctx, params)
+}
+
+// ModifyInstancePlacement calls ModifyInstancePlacementFunc
+func (m *MockEC2API) ModifyInstancePlacement(ctx context.Context, params *ec2.ModifyInstancePlacementInput, optFns ...func(*ec2.Options)) (*ec2.ModifyInstancePlacementOutput, error) {
+	return mockCall(m, "ModifyInstancePlacement", m.ModifyInstancePlacementFunc, ctx, params)
+}
+
+// DescribeInstanceTypeOfferings calls DescribeInstanceTypeOfferingsFunc
+func (m *MockEC2API) DescribeInstanceTypeOfferings(ctx context.Context, params *ec2.DescribeInstanceTypeOfferingsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	return mockCall(m, "DescribeInstanceTypeOfferings", m.DescribeInstanceTypeOfferingsFunc, ctx, params)
+}
+
+// DescribeVolumes calls DescribeVolumesFunc
+func (m *MockEC2API) DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	return mockCall(m, "DescribeVolumes", m.DescribeVolumesFunc, ctx, params)
+}
+
+// CreateVolume calls CreateVolumeFunc
+func (m *MockEC2API) CreateVolume(ctx context.Context, params *ec2.CreateVolumeInput, optFns ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error) {
+	return mockCall(m, "CreateVolume", m.CreateVolumeFunc, ctx, params)
+}
+
+// AttachVolume calls AttachVolumeFunc
+func (m *MockEC2API) AttachVolume(ctx context.Context, params *ec2.AttachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.AttachVolumeOutput, error) {
+	return mockCall(m, "AttachVolume", m.AttachVolumeFunc, ctx, params)
+}
+
+// DetachVolume calls DetachVolumeFunc
+func (m *MockEC2API) DetachVolume(ctx context.Context, params *ec2.DetachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DetachVolumeOutput, error) {
+	return mockCall(m, "DetachVolume", m.DetachVolumeFunc, ctx, params)
+}
+
+// DeleteVolume calls DeleteVolumeFunc
+func (m *MockEC2API) DeleteVolume(ctx context.Context, params *ec2.DeleteVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error) {
+	return mockCall(m, "DeleteVolume", m.DeleteVolumeFunc, ctx, params)
+}
+
+// DescribeSnapshots calls DescribeSnapshotsFunc
+func (m *MockEC2API) DescribeSnapshots(ctx context.Context, params *ec2.DescribeSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error) {
+	return mockCall(m, "DescribeSnapshots", m.DescribeSnapshotsFunc, ctx, params)
+}
+
+// DescribeRegions calls DescribeRegionsFunc
+func (m *MockEC2API) DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	return mockCall(m, "DescribeRegions", m.DescribeRegionsFunc, ctx, params)
+}
+
+// DescribeVpcs calls DescribeVpcsFunc
+func (m *MockEC2API) DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	return mockCall(m, "DescribeVpcs", m.DescribeVpcsFunc, ctx, params)
+}
+
+// DescribeSubnets calls DescribeSubnetsFunc
+func (m *MockEC2API) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	return mockCall(m, "DescribeSubnets", m.DescribeSubnetsFunc, ctx, params)
+}
+
+// DescribeAddresses calls DescribeAddressesFunc
+func (m *MockEC2API) DescribeAddresses(ctx context.Context, params *ec2.DescribeAddressesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	return mockCall(m, "DescribeAddresses", m.DescribeAddressesFunc, ctx, params)
+}
+
+// DescribeRouteTables calls DescribeRouteTablesFunc
+func (m *MockEC2API) DescribeRouteTables(ctx context.Context, params *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	return mockCall(m, "DescribeRouteTables", m.DescribeRouteTablesFunc, ctx, params)
+}
+
+// DescribeNetworkAcls calls DescribeNetworkAclsFunc
+func (m *MockEC2API) DescribeNetworkAcls(ctx context.Context, params *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+	return mockCall(m, "DescribeNetworkAcls", m.DescribeNetworkAclsFunc, ctx, params)
+}
+
+// DescribeImages calls DescribeImagesFunc
+func (m *MockEC2API) DescribeImages(ctx context.Context, params *ec2.DescribeImagesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	return mockCall(m, "DescribeImages", m.DescribeImagesFunc, ctx, params)
+}
+
+// DescribeKeyPairs calls DescribeKeyPairsFunc
+func (m *MockEC2API) DescribeKeyPairs(ctx context.Context, params *ec2.DescribeKeyPairsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeKeyPairsOutput, error) {
+	return mockCall(m, "DescribeKeyPairs", m.DescribeKeyPairsFunc, ctx, params)
+}
+
+// MockEC2API implements EC2API
+var _ EC2API = (*MockEC2API)(nil)
+
+// CreateNetworkInsightsPath calls CreateNetworkInsightsPathFunc
+func (m *MockEC2API) CreateNetworkInsightsPath(ctx context.Context, params *ec2.CreateNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkInsightsPathOutput, error) {
+	return mockCall(m, "CreateNetworkInsightsPath", m.CreateNetworkInsightsPathFunc, ctx, params)
+}
+
+// StartNetworkInsightsAnalysis calls StartNetworkInsightsAnalysisFunc
+func (m *MockEC2API) StartNetworkInsightsAnalysis(ctx context.Context, params *ec2.StartNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.StartNetworkInsightsAnalysisOutput, error) {
+	return mockCall(m, "StartNetworkInsightsAnalysis", m.StartNetworkInsightsAnalysisFunc, ctx, params)
+}
+
+// DescribeNetworkInsightsAnalyses calls DescribeNetworkInsightsAnalysesFunc
+func (m *MockEC2API) DescribeNetworkInsightsAnalyses(ctx context.Context, params *ec2.DescribeNetworkInsightsAnalysesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInsightsAnalysesOutput, error) {
+	return mockCall(m, "DescribeNetworkInsightsAnalyses", m.DescribeNetworkInsightsAnalysesFunc, ctx, params)
+}
@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// cloudWatchAgentNamespace is the default namespace of the metrics published
+// by the CloudWatch agent
+const cloudWatchAgentNamespace = "CWAgent"
+
+// HasCloudWatchAgent returns true if the CloudWatch agent of an instance
+// published metrics in the last 3 hours, in the default namespace of the
+// agent
+func (c *EC2Client) HasCloudWatchAgent(ctx context.Context, instanceID string) (bool, error) {
+	c.log.Debug("Detecting CloudWatch agent", "instanceID", instanceID)
+
+	output, err := c.cloudwatch.ListMetrics(ctx, &cloudwatch.ListMetricsInput{
+		Namespace: aws.String(cloudWatchAgentNamespace),
+		Dimensions: []types.DimensionFilter{
+			{Name: aws.String("InstanceId"), Value: aws.String(instanceID)},
+		},
+		RecentlyActive: types.RecentlyActivePt3h,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list the agent metrics of instance %s: %w", instanceID, err)
+	}
+
+	return len(output.Metrics) > 0, nil
+}
+
+// SetDetailedMonitoring enables or disables the detailed monitoring of an
+// instance, publishing its metrics every minute instead of every 5 minutes.
+// It returns the new monitoring state, pending or disabling until applied.
+func (c *EC2Client) SetDetailedMonitoring(ctx context.Context, instanceID string, enabled bool) (string, error) {
+	c.log.Info("Setting detailed monitoring", "instanceID", instanceID, "enabled", enabled)
+
+	var state string
+	var err error
+	if enabled {
+		var output *ec2.MonitorInstancesOutput
+		output, err = c.client.MonitorInstances(ctx, &ec2.MonitorInstancesInput{
+			InstanceIds: []string{instanceID},
+		})
+		if err == nil && len(output.InstanceMonitorings) > 0 && output.InstanceMonitorings[0].Monitoring != nil {
+			state = string(output.InstanceMonitorings[0].Monitoring.State)
+		}
+		c.record(ctx, "MonitorInstances", instanceID, nil, err)
+	} else {
+		var output *ec2.UnmonitorInstancesOutput
+		output, err = c.client.UnmonitorInstances(ctx, &ec2.UnmonitorInstancesInput{
+			InstanceIds: []string{instanceID},
+		})
+		if err == nil && len(output.InstanceMonitorings) > 0 && output.InstanceMonitorings[0].Monitoring != nil {
+			state = string(output.InstanceMonitorings[0].Monitoring.State)
+		}
+		c.record(ctx, "UnmonitorInstances", instanceID, nil, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to set detailed monitoring of %s to %t: %w", instanceID, enabled, err)
+	}
+
+	return state, nil
+}
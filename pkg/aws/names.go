@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Kinds of the resources referenced by the instances whose names are
+// described by DescribeNames
+const (
+	ImageResource   = "image"    // AMI, named by its name
+	SubnetResource  = "subnet"   // Subnet, named by its Name tag
+	KeyPairResource = "key-pair" // Key pair, referenced by name and described by its type
+)
+
+// maxFilterValues is the maximum number of values of a filter of the EC2 API
+const maxFilterValues = 200
+
+// DescribeNames describes resources of a kind, referenced by the instances by
+// ID, and returns their names by ID: the names of the AMIs, the Name tags of
+// the subnets and the types of the key pairs. The resources not found, e.g.
+// the AMIs deregistered, are missing from the result. The resources are
+// filtered rather than requested by ID so that a single missing one does not
+// fail the whole call.
+func (c *EC2Client) DescribeNames(ctx context.Context, kind string, ids []string) (map[string]string, error) {
+	c.log.Debug("Describing resource names", "kind", kind, "count", len(ids))
+
+	names := make(map[string]string, len(ids))
+	for start := 0; start < len(ids); start += maxFilterValues {
+		chunk := ids[start:min(start+maxFilterValues, len(ids))]
+
+		switch kind {
+		case ImageResource:
+			output, err := c.client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+				Filters:           []types.Filter{{Name: aws.String("image-id"), Values: chunk}},
+				IncludeDeprecated: aws.Bool(true),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe images: %w", err)
+			}
+			for _, image := range output.Images {
+				names[aws.ToString(image.ImageId)] = aws.ToString(image.Name)
+			}
+
+		case SubnetResource:
+			paginator := ec2.NewDescribeSubnetsPaginator(c.client, &ec2.DescribeSubnetsInput{
+				Filters: []types.Filter{{Name: aws.String("subnet-id"), Values: chunk}},
+			})
+			for paginator.HasMorePages() {
+				output, err := paginator.NextPage(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("failed to describe subnets: %w", err)
+				}
+				for _, subnet := range output.Subnets {
+					names[aws.ToString(subnet.SubnetId)] = tagValue(subnet.Tags, "Name")
+				}
+			}
+
+		case KeyPairResource:
+			output, err := c.client.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+				Filters: []types.Filter{{Name: aws.String("key-name"), Values: chunk}},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe key pairs: %w", err)
+			}
+			for _, key := range output.KeyPairs {
+				names[aws.ToString(key.KeyName)] = string(key.KeyType)
+			}
+
+		default:
+			return nil, fmt.Errorf("unknown resource kind %q", kind)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// SetSourceDestCheck enables or disables the source/destination check of the
+// primary network interface of an instance, which must be disabled for the
+// instances routing traffic, e.g. NAT instances
+func (c *EC2Client) SetSourceDestCheck(ctx context.Context, instanceID string, enabled bool) error {
+	c.log.Info("Setting source/destination check", "instanceID", instanceID, "enabled", enabled)
+
+	_, err := c.client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId:      aws.String(instanceID),
+		SourceDestCheck: &types.AttributeBooleanValue{Value: aws.Bool(enabled)},
+	})
+	c.record(ctx, "ModifyInstanceAttribute", instanceID, map[string]string{"sourceDestCheck": strconv.FormatBool(enabled)}, err)
+	if err != nil {
+		return fmt.Errorf("failed to modify source/destination check of %s: %w", instanceID, err)
+	}
+
+	return nil
+}
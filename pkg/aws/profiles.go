@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// ListProfiles returns the names of the profiles defined in the shared AWS
+// config and credentials files
+func ListProfiles() []string {
+	configFile := os.Getenv(envConfigFile)
+	if configFile == "" {
+		configFile = config.DefaultSharedConfigFilename()
+	}
+	credentialsFile := os.Getenv(envSharedCredentialsFile)
+	if credentialsFile == "" {
+		credentialsFile = config.DefaultSharedCredentialsFilename()
+	}
+
+	seen := make(map[string]struct{})
+	for _, name := range readProfileSections(configFile, true) {
+		seen[name] = struct{}{}
+	}
+	for _, name := range readProfileSections(credentialsFile, false) {
+		seen[name] = struct{}{}
+	}
+
+	profiles := make([]string, 0, len(seen))
+	for name := range seen {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+
+	return profiles
+}
+
+// readProfileSections reads the profile section names of a shared file.
+// Sections of the config file are prefixed with "profile " except the
+// default one, while the credentials file uses bare profile names.
+func readProfileSections(path string, isConfig bool) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var profiles []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+
+		section := strings.TrimSpace(line[1 : len(line)-1])
+		if isConfig {
+			if name, ok := strings.CutPrefix(section, "profile "); ok {
+				section = strings.TrimSpace(name)
+			} else if section != "default" {
+				// sso-session and services sections are not profiles
+				continue
+			}
+		}
+
+		if section != "" {
+			profiles = append(profiles, section)
+		}
+	}
+
+	return profiles
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	trailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/audit"
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// maxTrailPages is the maximum number of pages of CloudTrail events looked up
+// at once, LookupEvents being limited to 2 calls per second
+const maxTrailPages = 5
+
+// protectionAttributes are the attributes of the protections, by protection
+var protectionAttributes = map[string]string{
+	model.ProtectionTermination: "disableApiTermination",
+	model.ProtectionStop:        "disableApiStop",
+}
+
+// SetProtection enables or disables the termination or the stop protection
+// of an instance
+func (c *EC2Client) SetProtection(ctx context.Context, instanceID, protection string, enabled bool) error {
+	c.log.Info("Setting protection", "instanceID", instanceID, "protection", protection, "enabled", enabled)
+
+	input := &ec2.ModifyInstanceAttributeInput{InstanceId: aws.String(instanceID)}
+	value := &types.AttributeBooleanValue{Value: aws.Bool(enabled)}
+	switch protection {
+	case model.ProtectionTermination:
+		input.DisableApiTermination = value
+	case model.ProtectionStop:
+		input.DisableApiStop = value
+	default:
+		return fmt.Errorf("unknown protection %q", protection)
+	}
+
+	_, err := c.client.ModifyInstanceAttribute(ctx, input)
+	c.record(ctx, "ModifyInstanceAttribute", instanceID, map[string]string{protectionAttributes[protection]: strconv.FormatBool(enabled)}, err)
+	if err != nil {
+		return fmt.Errorf("failed to modify %s protection of %s: %w", protection, instanceID, err)
+	}
+
+	return nil
+}
+
+// modifyAttributeEvent holds the fields of a ModifyInstanceAttribute event of
+// CloudTrail changing a protection
+type modifyAttributeEvent struct {
+	UserIdentity struct {
+		ARN string `json:"arn"`
+	} `json:"userIdentity"`
+	RequestParameters map[string]json.RawMessage `json:"requestParameters"`
+	ErrorCode         string                     `json:"errorCode"`
+}
+
+// LookupProtectionChanges looks up in CloudTrail the changes of the
+// protections of an instance in the last 90 days, the most recent first
+func (c *EC2Client) LookupProtectionChanges(ctx context.Context, instanceID string) ([]model.ProtectionChange, error) {
+	c.log.Debug("Looking up protection changes", "instanceID", instanceID)
+
+	// LookupEvents accepts a single attribute: the events of the instance
+	// are filtered by name below
+	var changes []model.ProtectionChange
+	paginator := cloudtrail.NewLookupEventsPaginator(c.cloudtrail, &cloudtrail.LookupEventsInput{
+		LookupAttributes: []trailtypes.LookupAttribute{
+			{AttributeKey: trailtypes.LookupAttributeKeyResourceName, AttributeValue: aws.String(instanceID)},
+		},
+		MaxResults: aws.Int32(50),
+	})
+	for page := 0; page < maxTrailPages && paginator.HasMorePages(); page++ {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up CloudTrail events of %s: %w", instanceID, err)
+		}
+
+		for _, event := range output.Events {
+			if aws.ToString(event.EventName) != "ModifyInstanceAttribute" {
+				continue
+			}
+			var detail modifyAttributeEvent
+			if err := json.Unmarshal([]byte(aws.ToString(event.CloudTrailEvent)), &detail); err != nil || detail.ErrorCode != "" {
+				continue
+			}
+			user := detail.UserIdentity.ARN
+			if user == "" {
+				user = aws.ToString(event.Username)
+			}
+			for _, change := range protectionParameters(detail.RequestParameters) {
+				change.Time = aws.ToTime(event.EventTime)
+				change.User = user
+				change.Source = model.SourceCloudTrail
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// protectionParameters returns the protections changed by the parameters of
+// a ModifyInstanceAttribute call, set either as {"disableApiStop": {"value":
+// true}} or as {"attribute": "disableApiStop", "value": "true"}
+func protectionParameters(params map[string]json.RawMessage) []model.ProtectionChange {
+	var changes []model.ProtectionChange
+	for protection, attribute := range protectionAttributes {
+		var enabled bool
+		if raw, ok := params[attribute]; ok {
+			var value struct {
+				Value bool `json:"value"`
+			}
+			if json.Unmarshal(raw, &value) != nil {
+				continue
+			}
+			enabled = value.Value
+		} else {
+			var name, value string
+			_ = json.Unmarshal(params["attribute"], &name)
+			_ = json.Unmarshal(params["value"], &value)
+			if name != attribute {
+				continue
+			}
+			enabled, _ = strconv.ParseBool(value)
+		}
+		changes = append(changes, model.ProtectionChange{Protection: protection, Enabled: enabled})
+	}
+	return changes
+}
+
+// AuditProtectionChanges returns the changes of the protections of an
+// instance made with e2c, from the entries of an audit log, the most recent
+// first
+func AuditProtectionChanges(entries []audit.Entry, instanceID string) []model.ProtectionChange {
+	var changes []model.ProtectionChange
+	for _, entry := range entries {
+		if entry.Instance != instanceID || entry.Action != "ModifyInstanceAttribute" || entry.Result != audit.ResultSuccess {
+			continue
+		}
+		for protection, attribute := range protectionAttributes {
+			value, ok := entry.Params[attribute]
+			if !ok {
+				continue
+			}
+			enabled, _ := strconv.ParseBool(value)
+			changes = append(changes, model.ProtectionChange{
+				Time:       entry.Time,
+				User:       entry.User,
+				Protection: protection,
+				Enabled:    enabled,
+				Source:     model.SourceAudit,
+			})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Time.After(changes[j].Time)
+	})
+	return changes
+}
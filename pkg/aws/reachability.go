@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// reachabilityInterval is the delay between two checks of a running
+// Reachability Analyzer analysis, which usually takes a minute or so
+const reachabilityInterval = 3 * time.Second
+
+// AnalyzeReachability creates a VPC Reachability Analyzer path from an
+// instance to a destination, an IP address or the ID of a resource such as an
+// instance or an internet gateway, on a TCP or UDP port, runs an analysis of
+// the path and waits for its result. The path is kept, tagged with its
+// purpose, to run it again from the AWS console.
+func (c *EC2Client) AnalyzeReachability(ctx context.Context, instanceID, destination string, port int32, protocol string) (*model.Reachability, error) {
+	c.log.Info("Analyzing reachability", "instanceID", instanceID, "destination", destination, "port", port, "protocol", protocol)
+
+	name := fmt.Sprintf("e2c %s to %s:%d/%s", instanceID, destination, port, protocol)
+	input := &ec2.CreateNetworkInsightsPathInput{
+		ClientToken: aws.String(fmt.Sprintf("e2c-%s-%d", instanceID, time.Now().UnixNano())),
+		Protocol:    types.Protocol(strings.ToLower(protocol)),
+		Source:      aws.String(instanceID),
+		TagSpecifications: []types.TagSpecification{{
+			ResourceType: types.ResourceTypeNetworkInsightsPath,
+			Tags:         []types.Tag{{Key: aws.String("Name"), Value: aws.String(name)}},
+		}},
+	}
+	if net.ParseIP(destination) != nil {
+		// A destination outside of the account, e.g. on premises
+		input.FilterAtSource = &types.PathRequestFilter{
+			DestinationAddress:   aws.String(destination),
+			DestinationPortRange: &types.RequestFilterPortRange{FromPort: aws.Int32(port), ToPort: aws.Int32(port)},
+		}
+	} else {
+		input.Destination = aws.String(destination)
+		input.DestinationPort = aws.Int32(port)
+	}
+
+	path, err := c.client.CreateNetworkInsightsPath(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the path from %s to %s: %w", instanceID, destination, err)
+	}
+	pathID := aws.ToString(path.NetworkInsightsPath.NetworkInsightsPathId)
+
+	started, err := c.client.StartNetworkInsightsAnalysis(ctx, &ec2.StartNetworkInsightsAnalysisInput{
+		NetworkInsightsPathId: aws.String(pathID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the analysis of path %s: %w", pathID, err)
+	}
+	analysisID := aws.ToString(started.NetworkInsightsAnalysis.NetworkInsightsAnalysisId)
+
+	for {
+		output, err := c.client.DescribeNetworkInsightsAnalyses(ctx, &ec2.DescribeNetworkInsightsAnalysesInput{
+			NetworkInsightsAnalysisIds: []string{analysisID},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe analysis %s: %w", analysisID, err)
+		}
+		if len(output.NetworkInsightsAnalyses) == 0 {
+			return nil, fmt.Errorf("analysis %s not found", analysisID)
+		}
+
+		analysis := output.NetworkInsightsAnalyses[0]
+		if analysis.Status != types.AnalysisStatusRunning {
+			result := convertReachability(pathID, analysis)
+			c.log.Info("Analyzed reachability", "instanceID", instanceID, "destination", destination,
+				"status", result.Status, "reachable", result.Reachable)
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("analysis %s interrupted: %w", analysisID, ctx.Err())
+		case <-time.After(reachabilityInterval):
+		}
+	}
+}
+
+// convertReachability converts a Reachability Analyzer analysis
+func convertReachability(pathID string, analysis types.NetworkInsightsAnalysis) *model.Reachability {
+	result := &model.Reachability{
+		PathID:       pathID,
+		AnalysisID:   aws.ToString(analysis.NetworkInsightsAnalysisId),
+		Status:       string(analysis.Status),
+		StatusDetail: aws.ToString(analysis.StatusMessage),
+		Reachable:    aws.ToBool(analysis.NetworkPathFound),
+	}
+
+	for _, explanation := range analysis.Explanations {
+		result.Explanations = append(result.Explanations, model.ReachabilityExplanation{
+			Code:      aws.ToString(explanation.ExplanationCode),
+			Component: explanationComponent(explanation),
+			Detail:    explanationDetail(explanation),
+		})
+	}
+	return result
+}
+
+// explanationComponent returns the kind and the ID of the component blocking
+// the path, the most specific first: a security group or a network ACL
+// rather than the network interface they are attached to
+func explanationComponent(explanation types.Explanation) string {
+	components := []struct {
+		kind      string
+		component *types.AnalysisComponent
+	}{
+		{"security group", explanation.SecurityGroup},
+		{"network ACL", explanation.Acl},
+		{"route table", explanation.RouteTable},
+		{"route table", explanation.SubnetRouteTable},
+		{"internet gateway", explanation.InternetGateway},
+		{"NAT gateway", explanation.NatGateway},
+		{"VPC endpoint", explanation.VpcEndpoint},
+		{"VPC peering", explanation.VpcPeeringConnection},
+		{"transit gateway", explanation.TransitGateway},
+		{"network interface", explanation.NetworkInterface},
+		{"subnet", explanation.Subnet},
+		{"VPC", explanation.Vpc},
+		{"component", explanation.Component},
+	}
+	for _, c := range components {
+		if c.component != nil {
+			return c.kind + " " + aws.ToString(c.component.Id)
+		}
+	}
+	if len(explanation.SecurityGroups) > 0 {
+		ids := make([]string, 0, len(explanation.SecurityGroups))
+		for _, group := range explanation.SecurityGroups {
+			ids = append(ids, aws.ToString(group.Id))
+		}
+		return "security groups " + strings.Join(ids, ", ")
+	}
+	return aws.ToString(explanation.MissingComponent)
+}
+
+// explanationDetail returns the direction, the ports and the addresses
+// involved in an explanation, if any
+func explanationDetail(explanation types.Explanation) string {
+	var details []string
+	if direction := aws.ToString(explanation.Direction); direction != "" {
+		details = append(details, direction)
+	}
+	if len(explanation.Protocols) > 0 {
+		details = append(details, "protocols "+strings.Join(explanation.Protocols, ", "))
+	}
+	for _, portRange := range explanation.PortRanges {
+		details = append(details, fmt.Sprintf("ports %d-%d", aws.ToInt32(portRange.From), aws.ToInt32(portRange.To)))
+	}
+	if len(explanation.Cidrs) > 0 {
+		details = append(details, "CIDRs "+strings.Join(explanation.Cidrs, ", "))
+	}
+	if missing := aws.ToString(explanation.MissingComponent); missing != "" {
+		details = append(details, "missing "+missing)
+	}
+	return strings.Join(details, ", ")
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// probeTimeout bounds each request of a latency probe
+const probeTimeout = 5 * time.Second
+
+// RegionLatency is the latency of the EC2 endpoint of a region
+type RegionLatency struct {
+	Region    string
+	Connect   time.Duration // First request, DNS resolution and TLS handshake included
+	RoundTrip time.Duration // Second request, on the connection already open
+	Err       error
+}
+
+// ListRegions retrieves the regions enabled for the account, sorted by name
+func (c *EC2Client) ListRegions(ctx context.Context) ([]string, error) {
+	c.log.Info("Listing regions")
+
+	output, err := c.client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe regions: %w", err)
+	}
+
+	regions := make([]string, 0, len(output.Regions))
+	for _, region := range output.Regions {
+		regions = append(regions, aws.ToString(region.RegionName))
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// ProbeRegion measures the latency of the EC2 endpoint of a region with two
+// unsigned requests, through the proxy of the SDK if any. The endpoint
+// answers them with an error, which is enough to time the round trip.
+func (c *EC2Client) ProbeRegion(ctx context.Context, region string) RegionLatency {
+	result := RegionLatency{Region: region}
+	endpoint := fmt.Sprintf("https://ec2.%s.amazonaws.com/", region)
+	if c.cfg.BaseEndpoint != nil {
+		endpoint = *c.cfg.BaseEndpoint
+	}
+	client := c.cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	probe := func() (time.Duration, error) {
+		ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+
+		// Read the response so that the connection is reused
+		if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+			return 0, err
+		}
+		return time.Since(start), nil
+	}
+
+	if result.Connect, result.Err = probe(); result.Err != nil {
+		return result
+	}
+	result.RoundTrip, result.Err = probe()
+	return result
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// aclProtocols are the names of the protocol numbers of the network ACL
+// rules
+var aclProtocols = map[string]string{
+	"-1": "all",
+	"1":  "icmp",
+	"6":  "tcp",
+	"17": "udp",
+	"58": "icmpv6",
+}
+
+// GetSubnetRouting retrieves the route table and the network ACL of a
+// subnet. Without a route table of its own, the subnet uses the main route
+// table of its VPC.
+func (c *EC2Client) GetSubnetRouting(ctx context.Context, subnetID, vpcID string) (*model.SubnetRouting, error) {
+	c.log.Info("Getting subnet routing", "subnetID", subnetID)
+
+	routing := &model.SubnetRouting{}
+
+	tables, err := c.client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{{Name: aws.String("association.subnet-id"), Values: []string{subnetID}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe route table of subnet %s: %w", subnetID, err)
+	}
+	if len(tables.RouteTables) == 0 {
+		tables, err = c.client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+			Filters: []types.Filter{
+				{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+				{Name: aws.String("association.main"), Values: []string{"true"}},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe main route table of VPC %s: %w", vpcID, err)
+		}
+		routing.MainRouteTable = true
+	}
+	if len(tables.RouteTables) > 0 {
+		table := tables.RouteTables[0]
+		routing.RouteTableID = aws.ToString(table.RouteTableId)
+		routing.RouteTableName = tagValue(table.Tags, "Name")
+		for _, route := range table.Routes {
+			routing.Routes = append(routing.Routes, convertRoute(route))
+		}
+	}
+
+	acls, err := c.client.DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{
+		Filters: []types.Filter{{Name: aws.String("association.subnet-id"), Values: []string{subnetID}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe network ACL of subnet %s: %w", subnetID, err)
+	}
+	if len(acls.NetworkAcls) > 0 {
+		acl := acls.NetworkAcls[0]
+		routing.NetworkACLID = aws.ToString(acl.NetworkAclId)
+		routing.NetworkACLName = tagValue(acl.Tags, "Name")
+		for _, entry := range acl.Entries {
+			routing.ACLEntries = append(routing.ACLEntries, convertACLEntry(entry))
+		}
+		sort.SliceStable(routing.ACLEntries, func(i, j int) bool {
+			a, b := routing.ACLEntries[i], routing.ACLEntries[j]
+			if a.Egress != b.Egress {
+				return !a.Egress
+			}
+			return a.RuleNumber < b.RuleNumber
+		})
+	}
+
+	return routing, nil
+}
+
+// convertRoute converts a route of a route table
+func convertRoute(route types.Route) model.Route {
+	destination := aws.ToString(route.DestinationCidrBlock)
+	for _, other := range []*string{route.DestinationIpv6CidrBlock, route.DestinationPrefixListId} {
+		if destination == "" {
+			destination = aws.ToString(other)
+		}
+	}
+
+	target := ""
+	for _, id := range []*string{
+		route.GatewayId, route.NatGatewayId, route.TransitGatewayId, route.VpcPeeringConnectionId,
+		route.EgressOnlyInternetGatewayId, route.InstanceId, route.NetworkInterfaceId,
+		route.LocalGatewayId, route.CarrierGatewayId, route.CoreNetworkArn,
+	} {
+		if target == "" {
+			target = aws.ToString(id)
+		}
+	}
+
+	return model.Route{
+		Destination: destination,
+		Target:      target,
+		State:       string(route.State),
+	}
+}
+
+// convertACLEntry converts a rule of a network ACL
+func convertACLEntry(entry types.NetworkAclEntry) model.ACLEntry {
+	protocol := aws.ToString(entry.Protocol)
+	if name, ok := aclProtocols[protocol]; ok {
+		protocol = name
+	}
+
+	ports := ""
+	if entry.PortRange != nil {
+		from, to := aws.ToInt32(entry.PortRange.From), aws.ToInt32(entry.PortRange.To)
+		ports = strconv.Itoa(int(from))
+		if to != from {
+			ports += "-" + strconv.Itoa(int(to))
+		}
+	}
+
+	cidr := aws.ToString(entry.CidrBlock)
+	if cidr == "" {
+		cidr = aws.ToString(entry.Ipv6CidrBlock)
+	}
+
+	return model.ACLEntry{
+		RuleNumber: int(aws.ToInt32(entry.RuleNumber)),
+		Egress:     aws.ToBool(entry.Egress),
+		Protocol:   protocol,
+		Ports:      ports,
+		CIDR:       cidr,
+		Action:     string(entry.RuleAction),
+	}
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// GetObject retrieves the content of an S3 object, e.g. a Terraform state
+// stored in an S3 backend, from a bucket of the region of the client
+func (c *EC2Client) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	c.log.Debug("Getting S3 object", "bucket", bucket, "key", key)
+
+	output, err := c.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+	}
+	return data, nil
+}
+
+// ListObjectKeys returns the keys of the objects of an S3 bucket starting with
+// a prefix
+func (c *EC2Client) ListObjectKeys(ctx context.Context, bucket, prefix string) ([]string, error) {
+	c.log.Debug("Listing S3 objects", "bucket", bucket, "prefix", prefix)
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(c.s3, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		for _, object := range output.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+)
+
+// stopInstancesTarget is the universal target of EventBridge Scheduler
+// calling the EC2 StopInstances action
+const stopInstancesTarget = "arn:aws:scheduler:::aws-sdk:ec2:stopInstances"
+
+// StopSchedule is an EventBridge Scheduler schedule stopping instances
+type StopSchedule struct {
+	Name string
+	// Expression is the cron expression of the schedule, e.g.
+	// cron(0 19 ? * MON,FRI *)
+	Expression string
+	// Timezone is the IANA time zone of the expression
+	Timezone string
+	// RoleARN is the role assumed by EventBridge Scheduler to stop the
+	// instances
+	RoleARN     string
+	InstanceIDs []string
+	Description string
+}
+
+// PutStopSchedule creates the EventBridge Scheduler schedule stopping the
+// instances, or updates it if it exists. The instances are the ones given:
+// the schedule must be put again when they change.
+func (c *EC2Client) PutStopSchedule(ctx context.Context, schedule StopSchedule) error {
+	c.log.Info("Putting stop schedule", "name", schedule.Name, "expression", schedule.Expression, "instances", len(schedule.InstanceIDs))
+
+	input, err := json.Marshal(map[string][]string{"InstanceIds": schedule.InstanceIDs})
+	if err != nil {
+		return err
+	}
+	target := &types.Target{
+		Arn:     aws.String(stopInstancesTarget),
+		RoleArn: aws.String(schedule.RoleARN),
+		Input:   aws.String(string(input)),
+	}
+	window := &types.FlexibleTimeWindow{Mode: types.FlexibleTimeWindowModeOff}
+
+	action := "CreateSchedule"
+	_, err = c.scheduler.CreateSchedule(ctx, &scheduler.CreateScheduleInput{
+		Name:                       aws.String(schedule.Name),
+		ScheduleExpression:         aws.String(schedule.Expression),
+		ScheduleExpressionTimezone: optionalString(schedule.Timezone),
+		FlexibleTimeWindow:         window,
+		Target:                     target,
+		Description:                optionalString(schedule.Description),
+		State:                      types.ScheduleStateEnabled,
+	})
+	var conflict *types.ConflictException
+	if errors.As(err, &conflict) {
+		action = "UpdateSchedule"
+		_, err = c.scheduler.UpdateSchedule(ctx, &scheduler.UpdateScheduleInput{
+			Name:                       aws.String(schedule.Name),
+			ScheduleExpression:         aws.String(schedule.Expression),
+			ScheduleExpressionTimezone: optionalString(schedule.Timezone),
+			FlexibleTimeWindow:         window,
+			Target:                     target,
+			Description:                optionalString(schedule.Description),
+			State:                      types.ScheduleStateEnabled,
+		})
+	}
+	params := map[string]string{
+		"name":       schedule.Name,
+		"expression": schedule.Expression,
+		"timezone":   schedule.Timezone,
+	}
+	for _, id := range schedule.InstanceIDs {
+		c.record(ctx, action, id, params, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put schedule %s: %w", schedule.Name, err)
+	}
+
+	return nil
+}
+
+// optionalString returns a pointer to a string, nil if it is empty
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
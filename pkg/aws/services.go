@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/health"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// services are the clients of the AWS services other than EC2
+type services struct {
+	logs           *cloudwatchlogs.Client
+	cloudwatch     *cloudwatch.Client
+	cloudformation *cloudformation.Client
+	health         *health.Client
+	ssm            *ssm.Client
+	s3             *s3.Client
+	scheduler      *scheduler.Client
+	cloudtrail     *cloudtrail.Client
+}
+
+// newServices creates the clients of the other AWS services from the
+// configuration of the EC2 client, so that their calls are traced, retried,
+// bounded and sent to the custom endpoint the same way
+func newServices(cfg aws.Config) services {
+	return services{
+		logs:           cloudwatchlogs.NewFromConfig(cfg),
+		cloudwatch:     cloudwatch.NewFromConfig(cfg),
+		cloudformation: cloudformation.NewFromConfig(cfg),
+		health: health.NewFromConfig(cfg, func(o *health.Options) {
+			o.Region = healthRegion
+		}),
+		ssm: ssm.NewFromConfig(cfg),
+		s3: s3.NewFromConfig(cfg, func(o *s3.Options) {
+			// The buckets of an AWS compatible endpoint, such as
+			// LocalStack, are addressed by path
+			o.UsePathStyle = cfg.BaseEndpoint != nil
+		}),
+		scheduler:  scheduler.NewFromConfig(cfg),
+		cloudtrail: cloudtrail.NewFromConfig(cfg),
+	}
+}
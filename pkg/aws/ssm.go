@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/smithy-go"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+const (
+	// commandTimeout is how long a command may run on an instance before
+	// SSM stops it
+	commandTimeout = 10 * time.Minute
+
+	// commandInterval is the delay between two checks of a running command
+	commandInterval = 2 * time.Second
+)
+
+// SendCommand runs a shell command on instances with SSM Run Command, with
+// PowerShell on Windows instances, and returns the ID of the command. The
+// instances must run the SSM agent with an instance profile allowing it.
+func (c *EC2Client) SendCommand(ctx context.Context, instanceIDs []string, command string, windows bool) (string, error) {
+	c.log.Info("Sending command", "instances", len(instanceIDs), "windows", windows)
+
+	document := "AWS-RunShellScript"
+	if windows {
+		document = "AWS-RunPowerShellScript"
+	}
+
+	output, err := c.ssm.SendCommand(ctx, &ssm.SendCommandInput{
+		DocumentName: aws.String(document),
+		InstanceIds:  instanceIDs,
+		Parameters: map[string][]string{
+			"commands":         {command},
+			"executionTimeout": {strconv.Itoa(int(commandTimeout.Seconds()))},
+		},
+		Comment: aws.String("Sent by e2c"),
+	})
+	for _, id := range instanceIDs {
+		c.record(ctx, "SendCommand", id, map[string]string{"command": command, "document": document}, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to send command: %w", err)
+	}
+	if output.Command == nil {
+		return "", nil
+	}
+
+	return aws.ToString(output.Command.CommandId), nil
+}
+
+// GetCommandInvocation retrieves the status and the output of a command on
+// an instance. SSM returns the first 24000 characters of the output only.
+func (c *EC2Client) GetCommandInvocation(ctx context.Context, commandID, instanceID string) (*model.CommandInvocation, error) {
+	c.log.Debug("Getting command invocation", "commandID", commandID, "instanceID", instanceID)
+
+	output, err := c.ssm.GetCommandInvocation(ctx, &ssm.GetCommandInvocationInput{
+		CommandId:  aws.String(commandID),
+		InstanceId: aws.String(instanceID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get command invocation on %s: %w", instanceID, err)
+	}
+
+	return &model.CommandInvocation{
+		CommandID:    commandID,
+		InstanceID:   instanceID,
+		Status:       string(output.Status),
+		StatusDetail: aws.ToString(output.StatusDetails),
+		ExitCode:     int(output.ResponseCode),
+		Output:       aws.ToString(output.StandardOutputContent),
+		Error:        aws.ToString(output.StandardErrorContent),
+	}, nil
+}
+
+// WaitCommandInvocation checks a command on an instance until it is done,
+// calling onUpdate each time its status or its output changes, and returns
+// its final state
+func (c *EC2Client) WaitCommandInvocation(ctx context.Context, commandID, instanceID string, onUpdate func(model.CommandInvocation)) (*model.CommandInvocation, error) {
+	var last model.CommandInvocation
+	for {
+		invocation, err := c.GetCommandInvocation(ctx, commandID, instanceID)
+		var apiErr smithy.APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvocationDoesNotExist":
+			// Not delivered to the instance yet
+			invocation = &model.CommandInvocation{CommandID: commandID, InstanceID: instanceID, Status: model.CommandPending, ExitCode: -1}
+		case err != nil:
+			return nil, err
+		}
+
+		if *invocation != last {
+			last = *invocation
+			if onUpdate != nil {
+				onUpdate(last)
+			}
+		}
+		if invocation.Done() {
+			return invocation, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("command %s interrupted: %w", commandID, ctx.Err())
+		case <-time.After(commandInterval):
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+const (
+	// ssoScope is the scope of the tokens of the sso-session profiles
+	ssoScope = "sso:account:access"
+	// deviceGrantType is the grant type of the device authorization flow
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// SSOConfig is the IAM Identity Center configuration of a profile
+type SSOConfig struct {
+	Session  string // Name of the sso-session, empty for the legacy profiles
+	StartURL string
+	Region   string
+}
+
+// cacheKey returns the key of the cached token of the configuration: the
+// name of the session, or the start URL for the legacy profiles
+func (s *SSOConfig) cacheKey() string {
+	if s.Session != "" {
+		return s.Session
+	}
+	return s.StartURL
+}
+
+// SSOConfig returns the IAM Identity Center configuration of the profile of
+// the client, or of its source profile when a role is assumed, nil if it does
+// not use IAM Identity Center
+func (c *EC2Client) SSOConfig(ctx context.Context) *SSOConfig {
+	profile := c.profile
+	if profile == "" {
+		profile = os.Getenv(envProfile)
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	shared, err := config.LoadSharedConfigProfile(ctx, profile)
+	if err != nil {
+		c.log.Debug("Failed to load the shared profile", "profile", profile, "error", err)
+		return nil
+	}
+	for sc := &shared; sc != nil; sc = sc.Source {
+		switch {
+		case sc.SSOSession != nil:
+			return &SSOConfig{
+				Session:  sc.SSOSession.Name,
+				StartURL: sc.SSOSession.SSOStartURL,
+				Region:   sc.SSOSession.SSORegion,
+			}
+		case sc.SSOStartURL != "":
+			return &SSOConfig{
+				StartURL: sc.SSOStartURL,
+				Region:   sc.SSORegion,
+			}
+		}
+	}
+	return nil
+}
+
+// SSOLogin is a running device authorization of IAM Identity Center: the
+// user opens the verification URL, checks the code and approves the access,
+// while e2c waits for the token
+type SSOLogin struct {
+	VerificationURL string // URL to open, with the code
+	UserCode        string // Code displayed on the verification page
+	ExpiresAt       time.Time
+
+	config        SSOConfig
+	client        *ssooidc.Client
+	clientID      string
+	clientSecret  string
+	clientExpires time.Time
+	deviceCode    string
+	interval      time.Duration
+}
+
+// StartSSOLogin registers e2c as a client of IAM Identity Center and starts
+// the device authorization, like aws sso login
+func (c *EC2Client) StartSSOLogin(ctx context.Context, sso *SSOConfig) (*SSOLogin, error) {
+	c.log.Info("Starting IAM Identity Center login", "startURL", sso.StartURL, "session", sso.Session)
+
+	client := ssooidc.NewFromConfig(c.cfg, func(o *ssooidc.Options) {
+		o.Region = sso.Region
+	})
+
+	register := &ssooidc.RegisterClientInput{
+		ClientName: aws.String("e2c"),
+		ClientType: aws.String("public"),
+	}
+	if sso.Session != "" {
+		register.Scopes = []string{ssoScope}
+	}
+	registered, err := client.RegisterClient(ctx, register)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register the SSO client: %w", err)
+	}
+
+	authorization, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     registered.ClientId,
+		ClientSecret: registered.ClientSecret,
+		StartUrl:     aws.String(sso.StartURL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the SSO device authorization: %w", err)
+	}
+
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &SSOLogin{
+		VerificationURL: aws.ToString(authorization.VerificationUriComplete),
+		UserCode:        aws.ToString(authorization.UserCode),
+		ExpiresAt:       time.Now().Add(time.Duration(authorization.ExpiresIn) * time.Second),
+		config:          *sso,
+		client:          client,
+		clientID:        aws.ToString(registered.ClientId),
+		clientSecret:    aws.ToString(registered.ClientSecret),
+		clientExpires:   time.Unix(registered.ClientSecretExpiresAt, 0),
+		deviceCode:      aws.ToString(authorization.DeviceCode),
+		interval:        interval,
+	}, nil
+}
+
+// Wait waits until the user approves the access, then stores the token in
+// the cache of the AWS SDK and CLI, ~/.aws/sso/cache, where the credentials
+// provider of the profile reads it
+func (l *SSOLogin) Wait(ctx context.Context) error {
+	interval := l.interval
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		token, err := l.client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     aws.String(l.clientID),
+			ClientSecret: aws.String(l.clientSecret),
+			GrantType:    aws.String(deviceGrantType),
+			DeviceCode:   aws.String(l.deviceCode),
+		})
+
+		var pending *types.AuthorizationPendingException
+		var slowDown *types.SlowDownException
+		switch {
+		case errors.As(err, &pending):
+			continue
+		case errors.As(err, &slowDown):
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return fmt.Errorf("failed to get the SSO token: %w", err)
+		}
+
+		return l.storeToken(token)
+	}
+}
+
+// ssoCachedToken is a token in the format of the SSO cache of the AWS SDK
+// and CLI
+type ssoCachedToken struct {
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	Region                string `json:"region,omitempty"`
+	StartURL              string `json:"startUrl,omitempty"`
+}
+
+// storeToken writes a token to the SSO cache
+func (l *SSOLogin) storeToken(token *ssooidc.CreateTokenOutput) error {
+	path, err := ssocreds.StandardCachedTokenFilepath(l.config.cacheKey())
+	if err != nil {
+		return err
+	}
+
+	cached := ssoCachedToken{
+		AccessToken: aws.ToString(token.AccessToken),
+		ExpiresAt:   time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).UTC().Format(time.RFC3339),
+		Region:      l.config.Region,
+		StartURL:    l.config.StartURL,
+	}
+	// The sso-session profiles refresh the token with the client
+	if l.config.Session != "" {
+		cached.RefreshToken = aws.ToString(token.RefreshToken)
+		cached.ClientID = l.clientID
+		cached.ClientSecret = l.clientSecret
+		cached.RegistrationExpiresAt = l.clientExpires.UTC().Format(time.RFC3339)
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("failed to encode the SSO token: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create the SSO cache: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to store the SSO token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Identity represents the AWS identity used by the client
+type Identity struct {
+	Account string // AWS account ID
+	ARN     string // ARN of the caller
+	UserID  string // Unique identifier of the caller
+}
+
+// GetCallerIdentity returns the identity of the caller from STS
+func (c *EC2Client) GetCallerIdentity(ctx context.Context) (*Identity, error) {
+	c.log.Info("Getting caller identity")
+
+	client := sts.NewFromConfig(c.cfg)
+	output, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	return &Identity{
+		Account: aws.ToString(output.Account),
+		ARN:     aws.ToString(output.Arn),
+		UserID:  aws.ToString(output.UserId),
+	}, nil
+}
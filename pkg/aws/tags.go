@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// ListRelatedResources retrieves the IDs of the resources attached to an
+// instance which are billed separately, and whose tags should match those of
+// the instance for the cost reports: its EBS volumes, its network interfaces
+// and the allocations of its Elastic IPs
+func (c *EC2Client) ListRelatedResources(ctx context.Context, instance model.Instance) ([]string, error) {
+	c.log.Info("Listing related resources", "instanceID", instance.ID)
+
+	var resources []string
+	for _, device := range instance.BlockDevices {
+		if device.VolumeID != "" {
+			resources = append(resources, device.VolumeID)
+		}
+	}
+	for _, eni := range instance.NetworkInterfaces {
+		resources = append(resources, eni.ID)
+	}
+
+	output, err := c.client.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("instance-id"), Values: []string{instance.ID}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the Elastic IPs of %s: %w", instance.ID, err)
+	}
+	for _, address := range output.Addresses {
+		if address.AllocationId != nil {
+			resources = append(resources, aws.ToString(address.AllocationId))
+		}
+	}
+
+	return resources, nil
+}
+
+// SetTags creates or overwrites tags on an instance, and on related
+// resources, e.g. from ListRelatedResources, in a single call
+func (c *EC2Client) SetTags(ctx context.Context, instanceID string, resources []string, tags map[string]string) error {
+	c.log.Info("Setting tags", "instanceID", instanceID, "tags", tags, "resources", resources)
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	input := &ec2.CreateTagsInput{
+		Resources: append([]string{instanceID}, resources...),
+	}
+	params := make(map[string]string, len(tags)+1)
+	for _, key := range keys {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+		params["tag:"+key] = tags[key]
+	}
+	if len(resources) > 0 {
+		params["resources"] = strings.Join(resources, ",")
+	}
+
+	_, err := c.client.CreateTags(ctx, input)
+	c.record(ctx, "CreateTags", instanceID, params, err)
+	if err != nil {
+		return fmt.Errorf("failed to tag %s: %w", instanceID, err)
+	}
+
+	return nil
+}
+
+// DeleteTags deletes tags from an instance
+func (c *EC2Client) DeleteTags(ctx context.Context, instanceID string, keys []string) error {
+	c.log.Info("Deleting tags", "instanceID", instanceID, "keys", keys)
+
+	input := &ec2.DeleteTagsInput{
+		Resources: []string{instanceID},
+	}
+	for _, key := range keys {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key)})
+	}
+
+	_, err := c.client.DeleteTags(ctx, input)
+	c.record(ctx, "DeleteTags", instanceID, map[string]string{"keys": strings.Join(keys, ",")}, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete the tags of %s: %w", instanceID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Metrics records the latency and the errors of the calls to the AWS APIs,
+// retries included, e.g. to export them to an OpenTelemetry collector
+type Metrics interface {
+	RecordCall(service, operation, region string, d time.Duration, err error)
+}
+
+// Tracer traces the calls to the AWS APIs made with the context of a traced
+// operation
+type Tracer interface {
+	// StartCall starts the span of a call made with ctx, named e.g.
+	// EC2.StopInstances, and returns the function ending it with the error
+	// of the call, nil if ctx is not traced
+	StartCall(ctx context.Context, name string, attributes map[string]string) func(err error)
+}
+
+// Telemetry records the calls of a client, the zero value recording nothing
+type Telemetry struct {
+	Metrics Metrics // Latency and errors of the calls, nil if not recorded
+	Tracer  Tracer  // Spans of the calls, nil if not traced
+}
+
+// traceMiddleware returns the middleware recording the calls to the AWS APIs
+// made with the context of a user action, or of an operation traced in the
+// background, as client spans of its trace, retries included, and their
+// latency and errors in the metrics, if recorded
+func traceMiddleware(m Metrics, t Tracer) middleware.InitializeMiddleware {
+	return middleware.InitializeMiddlewareFunc("E2CTrace", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		service := awsmiddleware.GetServiceID(ctx)
+		operation := awsmiddleware.GetOperationName(ctx)
+		region := awsmiddleware.GetRegion(ctx)
+		var end func(error)
+		if t != nil {
+			attributes := map[string]string{
+				"rpc.system":   "aws-api",
+				"rpc.service":  service,
+				"rpc.method":   operation,
+				"cloud.region": region,
+			}
+			if ids := instanceIDs(in.Parameters); len(ids) > 0 {
+				attributes["aws.ec2.instance_ids"] = strings.Join(ids, ",")
+			}
+			end = t.StartCall(ctx, service+"."+operation, attributes)
+		}
+		start := time.Now()
+		out, metadata, err := next.HandleInitialize(ctx, in)
+		if end != nil {
+			end(err)
+		}
+		if m != nil {
+			m.RecordCall(service, operation, region, time.Since(start), err)
+		}
+		return out, metadata, err
+	})
+}
+
+// instanceIDs returns the IDs of the instances of the input of a call, from
+// its InstanceIds or InstanceId field, e.g. StopInstancesInput, nil if none
+func instanceIDs(params any) []string {
+	v := reflect.Indirect(reflect.ValueOf(params))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if field := v.FieldByName("InstanceIds"); field.IsValid() {
+		if ids, ok := field.Interface().([]string); ok {
+			return ids
+		}
+	}
+	if field := v.FieldByName("InstanceId"); field.IsValid() {
+		if id, ok := field.Interface().(*string); ok && id != nil {
+			return []string{*id}
+		}
+	}
+	return nil
+}
+
+// withTracing adds the tracing middleware to the clients created from an
+// AWS config, if the calls are recorded
+func withTracing(cfg *aws.Config, telemetry Telemetry) {
+	if telemetry.Metrics == nil && telemetry.Tracer == nil {
+		return
+	}
+	traced := traceMiddleware(telemetry.Metrics, telemetry.Tracer)
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		// After the registration of the service metadata
+		return stack.Initialize.Add(traced, middleware.After)
+	})
+}
@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// GetVolume retrieves an EBS volume
+func (c *EC2Client) GetVolume(ctx context.Context, volumeID string) (*model.Volume, error) {
+	c.log.Info("Getting EBS volume", "volumeID", volumeID)
+
+	output, err := c.client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+		VolumeIds: []string{volumeID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe volume %s: %w", volumeID, err)
+	}
+	if len(output.Volumes) == 0 {
+		return nil, fmt.Errorf("volume %s not found", volumeID)
+	}
+
+	volume := output.Volumes[0]
+	return &model.Volume{
+		ID:               aws.ToString(volume.VolumeId),
+		AvailabilityZone: aws.ToString(volume.AvailabilityZone),
+		Type:             string(volume.VolumeType),
+		Size:             int(aws.ToInt32(volume.Size)),
+		IOPS:             int(aws.ToInt32(volume.Iops)),
+		Throughput:       int(aws.ToInt32(volume.Throughput)),
+		State:            string(volume.State),
+	}, nil
+}
+
+// ListSnapshots retrieves the completed snapshots of an EBS volume owned by
+// the account, the most recent first
+func (c *EC2Client) ListSnapshots(ctx context.Context, volumeID string) ([]model.Snapshot, error) {
+	c.log.Info("Listing snapshots", "volumeID", volumeID)
+
+	snapshots := make([]model.Snapshot, 0)
+	paginator := ec2.NewDescribeSnapshotsPaginator(c.client, &ec2.DescribeSnapshotsInput{
+		OwnerIds: []string{"self"},
+		Filters: []types.Filter{
+			{Name: aws.String("volume-id"), Values: []string{volumeID}},
+			{Name: aws.String("status"), Values: []string{string(types.SnapshotStateCompleted)}},
+		},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe snapshots of volume %s: %w", volumeID, err)
+		}
+		for _, snapshot := range output.Snapshots {
+			snapshots = append(snapshots, model.Snapshot{
+				ID:          aws.ToString(snapshot.SnapshotId),
+				VolumeID:    aws.ToString(snapshot.VolumeId),
+				Description: aws.ToString(snapshot.Description),
+				StartTime:   aws.ToTime(snapshot.StartTime),
+				Size:        int(aws.ToInt32(snapshot.VolumeSize)),
+				State:       string(snapshot.State),
+				Encrypted:   aws.ToBool(snapshot.Encrypted),
+			})
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].StartTime.After(snapshots[j].StartTime)
+	})
+	return snapshots, nil
+}
+
+// CreateVolumeFromSnapshot creates a volume from a snapshot, in the
+// availability zone and with the type and performance of the source volume,
+// to replace it on an instance. It returns the ID of the new volume.
+func (c *EC2Client) CreateVolumeFromSnapshot(ctx context.Context, instanceID, snapshotID string, source *model.Volume) (string, error) {
+	c.log.Info("Creating EBS volume from snapshot", "instanceID", instanceID, "snapshotID", snapshotID, "availabilityZone", source.AvailabilityZone)
+
+	input := &ec2.CreateVolumeInput{
+		AvailabilityZone: aws.String(source.AvailabilityZone),
+		SnapshotId:       aws.String(snapshotID),
+		VolumeType:       types.VolumeType(source.Type),
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeVolume,
+				Tags: []types.Tag{
+					{Key: aws.String("Name"), Value: aws.String(fmt.Sprintf("%s restored from %s", instanceID, snapshotID))},
+				},
+			},
+		},
+	}
+	switch types.VolumeType(source.Type) {
+	case types.VolumeTypeIo1, types.VolumeTypeIo2:
+		input.Iops = aws.Int32(int32(source.IOPS))
+	case types.VolumeTypeGp3:
+		input.Iops = aws.Int32(int32(source.IOPS))
+		input.Throughput = aws.Int32(int32(source.Throughput))
+	}
+
+	output, err := c.client.CreateVolume(ctx, input)
+	c.record(ctx, "CreateVolume", instanceID, map[string]string{"snapshotId": snapshotID}, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to create volume from snapshot %s: %w", snapshotID, err)
+	}
+
+	return aws.ToString(output.VolumeId), nil
+}
+
+// WaitVolumeAvailable waits until an EBS volume is available, i.e. created
+// or detached, or the timeout has elapsed
+func (c *EC2Client) WaitVolumeAvailable(ctx context.Context, volumeID string, timeout time.Duration) error {
+	c.log.Info("Waiting for EBS volume to be available", "volumeID", volumeID, "timeout", timeout)
+
+	waiter := ec2.NewVolumeAvailableWaiter(c.client)
+	err := waiter.Wait(ctx, &ec2.DescribeVolumesInput{
+		VolumeIds: []string{volumeID},
+	}, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to wait for volume %s to be available: %w", volumeID, err)
+	}
+
+	return nil
+}
+
+// WaitVolumeInUse waits until an EBS volume is attached, or the timeout has
+// elapsed
+func (c *EC2Client) WaitVolumeInUse(ctx context.Context, volumeID string, timeout time.Duration) error {
+	c.log.Info("Waiting for EBS volume to be in use", "volumeID", volumeID, "timeout", timeout)
+
+	waiter := ec2.NewVolumeInUseWaiter(c.client)
+	err := waiter.Wait(ctx, &ec2.DescribeVolumesInput{
+		VolumeIds: []string{volumeID},
+	}, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to wait for volume %s to be in use: %w", volumeID, err)
+	}
+
+	return nil
+}
+
+// DetachVolume detaches an EBS volume from an instance
+func (c *EC2Client) DetachVolume(ctx context.Context, instanceID, volumeID string) error {
+	c.log.Info("Detaching EBS volume", "instanceID", instanceID, "volumeID", volumeID)
+
+	_, err := c.client.DetachVolume(ctx, &ec2.DetachVolumeInput{
+		InstanceId: aws.String(instanceID),
+		VolumeId:   aws.String(volumeID),
+	})
+	c.record(ctx, "DetachVolume", instanceID, map[string]string{"volumeId": volumeID}, err)
+	if err != nil {
+		return fmt.Errorf("failed to detach volume %s: %w", volumeID, err)
+	}
+
+	return nil
+}
+
+// AttachVolume attaches an EBS volume to an instance as the given device
+func (c *EC2Client) AttachVolume(ctx context.Context, instanceID, volumeID, device string) error {
+	c.log.Info("Attaching EBS volume", "instanceID", instanceID, "volumeID", volumeID, "device", device)
+
+	_, err := c.client.AttachVolume(ctx, &ec2.AttachVolumeInput{
+		InstanceId: aws.String(instanceID),
+		VolumeId:   aws.String(volumeID),
+		Device:     aws.String(device),
+	})
+	c.record(ctx, "AttachVolume", instanceID, map[string]string{"volumeId": volumeID, "device": device}, err)
+	if err != nil {
+		return fmt.Errorf("failed to attach volume %s: %w", volumeID, err)
+	}
+
+	return nil
+}
+
+// DeleteVolume deletes an EBS volume created for an instance
+func (c *EC2Client) DeleteVolume(ctx context.Context, instanceID, volumeID string) error {
+	c.log.Info("Deleting EBS volume", "instanceID", instanceID, "volumeID", volumeID)
+
+	_, err := c.client.DeleteVolume(ctx, &ec2.DeleteVolumeInput{
+		VolumeId: aws.String(volumeID),
+	})
+	c.record(ctx, "DeleteVolume", instanceID, map[string]string{"volumeId": volumeID}, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete volume %s: %w", volumeID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// ListVPCs returns the VPCs of the region with their subnets, sorted by name
+func (c *EC2Client) ListVPCs(ctx context.Context) ([]model.VPC, error) {
+	c.log.Info("Listing VPCs", "region", c.region)
+
+	vpcs := make([]model.VPC, 0)
+	index := make(map[string]int)
+
+	vpcPaginator := ec2.NewDescribeVpcsPaginator(c.client, &ec2.DescribeVpcsInput{})
+	for vpcPaginator.HasMorePages() {
+		output, err := vpcPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe VPCs: %w", err)
+		}
+		for _, vpc := range output.Vpcs {
+			id := aws.ToString(vpc.VpcId)
+			index[id] = len(vpcs)
+			vpcs = append(vpcs, model.VPC{
+				ID:        id,
+				Name:      tagValue(vpc.Tags, "Name"),
+				CIDR:      aws.ToString(vpc.CidrBlock),
+				State:     string(vpc.State),
+				IsDefault: aws.ToBool(vpc.IsDefault),
+			})
+		}
+	}
+
+	subnetPaginator := ec2.NewDescribeSubnetsPaginator(c.client, &ec2.DescribeSubnetsInput{})
+	for subnetPaginator.HasMorePages() {
+		output, err := subnetPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe subnets: %w", err)
+		}
+		for _, subnet := range output.Subnets {
+			i, ok := index[aws.ToString(subnet.VpcId)]
+			if !ok {
+				continue
+			}
+			vpcs[i].Subnets = append(vpcs[i].Subnets, model.Subnet{
+				ID:               aws.ToString(subnet.SubnetId),
+				Name:             tagValue(subnet.Tags, "Name"),
+				VpcID:            aws.ToString(subnet.VpcId),
+				CIDR:             aws.ToString(subnet.CidrBlock),
+				AvailabilityZone: aws.ToString(subnet.AvailabilityZone),
+				AvailableIPs:     int(aws.ToInt32(subnet.AvailableIpAddressCount)),
+				State:            string(subnet.State),
+				DefaultForAZ:     aws.ToBool(subnet.DefaultForAz),
+			})
+		}
+	}
+
+	sort.Slice(vpcs, func(i, j int) bool {
+		return vpcs[i].DisplayName() < vpcs[j].DisplayName()
+	})
+	for _, vpc := range vpcs {
+		sort.Slice(vpc.Subnets, func(i, j int) bool {
+			a, b := vpc.Subnets[i], vpc.Subnets[j]
+			if a.AvailabilityZone != b.AvailabilityZone {
+				return a.AvailabilityZone < b.AvailabilityZone
+			}
+			return a.CIDR < b.CIDR
+		})
+	}
+
+	return vpcs, nil
+}
+
+// tagValue returns the value of a tag, or an empty string if it is not set
+func tagValue(tags []types.Tag, key string) string {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == key {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+// Statuses of an SSM Run Command invocation
+const (
+	CommandPending    = "Pending"
+	CommandInProgress = "InProgress"
+	CommandDelayed    = "Delayed"
+	CommandSuccess    = "Success"
+)
+
+// CommandInvocation is the execution of an SSM Run Command on an instance
+type CommandInvocation struct {
+	CommandID    string
+	InstanceID   string
+	Status       string // e.g. Pending, InProgress, Success, Failed, TimedOut
+	StatusDetail string
+	ExitCode     int // -1 until the command exits
+	Output       string
+	Error        string
+}
+
+// Done returns true once the command has exited or was stopped on the
+// instance
+func (c CommandInvocation) Done() bool {
+	switch c.Status {
+	case "", CommandPending, CommandInProgress, CommandDelayed, "Cancelling":
+		return false
+	default:
+		return true
+	}
+}
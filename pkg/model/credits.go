@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// CreditsStandard is the credit specification of burstable instances
+	// throttled once their credits are spent
+	CreditsStandard = "standard"
+	// CreditsUnlimited is the credit specification of burstable instances
+	// which can burst beyond their credits, at an additional cost
+	CreditsUnlimited = "unlimited"
+)
+
+// burstableFamilies are the instance families with CPU credits
+var burstableFamilies = []string{"t1", "t2", "t3", "t3a", "t4g"}
+
+// CPUCredits represents the CPU credits of a burstable instance
+type CPUCredits struct {
+	Specification string    // Credit specification (standard or unlimited)
+	Balance       float64   // Credits available, from CloudWatch
+	BalanceTime   time.Time // Time of the balance, zero if not available
+	BalanceErr    error     // Error raised while retrieving the balance, if any
+}
+
+// HasBalance returns true if the credit balance was retrieved
+func (c *CPUCredits) HasBalance() bool {
+	return !c.BalanceTime.IsZero()
+}
+
+// IsBurstable returns true if the instance type uses CPU credits
+func (i *Instance) IsBurstable() bool {
+	family, _, _ := strings.Cut(i.Type, ".")
+	for _, burstable := range burstableFamilies {
+		if family == burstable {
+			return true
+		}
+	}
+	return false
+}
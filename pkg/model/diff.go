@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kinds of changes of an instance between two inventories
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// FieldChange is the change of an attribute of an instance, e.g. its type
+// or a tag
+type FieldChange struct {
+	Field string // type, state or tag:<key>
+	From  string // Empty if the tag was added
+	To    string // Empty if the tag was removed
+}
+
+// String returns the change, e.g. "type: t3.micro → m5.large", with (none)
+// for a tag added or removed
+func (c FieldChange) String() string {
+	from, to := c.From, c.To
+	if from == "" {
+		from = "(none)"
+	}
+	if to == "" {
+		to = "(none)"
+	}
+	return fmt.Sprintf("%s: %s → %s", c.Field, from, to)
+}
+
+// InstanceDiff is an instance added, removed or changed between two
+// inventories
+type InstanceDiff struct {
+	ID      string        // Instance ID
+	Name    string        // Instance name, in the most recent inventory
+	Kind    string        // added, removed or changed
+	Changes []FieldChange // Changes of a changed instance
+}
+
+// diffOrder is the order of the kinds of changes in a diff
+var diffOrder = map[string]int{DiffAdded: 0, DiffRemoved: 1, DiffChanged: 2}
+
+// DiffInstances compares two inventories of instances, and returns the
+// instances added, removed, and those whose type, state or tags changed,
+// sorted by kind then by ID
+func DiffInstances(from, to []Instance) []InstanceDiff {
+	before := make(map[string]Instance, len(from))
+	for _, instance := range from {
+		before[instance.ID] = instance
+	}
+	after := make(map[string]Instance, len(to))
+	for _, instance := range to {
+		after[instance.ID] = instance
+	}
+
+	diffs := []InstanceDiff{}
+	for _, instance := range to {
+		previous, ok := before[instance.ID]
+		if !ok {
+			diffs = append(diffs, InstanceDiff{ID: instance.ID, Name: instance.Name, Kind: DiffAdded})
+			continue
+		}
+		if changes := diffFields(previous, instance); len(changes) > 0 {
+			diffs = append(diffs, InstanceDiff{ID: instance.ID, Name: instance.Name, Kind: DiffChanged, Changes: changes})
+		}
+	}
+	for _, instance := range from {
+		if _, ok := after[instance.ID]; !ok {
+			diffs = append(diffs, InstanceDiff{ID: instance.ID, Name: instance.Name, Kind: DiffRemoved})
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		if diffs[i].Kind != diffs[j].Kind {
+			return diffOrder[diffs[i].Kind] < diffOrder[diffs[j].Kind]
+		}
+		return diffs[i].ID < diffs[j].ID
+	})
+	return diffs
+}
+
+// diffFields returns the changes of the type, the state and the tags of an
+// instance, the tags sorted by key
+func diffFields(from, to Instance) []FieldChange {
+	var changes []FieldChange
+	if from.Type != to.Type {
+		changes = append(changes, FieldChange{Field: "type", From: from.Type, To: to.Type})
+	}
+	if from.State != to.State {
+		changes = append(changes, FieldChange{Field: "state", From: from.State, To: to.State})
+	}
+
+	keys := make(map[string]bool, len(from.Tags)+len(to.Tags))
+	for key := range from.Tags {
+		keys[key] = true
+	}
+	for key := range to.Tags {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		before, hadTag := from.Tags[key]
+		after, hasTag := to.Tags[key]
+		if before != after || hadTag != hasTag {
+			changes = append(changes, FieldChange{Field: "tag:" + key, From: before, To: after})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nlamirault/e2c/pkg/model"
+	"github.com/nlamirault/e2c/pkg/model/modeltest"
+)
+
+func TestDiffInstances(t *testing.T) {
+	web := modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("t3.micro").WithTag("Team", "payments")
+	db := modeltest.NewInstance().WithID("i-0db").WithName("db").WithType("r5.large")
+
+	tests := []struct {
+		name string
+		from []model.Instance
+		to   []model.Instance
+		want []model.InstanceDiff
+	}{
+		{
+			name: "no inventory",
+			want: []model.InstanceDiff{},
+		},
+		{
+			name: "unchanged",
+			from: []model.Instance{web.Build(), db.Build()},
+			to:   []model.Instance{db.Build(), web.Build()},
+			want: []model.InstanceDiff{},
+		},
+		{
+			name: "type",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("m5.large").WithTag("Team", "payments").Build()},
+			want: []model.InstanceDiff{{ID: "i-0web", Name: "web", Kind: model.DiffChanged, Changes: []model.FieldChange{
+				{Field: "type", From: "t3.micro", To: "m5.large"},
+			}}},
+		},
+		{
+			name: "state",
+			from: []model.Instance{db.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0db").WithName("db").WithType("r5.large").Stopped().Build()},
+			want: []model.InstanceDiff{{ID: "i-0db", Name: "db", Kind: model.DiffChanged, Changes: []model.FieldChange{
+				{Field: "state", From: "running", To: "stopped"},
+			}}},
+		},
+		{
+			name: "tag changed",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("t3.micro").WithTag("Team", "platform").Build()},
+			want: []model.InstanceDiff{{ID: "i-0web", Name: "web", Kind: model.DiffChanged, Changes: []model.FieldChange{
+				{Field: "tag:Team", From: "payments", To: "platform"},
+			}}},
+		},
+		{
+			name: "tag added and removed",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("t3.micro").WithTag("Owner", "alice").Build()},
+			want: []model.InstanceDiff{{ID: "i-0web", Name: "web", Kind: model.DiffChanged, Changes: []model.FieldChange{
+				{Field: "tag:Owner", To: "alice"},
+				{Field: "tag:Team", From: "payments"},
+			}}},
+		},
+		{
+			name: "tag emptied",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0web").WithName("web").WithType("t3.micro").WithTag("Team", "").Build()},
+			want: []model.InstanceDiff{{ID: "i-0web", Name: "web", Kind: model.DiffChanged, Changes: []model.FieldChange{
+				{Field: "tag:Team", From: "payments"},
+			}}},
+		},
+		{
+			name: "type, state and tags, renamed",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{modeltest.NewInstance().WithID("i-0web").WithName("web-blue").WithType("m5.large").Stopped().WithTag("Team", "payments").Build()},
+			want: []model.InstanceDiff{{ID: "i-0web", Name: "web-blue", Kind: model.DiffChanged, Changes: []model.FieldChange{
+				{Field: "type", From: "t3.micro", To: "m5.large"},
+				{Field: "state", From: "running", To: "stopped"},
+				{Field: "tag:Name", From: "web", To: "web-blue"},
+			}}},
+		},
+		{
+			name: "added",
+			from: []model.Instance{web.Build()},
+			to:   []model.Instance{web.Build(), db.Build()},
+			want: []model.InstanceDiff{{ID: "i-0db", Name: "db", Kind: model.DiffAdded}},
+		},
+		{
+			name: "removed",
+			from: []model.Instance{web.Build(), db.Build()},
+			to:   []model.Instance{web.Build()},
+			want: []model.InstanceDiff{{ID: "i-0db", Name: "db", Kind: model.DiffRemoved}},
+		},
+		{
+			name: "all from scratch",
+			to:   []model.Instance{web.Build(), db.Build()},
+			want: []model.InstanceDiff{
+				{ID: "i-0db", Name: "db", Kind: model.DiffAdded},
+				{ID: "i-0web", Name: "web", Kind: model.DiffAdded},
+			},
+		},
+		{
+			name: "sorted by kind then by ID",
+			from: []model.Instance{
+				modeltest.NewInstance().WithID("i-3").WithName("c").Build(),
+				modeltest.NewInstance().WithID("i-4").WithName("d").Build(),
+				modeltest.NewInstance().WithID("i-2").WithName("b").Build(),
+			},
+			to: []model.Instance{
+				modeltest.NewInstance().WithID("i-4").WithName("d").Stopped().Build(),
+				modeltest.NewInstance().WithID("i-6").WithName("f").Build(),
+				modeltest.NewInstance().WithID("i-2").WithName("b").Stopped().Build(),
+				modeltest.NewInstance().WithID("i-5").WithName("e").Build(),
+			},
+			want: []model.InstanceDiff{
+				{ID: "i-5", Name: "e", Kind: model.DiffAdded},
+				{ID: "i-6", Name: "f", Kind: model.DiffAdded},
+				{ID: "i-3", Name: "c", Kind: model.DiffRemoved},
+				{ID: "i-2", Name: "b", Kind: model.DiffChanged, Changes: []model.FieldChange{{Field: "state", From: "running", To: "stopped"}}},
+				{ID: "i-4", Name: "d", Kind: model.DiffChanged, Changes: []model.FieldChange{{Field: "state", From: "running", To: "stopped"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := model.DiffInstances(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffInstances() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldChangeString(t *testing.T) {
+	tests := []struct {
+		change model.FieldChange
+		want   string
+	}{
+		{change: model.FieldChange{Field: "type", From: "t3.micro", To: "m5.large"}, want: "type: t3.micro → m5.large"},
+		{change: model.FieldChange{Field: "tag:Owner", To: "alice"}, want: "tag:Owner: (none) → alice"},
+		{change: model.FieldChange{Field: "tag:Team", From: "payments"}, want: "tag:Team: payments → (none)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.change.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+// filterKeys maps the filter expression keys to EC2 API filter names
+var filterKeys = map[string]string{
+	"name":   "tag:Name",
+	"state":  "instance-state-name",
+	"type":   "instance-type",
+	"vpc":    "vpc-id",
+	"subnet": "subnet-id",
+}
+
+// Filter represents a parsed filter expression.
+//
+// A filter expression is a list of space separated terms. Terms of the form
+// key:value are sent to the EC2 API, the remaining ones are matched against
+// the instances on the client side:
+//
+//	name:web-* state:running type:t3.micro vpc:vpc-0123 tag:Team=payments
+//
+// Double quotes allow spaces in a term: tag:"Cost Center=R&D Lab".
+//
+// Terms of the form flag:name, e.g. flag:enclave or flag:spot, are matched
+// on the client side against the features enabled on the instances.
+type Filter struct {
+	Server map[string][]string // EC2 API filters indexed by name
+	Flags  []string            // Features the instances must have
+	Text   string              // Free text matched on the client side
+}
+
+// ParseFilter parses a filter expression
+func ParseFilter(expr string) Filter {
+	filter := Filter{
+		Server: make(map[string][]string),
+	}
+
+	var text []string
+	for _, term := range splitTerms(expr) {
+		key, value, found := strings.Cut(term, ":")
+		if !found || value == "" {
+			text = append(text, term)
+			continue
+		}
+
+		if strings.EqualFold(key, "flag") {
+			filter.Flags = append(filter.Flags, strings.Split(value, ",")...)
+			continue
+		}
+
+		if strings.EqualFold(key, "tag") {
+			tagKey, tagValue, found := strings.Cut(value, "=")
+			if !found || tagKey == "" {
+				// Only the tag key is given, match any value
+				filter.Server["tag-key"] = append(filter.Server["tag-key"], value)
+				continue
+			}
+			filter.Server["tag:"+tagKey] = append(filter.Server["tag:"+tagKey], tagValue)
+			continue
+		}
+
+		name, ok := filterKeys[strings.ToLower(key)]
+		if !ok {
+			text = append(text, term)
+			continue
+		}
+		filter.Server[name] = append(filter.Server[name], strings.Split(value, ",")...)
+	}
+
+	filter.Text = strings.Join(text, " ")
+	return filter
+}
+
+// IsEmpty returns true if the filter does not restrict anything
+func (f Filter) IsEmpty() bool {
+	return len(f.Server) == 0 && len(f.Flags) == 0 && f.Text == ""
+}
+
+// MatchesFlags returns true if the instance has all the flags of the filter
+func (f Filter) MatchesFlags(instance Instance) bool {
+	for _, flag := range f.Flags {
+		if !instance.HasFlag(flag) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasServer returns true if the filter contains EC2 API filters
+func (f Filter) HasServer() bool {
+	return len(f.Server) > 0
+}
+
+// ServerNames returns the names of the EC2 API filters, sorted
+func (f Filter) ServerNames() []string {
+	names := make([]string, 0, len(f.Server))
+	for name := range f.Server {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServerWithin returns the EC2 API filters of the filter within a scope,
+// another filter: those of the scope, then those of the filter on the other
+// names. The scope takes precedence on a name in both, as the EC2 API
+// matches any of the values of a name.
+func (f Filter) ServerWithin(scope Filter) map[string][]string {
+	server := make(map[string][]string, len(f.Server)+len(scope.Server))
+	for name, values := range f.Server {
+		server[name] = values
+	}
+	for name, values := range scope.Server {
+		server[name] = values
+	}
+	return server
+}
+
+// TagFilter returns the filter expression matching the instances with a tag,
+// quoted if the key or the value contains spaces
+func TagFilter(key, value string) string {
+	term := key + "=" + value
+	if strings.ContainsAny(term, " \t\"") {
+		term = `"` + strings.ReplaceAll(term, `"`, "") + `"`
+	}
+	return "tag:" + term
+}
+
+// splitTerms splits a filter expression on the spaces which are not enclosed
+// in double quotes, and removes the quotes
+func splitTerms(expr string) []string {
+	var (
+		terms  []string
+		term   strings.Builder
+		quoted bool
+	)
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (r == ' ' || r == '\t'):
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms
+}
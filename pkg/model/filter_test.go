@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		server map[string][]string
+		flags  []string
+		text   string
+	}{
+		// Operators: the keys sent to the EC2 API
+		{name: "empty", expr: ""},
+		{name: "blank", expr: "  \t "},
+		{name: "name", expr: "name:web-*", server: map[string][]string{"tag:Name": {"web-*"}}},
+		{name: "state", expr: "state:running", server: map[string][]string{"instance-state-name": {"running"}}},
+		{name: "type", expr: "type:t3.micro", server: map[string][]string{"instance-type": {"t3.micro"}}},
+		{name: "vpc", expr: "vpc:vpc-0123", server: map[string][]string{"vpc-id": {"vpc-0123"}}},
+		{name: "subnet", expr: "subnet:subnet-0123", server: map[string][]string{"subnet-id": {"subnet-0123"}}},
+		{name: "key case insensitive", expr: "STATE:running", server: map[string][]string{"instance-state-name": {"running"}}},
+		{name: "any of the values", expr: "state:running,stopped", server: map[string][]string{"instance-state-name": {"running", "stopped"}}},
+		{name: "repeated key", expr: "type:t3.micro type:t3.small", server: map[string][]string{"instance-type": {"t3.micro", "t3.small"}}},
+		{
+			name: "all the operators",
+			expr: "name:web-* state:running type:t3.micro vpc:vpc-0123 tag:Team=payments",
+			server: map[string][]string{
+				"tag:Name":            {"web-*"},
+				"instance-state-name": {"running"},
+				"instance-type":       {"t3.micro"},
+				"vpc-id":              {"vpc-0123"},
+				"tag:Team":            {"payments"},
+			},
+		},
+		{name: "tag value", expr: "tag:Team=payments", server: map[string][]string{"tag:Team": {"payments"}}},
+		{name: "tag key only", expr: "tag:Team", server: map[string][]string{"tag-key": {"Team"}}},
+		{name: "tag empty value", expr: "tag:Team=", server: map[string][]string{"tag:Team": {""}}},
+		{name: "tag value with a comma", expr: "tag:Owners=alice,bob", server: map[string][]string{"tag:Owners": {"alice,bob"}}},
+		{name: "flag", expr: "flag:spot", flags: []string{"spot"}},
+		{name: "flags", expr: "flag:spot,arm FLAG:enclave", flags: []string{"spot", "arm", "enclave"}},
+		{name: "text", expr: "web db", text: "web db"},
+		{name: "text and operators", expr: "web state:running flag:spot", server: map[string][]string{"instance-state-name": {"running"}}, flags: []string{"spot"}, text: "web"},
+
+		// Quoting
+		{name: "quoted tag", expr: `tag:"Cost Center=R&D Lab"`, server: map[string][]string{"tag:Cost Center": {"R&D Lab"}}},
+		{name: "quoted value", expr: `tag:Team="data platform"`, server: map[string][]string{"tag:Team": {"data platform"}}},
+		{name: "quoted text", expr: `"web 1" db`, text: "web 1 db"},
+		{name: "tab separator", expr: "web\tstate:running", server: map[string][]string{"instance-state-name": {"running"}}, text: "web"},
+		{name: "unterminated quote", expr: `tag:"Cost Center`, server: map[string][]string{"tag-key": {"Cost Center"}}},
+		{name: "empty quotes", expr: `"" web`, text: "web"},
+
+		// Negation is not supported: the terms are matched as text, never
+		// sent to the EC2 API
+		{name: "negated operator", expr: "-state:running", text: "-state:running"},
+		{name: "negated with a bang", expr: "!type:t3.micro", text: "!type:t3.micro"},
+		{name: "negated text", expr: "!web", text: "!web"},
+
+		// Invalid terms are matched as text
+		{name: "unknown key", expr: "zone:eu-west-1a", text: "zone:eu-west-1a"},
+		{name: "empty value", expr: "state:", text: "state:"},
+		{name: "empty key", expr: ":running", text: ":running"},
+		{name: "tag without key", expr: "tag:=prod", server: map[string][]string{"tag-key": {"=prod"}}},
+		{name: "colon only", expr: ":", text: ":"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := ParseFilter(tt.expr)
+
+			server := tt.server
+			if server == nil {
+				server = map[string][]string{}
+			}
+			if !reflect.DeepEqual(filter.Server, server) {
+				t.Errorf("ParseFilter(%q).Server = %q, want %q", tt.expr, filter.Server, server)
+			}
+			if !reflect.DeepEqual(filter.Flags, tt.flags) {
+				t.Errorf("ParseFilter(%q).Flags = %q, want %q", tt.expr, filter.Flags, tt.flags)
+			}
+			if filter.Text != tt.text {
+				t.Errorf("ParseFilter(%q).Text = %q, want %q", tt.expr, filter.Text, tt.text)
+			}
+			if got, want := filter.IsEmpty(), tt.server == nil && tt.flags == nil && tt.text == ""; got != want {
+				t.Errorf("ParseFilter(%q).IsEmpty() = %v, want %v", tt.expr, got, want)
+			}
+			if got, want := filter.HasServer(), tt.server != nil; got != want {
+				t.Errorf("ParseFilter(%q).HasServer() = %v, want %v", tt.expr, got, want)
+			}
+		})
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       string
+	}{
+		{key: "Team", value: "payments", want: "tag:Team=payments"},
+		{key: "Cost Center", value: "R&D Lab", want: `tag:"Cost Center=R&D Lab"`},
+		{key: "Team", value: "data\tplatform", want: "tag:\"Team=data\tplatform\""},
+		{key: "Quote", value: `say "hi"`, want: `tag:"Quote=say hi"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := TagFilter(tt.key, tt.value)
+			if got != tt.want {
+				t.Errorf("TagFilter(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+			}
+
+			// The expression is parsed back into the filter on the tag,
+			// without the quotes of the value
+			parsed := ParseFilter(got).Server
+			if len(parsed) != 1 || len(parsed["tag:"+tt.key]) != 1 {
+				t.Fatalf("ParseFilter(%q).Server = %q, want the tag %s", got, parsed, tt.key)
+			}
+		})
+	}
+}
+
+func TestServerNames(t *testing.T) {
+	filter := ParseFilter("type:t3.micro tag:Team=payments name:web-* web")
+	want := []string{"instance-type", "tag:Name", "tag:Team"}
+	if got := filter.ServerNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ServerNames() = %q, want %q", got, want)
+	}
+}
+
+func TestServerWithin(t *testing.T) {
+	filter := ParseFilter("state:stopped type:t3.micro")
+	scope := ParseFilter("state:running vpc:vpc-0123")
+
+	want := map[string][]string{
+		"instance-state-name": {"running"},
+		"instance-type":       {"t3.micro"},
+		"vpc-id":              {"vpc-0123"},
+	}
+	if got := filter.ServerWithin(scope); !reflect.DeepEqual(got, want) {
+		t.Errorf("ServerWithin() = %q, want %q", got, want)
+	}
+	if got := filter.ServerWithin(Filter{}); !reflect.DeepEqual(got, filter.Server) {
+		t.Errorf("ServerWithin() without scope = %q, want %q", got, filter.Server)
+	}
+}
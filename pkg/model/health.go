@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import "time"
+
+// HealthEvent represents an AWS Health event, such as an EC2 operational
+// issue in a region or a scheduled change
+type HealthEvent struct {
+	ARN              string
+	Service          string    // Service affected (EC2)
+	TypeCode         string    // Type of the event (e.g. AWS_EC2_OPERATIONAL_ISSUE)
+	Category         string    // issue, scheduledChange or accountNotification
+	Region           string    // Region affected, or global
+	AvailabilityZone string    // Availability zone affected, if any
+	Status           string    // open, upcoming or closed
+	StartTime        time.Time // Start of the event
+	EndTime          time.Time // End of the event, zero if not ended
+	LastUpdated      time.Time // Last update of the event
+}
+
+// IsIssue returns true if the event is an open operational issue
+func (e HealthEvent) IsIssue() bool {
+	return e.Category == "issue" && e.Status == "open"
+}
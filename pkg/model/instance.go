@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package model defines the EC2 resources listed by e2c, independent of the
+// AWS SDK types.
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Instance represents an EC2 instance
+type Instance struct {
+	ID           string            // Instance ID
+	Name         string            // Instance name (from Name tag)
+	Type         string            // Instance type (e.g., t2.micro)
+	State        string            // Current state (running, stopped, etc.)
+	Region       string            // AWS region
+	Account      string            // Name of the account, when several accounts are listed
+	LaunchTime   time.Time         // When the instance was launched
+	Age          time.Duration     // Age of the instance
+	PrivateIP    string            // Private IP address
+	PublicIP     string            // Public IP address
+	IPv6Address  string            // Primary IPv6 address, if any
+	Platform     string            // Platform details (e.g., Linux/UNIX, Windows)
+	Architecture string            // Architecture (e.g., x86_64, arm64)
+	CPUCores     int               // Number of CPU cores
+	CPUThreads   int               // Number of threads per CPU core
+	Tags         map[string]string // AWS tags associated with the instance
+
+	// Network
+	VpcID             string             // VPC the instance runs in
+	SubnetID          string             // Subnet of the primary network interface
+	PrivateDNSName    string             // Private DNS name
+	PublicDNSName     string             // Public DNS name
+	SecurityGroups    []SecurityGroup    // Security groups of the instance
+	NetworkInterfaces []NetworkInterface // Attached network interfaces
+	SourceDestCheck   bool               // Source/destination check of the primary network interface
+
+	// Storage
+	RootDeviceName string        // Root device name (e.g., /dev/xvda)
+	RootDeviceType string        // Root device type (ebs or instance-store)
+	EBSOptimized   bool          // EBS optimization enabled
+	BlockDevices   []BlockDevice // Block device mappings
+
+	// Security
+	ImageID            string // AMI used to launch the instance
+	KeyName            string // Key pair name
+	IAMInstanceProfile string // ARN of the IAM instance profile
+	MetadataHTTPTokens string // IMDS token requirement (optional or required)
+
+	// Monitoring
+	Monitoring string // Detailed monitoring state (disabled, enabled, ...)
+
+	// Hardware and placement
+	Enclave             bool     // Nitro Enclaves enabled
+	Hibernation         bool     // Hibernation configured
+	Accelerators        []string // Elastic inference accelerators and elastic GPUs
+	Lifecycle           string   // spot, scheduled or capacity-block, empty if on-demand
+	CapacityReservation string   // ID of the capacity reservation the instance runs in
+	OutpostARN          string   // ARN of the Outpost the instance runs on
+	Tenancy             string   // Tenancy of the instance (default, dedicated, host)
+	AvailabilityZone    string   // Availability zone of the instance
+	PlacementGroup      string   // Name of the placement group, if any
+	HostID              string   // ID of the dedicated host, if any
+	BootMode            string   // Boot mode (legacy-bios, uefi)
+	TPMSupport          string   // NitroTPM version, empty if not supported
+	Licenses            []string // ARNs of the license configurations
+}
+
+// SecurityGroup represents a security group attached to an instance
+type SecurityGroup struct {
+	ID   string // Security group ID
+	Name string // Security group name
+}
+
+// NetworkInterface represents an elastic network interface attached to an instance
+type NetworkInterface struct {
+	ID              string          // Network interface ID
+	Description     string          // Description of the interface
+	SubnetID        string          // Subnet ID
+	VpcID           string          // VPC ID
+	PrivateIP       string          // Primary private IP address
+	PublicIP        string          // Public IP address associated with the interface
+	MACAddress      string          // MAC address
+	Status          string          // Status of the interface
+	Primary         bool            // Primary interface of the instance (device index 0)
+	SourceDestCheck bool            // Source/destination checking enabled
+	SecurityGroups  []SecurityGroup // Security groups of the interface
+}
+
+// BlockDevice represents a block device mapping of an instance
+type BlockDevice struct {
+	DeviceName          string    // Device name (e.g., /dev/sda1)
+	VolumeID            string    // EBS volume ID
+	Status              string    // Attachment status
+	DeleteOnTermination bool      // Volume deleted when the instance terminates
+	AttachTime          time.Time // When the volume was attached
+}
+
+// GetSSHCommand returns an SSH command for connecting to the instance
+func (i *Instance) GetSSHCommand(username string) string {
+	ip := i.PublicIP
+	if ip == "" {
+		ip = i.PrivateIP
+	}
+
+	if ip == "" {
+		return "No IP address available for SSH connection"
+	}
+
+	return fmt.Sprintf("ssh %s@%s", username, ip)
+}
+
+// IsRunning returns true if the instance is running
+func (i *Instance) IsRunning() bool {
+	return i.State == "running"
+}
+
+// IsStopped returns true if the instance is stopped
+func (i *Instance) IsStopped() bool {
+	return i.State == "stopped"
+}
+
+// DisplayName returns the name to display (name or ID if name is empty)
+func (i *Instance) DisplayName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	return i.ID
+}
+
+// CloudFormationStack returns the name of the CloudFormation stack managing
+// the instance, or an empty string if it is not managed by CloudFormation
+func (i *Instance) CloudFormationStack() string {
+	return i.Tags["aws:cloudformation:stack-name"]
+}
+
+// CloudFormationLogicalID returns the logical ID of the instance in its
+// CloudFormation stack
+func (i *Instance) CloudFormationLogicalID() string {
+	return i.Tags["aws:cloudformation:logical-id"]
+}
+
+// AutoScalingGroup returns the name of the Auto Scaling group of the
+// instance, empty if it is not in a group
+func (i *Instance) AutoScalingGroup() string {
+	return i.Tags["aws:autoscaling:groupName"]
+}
+
+// StateColor returns the color name to use for the instance state
+func (i *Instance) StateColor() string {
+	switch i.State {
+	case "running":
+		return "green"
+	case "stopped":
+		return "red"
+	case "stopping":
+		return "yellow"
+	case "pending":
+		return "yellow"
+	case "shutting-down":
+		return "yellow"
+	case "terminated":
+		return "gray"
+	default:
+		return "white"
+	}
+}
+
+// Flags returns the names of the uncommon features enabled on the instance,
+// matched by the flag: filter terms
+func (i *Instance) Flags() []string {
+	var flags []string
+	if i.Enclave {
+		flags = append(flags, "enclave")
+	}
+	if i.Hibernation {
+		flags = append(flags, "hibernation")
+	}
+	if len(i.Accelerators) > 0 {
+		flags = append(flags, "accelerator")
+	}
+	if i.Lifecycle != "" {
+		flags = append(flags, i.Lifecycle)
+	}
+	if i.CapacityReservation != "" {
+		flags = append(flags, "capacity-reservation")
+	}
+	if i.OutpostARN != "" {
+		flags = append(flags, "outpost")
+	}
+	if i.Tenancy != "" && i.Tenancy != "default" {
+		flags = append(flags, i.Tenancy)
+	}
+	if i.TPMSupport != "" {
+		flags = append(flags, "tpm")
+	}
+	if len(i.Licenses) > 0 {
+		flags = append(flags, "license")
+	}
+	return flags
+}
+
+// HasFlag returns true if the given feature is enabled on the instance
+func (i *Instance) HasFlag(flag string) bool {
+	for _, f := range i.Flags() {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import "time"
+
+// LogEvent represents an event of a CloudWatch Logs stream
+type LogEvent struct {
+	ID        string
+	Timestamp time.Time
+	Stream    string
+	Message   string
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// InstanceMetrics represents the CloudWatch metrics of an instance over a
+// period of time
+type InstanceMetrics struct {
+	Period time.Duration // Period of the datapoints, e.g. 5 minutes
+	Series []MetricSeries
+}
+
+// MetricSeries represents the datapoints of a metric of an instance, sorted
+// by time
+type MetricSeries struct {
+	Name   string // Metric name, e.g. CPUUtilization
+	Stat   string // Statistic of the datapoints, e.g. Average or Sum
+	Unit   string // e.g. Percent or Bytes
+	Times  []time.Time
+	Values []float64
+}
+
+// Last returns the most recent value, false if there is none
+func (s MetricSeries) Last() (float64, bool) {
+	if len(s.Values) == 0 {
+		return 0, false
+	}
+	return s.Values[len(s.Values)-1], true
+}
+
+// Max returns the highest value, 0 if there is none
+func (s MetricSeries) Max() float64 {
+	if len(s.Values) == 0 {
+		return 0
+	}
+	return slices.Max(s.Values)
+}
+
+// Average returns the average of the values, 0 if there is none
+func (s MetricSeries) Average() float64 {
+	if len(s.Values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, value := range s.Values {
+		total += value
+	}
+	return total / float64(len(s.Values))
+}
@@ -0,0 +1,233 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package modeltest builds realistic model.Instance fixtures for the tests,
+// the benchmarks and the fake backend of e2c selftest, rather than writing
+// instance literals by hand:
+//
+//	instance := modeltest.NewInstance().WithName("web-1").Stopped().Spot().Build()
+//	instances := modeltest.NewGenerator(42).Instances(500)
+package modeltest
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+const (
+	// Region is the region of the fixtures
+	Region = "us-east-1"
+
+	// VpcID and SubnetID are the network of the fixtures
+	VpcID    = "vpc-0fixture000000001"
+	SubnetID = "subnet-0fixture00000001"
+)
+
+// Builder builds an instance fixture, a running Linux instance by default
+type Builder struct {
+	instance model.Instance
+	now      time.Time
+}
+
+// NewInstance creates a builder of a running on-demand Linux instance, a day
+// old
+func NewInstance() *Builder {
+	now := time.Now()
+	b := &Builder{
+		instance: model.Instance{
+			ID:                 "i-0fixture000000001",
+			Name:               "fixture",
+			Type:               "t3.medium",
+			State:              "running",
+			Region:             Region,
+			PrivateIP:          "10.0.1.10",
+			Platform:           "Linux/UNIX",
+			Architecture:       "x86_64",
+			CPUCores:           1,
+			CPUThreads:         2,
+			Tags:               map[string]string{"Name": "fixture"},
+			VpcID:              VpcID,
+			SubnetID:           SubnetID,
+			PrivateDNSName:     "ip-10-0-1-10.ec2.internal",
+			RootDeviceName:     "/dev/xvda",
+			RootDeviceType:     "ebs",
+			EBSOptimized:       true,
+			ImageID:            "ami-0fixture000000001",
+			MetadataHTTPTokens: "required",
+			Monitoring:         "disabled",
+			Tenancy:            "default",
+			AvailabilityZone:   Region + "a",
+			BootMode:           "uefi",
+		},
+		now: now,
+	}
+	return b.WithAge(24 * time.Hour)
+}
+
+// WithID sets the ID of the instance
+func (b *Builder) WithID(id string) *Builder {
+	b.instance.ID = id
+	return b
+}
+
+// WithName sets the name of the instance, and its Name tag
+func (b *Builder) WithName(name string) *Builder {
+	b.instance.Name = name
+	b.instance.Tags["Name"] = name
+	return b
+}
+
+// WithType sets the instance type
+func (b *Builder) WithType(instanceType string) *Builder {
+	b.instance.Type = instanceType
+	return b
+}
+
+// WithState sets the state of the instance, dropping the public addresses
+// unless it is running
+func (b *Builder) WithState(state string) *Builder {
+	b.instance.State = state
+	if state != "running" {
+		b.instance.PublicIP = ""
+		b.instance.PublicDNSName = ""
+	}
+	return b
+}
+
+// Stopped makes the instance stopped
+func (b *Builder) Stopped() *Builder {
+	return b.WithState("stopped")
+}
+
+// WithTag adds a tag to the instance
+func (b *Builder) WithTag(key, value string) *Builder {
+	b.instance.Tags[key] = value
+	if key == "Name" {
+		b.instance.Name = value
+	}
+	return b
+}
+
+// WithTags adds tags to the instance
+func (b *Builder) WithTags(tags map[string]string) *Builder {
+	for key, value := range tags {
+		b.WithTag(key, value)
+	}
+	return b
+}
+
+// WithAge sets the launch time of the instance so that it is as old as given
+func (b *Builder) WithAge(age time.Duration) *Builder {
+	b.instance.LaunchTime = b.now.Add(-age).UTC().Truncate(time.Second)
+	b.instance.Age = age.Round(time.Second)
+	return b
+}
+
+// WithPrivateIP sets the private IPv4 address of the instance
+func (b *Builder) WithPrivateIP(ip string) *Builder {
+	b.instance.PrivateIP = ip
+	b.instance.PrivateDNSName = privateDNSName(ip)
+	return b
+}
+
+// WithPublicIP sets the public IPv4 address of the instance
+func (b *Builder) WithPublicIP(ip string) *Builder {
+	b.instance.PublicIP = ip
+	b.instance.PublicDNSName = publicDNSName(ip)
+	return b
+}
+
+// WithIPv6 sets the IPv6 address of the instance
+func (b *Builder) WithIPv6(ip string) *Builder {
+	b.instance.IPv6Address = ip
+	return b
+}
+
+// WithAccount sets the account of the instance, when several accounts are
+// listed
+func (b *Builder) WithAccount(account string) *Builder {
+	b.instance.Account = account
+	return b
+}
+
+// WithZone sets the availability zone of the instance, and its region
+func (b *Builder) WithZone(zone string) *Builder {
+	b.instance.AvailabilityZone = zone
+	b.instance.Region = zone[:len(zone)-1]
+	return b
+}
+
+// Spot makes the instance a spot instance
+func (b *Builder) Spot() *Builder {
+	b.instance.Lifecycle = "spot"
+	return b
+}
+
+// Windows makes the instance a Windows instance, booted with the BIOS as the
+// Windows AMIs
+func (b *Builder) Windows() *Builder {
+	b.instance.Platform = "Windows"
+	b.instance.RootDeviceName = "/dev/sda1"
+	b.instance.BootMode = "legacy-bios"
+	return b
+}
+
+// Arm makes the instance an arm64 (Graviton) instance
+func (b *Builder) Arm() *Builder {
+	b.instance.Architecture = "arm64"
+	b.instance.CPUThreads = 1
+	return b
+}
+
+// Build returns the instance, with its primary network interface and root
+// volume. The builder can be changed and built again.
+func (b *Builder) Build() model.Instance {
+	instance := b.instance
+	instance.Tags = maps.Clone(b.instance.Tags)
+
+	groups := []model.SecurityGroup{{ID: "sg-0fixture000000001", Name: "default"}}
+	instance.SecurityGroups = groups
+	instance.NetworkInterfaces = []model.NetworkInterface{{
+		ID:              "eni-" + suffix(instance.ID),
+		SubnetID:        instance.SubnetID,
+		VpcID:           instance.VpcID,
+		PrivateIP:       instance.PrivateIP,
+		PublicIP:        instance.PublicIP,
+		MACAddress:      "0a:00:00:00:00:01",
+		Status:          "in-use",
+		Primary:         true,
+		SourceDestCheck: true,
+		SecurityGroups:  groups,
+	}}
+	instance.SourceDestCheck = true
+	if instance.RootDeviceType == "ebs" {
+		instance.BlockDevices = []model.BlockDevice{{
+			DeviceName:          instance.RootDeviceName,
+			VolumeID:            "vol-" + suffix(instance.ID),
+			Status:              "attached",
+			DeleteOnTermination: true,
+			AttachTime:          instance.LaunchTime,
+		}}
+	}
+	return instance
+}
+
+// suffix returns the ID of an instance without its prefix, to derive the IDs
+// of its resources
+func suffix(id string) string {
+	return strings.TrimPrefix(id, "i-")
+}
+
+// privateDNSName returns the private DNS name of an address in us-east-1
+func privateDNSName(ip string) string {
+	return fmt.Sprintf("ip-%s.ec2.internal", strings.ReplaceAll(ip, ".", "-"))
+}
+
+// publicDNSName returns the public DNS name of an address in us-east-1
+func publicDNSName(ip string) string {
+	return fmt.Sprintf("ec2-%s.compute-1.amazonaws.com", strings.ReplaceAll(ip, ".", "-"))
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package modeltest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// Values the generated instances are picked from
+var (
+	roles        = []string{"web", "api", "worker", "db", "cache", "bastion", "batch", "search"}
+	environments = []string{"production", "staging", "development"}
+	teams        = []string{"payments", "platform", "search", "data", "identity"}
+	x86Types     = []string{"t3.micro", "t3.medium", "m5.large", "m5.xlarge", "c5.2xlarge", "r5.xlarge"}
+	armTypes     = []string{"t4g.small", "m6g.large", "c7g.xlarge", "r6g.2xlarge"}
+	zones        = []string{"us-east-1a", "us-east-1b", "us-east-1c"}
+)
+
+// states are the states of the generated instances, and their weight: most
+// instances run, some are stopped and a few are changing state
+var states = []struct {
+	name   string
+	weight int
+}{
+	{"running", 70},
+	{"stopped", 20},
+	{"pending", 4},
+	{"stopping", 3},
+	{"shutting-down", 2},
+	{"terminated", 1},
+}
+
+// Generator generates random but reproducible instances, from a seed
+type Generator struct {
+	rand *rand.Rand
+	next int // Index of the next instance, unique in its ID and name
+
+	// Share of the generated instances with an IPv6 address, spot, Windows
+	// or arm64 ones, from 0 to 1
+	IPv6Ratio    float64
+	SpotRatio    float64
+	WindowsRatio float64
+	ArmRatio     float64
+}
+
+// NewGenerator creates a generator of instances, generating the same ones for
+// the same seed
+func NewGenerator(seed uint64) *Generator {
+	return &Generator{
+		rand:         rand.New(rand.NewPCG(seed, seed)),
+		next:         1,
+		IPv6Ratio:    0.2,
+		SpotRatio:    0.15,
+		WindowsRatio: 0.1,
+		ArmRatio:     0.25,
+	}
+}
+
+// Builder returns the builder of the next instance, to change it before
+// building it
+func (g *Generator) Builder() *Builder {
+	n := g.next
+	g.next++
+
+	role := pick(g.rand, roles)
+	environment := pick(g.rand, environments)
+	b := NewInstance().
+		WithID(fmt.Sprintf("i-0%016x", g.rand.Uint64())).
+		WithName(fmt.Sprintf("%s-%s-%d", role, environment, n)).
+		WithTag("Environment", environment).
+		WithTag("Team", pick(g.rand, teams)).
+		WithTag("Role", role).
+		WithZone(pick(g.rand, zones)).
+		WithPrivateIP(fmt.Sprintf("10.0.%d.%d", g.rand.IntN(8), 4+g.rand.IntN(250))).
+		WithAge(g.age())
+
+	if g.rand.Float64() < g.ArmRatio {
+		b.Arm().WithType(pick(g.rand, armTypes))
+	} else {
+		b.WithType(pick(g.rand, x86Types))
+		if g.rand.Float64() < g.WindowsRatio {
+			b.Windows()
+		}
+	}
+	if g.rand.Float64() < g.SpotRatio {
+		b.Spot()
+	}
+	if role == "web" || role == "bastion" {
+		b.WithPublicIP(fmt.Sprintf("54.%d.%d.%d", g.rand.IntN(256), g.rand.IntN(256), 1+g.rand.IntN(254)))
+	}
+	if g.rand.Float64() < g.IPv6Ratio {
+		b.WithIPv6(fmt.Sprintf("2600:1f18:%x:%x::%x", g.rand.IntN(0x10000), g.rand.IntN(0x10000), 1+g.rand.IntN(0xffff)))
+	}
+	return b.WithState(g.state())
+}
+
+// Instance generates an instance
+func (g *Generator) Instance() model.Instance {
+	return g.Builder().Build()
+}
+
+// Instances generates n instances
+func (g *Generator) Instances(n int) []model.Instance {
+	instances := make([]model.Instance, 0, n)
+	for range n {
+		instances = append(instances, g.Instance())
+	}
+	return instances
+}
+
+// state picks the state of an instance, according to the weights
+func (g *Generator) state() string {
+	total := 0
+	for _, state := range states {
+		total += state.weight
+	}
+	n := g.rand.IntN(total)
+	for _, state := range states {
+		if n < state.weight {
+			return state.name
+		}
+		n -= state.weight
+	}
+	return states[0].name
+}
+
+// age picks the age of an instance, from minutes for the ones just launched
+// to a couple of years for the forgotten ones
+func (g *Generator) age() time.Duration {
+	switch n := g.rand.IntN(10); {
+	case n == 0:
+		return time.Duration(1+g.rand.IntN(59)) * time.Minute
+	case n < 7:
+		return time.Duration(1+g.rand.IntN(90*24)) * time.Hour
+	default:
+		return time.Duration(90+g.rand.IntN(640)) * 24 * time.Hour
+	}
+}
+
+// pick picks a value at random
+func pick[T any](r *rand.Rand, values []T) T {
+	return values[r.IntN(len(values))]
+}
@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+// Reachability is the result of a VPC Reachability Analyzer analysis of the
+// path from an instance to a destination
+type Reachability struct {
+	PathID       string
+	AnalysisID   string
+	Status       string // running, succeeded or failed
+	StatusDetail string // Why the analysis failed, if it did
+	Reachable    bool
+	Explanations []ReachabilityExplanation // Why the destination is not reachable
+}
+
+// ReachabilityExplanation is a component blocking the path to the
+// destination
+type ReachabilityExplanation struct {
+	Code      string // e.g. ENI_SG_RULES_MISMATCH
+	Component string // Kind and ID of the component, e.g. security group sg-0123
+	Detail    string // Direction, ports or CIDRs involved, if any
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import "strings"
+
+// Stack represents a CloudFormation stack and the resources it manages
+type Stack struct {
+	Name         string
+	ID           string
+	Status       string // e.g. CREATE_COMPLETE, UPDATE_ROLLBACK_FAILED
+	StatusReason string // Reason of the status, e.g. of a failure, if any
+	Drift        string // Drift status, e.g. DRIFTED, NOT_CHECKED
+	Resources    []StackResource
+}
+
+// StackResource represents a resource managed by a CloudFormation stack
+type StackResource struct {
+	LogicalID    string
+	PhysicalID   string // e.g. the ID of an instance, empty until created
+	Type         string // e.g. AWS::EC2::Instance
+	Status       string // e.g. CREATE_COMPLETE
+	StatusReason string
+}
+
+// Failed returns true if the last operation on the stack failed or was
+// rolled back
+func (s Stack) Failed() bool {
+	return strings.HasSuffix(s.Status, "_FAILED") || strings.Contains(s.Status, "ROLLBACK")
+}
+
+// InProgress returns true while an operation is running on the stack
+func (s Stack) InProgress() bool {
+	return strings.HasSuffix(s.Status, "_IN_PROGRESS")
+}
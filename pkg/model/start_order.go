@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StartOrderTag is the tag defining the tier in which an instance is started
+// by the start group action, lower tiers first (e.g. e2c:start-order=1)
+const StartOrderTag = "e2c:start-order"
+
+// StartTier is a set of instances started together
+type StartTier struct {
+	Order     int  // Value of the start order tag
+	Untagged  bool // Instances without a valid start order, started last
+	Instances []Instance
+}
+
+// StartOrder returns the start order of the instance, and false if the tag
+// is not set or is not a number
+func (i Instance) StartOrder() (int, bool) {
+	value, ok := i.Tags[StartOrderTag]
+	if !ok {
+		return 0, false
+	}
+	order, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return order, true
+}
+
+// StartTiers groups the instances by start order, in ascending order. The
+// instances without start order are in a last tier.
+func StartTiers(instances []Instance) []StartTier {
+	byOrder := make(map[int][]Instance)
+	var untagged []Instance
+	for _, instance := range instances {
+		order, ok := instance.StartOrder()
+		if !ok {
+			untagged = append(untagged, instance)
+			continue
+		}
+		byOrder[order] = append(byOrder[order], instance)
+	}
+
+	orders := make([]int, 0, len(byOrder))
+	for order := range byOrder {
+		orders = append(orders, order)
+	}
+	sort.Ints(orders)
+
+	tiers := make([]StartTier, 0, len(orders)+1)
+	for _, order := range orders {
+		tiers = append(tiers, StartTier{Order: order, Instances: byOrder[order]})
+	}
+	if len(untagged) > 0 {
+		tiers = append(tiers, StartTier{Untagged: true, Instances: untagged})
+	}
+	return tiers
+}
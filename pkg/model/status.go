@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// InstanceStatus represents the status checks of an EC2 instance
+type InstanceStatus struct {
+	State    string // State of the instance (running, stopped, ...)
+	System   string // System status check (ok, impaired, initializing, ...)
+	Instance string // Instance status check (ok, impaired, initializing, ...)
+	EBS      string // Attached EBS status check
+	Events   []ScheduledEvent
+}
+
+// ScheduledEvent represents an event scheduled by AWS on an instance
+// (instance-retirement, system-reboot, ...)
+type ScheduledEvent struct {
+	ID          string
+	Code        string
+	Description string
+	NotBefore   time.Time // Earliest start of the event
+	NotAfter    time.Time // Latest end of the event
+	Deadline    time.Time // Deadline to reschedule the event, if any
+}
+
+// IsActive returns false once the event was completed or canceled
+func (e ScheduledEvent) IsActive() bool {
+	return !strings.HasPrefix(e.Description, "[Completed]") &&
+		!strings.HasPrefix(e.Description, "[Canceled]")
+}
+
+// Protection represents the protections enabled on an EC2 instance
+type Protection struct {
+	Termination bool      // Termination protection (disableApiTermination)
+	Stop        bool      // Stop protection (disableApiStop)
+	FetchedAt   time.Time // Time the protections were retrieved
+}
+
+// String returns the protections enabled, e.g. "termination+stop", or "none"
+func (p Protection) String() string {
+	switch {
+	case p.Termination && p.Stop:
+		return "termination+stop"
+	case p.Termination:
+		return "termination"
+	case p.Stop:
+		return "stop"
+	default:
+		return "none"
+	}
+}
+
+// Protections of an instance, as named in the changes
+const (
+	ProtectionTermination = "termination"
+	ProtectionStop        = "stop"
+)
+
+// Sources of the changes of the protections
+const (
+	SourceCloudTrail = "cloudtrail" // Event of CloudTrail, made by anyone
+	SourceAudit      = "audit"      // Entry of the local audit log, made with e2c
+)
+
+// ProtectionChange is a change of a protection of an instance
+type ProtectionChange struct {
+	Time       time.Time
+	User       string // ARN of the caller, or its name
+	Protection string // ProtectionTermination or ProtectionStop
+	Enabled    bool   // The protection was enabled, or disabled
+	Source     string // SourceCloudTrail or SourceAudit
+}
+
+// ProtectionHistory holds the changes of the protections of an instance, the
+// most recent first
+type ProtectionHistory struct {
+	Changes       []ProtectionChange
+	CloudTrailErr error // Error raised while looking up CloudTrail, if any
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+import "time"
+
+// Volume represents an EBS volume
+type Volume struct {
+	ID               string
+	AvailabilityZone string
+	Type             string // gp2, gp3, io1, ...
+	Size             int    // Size in GiB
+	IOPS             int    // Provisioned IOPS, 0 if not applicable
+	Throughput       int    // Provisioned throughput in MiB/s (gp3), 0 if not applicable
+	State            string
+}
+
+// Snapshot represents an EBS snapshot of a volume
+type Snapshot struct {
+	ID          string
+	VolumeID    string
+	Description string
+	StartTime   time.Time
+	Size        int // Size in GiB
+	State       string
+	Encrypted   bool
+}
+
+// RootVolume returns the ID of the EBS root volume of the instance, empty if
+// the root device is not an EBS volume
+func (i *Instance) RootVolume() string {
+	if i.RootDeviceType != "ebs" {
+		return ""
+	}
+	for _, device := range i.BlockDevices {
+		if device.DeviceName == i.RootDeviceName {
+			return device.VolumeID
+		}
+	}
+	return ""
+}
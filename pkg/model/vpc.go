@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package model
+
+// VPC represents an AWS VPC and its subnets
+type VPC struct {
+	ID        string
+	Name      string
+	CIDR      string
+	State     string
+	IsDefault bool
+	Subnets   []Subnet
+}
+
+// Subnet represents a subnet of a VPC
+type Subnet struct {
+	ID               string
+	Name             string
+	VpcID            string
+	CIDR             string
+	AvailabilityZone string
+	AvailableIPs     int
+	State            string
+	DefaultForAZ     bool
+}
+
+// AvailableIPs returns the number of free IP addresses in the subnets of the VPC
+func (v VPC) AvailableIPs() int {
+	total := 0
+	for _, subnet := range v.Subnets {
+		total += subnet.AvailableIPs
+	}
+	return total
+}
+
+// DisplayName returns the name of the VPC, or its ID if it has no name
+func (v VPC) DisplayName() string {
+	if v.Name != "" {
+		return v.Name
+	}
+	return v.ID
+}
+
+// SubnetRouting is the route table and the network ACL of a subnet
+type SubnetRouting struct {
+	RouteTableID   string
+	RouteTableName string
+	MainRouteTable bool // The subnet uses the main route table of the VPC
+	Routes         []Route
+	NetworkACLID   string
+	NetworkACLName string
+	ACLEntries     []ACLEntry // Sorted by rule number, the inbound ones first
+}
+
+// Route is a route of a route table
+type Route struct {
+	Destination string // CIDR block or prefix list
+	Target      string // e.g. local, igw-0123, nat-0123
+	State       string // active or blackhole
+}
+
+// ACLEntry is a rule of a network ACL
+type ACLEntry struct {
+	RuleNumber int // 32767 for the default rule
+	Egress     bool
+	Protocol   string // e.g. tcp, udp, icmp or all
+	Ports      string // e.g. 443 or 1024-65535, empty for all
+	CIDR       string
+	Action     string // allow or deny
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import "github.com/nlamirault/e2c/pkg/model"
+
+// Action describes a change of the state
+type Action interface {
+	Name() string
+}
+
+// SessionStarted is dispatched when another profile, region or context is
+// displayed: the instances, events, VPCs and AWS Health events loaded for
+// the previous session are then dropped, and the ones still retrieved for it
+// are ignored
+type SessionStarted struct {
+	Session uint64
+}
+
+// Name returns the name of the action
+func (SessionStarted) Name() string { return "SessionStarted" }
+
+// InstancesLoaded is dispatched for each page of instances retrieved, with
+// all the instances retrieved so far. Complete is set on the last page.
+type InstancesLoaded struct {
+	Instances []model.Instance
+	Page      int
+	Complete  bool
+	Session   uint64 // Session the instances were retrieved for
+}
+
+// Name returns the name of the action
+func (InstancesLoaded) Name() string { return "InstancesLoaded" }
+
+// EventsLoaded is dispatched when the scheduled events were retrieved
+type EventsLoaded struct {
+	Events  map[string][]model.ScheduledEvent
+	Session uint64 // Session the events were retrieved for
+}
+
+// Name returns the name of the action
+func (EventsLoaded) Name() string { return "EventsLoaded" }
+
+// VPCsLoaded is dispatched when the VPCs were retrieved
+type VPCsLoaded struct {
+	VPCs    []model.VPC
+	Session uint64 // Session the VPCs were retrieved for
+}
+
+// Name returns the name of the action
+func (VPCsLoaded) Name() string { return "VPCsLoaded" }
+
+// HealthEventsLoaded is dispatched when the AWS Health events were retrieved
+type HealthEventsLoaded struct {
+	Events  []model.HealthEvent
+	Session uint64 // Session the events were retrieved for
+}
+
+// Name returns the name of the action
+func (HealthEventsLoaded) Name() string { return "HealthEventsLoaded" }
+
+// ProtectionLoaded is dispatched when the protections of an instance were
+// retrieved
+type ProtectionLoaded struct {
+	InstanceID string
+	Protection model.Protection
+}
+
+// Name returns the name of the action
+func (ProtectionLoaded) Name() string { return "ProtectionLoaded" }
+
+// ProtectionsLoaded is dispatched when the protections of several instances
+// were retrieved, e.g. by a scan, to update the state once for all of them
+type ProtectionsLoaded struct {
+	Protections map[string]model.Protection // Protections by instance ID
+}
+
+// Name returns the name of the action
+func (ProtectionsLoaded) Name() string { return "ProtectionsLoaded" }
+
+// ProtectionInvalidated is dispatched when the protections of an instance
+// may have changed, e.g. after an action on it, to retrieve them again
+type ProtectionInvalidated struct {
+	InstanceID string
+}
+
+// Name returns the name of the action
+func (ProtectionInvalidated) Name() string { return "ProtectionInvalidated" }
+
+// ProtectionsCleared is dispatched when the protections scanned so far no
+// longer apply, e.g. after switching to another account
+type ProtectionsCleared struct{}
+
+// Name returns the name of the action
+func (ProtectionsCleared) Name() string { return "ProtectionsCleared" }
+
+// stale reports whether an action was retrieved for a session which is no
+// longer displayed, e.g. by a refresh still running when the region was
+// switched
+func stale(state *State, action Action) bool {
+	switch a := action.(type) {
+	case InstancesLoaded:
+		return a.Session != state.Session
+	case EventsLoaded:
+		return a.Session != state.Session
+	case VPCsLoaded:
+		return a.Session != state.Session
+	case HealthEventsLoaded:
+		return a.Session != state.Session
+	}
+	return false
+}
+
+// reduce returns the state resulting from an action
+func reduce(state State, action Action) State {
+	switch a := action.(type) {
+	case SessionStarted:
+		state.Session = a.Session
+		state.Instances = []model.Instance{}
+		state.Pages = 0
+		state.Loading = false
+		state.Events = map[string][]model.ScheduledEvent{}
+		state.VPCs = nil
+		state.Health = nil
+	case InstancesLoaded:
+		state.Instances = a.Instances
+		state.Pages = a.Page
+		state.Loading = !a.Complete
+	case EventsLoaded:
+		state.Events = a.Events
+	case VPCsLoaded:
+		state.VPCs = a.VPCs
+	case HealthEventsLoaded:
+		state.Health = a.Events
+	case ProtectionLoaded:
+		state.Protections = withProtections(state.Protections, map[string]model.Protection{a.InstanceID: a.Protection})
+	case ProtectionsLoaded:
+		if len(a.Protections) > 0 {
+			state.Protections = withProtections(state.Protections, a.Protections)
+		}
+	case ProtectionInvalidated:
+		if _, ok := state.Protections[a.InstanceID]; ok {
+			protections := make(map[string]model.Protection, len(state.Protections))
+			for id, protection := range state.Protections {
+				if id != a.InstanceID {
+					protections[id] = protection
+				}
+			}
+			state.Protections = protections
+		}
+	case ProtectionsCleared:
+		state.Protections = map[string]model.Protection{}
+	}
+	return state
+}
+
+// withProtections returns a copy of the protections with the loaded ones
+// added, the state being immutable
+func withProtections(current, loaded map[string]model.Protection) map[string]model.Protection {
+	protections := make(map[string]model.Protection, len(current)+len(loaded))
+	for id, protection := range current {
+		protections[id] = protection
+	}
+	for id, protection := range loaded {
+		protections[id] = protection
+	}
+	return protections
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package store caches the instances of a region and their enrichments,
+// shared between the goroutines refreshing them and the ones reading them:
+// the instances, their scheduled events and protections, the VPCs and the
+// AWS Health events. The data loaded on demand for a single view, such as
+// the tabs of the detail view, the volumes or the SSM sessions, stays in the
+// state of that view.
+package store
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// State is a snapshot of the data shared between the AWS client and the
+// views. A State is never modified once published: the reducer builds a new
+// one, replacing the slices and maps it changes instead of mutating them.
+type State struct {
+	Instances   []model.Instance                  // Instances of the last refresh
+	Pages       int                               // Number of pages of instances loaded
+	Loading     bool                              // More pages of instances are being loaded
+	Events      map[string][]model.ScheduledEvent // Active scheduled events by instance ID
+	VPCs        []model.VPC                       // VPCs of the region, with their subnets
+	Health      []model.HealthEvent               // Open and upcoming AWS Health events of EC2
+	Protections map[string]model.Protection       // Protections by instance ID, kept across refreshes
+	Session     uint64                            // Session displayed, see SessionStarted
+	Version     uint64                            // Incremented by each action
+	UpdatedAt   time.Time                         // Time of the last action
+}
+
+// Listener is called after an action was applied, with the new state. It is
+// called from the store goroutine: listeners updating the UI must queue
+// their changes in the UI goroutine, and must never dispatch actions
+// synchronously, the store goroutine blocking on its own queue once full.
+type Listener func(state *State, action Action)
+
+// Store owns the shared data. All the mutations are actions dispatched to
+// the store, applied in order by a single writer goroutine, which then
+// notifies the subscribed listeners. Readers get immutable snapshots without
+// locking.
+type Store struct {
+	ctx     context.Context // Stops the store, and unblocks the dispatches
+	log     *slog.Logger
+	actions chan Action
+	current atomic.Pointer[State]
+
+	listenersM sync.Mutex
+	listeners  map[int]Listener
+	nextID     int
+}
+
+// New creates a new store with an empty state, applying the actions until
+// the context is cancelled
+func New(ctx context.Context, log *slog.Logger) *Store {
+	s := &Store{
+		ctx:       ctx,
+		log:       log,
+		actions:   make(chan Action, 64),
+		listeners: make(map[int]Listener),
+	}
+	s.current.Store(&State{
+		Instances:   []model.Instance{},
+		Events:      map[string][]model.ScheduledEvent{},
+		Protections: map[string]model.Protection{},
+	})
+	return s
+}
+
+// Run applies the dispatched actions until the context of the store is
+// cancelled
+func (s *Store) Run() {
+	for {
+		select {
+		case action := <-s.actions:
+			if current := s.current.Load(); stale(current, action) {
+				s.log.Debug("Store action of a previous session dropped", "action", action.Name(), "session", current.Session)
+				continue
+			}
+			next := reduce(*s.current.Load(), action)
+			next.Version++
+			next.UpdatedAt = time.Now()
+			s.current.Store(&next)
+			s.log.Debug("Store action applied", "action", action.Name(), "version", next.Version)
+			s.notify(&next, action)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// Dispatch queues an action to be applied to the state. It blocks while the
+// queue is full, and drops the action once the store is stopped.
+func (s *Store) Dispatch(action Action) {
+	select {
+	case s.actions <- action:
+	case <-s.ctx.Done():
+	}
+}
+
+// Snapshot returns the current state, which must not be modified
+func (s *Store) Snapshot() *State {
+	return s.current.Load()
+}
+
+// Subscribe registers a listener called after each action, and returns the
+// function removing it
+func (s *Store) Subscribe(listener Listener) func() {
+	s.listenersM.Lock()
+	defer s.listenersM.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.listeners[id] = listener
+
+	return func() {
+		s.listenersM.Lock()
+		defer s.listenersM.Unlock()
+		delete(s.listeners, id)
+	}
+}
+
+// notify calls the listeners with the new state
+func (s *Store) notify(state *State, action Action) {
+	s.listenersM.Lock()
+	listeners := make([]Listener, 0, len(s.listeners))
+	for _, listener := range s.listeners {
+		listeners = append(listeners, listener)
+	}
+	s.listenersM.Unlock()
+
+	for _, listener := range listeners {
+		listener(state, action)
+	}
+}
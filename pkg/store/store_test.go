@@ -0,0 +1,305 @@
+// SPDX-FileCopyrightText: Copyright (C) Nicolas Lamirault <nicolas.lamirault@gmail.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package store
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlamirault/e2c/pkg/model"
+)
+
+// newTestStore creates a running store without logs, stopped at the end of
+// the test
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	s := New(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	go s.Run()
+	return s
+}
+
+func TestReduce(t *testing.T) {
+	instances := []model.Instance{{ID: "i-1"}, {ID: "i-2"}}
+	protected := model.Protection{Termination: true}
+	unprotected := model.Protection{}
+
+	initial := State{
+		Instances:   []model.Instance{{ID: "i-0"}},
+		Pages:       2,
+		Events:      map[string][]model.ScheduledEvent{"i-0": {{Code: "instance-retirement"}}},
+		VPCs:        []model.VPC{{ID: "vpc-0"}},
+		Health:      []model.HealthEvent{{ARN: "arn:health:0"}},
+		Protections: map[string]model.Protection{"i-1": unprotected, "i-2": unprotected},
+		Session:     1,
+	}
+
+	tests := []struct {
+		name   string
+		action Action
+		want   func(state State) State
+	}{
+		{
+			name:   "first page of instances",
+			action: InstancesLoaded{Instances: instances, Page: 1},
+			want: func(state State) State {
+				state.Instances, state.Pages, state.Loading = instances, 1, true
+				return state
+			},
+		},
+		{
+			name:   "last page of instances",
+			action: InstancesLoaded{Instances: instances, Page: 3, Complete: true},
+			want: func(state State) State {
+				state.Instances, state.Pages, state.Loading = instances, 3, false
+				return state
+			},
+		},
+		{
+			name:   "events",
+			action: EventsLoaded{Events: map[string][]model.ScheduledEvent{"i-1": {{Code: "system-reboot"}}}},
+			want: func(state State) State {
+				state.Events = map[string][]model.ScheduledEvent{"i-1": {{Code: "system-reboot"}}}
+				return state
+			},
+		},
+		{
+			name:   "VPCs",
+			action: VPCsLoaded{VPCs: []model.VPC{{ID: "vpc-1"}}},
+			want: func(state State) State {
+				state.VPCs = []model.VPC{{ID: "vpc-1"}}
+				return state
+			},
+		},
+		{
+			name:   "health events",
+			action: HealthEventsLoaded{Events: []model.HealthEvent{{ARN: "arn:health"}}},
+			want: func(state State) State {
+				state.Health = []model.HealthEvent{{ARN: "arn:health"}}
+				return state
+			},
+		},
+		{
+			name:   "protection",
+			action: ProtectionLoaded{InstanceID: "i-3", Protection: protected},
+			want: func(state State) State {
+				state.Protections = map[string]model.Protection{"i-1": unprotected, "i-2": unprotected, "i-3": protected}
+				return state
+			},
+		},
+		{
+			name:   "protections",
+			action: ProtectionsLoaded{Protections: map[string]model.Protection{"i-2": protected, "i-3": protected}},
+			want: func(state State) State {
+				state.Protections = map[string]model.Protection{"i-1": unprotected, "i-2": protected, "i-3": protected}
+				return state
+			},
+		},
+		{
+			name:   "no protections",
+			action: ProtectionsLoaded{},
+			want:   func(state State) State { return state },
+		},
+		{
+			name:   "protection invalidated",
+			action: ProtectionInvalidated{InstanceID: "i-1"},
+			want: func(state State) State {
+				state.Protections = map[string]model.Protection{"i-2": unprotected}
+				return state
+			},
+		},
+		{
+			name:   "unknown protection invalidated",
+			action: ProtectionInvalidated{InstanceID: "i-9"},
+			want:   func(state State) State { return state },
+		},
+		{
+			name:   "session started",
+			action: SessionStarted{Session: 2},
+			want: func(state State) State {
+				// The data of the previous session is dropped, the
+				// protections are cleared with ProtectionsCleared
+				state.Session = 2
+				state.Instances, state.Pages, state.Loading = []model.Instance{}, 0, false
+				state.Events = map[string][]model.ScheduledEvent{}
+				state.VPCs, state.Health = nil, nil
+				return state
+			},
+		},
+		{
+			name:   "protections cleared",
+			action: ProtectionsCleared{},
+			want: func(state State) State {
+				state.Protections = map[string]model.Protection{}
+				return state
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := map[string]model.Protection{}
+			for id, protection := range initial.Protections {
+				before[id] = protection
+			}
+
+			got := reduce(initial, tt.action)
+			if want := tt.want(initial); !reflect.DeepEqual(got, want) {
+				t.Errorf("reduce(%s) = %+v, want %+v", tt.action.Name(), got, want)
+			}
+			// The previous state is never modified
+			if !reflect.DeepEqual(initial.Protections, before) {
+				t.Errorf("reduce(%s) modified the protections of the previous state: %v", tt.action.Name(), initial.Protections)
+			}
+		})
+	}
+}
+
+func TestStoreDispatch(t *testing.T) {
+	s := newTestStore(t)
+
+	var (
+		mutex   sync.Mutex
+		actions []string
+	)
+	applied := make(chan *State, 3)
+	s.Subscribe(func(state *State, action Action) {
+		mutex.Lock()
+		actions = append(actions, action.Name())
+		mutex.Unlock()
+		applied <- state
+	})
+
+	s.Dispatch(InstancesLoaded{Instances: []model.Instance{{ID: "i-1"}}, Page: 1})
+	s.Dispatch(ProtectionLoaded{InstanceID: "i-1", Protection: model.Protection{Stop: true}})
+	s.Dispatch(InstancesLoaded{Instances: []model.Instance{{ID: "i-1"}, {ID: "i-2"}}, Page: 2, Complete: true})
+
+	var last *State
+	for i := 1; i <= 3; i++ {
+		select {
+		case last = <-applied:
+			if last.Version != uint64(i) {
+				t.Errorf("version of action %d = %d, want %d", i, last.Version, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("action %d not applied", i)
+		}
+	}
+
+	if want := []string{"InstancesLoaded", "ProtectionLoaded", "InstancesLoaded"}; !reflect.DeepEqual(actions, want) {
+		t.Errorf("actions = %q, want %q", actions, want)
+	}
+	if got := s.Snapshot(); got != last {
+		t.Errorf("Snapshot() is not the last state applied")
+	}
+	if len(last.Instances) != 2 || last.Loading || !last.Protections["i-1"].Stop {
+		t.Errorf("state = %+v, want 2 instances loaded and the protections of i-1", last)
+	}
+}
+
+func TestStoreStaleSession(t *testing.T) {
+	s := newTestStore(t)
+
+	applied := make(chan Action, 10)
+	s.Subscribe(func(state *State, action Action) { applied <- action })
+
+	// Data of the first session, then dropped by the switch
+	s.Dispatch(VPCsLoaded{VPCs: []model.VPC{{ID: "vpc-old"}}})
+	s.Dispatch(HealthEventsLoaded{Events: []model.HealthEvent{{ARN: "arn:health:old"}}})
+	s.Dispatch(SessionStarted{Session: 1})
+
+	// Retrieved for the first session once the second one is displayed
+	s.Dispatch(InstancesLoaded{Instances: []model.Instance{{ID: "i-old"}}, Complete: true})
+	s.Dispatch(EventsLoaded{Events: map[string][]model.ScheduledEvent{"i-old": {{Code: "system-reboot"}}}})
+	s.Dispatch(VPCsLoaded{VPCs: []model.VPC{{ID: "vpc-old"}}})
+	s.Dispatch(HealthEventsLoaded{Events: []model.HealthEvent{{ARN: "arn:health:old"}}})
+
+	s.Dispatch(InstancesLoaded{Instances: []model.Instance{{ID: "i-new"}}, Complete: true, Session: 1})
+	s.Dispatch(VPCsLoaded{VPCs: []model.VPC{{ID: "vpc-new"}}, Session: 1})
+
+	var actions []string
+	for range 5 {
+		select {
+		case action := <-applied:
+			actions = append(actions, action.Name())
+		case <-time.After(time.Second):
+			t.Fatalf("actions applied = %q, want 5", actions)
+		}
+	}
+	if want := []string{"VPCsLoaded", "HealthEventsLoaded", "SessionStarted", "InstancesLoaded", "VPCsLoaded"}; !reflect.DeepEqual(actions, want) {
+		t.Errorf("actions = %q, want %q", actions, want)
+	}
+
+	state := s.Snapshot()
+	if len(state.Instances) != 1 || state.Instances[0].ID != "i-new" {
+		t.Errorf("instances = %+v, want the ones of the session displayed", state.Instances)
+	}
+	if len(state.VPCs) != 1 || state.VPCs[0].ID != "vpc-new" {
+		t.Errorf("VPCs = %+v, want the ones of the session displayed", state.VPCs)
+	}
+	if len(state.Events) != 0 || len(state.Health) != 0 {
+		t.Errorf("events = %+v, health = %+v, want none of the previous session", state.Events, state.Health)
+	}
+	if state.Version != 5 {
+		t.Errorf("version = %d, want 5 actions applied", state.Version)
+	}
+}
+
+func TestStoreUnsubscribe(t *testing.T) {
+	s := newTestStore(t)
+
+	first := make(chan Action, 2)
+	second := make(chan Action, 2)
+	unsubscribe := s.Subscribe(func(state *State, action Action) { first <- action })
+	s.Subscribe(func(state *State, action Action) { second <- action })
+
+	s.Dispatch(VPCsLoaded{})
+	<-first
+	<-second
+
+	unsubscribe()
+	s.Dispatch(ProtectionsCleared{})
+	select {
+	case <-second:
+	case <-time.After(time.Second):
+		t.Fatal("action not notified to the remaining listener")
+	}
+	select {
+	case action := <-first:
+		t.Errorf("%s notified to a removed listener", action.Name())
+	default:
+	}
+}
+
+func TestStoreStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	cancel()
+
+	// The queue is never drained: the dispatches must not block once the
+	// store is stopped
+	done := make(chan struct{})
+	go func() {
+		for range cap(s.actions) + 10 {
+			s.Dispatch(ProtectionsCleared{})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Dispatch() blocked on a stopped store")
+	}
+	if got := s.Snapshot(); got.Version != 0 || len(got.Instances) != 0 {
+		t.Errorf("Snapshot() = %+v, want the empty state", got)
+	}
+}